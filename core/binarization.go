@@ -0,0 +1,38 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"image"
+
+	"github.com/TheLinker/unipdf/v3/internal/imageutil"
+)
+
+// BinarizationMethod is the method used to compute the thresholds when converting
+// gray and color images to binary (black/white) images.
+type BinarizationMethod = imageutil.BinarizationMethod
+
+// Binarization methods.
+const (
+	// BinarizeTriangle uses a single threshold for the whole image, computed with the triangle method.
+	BinarizeTriangle = imageutil.BinarizeTriangle
+	// BinarizeOtsu uses the Otsu thresholds of the tiles of the image, interpolated between the tiles.
+	BinarizeOtsu = imageutil.BinarizeOtsu
+	// BinarizeSauvola uses the Sauvola local thresholds, suited for the unevenly lit documents.
+	BinarizeSauvola = imageutil.BinarizeSauvola
+	// BinarizeNiblack uses the Niblack local thresholds.
+	BinarizeNiblack = imageutil.BinarizeNiblack
+)
+
+// BinarizationParams are the parameters of the conversion of gray and color images to binary
+// images. The zero value uses a global threshold computed with the triangle method.
+type BinarizationParams = imageutil.BinarizationParams
+
+// BinarizeImage converts the image 'i' to a binary image using the 'params' thresholding method.
+// The returned gray image contains black (0) and white (255) pixels only.
+func BinarizeImage(i image.Image, params BinarizationParams) *image.Gray {
+	return imageutil.Binarize(i, params)
+}
@@ -128,33 +128,42 @@ func (parser *PdfParser) lookupObjectViaOS(sobjNumber int, objNum int) (PdfObjec
 
 		common.Log.Trace("Parsing offset map")
 		// Load the offset map (relative to the beginning of the stream...)
-		offsets := map[int]int64{}
-		// Object list and offsets.
-		for i := 0; i < int(*N); i++ {
-			parser.skipSpaces()
-			// Object number.
-			obj, err := parser.parseNumber()
+		var offsets map[int]int64
+		if !parser.noLexer {
+			offsets, err = parser.parseObjectStreamOffsets(ds, int64(*N), int64(*firstOffset))
 			if err != nil {
-				return nil, err
-			}
-			onum, ok := obj.(*PdfObjectInteger)
-			if !ok {
-				return nil, errors.New("invalid object stream offset table")
+				common.Log.Debug("Parsing the object stream offsets again: %v", err)
 			}
+		}
+		if offsets == nil {
+			offsets = map[int]int64{}
+			// Object list and offsets.
+			for i := 0; i < int(*N); i++ {
+				parser.skipSpaces()
+				// Object number.
+				obj, err := parser.parseNumber()
+				if err != nil {
+					return nil, err
+				}
+				onum, ok := obj.(*PdfObjectInteger)
+				if !ok {
+					return nil, errors.New("invalid object stream offset table")
+				}
 
-			parser.skipSpaces()
-			// Offset.
-			obj, err = parser.parseNumber()
-			if err != nil {
-				return nil, err
-			}
-			offset, ok := obj.(*PdfObjectInteger)
-			if !ok {
-				return nil, errors.New("invalid object stream offset table")
-			}
+				parser.skipSpaces()
+				// Offset.
+				obj, err = parser.parseNumber()
+				if err != nil {
+					return nil, err
+				}
+				offset, ok := obj.(*PdfObjectInteger)
+				if !ok {
+					return nil, errors.New("invalid object stream offset table")
+				}
 
-			common.Log.Trace("obj %d offset %d", *onum, *offset)
-			offsets[int(*onum)] = int64(*firstOffset + *offset)
+				common.Log.Trace("obj %d offset %d", *onum, *offset)
+				offsets[int(*onum)] = int64(*firstOffset + *offset)
+			}
 		}
 
 		objstm = objectStream{N: int(*N), ds: ds, offsets: offsets}
@@ -173,13 +182,24 @@ func (parser *PdfParser) lookupObjectViaOS(sobjNumber int, objNum int) (PdfObjec
 	offset := objstm.offsets[objNum]
 	common.Log.Trace("ACTUAL offset[%d] = %d", objNum, offset)
 
-	bufReader.Seek(offset, os.SEEK_SET)
-	parser.reader = bufio.NewReader(bufReader)
+	var val PdfObject
+	var err error
+	parsed := false
+	if !parser.noLexer && offset >= 0 && offset < int64(len(objstm.ds)) {
+		l := newLexer(parser, objstm.ds)
+		l.pos = int(offset)
+		val, err = l.parseObject()
+		parsed = err == nil
+	}
+	if !parsed {
+		bufReader.Seek(offset, os.SEEK_SET)
+		parser.reader = bufio.NewReader(bufReader)
 
-	bb, _ := parser.reader.Peek(100)
-	common.Log.Trace("OBJ peek \"%s\"", string(bb))
+		bb, _ := parser.reader.Peek(100)
+		common.Log.Trace("OBJ peek \"%s\"", string(bb))
 
-	val, err := parser.parseObject()
+		val, err = parser.parseObject()
+	}
 	if err != nil {
 		common.Log.Debug("ERROR Fail to read object (%s)", err)
 		return nil, err
@@ -259,10 +279,13 @@ func (parser *PdfParser) lookupByNumber(objNumber int, attemptRepairs bool) (Pdf
 		common.Log.Trace("xrefobj gen %d", xref.Generation)
 		common.Log.Trace("xrefobj offset %d", xref.Offset)
 
-		parser.rs.Seek(xref.Offset, os.SEEK_SET)
-		parser.reader = bufio.NewReader(parser.rs)
-
-		obj, err := parser.ParseIndirectObject()
+		obj, ok := parser.parseIndirectObjectAt(xref.Offset)
+		var err error
+		if !ok {
+			parser.rs.Seek(xref.Offset, os.SEEK_SET)
+			parser.reader = bufio.NewReader(parser.rs)
+			obj, err = parser.ParseIndirectObject()
+		}
 		if err != nil {
 			common.Log.Debug("ERROR Failed reading xref (%s)", err)
 			// Offset pointing to a non-object.  Try to repair the file.
@@ -10,6 +10,7 @@ import (
 	"crypto/rand"
 	"errors"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/TheLinker/unipdf/v3/common"
@@ -596,6 +597,38 @@ func (crypt *PdfCrypt) isDecrypted(obj PdfObject) bool {
 	return false
 }
 
+// streamCryptFilter returns the name of the crypt filter of the stream of dictionary `dict`,
+// "Identity" if the data of the stream is not encrypted.
+func (crypt *PdfCrypt) streamCryptFilter(dict *PdfObjectDictionary) string {
+	if crypt.encrypt.V < 4 {
+		return stdCryptFilter // Default RC4.
+	}
+	streamFilter := crypt.streamFilter
+	common.Log.Trace("this.streamFilter = %s", crypt.streamFilter)
+
+	// The Crypt filter shall be the first filter in the Filter array entry.
+	if filters, ok := dict.Get("Filter").(*PdfObjectArray); ok {
+		if firstFilter, ok := GetName(filters.Get(0)); ok && *firstFilter == "Crypt" {
+			// Crypt filter overriding the default.
+			// Default option is Identity.
+			streamFilter = "Identity"
+
+			// Check if valid crypt filter specified in the decode params.
+			if decodeParams, ok := dict.Get("DecodeParms").(*PdfObjectDictionary); ok {
+				if filterName, ok := decodeParams.Get("Name").(*PdfObjectName); ok {
+					if _, ok := crypt.cryptFilters[string(*filterName)]; ok {
+						common.Log.Trace("Using stream filter %s", *filterName)
+						streamFilter = string(*filterName)
+					}
+				}
+			}
+		}
+	}
+
+	common.Log.Trace("with %s filter", streamFilter)
+	return streamFilter
+}
+
 // Decrypt a buffer with a selected crypt filter.
 func (crypt *PdfCrypt) decryptBytes(buf []byte, filter string, okey []byte) ([]byte, error) {
 	common.Log.Trace("Decrypt bytes")
@@ -646,40 +679,10 @@ func (crypt *PdfCrypt) Decrypt(obj PdfObject, parentObjNum, parentGenNum int64)
 		genNum := obj.GenerationNumber
 		common.Log.Trace("Decrypting stream %d %d !", objNum, genNum)
 
-		// TODO: Check for crypt filter (V4).
-		// The Crypt filter shall be the first filter in the Filter array entry.
-
-		streamFilter := stdCryptFilter // Default RC4.
-		if crypt.encrypt.V >= 4 {
-			streamFilter = crypt.streamFilter
-			common.Log.Trace("this.streamFilter = %s", crypt.streamFilter)
-
-			if filters, ok := dict.Get("Filter").(*PdfObjectArray); ok {
-				// Crypt filter can only be the first entry.
-				if firstFilter, ok := GetName(filters.Get(0)); ok {
-					if *firstFilter == "Crypt" {
-						// Crypt filter overriding the default.
-						// Default option is Identity.
-						streamFilter = "Identity"
-
-						// Check if valid crypt filter specified in the decode params.
-						if decodeParams, ok := dict.Get("DecodeParms").(*PdfObjectDictionary); ok {
-							if filterName, ok := decodeParams.Get("Name").(*PdfObjectName); ok {
-								if _, ok := crypt.cryptFilters[string(*filterName)]; ok {
-									common.Log.Trace("Using stream filter %s", *filterName)
-									streamFilter = string(*filterName)
-								}
-							}
-						}
-					}
-				}
-			}
-
-			common.Log.Trace("with %s filter", streamFilter)
-			if streamFilter == "Identity" {
-				// Identity: pass unchanged.
-				return nil
-			}
+		streamFilter := crypt.streamCryptFilter(dict)
+		if streamFilter == "Identity" {
+			// Identity: pass unchanged.
+			return nil
 		}
 
 		err := crypt.Decrypt(dict, objNum, genNum)
@@ -692,6 +695,14 @@ func (crypt *PdfCrypt) Decrypt(obj PdfObject, parentObjNum, parentGenNum int64)
 			return err
 		}
 
+		if obj.source != nil {
+			// The data of the streamed streams is decrypted while it is read.
+			obj.source = wrapStreamSource(obj.source, func(r io.Reader) (io.Reader, error) {
+				return crypt.newDecryptReader(r, streamFilter, okey)
+			})
+			return nil
+		}
+
 		obj.Stream, err = crypt.decryptBytes(obj.Stream, streamFilter, okey)
 		if err != nil {
 			return err
@@ -771,6 +782,36 @@ func (crypt *PdfCrypt) Decrypt(obj PdfObject, parentObjNum, parentGenNum int64)
 	return nil
 }
 
+// newDecryptReader returns a reader of the data of `r` decrypted with the crypt filter `filter`
+// and the object key `okey`.
+func (crypt *PdfCrypt) newDecryptReader(r io.Reader, filter string, okey []byte) (io.Reader, error) {
+	f, ok := crypt.cryptFilters[filter]
+	if !ok {
+		return nil, fmt.Errorf("unknown crypt filter (%s)", filter)
+	}
+	if sf, ok := f.(crypto.StreamFilter); ok {
+		return sf.NewDecryptReader(r, okey)
+	}
+	return newBufferedReader(r, func(data []byte) ([]byte, error) {
+		return f.DecryptBytes(data, okey)
+	}), nil
+}
+
+// newEncryptReader returns a reader of the data of `r` encrypted with the crypt filter `filter`
+// and the object key `okey`.
+func (crypt *PdfCrypt) newEncryptReader(r io.Reader, filter string, okey []byte) (io.Reader, error) {
+	f, ok := crypt.cryptFilters[filter]
+	if !ok {
+		return nil, fmt.Errorf("unknown crypt filter (%s)", filter)
+	}
+	if sf, ok := f.(crypto.StreamFilter); ok {
+		return sf.NewEncryptReader(r, okey)
+	}
+	return newBufferedReader(r, func(data []byte) ([]byte, error) {
+		return f.EncryptBytes(data, okey)
+	}), nil
+}
+
 // Check if object has already been processed.
 func (crypt *PdfCrypt) isEncrypted(obj PdfObject) bool {
 	_, ok := crypt.encryptedObjects[obj]
@@ -829,42 +870,10 @@ func (crypt *PdfCrypt) Encrypt(obj PdfObject, parentObjNum, parentGenNum int64)
 		genNum := obj.GenerationNumber
 		common.Log.Trace("Encrypting stream %d %d !", objNum, genNum)
 
-		// TODO: Check for crypt filter (V4).
-		// The Crypt filter shall be the first filter in the Filter array entry.
-
-		streamFilter := stdCryptFilter // Default RC4.
-		if crypt.encrypt.V >= 4 {
-			// For now.  Need to change when we add support for more than
-			// Identity / RC4.
-			streamFilter = crypt.streamFilter
-			common.Log.Trace("this.streamFilter = %s", crypt.streamFilter)
-
-			if filters, ok := dict.Get("Filter").(*PdfObjectArray); ok {
-				// Crypt filter can only be the first entry.
-				if firstFilter, ok := GetName(filters.Get(0)); ok {
-					if *firstFilter == "Crypt" {
-						// Crypt filter overriding the default.
-						// Default option is Identity.
-						streamFilter = "Identity"
-
-						// Check if valid crypt filter specified in the decode params.
-						if decodeParams, ok := dict.Get("DecodeParms").(*PdfObjectDictionary); ok {
-							if filterName, ok := decodeParams.Get("Name").(*PdfObjectName); ok {
-								if _, ok := crypt.cryptFilters[string(*filterName)]; ok {
-									common.Log.Trace("Using stream filter %s", *filterName)
-									streamFilter = string(*filterName)
-								}
-							}
-						}
-					}
-				}
-			}
-
-			common.Log.Trace("with %s filter", streamFilter)
-			if streamFilter == "Identity" {
-				// Identity: pass unchanged.
-				return nil
-			}
+		streamFilter := crypt.streamCryptFilter(dict)
+		if streamFilter == "Identity" {
+			// Identity: pass unchanged.
+			return nil
 		}
 
 		err := crypt.Encrypt(obj.PdfObjectDictionary, objNum, genNum)
@@ -877,6 +886,14 @@ func (crypt *PdfCrypt) Encrypt(obj PdfObject, parentObjNum, parentGenNum int64)
 			return err
 		}
 
+		if obj.source != nil {
+			// The data of the streamed streams is encrypted while it is read.
+			obj.source = wrapStreamSource(obj.source, func(r io.Reader) (io.Reader, error) {
+				return crypt.newEncryptReader(r, streamFilter, okey)
+			})
+			return nil
+		}
+
 		obj.Stream, err = crypt.encryptBytes(obj.Stream, streamFilter, okey)
 		if err != nil {
 			return err
@@ -8,10 +8,15 @@
 package core
 
 import (
+	"bytes"
+	"io/ioutil"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+
 	"github.com/TheLinker/unipdf/v3/common"
 	"github.com/TheLinker/unipdf/v3/core/security"
+	"github.com/TheLinker/unipdf/v3/core/security/crypt"
 )
 
 func init() {
@@ -95,3 +100,40 @@ func TestDecryption1(t *testing.T) {
 		return
 	}
 }
+
+// TestCryptFilterStreams checks that the data encrypted and decrypted while it is read, as for
+// the streamed streams, is the data encrypted and decrypted by the filters as a whole.
+func TestCryptFilterStreams(t *testing.T) {
+	filters := map[string]crypt.Filter{
+		"V2":    crypt.NewFilterV2(16),
+		"AESV2": crypt.NewFilterAESV2(),
+		"AESV3": crypt.NewFilterAESV3(),
+	}
+	for name, f := range filters {
+		sf, ok := f.(crypt.StreamFilter)
+		require.True(t, ok, name)
+		fkey := bytes.Repeat([]byte{0x5a}, f.KeyLength())
+		okey, err := f.MakeKey(12, 0, fkey)
+		require.NoError(t, err, name)
+
+		for _, n := range []int{0, 1, 15, 16, 17, 5000} {
+			data := streamingTestData(n)
+
+			// The AES encryption uses a random IV, so the data encrypted while it is read is
+			// checked by decrypting it as a whole.
+			r, err := sf.NewEncryptReader(bytes.NewReader(data), okey)
+			require.NoError(t, err, name)
+			encrypted, err := ioutil.ReadAll(r)
+			require.NoError(t, err, name)
+			decrypted, err := f.DecryptBytes(append([]byte{}, encrypted...), okey)
+			require.NoError(t, err, name)
+			require.Equal(t, data, append([]byte{}, decrypted...), "%s %d", name, n)
+
+			r, err = sf.NewDecryptReader(bytes.NewReader(encrypted), okey)
+			require.NoError(t, err, name)
+			decrypted, err = ioutil.ReadAll(r)
+			require.NoError(t, err, name)
+			require.Equal(t, data, append([]byte{}, decrypted...), "%s %d", name, n)
+		}
+	}
+}
@@ -36,6 +36,7 @@ import (
 	"github.com/TheLinker/unipdf/v3/common"
 
 	"github.com/TheLinker/unipdf/v3/internal/ccittfax"
+	"github.com/TheLinker/unipdf/v3/internal/imageutil"
 )
 
 // Stream encoding filter names.
@@ -321,11 +322,7 @@ func (enc *FlateEncoder) postDecodePredict(outData []byte) ([]byte, error) {
 			// 0-255  -255 255 ; 0-255=-255;
 			for i := 0; i < rows; i++ {
 				rowData := outData[rowLength*i : rowLength*(i+1)]
-				// Predicts the same as the sample to the left.
-				// Interleaved by colors.
-				for j := enc.Colors; j < rowLength; j++ {
-					rowData[j] += rowData[j-enc.Colors]
-				}
+				tiffDecodeRow(rowData, enc.Colors)
 				pOutBuffer.Write(rowData)
 			}
 			pOutData := pOutBuffer.Bytes()
@@ -335,7 +332,7 @@ func (enc *FlateEncoder) postDecodePredict(outData []byte) ([]byte, error) {
 			common.Log.Trace("PNG Encoding")
 			// Columns represents the number of samples per row; Each sample can contain multiple color
 			// components.
-			rowLength := int(enc.Columns*enc.Colors + 1) // 1 byte to specify predictor algorithms per row.
+			rowLength := enc.pngRowLength() + 1 // 1 byte to specify predictor algorithms per row.
 			rows := len(outData) / rowLength
 			if len(outData)%rowLength != 0 {
 				return nil, fmt.Errorf("invalid row length (%d/%d)", len(outData), rowLength)
@@ -354,46 +351,13 @@ func (enc *FlateEncoder) postDecodePredict(outData []byte) ([]byte, error) {
 				prevRowData[i] = 0
 			}
 
-			bytesPerPixel := enc.Colors // Assuming BPC = 8.
+			bytesPerPixel := enc.pngBytesPerPixel()
 
 			for i := 0; i < rows; i++ {
 				rowData := outData[rowLength*i : rowLength*(i+1)]
-
-				fb := rowData[0]
-				switch fb {
-				case pfNone:
-				case pfSub:
-					for j := 1 + bytesPerPixel; j < rowLength; j++ {
-						rowData[j] += rowData[j-bytesPerPixel]
-					}
-				case pfUp:
-					// Up: Predicts the same as the sample above
-					for j := 1; j < rowLength; j++ {
-						rowData[j] += prevRowData[j]
-					}
-				case pfAvg:
-					// Avg: Predicts the same as the average of the sample to the left and above.
-					for j := 1; j < bytesPerPixel+1; j++ {
-						rowData[j] += prevRowData[j] / 2
-					}
-					for j := bytesPerPixel + 1; j < rowLength; j++ {
-						rowData[j] += byte((int(rowData[j-bytesPerPixel]) + int(prevRowData[j])) / 2)
-					}
-				case pfPaeth:
-					// Paeth: a nonlinear function of the sample to the left (a), sample above (b)
-					// and the upper left (c).
-					for j := 1; j < rowLength; j++ {
-						var a, b, c byte
-						b = prevRowData[j] // above.
-						if j >= bytesPerPixel+1 {
-							a = rowData[j-bytesPerPixel]
-							c = prevRowData[j-bytesPerPixel]
-						}
-						rowData[j] += paeth(a, b, c)
-					}
-				default:
-					common.Log.Debug("ERROR: Invalid filter byte (%d) @row %d", fb, i)
-					return nil, fmt.Errorf("invalid filter byte (%d)", fb)
+				if err := pngDecodeRow(rowData, prevRowData, bytesPerPixel); err != nil {
+					common.Log.Debug("ERROR: Invalid filter byte (%d) @row %d", rowData[0], i)
+					return nil, err
 				}
 
 				copy(prevRowData, rowData)
@@ -412,11 +376,11 @@ func (enc *FlateEncoder) postDecodePredict(outData []byte) ([]byte, error) {
 
 // DecodeStream decodes a FlateEncoded stream object and give back decoded bytes.
 func (enc *FlateEncoder) DecodeStream(streamObj *PdfObjectStream) ([]byte, error) {
-	// TODO: Handle more filter bytes and support more values of BitsPerComponent.
+	// TODO: Support more values of BitsPerComponent for the TIFF predictor.
 
 	common.Log.Trace("FlateDecode stream")
 	common.Log.Trace("Predictor: %d", enc.Predictor)
-	if enc.BitsPerComponent != 8 {
+	if enc.Predictor == 2 && enc.BitsPerComponent != 8 {
 		return nil, fmt.Errorf("invalid BitsPerComponent=%d (only 8 supported)", enc.BitsPerComponent)
 	}
 
@@ -430,38 +394,34 @@ func (enc *FlateEncoder) DecodeStream(streamObj *PdfObjectStream) ([]byte, error
 
 // EncodeBytes encodes a bytes array and return the encoded value based on the encoder parameters.
 func (enc *FlateEncoder) EncodeBytes(data []byte) ([]byte, error) {
-	if enc.Predictor != 1 && enc.Predictor != 11 {
-		common.Log.Debug("Encoding error: FlateEncoder Predictor = 1, 11 only supported")
+	if enc.Predictor != 1 && (enc.Predictor < 10 || enc.Predictor > 15) {
+		common.Log.Debug("Encoding error: FlateEncoder Predictor = 1, 10-15 only supported")
 		return nil, ErrUnsupportedEncodingParameters
 	}
 
-	if enc.Predictor == 11 {
-		// The length of each output row in number of samples.
-		// N.B. Each output row has one extra sample as compared to the input to indicate the
+	if enc.Predictor >= 10 {
+		// The length of each output row in bytes.
+		// N.B. Each output row has one extra byte as compared to the input to indicate the
 		// predictor type.
-		rowLength := int(enc.Columns)
-		rows := len(data) / rowLength
-		if len(data)%rowLength != 0 {
+		rowLength := enc.pngRowLength()
+		if rowLength < 1 || len(data)%rowLength != 0 {
 			common.Log.Error("Invalid row length")
 			return nil, errors.New("invalid row length")
 		}
+		rows := len(data) / rowLength
+		bpp := enc.pngBytesPerPixel()
 
 		pOutBuffer := bytes.NewBuffer(nil)
-
+		prevRowData := make([]byte, rowLength)
 		tmpData := make([]byte, rowLength)
-
+		bestData := make([]byte, rowLength)
 		for i := 0; i < rows; i++ {
 			rowData := data[rowLength*i : rowLength*(i+1)]
+			method := enc.pngEncodeRow(rowData, prevRowData, tmpData, bestData, bpp)
 
-			// PNG SUB method.
-			// Sub: Predicts the same as the sample to the left.
-			tmpData[0] = rowData[0]
-			for j := 1; j < rowLength; j++ {
-				tmpData[j] = byte(int(rowData[j]-rowData[j-1]) % 256)
-			}
-
-			pOutBuffer.WriteByte(1) // sub method
-			pOutBuffer.Write(tmpData)
+			pOutBuffer.WriteByte(method)
+			pOutBuffer.Write(bestData)
+			prevRowData = rowData
 		}
 
 		data = pOutBuffer.Bytes()
@@ -475,6 +435,145 @@ func (enc *FlateEncoder) EncodeBytes(data []byte) ([]byte, error) {
 	return b.Bytes(), nil
 }
 
+// pngRowLength returns the number of bytes of a row of samples for the PNG predictors.
+func (enc *FlateEncoder) pngRowLength() int {
+	bpc := enc.BitsPerComponent
+	if bpc <= 0 {
+		bpc = 8
+	}
+	return (enc.Columns*enc.Colors*bpc + 7) / 8
+}
+
+// pngBytesPerPixel returns the distance in bytes to the corresponding byte of the previous sample
+// for the PNG predictors, at least 1.
+func (enc *FlateEncoder) pngBytesPerPixel() int {
+	bpc := enc.BitsPerComponent
+	if bpc <= 0 {
+		bpc = 8
+	}
+	bpp := (enc.Colors*bpc + 7) / 8
+	if bpp < 1 {
+		bpp = 1
+	}
+	return bpp
+}
+
+// pngEncodeRow applies the PNG filter of the predictor of the encoder to `row` preceded by `prev`,
+// writing the result to `out`, and returns the filter applied. `tmp` is a buffer of the length of
+// the row and `bpp` is the number of bytes per pixel.
+func (enc *FlateEncoder) pngEncodeRow(row, prev, tmp, out []byte, bpp int) byte {
+	var method byte
+	switch enc.Predictor {
+	case 10:
+		method = pfNone
+	case 11, 12, 13, 14:
+		method = byte(enc.Predictor - 10)
+	case 15:
+		// PNG optimum: pick the filter with the smallest sum of absolute
+		// differences for each row.
+		bestSum := -1
+		for m := byte(pfNone); m <= pfPaeth; m++ {
+			pngFilterRow(m, row, prev, tmp, bpp)
+			if sum := pngFilterCost(tmp); bestSum < 0 || sum < bestSum {
+				bestSum = sum
+				method = m
+				copy(out, tmp)
+			}
+		}
+		return method
+	}
+	pngFilterRow(method, row, prev, out, bpp)
+	return method
+}
+
+// pngDecodeRow reverses in place the PNG filter of `row`, whose first byte is the filter type,
+// preceded by the decoded row `prev`. `bpp` is the number of bytes per pixel.
+func pngDecodeRow(row, prev []byte, bpp int) error {
+	rowLength := len(row)
+	switch fb := row[0]; fb {
+	case pfNone:
+	case pfSub:
+		for j := 1 + bpp; j < rowLength; j++ {
+			row[j] += row[j-bpp]
+		}
+	case pfUp:
+		// Up: Predicts the same as the sample above
+		for j := 1; j < rowLength; j++ {
+			row[j] += prev[j]
+		}
+	case pfAvg:
+		// Avg: Predicts the same as the average of the sample to the left and above.
+		for j := 1; j < bpp+1 && j < rowLength; j++ {
+			row[j] += prev[j] / 2
+		}
+		for j := bpp + 1; j < rowLength; j++ {
+			row[j] += byte((int(row[j-bpp]) + int(prev[j])) / 2)
+		}
+	case pfPaeth:
+		// Paeth: a nonlinear function of the sample to the left (a), sample above (b)
+		// and the upper left (c).
+		for j := 1; j < rowLength; j++ {
+			var a, b, c byte
+			b = prev[j] // above.
+			if j >= bpp+1 {
+				a = row[j-bpp]
+				c = prev[j-bpp]
+			}
+			row[j] += paeth(a, b, c)
+		}
+	default:
+		return fmt.Errorf("invalid filter byte (%d)", fb)
+	}
+	return nil
+}
+
+// tiffDecodeRow reverses in place the TIFF predictor 2 of `row` of `colors` interleaved color
+// components: each sample predicts the same as the sample to the left.
+func tiffDecodeRow(row []byte, colors int) {
+	for j := colors; j < len(row); j++ {
+		row[j] += row[j-colors]
+	}
+}
+
+// pngFilterRow applies the PNG filter `method` to `row` preceded by `prev` and writes the result
+// to `out`. `bpp` is the number of bytes per pixel.
+func pngFilterRow(method byte, row, prev, out []byte, bpp int) {
+	for j := range row {
+		var a, b, c byte
+		if j >= bpp {
+			a = row[j-bpp]
+			c = prev[j-bpp]
+		}
+		b = prev[j]
+		switch method {
+		case pfNone:
+			out[j] = row[j]
+		case pfSub:
+			out[j] = row[j] - a
+		case pfUp:
+			out[j] = row[j] - b
+		case pfAvg:
+			out[j] = row[j] - byte((int(a)+int(b))/2)
+		case pfPaeth:
+			out[j] = row[j] - paeth(a, b, c)
+		}
+	}
+}
+
+// pngFilterCost estimates how well the filtered row `out` compresses: the sum of the filtered
+// bytes taken as signed differences.
+func pngFilterCost(out []byte) int {
+	sum := 0
+	for _, v := range out {
+		d := int(int8(v))
+		if d < 0 {
+			d = -d
+		}
+		sum += d
+	}
+	return sum
+}
+
 // LZWEncoder provides LZW encoding/decoding functionality.
 type LZWEncoder struct {
 	Predictor        int
@@ -940,19 +1039,35 @@ func newDCTEncoderFromStream(streamObj *PdfObjectStream, multiEnc *MultiEncoder)
 		return encoder, nil
 	}
 
-	// If using DCTDecode in combination with other filters, make sure to decode that first...
-	encoded := streamObj.Stream
-	if multiEnc != nil {
-		e, err := multiEnc.DecodeBytes(encoded)
+	var bufReader io.Reader
+	if streamObj.source != nil {
+		// Only the header of the data of streamed streams is read.
+		rc, err := NewStreamReader(streamObj)
 		if err != nil {
 			return nil, err
 		}
-		encoded = e
+		defer rc.Close()
+		bufReader = rc
+		if multiEnc != nil {
+			bufReader, err = newStreamDecodeReader(multiEnc, streamObj, rc)
+			if err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		// If using DCTDecode in combination with other filters, make sure to decode that first...
+		encoded := streamObj.Stream
+		if multiEnc != nil {
+			e, err := multiEnc.DecodeBytes(encoded)
+			if err != nil {
+				return nil, err
+			}
+			encoded = e
 
+		}
+		bufReader = bytes.NewReader(encoded)
 	}
 
-	bufReader := bytes.NewReader(encoded)
-
 	cfg, err := jpeg.DecodeConfig(bufReader)
 	//img, _, err := goimage.Decode(bufReader)
 	if err != nil {
@@ -1675,6 +1790,10 @@ type CCITTFaxEncoder struct {
 	EndOfBlock             bool
 	BlackIs1               bool
 	DamagedRowsBeforeError int
+
+	// Binarization defines the thresholding method used by EncodeBytes to convert the gray
+	// image data to black and white pixels. If not set, only the white (255) values are white.
+	Binarization *BinarizationParams
 }
 
 // NewCCITTFaxEncoder makes a new CCITTFax encoder.
@@ -1952,6 +2071,16 @@ func (enc *CCITTFaxEncoder) DecodeStream(streamObj *PdfObjectStream) ([]byte, er
 // EncodeBytes encodes the image data using either Group3 or Group4 CCITT facsimile (fax) encoding.
 // `data` is expected to be 1 color component, 1 byte per component.
 func (enc *CCITTFaxEncoder) EncodeBytes(data []byte) ([]byte, error) {
+	if enc.Binarization != nil && enc.Columns > 0 {
+		rows := len(data) / enc.Columns
+		gray := &goimage.Gray{
+			Pix:    data[:rows*enc.Columns],
+			Stride: enc.Columns,
+			Rect:   goimage.Rect(0, 0, enc.Columns, rows),
+		}
+		data = imageutil.Binarize(gray, *enc.Binarization).Pix
+	}
+
 	var pixels [][]byte
 
 	for i := 0; i < len(data); i += enc.Columns {
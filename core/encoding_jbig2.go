@@ -275,7 +275,15 @@ func (enc *JBIG2Encoder) UpdateParams(params *PdfObjectDictionary) {
 func (enc *JBIG2Encoder) encodeImage(i image.Image) ([]byte, error) {
 	const processName = "encodeImage"
 	// convert the input into jbig2 image
-	jbig2Image, err := GoImageToJBIG2(i, JB2ImageAutoThreshold)
+	var (
+		jbig2Image *JBIG2Image
+		err        error
+	)
+	if params := enc.DefaultPageSettings.Binarization; params != nil {
+		jbig2Image, err = GoImageToJBIG2Binarized(i, *params)
+	} else {
+		jbig2Image, err = GoImageToJBIG2(i, JB2ImageAutoThreshold)
+	}
 	if err != nil {
 		return nil, errors.Wrap(err, processName, "convert input image to jbig2 img")
 	}
@@ -418,6 +426,17 @@ func GoImageToJBIG2(i image.Image, bwThreshold float64) (*JBIG2Image, error) {
 	return bwToJBIG2Image(gray), nil
 }
 
+// GoImageToJBIG2Binarized creates a binary image on the base of 'i' golang image.Image, converting
+// the gray and color images with the 'params' thresholding method. The local thresholding methods
+// should be used for the unevenly lit images, like the phone captured documents.
+func GoImageToJBIG2Binarized(i image.Image, params BinarizationParams) (*JBIG2Image, error) {
+	const processName = "GoImageToJBIG2Binarized"
+	if i == nil {
+		return nil, errors.Error(processName, "image 'i' not defined")
+	}
+	return bwToJBIG2Image(imageutil.Binarize(i, params)), nil
+}
+
 func bwToJBIG2Image(i *image.Gray) *JBIG2Image {
 	bounds := i.Bounds()
 	// compute the rowStride - number of bytes in the row.
@@ -468,6 +487,10 @@ type JBIG2EncoderSettings struct {
 	// but the more lossy.
 	// Default value: 0.95
 	Threshold float64
+	// Binarization defines the thresholding method used by the EncodeImage method to convert
+	// the gray and color images to binary images. If not set, a global threshold computed
+	// with the triangle method is used.
+	Binarization *BinarizationParams
 }
 
 // Validate validates the page settings for the JBIG2 encoder.
@@ -50,3 +50,32 @@ func TestImageToJBIG2Image(t *testing.T) {
 		assert.Equal(t, jb2.Data, bm.Data)
 	})
 }
+
+// TestImageToJBIG2ImageBinarized tests the conversion of the unevenly lit gray images to JBIG2Image.
+func TestImageToJBIG2ImageBinarized(t *testing.T) {
+	// The background darkens from the right to the left and the dark squares are darker than
+	// the background around them.
+	g := image.NewGray(image.Rect(0, 0, 200, 40))
+	bounds := g.Bounds()
+	for x := 0; x < bounds.Dx(); x++ {
+		bg := uint8(40 + x)
+		for y := 0; y < bounds.Dy(); y++ {
+			v := bg
+			if x%40 >= 10 && x%40 < 20 && y >= 15 && y < 25 {
+				v = bg / 3
+			}
+			g.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+
+	jb2, err := GoImageToJBIG2Binarized(g, BinarizationParams{Method: BinarizeSauvola, WindowSize: 21})
+	require.NoError(t, err)
+	bm, err := jb2.toBitmap()
+	require.NoError(t, err)
+
+	// Only the squares are black.
+	assert.Equal(t, 5*10*10, bm.CountPixels())
+	assert.True(t, bm.GetPixel(15, 20))
+	assert.True(t, bm.GetPixel(175, 20))
+	assert.False(t, bm.GetPixel(5, 5))
+}
@@ -0,0 +1,610 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+
+	lzw0 "compress/lzw"
+
+	lzw1 "golang.org/x/image/tiff/lzw"
+
+	"github.com/TheLinker/unipdf/v3/common"
+)
+
+// streamDecoder is implemented by the encoders which can decode their data while it is read.
+type streamDecoder interface {
+	// newDecodeReader returns a reader of the data of `r` decoded like DecodeBytes.
+	newDecodeReader(r io.Reader) (io.Reader, error)
+}
+
+// streamEncoder is implemented by the encoders which can encode their data while it is written.
+type streamEncoder interface {
+	// newEncodeWriter returns a writer encoding the data written like EncodeBytes to `w`. The
+	// encoded data is flushed when the writer is closed, `w` not being closed.
+	newEncodeWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+// newStreamDecodeReader returns a reader of the data of `r`, the encoded data of `streamObj`,
+// decoded with `encoder` like DecodeStream.
+func newStreamDecodeReader(encoder StreamEncoder, streamObj *PdfObjectStream, r io.Reader) (io.Reader, error) {
+	switch enc := encoder.(type) {
+	case *FlateEncoder:
+		if enc.Predictor == 2 && enc.BitsPerComponent != 8 {
+			return nil, fmt.Errorf("invalid BitsPerComponent=%d (only 8 supported)", enc.BitsPerComponent)
+		}
+		fr, err := enc.newDecodeReader(r)
+		if err != nil || enc.Predictor <= 1 {
+			return fr, err
+		}
+		return enc.newPredictorReader(fr)
+	case *LZWEncoder:
+		if enc.Predictor <= 1 {
+			return enc.newDecodeReader(r)
+		}
+	case streamDecoder:
+		return enc.newDecodeReader(r)
+	}
+
+	// The other filters and the predictors of LZW are applied to the whole data.
+	return newBufferedReader(r, func(data []byte) ([]byte, error) {
+		s := &PdfObjectStream{
+			PdfObjectReference:  streamObj.PdfObjectReference,
+			PdfObjectDictionary: streamObj.PdfObjectDictionary,
+			Stream:              data,
+		}
+		return encoder.DecodeStream(s)
+	}), nil
+}
+
+// newStreamEncodeWriter returns a writer encoding the data written with `encoder` like
+// EncodeBytes to `w`.
+func newStreamEncodeWriter(encoder StreamEncoder, w io.Writer) (io.WriteCloser, error) {
+	if enc, ok := encoder.(streamEncoder); ok {
+		return enc.newEncodeWriter(w)
+	}
+	return &bufferedWriter{w: w, encode: encoder.EncodeBytes}, nil
+}
+
+// bufferedWriter encodes the data written as a whole when it is closed.
+type bufferedWriter struct {
+	w      io.Writer
+	encode func(data []byte) ([]byte, error)
+	buf    bytes.Buffer
+}
+
+// Write implements io.Writer interface.
+func (w *bufferedWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+// Close implements io.Closer interface.
+func (w *bufferedWriter) Close() error {
+	encoded, err := w.encode(w.buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.w.Write(encoded)
+	return err
+}
+
+// nopWriteCloser is a writer whose Close method does nothing.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close implements io.Closer interface.
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// fillReader is a reader of the data produced by chunks by its fill function, which returns
+// io.EOF at the end of the data.
+type fillReader struct {
+	fill func() ([]byte, error)
+	out  []byte
+	err  error
+}
+
+// Read implements io.Reader interface.
+func (r *fillReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 && r.err == nil {
+		r.out, r.err = r.fill()
+	}
+	if len(r.out) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// newDecodeReader implements streamDecoder interface.
+func (enc *RawEncoder) newDecodeReader(r io.Reader) (io.Reader, error) {
+	return r, nil
+}
+
+// newEncodeWriter implements streamEncoder interface.
+func (enc *RawEncoder) newEncodeWriter(w io.Writer) (io.WriteCloser, error) {
+	return nopWriteCloser{w}, nil
+}
+
+// newDecodeReader implements streamDecoder interface.
+func (enc *FlateEncoder) newDecodeReader(r io.Reader) (io.Reader, error) {
+	zr, err := zlib.NewReader(r)
+	if err == io.EOF {
+		common.Log.Debug("ERROR: empty Flate encoded buffer. Returning empty byte slice.")
+		return bytes.NewReader(nil), nil
+	}
+	if err != nil {
+		common.Log.Debug("Decoding error %v\n", err)
+		return nil, err
+	}
+	return &flateReader{r: zr}, nil
+}
+
+// flateReader reads Flate encoded data, ending the data at the first corrupted input like
+// FlateEncoder.DecodeBytes.
+type flateReader struct {
+	r io.Reader
+}
+
+// Read implements io.Reader interface.
+func (r *flateReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	switch err.(type) {
+	case flate.CorruptInputError:
+		common.Log.Debug("ERROR: Flate decoding: %v", err)
+		err = io.EOF
+	default:
+		if err == io.ErrUnexpectedEOF || err == zlib.ErrChecksum {
+			common.Log.Debug("ERROR: Flate decoding: %v", err)
+			err = io.EOF
+		}
+	}
+	return n, err
+}
+
+// newPredictorReader returns a reader of the data of `r` whose predictor is reversed row by row
+// like postDecodePredict.
+func (enc *FlateEncoder) newPredictorReader(r io.Reader) (io.Reader, error) {
+	var rowLength, skip int
+	var decodeRow func(row, prev []byte) error
+	switch {
+	case enc.Predictor == 2:
+		rowLength = enc.Columns * enc.Colors
+		if rowLength < 1 {
+			// No data. Return empty set.
+			return bytes.NewReader(nil), nil
+		}
+		decodeRow = func(row, prev []byte) error {
+			tiffDecodeRow(row, enc.Colors)
+			return nil
+		}
+	case enc.Predictor >= 10 && enc.Predictor <= 15:
+		// 1 byte to specify predictor algorithms per row.
+		rowLength = enc.pngRowLength() + 1
+		skip = 1
+		bpp := enc.pngBytesPerPixel()
+		decodeRow = func(row, prev []byte) error {
+			return pngDecodeRow(row, prev, bpp)
+		}
+	default:
+		common.Log.Debug("ERROR: Unsupported predictor (%d)", enc.Predictor)
+		return nil, fmt.Errorf("unsupported predictor (%d)", enc.Predictor)
+	}
+
+	row := make([]byte, rowLength)
+	prev := make([]byte, rowLength)
+	rows := 0
+	return &fillReader{fill: func() ([]byte, error) {
+		n, err := io.ReadFull(r, row)
+		switch {
+		case err == io.EOF && rows > 0:
+			return nil, io.EOF
+		case err == io.EOF:
+			common.Log.Debug("Row length cannot be longer than data length (%d/%d)", rowLength, 0)
+			return nil, errors.New("range check error")
+		case err == io.ErrUnexpectedEOF:
+			return nil, fmt.Errorf("invalid row length (%d/%d)", n, rowLength)
+		case err != nil:
+			return nil, err
+		}
+		if err := decodeRow(row, prev); err != nil {
+			common.Log.Debug("ERROR: Invalid filter byte (%d) @row %d", row[0], rows)
+			return nil, err
+		}
+		rows++
+		copy(prev, row)
+		return row[skip:], nil
+	}}, nil
+}
+
+// newEncodeWriter implements streamEncoder interface.
+func (enc *FlateEncoder) newEncodeWriter(w io.Writer) (io.WriteCloser, error) {
+	if enc.Predictor != 1 && (enc.Predictor < 10 || enc.Predictor > 15) {
+		common.Log.Debug("Encoding error: FlateEncoder Predictor = 1, 10-15 only supported")
+		return nil, ErrUnsupportedEncodingParameters
+	}
+	zw := zlib.NewWriter(w)
+	if enc.Predictor == 1 {
+		return zw, nil
+	}
+
+	rowLength := enc.pngRowLength()
+	if rowLength < 1 {
+		common.Log.Error("Invalid row length")
+		return nil, errors.New("invalid row length")
+	}
+	return &pngEncodeWriter{
+		enc:  enc,
+		w:    zw,
+		bpp:  enc.pngBytesPerPixel(),
+		row:  make([]byte, rowLength),
+		prev: make([]byte, rowLength),
+		tmp:  make([]byte, rowLength),
+		out:  make([]byte, rowLength+1),
+	}, nil
+}
+
+// pngEncodeWriter applies the PNG predictor of its encoder to the rows of data written, like
+// FlateEncoder.EncodeBytes.
+type pngEncodeWriter struct {
+	enc *FlateEncoder
+	w   io.WriteCloser
+	bpp int
+
+	row, prev, tmp, out []byte
+	n                   int // Number of bytes of the current row written.
+}
+
+// Write implements io.Writer interface.
+func (w *pngEncodeWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := copy(w.row[w.n:], p)
+		w.n += n
+		p = p[n:]
+		written += n
+		if w.n < len(w.row) {
+			break
+		}
+
+		w.out[0] = w.enc.pngEncodeRow(w.row, w.prev, w.tmp, w.out[1:], w.bpp)
+		if _, err := w.w.Write(w.out); err != nil {
+			return written, err
+		}
+		w.row, w.prev = w.prev, w.row
+		w.n = 0
+	}
+	return written, nil
+}
+
+// Close implements io.Closer interface.
+func (w *pngEncodeWriter) Close() error {
+	if w.n != 0 {
+		common.Log.Error("Invalid row length")
+		return errors.New("invalid row length")
+	}
+	return w.w.Close()
+}
+
+// newDecodeReader implements streamDecoder interface.
+func (enc *LZWEncoder) newDecodeReader(r io.Reader) (io.Reader, error) {
+	if enc.EarlyChange == 1 {
+		// LZW implementation with code length increases one code early (1).
+		return lzw1.NewReader(r, lzw1.MSB, 8), nil
+	}
+	// 0: LZW implementation with postponed code length increases (0).
+	return lzw0.NewReader(r, lzw0.MSB, 8), nil
+}
+
+// newEncodeWriter implements streamEncoder interface.
+func (enc *LZWEncoder) newEncodeWriter(w io.Writer) (io.WriteCloser, error) {
+	if enc.Predictor != 1 {
+		return nil, fmt.Errorf("LZW Predictor = 1 only supported yet")
+	}
+	if enc.EarlyChange == 1 {
+		return nil, fmt.Errorf("LZW Early Change = 0 only supported yet")
+	}
+	return lzw0.NewWriter(w, lzw0.MSB, 8), nil
+}
+
+// newDecodeReader implements streamDecoder interface.
+func (enc *RunLengthEncoder) newDecodeReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	buf := make([]byte, 128)
+	return &fillReader{fill: func() ([]byte, error) {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			// The data ends without the EOD marker.
+			return nil, io.ErrUnexpectedEOF
+		} else if err != nil {
+			return nil, err
+		}
+		switch {
+		case b > 128:
+			v, err := br.ReadByte()
+			if err == io.EOF {
+				return nil, io.ErrUnexpectedEOF
+			} else if err != nil {
+				return nil, err
+			}
+			run := buf[:257-int(b)]
+			for i := range run {
+				run[i] = v
+			}
+			return run, nil
+		case b < 128:
+			literal := buf[:int(b)+1]
+			if _, err := io.ReadFull(br, literal); err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return nil, err
+			}
+			return literal, nil
+		}
+		// EOD.
+		return nil, io.EOF
+	}}, nil
+}
+
+// newDecodeReader implements streamDecoder interface.
+func (enc *ASCIIHexEncoder) newDecodeReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	out := make([]byte, 0, 512)
+	return &fillReader{fill: func() ([]byte, error) {
+		out = out[:0]
+		var high byte
+		odd := false
+		for len(out) < cap(out) {
+			b, err := br.ReadByte()
+			switch {
+			case err == io.EOF:
+				// The data ends without the EOD marker.
+				return nil, io.ErrUnexpectedEOF
+			case err != nil:
+				return nil, err
+			case b == '>':
+				if odd {
+					out = append(out, high<<4)
+				}
+				return out, io.EOF
+			case IsWhiteSpace(b):
+			case isHexDigit(b):
+				if odd {
+					out = append(out, high<<4|hexValue(b))
+				} else {
+					high = hexValue(b)
+				}
+				odd = !odd
+			default:
+				common.Log.Debug("ERROR: Invalid ascii hex character (%c)", b)
+				return nil, fmt.Errorf("invalid ascii hex character (%c)", b)
+			}
+		}
+		return out, nil
+	}}, nil
+}
+
+// newEncodeWriter implements streamEncoder interface.
+func (enc *ASCIIHexEncoder) newEncodeWriter(w io.Writer) (io.WriteCloser, error) {
+	return &asciiHexWriter{w: w}, nil
+}
+
+// asciiHexWriter encodes the data written like ASCIIHexEncoder.EncodeBytes.
+type asciiHexWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+// Write implements io.Writer interface.
+func (w *asciiHexWriter) Write(p []byte) (int, error) {
+	const digits = "0123456789ABCDEF"
+	w.buf = w.buf[:0]
+	for _, b := range p {
+		w.buf = append(w.buf, digits[b>>4], digits[b&0x0f], ' ')
+	}
+	if _, err := w.w.Write(w.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close implements io.Closer interface.
+func (w *asciiHexWriter) Close() error {
+	_, err := w.w.Write([]byte{'>'})
+	return err
+}
+
+// newDecodeReader implements streamDecoder interface.
+func (enc *ASCII85Encoder) newDecodeReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	var out [4]byte
+	var codes [5]byte
+	eod := false
+
+	// decode returns the first `toWrite` bytes of the group of `codes`, the unused codes being
+	// padded with 'u'.
+	decode := func(toWrite int) []byte {
+		for m := toWrite + 1; m < 5; m++ {
+			codes[m] = 84
+		}
+		value := uint32(codes[0])*85*85*85*85 + uint32(codes[1])*85*85*85 +
+			uint32(codes[2])*85*85 + uint32(codes[3])*85 + uint32(codes[4])
+		out[0] = byte(value >> 24)
+		out[1] = byte(value >> 16)
+		out[2] = byte(value >> 8)
+		out[3] = byte(value)
+		codes = [5]byte{}
+		return out[:toWrite]
+	}
+
+	return &fillReader{fill: func() ([]byte, error) {
+		if eod {
+			return nil, io.EOF
+		}
+		count := 0
+		for {
+			code, err := br.ReadByte()
+			switch {
+			case err == io.EOF:
+				eod = true
+				if count == 0 {
+					return nil, io.EOF
+				}
+				// The last group is not terminated.
+				return decode(4), nil
+			case err != nil:
+				return nil, err
+			case IsWhiteSpace(code):
+				continue
+			case code == '~':
+				if next, err := br.Peek(1); err == nil && next[0] == '>' {
+					// EOD marker. Marks end of data.
+					eod = true
+					toWrite := count - 1
+					if toWrite < 0 {
+						toWrite = 0
+					}
+					return decode(toWrite), nil
+				}
+				common.Log.Error("Failed decoding, invalid code")
+				return nil, errors.New("invalid code encountered")
+			case code >= '!' && code <= 'u':
+				codes[count] = code - '!'
+				count++
+				if count == 5 {
+					return decode(4), nil
+				}
+			case code == 'z' && count == 0:
+				// 'z' means that all 5 codes are 0.
+				return decode(4), nil
+			default:
+				common.Log.Error("Failed decoding, invalid code")
+				return nil, errors.New("invalid code encountered")
+			}
+		}
+	}}, nil
+}
+
+// newEncodeWriter implements streamEncoder interface.
+func (enc *ASCII85Encoder) newEncodeWriter(w io.Writer) (io.WriteCloser, error) {
+	return &ascii85Writer{enc: enc, w: w}, nil
+}
+
+// ascii85Writer encodes the data written like ASCII85Encoder.EncodeBytes.
+type ascii85Writer struct {
+	enc   *ASCII85Encoder
+	w     io.Writer
+	group [4]byte
+	n     int // Number of bytes of the current group written.
+	buf   []byte
+}
+
+// Write implements io.Writer interface.
+func (w *ascii85Writer) Write(p []byte) (int, error) {
+	w.buf = w.buf[:0]
+	for _, b := range p {
+		w.group[w.n] = b
+		w.n++
+		if w.n == 4 {
+			w.encodeGroup()
+		}
+	}
+	if _, err := w.w.Write(w.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// encodeGroup encodes the current group of bytes to the buffer.
+func (w *ascii85Writer) encodeGroup() {
+	for i := w.n; i < 4; i++ {
+		w.group[i] = 0
+	}
+	base256 := uint32(w.group[0])<<24 | uint32(w.group[1])<<16 | uint32(w.group[2])<<8 | uint32(w.group[3])
+	if base256 == 0 && w.n == 4 {
+		w.buf = append(w.buf, 'z')
+	} else {
+		base85vals := w.enc.base256Tobase85(base256)
+		for _, val := range base85vals[:w.n+1] {
+			w.buf = append(w.buf, val+'!')
+		}
+	}
+	w.n = 0
+}
+
+// Close implements io.Closer interface.
+func (w *ascii85Writer) Close() error {
+	w.buf = w.buf[:0]
+	if w.n > 0 {
+		w.encodeGroup()
+	}
+	// EOD.
+	w.buf = append(w.buf, '~', '>')
+	_, err := w.w.Write(w.buf)
+	return err
+}
+
+// newDecodeReader implements streamDecoder interface.
+func (enc *MultiEncoder) newDecodeReader(r io.Reader) (io.Reader, error) {
+	// Apply in forward order.
+	for _, encoder := range enc.encoders {
+		if dec, ok := encoder.(streamDecoder); ok {
+			var err error
+			if r, err = dec.newDecodeReader(r); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		r = newBufferedReader(r, encoder.DecodeBytes)
+	}
+	return r, nil
+}
+
+// newEncodeWriter implements streamEncoder interface.
+func (enc *MultiEncoder) newEncodeWriter(w io.Writer) (io.WriteCloser, error) {
+	// The data written is encoded by the last encoder first.
+	mw := &multiEncodeWriter{w: w}
+	for _, encoder := range enc.encoders {
+		ew, err := newStreamEncodeWriter(encoder, mw.w)
+		if err != nil {
+			return nil, err
+		}
+		mw.w = ew
+		mw.writers = append(mw.writers, ew)
+	}
+	return mw, nil
+}
+
+// multiEncodeWriter encodes the data written with a chain of encode writers.
+type multiEncodeWriter struct {
+	w       io.Writer
+	writers []io.WriteCloser
+}
+
+// Write implements io.Writer interface.
+func (w *multiEncodeWriter) Write(p []byte) (int, error) {
+	return w.w.Write(p)
+}
+
+// Close implements io.Closer interface.
+func (w *multiEncodeWriter) Close() error {
+	for i := len(w.writers) - 1; i >= 0; i-- {
+		if err := w.writers[i].Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
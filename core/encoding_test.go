@@ -43,6 +43,58 @@ func TestFlateEncodingPredictor1(t *testing.T) {
 	}
 }
 
+// Test PNG predictors encoding with different sample layouts.
+func TestFlateEncodingPNGPredictors(t *testing.T) {
+	testcases := []struct {
+		BitsPerComponent int
+		Colors           int
+		Columns          int
+	}{
+		{8, 1, 12},
+		{8, 3, 4},
+		{16, 1, 6},
+		{4, 1, 24},
+		{2, 1, 48},
+		{1, 1, 96},
+	}
+
+	// 12 bytes per row.
+	rawStream := []byte{}
+	for i := 0; i < 12*5; i++ {
+		rawStream = append(rawStream, byte(i*i+i/12))
+	}
+
+	for _, tcase := range testcases {
+		for predictor := 10; predictor <= 15; predictor++ {
+			encoder := NewFlateEncoder()
+			encoder.Predictor = predictor
+			encoder.BitsPerComponent = tcase.BitsPerComponent
+			encoder.Colors = tcase.Colors
+			encoder.Columns = tcase.Columns
+
+			encoded, err := encoder.EncodeBytes(rawStream)
+			if err != nil {
+				t.Fatalf("Failed to encode data: %v", err)
+			}
+
+			decoded, err := encoder.DecodeBytes(encoded)
+			if err != nil {
+				t.Fatalf("Failed to decode data: %v", err)
+			}
+			decoded, err = encoder.postDecodePredict(decoded)
+			if err != nil {
+				t.Fatalf("Failed to predict data: %v", err)
+			}
+
+			if !compareSlices(decoded, rawStream) {
+				t.Errorf("Slices not matching (%+v, predictor %d)", tcase, predictor)
+				t.Errorf("Decoded (%d): % x", len(decoded), decoded)
+				t.Fatalf("Raw     (%d): % x", len(rawStream), rawStream)
+			}
+		}
+	}
+}
+
 // Test post decoding predictors.
 func TestPostDecodingPredictors(t *testing.T) {
 
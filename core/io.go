@@ -55,6 +55,20 @@ func (r *offsetReader) Seek(offset int64, whence int) (int64, error) {
 	return n, nil
 }
 
+// errNoReaderAt is returned by offsetReader.ReadAt if the underlying reader does not implement
+// io.ReaderAt.
+var errNoReaderAt = errors.New("core.offsetReader.ReadAt: reader does not support ReadAt")
+
+// ReadAt reads len(p) bytes at offset `off` after the offset of the reader. An error is returned
+// if the underlying reader does not implement io.ReaderAt.
+func (r *offsetReader) ReadAt(p []byte, off int64) (int, error) {
+	ra, ok := r.reader.(io.ReaderAt)
+	if !ok {
+		return 0, errNoReaderAt
+	}
+	return ra.ReadAt(p, off+r.offset)
+}
+
 // ReadAtLeast reads at least n bytes into slice p.
 // Returns the number of bytes read (should always be == n), and an error on failure.
 func (parser *PdfParser) ReadAtLeast(p []byte, n int) (int, error) {
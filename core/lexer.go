@@ -0,0 +1,674 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/TheLinker/unipdf/v3/common"
+)
+
+// errLexer is returned by the lexer when the data cannot be tokenized. The parser then falls back
+// to its buffered reader, which handles the malformed files.
+var errLexer = errors.New("lexer: unexpected data")
+
+// lexer parses the PDF objects held in a byte slice. The tokens are slices of the data, which are
+// not copied unless the objects need their own copy (the names and the strings).
+type lexer struct {
+	data []byte
+	pos  int
+
+	// parser is the parser of the document of the data, set in the parsed references and
+	// dictionaries.
+	parser *PdfParser
+	pool   *objectPool
+}
+
+// newLexer returns a lexer of `data` from the document of `parser`.
+func newLexer(parser *PdfParser, data []byte) *lexer {
+	if parser.pool == nil {
+		parser.pool = newObjectPool()
+	}
+	return &lexer{data: data, parser: parser, pool: parser.pool}
+}
+
+// eof returns true if all the data has been read.
+func (l *lexer) eof() bool {
+	return l.pos >= len(l.data)
+}
+
+// skipSpaces skips the white spaces and the comments.
+func (l *lexer) skipSpaces() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if IsWhiteSpace(c) {
+			l.pos++
+			continue
+		}
+		if c != '%' {
+			return
+		}
+		for l.pos < len(l.data) && l.data[l.pos] != '\r' && l.data[l.pos] != '\n' {
+			l.pos++
+		}
+	}
+}
+
+// hasPrefix returns true if the remaining data starts with `prefix`.
+func (l *lexer) hasPrefix(prefix string) bool {
+	if len(l.data)-l.pos < len(prefix) {
+		return false
+	}
+	return string(l.data[l.pos:l.pos+len(prefix)]) == prefix
+}
+
+// parseObject parses the direct object at the current position.
+func (l *lexer) parseObject() (PdfObject, error) {
+	l.skipSpaces()
+	if l.eof() {
+		return nil, errLexer
+	}
+	switch c := l.data[l.pos]; {
+	case c == '/':
+		return l.parseName()
+	case c == '(':
+		return l.parseString(), nil
+	case c == '[':
+		return l.parseArray()
+	case c == '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			return l.parseDict()
+		}
+		return l.parseHexString()
+	case l.hasPrefix("null"):
+		l.pos += 4
+		return &PdfObjectNull{}, nil
+	case l.hasPrefix("true"):
+		l.pos += 4
+		return MakeBool(true), nil
+	case l.hasPrefix("false"):
+		l.pos += 5
+		return MakeBool(false), nil
+	}
+	return l.parseNumberOrReference()
+}
+
+// parseName parses the name starting with '/' at the current position.
+func (l *lexer) parseName() (*PdfObjectName, error) {
+	if l.eof() || l.data[l.pos] != '/' {
+		return nil, errLexer
+	}
+	l.pos++
+	start := l.pos
+	escaped := false
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if IsWhiteSpace(c) || c == '/' || c == '[' || c == '(' || c == ']' || c == '<' || c == '>' {
+			break
+		}
+		if c == '#' {
+			escaped = true
+		}
+		l.pos++
+	}
+	token := l.data[start:l.pos]
+	if !escaped {
+		return l.pool.name(token), nil
+	}
+
+	// Decode the '#' hexadecimal codes, the invalid ones being kept as literal '#'.
+	decoded := make([]byte, 0, len(token))
+	for i := 0; i < len(token); i++ {
+		if token[i] == '#' && i+2 < len(token) && isHexDigit(token[i+1]) && isHexDigit(token[i+2]) {
+			decoded = append(decoded, hexValue(token[i+1])<<4|hexValue(token[i+2]))
+			i += 2
+			continue
+		}
+		decoded = append(decoded, token[i])
+	}
+	return l.pool.name(decoded), nil
+}
+
+// parseString parses the literal string starting with '(' at the current position. The
+// unterminated strings end with the data.
+func (l *lexer) parseString() *PdfObjectString {
+	l.pos++
+	start := l.pos
+	depth := 1
+	// Fast path for the strings without escape sequences.
+	for i := start; i < len(l.data); i++ {
+		c := l.data[i]
+		if c == '\\' {
+			break
+		}
+		if c == '(' {
+			depth++
+		} else if c == ')' {
+			if depth--; depth == 0 {
+				l.pos = i + 1
+				return MakeString(string(l.data[start:i]))
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	depth = 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '\\':
+			if l.pos >= len(l.data) {
+				return MakeString(buf.String())
+			}
+			c = l.data[l.pos]
+			l.pos++
+			if IsOctalDigit(c) {
+				code := int(c - '0')
+				for n := 1; n < 3 && l.pos < len(l.data) && IsOctalDigit(l.data[l.pos]); n++ {
+					code = code*8 + int(l.data[l.pos]-'0')
+					l.pos++
+				}
+				buf.WriteByte(byte(code))
+				continue
+			}
+			switch c {
+			case 'n':
+				buf.WriteByte('\n')
+			case 'r':
+				buf.WriteByte('\r')
+			case 't':
+				buf.WriteByte('\t')
+			case 'b':
+				buf.WriteByte('\b')
+			case 'f':
+				buf.WriteByte('\f')
+			case '(', ')', '\\':
+				buf.WriteByte(c)
+			}
+			continue
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return MakeString(buf.String())
+			}
+		}
+		buf.WriteByte(c)
+	}
+	return MakeString(buf.String())
+}
+
+// parseHexString parses the hexadecimal string starting with '<' at the current position.
+func (l *lexer) parseHexString() (*PdfObjectString, error) {
+	l.pos++
+	end := bytes.IndexByte(l.data[l.pos:], '>')
+	if end < 0 {
+		return nil, errLexer
+	}
+	token := l.data[l.pos : l.pos+end]
+	l.pos += end + 1
+
+	decoded := make([]byte, 0, (len(token)+1)/2)
+	var hi byte
+	odd, invalid := false, false
+	for _, c := range token {
+		if IsWhiteSpace(c) {
+			continue
+		}
+		if !isHexDigit(c) {
+			// The decoding stops at the first invalid digit, after the last complete byte.
+			invalid = true
+			break
+		}
+		if odd {
+			decoded = append(decoded, hi<<4|hexValue(c))
+		} else {
+			hi = hexValue(c)
+		}
+		odd = !odd
+	}
+	if odd && !invalid {
+		// The missing last digit is 0.
+		decoded = append(decoded, hi<<4)
+	}
+	return MakeHexString(string(decoded)), nil
+}
+
+// parseArray parses the array starting with '[' at the current position.
+func (l *lexer) parseArray() (*PdfObjectArray, error) {
+	l.pos++
+	arr := &PdfObjectArray{}
+	for {
+		l.skipSpaces()
+		if l.eof() {
+			return nil, errLexer
+		}
+		if l.data[l.pos] == ']' {
+			l.pos++
+			return arr, nil
+		}
+		obj, err := l.parseObject()
+		if err != nil {
+			return nil, err
+		}
+		arr.vec = append(arr.vec, obj)
+	}
+}
+
+// parseDict parses the dictionary starting with '<<' at the current position.
+func (l *lexer) parseDict() (*PdfObjectDictionary, error) {
+	l.pos += 2
+	dict := MakeDict()
+	dict.parser = l.parser
+	for {
+		l.skipSpaces()
+		if l.eof() {
+			return nil, errLexer
+		}
+		if l.hasPrefix(">>") {
+			l.pos += 2
+			return dict, nil
+		}
+
+		key, err := l.parseName()
+		if err != nil {
+			return nil, err
+		}
+		if len(*key) > 4 && (*key)[len(*key)-4:] == "null" {
+			// Some writers have a bug where the null is appended without space, e.g.
+			// "/Boundsnull".
+			l.skipSpaces()
+			if !l.eof() && l.data[l.pos] == '/' {
+				dict.Set((*key)[:len(*key)-4], MakeNull())
+				continue
+			}
+		}
+
+		val, err := l.parseObject()
+		if err != nil {
+			return nil, err
+		}
+		dict.Set(*key, val)
+	}
+}
+
+// parseNumberOrReference parses the number or the reference at the current position.
+func (l *lexer) parseNumberOrReference() (PdfObject, error) {
+	start := l.pos
+	num, isInt, ok := l.scanNumber()
+	if !ok {
+		common.Log.Debug("ERROR lexer: unknown token at %d", start)
+		return nil, errLexer
+	}
+	if !isInt {
+		return l.pool.float(l.parseFloat(start)), nil
+	}
+
+	// Reference "objNum genNum R".
+	if ref, ok := l.scanReference(start, num); ok {
+		return ref, nil
+	}
+	return l.pool.integer(num), nil
+}
+
+// scanReference returns the reference starting with the integer `num` at the position `start`
+// and moves after it, or returns false if the integer does not start a reference.
+func (l *lexer) scanReference(start int, num int64) (*PdfObjectReference, bool) {
+	end := l.pos
+	if l.data[start] == '+' || l.data[start] == '.' || end == len(l.data) || !IsWhiteSpace(l.data[end]) {
+		return nil, false
+	}
+	l.skipWhiteSpaces()
+	genStart := l.pos
+	gen, isInt, ok := l.scanNumber()
+	if ok && isInt && IsDecimalDigit(l.data[genStart]) && l.pos < len(l.data) && IsWhiteSpace(l.data[l.pos]) {
+		l.skipWhiteSpaces()
+		if !l.eof() && l.data[l.pos] == 'R' {
+			l.pos++
+			if num < 0 {
+				num = -num
+			}
+			return l.pool.reference(l.parser, num, gen), true
+		}
+	}
+	l.pos = end
+	return nil, false
+}
+
+// skipWhiteSpaces skips the white spaces, not the comments.
+func (l *lexer) skipWhiteSpaces() {
+	for l.pos < len(l.data) && IsWhiteSpace(l.data[l.pos]) {
+		l.pos++
+	}
+}
+
+// scanNumber scans the number at the current position with the syntax accepted by ParseNumber.
+// It returns the value of the integers, whether the number is an integer, and false if there is no
+// number at the current position.
+func (l *lexer) scanNumber() (int64, bool, bool) {
+	start := l.pos
+	isInt := true
+	allowSigns := true
+	var val int64
+	negative := false
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case allowSigns && (c == '-' || c == '+'):
+			allowSigns = false
+			if isInt {
+				negative = c == '-'
+			}
+		case IsDecimalDigit(c):
+			allowSigns = false
+			if isInt {
+				val = val*10 + int64(c-'0')
+			}
+		case c == '.':
+			allowSigns = false
+			isInt = false
+		case c == 'e' || c == 'E':
+			isInt = false
+			allowSigns = true
+		default:
+			goto done
+		}
+		l.pos++
+	}
+done:
+	if l.pos == start || !hasDigit(l.data[start:l.pos]) {
+		l.pos = start
+		return 0, false, false
+	}
+	if isInt && l.pos-start > 18 {
+		// Possible overflow, parse like ParseNumber.
+		v, err := strconv.ParseInt(string(l.data[start:l.pos]), 10, 64)
+		if err != nil {
+			common.Log.Debug("Error parsing number %s err=%v. Using 0. Output may be incorrect",
+				l.data[start:l.pos], err)
+		}
+		return v, true, true
+	}
+	if negative {
+		val = -val
+	}
+	return val, isInt, true
+}
+
+// parseFloat returns the value of the floating point number from the position `start` to the
+// current position.
+func (l *lexer) parseFloat(start int) float64 {
+	v, err := strconv.ParseFloat(string(l.data[start:l.pos]), 64)
+	if err != nil {
+		common.Log.Debug("Error parsing number %s err=%v. Using 0.0. Output may be incorrect",
+			l.data[start:l.pos], err)
+		return 0
+	}
+	return v
+}
+
+// hasDigit returns true if `b` contains a decimal digit.
+func hasDigit(b []byte) bool {
+	for _, c := range b {
+		if IsDecimalDigit(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHexDigit returns true if `c` is a hexadecimal digit.
+func isHexDigit(c byte) bool {
+	return IsDecimalDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// hexValue returns the value of the hexadecimal digit `c`.
+func hexValue(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	}
+	return c - '0'
+}
+
+// Number of objects of the blocks allocated by the object pools, sized per object type so that
+// each block holds about 1 KiB.
+const (
+	integerBlockSize   = 128 // 8 bytes per integer.
+	floatBlockSize     = 128 // 8 bytes per float.
+	nameBlockSize      = 64  // 16 bytes per name.
+	referenceBlockSize = 40  // 24 bytes per reference.
+)
+
+// objectPool allocates the parsed numbers, names and references by blocks, and interns the
+// names, which are repeated throughout the documents.
+// The objects of a block share its memory: a block is retained as long as any of its objects is
+// referenced, so that an object kept after the other objects of the document are released retains
+// the about 1 KiB of its block. The pool of a parser holds only the remainder of the blocks being
+// filled.
+type objectPool struct {
+	integers   []PdfObjectInteger
+	floats     []PdfObjectFloat
+	names      []PdfObjectName
+	references []PdfObjectReference
+	interned   map[string]PdfObjectName
+}
+
+// newObjectPool returns an empty object pool.
+func newObjectPool() *objectPool {
+	return &objectPool{interned: make(map[string]PdfObjectName)}
+}
+
+// integer returns a new integer object of value `v`.
+func (p *objectPool) integer(v int64) *PdfObjectInteger {
+	if len(p.integers) == 0 {
+		p.integers = make([]PdfObjectInteger, integerBlockSize)
+	}
+	obj := &p.integers[0]
+	p.integers = p.integers[1:]
+	*obj = PdfObjectInteger(v)
+	return obj
+}
+
+// float returns a new floating point number object of value `v`.
+func (p *objectPool) float(v float64) *PdfObjectFloat {
+	if len(p.floats) == 0 {
+		p.floats = make([]PdfObjectFloat, floatBlockSize)
+	}
+	obj := &p.floats[0]
+	p.floats = p.floats[1:]
+	*obj = PdfObjectFloat(v)
+	return obj
+}
+
+// name returns a new name object of value `token`, the value being interned.
+func (p *objectPool) name(token []byte) *PdfObjectName {
+	name, ok := p.interned[string(token)]
+	if !ok {
+		name = PdfObjectName(token)
+		p.interned[string(name)] = name
+	}
+	if len(p.names) == 0 {
+		p.names = make([]PdfObjectName, nameBlockSize)
+	}
+	obj := &p.names[0]
+	p.names = p.names[1:]
+	*obj = name
+	return obj
+}
+
+// reference returns a new reference object to the object `num` of generation `gen` of the
+// document of `parser`.
+func (p *objectPool) reference(parser *PdfParser, num, gen int64) *PdfObjectReference {
+	if len(p.references) == 0 {
+		p.references = make([]PdfObjectReference, referenceBlockSize)
+	}
+	obj := &p.references[0]
+	p.references = p.references[1:]
+	*obj = PdfObjectReference{parser: parser, ObjectNumber: num, GenerationNumber: gen}
+	return obj
+}
+
+// parseIndirectObjectAt parses the indirect object at `offset` in the file with the lexer. It
+// returns false if the object cannot be parsed with the lexer, e.g. if the file cannot be read at
+// random offsets or if the object is malformed, ParseIndirectObject being used instead.
+func (parser *PdfParser) parseIndirectObjectAt(offset int64) (PdfObject, bool) {
+	ra, ok := parser.rs.(io.ReaderAt)
+	if parser.noLexer || !ok || offset < 0 || offset >= parser.fileSize {
+		return nil, false
+	}
+
+	// The object ends before the next object of the xref table.
+	end := parser.xrefNextObjectOffset(offset + 1)
+	if end <= offset || end > parser.fileSize {
+		end = parser.fileSize
+	}
+	if parser.isStreamedLength(end - offset) {
+		// The object may hold a large stream whose data is not loaded.
+		return nil, false
+	}
+	data := make([]byte, end-offset)
+	if n, err := ra.ReadAt(data, offset); n < len(data) && (err != io.EOF || n == 0) {
+		return nil, false
+	} else {
+		data = data[:n]
+	}
+
+	l := newLexer(parser, data)
+	l.skipSpaces()
+	objNum, isInt, ok := l.scanNumber()
+	if !ok || !isInt || objNum < 0 {
+		return nil, false
+	}
+	l.skipWhiteSpaces()
+	genNum, isInt, ok := l.scanNumber()
+	if !ok || !isInt || genNum < 0 {
+		return nil, false
+	}
+	l.skipWhiteSpaces()
+	if !l.hasPrefix("obj") {
+		return nil, false
+	}
+	l.pos += 3
+
+	obj, err := l.parseObject()
+	if err != nil {
+		return nil, false
+	}
+	l.skipSpaces()
+
+	switch {
+	case l.eof() || l.hasPrefix("endobj"):
+		indirect := &PdfIndirectObject{PdfObject: obj}
+		indirect.parser = parser
+		indirect.ObjectNumber = objNum
+		indirect.GenerationNumber = genNum
+		return indirect, true
+	case !l.hasPrefix("stream"):
+		return nil, false
+	}
+
+	dict, ok := obj.(*PdfObjectDictionary)
+	if !ok {
+		return nil, false
+	}
+	l.pos += 6
+	if l.hasPrefix("\r\n") {
+		l.pos += 2
+	} else if l.hasPrefix("\n") || l.hasPrefix("\r") {
+		l.pos++
+	} else {
+		return nil, false
+	}
+
+	lengthObj, err := parser.traceStreamLength(dict.Get("Length"))
+	if err != nil {
+		return nil, false
+	}
+	length, ok := lengthObj.(*PdfObjectInteger)
+	if !ok || *length < 0 || int64(*length) > int64(len(data)-l.pos) {
+		return nil, false
+	}
+	start := l.pos
+	l.pos += int(*length)
+	l.skipWhiteSpaces()
+	if !l.hasPrefix("endstream") {
+		// The length is corrected by ParseIndirectObject.
+		return nil, false
+	}
+
+	stream := &PdfObjectStream{PdfObjectDictionary: dict}
+	// The stream data is not copied from the data read.
+	stream.Stream = data[start : start+int(*length) : start+int(*length)]
+	stream.ObjectNumber = objNum
+	stream.GenerationNumber = genNum
+	stream.PdfObjectReference.parser = parser
+	return stream, true
+}
+
+// parseObjectStreamOffsets returns the offsets of the `n` objects of the decoded object stream
+// `ds` by object number, the objects starting at offset `first`.
+func (parser *PdfParser) parseObjectStreamOffsets(ds []byte, n, first int64) (map[int]int64, error) {
+	l := newLexer(parser, ds)
+	offsets := make(map[int]int64, n)
+	for i := int64(0); i < n; i++ {
+		l.skipSpaces()
+		onum, isInt, ok := l.scanNumber()
+		if !ok || !isInt {
+			return nil, errors.New("invalid object stream offset table")
+		}
+		l.skipSpaces()
+		offset, isInt, ok := l.scanNumber()
+		if !ok || !isInt {
+			return nil, errors.New("invalid object stream offset table")
+		}
+		common.Log.Trace("obj %d offset %d", onum, offset)
+		offsets[int(onum)] = first + offset
+	}
+	return offsets, nil
+}
+
+// parseXrefEntryLine parses the xref table entry line `txt` in the standard format, i.e. the
+// 10-digit offset, the 5-digit generation number and the 'n' or 'f' keyword, optionally followed
+// by white spaces. It returns false if the line is not in this format.
+func parseXrefEntryLine(txt string) (offset int64, gen int, inUse bool, ok bool) {
+	if len(txt) < 18 || txt[10] != ' ' || txt[16] != ' ' {
+		return 0, 0, false, false
+	}
+	for i := 0; i < 16; i++ {
+		if i == 10 {
+			continue
+		}
+		c := txt[i]
+		if !IsDecimalDigit(c) {
+			return 0, 0, false, false
+		}
+		if i < 10 {
+			offset = offset*10 + int64(c-'0')
+		} else {
+			gen = gen*10 + int(c-'0')
+		}
+	}
+	switch txt[17] {
+	case 'n':
+		inUse = true
+	case 'f':
+	default:
+		return 0, 0, false, false
+	}
+	for i := 18; i < len(txt); i++ {
+		if !IsWhiteSpace(txt[i]) {
+			return 0, 0, false, false
+		}
+	}
+	return offset, gen, inUse, true
+}
@@ -0,0 +1,184 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// lexerTestFiles are the files of the testdata loaded by the lexer tests and benchmarks.
+func lexerTestFiles(t testing.TB) []string {
+	var files []string
+	for _, pattern := range []string{"testdata/*.pdf", "../model/testdata/*.pdf"} {
+		matches, err := filepath.Glob(pattern)
+		require.NoError(t, err)
+		files = append(files, matches...)
+	}
+	require.NotEmpty(t, files)
+	return files
+}
+
+// loadAllObjects loads all the objects of the xref table of `data`, returning their
+// serialization by object number. The objects are parsed with the buffered reader of the parser
+// if `lexer` is false, the objects loaded with the xref tables being parsed again.
+func loadAllObjects(t testing.TB, data []byte, lexer bool) map[int]string {
+	parser, err := NewParser(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	if !lexer {
+		parser.noLexer = true
+		parser.ObjCache = make(objectCache)
+		parser.objstms = make(objectStreams)
+	}
+	var nums []int
+	for num := range parser.xrefs.ObjectMap {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+
+	objects := make(map[int]string, len(nums))
+	for _, num := range nums {
+		obj, err := parser.LookupByNumber(num)
+		if err != nil {
+			continue
+		}
+		s := obj.WriteString()
+		if stream, ok := obj.(*PdfObjectStream); ok {
+			s += string(stream.Stream)
+		}
+		objects[num] = s
+	}
+	return objects
+}
+
+func TestLexerParseObject(t *testing.T) {
+	inputs := []string{
+		"/Name",
+		"/A#20B#2fC",
+		"/",
+		"(simple string)",
+		"(nested (parentheses) and \\(escapes\\) \\n\\r\\t\\b\\f\\\\ \\101\\7)",
+		"(line\\\ncontinuation)",
+		"<48656C6C6F>",
+		"<48 65 6c 6C 6>",
+		"<>",
+		"123",
+		"-17",
+		"+4",
+		"3.14",
+		"-.5",
+		"4.",
+		"12 0 R",
+		"12 0 obj",
+		"[1 2 0 R /Name (str) <00ff> [true false null] 3.5]",
+		"<< /Type /Page /Parent 3 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 5 0 R >> >> >>",
+		"<< /Key null /Other 1 >>",
+		"<< % comment\n/Key /Value >>",
+		"[ 1 % comment\n 2 ]",
+		"true",
+		"false",
+		"null",
+	}
+
+	for _, input := range inputs {
+		// The buffered reader expects data after the objects.
+		parser := makeParserForText(input + " ")
+		expected, err := parser.parseObject()
+		require.NoError(t, err, input)
+
+		l := newLexer(parser, []byte(input))
+		obj, err := l.parseObject()
+		require.NoError(t, err, input)
+		require.Equal(t, expected.WriteString(), obj.WriteString(), input)
+		require.Equal(t, expected.String(), obj.String(), input)
+	}
+}
+
+func TestLexerParseXrefEntryLine(t *testing.T) {
+	offset, gen, inUse, ok := parseXrefEntryLine("0000012345 00002 n ")
+	require.True(t, ok)
+	require.Equal(t, int64(12345), offset)
+	require.Equal(t, 2, gen)
+	require.True(t, inUse)
+
+	_, gen, inUse, ok = parseXrefEntryLine("0000000000 65535 f")
+	require.True(t, ok)
+	require.Equal(t, 65535, gen)
+	require.False(t, inUse)
+
+	for _, txt := range []string{"1 2", "0000012345 00002 x ", "000001234 000002 n", "0000012345 00002 n x"} {
+		_, _, _, ok = parseXrefEntryLine(txt)
+		require.False(t, ok, txt)
+	}
+}
+
+// TestLexerLoadObjects checks that the objects loaded with the lexer are the same as the objects
+// loaded with the buffered reader of the parser.
+func TestLexerLoadObjects(t *testing.T) {
+	for _, file := range lexerTestFiles(t) {
+		data, err := ioutil.ReadFile(file)
+		require.NoError(t, err)
+
+		expected := loadAllObjects(t, data, false)
+		objects := loadAllObjects(t, data, true)
+		require.Equal(t, expected, objects, file)
+	}
+}
+
+func benchmarkLoadObjects(b *testing.B, lexer bool) {
+	var files [][]byte
+	for _, file := range lexerTestFiles(b) {
+		data, err := ioutil.ReadFile(file)
+		require.NoError(b, err)
+		files = append(files, data)
+	}
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, data := range files {
+			loadAllObjects(b, data, lexer)
+		}
+	}
+}
+
+func BenchmarkLoadObjectsLexer(b *testing.B)  { benchmarkLoadObjects(b, true) }
+func BenchmarkLoadObjectsReader(b *testing.B) { benchmarkLoadObjects(b, false) }
+
+const benchmarkObject = "<< /Type /Page /Parent 3 0 R /MediaBox [0 0 612 792] /Contents [4 0 R 5 0 R] " +
+	"/Resources << /Font << /F1 6 0 R /F2 7 0 R >> /ProcSet [/PDF /Text] >> " +
+	"/Annots [<< /Type /Annot /Subtype /Link /Rect [72.5 700.25 200 714] /URI (https://example.com) >>] " +
+	"/Widths [250 333 408 500 500 833 778 180 333 333 500 564 250 333 250 278] >>"
+
+func BenchmarkParseObjectLexer(b *testing.B) {
+	parser := makeParserForText("")
+	data := []byte(benchmarkObject)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l := newLexer(parser, data)
+		if _, err := l.parseObject(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseObjectReader(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		parser := makeParserForText(benchmarkObject)
+		if _, err := parser.parseObject(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/TheLinker/unipdf/v3/common"
+	"github.com/TheLinker/unipdf/v3/internal/jbig2/reader"
+)
+
+// reLinearizationObj matches the header of the first object of the file.
+var reLinearizationObj = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj`)
+
+// Linearization represents the linearization parameters of a linearized document (section 7.2 and
+// annex F), which locate the data of the first page and of the other pages of the document so
+// that they can be read without reading the whole file.
+type Linearization struct {
+	// FileLength is the length of the file (L).
+	FileLength int64
+
+	// FirstPageObject is the object number of the page object of the first page (O).
+	FirstPageObject int64
+
+	// FirstPageEnd is the offset of the end of the first page section (E), which holds the objects
+	// needed to display the first page and the document catalog.
+	FirstPageEnd int64
+
+	// NumPages is the number of pages of the document (N).
+	NumPages int
+
+	// MainXrefOffset is the offset of the main cross-reference table (T).
+	MainXrefOffset int64
+
+	// HintOffset and HintLength are the offset and the length of the primary hint stream (H).
+	HintOffset int64
+	HintLength int64
+
+	// Offsets of the pages, followed by the offset of the end of the last page, read from the
+	// page offset hint table. Nil if the hint table could not be loaded.
+	pageOffsets []int64
+}
+
+// PageRange returns the offset and the length of the data of page `pageNum` (1-based), read from
+// the page offset hint table. The bool flag is false if the range is unknown.
+func (lin *Linearization) PageRange(pageNum int) (int64, int64, bool) {
+	if pageNum < 1 || pageNum >= len(lin.pageOffsets) {
+		return 0, 0, false
+	}
+	offset := lin.pageOffsets[pageNum-1]
+	return offset, lin.pageOffsets[pageNum] - offset, true
+}
+
+// GetLinearization returns the linearization parameters of the document if it is linearized and
+// has not been updated since it was linearized, nil otherwise. The page ranges are loaded from the
+// hint stream once the document is decrypted for encrypted documents. An error is returned if the
+// hint stream is invalid, e.g. if it does not match the number of pages of the document.
+func (parser *PdfParser) GetLinearization() (*Linearization, error) {
+	// The linearization dictionary is the first object of the file, within its first 1024 bytes.
+	size := parser.fileSize
+	if size > 1024 {
+		size = 1024
+	}
+	head, err := parser.ReadBytesAt(0, size)
+	if err != nil {
+		return nil, err
+	}
+	loc := reLinearizationObj.FindIndex(head)
+	if loc == nil {
+		return nil, nil
+	}
+
+	offset := parser.GetFileOffset()
+	defer parser.SetFileOffset(offset)
+	parser.SetFileOffset(int64(loc[0]))
+	obj, err := parser.ParseIndirectObject()
+	if err != nil {
+		common.Log.Debug("ERROR: Failed parsing first object: %v", err)
+		return nil, nil
+	}
+	ind, ok := obj.(*PdfIndirectObject)
+	if !ok {
+		return nil, nil
+	}
+	dict, ok := GetDict(ind.PdfObject)
+	if !ok || dict.Get("Linearized") == nil {
+		return nil, nil
+	}
+
+	lin := &Linearization{}
+	getInt := func(key PdfObjectName) int64 {
+		v, _ := GetIntVal(dict.Get(key))
+		return int64(v)
+	}
+	lin.FileLength = getInt("L")
+	lin.FirstPageObject = getInt("O")
+	lin.FirstPageEnd = getInt("E")
+	lin.NumPages = int(getInt("N"))
+	lin.MainXrefOffset = getInt("T")
+	if hints, ok := GetArray(dict.Get("H")); ok && hints.Len() >= 2 {
+		if vals, err := hints.ToInt64Slice(); err == nil {
+			lin.HintOffset, lin.HintLength = vals[0], vals[1]
+		}
+	}
+	if lin.FileLength != parser.fileSize {
+		// The document was updated: the linearization parameters do not describe it anymore.
+		common.Log.Debug("Linearized length %d != file size %d", lin.FileLength, parser.fileSize)
+		return nil, nil
+	}
+
+	if parser.crypter != nil && !parser.IsAuthenticated() {
+		common.Log.Debug("Hint stream of encrypted document not decrypted")
+		return lin, nil
+	}
+	if err := parser.loadPageOffsetHints(lin); err != nil {
+		common.Log.Debug("ERROR: Failed loading page offset hint table: %v", err)
+		return nil, err
+	}
+	return lin, nil
+}
+
+// pageTreeCount returns the number of pages of the page tree of the document.
+func (parser *PdfParser) pageTreeCount() (int, bool) {
+	if parser.trailer == nil {
+		return 0, false
+	}
+	catalog, ok := GetDict(parser.trailer.Get("Root"))
+	if !ok {
+		return 0, false
+	}
+	pages, ok := GetDict(catalog.Get("Pages"))
+	if !ok {
+		return 0, false
+	}
+	return GetIntVal(pages.Get("Count"))
+}
+
+// loadPageOffsetHints loads the page offsets of `lin` from the page offset hint table (annex F.4),
+// at the beginning of the data of the primary hint stream.
+func (parser *PdfParser) loadPageOffsetHints(lin *Linearization) error {
+	if lin.HintLength <= 0 || lin.NumPages <= 0 {
+		return nil
+	}
+	// The number of pages sizes the hint table: it must be the number of pages of the page tree.
+	if count, ok := parser.pageTreeCount(); !ok || lin.NumPages > count {
+		return fmt.Errorf("invalid number of pages %d (page tree count %d)", lin.NumPages, count)
+	}
+
+	// The hint stream is parsed for its object number, then looked up to be decrypted.
+	parser.SetFileOffset(lin.HintOffset)
+	obj, err := parser.ParseIndirectObject()
+	if err != nil {
+		return err
+	}
+	stream, ok := obj.(*PdfObjectStream)
+	if !ok {
+		return errors.New("hint stream not a stream")
+	}
+	obj, err = parser.LookupByNumber(int(stream.ObjectNumber))
+	if err != nil {
+		return err
+	}
+	if stream, ok = obj.(*PdfObjectStream); !ok {
+		return errors.New("hint stream not a stream")
+	}
+	data, err := DecodeStream(stream)
+	if err != nil {
+		return err
+	}
+
+	// Header of the page offset hint table (table F.3): the items 1 to 5 locate the pages.
+	r := reader.New(data)
+	readBits := func(n byte) int64 {
+		if err != nil || n == 0 {
+			return 0
+		}
+		var v uint64
+		v, err = r.ReadBits(n)
+		return int64(v)
+	}
+	readBits(32) // Least number of objects in a page.
+	firstPageOffset := readBits(32)
+	objectsBits := readBits(16)
+	leastPageLength := readBits(32)
+	lengthBits := readBits(16)
+	if err != nil {
+		return err
+	}
+	if objectsBits > 32 || lengthBits > 32 {
+		return errors.New("invalid page offset hint table")
+	}
+	// The entries of the pages must fit in the data of the hint stream.
+	if int64(lin.NumPages)*(objectsBits+lengthBits) > int64(len(data))*8 {
+		return fmt.Errorf("page offset hint table too short for %d pages", lin.NumPages)
+	}
+	// Items 6 to 13 of the header.
+	for _, n := range []byte{32, 16, 32, 16, 16, 16, 16, 16} {
+		readBits(n)
+	}
+
+	// Per-page entries (table F.4): item 1, the number of objects of each page, then item 2, the
+	// length of each page, each item starting at a byte boundary.
+	for i := 0; i < lin.NumPages; i++ {
+		readBits(byte(objectsBits))
+	}
+	r.Align()
+	offsets := make([]int64, lin.NumPages+1)
+	offsets[0] = firstPageOffset
+	for i := 0; i < lin.NumPages; i++ {
+		offsets[i+1] = offsets[i] + leastPageLength + readBits(byte(lengthBits))
+	}
+	if err != nil {
+		return err
+	}
+
+	// The offsets of the hint tables do not account for the hint stream.
+	for i, offset := range offsets {
+		if offset >= lin.HintOffset {
+			offsets[i] += lin.HintLength
+		}
+	}
+	lin.pageOffsets = offsets
+	return nil
+}
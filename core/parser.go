@@ -48,6 +48,7 @@ type PdfParser struct {
 	trailer          *PdfObjectDictionary
 	crypter          *PdfCrypt
 	repairsAttempted bool // Avoid multiple attempts for repair.
+	repairs          Repairs
 
 	ObjCache objectCache
 
@@ -56,6 +57,16 @@ type PdfParser struct {
 	// the length reference (if not object) prior to reading the actual stream.  This has risks of endless looping.
 	// Tracking is necessary to avoid recursive loops.
 	streamLengthReferenceLookupInProgress map[int64]bool
+
+	// pool allocates the objects parsed by the lexer.
+	pool *objectPool
+	// noLexer disables the lexer, the objects being parsed with the buffered reader, e.g. for
+	// comparing the objects parsed in both ways in the tests.
+	noLexer bool
+
+	// streamedSize is the size above which the data of the streams is read from the file when
+	// it is needed rather than when the streams are parsed. Disabled if 0.
+	streamedSize int64
 }
 
 // Version represents a version of a PDF standard.
@@ -190,17 +201,19 @@ func (parser *PdfParser) readComment() (string, error) {
 func (parser *PdfParser) readTextLine() (string, error) {
 	var r bytes.Buffer
 	for {
-		bb, err := parser.reader.Peek(1)
-		if err != nil {
+		if _, err := parser.reader.Peek(1); err != nil {
 			common.Log.Debug("Error %s", err.Error())
 			return r.String(), err
 		}
-		if (bb[0] != '\r') && (bb[0] != '\n') {
-			b, _ := parser.reader.ReadByte()
-			r.WriteByte(b)
-		} else {
+		// Scan the buffered data rather than reading byte by byte.
+		bb, _ := parser.reader.Peek(parser.reader.Buffered())
+		if i := bytes.IndexAny(bb, "\r\n"); i >= 0 {
+			r.Write(bb[:i])
+			parser.reader.Discard(i)
 			break
 		}
+		r.Write(bb)
+		parser.reader.Discard(len(bb))
 	}
 	return r.String(), nil
 }
@@ -719,6 +732,21 @@ func (parser *PdfParser) parseXrefTable() (*PdfObjectDictionary, error) {
 			return nil, err
 		}
 
+		if offset, gen, inUse, ok := parseXrefEntryLine(txt); ok && insideSubsection {
+			// Fast path for the entries in the standard format.
+			unmatchedContent = ""
+			if inUse && offset > 1 {
+				x, ok := parser.xrefs.ObjectMap[curObjNum]
+				if !ok || gen > x.Generation {
+					parser.xrefs.ObjectMap[curObjNum] = XrefObject{ObjectNumber: curObjNum,
+						XType:  XrefTypeTableEntry,
+						Offset: offset, Generation: gen}
+				}
+			}
+			curObjNum++
+			continue
+		}
+
 		result1 := reXrefSubsection.FindStringSubmatch(txt)
 		if len(result1) == 0 {
 			// Try to match invalid subsection beginning lines from previously
@@ -1516,6 +1544,10 @@ func (parser *PdfParser) ParseIndirectObject() (PdfObject, error) {
 					}
 					common.Log.Trace("Stream dict %s", dict)
 
+					// The streams with a wrong Length are recovered by locating their endstream
+					// keyword.
+					streamStartOffset := parser.GetFileOffset()
+
 					// Special stream length tracing function used to avoid endless recursive looping.
 					slo, err := parser.traceStreamLength(dict.Get("Length"))
 					if err != nil {
@@ -1530,13 +1562,12 @@ func (parser *PdfParser) ParseIndirectObject() (PdfObject, error) {
 					}
 					streamLength := *pstreamLength
 					if streamLength < 0 {
-						return nil, errors.New("stream needs to be longer than 0")
+						return parser.recoverStream(&indirect, streamStartOffset, errors.New("stream needs to be longer than 0"))
 					}
 
 					// Validate the stream length based on the cross references.
 					// Find next object with closest offset to current object and calculate
 					// the expected stream length based on that.
-					streamStartOffset := parser.GetFileOffset()
 					nextObjectOffset := parser.xrefNextObjectOffset(streamStartOffset)
 					if streamStartOffset+int64(streamLength) > nextObjectOffset && nextObjectOffset > streamStartOffset {
 						common.Log.Debug("Expected ending at %d", streamStartOffset+int64(streamLength))
@@ -1544,7 +1575,7 @@ func (parser *PdfParser) ParseIndirectObject() (PdfObject, error) {
 						// endstream + "\n" endobj + "\n" (17)
 						newLength := nextObjectOffset - streamStartOffset - 17
 						if newLength < 0 {
-							return nil, errors.New("invalid stream length, going past boundaries")
+							return parser.recoverStream(&indirect, streamStartOffset, errors.New("invalid stream length, going past boundaries"))
 						}
 
 						common.Log.Debug("Attempting a length correction to %d...", newLength)
@@ -1555,25 +1586,44 @@ func (parser *PdfParser) ParseIndirectObject() (PdfObject, error) {
 					// Make sure is less than actual file size.
 					if int64(streamLength) > parser.fileSize {
 						common.Log.Debug("ERROR: Stream length cannot be larger than file size")
-						return nil, errors.New("invalid stream length, larger than file size")
+						return parser.recoverStream(&indirect, streamStartOffset, errors.New("invalid stream length, larger than file size"))
 					}
 
-					stream := make([]byte, streamLength)
-					_, err = parser.ReadAtLeast(stream, int(streamLength))
-					if err != nil {
-						common.Log.Debug("ERROR stream (%d): %X", len(stream), stream)
-						common.Log.Debug("ERROR: %v", err)
-						return nil, err
+					var stream []byte
+					streamed := parser.isStreamedLength(int64(streamLength))
+					if streamed {
+						// The data of the large streams is read from the file when it is needed.
+						parser.SetFileOffset(streamStartOffset + int64(streamLength))
+					} else {
+						stream = make([]byte, streamLength)
+						_, err = parser.ReadAtLeast(stream, int(streamLength))
+						if err != nil {
+							common.Log.Debug("ERROR stream (%d): %X", len(stream), stream)
+							common.Log.Debug("ERROR: %v", err)
+							return parser.recoverStream(&indirect, streamStartOffset, err)
+						}
+					}
+
+					parser.skipSpaces()
+					if bb, _ := parser.reader.Peek(9); string(bb) != "endstream" {
+						// The data read is not followed by the endstream keyword.
+						dataEndOffset := parser.GetFileOffset()
+						if recovered, err := parser.recoverStream(&indirect, streamStartOffset, nil); err == nil {
+							return recovered, nil
+						}
+						parser.SetFileOffset(dataEndOffset)
 					}
 
 					streamobj := PdfObjectStream{}
 					streamobj.Stream = stream
+					if streamed {
+						streamobj.source = parser.fileStreamSource(streamStartOffset, int64(streamLength))
+					}
 					streamobj.PdfObjectDictionary = indirect.PdfObject.(*PdfObjectDictionary)
 					streamobj.ObjectNumber = indirect.ObjectNumber
 					streamobj.GenerationNumber = indirect.GenerationNumber
 					streamobj.PdfObjectReference.parser = parser
 
-					parser.skipSpaces()
 					parser.reader.Discard(9) // endstream
 					parser.skipSpaces()
 					return &streamobj, nil
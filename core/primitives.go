@@ -81,6 +81,10 @@ type PdfObjectStream struct {
 	PdfObjectReference
 	*PdfObjectDictionary
 	Stream []byte
+
+	// source returns a reader of the data of the streamed streams, whose data is not held in
+	// Stream but read from the file or from a reader when it is needed.
+	source streamSource
 }
 
 // PdfObjectStreams represents the primitive PDF object streams.
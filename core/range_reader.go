@@ -0,0 +1,268 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"container/list"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/TheLinker/unipdf/v3/common"
+)
+
+// Default options of the range readers.
+const (
+	defaultRangeBlockSize = 64 * 1024
+	defaultRangeCacheSize = 256
+	defaultRangeReadAhead = 4
+)
+
+// RangeReaderOptions defines the options of a RangeReader.
+type RangeReaderOptions struct {
+	// BlockSize is the size of the blocks fetched from the underlying reader, 64 KiB by default.
+	BlockSize int
+
+	// CacheSize is the maximum number of blocks held in the cache, 256 by default. The least
+	// recently used blocks are evicted from the cache when it is full.
+	CacheSize int
+
+	// ReadAhead is the number of blocks fetched ahead of the block read when the blocks are read
+	// sequentially, 4 by default. No block is fetched ahead if negative.
+	ReadAhead int
+}
+
+// RangeReader is a reader of the data of an io.ReaderAt, such as a reader of a document in remote
+// storage fetching its data with HTTP range requests, which reads the data by blocks and caches
+// them so that the small reads of the parser do not result in as many reads of the underlying
+// reader. The blocks following the blocks read sequentially are fetched ahead with the same read
+// and ranges known to be needed, e.g. the first page section of linearized documents, can be
+// fetched with a single read with Prefetch.
+// RangeReader implements io.ReadSeeker and io.ReaderAt and is safe for concurrent use with ReadAt.
+type RangeReader struct {
+	r    io.ReaderAt
+	size int64
+	opts RangeReaderOptions
+
+	// Offset of Read and Seek.
+	offset int64
+
+	mu     sync.Mutex
+	blocks map[int64]*list.Element
+	lru    *list.List
+	// Index of the last block read, for detecting the sequential reads.
+	lastBlock int64
+}
+
+// rangeBlock is a cached block of a RangeReader.
+type rangeBlock struct {
+	index int64
+	data  []byte
+}
+
+// NewRangeReader returns a new RangeReader reading the `size` bytes of data of `r` with the
+// options `opts`, the default options being used if nil.
+func NewRangeReader(r io.ReaderAt, size int64, opts *RangeReaderOptions) *RangeReader {
+	rr := &RangeReader{
+		r:    r,
+		size: size,
+		opts: RangeReaderOptions{
+			BlockSize: defaultRangeBlockSize,
+			CacheSize: defaultRangeCacheSize,
+			ReadAhead: defaultRangeReadAhead,
+		},
+		blocks:    map[int64]*list.Element{},
+		lru:       list.New(),
+		lastBlock: -2,
+	}
+	if opts != nil {
+		if opts.BlockSize > 0 {
+			rr.opts.BlockSize = opts.BlockSize
+		}
+		if opts.CacheSize > 0 {
+			rr.opts.CacheSize = opts.CacheSize
+		}
+		if opts.ReadAhead != 0 {
+			rr.opts.ReadAhead = opts.ReadAhead
+		}
+	}
+	if rr.opts.ReadAhead < 0 {
+		rr.opts.ReadAhead = 0
+	}
+	// The blocks read ahead must not evict the block read.
+	if rr.opts.ReadAhead >= rr.opts.CacheSize {
+		rr.opts.ReadAhead = rr.opts.CacheSize - 1
+	}
+	return rr
+}
+
+// Size returns the size of the data of `rr`.
+func (rr *RangeReader) Size() int64 {
+	return rr.size
+}
+
+// Read implements io.Reader interface.
+func (rr *RangeReader) Read(p []byte) (int, error) {
+	n, err := rr.ReadAt(p, rr.offset)
+	rr.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Seek implements io.Seeker interface.
+func (rr *RangeReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += rr.offset
+	case io.SeekEnd:
+		offset += rr.size
+	default:
+		return 0, errors.New("core.RangeReader.Seek: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("core.RangeReader.Seek: negative position")
+	}
+	rr.offset = offset
+	return offset, nil
+}
+
+// ReadAt implements io.ReaderAt interface.
+func (rr *RangeReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("core.RangeReader.ReadAt: negative offset")
+	}
+	if off >= rr.size {
+		return 0, io.EOF
+	}
+
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	n := 0
+	for n < len(p) && off < rr.size {
+		index := off / int64(rr.opts.BlockSize)
+		data, err := rr.block(index)
+		if err != nil {
+			return n, err
+		}
+		start := int(off - index*int64(rr.opts.BlockSize))
+		if start >= len(data) {
+			// The underlying reader returned less data than its size.
+			return n, io.ErrUnexpectedEOF
+		}
+		c := copy(p[n:], data[start:])
+		n += c
+		off += int64(c)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Prefetch fetches the blocks of the `length` bytes of data at `offset` which are not cached with
+// a single read of the underlying reader, the range being limited to the size of the cache.
+func (rr *RangeReader) Prefetch(offset, length int64) error {
+	if offset < 0 || length <= 0 || offset >= rr.size {
+		return nil
+	}
+	if end := rr.size - offset; length > end {
+		length = end
+	}
+
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	bs := int64(rr.opts.BlockSize)
+	first := offset / bs
+	last := (offset + length - 1) / bs
+	if max := first + int64(rr.opts.CacheSize) - 1; last > max {
+		last = max
+	}
+	// Only the missing blocks are fetched, those at both ends of the range being cached.
+	for first <= last && rr.blocks[first] != nil {
+		first++
+	}
+	for last >= first && rr.blocks[last] != nil {
+		last--
+	}
+	if first > last {
+		return nil
+	}
+	return rr.fetch(first, last)
+}
+
+// block returns the data of block `index`, fetching it if it is not cached. The blocks following
+// `index` are fetched with it if the blocks are read sequentially. The lock of `rr` must be held.
+func (rr *RangeReader) block(index int64) ([]byte, error) {
+	sequential := index == rr.lastBlock+1
+	rr.lastBlock = index
+	if e, ok := rr.blocks[index]; ok {
+		rr.lru.MoveToFront(e)
+		return e.Value.(*rangeBlock).data, nil
+	}
+
+	last := index
+	if sequential {
+		numBlocks := (rr.size + int64(rr.opts.BlockSize) - 1) / int64(rr.opts.BlockSize)
+		for last+1 < numBlocks && last-index < int64(rr.opts.ReadAhead) && rr.blocks[last+1] == nil {
+			last++
+		}
+	}
+	if err := rr.fetch(index, last); err != nil {
+		return nil, err
+	}
+	return rr.blocks[index].Value.(*rangeBlock).data, nil
+}
+
+// fetch reads the blocks `first` to `last` with a single read of the underlying reader and
+// caches them. The lock of `rr` must be held.
+func (rr *RangeReader) fetch(first, last int64) error {
+	bs := int64(rr.opts.BlockSize)
+	offset := first * bs
+	end := (last + 1) * bs
+	if end > rr.size {
+		end = rr.size
+	}
+	data := make([]byte, end-offset)
+	common.Log.Trace("RangeReader: fetching blocks %d-%d (%d bytes at %d)", first, last, len(data), offset)
+	n, err := rr.r.ReadAt(data, offset)
+	if n < len(data) {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		common.Log.Debug("ERROR: Failed fetching %d bytes at %d: %v", len(data), offset, err)
+		return err
+	}
+
+	for index := first; index <= last; index++ {
+		start := (index - first) * bs
+		stop := start + bs
+		if stop > int64(len(data)) {
+			stop = int64(len(data))
+		}
+		rr.add(&rangeBlock{index: index, data: data[start:stop:stop]})
+	}
+	return nil
+}
+
+// add adds `b` to the cache, evicting the least recently used block if the cache is full. The
+// lock of `rr` must be held.
+func (rr *RangeReader) add(b *rangeBlock) {
+	if e, ok := rr.blocks[b.index]; ok {
+		rr.lru.MoveToFront(e)
+		return
+	}
+	for rr.lru.Len() >= rr.opts.CacheSize {
+		e := rr.lru.Back()
+		rr.lru.Remove(e)
+		delete(rr.blocks, e.Value.(*rangeBlock).index)
+	}
+	rr.blocks[b.index] = rr.lru.PushFront(b)
+}
@@ -0,0 +1,208 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// countingReaderAt is a reader at counting the reads and the bytes read.
+type countingReaderAt struct {
+	r         *bytes.Reader
+	reads     int
+	bytesRead int64
+}
+
+// ReadAt implements io.ReaderAt interface.
+func (r *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	r.reads++
+	n, err := r.r.ReadAt(p, off)
+	r.bytesRead += int64(n)
+	return n, err
+}
+
+func TestRangeReader(t *testing.T) {
+	data := streamingTestData(10000)
+	cr := &countingReaderAt{r: bytes.NewReader(data)}
+	rr := NewRangeReader(cr, int64(len(data)), &RangeReaderOptions{BlockSize: 100, CacheSize: 10, ReadAhead: 3})
+
+	// Random access reads fetch single blocks.
+	p := make([]byte, 50)
+	n, err := rr.ReadAt(p, 5020)
+	require.NoError(t, err)
+	require.Equal(t, 50, n)
+	require.Equal(t, data[5020:5070], p)
+	require.Equal(t, 1, cr.reads)
+	require.Equal(t, int64(100), cr.bytesRead)
+
+	// Reads of cached blocks do not read the underlying reader.
+	_, err = rr.ReadAt(p, 5000)
+	require.NoError(t, err)
+	require.Equal(t, data[5000:5050], p)
+	require.Equal(t, 1, cr.reads)
+
+	// Sequential reads fetch the following blocks ahead.
+	_, err = rr.ReadAt(p, 5100)
+	require.NoError(t, err)
+	require.Equal(t, data[5100:5150], p)
+	require.Equal(t, 2, cr.reads)
+	require.Equal(t, int64(500), cr.bytesRead)
+	p = make([]byte, 300)
+	_, err = rr.ReadAt(p, 5150)
+	require.NoError(t, err)
+	require.Equal(t, data[5150:5450], p)
+	require.Equal(t, 2, cr.reads)
+
+	// Reads at the end of the data.
+	n, err = rr.ReadAt(p, 9900)
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, 100, n)
+	require.Equal(t, data[9900:], p[:n])
+	_, err = rr.ReadAt(p, 10000)
+	require.Equal(t, io.EOF, err)
+
+	// Read and Seek.
+	_, err = rr.Seek(-150, io.SeekEnd)
+	require.NoError(t, err)
+	tail, err := ioutil.ReadAll(rr)
+	require.NoError(t, err)
+	require.Equal(t, data[9850:], tail)
+	_, err = rr.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	all, err := ioutil.ReadAll(rr)
+	require.NoError(t, err)
+	require.Equal(t, data, all)
+}
+
+func TestRangeReaderPrefetch(t *testing.T) {
+	data := streamingTestData(10000)
+	cr := &countingReaderAt{r: bytes.NewReader(data)}
+	rr := NewRangeReader(cr, int64(len(data)), &RangeReaderOptions{BlockSize: 100, CacheSize: 20, ReadAhead: -1})
+
+	// The cached blocks at the ends of the range are not fetched again.
+	p := make([]byte, 10)
+	_, err := rr.ReadAt(p, 1000)
+	require.NoError(t, err)
+	require.NoError(t, rr.Prefetch(1000, 1000))
+	require.Equal(t, 2, cr.reads)
+	require.Equal(t, int64(1000), cr.bytesRead)
+
+	p = make([]byte, 1000)
+	_, err = rr.ReadAt(p, 1000)
+	require.NoError(t, err)
+	require.Equal(t, data[1000:2000], p)
+	require.Equal(t, 2, cr.reads)
+
+	// The range is limited to the size of the cache.
+	require.NoError(t, rr.Prefetch(3000, 5000))
+	require.Equal(t, 3, cr.reads)
+	require.Equal(t, int64(3000), cr.bytesRead)
+}
+
+// TestParserRangeReader checks that the documents parsed from range readers are parsed like the
+// documents read from memory.
+func TestParserRangeReader(t *testing.T) {
+	for _, file := range lexerTestFiles(t) {
+		data, err := ioutil.ReadFile(file)
+		require.NoError(t, err)
+		expected := loadAllObjects(t, data, true)
+		if expected == nil {
+			continue
+		}
+
+		parser, err := NewParser(NewRangeReader(bytes.NewReader(data), int64(len(data)), &RangeReaderOptions{BlockSize: 512}))
+		require.NoError(t, err, file)
+		for num, s := range expected {
+			obj, err := parser.LookupByNumber(num)
+			require.NoError(t, err, file)
+			objStr := obj.WriteString()
+			if stream, ok := obj.(*PdfObjectStream); ok {
+				objStr += string(stream.Stream)
+			}
+			require.Equal(t, s, objStr, "%s %d", file, num)
+		}
+	}
+}
+
+func TestLinearization(t *testing.T) {
+	data, err := ioutil.ReadFile("../fjson/testdata/advancedform.pdf")
+	require.NoError(t, err)
+	parser, err := NewParser(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	lin, err := parser.GetLinearization()
+	require.NoError(t, err)
+	require.NotNil(t, lin)
+	require.Equal(t, int64(len(data)), lin.FileLength)
+	require.Equal(t, int64(2608), lin.FirstPageObject)
+	require.Equal(t, int64(109938), lin.FirstPageEnd)
+	require.Equal(t, 12, lin.NumPages)
+	require.Equal(t, int64(615), lin.HintOffset)
+	require.Equal(t, int64(551), lin.HintLength)
+
+	// The page objects are in the page sections, in the order of the pages.
+	var pageObjects []XrefObject
+	for num, xref := range parser.xrefs.ObjectMap {
+		obj, err := parser.LookupByNumber(num)
+		require.NoError(t, err)
+		if dict, ok := GetDict(obj); ok && dict.Get("Type") != nil && dict.Get("Type").String() == "Page" {
+			pageObjects = append(pageObjects, xref)
+		}
+	}
+	sort.Slice(pageObjects, func(i, j int) bool {
+		return pageObjects[i].Offset < pageObjects[j].Offset
+	})
+	require.Len(t, pageObjects, lin.NumPages)
+	require.Equal(t, lin.FirstPageObject, int64(pageObjects[0].ObjectNumber))
+
+	end := int64(0)
+	for i, xref := range pageObjects {
+		offset, length, ok := lin.PageRange(i + 1)
+		require.True(t, ok, i)
+		require.True(t, offset >= end && length > 0, i)
+		end = offset + length
+		require.True(t, xref.Offset >= offset && xref.Offset < end, i)
+	}
+	require.True(t, end <= int64(len(data)))
+	offset, _, _ := lin.PageRange(1)
+	require.True(t, offset < lin.FirstPageEnd)
+	_, _, ok := lin.PageRange(lin.NumPages + 1)
+	require.False(t, ok)
+
+	// The documents which are not linearized.
+	data, err = ioutil.ReadFile("../model/testdata/minimal.pdf")
+	require.NoError(t, err)
+	parser, err = NewParser(bytes.NewReader(data))
+	require.NoError(t, err)
+	lin, err = parser.GetLinearization()
+	require.NoError(t, err)
+	require.Nil(t, lin)
+}
+
+// TestLinearizationInvalidNumPages checks that the hint table of a document whose number of pages
+// does not match its page tree is not loaded.
+func TestLinearizationInvalidNumPages(t *testing.T) {
+	data, err := ioutil.ReadFile("../fjson/testdata/advancedform.pdf")
+	require.NoError(t, err)
+	// The padding following the linearization dictionary keeps the offsets of the objects.
+	old := []byte("/N 12/T 262899/H [ 615 551]>>\rendobj\r          ")
+	invalid := []byte("/N 2000000000/T 262899/H [ 615 551]>>\rendobj\r  ")
+	require.Equal(t, len(old), len(invalid))
+	require.True(t, bytes.Contains(data, old))
+	data = bytes.Replace(data, old, invalid, 1)
+
+	parser, err := NewParser(bytes.NewReader(data))
+	require.NoError(t, err)
+	lin, err := parser.GetLinearization()
+	require.Error(t, err)
+	require.Nil(t, lin)
+}
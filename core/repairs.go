@@ -8,6 +8,7 @@
 package core
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"os"
@@ -183,6 +184,7 @@ func (parser *PdfParser) repairRebuildXrefsTopDown() (*XrefTable, error) {
 		last = append(last[1:bufLen], b)
 	}
 
+	parser.repairs.XrefRebuilt = true
 	return &xrefTable, nil
 }
 
@@ -285,3 +287,324 @@ func (parser *PdfParser) seekPdfVersionTopDown() (int, int, error) {
 
 	return 0, 0, errors.New("version not found")
 }
+
+// Repairs describes the repairs made by the parser to load a damaged file.
+type Repairs struct {
+	// XrefRebuilt is set if the cross-reference table could not be loaded and was rebuilt by
+	// scanning the file for objects.
+	XrefRebuilt bool
+
+	// TrailerRebuilt is set if the trailer was missing or did not refer to a catalog and was
+	// rebuilt.
+	TrailerRebuilt bool
+
+	// CatalogFound is set if the catalog of the rebuilt trailer was located by its
+	// /Type /Catalog entry.
+	CatalogFound bool
+
+	// Streams are the numbers of the stream objects whose /Length was corrected by locating
+	// their endstream keyword. The streams are reported once loaded.
+	Streams []int64
+}
+
+// GetRepairs returns the repairs made by the parser so far.
+func (parser *PdfParser) GetRepairs() Repairs {
+	repairs := parser.repairs
+	repairs.Streams = append([]int64(nil), parser.repairs.Streams...)
+	return repairs
+}
+
+// NewParserWithRecovery creates a new parser for the PDF file `rs` like NewParser, recovering
+// the truncated and the damaged files: the cross-reference table is rebuilt by scanning the file
+// for objects when it cannot be loaded, and the trailer is rebuilt when it is missing or does
+// not refer to a catalog, the catalog being located by its /Type /Catalog entry.
+// The repairs made are returned by GetRepairs. An error is returned if no object is found.
+func NewParserWithRecovery(rs io.ReadSeeker) (*PdfParser, error) {
+	parser := &PdfParser{
+		rs:                                    rs,
+		ObjCache:                              make(objectCache),
+		streamLengthReferenceLookupInProgress: map[int64]bool{},
+	}
+
+	majorVersion, minorVersion, err := parser.parsePdfVersion()
+	if err != nil {
+		common.Log.Debug("Unable to parse version: %v - assuming 1.7", err)
+		majorVersion, minorVersion = 1, 7
+		parser.SetFileOffset(0)
+	}
+	parser.version.Major = majorVersion
+	parser.version.Minor = minorVersion
+
+	parser.trailer, err = parser.loadXrefs()
+	if err != nil || len(parser.xrefs.ObjectMap) == 0 {
+		common.Log.Debug("Unable to load the xref table (%v) - rebuilding it", err)
+		if parser.fileSize, err = parser.rs.Seek(0, io.SeekEnd); err != nil {
+			return nil, err
+		}
+		xrefTable, err := parser.repairRebuildXrefsTopDown()
+		if err != nil {
+			return nil, err
+		}
+		parser.xrefs = *xrefTable
+		parser.objstms = make(objectStreams)
+		parser.trailer = nil
+		parser.repairObjectStreamEntries()
+	}
+	if len(parser.xrefs.ObjectMap) == 0 {
+		return nil, errors.New("no object found")
+	}
+
+	if !parser.hasCatalog(parser.trailer) {
+		parser.repairTrailer()
+	}
+	return parser, nil
+}
+
+// hasCatalog returns true if the /Root entry of `trailer` refers to a catalog dictionary.
+func (parser *PdfParser) hasCatalog(trailer *PdfObjectDictionary) bool {
+	if trailer == nil {
+		return false
+	}
+	ref, ok := trailer.Get("Root").(*PdfObjectReference)
+	if !ok {
+		return false
+	}
+	obj, err := parser.LookupByReference(*ref)
+	if err != nil {
+		return false
+	}
+	return isCatalog(obj)
+}
+
+// isCatalog returns true if `obj` is a catalog dictionary.
+func isCatalog(obj PdfObject) bool {
+	ind, ok := obj.(*PdfIndirectObject)
+	if !ok {
+		return false
+	}
+	dict, ok := GetDict(ind.PdfObject)
+	if !ok {
+		return false
+	}
+	name, ok := GetName(dict.Get("Type"))
+	return ok && *name == "Catalog"
+}
+
+// repairObjectStreamEntries adds the objects of the object streams of the rebuilt
+// cross-reference table, which only has the entries of the objects found in the file.
+func (parser *PdfParser) repairObjectStreamEntries() {
+	for _, objNum := range parser.GetObjectNums() {
+		obj, err := parser.LookupByNumber(objNum)
+		if err != nil {
+			continue
+		}
+		stream, ok := obj.(*PdfObjectStream)
+		if !ok {
+			continue
+		}
+		name, ok := GetName(stream.Get("Type"))
+		if !ok || *name != "ObjStm" {
+			continue
+		}
+		n, ok := GetIntVal(stream.Get("N"))
+		if !ok {
+			continue
+		}
+		data, err := DecodeStream(stream)
+		if err != nil {
+			common.Log.Debug("Unable to decode object stream %d: %v", objNum, err)
+			continue
+		}
+
+		l := newLexer(parser, data)
+		for i := 0; i < n; i++ {
+			l.skipSpaces()
+			num, isInt, ok := l.scanNumber()
+			if !ok || !isInt {
+				break
+			}
+			l.skipSpaces()
+			if _, isInt, ok = l.scanNumber(); !ok || !isInt {
+				break
+			}
+			// The objects found in the file have precedence.
+			if _, has := parser.xrefs.ObjectMap[int(num)]; has {
+				continue
+			}
+			parser.xrefs.ObjectMap[int(num)] = XrefObject{
+				XType:        XrefTypeObjectStream,
+				ObjectNumber: int(num),
+				OsObjNumber:  objNum,
+				OsObjIndex:   i,
+			}
+		}
+	}
+}
+
+// repairTrailer rebuilds the trailer from the last trailer dictionary or cross-reference stream
+// found in the file which refers to a catalog. The trailer refers to the catalog located by its
+// /Type /Catalog entry otherwise. It is left unchanged if no catalog is found.
+func (parser *PdfParser) repairTrailer() {
+	var candidates []*PdfObjectDictionary
+	for _, offset := range parser.repairLocateTrailers() {
+		parser.SetFileOffset(offset + int64(len("trailer")))
+		parser.skipSpaces()
+		parser.skipComments()
+		if dict, err := parser.ParseDict(); err == nil {
+			candidates = append(candidates, dict)
+		}
+	}
+
+	var catalog *PdfIndirectObject
+	var catalogOffset int64 = -1
+	for _, objNum := range parser.GetObjectNums() {
+		obj, err := parser.LookupByNumber(objNum)
+		if err != nil {
+			continue
+		}
+		if stream, ok := obj.(*PdfObjectStream); ok {
+			if name, ok := GetName(stream.Get("Type")); ok && *name == "XRef" {
+				candidates = append(candidates, stream.PdfObjectDictionary)
+			}
+			continue
+		}
+		if !isCatalog(obj) {
+			continue
+		}
+		// Prefer the last catalog of the file, i.e. the catalog of the last revision.
+		offset := parser.xrefs.ObjectMap[objNum].Offset
+		if xref := parser.xrefs.ObjectMap[objNum]; xref.XType == XrefTypeObjectStream {
+			offset = parser.xrefs.ObjectMap[xref.OsObjNumber].Offset
+		}
+		if offset >= catalogOffset {
+			catalog, catalogOffset = obj.(*PdfIndirectObject), offset
+		}
+	}
+
+	trailer := MakeDict()
+	for i := len(candidates) - 1; i >= 0; i-- {
+		if parser.hasCatalog(candidates[i]) {
+			for _, key := range []PdfObjectName{"Root", "Info", "ID", "Encrypt"} {
+				if val := candidates[i].Get(key); val != nil {
+					trailer.Set(key, val)
+				}
+			}
+			break
+		}
+	}
+	if trailer.Get("Root") == nil {
+		if catalog == nil {
+			common.Log.Debug("ERROR: Catalog not found")
+			return
+		}
+		trailer.Set("Root", &PdfObjectReference{
+			ObjectNumber:     catalog.ObjectNumber,
+			GenerationNumber: catalog.GenerationNumber,
+			parser:           parser,
+		})
+		parser.repairs.CatalogFound = true
+	}
+
+	size := 0
+	for objNum := range parser.xrefs.ObjectMap {
+		if objNum >= size {
+			size = objNum + 1
+		}
+	}
+	trailer.Set("Size", MakeInteger(int64(size)))
+	parser.trailer = trailer
+	parser.repairs.TrailerRebuilt = true
+}
+
+// repairLocateTrailers returns the offsets of the trailer keywords of the file.
+func (parser *PdfParser) repairLocateTrailers() []int64 {
+	return parser.repairLocateKeyword(0, []byte("trailer"), -1)
+}
+
+// repairLocateKeyword returns the offsets of the first `n` occurrences of `keyword` in the file
+// after offset `start`, or of all the occurrences if `n` is negative. The file offset is not
+// restored.
+func (parser *PdfParser) repairLocateKeyword(start int64, keyword []byte, n int) []int64 {
+	const bufLen = 4096
+	var offsets []int64
+	buf := make([]byte, bufLen+len(keyword))
+	// The data of the previous read kept to find the keywords across the reads.
+	kept := 0
+	for offset := start; n < 0 || len(offsets) < n; {
+		read, err := parser.repairReadAt(buf[kept:kept+bufLen], offset)
+		data := buf[:kept+read]
+		for i := 0; n < 0 || len(offsets) < n; {
+			j := bytes.Index(data[i:], keyword)
+			if j < 0 {
+				break
+			}
+			offsets = append(offsets, offset-int64(kept)+int64(i+j))
+			i += j + len(keyword)
+		}
+		if err != nil || read == 0 {
+			break
+		}
+		offset += int64(read)
+		kept = len(keyword) - 1
+		if kept > len(data) {
+			kept = len(data)
+		}
+		copy(buf, data[len(data)-kept:])
+	}
+	return offsets
+}
+
+// repairReadAt reads the data of the file at `offset` into `p`, returning the number of bytes
+// read. The file offset is not restored.
+func (parser *PdfParser) repairReadAt(p []byte, offset int64) (int, error) {
+	if _, err := parser.rs.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(parser.rs, p)
+}
+
+// recoverStream recovers the stream of `indirect` whose data starts at offset `start` and whose
+// /Length is invalid by locating its endstream keyword. `cause` is the error of the invalid
+// length, which is returned if the keyword is not found before the next object.
+func (parser *PdfParser) recoverStream(indirect *PdfIndirectObject, start int64, cause error) (PdfObject, error) {
+	if cause == nil {
+		cause = errors.New("endstream not found")
+	}
+	dict, ok := indirect.PdfObject.(*PdfObjectDictionary)
+	if !ok {
+		return nil, cause
+	}
+
+	offsets := parser.repairLocateKeyword(start, []byte("endstream"), 1)
+	if len(offsets) == 0 {
+		return nil, cause
+	}
+	end := offsets[0]
+	if next := parser.xrefNextObjectOffset(start + 1); next > start && end > next {
+		// The keyword is the keyword of another stream.
+		return nil, cause
+	}
+
+	data := make([]byte, end-start)
+	if _, err := parser.repairReadAt(data, start); err != nil {
+		return nil, cause
+	}
+	// The end of line marker before the keyword is not part of the data.
+	if bytes.HasSuffix(data, []byte("\r\n")) {
+		data = data[:len(data)-2]
+	} else if bytes.HasSuffix(data, []byte("\n")) || bytes.HasSuffix(data, []byte("\r")) {
+		data = data[:len(data)-1]
+	}
+	common.Log.Debug("Recovered stream %d with length %d", indirect.ObjectNumber, len(data))
+	dict.Set("Length", MakeInteger(int64(len(data))))
+	parser.repairs.Streams = append(parser.repairs.Streams, indirect.ObjectNumber)
+
+	parser.SetFileOffset(end + int64(len("endstream")))
+	parser.skipSpaces()
+
+	stream := &PdfObjectStream{PdfObjectDictionary: dict, Stream: data}
+	stream.ObjectNumber = indirect.ObjectNumber
+	stream.GenerationNumber = indirect.GenerationNumber
+	stream.PdfObjectReference.parser = parser
+	return stream, nil
+}
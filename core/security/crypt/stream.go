@@ -0,0 +1,210 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rc4"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// StreamFilter is implemented by the crypt filters which can decrypt and encrypt the data of
+// streams while it is read, without holding the data in memory.
+type StreamFilter interface {
+	// NewDecryptReader returns a reader of the data of `r` decrypted using the object encryption
+	// key `okey`, as returned by MakeKey.
+	NewDecryptReader(r io.Reader, okey []byte) (io.Reader, error)
+	// NewEncryptReader returns a reader of the data of `r` encrypted using the object encryption
+	// key `okey`, as returned by MakeKey.
+	NewEncryptReader(r io.Reader, okey []byte) (io.Reader, error)
+}
+
+var (
+	_ StreamFilter = filterIdentity{}
+	_ StreamFilter = filterV2{}
+	_ StreamFilter = filterAESV2{}
+	_ StreamFilter = filterAESV3{}
+)
+
+// NewDecryptReader implements StreamFilter interface.
+func (filterIdentity) NewDecryptReader(r io.Reader, okey []byte) (io.Reader, error) {
+	return r, nil
+}
+
+// NewEncryptReader implements StreamFilter interface.
+func (filterIdentity) NewEncryptReader(r io.Reader, okey []byte) (io.Reader, error) {
+	return r, nil
+}
+
+// NewDecryptReader implements StreamFilter interface.
+func (filterV2) NewDecryptReader(r io.Reader, okey []byte) (io.Reader, error) {
+	ciph, err := rc4.NewCipher(okey)
+	if err != nil {
+		return nil, err
+	}
+	return &cipher.StreamReader{S: ciph, R: r}, nil
+}
+
+// NewEncryptReader implements StreamFilter interface.
+func (f filterV2) NewEncryptReader(r io.Reader, okey []byte) (io.Reader, error) {
+	// RC4 encryption and decryption are the same operation.
+	return f.NewDecryptReader(r, okey)
+}
+
+// aesChunkSize is the size of the chunks of data encrypted and decrypted by the AES readers, a
+// multiple of the block size.
+const aesChunkSize = 256 * aes.BlockSize
+
+// NewDecryptReader implements StreamFilter interface.
+func (filterAES) NewDecryptReader(r io.Reader, okey []byte) (io.Reader, error) {
+	ciph, err := aes.NewCipher(okey)
+	if err != nil {
+		return nil, err
+	}
+	return &aesDecryptReader{r: r, ciph: ciph}, nil
+}
+
+// NewEncryptReader implements StreamFilter interface.
+func (filterAES) NewEncryptReader(r io.Reader, okey []byte) (io.Reader, error) {
+	ciph, err := aes.NewCipher(okey)
+	if err != nil {
+		return nil, err
+	}
+	// The initialization vector is a random block written before the encrypted data.
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, err
+	}
+	return &aesEncryptReader{
+		r:    r,
+		mode: cipher.NewCBCEncrypter(ciph, iv),
+		out:  iv,
+		in:   make([]byte, aesChunkSize+aes.BlockSize),
+	}, nil
+}
+
+// aesDecryptReader decrypts the AES encrypted data of a reader like filterAES.DecryptBytes.
+type aesDecryptReader struct {
+	r    io.Reader
+	ciph cipher.Block
+	mode cipher.BlockMode
+
+	in   []byte // Buffer of the encrypted data.
+	out  []byte // Decrypted data not read yet.
+	last []byte // Last decrypted block, holding the padding at the end of the data.
+	err  error
+}
+
+// Read implements io.Reader interface.
+func (r *aesDecryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 && r.err == nil {
+		r.err = r.fill()
+	}
+	if len(r.out) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// fill decrypts the next chunk of data.
+func (r *aesDecryptReader) fill() error {
+	if r.mode == nil {
+		iv := make([]byte, aes.BlockSize)
+		if n, err := io.ReadFull(r.r, iv); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return fmt.Errorf("AES: Buf len < 16 (%d)", n)
+			}
+			return err
+		}
+		r.mode = cipher.NewCBCDecrypter(r.ciph, iv)
+		r.in = make([]byte, aesChunkSize+aes.BlockSize)
+	}
+
+	// The last decrypted block of the previous chunk is kept before the chunk until the end of
+	// the data is reached.
+	in := r.in[:copy(r.in, r.last)]
+	n, err := io.ReadFull(r.r, r.in[len(in):len(in)+aesChunkSize])
+	end := err == io.EOF || err == io.ErrUnexpectedEOF
+	if err != nil && !end {
+		return err
+	}
+	if n%aes.BlockSize != 0 {
+		return fmt.Errorf("AES buf length not multiple of 16 (%d)", n)
+	}
+	data := r.in[len(in) : len(in)+n]
+	r.mode.CryptBlocks(data, data)
+	data = r.in[:len(in)+n]
+
+	if !end {
+		r.out = data[:len(data)-aes.BlockSize]
+		r.last = append(r.last[:0], data[len(data)-aes.BlockSize:]...)
+		return nil
+	}
+
+	// The padded length is indicated by the last values. Remove those.
+	if len(data) > 0 {
+		padLen := int(data[len(data)-1])
+		if padLen > len(data) {
+			return errors.New("invalid pad length")
+		}
+		data = data[:len(data)-padLen]
+	}
+	r.out = data
+	r.last = nil
+	return io.EOF
+}
+
+// aesEncryptReader encrypts the data of a reader with AES like filterAES.EncryptBytes.
+type aesEncryptReader struct {
+	r    io.Reader
+	mode cipher.BlockMode
+
+	in  []byte // Buffer of the data to encrypt.
+	out []byte // Encrypted data not read yet.
+	err error
+}
+
+// Read implements io.Reader interface.
+func (r *aesEncryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 && r.err == nil {
+		r.err = r.fill()
+	}
+	if len(r.out) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// fill encrypts the next chunk of data.
+func (r *aesEncryptReader) fill() error {
+	n, err := io.ReadFull(r.r, r.in[:aesChunkSize])
+	end := err == io.EOF || err == io.ErrUnexpectedEOF
+	if err != nil && !end {
+		return err
+	}
+	data := r.in[:n]
+	if end {
+		// The data is padded with 16 - (M mod 16) bytes whose value is 16 - (M mod 16).
+		pad := aes.BlockSize - n%aes.BlockSize
+		for i := 0; i < pad; i++ {
+			data = append(data, byte(pad))
+		}
+	}
+	r.mode.CryptBlocks(data, data)
+	r.out = data
+	if end {
+		return io.EOF
+	}
+	return nil
+}
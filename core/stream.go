@@ -7,6 +7,7 @@ package core
 
 import (
 	"fmt"
+	"io/ioutil"
 
 	"github.com/TheLinker/unipdf/v3/common"
 )
@@ -84,6 +85,16 @@ func NewEncoderFromStream(streamObj *PdfObjectStream) (StreamEncoder, error) {
 func DecodeStream(streamObj *PdfObjectStream) ([]byte, error) {
 	common.Log.Trace("Decode stream")
 
+	if streamObj.source != nil {
+		// The data of the streamed streams is read from their source.
+		r, err := NewDecodedStreamReader(streamObj)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+	}
+
 	encoder, err := NewEncoderFromStream(streamObj)
 	if err != nil {
 		common.Log.Debug("ERROR: Stream decoding failed: %v", err)
@@ -0,0 +1,203 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+
+	"github.com/TheLinker/unipdf/v3/common"
+)
+
+// streamSource returns a reader of the data of a streamed stream.
+type streamSource func() (io.ReadCloser, error)
+
+// wrapStreamSource returns a source of the data of `source` transformed by `wrap`.
+func wrapStreamSource(source streamSource, wrap func(r io.Reader) (io.Reader, error)) streamSource {
+	return func() (io.ReadCloser, error) {
+		rc, err := source()
+		if err != nil {
+			return nil, err
+		}
+		r, err := wrap(rc)
+		if err != nil {
+			rc.Close()
+			return nil, err
+		}
+		return readCloser{Reader: r, Closer: rc}, nil
+	}
+}
+
+// readCloser is a reader whose Close method closes the underlying reader of the data.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// bufferedReader is a reader of the data of a reader transformed as a whole, the data being read
+// and transformed when it is first read. It is used by the transformations which cannot be
+// applied while the data is read.
+type bufferedReader struct {
+	r         io.Reader
+	transform func(data []byte) ([]byte, error)
+	out       *bytes.Reader
+	err       error
+}
+
+// newBufferedReader returns a reader of the data of `r` transformed by `transform`.
+func newBufferedReader(r io.Reader, transform func(data []byte) ([]byte, error)) io.Reader {
+	return &bufferedReader{r: r, transform: transform}
+}
+
+// Read implements io.Reader interface.
+func (r *bufferedReader) Read(p []byte) (int, error) {
+	if r.out == nil && r.err == nil {
+		data, err := ioutil.ReadAll(r.r)
+		if err == nil {
+			data, err = r.transform(data)
+		}
+		r.out, r.err = bytes.NewReader(data), err
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	return r.out.Read(p)
+}
+
+// IsStreamed returns true if the data of `stream` is not held in Stream but read when it is
+// needed, from the file for the large streams of the documents parsed with a streamed size set
+// by SetStreamedSize or from a reader for the streams made with MakeStreamFromReader.
+// The data of the streamed streams is read with NewStreamReader and NewDecodedStreamReader and is
+// copied from its source by the writer.
+func (stream *PdfObjectStream) IsStreamed() bool {
+	return stream.source != nil
+}
+
+// NewStreamReader returns a reader of the encoded data of `streamObj`, i.e. of the data of Stream
+// for the streams which are not streamed. The data of the streamed streams of encrypted documents
+// is decrypted while it is read. The reader must be closed when done.
+func NewStreamReader(streamObj *PdfObjectStream) (io.ReadCloser, error) {
+	if streamObj.source == nil {
+		return ioutil.NopCloser(bytes.NewReader(streamObj.Stream)), nil
+	}
+	return streamObj.source()
+}
+
+// NewDecodedStreamReader returns a reader of the decoded data of `streamObj`, the data being read
+// and decoded lazily, without holding it in memory, for the Flate, LZW, RunLength, ASCIIHex and
+// ASCII85 filters and the predictors. The data of the other filters is decoded as a whole when
+// it is first read. The reader must be closed when done.
+func NewDecodedStreamReader(streamObj *PdfObjectStream) (io.ReadCloser, error) {
+	encoder, err := NewEncoderFromStream(streamObj)
+	if err != nil {
+		common.Log.Debug("ERROR: Stream decoding failed: %v", err)
+		return nil, err
+	}
+	raw, err := NewStreamReader(streamObj)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newStreamDecodeReader(encoder, streamObj, raw)
+	if err != nil {
+		raw.Close()
+		return nil, err
+	}
+	return readCloser{Reader: r, Closer: raw}, nil
+}
+
+// MakeStreamFromReader creates a streamed PdfObjectStream whose data is returned by `open` and
+// encoded with `encoder`, raw encoding being used if encoder is nil. The data is read when the
+// stream is written, `open` being called each time the data is needed, and encoded while it is
+// read for the Flate, LZW, ASCIIHex and ASCII85 encoders, so that large streams such as embedded
+// videos are written without being held in memory.
+func MakeStreamFromReader(open func() (io.ReadCloser, error), encoder StreamEncoder) (*PdfObjectStream, error) {
+	if open == nil {
+		return nil, errors.New("stream data reader required")
+	}
+	if encoder == nil {
+		encoder = NewRawEncoder()
+	}
+	if lzwenc, is := encoder.(*LZWEncoder); is {
+		// Make sure to use EarlyChange 0.. We do not have write support for 1 yet.
+		lzwenc.EarlyChange = 0
+	}
+
+	stream := &PdfObjectStream{}
+	stream.PdfObjectDictionary = encoder.MakeStreamDict()
+	stream.source = func() (io.ReadCloser, error) {
+		rc, err := open()
+		if err != nil {
+			return nil, err
+		}
+
+		// The data is encoded by the writers of the encoders, writing to a pipe.
+		pr, pw := io.Pipe()
+		go func() {
+			defer rc.Close()
+			ew, err := newStreamEncodeWriter(encoder, pw)
+			if err == nil {
+				_, err = io.Copy(ew, rc)
+				if cerr := ew.Close(); err == nil {
+					err = cerr
+				}
+			}
+			pw.CloseWithError(err)
+		}()
+		return pr, nil
+	}
+	return stream, nil
+}
+
+// SetStreamedSize sets the size above which the streams are streamed: the data of the streams
+// whose Length is larger than `size` is not loaded in memory when they are parsed but read from
+// the file when it is needed, e.g. by NewDecodedStreamReader or by the writer. The file must
+// remain open while the streams are used. All the streams are loaded in memory if `size` is 0,
+// the default.
+func (parser *PdfParser) SetStreamedSize(size int64) {
+	parser.streamedSize = size
+}
+
+// isStreamedLength returns true if the data of a stream of length `length` is streamed.
+func (parser *PdfParser) isStreamedLength(length int64) bool {
+	return parser.streamedSize > 0 && length > parser.streamedSize
+}
+
+// fileStreamSource returns a source of the `length` bytes of stream data at `offset` in the file.
+func (parser *PdfParser) fileStreamSource(offset, length int64) streamSource {
+	return func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(io.NewSectionReader(parserReaderAt{parser}, offset, length)), nil
+	}
+}
+
+// parserReaderAt reads the file of a parser at random offsets, seeking in the file and restoring
+// the offset of the parser if the file does not implement io.ReaderAt.
+type parserReaderAt struct {
+	parser *PdfParser
+}
+
+// ReadAt implements io.ReaderAt interface.
+func (r parserReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	parser := r.parser
+	if ra, ok := parser.rs.(io.ReaderAt); ok {
+		n, err := ra.ReadAt(p, off)
+		if err != errNoReaderAt {
+			return n, err
+		}
+	}
+
+	offset := parser.GetFileOffset()
+	defer parser.SetFileOffset(offset)
+	if _, err := parser.rs.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(parser.rs, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
@@ -0,0 +1,250 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// streamingTestData returns `n` bytes of data with runs and repeated sequences.
+func streamingTestData(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		switch {
+		case i%1000 < 700:
+			data[i] = byte(i*7 + i/13 + 1)
+		case i%1000 < 900:
+			data[i] = byte(i / 1000)
+		default:
+			data[i] = 0
+		}
+	}
+	return data
+}
+
+// streamingTestEncoders returns the encoders of the streaming tests.
+func streamingTestEncoders() map[string]func() StreamEncoder {
+	return map[string]func() StreamEncoder{
+		"raw":   func() StreamEncoder { return NewRawEncoder() },
+		"flate": func() StreamEncoder { return NewFlateEncoder() },
+		"flate png": func() StreamEncoder {
+			enc := NewFlateEncoder()
+			enc.Predictor = 15
+			enc.Columns = 16
+			enc.Colors = 3
+			return enc
+		},
+		"flate png up": func() StreamEncoder {
+			enc := NewFlateEncoder()
+			enc.Predictor = 12
+			enc.Columns = 48
+			return enc
+		},
+		"lzw": func() StreamEncoder {
+			enc := NewLZWEncoder()
+			enc.EarlyChange = 0
+			return enc
+		},
+		"ascii hex":  func() StreamEncoder { return NewASCIIHexEncoder() },
+		"ascii 85":   func() StreamEncoder { return NewASCII85Encoder() },
+		"run length": func() StreamEncoder { return NewRunLengthEncoder() },
+		"multi": func() StreamEncoder {
+			enc := NewMultiEncoder()
+			enc.AddEncoder(NewASCII85Encoder())
+			enc.AddEncoder(NewFlateEncoder())
+			return enc
+		},
+	}
+}
+
+// readDecodedStream reads the decoded data of `stream` with NewDecodedStreamReader.
+func readDecodedStream(t *testing.T, stream *PdfObjectStream) []byte {
+	r, err := NewDecodedStreamReader(stream)
+	require.NoError(t, err)
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	return data
+}
+
+func TestDecodedStreamReader(t *testing.T) {
+	for _, n := range []int{0, 1, 4, 48 * 5, 48 * 2084} {
+		data := streamingTestData(n)
+		for name, makeEncoder := range streamingTestEncoders() {
+			if n%48 != 0 && (name == "flate png" || name == "flate png up") {
+				continue
+			}
+			stream, err := MakeStream(data, makeEncoder())
+			require.NoError(t, err, name)
+			// The stream dictionary is parsed again, as for the streams of the documents.
+			stream.PdfObjectDictionary, err = NewParserFromString(stream.PdfObjectDictionary.WriteString()).ParseDict()
+			require.NoError(t, err, name)
+
+			expected, err := DecodeStream(stream)
+			if n == 0 && err != nil {
+				// The rows of the predictors cannot be empty and the empty run length
+				// encoded data has no EOD marker.
+				r, err := NewDecodedStreamReader(stream)
+				if err == nil {
+					_, err = ioutil.ReadAll(r)
+				}
+				require.Error(t, err, name)
+				continue
+			}
+			require.NoError(t, err, name)
+			require.Equal(t, data, append([]byte{}, expected...), name)
+			require.Equal(t, append([]byte{}, expected...), append([]byte{}, readDecodedStream(t, stream)...), name)
+		}
+	}
+}
+
+func TestDecodedStreamReaderTiffPredictor(t *testing.T) {
+	// Rows of 4 samples of 2 colors, each sample predicting the same as the sample to the left.
+	rows := []byte{
+		1, 2, 1, 1, 1, 1, 1, 1,
+		10, 20, 0, 0, 5, 5, 250, 250,
+	}
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	w.Write(rows)
+	w.Close()
+
+	dict, err := NewParserFromString("<< /Filter /FlateDecode /DecodeParms << /Predictor 2 /Columns 4 /Colors 2 /BitsPerComponent 8 >> >>").ParseDict()
+	require.NoError(t, err)
+	stream := &PdfObjectStream{PdfObjectDictionary: dict, Stream: b.Bytes()}
+
+	expected := []byte{
+		1, 2, 2, 3, 3, 4, 4, 5,
+		10, 20, 10, 20, 15, 25, 9, 19,
+	}
+	data, err := DecodeStream(stream)
+	require.NoError(t, err)
+	require.Equal(t, expected, data)
+	require.Equal(t, expected, readDecodedStream(t, stream))
+}
+
+func TestDecodedStreamReaderErrors(t *testing.T) {
+	for _, s := range []string{
+		"<< /Filter /ASCIIHexDecode >>\n414",
+		"<< /Filter /ASCIIHexDecode >>\n41X>",
+		"<< /Filter /ASCII85Decode >>\n87cU~x",
+		"<< /Filter /RunLengthDecode >>\n\x02ab",
+	} {
+		i := bytes.IndexByte([]byte(s), '\n')
+		dict, err := NewParserFromString(s[:i]).ParseDict()
+		require.NoError(t, err)
+		stream := &PdfObjectStream{PdfObjectDictionary: dict, Stream: []byte(s[i+1:])}
+
+		_, err = DecodeStream(stream)
+		require.Error(t, err, s)
+		r, err := NewDecodedStreamReader(stream)
+		require.NoError(t, err, s)
+		_, err = ioutil.ReadAll(r)
+		require.Error(t, err, s)
+	}
+}
+
+func TestMakeStreamFromReader(t *testing.T) {
+	data := streamingTestData(48 * 2084)
+	for name, makeEncoder := range streamingTestEncoders() {
+		opened := 0
+		stream, err := MakeStreamFromReader(func() (io.ReadCloser, error) {
+			opened++
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
+		}, makeEncoder())
+		require.NoError(t, err, name)
+		require.True(t, stream.IsStreamed(), name)
+		require.Equal(t, data, readDecodedStream(t, stream), name)
+
+		// The data is read each time it is needed.
+		r, err := NewStreamReader(stream)
+		require.NoError(t, err, name)
+		encoded, err := ioutil.ReadAll(r)
+		require.NoError(t, err, name)
+		require.NoError(t, r.Close(), name)
+		require.Equal(t, 2, opened, name)
+
+		decoded, err := DecodeStream(&PdfObjectStream{PdfObjectDictionary: stream.PdfObjectDictionary, Stream: encoded})
+		require.NoError(t, err, name)
+		require.Equal(t, data, decoded, name)
+	}
+}
+
+// TestParserStreamedSize checks that the data of the streamed streams of the test files, read
+// from the files, is the data of the streams loaded in memory.
+func TestParserStreamedSize(t *testing.T) {
+	passwords := map[string]string{
+		"issue6010_1.pdf": "abc",
+		"pr6531_1.pdf":    "asdfasdf",
+		"i-9.pdf":         "",
+	}
+
+	for _, file := range lexerTestFiles(t) {
+		password, isEncrypted := passwords[filepath.Base(file)]
+		data, err := ioutil.ReadFile(file)
+		require.NoError(t, err)
+		parser, err := NewParser(bytes.NewReader(data))
+		if err != nil {
+			continue
+		}
+		if encrypted, _ := parser.IsEncrypted(); encrypted != isEncrypted {
+			continue
+		}
+
+		f, err := os.Open(file)
+		require.NoError(t, err)
+		streamedParser, err := NewParser(f)
+		require.NoError(t, err, file)
+		streamedParser.SetStreamedSize(16)
+		if isEncrypted {
+			for _, p := range []*PdfParser{parser, streamedParser} {
+				_, err := p.IsEncrypted()
+				require.NoError(t, err, file)
+				ok, err := p.Decrypt([]byte(password))
+				require.NoError(t, err, file)
+				require.True(t, ok, file)
+			}
+		}
+
+		streamed := 0
+		for _, num := range parser.GetObjectNums() {
+			obj, err := parser.LookupByNumber(num)
+			if err != nil {
+				continue
+			}
+			stream, ok := obj.(*PdfObjectStream)
+			if !ok {
+				continue
+			}
+			streamedObj, err := streamedParser.LookupByNumber(num)
+			require.NoError(t, err, file)
+			streamedStream, ok := streamedObj.(*PdfObjectStream)
+			require.True(t, ok, file)
+			if streamedStream.IsStreamed() {
+				streamed++
+			}
+
+			expected, err := DecodeStream(stream)
+			decoded, streamedErr := DecodeStream(streamedStream)
+			if err != nil {
+				require.Error(t, streamedErr, "%s %d", file, num)
+				continue
+			}
+			require.NoError(t, streamedErr, "%s %d", file, num)
+			require.Equal(t, expected, decoded, "%s %d", file, num)
+		}
+		require.NoError(t, f.Close())
+		require.NotZero(t, streamed, file)
+	}
+}
@@ -57,6 +57,9 @@ type Creator struct {
 	// Outline.
 	outline *model.Outline
 
+	// Outline listed by the table of contents, set by AddOutlineTOC.
+	outlineTOC *model.Outline
+
 	// External outlines.
 	externalOutline *model.PdfOutlineTreeNode
 
@@ -66,6 +69,16 @@ type Creator struct {
 	// Page labels.
 	pageLabels core.PdfObject
 
+	// Document presentation.
+	viewerPreferences *model.ViewerPreferences
+	pageMode          model.PageMode
+	pageLayout        model.PageLayout
+	openAction        *model.OpenAction
+
+	// Embedded files and portable collection.
+	embeddedFiles []embeddedFile
+	collection    *model.PdfCollection
+
 	// Optimizer.
 	optimizer model.Optimizer
 
@@ -98,6 +111,50 @@ func (c *Creator) SetPageLabels(pageLabels core.PdfObject) {
 	c.pageLabels = pageLabels
 }
 
+// SetViewerPreferences sets the viewer preferences of the PDF file generated
+// by the creator (e.g. hiding the toolbar or displaying the document title).
+func (c *Creator) SetViewerPreferences(prefs *model.ViewerPreferences) {
+	c.viewerPreferences = prefs
+}
+
+// SetPageMode sets the page mode used when the PDF file generated by the
+// creator is opened (e.g. showing the outlines panel).
+func (c *Creator) SetPageMode(mode model.PageMode) {
+	c.pageMode = mode
+}
+
+// SetPageLayout sets the page layout used when the PDF file generated by
+// the creator is opened.
+func (c *Creator) SetPageLayout(layout model.PageLayout) {
+	c.pageLayout = layout
+}
+
+// SetOpenAction sets the action performed when the PDF file generated by
+// the creator is opened (e.g. displaying a specific page at a zoom level).
+func (c *Creator) SetOpenAction(action *model.OpenAction) {
+	c.openAction = action
+}
+
+// embeddedFile represents a file embedded in the PDF file generated by the
+// creator.
+type embeddedFile struct {
+	name     string
+	filespec *model.PdfFilespec
+}
+
+// AddEmbeddedFile embeds the file having the specified file specification in
+// the PDF file generated by the creator, under the specified name.
+func (c *Creator) AddEmbeddedFile(name string, fs *model.PdfFilespec) {
+	c.embeddedFiles = append(c.embeddedFiles, embeddedFile{name: name, filespec: fs})
+}
+
+// SetCollection turns the PDF file generated by the creator into a portable
+// collection (PDF portfolio) of its embedded files. The pages generated by
+// the creator are used as the cover sheet of the collection.
+func (c *Creator) SetCollection(collection *model.PdfCollection) {
+	c.collection = collection
+}
+
 // FrontpageFunctionArgs holds the input arguments to a front page drawing function.
 // It is designed as a struct, so additional parameters can be added in the future with backwards
 // compatibility.
@@ -475,6 +532,14 @@ func (c *Creator) Finalize() error {
 		}
 	}
 
+	if c.outlineTOC != nil {
+		tocPage := 0
+		if hasFrontPage {
+			tocPage = 1
+		}
+		c.adjustOutlineTOC(genpages, tocPage)
+	}
+
 	// Account for the front page and the table of content pages.
 	if c.outline != nil && c.AddOutlines {
 		var adjustOutlineDest func(item *model.OutlineItem)
@@ -682,6 +747,28 @@ func (c *Creator) Write(ws io.Writer) error {
 		}
 	}
 
+	// Document presentation.
+	if c.viewerPreferences != nil {
+		pdfWriter.SetViewerPreferences(c.viewerPreferences)
+	}
+	pdfWriter.SetPageMode(c.pageMode)
+	pdfWriter.SetPageLayout(c.pageLayout)
+	pdfWriter.SetOpenAction(c.openAction)
+
+	// Embedded files and portable collection.
+	for _, file := range c.embeddedFiles {
+		if err := pdfWriter.AddEmbeddedFile(file.name, file.filespec); err != nil {
+			common.Log.Debug("ERROR: Could not add embedded file: %v", err)
+			return err
+		}
+	}
+	if c.collection != nil {
+		if err := pdfWriter.SetCollection(c.collection); err != nil {
+			common.Log.Debug("ERROR: Could not set collection: %v", err)
+			return err
+		}
+	}
+
 	if c.subsetFonts != nil {
 		for _, font := range c.subsetFonts {
 			err := font.SubsetRegistered()
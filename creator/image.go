@@ -165,6 +165,13 @@ func (img *Image) ConvertToBinary() error {
 	return img.img.ConvertToBinary()
 }
 
+// ConvertToBinaryWithParams converts current image data into binary (Bi-level image) format.
+// If provided image is RGB or GrayScale the function converts it into binary image
+// using the 'params' thresholding method.
+func (img *Image) ConvertToBinaryWithParams(params core.BinarizationParams) error {
+	return img.img.ConvertToBinaryWithParams(params)
+}
+
 // makeXObject makes the encoded XObject Image that will be used in the PDF.
 func (img *Image) makeXObject() error {
 	encoder := img.encoder
@@ -0,0 +1,111 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package creator
+
+import (
+	"strconv"
+
+	"github.com/TheLinker/unipdf/v3/common"
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/model"
+)
+
+// AddOutlineTOC generates the table of contents of the creator from the items of `outline`,
+// such as the outline of merged documents returned by model.PdfReader.GetOutlines, the pages
+// of the documents being added to the creator with AddPage. The items up to the level
+// `maxLevel` (all the levels if 0) are listed, indented by level, with the numbers of their
+// destination pages and links to their destinations.
+//
+// The table of contents is prepended to the pages when the creator is finalized, using the
+// styling of the TOC component of the creator and the page size of the creator, and the outline
+// is written with its destinations shifted by the number of inserted pages and with an item for
+// the table of contents. The destinations of the outline items must refer to pages added to the
+// creator.
+//
+// Note that `outline` is modified: the destination page numbers of its items are set from their
+// page objects and the page objects are cleared by the call, then the destinations are shifted
+// to the pages of the output and a "Table of Contents" item is inserted first when the creator
+// is finalized.
+func (c *Creator) AddOutlineTOC(outline *model.Outline, maxLevel uint) {
+	// Page indices of the page objects.
+	pageIndices := make(map[*core.PdfIndirectObject]int, len(c.pages))
+	for i, page := range c.pages {
+		pageIndices[page.GetPageAsIndirectObject()] = i
+	}
+
+	var addItems func(items []*model.OutlineItem, level uint)
+	addItems = func(items []*model.OutlineItem, level uint) {
+		for _, item := range items {
+			dest := &item.Dest
+			if dest.PageObj != nil {
+				if i, ok := pageIndices[dest.PageObj]; ok {
+					dest.Page = int64(i)
+				}
+			}
+			dest.PageObj = nil
+
+			if maxLevel == 0 || level <= maxLevel {
+				c.addOutlineTOCLine(item, level)
+			}
+			addItems(item.Items(), level+1)
+		}
+	}
+	addItems(outline.Items(), 1)
+
+	c.AddTOC = true
+	c.outlineTOC = outline
+}
+
+// addOutlineTOCLine adds the line of the outline item `item` at the level `level` to the table
+// of contents.
+func (c *Creator) addOutlineTOCLine(item *model.OutlineItem, level uint) {
+	dest := item.Dest
+	if dest.Page < 0 || dest.Page >= int64(len(c.pages)) {
+		common.Log.Debug("WARN: outline item %q destination page %d not found", item.Title, dest.Page)
+		c.toc.Add("", item.Title, "", level)
+		return
+	}
+	line := c.toc.Add("", item.Title, strconv.FormatInt(dest.Page+1, 10), level)
+
+	// Link to the position of the destination, or to the top of the destination page.
+	y := dest.Y
+	if dest.Mode != "XYZ" || y == 0 {
+		if mbox, err := c.pages[dest.Page].GetMediaBox(); err == nil {
+			y = mbox.Ury
+		}
+	}
+
+	// The link positions are relative to the top left corner of the table of contents pages.
+	line.SetLink(dest.Page+1, dest.X, c.pageHeight-y)
+}
+
+// adjustOutlineTOC shifts the destinations of the outline of the table of contents by the
+// `inserted` pages inserted before the outline pages, and inserts the item of the table of
+// contents on page `tocPage`.
+func (c *Creator) adjustOutlineTOC(inserted, tocPage int) {
+	var adjust func(items []*model.OutlineItem)
+	adjust = func(items []*model.OutlineItem) {
+		for _, item := range items {
+			if item.Dest.Page >= 0 {
+				item.Dest.Page += int64(inserted)
+			}
+			if page := int(item.Dest.Page); page >= 0 && page < len(c.pages) {
+				item.Dest.PageObj = c.pages[page].GetPageAsIndirectObject()
+			} else {
+				common.Log.Debug("WARN: could not get page container for page %d", page)
+			}
+			adjust(item.Items())
+		}
+	}
+	adjust(c.outlineTOC.Items())
+
+	dest := model.NewOutlineDest(int64(tocPage), 0, c.pageHeight)
+	if tocPage < len(c.pages) {
+		dest.PageObj = c.pages[tocPage].GetPageAsIndirectObject()
+	}
+	c.outlineTOC.Insert(0, model.NewOutlineItem("Table of Contents", dest))
+	c.externalOutline = c.outlineTOC.ToOutlineTree()
+}
@@ -5,8 +5,12 @@
 package creator
 
 import (
+	"bytes"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+
+	"github.com/TheLinker/unipdf/v3/core"
 	"github.com/TheLinker/unipdf/v3/model"
 )
 
@@ -107,3 +111,87 @@ func TestTOCAdvanced(t *testing.T) {
 		t.Fatalf("Fail: %v\n", err)
 	}
 }
+
+func TestAddOutlineTOC(t *testing.T) {
+	// Document with three pages and an outline, such as merged documents.
+	c := New()
+	outline := model.NewOutline()
+	var parent *model.OutlineItem
+	for i, title := range []string{"First document", "Section", "Second document"} {
+		page := c.NewPage()
+		c.Draw(c.NewParagraph(title))
+		dest := model.NewOutlineDest(int64(i), 0, 500)
+		dest.PageObj = page.GetPageAsIndirectObject()
+		item := model.NewOutlineItem(title, dest)
+		if i == 1 {
+			parent.Add(item)
+		} else {
+			outline.Add(item)
+			parent = item
+		}
+	}
+	c.SetOutlineTree(outline.ToOutlineTree())
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	outline, err = reader.GetOutlines()
+	require.NoError(t, err)
+
+	c = New()
+	for _, page := range reader.PageList {
+		require.NoError(t, c.AddPage(page))
+	}
+	c.AddOutlineTOC(outline, 0)
+	lines := c.TOC().Lines()
+	require.Len(t, lines, 3)
+	require.Equal(t, "Section", lines[1].Title.Text)
+	require.Equal(t, uint(2), lines[1].Level())
+	buf.Reset()
+	require.NoError(t, c.Write(&buf))
+
+	reader, err = model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	numPages, err := reader.GetNumPages()
+	require.NoError(t, err)
+	require.Equal(t, 4, numPages)
+
+	// The page numbers account for the table of contents page.
+	for i, line := range lines {
+		require.Equal(t, string(rune('2'+i)), line.Page.Text)
+	}
+
+	// The table of contents links to the destinations.
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	annots, err := page.GetAnnotations()
+	require.NoError(t, err)
+	// Each chunk of the lines is a link.
+	linked := make(map[int]bool)
+	for _, annot := range annots {
+		link, ok := annot.GetContext().(*model.PdfAnnotationLink)
+		require.True(t, ok)
+		dest, ok := core.GetArray(link.Dest)
+		require.True(t, ok)
+		pageIdx, ok := core.GetIntVal(dest.Get(0))
+		require.True(t, ok)
+		linked[pageIdx] = true
+		y, err := core.GetNumberAsFloat(dest.Get(3))
+		require.NoError(t, err)
+		require.Equal(t, 500.0, y)
+	}
+	require.Equal(t, map[int]bool{1: true, 2: true, 3: true}, linked)
+
+	// The outline destinations are shifted.
+	outline, err = reader.GetOutlines()
+	require.NoError(t, err)
+	items := outline.Items()
+	require.Len(t, items, 3)
+	require.Equal(t, "Table of Contents", items[0].Title)
+	require.Equal(t, int64(0), items[0].Dest.Page)
+	require.Equal(t, "First document", items[1].Title)
+	require.Equal(t, int64(1), items[1].Dest.Page)
+	require.Equal(t, int64(2), items[1].Items()[0].Dest.Page)
+	require.Equal(t, int64(3), items[2].Dest.Page)
+}
@@ -0,0 +1,162 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+/*
+Package barcode detects and decodes the barcodes printed on the PDF pages:
+QR codes, DataMatrix symbols and the Code 128, Code 39 and Interleaved 2 of 5
+linear barcodes.
+
+The barcodes are read either from the images of the pages rendered with
+render.ImageDevice, which finds the barcodes drawn with vector graphics as
+well as the ones of the scanned pages, or from the image XObjects drawn on the
+pages, which is faster for the scanned documents. The barcodes found on the
+pages are located in the page coordinates.
+
+The QR codes are read at any rotation, the DataMatrix symbols and the linear
+barcodes when their edges are parallel to the edges of the image.
+
+Example:
+
+	barcodes, err := barcode.ReadPage(page, barcode.Options{
+		Formats: []barcode.Format{barcode.FormatQR},
+	})
+	if err != nil {
+		return err
+	}
+	for _, b := range barcodes {
+		fmt.Printf("%s %q at %v\n", b.Format, b.Value, b.BBox)
+	}
+
+The Split function uses the barcodes to split the scanned batches of documents
+at their separator sheets.
+*/
+package barcode
+
+import (
+	"fmt"
+	"image"
+	"sort"
+
+	"github.com/TheLinker/unipdf/v3/core"
+)
+
+// Format is a barcode symbology.
+type Format int
+
+// Barcode formats.
+const (
+	// FormatQR is the QR code.
+	FormatQR Format = iota
+	// FormatDataMatrix is the ECC 200 DataMatrix symbol.
+	FormatDataMatrix
+	// FormatCode128 is the Code 128 linear barcode.
+	FormatCode128
+	// FormatCode39 is the Code 39 linear barcode.
+	FormatCode39
+	// FormatITF is the Interleaved 2 of 5 linear barcode.
+	FormatITF
+)
+
+// String returns the name of the format.
+func (f Format) String() string {
+	switch f {
+	case FormatQR:
+		return "QR"
+	case FormatDataMatrix:
+		return "DataMatrix"
+	case FormatCode128:
+		return "Code128"
+	case FormatCode39:
+		return "Code39"
+	case FormatITF:
+		return "ITF"
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// allFormats are the formats read by default.
+var allFormats = []Format{FormatQR, FormatDataMatrix, FormatCode128, FormatCode39, FormatITF}
+
+// Options define the barcodes searched and how the images are processed.
+type Options struct {
+	// Formats are the formats searched. All the formats are searched if not set.
+	Formats []Format
+
+	// Binarization is the method used to convert the images to black and
+	// white before searching the barcodes. If not set, the images are
+	// binarized with the Otsu thresholds of their tiles.
+	Binarization *core.BinarizationParams
+
+	// Resolution is the resolution in DPI of the pages rendered by ReadPage.
+	// If not set, the pages are rendered at 200 DPI.
+	Resolution float64
+}
+
+// has reports whether the format `f` is searched.
+func (o *Options) has(f Format) bool {
+	if len(o.Formats) == 0 {
+		return true
+	}
+	for _, format := range o.Formats {
+		if format == f {
+			return true
+		}
+	}
+	return false
+}
+
+// Barcode is a barcode decoded from an image.
+type Barcode struct {
+	// Format is the format of the barcode.
+	Format Format
+
+	// Value is the decoded content of the barcode. The bytes of the 2D codes
+	// which are not valid UTF-8 are decoded as ISO-8859-1.
+	Value string
+
+	// Bounds is the bounding box of the barcode in the image, in pixels.
+	Bounds image.Rectangle
+}
+
+// ReadImage returns the barcodes found in the image `img`, sorted from top to
+// bottom and from left to right.
+func ReadImage(img image.Image, opts Options) []*Barcode {
+	params := core.BinarizationParams{Method: core.BinarizeOtsu}
+	if opts.Binarization != nil {
+		params = *opts.Binarization
+	}
+	m := newBitMatrix(core.BinarizeImage(img, params))
+
+	var barcodes []*Barcode
+	if opts.has(FormatQR) {
+		barcodes = append(barcodes, readQRCodes(m)...)
+	}
+	if opts.has(FormatDataMatrix) {
+		barcodes = append(barcodes, readDataMatrices(m)...)
+	}
+	var formats []Format
+	for _, f := range []Format{FormatCode128, FormatCode39, FormatITF} {
+		if opts.has(f) {
+			formats = append(formats, f)
+		}
+	}
+	if len(formats) > 0 {
+		barcodes = append(barcodes, readLinear(m, formats)...)
+	}
+
+	// The bounds are relative to the image origin.
+	origin := img.Bounds().Min
+	for _, b := range barcodes {
+		b.Bounds = b.Bounds.Add(origin)
+	}
+	sort.SliceStable(barcodes, func(i, j int) bool {
+		bi, bj := barcodes[i].Bounds.Min, barcodes[j].Bounds.Min
+		if bi.Y != bj.Y {
+			return bi.Y < bj.Y
+		}
+		return bi.X < bj.X
+	})
+	return barcodes
+}
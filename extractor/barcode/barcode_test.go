@@ -0,0 +1,219 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package barcode_test
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+
+	bc "github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/code39"
+	"github.com/boombuler/barcode/datamatrix"
+	"github.com/boombuler/barcode/qr"
+	"github.com/boombuler/barcode/twooffive"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TheLinker/unipdf/v3/extractor/barcode"
+)
+
+// drawCode returns an image of `code` with modules of `module` pixels and a
+// quiet zone of `quiet` pixels. The linear barcodes are `height` pixels high.
+func drawCode(code bc.Barcode, module, quiet, height int) *image.Gray {
+	b := code.Bounds()
+	w, h := b.Dx()*module, b.Dy()*module
+	if b.Dy() == 1 {
+		h = height
+	}
+	img := image.NewGray(image.Rect(0, 0, w+2*quiet, h+2*quiet))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			by := b.Min.Y
+			if b.Dy() > 1 {
+				by += y / module
+			}
+			if c := color.GrayModel.Convert(code.At(b.Min.X+x/module, by)).(color.Gray); c.Y < 128 {
+				img.SetGray(quiet+x, quiet+y, color.Gray{})
+			}
+		}
+	}
+	return img
+}
+
+// rotate returns `img` rotated by `angle` degrees around its center, on a
+// white background.
+func rotate(img *image.Gray, angle float64) *image.Gray {
+	b := img.Bounds()
+	size := int(math.Hypot(float64(b.Dx()), float64(b.Dy()))) + 2
+	out := image.NewGray(image.Rect(0, 0, size, size))
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	cx, cy := float64(b.Dx())/2, float64(b.Dy())/2
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dx, dy := float64(x)-float64(size)/2, float64(y)-float64(size)/2
+			sx := int(math.Floor(cx + dx*cos + dy*sin))
+			sy := int(math.Floor(cy - dx*sin + dy*cos))
+			v := uint8(255)
+			if image.Pt(sx, sy).In(b) {
+				v = img.GrayAt(sx, sy).Y
+			}
+			out.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+	return out
+}
+
+// readOne reads `img` and checks that it contains the single barcode `value`.
+func readOne(t *testing.T, img image.Image, format barcode.Format, value string) *barcode.Barcode {
+	barcodes := barcode.ReadImage(img, barcode.Options{})
+	require.Len(t, barcodes, 1)
+	assert.Equal(t, format, barcodes[0].Format)
+	assert.Equal(t, value, barcodes[0].Value)
+	return barcodes[0]
+}
+
+func TestReadLinear(t *testing.T) {
+	testcases := []struct {
+		name   string
+		format barcode.Format
+		value  string
+		encode func(string) (bc.Barcode, error)
+	}{
+		{"code128", barcode.FormatCode128, "unipdf-1234 Test", func(s string) (bc.Barcode, error) { return code128.Encode(s) }},
+		{"code128C", barcode.FormatCode128, "20191231", func(s string) (bc.Barcode, error) { return code128.Encode(s) }},
+		{"code39", barcode.FormatCode39, "SEPARATOR-42", func(s string) (bc.Barcode, error) { return code39.Encode(s, false, false) }},
+		{"itf", barcode.FormatITF, "12345670", func(s string) (bc.Barcode, error) { return twooffive.Encode(s, true) }},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, err := tc.encode(tc.value)
+			require.NoError(t, err)
+			img := drawCode(code, 2, 30, 60)
+
+			b := readOne(t, img, tc.format, tc.value)
+			assert.InDelta(t, 30, b.Bounds.Min.X, 1)
+			assert.InDelta(t, img.Bounds().Dx()-30, b.Bounds.Max.X, 1)
+
+			// Upside down and vertical barcodes.
+			readOne(t, rotate(img, 180), tc.format, tc.value)
+			readOne(t, rotate(img, 90), tc.format, tc.value)
+
+			// Only the requested formats are read.
+			assert.Empty(t, barcode.ReadImage(img, barcode.Options{Formats: []barcode.Format{barcode.FormatQR}}))
+		})
+	}
+}
+
+func TestCode39Alphabet(t *testing.T) {
+	code, err := code39.Encode("0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ-. $/+%", false, false)
+	require.NoError(t, err)
+	readOne(t, drawCode(code, 2, 40, 30), barcode.FormatCode39, "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ-. $/+%")
+}
+
+func TestReadQR(t *testing.T) {
+	long := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 8)
+	testcases := []struct {
+		name   string
+		value  string
+		level  qr.ErrorCorrectionLevel
+		mode   qr.Encoding
+		angle  float64
+		module int
+	}{
+		{"byte", "https://unidoc.io/unipdf", qr.M, qr.Auto, 0, 4},
+		{"numeric", "0123456789012345", qr.H, qr.Numeric, 0, 3},
+		{"alphanumeric", "SEPARATOR SHEET 42", qr.Q, qr.AlphaNumeric, 180, 4},
+		{"unicode", "Größe: 42 €", qr.L, qr.Unicode, 90, 4},
+		{"large", long, qr.L, qr.Auto, 0, 3},
+		{"rotated", "rotated by 20 degrees", qr.M, qr.Auto, 20, 5},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, err := qr.Encode(tc.value, tc.level, tc.mode)
+			require.NoError(t, err)
+			img := drawCode(code, tc.module, 40, 0)
+			if tc.angle != 0 {
+				img = rotate(img, tc.angle)
+			}
+			readOne(t, img, barcode.FormatQR, tc.value)
+		})
+	}
+}
+
+func TestReadQRDamaged(t *testing.T) {
+	code, err := qr.Encode("error correction", qr.H, qr.Auto)
+	require.NoError(t, err)
+	img := drawCode(code, 4, 40, 0)
+
+	// Invert a block of modules in the data area.
+	for y := 40 + 4*12; y < 40+4*16; y++ {
+		for x := 40 + 4*10; x < 40+4*13; x++ {
+			img.Pix[y*img.Stride+x] = 255 - img.Pix[y*img.Stride+x]
+		}
+	}
+	b := readOne(t, img, barcode.FormatQR, "error correction")
+	assert.Equal(t, image.Rect(40, 40, 40+4*code.Bounds().Dx(), 40+4*code.Bounds().Dy()), b.Bounds)
+}
+
+func TestReadDataMatrix(t *testing.T) {
+	testcases := []struct {
+		name  string
+		value string
+		angle float64
+	}{
+		{"small", "unipdf", 0},
+		{"digits", "1234567890", 90},
+		{"regions", strings.Repeat("DataMatrix ", 6), 0},
+		{"blocks", strings.Repeat("Interleaved blocks. ", 9), 270},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, err := datamatrix.Encode(tc.value)
+			require.NoError(t, err)
+			img := drawCode(code, 4, 20, 0)
+			if tc.angle != 0 {
+				img = rotate(img, tc.angle)
+			}
+			readOne(t, img, barcode.FormatDataMatrix, tc.value)
+		})
+	}
+}
+
+func TestReadMultiple(t *testing.T) {
+	qrCode, err := qr.Encode("first", qr.M, qr.Auto)
+	require.NoError(t, err)
+	dmCode, err := datamatrix.Encode("second")
+	require.NoError(t, err)
+	linear, err := code128.Encode("third")
+	require.NoError(t, err)
+
+	img := image.NewGray(image.Rect(0, 0, 600, 500))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	paste := func(src *image.Gray, x, y int) {
+		b := src.Bounds()
+		for sy := 0; sy < b.Dy(); sy++ {
+			copy(img.Pix[(y+sy)*img.Stride+x:], src.Pix[sy*src.Stride:sy*src.Stride+b.Dx()])
+		}
+	}
+	paste(drawCode(qrCode, 4, 20, 0), 300, 20)
+	paste(drawCode(dmCode, 5, 20, 0), 20, 40)
+	paste(drawCode(linear, 2, 30, 60), 50, 300)
+
+	barcodes := barcode.ReadImage(img, barcode.Options{})
+	require.Len(t, barcodes, 3)
+	assert.Equal(t, "first", barcodes[0].Value)
+	assert.Equal(t, "second", barcodes[1].Value)
+	assert.Equal(t, "third", barcodes[2].Value)
+}
@@ -0,0 +1,104 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package barcode
+
+import (
+	"image"
+	"unicode/utf8"
+)
+
+// bitMatrix is a black and white image. The dark pixels are set.
+type bitMatrix struct {
+	width, height int
+	bits          []bool
+}
+
+// newBitMatrix returns the matrix of the dark pixels of the binary image `img`.
+func newBitMatrix(img *image.Gray) *bitMatrix {
+	b := img.Bounds()
+	m := &bitMatrix{
+		width:  b.Dx(),
+		height: b.Dy(),
+		bits:   make([]bool, b.Dx()*b.Dy()),
+	}
+	for y := 0; y < m.height; y++ {
+		row := img.Pix[y*img.Stride : y*img.Stride+m.width]
+		for x, v := range row {
+			m.bits[y*m.width+x] = v < 128
+		}
+	}
+	return m
+}
+
+// get reports whether the pixel (x, y) is dark. The pixels outside of the
+// matrix are light.
+func (m *bitMatrix) get(x, y int) bool {
+	if x < 0 || y < 0 || x >= m.width || y >= m.height {
+		return false
+	}
+	return m.bits[y*m.width+x]
+}
+
+// row returns the pixels of the row `y`.
+func (m *bitMatrix) row(y int) []bool {
+	return m.bits[y*m.width : (y+1)*m.width]
+}
+
+// column returns the pixels of the column `x`.
+func (m *bitMatrix) column(x int) []bool {
+	col := make([]bool, m.height)
+	for y := range col {
+		col[y] = m.bits[y*m.width+x]
+	}
+	return col
+}
+
+// transpose returns the matrix flipped along its diagonal.
+func (m *bitMatrix) transpose() *bitMatrix {
+	t := &bitMatrix{width: m.height, height: m.width, bits: make([]bool, len(m.bits))}
+	for y := 0; y < m.height; y++ {
+		for x := 0; x < m.width; x++ {
+			t.bits[x*t.width+y] = m.bits[y*m.width+x]
+		}
+	}
+	return t
+}
+
+// crop returns the part of the matrix within `r`.
+func (m *bitMatrix) crop(r image.Rectangle) *bitMatrix {
+	c := &bitMatrix{width: r.Dx(), height: r.Dy(), bits: make([]bool, r.Dx()*r.Dy())}
+	for y := 0; y < c.height; y++ {
+		for x := 0; x < c.width; x++ {
+			c.bits[y*c.width+x] = m.get(r.Min.X+x, r.Min.Y+y)
+		}
+	}
+	return c
+}
+
+// rotate returns the matrix rotated by 90 degrees counterclockwise.
+func (m *bitMatrix) rotate() *bitMatrix {
+	r := &bitMatrix{width: m.height, height: m.width, bits: make([]bool, len(m.bits))}
+	for y := 0; y < m.height; y++ {
+		for x := 0; x < m.width; x++ {
+			r.bits[(m.width-1-x)*r.width+y] = m.bits[y*m.width+x]
+		}
+	}
+	return r
+}
+
+// decodeBytes returns the text of the bytes decoded from a 2D code. The bytes
+// are UTF-8 if `isUTF8` is true or if they are valid UTF-8, and ISO-8859-1
+// otherwise.
+func decodeBytes(data []byte, isUTF8 bool) string {
+	if isUTF8 || utf8.Valid(data) {
+		return string(data)
+	}
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
@@ -0,0 +1,222 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package barcode
+
+import (
+	"math"
+	"strings"
+)
+
+// code128Patterns are the widths in modules of the bars and spaces of the
+// Code 128 symbols. The stop symbol 106 has a final 2 modules bar.
+var code128Patterns = [107][]int{
+	{2, 1, 2, 2, 2, 2}, {2, 2, 2, 1, 2, 2}, {2, 2, 2, 2, 2, 1}, {1, 2, 1, 2, 2, 3},
+	{1, 2, 1, 3, 2, 2}, {1, 3, 1, 2, 2, 2}, {1, 2, 2, 2, 1, 3}, {1, 2, 2, 3, 1, 2},
+	{1, 3, 2, 2, 1, 2}, {2, 2, 1, 2, 1, 3}, {2, 2, 1, 3, 1, 2}, {2, 3, 1, 2, 1, 2},
+	{1, 1, 2, 2, 3, 2}, {1, 2, 2, 1, 3, 2}, {1, 2, 2, 2, 3, 1}, {1, 1, 3, 2, 2, 2},
+	{1, 2, 3, 1, 2, 2}, {1, 2, 3, 2, 2, 1}, {2, 2, 3, 2, 1, 1}, {2, 2, 1, 1, 3, 2},
+	{2, 2, 1, 2, 3, 1}, {2, 1, 3, 2, 1, 2}, {2, 2, 3, 1, 1, 2}, {3, 1, 2, 1, 3, 1},
+	{3, 1, 1, 2, 2, 2}, {3, 2, 1, 1, 2, 2}, {3, 2, 1, 2, 2, 1}, {3, 1, 2, 2, 1, 2},
+	{3, 2, 2, 1, 1, 2}, {3, 2, 2, 2, 1, 1}, {2, 1, 2, 1, 2, 3}, {2, 1, 2, 3, 2, 1},
+	{2, 3, 2, 1, 2, 1}, {1, 1, 1, 3, 2, 3}, {1, 3, 1, 1, 2, 3}, {1, 3, 1, 3, 2, 1},
+	{1, 1, 2, 3, 1, 3}, {1, 3, 2, 1, 1, 3}, {1, 3, 2, 3, 1, 1}, {2, 1, 1, 3, 1, 3},
+	{2, 3, 1, 1, 1, 3}, {2, 3, 1, 3, 1, 1}, {1, 1, 2, 1, 3, 3}, {1, 1, 2, 3, 3, 1},
+	{1, 3, 2, 1, 3, 1}, {1, 1, 3, 1, 2, 3}, {1, 1, 3, 3, 2, 1}, {1, 3, 3, 1, 2, 1},
+	{3, 1, 3, 1, 2, 1}, {2, 1, 1, 3, 3, 1}, {2, 3, 1, 1, 3, 1}, {2, 1, 3, 1, 1, 3},
+	{2, 1, 3, 3, 1, 1}, {2, 1, 3, 1, 3, 1}, {3, 1, 1, 1, 2, 3}, {3, 1, 1, 3, 2, 1},
+	{3, 3, 1, 1, 2, 1}, {3, 1, 2, 1, 1, 3}, {3, 1, 2, 3, 1, 1}, {3, 3, 2, 1, 1, 1},
+	{3, 1, 4, 1, 1, 1}, {2, 2, 1, 4, 1, 1}, {4, 3, 1, 1, 1, 1}, {1, 1, 1, 2, 2, 4},
+	{1, 1, 1, 4, 2, 2}, {1, 2, 1, 1, 2, 4}, {1, 2, 1, 4, 2, 1}, {1, 4, 1, 1, 2, 2},
+	{1, 4, 1, 2, 2, 1}, {1, 1, 2, 2, 1, 4}, {1, 1, 2, 4, 1, 2}, {1, 2, 2, 1, 1, 4},
+	{1, 2, 2, 4, 1, 1}, {1, 4, 2, 1, 1, 2}, {1, 4, 2, 2, 1, 1}, {2, 4, 1, 2, 1, 1},
+	{2, 2, 1, 1, 1, 4}, {4, 1, 3, 1, 1, 1}, {2, 4, 1, 1, 1, 2}, {1, 3, 4, 1, 1, 1},
+	{1, 1, 1, 2, 4, 2}, {1, 2, 1, 1, 4, 2}, {1, 2, 1, 2, 4, 1}, {1, 1, 4, 2, 1, 2},
+	{1, 2, 4, 1, 1, 2}, {1, 2, 4, 2, 1, 1}, {4, 1, 1, 2, 1, 2}, {4, 2, 1, 1, 1, 2},
+	{4, 2, 1, 2, 1, 1}, {2, 1, 2, 1, 4, 1}, {2, 1, 4, 1, 2, 1}, {4, 1, 2, 1, 2, 1},
+	{1, 1, 1, 1, 4, 3}, {1, 1, 1, 3, 4, 1}, {1, 3, 1, 1, 4, 1}, {1, 1, 4, 1, 1, 3},
+	{1, 1, 4, 3, 1, 1}, {4, 1, 1, 1, 1, 3}, {4, 1, 1, 3, 1, 1}, {1, 1, 3, 1, 4, 1},
+	{1, 1, 4, 1, 3, 1}, {3, 1, 1, 1, 4, 1}, {4, 1, 1, 1, 3, 1}, {2, 1, 1, 4, 1, 2},
+	{2, 1, 1, 2, 1, 4}, {2, 1, 1, 2, 3, 2}, {2, 3, 3, 1, 1, 1, 2},
+}
+
+// Code 128 special symbols.
+const (
+	code128FNC1   = 102
+	code128StartA = 103
+	code128StartB = 104
+	code128StartC = 105
+	code128Stop   = 106
+)
+
+const (
+	code128MaxAvgVariance        = 0.25
+	code128MaxIndividualVariance = 0.7
+)
+
+// decodeCode128Symbol returns the symbol whose pattern matches best the first
+// six runs of `counters`.
+func decodeCode128Symbol(counters []int) (int, bool) {
+	best, bestVariance := -1, code128MaxAvgVariance
+	for code, pattern := range code128Patterns {
+		v := patternVariance(counters, pattern[:6], code128MaxIndividualVariance)
+		if v < bestVariance {
+			best, bestVariance = code, v
+		}
+	}
+	return best, best >= 0
+}
+
+// decodeCode128 decodes the Code 128 barcode starting at the dark run `i`.
+func decodeCode128(runs []int, i int) (string, int, bool) {
+	if i+6 > len(runs) {
+		return "", 0, false
+	}
+	start, ok := decodeCode128Symbol(runs[i : i+6])
+	if !ok || start < code128StartA || start > code128StartC {
+		return "", 0, false
+	}
+	// The quiet zone must be at least half as wide as the start symbol.
+	if 2*runs[i-1] < sumInts(runs[i:i+6]) {
+		return "", 0, false
+	}
+
+	codes := []int{start}
+	j := i + 6
+	for {
+		if j+6 > len(runs) {
+			return "", 0, false
+		}
+		code, ok := decodeCode128Symbol(runs[j : j+6])
+		if !ok {
+			return "", 0, false
+		}
+		codes = append(codes, code)
+		if code != code128Stop {
+			if code >= code128StartA {
+				return "", 0, false
+			}
+			j += 6
+			continue
+		}
+
+		// The stop symbol ends with a 2 modules bar and is followed by a quiet zone.
+		if j+6 >= len(runs) {
+			return "", 0, false
+		}
+		unit := float64(sumInts(runs[j:j+6])) / 11
+		if math.Abs(float64(runs[j+6])-2*unit) > code128MaxIndividualVariance*unit {
+			return "", 0, false
+		}
+		if j+7 < len(runs) && 2*float64(runs[j+7]) < 13*unit {
+			return "", 0, false
+		}
+		j += 6
+		break
+	}
+
+	// The start, the checksum and the stop symbols.
+	if len(codes) < 4 {
+		return "", 0, false
+	}
+	data := codes[1 : len(codes)-2]
+	sum := codes[0]
+	for k, code := range data {
+		sum += (k + 1) * code
+	}
+	if sum%103 != codes[len(codes)-2] {
+		return "", 0, false
+	}
+
+	value, ok := code128Text(codes[0], data)
+	if !ok {
+		return "", 0, false
+	}
+	return value, j, true
+}
+
+// code128Text returns the text of the data symbols `data` of a Code 128
+// barcode starting with the `start` symbol. The FNC1 symbols which are not
+// at the start of the data are decoded as the GS character.
+func code128Text(start int, data []int) (string, bool) {
+	const (
+		setA = iota
+		setB
+		setC
+	)
+	set := setA + start - code128StartA
+
+	var sb strings.Builder
+	shift := false
+	upper, upperLatched := false, false
+	write := func(c int) {
+		if upper != upperLatched {
+			c += 128
+		}
+		upper = false
+		sb.WriteRune(rune(c))
+	}
+
+	for k, code := range data {
+		current := set
+		if shift {
+			current = setA + setB - set
+			shift = false
+		}
+		switch current {
+		case setA, setB:
+			switch {
+			case code < 64:
+				write(code + ' ')
+			case code < 96:
+				if current == setA {
+					write(code - 64)
+				} else {
+					write(code + ' ')
+				}
+			case code == 96 || code == 97:
+				// FNC3 and FNC2.
+			case code == 98:
+				shift = true
+			case code == 99:
+				set = setC
+			case code == 100 && current == setA || code == 101 && current == setB:
+				set = setB + setA - current
+			case code == 100 || code == 101:
+				// FNC4 shifts the next character to the extended ASCII range,
+				// two FNC4 in a row latch the extended range.
+				if upper {
+					upper = false
+					upperLatched = !upperLatched
+				} else {
+					upper = true
+				}
+			case code == code128FNC1:
+				if k > 0 {
+					sb.WriteByte(0x1d)
+				}
+			default:
+				return "", false
+			}
+		case setC:
+			switch {
+			case code < 100:
+				sb.WriteByte(byte('0' + code/10))
+				sb.WriteByte(byte('0' + code%10))
+			case code == 100:
+				set = setB
+			case code == 101:
+				set = setA
+			case code == code128FNC1:
+				if k > 0 {
+					sb.WriteByte(0x1d)
+				}
+			default:
+				return "", false
+			}
+		}
+	}
+	return sb.String(), true
+}
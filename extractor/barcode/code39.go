@@ -0,0 +1,117 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package barcode
+
+import (
+	"math"
+	"strings"
+)
+
+// code39Alphabet are the characters of Code 39, encoded by code39Patterns.
+const code39Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ-. $/+%"
+
+// code39Patterns are the Code 39 characters: the bits of the 9 bars and
+// spaces, from the first bar, are set for the wide elements.
+var code39Patterns = [...]int{
+	0x034, 0x121, 0x061, 0x160, 0x031, 0x130, 0x070, 0x025, 0x124, 0x064,
+	0x109, 0x049, 0x148, 0x019, 0x118, 0x058, 0x00D, 0x10C, 0x04C, 0x01C,
+	0x103, 0x043, 0x142, 0x013, 0x112, 0x052, 0x007, 0x106, 0x046, 0x016,
+	0x181, 0x0C1, 0x1C0, 0x091, 0x190, 0x0D0, 0x085, 0x184, 0x0C4, 0x0A8,
+	0x0A2, 0x08A, 0x02A,
+}
+
+// code39Asterisk is the pattern of the start and stop character.
+const code39Asterisk = 0x094
+
+// code39Pattern returns the wide elements pattern of the 9 runs `counters`,
+// or -1 if the runs are not made of 3 wide and 6 narrow elements.
+func code39Pattern(counters []int) int {
+	maxNarrow := 0
+	for {
+		// The narrow elements are the ones not wider than maxNarrow.
+		next := math.MaxInt32
+		for _, c := range counters {
+			if c < next && c > maxNarrow {
+				next = c
+			}
+		}
+		if next == math.MaxInt32 {
+			return -1
+		}
+		maxNarrow = next
+
+		pattern, wide, wideWidth := 0, 0, 0
+		for k, c := range counters {
+			if c > maxNarrow {
+				pattern |= 1 << uint(len(counters)-1-k)
+				wide++
+				wideWidth += c
+			}
+		}
+		if wide == 3 {
+			// A wide element cannot be as wide as the two others together.
+			for _, c := range counters {
+				if c > maxNarrow && 2*c >= wideWidth {
+					return -1
+				}
+			}
+			return pattern
+		}
+		if wide < 3 {
+			return -1
+		}
+	}
+}
+
+// decodeCode39 decodes the Code 39 barcode starting at the dark run `i`.
+// The check character is not verified, nor the full ASCII sequences decoded.
+func decodeCode39(runs []int, i int) (string, int, bool) {
+	if i+9 > len(runs) || code39Pattern(runs[i:i+9]) != code39Asterisk {
+		return "", 0, false
+	}
+	width := sumInts(runs[i : i+9])
+	// The quiet zone must be at least half as wide as a character.
+	if 2*runs[i-1] < width {
+		return "", 0, false
+	}
+
+	var sb strings.Builder
+	j := i + 10
+	for {
+		if j+9 > len(runs) {
+			return "", 0, false
+		}
+		// The gap between the characters is narrow.
+		if runs[j-1] > width/4 {
+			return "", 0, false
+		}
+		pattern := code39Pattern(runs[j : j+9])
+		if pattern == code39Asterisk {
+			break
+		}
+		c := -1
+		for k, p := range code39Patterns {
+			if p == pattern {
+				c = k
+				break
+			}
+		}
+		if c < 0 {
+			return "", 0, false
+		}
+		sb.WriteByte(code39Alphabet[c])
+		j += 10
+	}
+
+	end := j + 8
+	if end+1 < len(runs) && 2*runs[end+1] < sumInts(runs[j:j+9]) {
+		return "", 0, false
+	}
+	if sb.Len() == 0 {
+		return "", 0, false
+	}
+	return sb.String(), end, true
+}
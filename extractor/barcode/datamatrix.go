@@ -0,0 +1,666 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package barcode
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"sort"
+
+	"github.com/TheLinker/unipdf/v3/common"
+)
+
+// dataMatrixSize is an ECC 200 DataMatrix symbol size.
+type dataMatrixSize struct {
+	rows, cols int
+	// regionRows and regionCols are the dimensions of the data regions.
+	regionRows, regionCols int
+	// ecCount is the number of error correction codewords, split in blocks.
+	ecCount, blocks int
+}
+
+// dataMatrixSizes are the square and the rectangular DataMatrix sizes.
+var dataMatrixSizes = []dataMatrixSize{
+	{10, 10, 8, 8, 5, 1}, {12, 12, 10, 10, 7, 1}, {14, 14, 12, 12, 10, 1},
+	{16, 16, 14, 14, 12, 1}, {18, 18, 16, 16, 14, 1}, {20, 20, 18, 18, 18, 1},
+	{22, 22, 20, 20, 20, 1}, {24, 24, 22, 22, 24, 1}, {26, 26, 24, 24, 28, 1},
+	{32, 32, 14, 14, 36, 1}, {36, 36, 16, 16, 42, 1}, {40, 40, 18, 18, 48, 1},
+	{44, 44, 20, 20, 56, 1}, {48, 48, 22, 22, 68, 1}, {52, 52, 24, 24, 84, 2},
+	{64, 64, 14, 14, 112, 2}, {72, 72, 16, 16, 144, 4}, {80, 80, 18, 18, 192, 4},
+	{88, 88, 20, 20, 224, 4}, {96, 96, 22, 22, 272, 4}, {104, 104, 24, 24, 336, 6},
+	{120, 120, 18, 18, 408, 6}, {132, 132, 20, 20, 496, 8}, {144, 144, 22, 22, 620, 10},
+	{8, 18, 6, 16, 7, 1}, {8, 32, 6, 14, 11, 1}, {12, 26, 10, 24, 14, 1},
+	{12, 36, 10, 16, 18, 1}, {16, 36, 14, 16, 24, 1}, {16, 48, 14, 22, 28, 1},
+}
+
+// readDataMatrices returns the DataMatrix symbols found in `m`. The symbols
+// are searched among the connected dark areas, which contain the solid L
+// shaped finder pattern of the symbols.
+func readDataMatrices(m *bitMatrix) []*Barcode {
+	var barcodes []*Barcode
+	for _, r := range darkComponents(m, 8) {
+		w, h := r.Dx(), r.Dy()
+		if w > 4*h || h > 4*w {
+			continue
+		}
+		c := m.crop(r)
+		for rotation := 0; rotation < 4; rotation++ {
+			if hasFinderL(c) {
+				value, err := readDataMatrix(c)
+				if err == nil {
+					barcodes = append(barcodes, &Barcode{Format: FormatDataMatrix, Value: value, Bounds: r})
+					break
+				}
+				common.Log.Debug("DataMatrix not decoded: %v", err)
+			}
+			c = c.rotate()
+		}
+	}
+	return barcodes
+}
+
+// darkComponents returns the bounding boxes of the 8-connected dark areas of
+// `m` which are at least `minSize` pixels wide and high.
+func darkComponents(m *bitMatrix, minSize int) []image.Rectangle {
+	visited := make([]bool, len(m.bits))
+	var boxes []image.Rectangle
+	var stack []int
+	for start, dark := range m.bits {
+		if !dark || visited[start] {
+			continue
+		}
+		visited[start] = true
+		stack = append(stack[:0], start)
+		box := image.Rect(start%m.width, start/m.width, start%m.width+1, start/m.width+1)
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := i%m.width, i/m.width
+			if x < box.Min.X {
+				box.Min.X = x
+			} else if x >= box.Max.X {
+				box.Max.X = x + 1
+			}
+			if y >= box.Max.Y {
+				box.Max.Y = y + 1
+			}
+			for ny := y - 1; ny <= y+1; ny++ {
+				for nx := x - 1; nx <= x+1; nx++ {
+					if nx < 0 || ny < 0 || nx >= m.width || ny >= m.height {
+						continue
+					}
+					j := ny*m.width + nx
+					if m.bits[j] && !visited[j] {
+						visited[j] = true
+						stack = append(stack, j)
+					}
+				}
+			}
+		}
+		if box.Dx() >= minSize && box.Dy() >= minSize {
+			boxes = append(boxes, box)
+		}
+	}
+	return boxes
+}
+
+// lineCoverage returns the ratio of the dark pixels of the row `y` of `c`, or of
+// its column `x` if `y` is negative.
+func lineCoverage(c *bitMatrix, x, y int) float64 {
+	dark, n := 0, c.width
+	if y < 0 {
+		n = c.height
+	}
+	for i := 0; i < n; i++ {
+		if y < 0 && c.get(x, i) || y >= 0 && c.get(i, y) {
+			dark++
+		}
+	}
+	return float64(dark) / float64(n)
+}
+
+// hasFinderL reports whether the left and bottom edges of `c` are solid and
+// its top and right edges are alternating, like the edges of a DataMatrix
+// symbol.
+func hasFinderL(c *bitMatrix) bool {
+	inset := 1 + minInt(c.width, c.height)/40
+	var left, bottom, top, right float64
+	for i := 0; i <= inset; i++ {
+		left = maxFloat(left, lineCoverage(c, i, -1))
+		bottom = maxFloat(bottom, lineCoverage(c, 0, c.height-1-i))
+		top = maxFloat(top, lineCoverage(c, 0, i))
+		right = maxFloat(right, lineCoverage(c, c.width-1-i, -1))
+	}
+	return left > 0.9 && bottom > 0.9 && top > 0.25 && top < 0.75 && right > 0.25 && right < 0.75
+}
+
+// finderThickness returns the thickness of the solid edge of `c` along its
+// left edge, or along its bottom edge if `bottom` is true.
+func finderThickness(c *bitMatrix, bottom bool) int {
+	n := c.height
+	if bottom {
+		n = c.width
+	}
+	var thickness []int
+	for i := 0; i < n; i++ {
+		t := 0
+		for {
+			dark := c.get(t, i)
+			if bottom {
+				dark = c.get(i, c.height-1-t)
+			}
+			if !dark {
+				break
+			}
+			t++
+		}
+		if t > 0 {
+			thickness = append(thickness, t)
+		}
+	}
+	if len(thickness) == 0 {
+		return 0
+	}
+	// Most of the modules next to the edge are light or dark, the thinnest
+	// sections are the modules of the edge.
+	sort.Ints(thickness)
+	return thickness[len(thickness)/10]
+}
+
+// countTiming returns the number of modules of the timing pattern of `c`
+// along its top edge, or along its right edge if `right` is true, at
+// `offset` pixels from the edge.
+func countTiming(c *bitMatrix, offset int, right bool) int {
+	var line []bool
+	if right {
+		line = c.column(c.width - 1 - offset)
+	} else {
+		line = c.row(offset)
+	}
+	runs, _ := runLengths(line)
+	dark := 0
+	for i := 1; i < len(runs); i += 2 {
+		if runs[i] > 0 {
+			dark++
+		}
+	}
+	return 2 * dark
+}
+
+// readDataMatrix decodes the DataMatrix symbol `c`, whose solid edges are the
+// left and bottom edges.
+func readDataMatrix(c *bitMatrix) (string, error) {
+	mw, mh := finderThickness(c, false), finderThickness(c, true)
+	if mw == 0 || mh == 0 {
+		return "", errors.New("no finder pattern")
+	}
+	cols := countTiming(c, mh/2, false)
+	rows := countTiming(c, mw/2, true)
+
+	var size *dataMatrixSize
+	for i := range dataMatrixSizes {
+		if dataMatrixSizes[i].rows == rows && dataMatrixSizes[i].cols == cols {
+			size = &dataMatrixSizes[i]
+			break
+		}
+	}
+	if size == nil {
+		return "", fmt.Errorf("invalid DataMatrix size %dx%d", rows, cols)
+	}
+
+	// Sample the modules of the data regions.
+	regionsV := rows / (size.regionRows + 2)
+	regionsH := cols / (size.regionCols + 2)
+	data := &bitMatrix{
+		width:  regionsH * size.regionCols,
+		height: regionsV * size.regionRows,
+	}
+	data.bits = make([]bool, data.width*data.height)
+	for r := 0; r < data.height; r++ {
+		row := r/size.regionRows*(size.regionRows+2) + r%size.regionRows + 1
+		y := (float64(row) + 0.5) * float64(c.height) / float64(rows)
+		for col := 0; col < data.width; col++ {
+			symCol := col/size.regionCols*(size.regionCols+2) + col%size.regionCols + 1
+			x := (float64(symCol) + 0.5) * float64(c.width) / float64(cols)
+			data.bits[r*data.width+col] = c.get(int(x), int(y))
+		}
+	}
+
+	codewords := readDataMatrixCodewords(data)
+	corrected, err := dataMatrixDataCodewords(codewords, size)
+	if err != nil {
+		return "", err
+	}
+	return decodeDataMatrixBitstream(corrected)
+}
+
+// readDataMatrixCodewords reads the codewords of the data modules `m` in the
+// ECC 200 placement order.
+func readDataMatrixCodewords(m *bitMatrix) []int {
+	numRows, numCols := m.height, m.width
+	read := make([]bool, numRows*numCols)
+	module := func(row, col int) int {
+		if row < 0 {
+			row += numRows
+			col += 4 - (numRows+4)%8
+		}
+		if col < 0 {
+			col += numCols
+			row += 4 - (numCols+4)%8
+		}
+		read[row*numCols+col] = true
+		if m.get(col, row) {
+			return 1
+		}
+		return 0
+	}
+	codeword := func(positions [8][2]int) int {
+		v := 0
+		for _, p := range positions {
+			v = v<<1 | module(p[0], p[1])
+		}
+		return v
+	}
+	utah := func(row, col int) int {
+		return codeword([8][2]int{
+			{row - 2, col - 2}, {row - 2, col - 1}, {row - 1, col - 2}, {row - 1, col - 1},
+			{row - 1, col}, {row, col - 2}, {row, col - 1}, {row, col},
+		})
+	}
+	r, c := numRows-1, numCols-1
+	corners := [4][8][2]int{
+		{{r, 0}, {r, 1}, {r, 2}, {0, c - 1}, {0, c}, {1, c}, {2, c}, {3, c}},
+		{{r - 2, 0}, {r - 1, 0}, {r, 0}, {0, c - 3}, {0, c - 2}, {0, c - 1}, {0, c}, {1, c}},
+		{{r, 0}, {r, c}, {0, c - 2}, {0, c - 1}, {0, c}, {1, c - 2}, {1, c - 1}, {1, c}},
+		{{r - 2, 0}, {r - 1, 0}, {r, 0}, {0, c - 1}, {0, c}, {1, c}, {2, c}, {3, c}},
+	}
+
+	total := numRows * numCols / 8
+	var codewords []int
+	var cornerRead [4]bool
+	row, col := 4, 0
+	for (row < numRows || col < numCols) && len(codewords) < total {
+		corner := -1
+		switch {
+		case row == numRows && col == 0:
+			corner = 0
+		case row == numRows-2 && col == 0 && numCols%4 != 0:
+			corner = 1
+		case row == numRows+4 && col == 2 && numCols%8 == 0:
+			corner = 2
+		case row == numRows-2 && col == 0 && numCols%8 == 4:
+			corner = 3
+		}
+		if corner >= 0 && !cornerRead[corner] {
+			codewords = append(codewords, codeword(corners[corner]))
+			cornerRead[corner] = true
+			row -= 2
+			col += 2
+			continue
+		}
+
+		// Sweep upward diagonally to the right, then downward to the left.
+		for {
+			if row < numRows && col >= 0 && !read[row*numCols+col] {
+				codewords = append(codewords, utah(row, col))
+			}
+			row -= 2
+			col += 2
+			if row < 0 || col >= numCols {
+				break
+			}
+		}
+		row++
+		col += 3
+		for {
+			if row >= 0 && col < numCols && !read[row*numCols+col] {
+				codewords = append(codewords, utah(row, col))
+			}
+			row += 2
+			col -= 2
+			if row >= numRows || col < 0 {
+				break
+			}
+		}
+		row += 3
+		col++
+	}
+	return codewords
+}
+
+// dataMatrixDataCodewords returns the corrected data codewords of the
+// interleaved codewords `raw` of a symbol of size `size`.
+func dataMatrixDataCodewords(raw []int, size *dataMatrixSize) ([]byte, error) {
+	dataCount := len(raw) - size.ecCount
+	if dataCount <= 0 {
+		return nil, errors.New("invalid DataMatrix codewords")
+	}
+	blocks := make([][]int, size.blocks)
+	for i, cw := range raw {
+		blocks[i%size.blocks] = append(blocks[i%size.blocks], cw)
+	}
+	ecPerBlock := size.ecCount / size.blocks
+	for _, b := range blocks {
+		if err := rsCorrect(dataMatrixField, b, ecPerBlock); err != nil {
+			return nil, err
+		}
+	}
+	data := make([]byte, dataCount)
+	for i := range data {
+		data[i] = byte(blocks[i%size.blocks][i/size.blocks])
+	}
+	return data, nil
+}
+
+// DataMatrix encodation modes.
+const (
+	dmASCII = iota
+	dmC40
+	dmText
+	dmX12
+	dmEDIFACT
+	dmBase256
+)
+
+var errDataMatrixData = errors.New("invalid DataMatrix data")
+
+// dataMatrixShift2 are the characters of the shift 2 set of the C40 and Text
+// encodations.
+const dataMatrixShift2 = "!\"#$%&'()*+,-./:;<=>?@[\\]^_"
+
+// decodeDataMatrixBitstream decodes the data codewords `data`.
+func decodeDataMatrixBitstream(data []byte) (string, error) {
+	d := &dmDecoder{data: data}
+	var suffix string
+	mode := dmASCII
+	for d.pos < len(data) {
+		var err error
+		switch mode {
+		case dmASCII:
+			mode, suffix, err = d.decodeASCII(suffix)
+		case dmC40, dmText:
+			err = d.decodeC40(mode == dmText)
+			mode = dmASCII
+		case dmX12:
+			err = d.decodeX12()
+			mode = dmASCII
+		case dmEDIFACT:
+			d.decodeEDIFACT()
+			mode = dmASCII
+		case dmBase256:
+			err = d.decodeBase256()
+			mode = dmASCII
+		}
+		if err != nil {
+			return "", err
+		}
+		if d.done {
+			break
+		}
+	}
+	d.out = append(d.out, suffix...)
+	return decodeBytes(d.out, d.isUTF8), nil
+}
+
+// dmDecoder decodes the codewords of a DataMatrix symbol.
+type dmDecoder struct {
+	data   []byte
+	pos    int
+	out    []byte
+	upper  bool
+	isUTF8 bool
+	done   bool
+}
+
+// write appends the character `c`, shifted to the extended ASCII range after
+// an upper shift.
+func (d *dmDecoder) write(c int) {
+	if d.upper {
+		c += 128
+		d.upper = false
+	}
+	d.out = append(d.out, byte(c))
+}
+
+// decodeASCII decodes the ASCII encodation codewords until a latch to
+// another mode, which is returned.
+func (d *dmDecoder) decodeASCII(suffix string) (int, string, error) {
+	for d.pos < len(d.data) {
+		cw := int(d.data[d.pos])
+		d.pos++
+		switch {
+		case cw == 0:
+			return 0, "", errDataMatrixData
+		case cw <= 128:
+			d.write(cw - 1)
+		case cw == 129:
+			// Padding.
+			d.done = true
+			return dmASCII, suffix, nil
+		case cw <= 229:
+			v := cw - 130
+			d.out = append(d.out, byte('0'+v/10), byte('0'+v%10))
+		case cw == 230:
+			return dmC40, suffix, nil
+		case cw == 231:
+			return dmBase256, suffix, nil
+		case cw == 232:
+			// FNC1, the GS1 symbols start with it.
+			if d.pos > 1 {
+				d.out = append(d.out, 0x1d)
+			}
+		case cw == 233, cw == 234:
+			// Structured append and reader programming.
+		case cw == 235:
+			d.upper = true
+		case cw == 236:
+			d.out = append(d.out, "[)>\x1e05\x1d"...)
+			suffix = "\x1e\x04" + suffix
+		case cw == 237:
+			d.out = append(d.out, "[)>\x1e06\x1d"...)
+			suffix = "\x1e\x04" + suffix
+		case cw == 238:
+			return dmX12, suffix, nil
+		case cw == 239:
+			return dmText, suffix, nil
+		case cw == 240:
+			return dmEDIFACT, suffix, nil
+		case cw == 241:
+			eci := d.readECI()
+			d.isUTF8 = eci == 26
+		default:
+			// 254 is the end of the data in the ASCII mode.
+			if cw != 254 || d.pos != len(d.data) {
+				return 0, "", errDataMatrixData
+			}
+		}
+	}
+	return dmASCII, suffix, nil
+}
+
+// readECI reads the ECI designator following the ECI codeword.
+func (d *dmDecoder) readECI() int {
+	next := func() int {
+		if d.pos >= len(d.data) {
+			return 0
+		}
+		d.pos++
+		return int(d.data[d.pos-1])
+	}
+	c1 := next()
+	switch {
+	case c1 <= 127:
+		return c1 - 1
+	case c1 <= 191:
+		return (c1-128)*254 + next() - 1 + 127
+	}
+	c2, c3 := next(), next()
+	return (c1-192)*64516 + 16383 + (c2-1)*254 + c3 - 1
+}
+
+// readTriple reads three values of the C40, Text and X12 encodations. It
+// returns false at the unlatch codeword or at the end of the data.
+func (d *dmDecoder) readTriple() ([3]int, bool) {
+	if d.pos+2 > len(d.data) || d.data[d.pos] == 254 {
+		if d.pos < len(d.data) && d.data[d.pos] == 254 {
+			d.pos++
+		}
+		return [3]int{}, false
+	}
+	v := int(d.data[d.pos])<<8 + int(d.data[d.pos+1]) - 1
+	d.pos += 2
+	return [3]int{v / 1600, v % 1600 / 40, v % 40}, true
+}
+
+// decodeC40 decodes the C40 or Text encodation codewords.
+func (d *dmDecoder) decodeC40(text bool) error {
+	shift := 0
+	for {
+		values, ok := d.readTriple()
+		if !ok {
+			return nil
+		}
+		for _, v := range values {
+			switch shift {
+			case 0:
+				switch {
+				case v < 3:
+					shift = v + 1
+				case v == 3:
+					d.write(' ')
+				case v < 14:
+					d.write('0' + v - 4)
+				case text:
+					d.write('a' + v - 14)
+				default:
+					d.write('A' + v - 14)
+				}
+				continue
+			case 1:
+				d.write(v)
+			case 2:
+				switch {
+				case v < len(dataMatrixShift2):
+					d.write(int(dataMatrixShift2[v]))
+				case v == 27:
+					d.out = append(d.out, 0x1d)
+				case v == 30:
+					d.upper = true
+				default:
+					return errDataMatrixData
+				}
+			case 3:
+				switch {
+				case !text:
+					d.write(v + 96)
+				case v == 0:
+					d.write('`')
+				case v < 27:
+					d.write('A' + v - 1)
+				default:
+					d.write(v + 96)
+				}
+			}
+			shift = 0
+		}
+	}
+}
+
+// decodeX12 decodes the ANSI X12 encodation codewords.
+func (d *dmDecoder) decodeX12() error {
+	for {
+		values, ok := d.readTriple()
+		if !ok {
+			return nil
+		}
+		for _, v := range values {
+			switch {
+			case v == 0:
+				d.write('\r')
+			case v == 1:
+				d.write('*')
+			case v == 2:
+				d.write('>')
+			case v == 3:
+				d.write(' ')
+			case v < 14:
+				d.write('0' + v - 4)
+			case v < 40:
+				d.write('A' + v - 14)
+			default:
+				return errDataMatrixData
+			}
+		}
+	}
+}
+
+// decodeEDIFACT decodes the EDIFACT encodation codewords.
+func (d *dmDecoder) decodeEDIFACT() {
+	r := &bitReader{data: d.data, pos: 8 * d.pos}
+	defer func() {
+		// The unlatch is followed by the padding of the last codeword.
+		d.pos = (r.pos + 7) / 8
+	}()
+	for r.available() > 16 {
+		for i := 0; i < 4; i++ {
+			v, ok := r.read(6)
+			if !ok || v == 0x1f {
+				return
+			}
+			if v&0x20 == 0 {
+				v |= 0x40
+			}
+			d.write(v)
+		}
+	}
+}
+
+// decodeBase256 decodes the Base 256 encodation codewords.
+func (d *dmDecoder) decodeBase256() error {
+	next := func() (int, bool) {
+		if d.pos >= len(d.data) {
+			return 0, false
+		}
+		// The codewords are randomized with their position.
+		random := 149*(d.pos+1)%255 + 1
+		v := int(d.data[d.pos]) - random
+		if v < 0 {
+			v += 256
+		}
+		d.pos++
+		return v, true
+	}
+	count, ok := next()
+	if !ok {
+		return errDataMatrixData
+	}
+	switch {
+	case count == 0:
+		count = len(d.data) - d.pos
+	case count >= 250:
+		c2, ok := next()
+		if !ok {
+			return errDataMatrixData
+		}
+		count = 250*(count-249) + c2
+	}
+	for i := 0; i < count; i++ {
+		v, ok := next()
+		if !ok {
+			return errDataMatrixData
+		}
+		d.out = append(d.out, byte(v))
+	}
+	return nil
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
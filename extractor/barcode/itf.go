@@ -0,0 +1,95 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package barcode
+
+import (
+	"sort"
+	"strings"
+)
+
+// itfPatterns are the Interleaved 2 of 5 digits: the bits of the 5 elements,
+// from the first one, are set for the wide elements.
+var itfPatterns = [10]int{
+	0x06, 0x11, 0x09, 0x18, 0x05, 0x14, 0x0C, 0x03, 0x12, 0x0A,
+}
+
+// itfMinLength is the minimum number of digits of the ITF barcodes, which
+// prevents from reading the short sequences of lines as barcodes.
+const itfMinLength = 6
+
+// itfDigit returns the digit encoded by the 5 elements `counters`, the narrow
+// elements being about `narrow` pixels wide.
+func itfDigit(counters [5]int, narrow float64) (int, bool) {
+	sorted := counters
+	sort.Ints(sorted[:])
+	// Two wide elements, at least 1.5 times wider than the narrow ones.
+	if float64(sorted[2]) > 1.6*narrow || 2*sorted[3] < 3*sorted[2] {
+		return 0, false
+	}
+	pattern := 0
+	for k, c := range counters {
+		if c >= sorted[3] {
+			pattern |= 1 << uint(4-k)
+		}
+	}
+	for digit, p := range itfPatterns {
+		if p == pattern {
+			return digit, true
+		}
+	}
+	return 0, false
+}
+
+// decodeITF decodes the Interleaved 2 of 5 barcode starting at the dark run
+// `i`. The check digit is not verified.
+func decodeITF(runs []int, i int) (string, int, bool) {
+	if i+4 > len(runs) {
+		return "", 0, false
+	}
+	// The start pattern is made of four narrow elements, after a quiet zone
+	// of 10 narrow elements.
+	narrow := float64(sumInts(runs[i:i+4])) / 4
+	for _, r := range runs[i : i+4] {
+		if float64(r) < 0.5*narrow || float64(r) > 1.5*narrow {
+			return "", 0, false
+		}
+	}
+	if float64(runs[i-1]) < 8*narrow {
+		return "", 0, false
+	}
+
+	var sb strings.Builder
+	j := i + 4
+	for {
+		// The stop pattern is a wide bar, a narrow space and a narrow bar,
+		// followed by a quiet zone.
+		if j+3 <= len(runs) && float64(runs[j]) >= 1.5*narrow &&
+			float64(runs[j+1]) <= 1.5*narrow && float64(runs[j+2]) <= 1.5*narrow &&
+			(j+3 == len(runs) || float64(runs[j+3]) >= 8*narrow) {
+			break
+		}
+		if j+10 > len(runs) {
+			return "", 0, false
+		}
+		var bars, spaces [5]int
+		for k := 0; k < 5; k++ {
+			bars[k] = runs[j+2*k]
+			spaces[k] = runs[j+2*k+1]
+		}
+		d1, ok1 := itfDigit(bars, narrow)
+		d2, ok2 := itfDigit(spaces, narrow)
+		if !ok1 || !ok2 {
+			return "", 0, false
+		}
+		sb.WriteByte(byte('0' + d1))
+		sb.WriteByte(byte('0' + d2))
+		j += 10
+	}
+	if sb.Len() < itfMinLength {
+		return "", 0, false
+	}
+	return sb.String(), j + 2, true
+}
@@ -0,0 +1,205 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package barcode
+
+import (
+	"image"
+	"math"
+)
+
+// linearDecoder decodes the linear barcode starting at the dark run `i` of
+// `runs`. It returns the decoded value and the index of the last dark run of
+// the barcode.
+type linearDecoder func(runs []int, i int) (value string, end int, ok bool)
+
+// linearDecoders are the decoders of the linear formats.
+var linearDecoders = map[Format]linearDecoder{
+	FormatCode128: decodeCode128,
+	FormatCode39:  decodeCode39,
+	FormatITF:     decodeITF,
+}
+
+// runLengths returns the lengths of the alternating light and dark runs of
+// `pixels`, starting with a light run which is empty when the first pixel is
+// dark, and the offsets of the runs.
+func runLengths(pixels []bool) (runs, starts []int) {
+	dark := false
+	count := 0
+	start := 0
+	for i, p := range pixels {
+		if p == dark {
+			count++
+			continue
+		}
+		runs = append(runs, count)
+		starts = append(starts, start)
+		dark, count, start = p, 1, i
+	}
+	runs = append(runs, count)
+	starts = append(starts, start)
+	return runs, starts
+}
+
+// linearHit is a barcode decoded on a row or a column of the image.
+type linearHit struct {
+	format Format
+	value  string
+
+	// lo and hi are the pixel offsets of the barcode along the line.
+	lo, hi int
+}
+
+// scanLine decodes the barcodes of the line of pixels `pixels`, read in both
+// directions.
+func scanLine(pixels []bool, formats []Format) []linearHit {
+	hits := scanRuns(pixels, formats)
+
+	reversed := make([]bool, len(pixels))
+	for i, p := range pixels {
+		reversed[len(pixels)-1-i] = p
+	}
+	for _, hit := range scanRuns(reversed, formats) {
+		hit.lo, hit.hi = len(pixels)-hit.hi, len(pixels)-hit.lo
+		hits = append(hits, hit)
+	}
+	return hits
+}
+
+// scanRuns decodes the barcodes of the line of pixels `pixels`, read from its
+// start.
+func scanRuns(pixels []bool, formats []Format) []linearHit {
+	runs, starts := runLengths(pixels)
+	var hits []linearHit
+	for i := 1; i < len(runs); i += 2 {
+		for _, f := range formats {
+			value, end, ok := linearDecoders[f](runs, i)
+			if !ok {
+				continue
+			}
+			hits = append(hits, linearHit{
+				format: f,
+				value:  value,
+				lo:     starts[i],
+				hi:     starts[end] + runs[end],
+			})
+			i = end
+			break
+		}
+	}
+	return hits
+}
+
+// linearCluster groups the hits of a barcode on consecutive lines.
+type linearCluster struct {
+	linearHit
+	first, last int
+	count       int
+}
+
+// readLinear returns the linear barcodes of the formats `formats` found in
+// the rows and in the columns of `m`.
+func readLinear(m *bitMatrix, formats []Format) []*Barcode {
+	barcodes := scanLines(m, formats, false)
+	return append(barcodes, scanLines(m.transpose(), formats, true)...)
+}
+
+// scanLines returns the linear barcodes found in the rows of `m`. The bounds
+// of the barcodes are transposed if `transposed` is true.
+func scanLines(m *bitMatrix, formats []Format, transposed bool) []*Barcode {
+	step := 1 + m.height/600
+	if step > 4 {
+		step = 4
+	}
+	minCount := 2
+	if m.height < 4*step {
+		minCount = 1
+	}
+
+	var clusters []*linearCluster
+	for y := step / 2; y < m.height; y += step {
+		for _, hit := range scanLine(m.row(y), formats) {
+			var found *linearCluster
+			for _, c := range clusters {
+				if c.format == hit.format && c.value == hit.value && y-c.last <= 3*step &&
+					hit.lo < c.hi && hit.hi > c.lo {
+					found = c
+					break
+				}
+			}
+			if found == nil {
+				clusters = append(clusters, &linearCluster{linearHit: hit, first: y, last: y, count: 1})
+				continue
+			}
+			found.lo = minInt(found.lo, hit.lo)
+			found.hi = maxInt(found.hi, hit.hi)
+			found.last = y
+			found.count++
+		}
+	}
+
+	var barcodes []*Barcode
+	for _, c := range clusters {
+		if c.count < minCount {
+			continue
+		}
+		bounds := image.Rect(c.lo, c.first, c.hi, c.last+1)
+		if transposed {
+			bounds = image.Rect(c.first, c.lo, c.last+1, c.hi)
+		}
+		barcodes = append(barcodes, &Barcode{Format: c.format, Value: c.value, Bounds: bounds})
+	}
+	return barcodes
+}
+
+// patternVariance returns the average variance of the widths of the runs
+// `counters` from the widths of the modules of `pattern`, relative to the
+// total width. It returns +Inf if the variance of one of the runs exceeds
+// `maxIndividualVariance` modules.
+func patternVariance(counters, pattern []int, maxIndividualVariance float64) float64 {
+	total, modules := 0, 0
+	for i, c := range counters {
+		total += c
+		modules += pattern[i]
+	}
+	if total < modules {
+		return math.Inf(1)
+	}
+	unit := float64(total) / float64(modules)
+	maxIndividualVariance *= unit
+
+	variance := 0.0
+	for i, c := range counters {
+		v := math.Abs(float64(c) - float64(pattern[i])*unit)
+		if v > maxIndividualVariance {
+			return math.Inf(1)
+		}
+		variance += v
+	}
+	return variance / float64(total)
+}
+
+// sumInts returns the sum of `values`.
+func sumInts(values []int) int {
+	sum := 0
+	for _, v := range values {
+		sum += v
+	}
+	return sum
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
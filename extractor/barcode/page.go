@@ -0,0 +1,124 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package barcode
+
+import (
+	"image"
+	"math"
+
+	"github.com/TheLinker/unipdf/v3/common"
+	"github.com/TheLinker/unipdf/v3/extractor"
+	"github.com/TheLinker/unipdf/v3/model"
+	"github.com/TheLinker/unipdf/v3/render"
+)
+
+// defaultResolution is the resolution in DPI of the pages rendered by ReadPage.
+const defaultResolution = 200
+
+// PageBarcode is a barcode found on a page.
+type PageBarcode struct {
+	Barcode
+
+	// BBox is the bounding box of the barcode in the page coordinates.
+	BBox model.PdfRectangle
+}
+
+// ReadPage renders `page` with render.ImageDevice at the resolution of `opts`
+// and returns the barcodes found on the rendered image. The Bounds of the
+// barcodes are in the pixels of the rendered image.
+func ReadPage(page *model.PdfPage, opts Options) ([]*PageBarcode, error) {
+	mbox, err := page.GetMediaBox()
+	if err != nil {
+		return nil, err
+	}
+	resolution := opts.Resolution
+	if resolution <= 0 {
+		resolution = defaultResolution
+	}
+
+	// The device renders the page from the origin of the page coordinates
+	// and crops the rendered image to the crop box.
+	width, height := mbox.Llx+mbox.Width(), mbox.Lly+mbox.Height()
+	device := render.NewImageDevice()
+	device.OutputWidth = int(math.Round(width * resolution / 72))
+	img, err := device.Render(page)
+	if err != nil {
+		return nil, err
+	}
+	scale := float64(device.OutputWidth) / width
+	var cropX, cropY float64
+	if box := page.CropBox; box != nil {
+		cropX = math.Floor(box.Llx * scale)
+		cropY = math.Floor((height - box.Ury) * scale)
+	}
+
+	toPage := func(x, y float64) (float64, float64) {
+		return (x + cropX) / scale, height - (y+cropY)/scale
+	}
+	return pageBarcodes(ReadImage(img, opts), toPage), nil
+}
+
+// ReadPageImages returns the barcodes found in the images drawn on `page`,
+// including the images of the form XObjects and the inline images. It is
+// faster than ReadPage for the scanned pages but it does not find the
+// barcodes drawn with vector graphics. The Bounds of the barcodes are in the
+// pixels of their images.
+func ReadPageImages(page *model.PdfPage, opts Options) ([]*PageBarcode, error) {
+	ext, err := extractor.New(page)
+	if err != nil {
+		return nil, err
+	}
+	images, err := ext.ExtractPageImages(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var barcodes []*PageBarcode
+	for _, mark := range images.Images {
+		img, err := mark.Image.ToGoImage()
+		if err != nil {
+			common.Log.Debug("ERROR: unable to convert image: %v", err)
+			continue
+		}
+
+		// The images are drawn in the unit square mapped to the page by the
+		// CTM, their first row at the top.
+		mark := mark
+		w, h := float64(img.Bounds().Dx()), float64(img.Bounds().Dy())
+		sin, cos := math.Sincos(-mark.Angle * math.Pi / 180)
+		toPage := func(x, y float64) (float64, float64) {
+			u := x / w * mark.Width
+			v := (1 - y/h) * mark.Height
+			return mark.X + u*cos - v*sin, mark.Y + u*sin + v*cos
+		}
+		barcodes = append(barcodes, pageBarcodes(ReadImage(img, opts), toPage)...)
+	}
+	return barcodes, nil
+}
+
+// pageBarcodes locates `barcodes` on the page with the `toPage` mapping of the
+// image pixels to the page coordinates.
+func pageBarcodes(barcodes []*Barcode, toPage func(x, y float64) (float64, float64)) []*PageBarcode {
+	result := make([]*PageBarcode, len(barcodes))
+	for i, b := range barcodes {
+		result[i] = &PageBarcode{Barcode: *b, BBox: pageRect(b.Bounds, toPage)}
+	}
+	return result
+}
+
+// pageRect returns the bounding box of the pixels `r` in the page coordinates.
+func pageRect(r image.Rectangle, toPage func(x, y float64) (float64, float64)) model.PdfRectangle {
+	bbox := model.PdfRectangle{
+		Llx: math.Inf(1), Lly: math.Inf(1),
+		Urx: math.Inf(-1), Ury: math.Inf(-1),
+	}
+	for _, p := range []image.Point{r.Min, {r.Max.X, r.Min.Y}, {r.Min.X, r.Max.Y}, r.Max} {
+		x, y := toPage(float64(p.X), float64(p.Y))
+		bbox.Llx, bbox.Urx = math.Min(bbox.Llx, x), math.Max(bbox.Urx, x)
+		bbox.Lly, bbox.Ury = math.Min(bbox.Lly, y), math.Max(bbox.Ury, y)
+	}
+	return bbox
+}
@@ -0,0 +1,370 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package barcode
+
+import (
+	"image"
+	"math"
+	"sort"
+
+	"github.com/TheLinker/unipdf/v3/common"
+)
+
+// finderPattern is a candidate QR code finder pattern: a dark square inside a
+// light ring inside a dark ring, whose sections have the 1:1:3:1:1 ratios.
+type finderPattern struct {
+	x, y float64
+	// size is the estimated module size, in pixels.
+	size  float64
+	count int
+}
+
+func (p *finderPattern) dist(q *finderPattern) float64 {
+	return math.Hypot(p.x-q.x, p.y-q.y)
+}
+
+// maxFinderCandidates limits the number of finder patterns combined in the
+// search of the QR codes.
+const maxFinderCandidates = 40
+
+// readQRCodes returns the QR codes found in `m`.
+func readQRCodes(m *bitMatrix) []*Barcode {
+	var barcodes []*Barcode
+	for _, triple := range qrFinderTriples(findFinderPatterns(m)) {
+		b, err := readQRCode(m, triple[0], triple[1], triple[2])
+		if err != nil {
+			common.Log.Debug("QR code not decoded: %v", err)
+			continue
+		}
+		barcodes = append(barcodes, b)
+	}
+	return barcodes
+}
+
+// finderRatio reports whether the widths `counts` of the dark, light, dark,
+// light and dark runs have the ratios of a finder pattern.
+func finderRatio(counts []int) bool {
+	total := sumInts(counts)
+	if total < 7 {
+		return false
+	}
+	module := float64(total) / 7
+	maxVariance := module / 2
+	return math.Abs(module-float64(counts[0])) < maxVariance &&
+		math.Abs(module-float64(counts[1])) < maxVariance &&
+		math.Abs(3*module-float64(counts[2])) < 3*maxVariance &&
+		math.Abs(module-float64(counts[3])) < maxVariance &&
+		math.Abs(module-float64(counts[4])) < maxVariance
+}
+
+// findFinderPatterns returns the finder patterns found in `m`, confirmed by
+// several rows.
+func findFinderPatterns(m *bitMatrix) []*finderPattern {
+	var patterns []*finderPattern
+	for y := 0; y < m.height; y++ {
+		runs, starts := runLengths(m.row(y))
+		for i := 1; i+5 <= len(runs); i += 2 {
+			counts := runs[i : i+5]
+			if !finderRatio(counts) {
+				continue
+			}
+			total := sumInts(counts)
+			cx := float64(starts[i+2]) + float64(runs[i+2])/2
+			cy, ok := crossCheck(m, int(cx), y, false, counts[2], total)
+			if !ok {
+				continue
+			}
+			cx, ok = crossCheck(m, int(cx), int(cy), true, counts[2], total)
+			if !ok {
+				continue
+			}
+			addFinderPattern(&patterns, cx, cy, float64(total)/7)
+		}
+	}
+
+	var confirmed []*finderPattern
+	for _, p := range patterns {
+		if p.count >= 2 {
+			confirmed = append(confirmed, p)
+		}
+	}
+	sort.SliceStable(confirmed, func(i, j int) bool {
+		return confirmed[i].count > confirmed[j].count
+	})
+	if len(confirmed) > maxFinderCandidates {
+		confirmed = confirmed[:maxFinderCandidates]
+	}
+	return confirmed
+}
+
+// addFinderPattern adds the pattern found at (x, y) to `patterns`, merging it
+// with the pattern already found at the same place.
+func addFinderPattern(patterns *[]*finderPattern, x, y, size float64) {
+	for _, p := range *patterns {
+		if math.Abs(y-p.y) <= size && math.Abs(x-p.x) <= size {
+			if d := math.Abs(size - p.size); d <= 1 || d <= p.size {
+				n := float64(p.count)
+				p.x = (n*p.x + x) / (n + 1)
+				p.y = (n*p.y + y) / (n + 1)
+				p.size = (n*p.size + size) / (n + 1)
+				p.count++
+				return
+			}
+		}
+	}
+	*patterns = append(*patterns, &finderPattern{x: x, y: y, size: size, count: 1})
+}
+
+// crossCheck checks the finder pattern ratios along the column `x` around the
+// row `y`, or along the row `y` around the column `x` if `horizontal` is true,
+// and returns the coordinate of the center of the pattern along that line.
+func crossCheck(m *bitMatrix, x, y int, horizontal bool, maxCount, originalTotal int) (float64, bool) {
+	pos, limit := y, m.height
+	get := func(p int) bool { return m.get(x, p) }
+	if horizontal {
+		pos, limit = x, m.width
+		get = func(p int) bool { return m.get(p, y) }
+	}
+
+	var counts [5]int
+	p := pos
+	for ; p >= 0 && get(p); p-- {
+		counts[2]++
+	}
+	for ; p >= 0 && !get(p) && counts[1] <= maxCount; p-- {
+		counts[1]++
+	}
+	for ; p >= 0 && get(p) && counts[0] <= maxCount; p-- {
+		counts[0]++
+	}
+	if counts[1] > maxCount || counts[0] > maxCount {
+		return 0, false
+	}
+
+	p = pos + 1
+	for ; p < limit && get(p); p++ {
+		counts[2]++
+	}
+	for ; p < limit && !get(p) && counts[3] <= maxCount; p++ {
+		counts[3]++
+	}
+	for ; p < limit && get(p) && counts[4] <= maxCount; p++ {
+		counts[4]++
+	}
+	if counts[3] > maxCount || counts[4] > maxCount {
+		return 0, false
+	}
+
+	total := sumInts(counts[:])
+	if 5*absInt(total-originalTotal) >= 2*originalTotal || !finderRatio(counts[:]) {
+		return 0, false
+	}
+	return float64(p-counts[4]-counts[3]) - float64(counts[2])/2, true
+}
+
+// qrFinderTriples returns the groups of three finder patterns which are
+// placed like the finder patterns of a QR code, ordered as the top left,
+// the top right and the bottom left patterns.
+func qrFinderTriples(patterns []*finderPattern) [][3]*finderPattern {
+	type candidate struct {
+		triple [3]*finderPattern
+		score  float64
+	}
+	var candidates []candidate
+	n := len(patterns)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			for k := j + 1; k < n; k++ {
+				if triple, score, ok := qrTriple(patterns[i], patterns[j], patterns[k]); ok {
+					candidates = append(candidates, candidate{triple, score})
+				}
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score < candidates[j].score
+	})
+
+	used := make(map[*finderPattern]bool)
+	var triples [][3]*finderPattern
+	for _, c := range candidates {
+		if used[c.triple[0]] || used[c.triple[1]] || used[c.triple[2]] {
+			continue
+		}
+		for _, p := range c.triple {
+			used[p] = true
+		}
+		triples = append(triples, c.triple)
+	}
+	return triples
+}
+
+// qrTriple checks whether the patterns `a`, `b` and `c` are at the corners of
+// a square and returns them ordered with a score, lower for the best squares.
+func qrTriple(a, b, c *finderPattern) ([3]*finderPattern, float64, bool) {
+	var triple [3]*finderPattern
+	minSize := math.Min(a.size, math.Min(b.size, c.size))
+	maxSize := math.Max(a.size, math.Max(b.size, c.size))
+	if maxSize > 1.5*minSize {
+		return triple, 0, false
+	}
+
+	// The top left pattern is the one opposite the longest side.
+	ab, bc, ca := a.dist(b), b.dist(c), c.dist(a)
+	topLeft, p1, p2 := c, a, b
+	hyp, leg1, leg2 := ab, bc, ca
+	if bc >= ab && bc >= ca {
+		topLeft, p1, p2 = a, b, c
+		hyp, leg1, leg2 = bc, ab, ca
+	} else if ca >= ab && ca >= bc {
+		topLeft, p1, p2 = b, c, a
+		hyp, leg1, leg2 = ca, ab, bc
+	}
+
+	legs := leg1*leg1 + leg2*leg2
+	legsDiff := math.Abs(leg1-leg2) / math.Max(leg1, leg2)
+	angleDiff := math.Abs(hyp*hyp-legs) / legs
+	size := (a.size + b.size + c.size) / 3
+	if legsDiff > 0.15 || angleDiff > 0.15 || leg1 < 10*size || leg1 > 180*size {
+		return triple, 0, false
+	}
+
+	// In the image coordinates, the bottom left pattern is on the left of the
+	// top right pattern seen from the top left pattern.
+	if (p2.x-topLeft.x)*(p1.y-topLeft.y)-(p2.y-topLeft.y)*(p1.x-topLeft.x) < 0 {
+		p1, p2 = p2, p1
+	}
+	triple = [3]*finderPattern{topLeft, p2, p1}
+	return triple, legsDiff + angleDiff + (maxSize-minSize)/maxSize, true
+}
+
+// qrGrid maps the modules of a QR code to the image pixels.
+type qrGrid struct {
+	origin, dx, dy [2]float64
+}
+
+// newQRGrid returns the grid of a QR code of dimension `dim` whose finder
+// patterns are `tl`, `tr` and `bl`.
+func newQRGrid(tl, tr, bl *finderPattern, dim int) *qrGrid {
+	span := float64(dim - 7)
+	g := &qrGrid{
+		dx: [2]float64{(tr.x - tl.x) / span, (tr.y - tl.y) / span},
+		dy: [2]float64{(bl.x - tl.x) / span, (bl.y - tl.y) / span},
+	}
+	// The centers of the finder patterns are 3.5 modules from the edges.
+	g.origin = [2]float64{
+		tl.x - 3.5*(g.dx[0]+g.dy[0]),
+		tl.y - 3.5*(g.dx[1]+g.dy[1]),
+	}
+	return g
+}
+
+// point returns the image coordinates of the point (u, v) of the grid, in
+// modules.
+func (g *qrGrid) point(u, v float64) (float64, float64) {
+	return g.origin[0] + u*g.dx[0] + v*g.dy[0], g.origin[1] + u*g.dx[1] + v*g.dy[1]
+}
+
+// sample returns the modules of the grid of dimension `dim` in `m`.
+func (g *qrGrid) sample(m *bitMatrix, dim int) *bitMatrix {
+	s := &bitMatrix{width: dim, height: dim, bits: make([]bool, dim*dim)}
+	for row := 0; row < dim; row++ {
+		for col := 0; col < dim; col++ {
+			x, y := g.point(float64(col)+0.5, float64(row)+0.5)
+			s.bits[row*dim+col] = m.get(int(math.Floor(x)), int(math.Floor(y)))
+		}
+	}
+	return s
+}
+
+// bounds returns the bounding box of the grid of dimension `dim`.
+func (g *qrGrid) bounds(dim int) image.Rectangle {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	d := float64(dim)
+	for _, c := range [][2]float64{{0, 0}, {d, 0}, {0, d}, {d, d}} {
+		x, y := g.point(c[0], c[1])
+		minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+		minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+	}
+	return image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY)))
+}
+
+// timingScore returns the ratio of the modules of the timing patterns of the
+// sampled QR code `s` which have the expected color.
+func timingScore(s *bitMatrix) float64 {
+	dim := s.width
+	good := 0
+	for i := 8; i < dim-8; i++ {
+		dark := i%2 == 0
+		if s.get(i, 6) == dark {
+			good++
+		}
+		if s.get(6, i) == dark {
+			good++
+		}
+	}
+	return float64(good) / float64(2*(dim-16))
+}
+
+// readQRCode decodes the QR code whose finder patterns are `tl`, `tr` and
+// `bl`.
+func readQRCode(m *bitMatrix, tl, tr, bl *finderPattern) (*Barcode, error) {
+	size := (tl.size + tr.size + bl.size) / 3
+	dim := (int(math.Round(tl.dist(tr)/size))+int(math.Round(tl.dist(bl)/size)))/2 + 7
+	// The dimensions of the QR codes are 17 + 4*version.
+	dim = 17 + 4*int(math.Round(float64(dim-17)/4))
+
+	// The module size estimated from the finder patterns is not accurate
+	// enough for the large codes: the dimensions around the estimate are
+	// tried, starting from the one with the best timing patterns.
+	type attempt struct {
+		dim    int
+		grid   *qrGrid
+		sample *bitMatrix
+		score  float64
+	}
+	var attempts []attempt
+	for d := dim - 8; d <= dim+8; d += 4 {
+		if d < 21 || d > 177 {
+			continue
+		}
+		g := newQRGrid(tl, tr, bl, d)
+		s := g.sample(m, d)
+		attempts = append(attempts, attempt{d, g, s, timingScore(s)})
+	}
+	sort.SliceStable(attempts, func(i, j int) bool {
+		return attempts[i].score > attempts[j].score
+	})
+
+	var err error
+	for _, a := range attempts {
+		if a.score < 0.7 {
+			break
+		}
+		var value string
+		value, err = decodeQRMatrix(a.sample)
+		if err != nil {
+			// The mirrored codes have their modules transposed.
+			var errMirror error
+			if value, errMirror = decodeQRMatrix(a.sample.transpose()); errMirror != nil {
+				continue
+			}
+		}
+		return &Barcode{Format: FormatQR, Value: value, Bounds: a.grid.bounds(a.dim)}, nil
+	}
+	if err == nil {
+		err = errQRFormat
+	}
+	return nil, err
+}
+
+func absInt(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
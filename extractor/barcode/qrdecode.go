@@ -0,0 +1,507 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package barcode
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"strings"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+// qrBlocks are the error correction blocks of the QR codes for each version
+// and each error correction level (L, M, Q, H): the number of error
+// correction codewords per block, followed by the number of blocks and of data
+// codewords per block of the two groups of blocks.
+var qrBlocks = [40][4][5]int{
+	{{7, 1, 19, 0, 0}, {10, 1, 16, 0, 0}, {13, 1, 13, 0, 0}, {17, 1, 9, 0, 0}},                // 1
+	{{10, 1, 34, 0, 0}, {16, 1, 28, 0, 0}, {22, 1, 22, 0, 0}, {28, 1, 16, 0, 0}},              // 2
+	{{15, 1, 55, 0, 0}, {26, 1, 44, 0, 0}, {18, 2, 17, 0, 0}, {22, 2, 13, 0, 0}},              // 3
+	{{20, 1, 80, 0, 0}, {18, 2, 32, 0, 0}, {26, 2, 24, 0, 0}, {16, 4, 9, 0, 0}},               // 4
+	{{26, 1, 108, 0, 0}, {24, 2, 43, 0, 0}, {18, 2, 15, 2, 16}, {22, 2, 11, 2, 12}},           // 5
+	{{18, 2, 68, 0, 0}, {16, 4, 27, 0, 0}, {24, 4, 19, 0, 0}, {28, 4, 15, 0, 0}},              // 6
+	{{20, 2, 78, 0, 0}, {18, 4, 31, 0, 0}, {18, 2, 14, 4, 15}, {26, 4, 13, 1, 14}},            // 7
+	{{24, 2, 97, 0, 0}, {22, 2, 38, 2, 39}, {22, 4, 18, 2, 19}, {26, 4, 14, 2, 15}},           // 8
+	{{30, 2, 116, 0, 0}, {22, 3, 36, 2, 37}, {20, 4, 16, 4, 17}, {24, 4, 12, 4, 13}},          // 9
+	{{18, 2, 68, 2, 69}, {26, 4, 43, 1, 44}, {24, 6, 19, 2, 20}, {28, 6, 15, 2, 16}},          // 10
+	{{20, 4, 81, 0, 0}, {30, 1, 50, 4, 51}, {28, 4, 22, 4, 23}, {24, 3, 12, 8, 13}},           // 11
+	{{24, 2, 92, 2, 93}, {22, 6, 36, 2, 37}, {26, 4, 20, 6, 21}, {28, 7, 14, 4, 15}},          // 12
+	{{26, 4, 107, 0, 0}, {22, 8, 37, 1, 38}, {24, 8, 20, 4, 21}, {22, 12, 11, 4, 12}},         // 13
+	{{30, 3, 115, 1, 116}, {24, 4, 40, 5, 41}, {20, 11, 16, 5, 17}, {24, 11, 12, 5, 13}},      // 14
+	{{22, 5, 87, 1, 88}, {24, 5, 41, 5, 42}, {30, 5, 24, 7, 25}, {24, 11, 12, 7, 13}},         // 15
+	{{24, 5, 98, 1, 99}, {28, 7, 45, 3, 46}, {24, 15, 19, 2, 20}, {30, 3, 15, 13, 16}},        // 16
+	{{28, 1, 107, 5, 108}, {28, 10, 46, 1, 47}, {28, 1, 22, 15, 23}, {28, 2, 14, 17, 15}},     // 17
+	{{30, 5, 120, 1, 121}, {26, 9, 43, 4, 44}, {28, 17, 22, 1, 23}, {28, 2, 14, 19, 15}},      // 18
+	{{28, 3, 113, 4, 114}, {26, 3, 44, 11, 45}, {26, 17, 21, 4, 22}, {26, 9, 13, 16, 14}},     // 19
+	{{28, 3, 107, 5, 108}, {26, 3, 41, 13, 42}, {30, 15, 24, 5, 25}, {28, 15, 15, 10, 16}},    // 20
+	{{28, 4, 116, 4, 117}, {26, 17, 42, 0, 0}, {28, 17, 22, 6, 23}, {30, 19, 16, 6, 17}},      // 21
+	{{28, 2, 111, 7, 112}, {28, 17, 46, 0, 0}, {30, 7, 24, 16, 25}, {24, 34, 13, 0, 0}},       // 22
+	{{30, 4, 121, 5, 122}, {28, 4, 47, 14, 48}, {30, 11, 24, 14, 25}, {30, 16, 15, 14, 16}},   // 23
+	{{30, 6, 117, 4, 118}, {28, 6, 45, 14, 46}, {30, 11, 24, 16, 25}, {30, 30, 16, 2, 17}},    // 24
+	{{26, 8, 106, 4, 107}, {28, 8, 47, 13, 48}, {30, 7, 24, 22, 25}, {30, 22, 15, 13, 16}},    // 25
+	{{28, 10, 114, 2, 115}, {28, 19, 46, 4, 47}, {28, 28, 22, 6, 23}, {30, 33, 16, 4, 17}},    // 26
+	{{30, 8, 122, 4, 123}, {28, 22, 45, 3, 46}, {30, 8, 23, 26, 24}, {30, 12, 15, 28, 16}},    // 27
+	{{30, 3, 117, 10, 118}, {28, 3, 45, 23, 46}, {30, 4, 24, 31, 25}, {30, 11, 15, 31, 16}},   // 28
+	{{30, 7, 116, 7, 117}, {28, 21, 45, 7, 46}, {30, 1, 23, 37, 24}, {30, 19, 15, 26, 16}},    // 29
+	{{30, 5, 115, 10, 116}, {28, 19, 47, 10, 48}, {30, 15, 24, 25, 25}, {30, 23, 15, 25, 16}}, // 30
+	{{30, 13, 115, 3, 116}, {28, 2, 46, 29, 47}, {30, 42, 24, 1, 25}, {30, 23, 15, 28, 16}},   // 31
+	{{30, 17, 115, 0, 0}, {28, 10, 46, 23, 47}, {30, 10, 24, 35, 25}, {30, 19, 15, 35, 16}},   // 32
+	{{30, 17, 115, 1, 116}, {28, 14, 46, 21, 47}, {30, 29, 24, 19, 25}, {30, 11, 15, 46, 16}}, // 33
+	{{30, 13, 115, 6, 116}, {28, 14, 46, 23, 47}, {30, 44, 24, 7, 25}, {30, 59, 16, 1, 17}},   // 34
+	{{30, 12, 121, 7, 122}, {28, 12, 47, 26, 48}, {30, 39, 24, 14, 25}, {30, 22, 15, 41, 16}}, // 35
+	{{30, 6, 121, 14, 122}, {28, 6, 47, 34, 48}, {30, 46, 24, 10, 25}, {30, 2, 15, 64, 16}},   // 36
+	{{30, 17, 122, 4, 123}, {28, 29, 46, 14, 47}, {30, 49, 24, 10, 25}, {30, 24, 15, 46, 16}}, // 37
+	{{30, 4, 122, 18, 123}, {28, 13, 46, 32, 47}, {30, 48, 24, 14, 25}, {30, 42, 15, 32, 16}}, // 38
+	{{30, 20, 117, 4, 118}, {28, 40, 47, 7, 48}, {30, 43, 24, 22, 25}, {30, 10, 15, 67, 16}},  // 39
+	{{30, 19, 118, 6, 119}, {28, 18, 47, 31, 48}, {30, 34, 24, 34, 25}, {30, 20, 15, 61, 16}}, // 40
+}
+
+// qrAlignmentCenters are the coordinates of the centers of the alignment
+// patterns for each version.
+var qrAlignmentCenters = [40][]int{
+	{}, {6, 18}, {6, 22}, {6, 26}, {6, 30}, {6, 34},
+	{6, 22, 38}, {6, 24, 42}, {6, 26, 46}, {6, 28, 50}, {6, 30, 54}, {6, 32, 58}, {6, 34, 62},
+	{6, 26, 46, 66}, {6, 26, 48, 70}, {6, 26, 50, 74}, {6, 30, 54, 78}, {6, 30, 56, 82},
+	{6, 30, 58, 86}, {6, 34, 62, 90},
+	{6, 28, 50, 72, 94}, {6, 26, 50, 74, 98}, {6, 30, 54, 78, 102}, {6, 28, 54, 80, 106},
+	{6, 32, 58, 84, 110}, {6, 30, 58, 86, 114}, {6, 34, 62, 90, 118},
+	{6, 26, 50, 74, 98, 122}, {6, 30, 54, 78, 102, 126}, {6, 26, 52, 78, 104, 130},
+	{6, 30, 56, 82, 108, 134}, {6, 34, 60, 86, 112, 138}, {6, 30, 58, 86, 114, 142},
+	{6, 34, 62, 90, 118, 146},
+	{6, 30, 54, 78, 102, 126, 150}, {6, 24, 50, 76, 102, 128, 154}, {6, 28, 54, 80, 106, 132, 158},
+	{6, 32, 58, 84, 110, 136, 162}, {6, 26, 54, 82, 110, 138, 166}, {6, 30, 58, 86, 114, 142, 170},
+}
+
+// qrECLevels maps the error correction level bits of the format information
+// to the index of the level in qrBlocks.
+var qrECLevels = [4]int{1, 0, 3, 2}
+
+// qrAlphanumeric are the characters of the alphanumeric mode.
+const qrAlphanumeric = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+var errQRFormat = errors.New("invalid QR code format information")
+
+// bchCode returns the BCH code of `value` for the generator polynomial `poly`
+// of degree `degree`.
+func bchCode(value, poly, degree int) int {
+	code := value << uint(degree)
+	for i := bits.Len(uint(code)) - 1; i >= degree; i-- {
+		if code&(1<<uint(i)) != 0 {
+			code ^= poly << uint(i-degree)
+		}
+	}
+	return value<<uint(degree) | code
+}
+
+// bestBCH returns the value among [min, max] whose code is the closest to one of
+// the codes read `read`, within 3 bits.
+func bestBCH(read []int, min, max int, code func(int) int) (int, bool) {
+	best, bestDistance := -1, 4
+	for v := min; v <= max; v++ {
+		c := code(v)
+		for _, r := range read {
+			if d := bits.OnesCount(uint(c ^ r)); d < bestDistance {
+				best, bestDistance = v, d
+			}
+		}
+	}
+	return best, best >= 0
+}
+
+// qrFormatCode returns the masked format information of the 5 bits `format`.
+func qrFormatCode(format int) int {
+	return bchCode(format, 0x537, 10) ^ 0x5412
+}
+
+// qrVersionCode returns the version information of `version`.
+func qrVersionCode(version int) int {
+	return bchCode(version, 0x1f25, 12)
+}
+
+// qrMasked reports whether the mask `mask` inverts the module at `row`, `col`.
+func qrMasked(mask, row, col int) bool {
+	switch mask {
+	case 0:
+		return (row+col)%2 == 0
+	case 1:
+		return row%2 == 0
+	case 2:
+		return col%3 == 0
+	case 3:
+		return (row+col)%3 == 0
+	case 4:
+		return (row/2+col/3)%2 == 0
+	case 5:
+		return (row*col)%2+(row*col)%3 == 0
+	case 6:
+		return ((row*col)%2+(row*col)%3)%2 == 0
+	}
+	return ((row+col)%2+(row*col)%3)%2 == 0
+}
+
+// qrFunctionPatterns returns the modules of the function patterns of a QR code
+// of version `version`, which do not hold data.
+func qrFunctionPatterns(version int) *bitMatrix {
+	dim := 17 + 4*version
+	m := &bitMatrix{width: dim, height: dim, bits: make([]bool, dim*dim)}
+	setRegion := func(left, top, width, height int) {
+		for y := top; y < top+height; y++ {
+			for x := left; x < left+width; x++ {
+				m.bits[y*dim+x] = true
+			}
+		}
+	}
+	// The finder patterns, their separators and the format information.
+	setRegion(0, 0, 9, 9)
+	setRegion(dim-8, 0, 8, 9)
+	setRegion(0, dim-8, 9, 8)
+
+	centers := qrAlignmentCenters[version-1]
+	n := len(centers)
+	for i, cy := range centers {
+		for j, cx := range centers {
+			if i == 0 && (j == 0 || j == n-1) || i == n-1 && j == 0 {
+				continue
+			}
+			setRegion(cx-2, cy-2, 5, 5)
+		}
+	}
+
+	// The timing patterns.
+	setRegion(6, 9, 1, dim-17)
+	setRegion(9, 6, dim-17, 1)
+
+	if version >= 7 {
+		setRegion(dim-11, 0, 3, 6)
+		setRegion(0, dim-11, 6, 3)
+	}
+	return m
+}
+
+// decodeQRMatrix decodes the modules `m` of a QR code.
+func decodeQRMatrix(m *bitMatrix) (string, error) {
+	dim := m.width
+	version := (dim - 17) / 4
+	if version < 1 || version > 40 || dim != 17+4*version {
+		return "", fmt.Errorf("invalid QR code dimension %d", dim)
+	}
+
+	// The two copies of the format information.
+	var f1, f2 int
+	copyBit := func(v int, x, y int) int {
+		if m.get(x, y) {
+			return v<<1 | 1
+		}
+		return v << 1
+	}
+	for i := 0; i < 6; i++ {
+		f1 = copyBit(f1, i, 8)
+	}
+	f1 = copyBit(f1, 7, 8)
+	f1 = copyBit(f1, 8, 8)
+	f1 = copyBit(f1, 8, 7)
+	for j := 5; j >= 0; j-- {
+		f1 = copyBit(f1, 8, j)
+	}
+	for j := dim - 1; j >= dim-7; j-- {
+		f2 = copyBit(f2, 8, j)
+	}
+	for i := dim - 8; i < dim; i++ {
+		f2 = copyBit(f2, i, 8)
+	}
+	format, ok := bestBCH([]int{f1, f2}, 0, 31, qrFormatCode)
+	if !ok {
+		return "", errQRFormat
+	}
+	level := qrECLevels[format>>3]
+	mask := format & 7
+
+	if version >= 7 {
+		var v1, v2 int
+		for j := 5; j >= 0; j-- {
+			for i := dim - 9; i >= dim-11; i-- {
+				v1 = copyBit(v1, i, j)
+			}
+		}
+		for i := 5; i >= 0; i-- {
+			for j := dim - 9; j >= dim-11; j-- {
+				v2 = copyBit(v2, i, j)
+			}
+		}
+		v, ok := bestBCH([]int{v1, v2}, 7, 40, qrVersionCode)
+		if !ok || v != version {
+			return "", errors.New("invalid QR code version information")
+		}
+	}
+
+	// Read the codewords in the zigzag order, skipping the function patterns.
+	function := qrFunctionPatterns(version)
+	var raw []int
+	current, count := 0, 0
+	up := true
+	for right := dim - 1; right > 0; right -= 2 {
+		if right == 6 {
+			right--
+		}
+		for k := 0; k < dim; k++ {
+			y := k
+			if up {
+				y = dim - 1 - k
+			}
+			for col := 0; col < 2; col++ {
+				x := right - col
+				if function.get(x, y) {
+					continue
+				}
+				current <<= 1
+				if m.get(x, y) != qrMasked(mask, y, x) {
+					current |= 1
+				}
+				count++
+				if count == 8 {
+					raw = append(raw, current)
+					current, count = 0, 0
+				}
+			}
+		}
+		up = !up
+	}
+
+	data, err := qrDataCodewords(raw, qrBlocks[version-1][level])
+	if err != nil {
+		return "", err
+	}
+	return decodeQRBitstream(data, version)
+}
+
+// qrDataCodewords returns the corrected data codewords of the interleaved
+// codewords `raw` split in the `blocks` blocks.
+func qrDataCodewords(raw []int, blocks [5]int) ([]byte, error) {
+	ecCount := blocks[0]
+	var dataCounts []int
+	for i := 0; i < blocks[1]; i++ {
+		dataCounts = append(dataCounts, blocks[2])
+	}
+	for i := 0; i < blocks[3]; i++ {
+		dataCounts = append(dataCounts, blocks[4])
+	}
+
+	codewords := make([][]int, len(dataCounts))
+	maxData := 0
+	for i, n := range dataCounts {
+		codewords[i] = make([]int, n+ecCount)
+		if n > maxData {
+			maxData = n
+		}
+	}
+	pos := 0
+	next := func() int {
+		if pos >= len(raw) {
+			return 0
+		}
+		pos++
+		return raw[pos-1]
+	}
+	for k := 0; k < maxData; k++ {
+		for i, n := range dataCounts {
+			if k < n {
+				codewords[i][k] = next()
+			}
+		}
+	}
+	for k := 0; k < ecCount; k++ {
+		for i, n := range dataCounts {
+			codewords[i][n+k] = next()
+		}
+	}
+
+	var data []byte
+	for i, n := range dataCounts {
+		if err := rsCorrect(qrField, codewords[i], ecCount); err != nil {
+			return nil, err
+		}
+		for _, c := range codewords[i][:n] {
+			data = append(data, byte(c))
+		}
+	}
+	return data, nil
+}
+
+// bitReader reads the bits of a byte stream, from the most significant bits.
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bitReader) available() int {
+	return 8*len(r.data) - r.pos
+}
+
+func (r *bitReader) read(n int) (int, bool) {
+	if n > r.available() {
+		return 0, false
+	}
+	v := 0
+	for i := 0; i < n; i++ {
+		b := r.data[r.pos/8] >> uint(7-r.pos%8) & 1
+		v = v<<1 | int(b)
+		r.pos++
+	}
+	return v, true
+}
+
+// qrCountBits returns the size of the character count of the segments of the
+// mode `mode` for the version `version`.
+func qrCountBits(mode, version int) int {
+	sizes := map[int][3]int{
+		1: {10, 12, 14}, // Numeric.
+		2: {9, 11, 13},  // Alphanumeric.
+		4: {8, 16, 16},  // Byte.
+		8: {8, 10, 12},  // Kanji.
+	}[mode]
+	switch {
+	case version < 10:
+		return sizes[0]
+	case version < 27:
+		return sizes[1]
+	}
+	return sizes[2]
+}
+
+var errQRData = errors.New("invalid QR code data")
+
+// decodeQRBitstream decodes the segments of the data codewords `data`.
+func decodeQRBitstream(data []byte, version int) (string, error) {
+	r := &bitReader{data: data}
+	var sb strings.Builder
+	isUTF8 := false
+	for r.available() >= 4 {
+		mode, _ := r.read(4)
+		switch mode {
+		case 0:
+			return sb.String(), nil
+		case 3:
+			// Structured append.
+			if _, ok := r.read(16); !ok {
+				return "", errQRData
+			}
+			continue
+		case 5:
+			// FNC1 in first position.
+			continue
+		case 9:
+			// FNC1 in second position.
+			if _, ok := r.read(8); !ok {
+				return "", errQRData
+			}
+			continue
+		case 7:
+			eci, ok := readQRECI(r)
+			if !ok {
+				return "", errQRData
+			}
+			isUTF8 = eci == 26
+			continue
+		case 1, 2, 4, 8:
+		default:
+			return "", fmt.Errorf("unsupported QR code mode %d", mode)
+		}
+
+		count, ok := r.read(qrCountBits(mode, version))
+		if !ok {
+			return "", errQRData
+		}
+		switch mode {
+		case 1:
+			for ; count >= 3; count -= 3 {
+				v, ok := r.read(10)
+				if !ok || v >= 1000 {
+					return "", errQRData
+				}
+				fmt.Fprintf(&sb, "%03d", v)
+			}
+			if count > 0 {
+				n, digits := 4, "%01d"
+				if count == 2 {
+					n, digits = 7, "%02d"
+				}
+				v, ok := r.read(n)
+				if !ok {
+					return "", errQRData
+				}
+				fmt.Fprintf(&sb, digits, v)
+			}
+		case 2:
+			for ; count >= 2; count -= 2 {
+				v, ok := r.read(11)
+				if !ok || v >= 45*45 {
+					return "", errQRData
+				}
+				sb.WriteByte(qrAlphanumeric[v/45])
+				sb.WriteByte(qrAlphanumeric[v%45])
+			}
+			if count > 0 {
+				v, ok := r.read(6)
+				if !ok || v >= 45 {
+					return "", errQRData
+				}
+				sb.WriteByte(qrAlphanumeric[v])
+			}
+		case 4:
+			buf := make([]byte, count)
+			for i := range buf {
+				v, ok := r.read(8)
+				if !ok {
+					return "", errQRData
+				}
+				buf[i] = byte(v)
+			}
+			sb.WriteString(decodeBytes(buf, isUTF8))
+		case 8:
+			buf := make([]byte, 0, 2*count)
+			for i := 0; i < count; i++ {
+				v, ok := r.read(13)
+				if !ok {
+					return "", errQRData
+				}
+				c := (v/0xc0)<<8 | v%0xc0
+				if c < 0x1f00 {
+					c += 0x8140
+				} else {
+					c += 0xc140
+				}
+				buf = append(buf, byte(c>>8), byte(c))
+			}
+			text, err := japanese.ShiftJIS.NewDecoder().Bytes(buf)
+			if err != nil {
+				return "", err
+			}
+			sb.Write(text)
+		}
+	}
+	return sb.String(), nil
+}
+
+// readQRECI reads the designator of an ECI segment.
+func readQRECI(r *bitReader) (int, bool) {
+	first, ok := r.read(8)
+	if !ok {
+		return 0, false
+	}
+	switch {
+	case first&0x80 == 0:
+		return first, true
+	case first&0xc0 == 0x80:
+		second, ok := r.read(8)
+		return (first&0x3f)<<8 | second, ok
+	case first&0xe0 == 0xc0:
+		rest, ok := r.read(16)
+		return (first&0x1f)<<16 | rest, ok
+	}
+	return 0, false
+}
@@ -0,0 +1,260 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package barcode
+
+import "errors"
+
+// errRSDecode is returned when the errors of a block cannot be corrected.
+var errRSDecode = errors.New("too many errors in the codewords")
+
+// galoisField is a GF(256) field used by the Reed-Solomon codes.
+type galoisField struct {
+	exp [256]int
+	log [256]int
+	// base is the exponent of the first root of the generator polynomial.
+	base int
+}
+
+// newGaloisField returns the field generated by the primitive polynomial
+// `primitive`.
+func newGaloisField(primitive, base int) *galoisField {
+	f := &galoisField{base: base}
+	x := 1
+	for i := 0; i < 256; i++ {
+		f.exp[i] = x
+		x <<= 1
+		if x >= 256 {
+			x ^= primitive
+		}
+	}
+	for i := 0; i < 255; i++ {
+		f.log[f.exp[i]] = i
+	}
+	return f
+}
+
+var (
+	// qrField is the field of the QR codes.
+	qrField = newGaloisField(0x011d, 0)
+	// dataMatrixField is the field of the DataMatrix symbols.
+	dataMatrixField = newGaloisField(0x012d, 1)
+)
+
+func (f *galoisField) mul(a, b int) int {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return f.exp[(f.log[a]+f.log[b])%255]
+}
+
+func (f *galoisField) inv(a int) int {
+	return f.exp[255-f.log[a]]
+}
+
+// gfPoly is a polynomial over a Galois field. The coefficients are stored from
+// the highest degree and there are no leading zero coefficients.
+type gfPoly struct {
+	f     *galoisField
+	coefs []int
+}
+
+func newGFPoly(f *galoisField, coefs []int) *gfPoly {
+	for len(coefs) > 1 && coefs[0] == 0 {
+		coefs = coefs[1:]
+	}
+	return &gfPoly{f: f, coefs: coefs}
+}
+
+// monomial returns the polynomial coef*x^degree.
+func monomial(f *galoisField, degree, coef int) *gfPoly {
+	if coef == 0 {
+		return newGFPoly(f, []int{0})
+	}
+	coefs := make([]int, degree+1)
+	coefs[0] = coef
+	return newGFPoly(f, coefs)
+}
+
+func (p *gfPoly) degree() int {
+	return len(p.coefs) - 1
+}
+
+func (p *gfPoly) isZero() bool {
+	return p.coefs[0] == 0
+}
+
+// coef returns the coefficient of the term of degree `degree`.
+func (p *gfPoly) coef(degree int) int {
+	return p.coefs[len(p.coefs)-1-degree]
+}
+
+// eval returns the value of the polynomial at `a`.
+func (p *gfPoly) eval(a int) int {
+	if a == 0 {
+		return p.coef(0)
+	}
+	result := 0
+	for _, c := range p.coefs {
+		result = p.f.mul(a, result) ^ c
+	}
+	return result
+}
+
+func (p *gfPoly) add(q *gfPoly) *gfPoly {
+	if p.isZero() {
+		return q
+	}
+	if q.isZero() {
+		return p
+	}
+	small, large := p.coefs, q.coefs
+	if len(small) > len(large) {
+		small, large = large, small
+	}
+	sum := make([]int, len(large))
+	diff := len(large) - len(small)
+	copy(sum, large[:diff])
+	for i := diff; i < len(large); i++ {
+		sum[i] = small[i-diff] ^ large[i]
+	}
+	return newGFPoly(p.f, sum)
+}
+
+func (p *gfPoly) mul(q *gfPoly) *gfPoly {
+	if p.isZero() || q.isZero() {
+		return newGFPoly(p.f, []int{0})
+	}
+	product := make([]int, len(p.coefs)+len(q.coefs)-1)
+	for i, a := range p.coefs {
+		for j, b := range q.coefs {
+			product[i+j] ^= p.f.mul(a, b)
+		}
+	}
+	return newGFPoly(p.f, product)
+}
+
+func (p *gfPoly) scale(degree, coef int) *gfPoly {
+	if coef == 0 {
+		return newGFPoly(p.f, []int{0})
+	}
+	product := make([]int, len(p.coefs)+degree)
+	for i, c := range p.coefs {
+		product[i] = p.f.mul(c, coef)
+	}
+	return newGFPoly(p.f, product)
+}
+
+// rsCorrect corrects in place the errors of the block `codewords` whose last
+// `ecCount` codewords are the error correction codewords.
+func rsCorrect(f *galoisField, codewords []int, ecCount int) error {
+	received := newGFPoly(f, codewords)
+	syndromes := make([]int, ecCount)
+	noError := true
+	for i := 0; i < ecCount; i++ {
+		s := received.eval(f.exp[(i+f.base)%255])
+		syndromes[ecCount-1-i] = s
+		if s != 0 {
+			noError = false
+		}
+	}
+	if noError {
+		return nil
+	}
+
+	sigma, omega, err := rsEuclidean(f, monomial(f, ecCount, 1), newGFPoly(f, syndromes), ecCount)
+	if err != nil {
+		return err
+	}
+	locations, err := rsErrorLocations(f, sigma)
+	if err != nil {
+		return err
+	}
+	magnitudes := rsErrorMagnitudes(f, omega, locations)
+	for i, loc := range locations {
+		pos := len(codewords) - 1 - f.log[loc]
+		if pos < 0 {
+			return errRSDecode
+		}
+		codewords[pos] ^= magnitudes[i]
+	}
+	return nil
+}
+
+// rsEuclidean returns the error locator and the error evaluator polynomials.
+func rsEuclidean(f *galoisField, a, b *gfPoly, r int) (*gfPoly, *gfPoly, error) {
+	if a.degree() < b.degree() {
+		a, b = b, a
+	}
+	rLast, rCur := a, b
+	tLast, tCur := newGFPoly(f, []int{0}), newGFPoly(f, []int{1})
+
+	for 2*rCur.degree() >= r {
+		rLastLast, tLastLast := rLast, tLast
+		rLast, tLast = rCur, tCur
+		if rLast.isZero() {
+			return nil, nil, errRSDecode
+		}
+		rCur = rLastLast
+		q := newGFPoly(f, []int{0})
+		dltInverse := f.inv(rLast.coef(rLast.degree()))
+		for rCur.degree() >= rLast.degree() && !rCur.isZero() {
+			diff := rCur.degree() - rLast.degree()
+			scale := f.mul(rCur.coef(rCur.degree()), dltInverse)
+			q = q.add(monomial(f, diff, scale))
+			rCur = rCur.add(rLast.scale(diff, scale))
+		}
+		tCur = q.mul(tLast).add(tLastLast)
+		if rCur.degree() >= rLast.degree() {
+			return nil, nil, errRSDecode
+		}
+	}
+
+	sigmaAtZero := tCur.coef(0)
+	if sigmaAtZero == 0 {
+		return nil, nil, errRSDecode
+	}
+	inverse := f.inv(sigmaAtZero)
+	return tCur.scale(0, inverse), rCur.scale(0, inverse), nil
+}
+
+// rsErrorLocations returns the inverses of the roots of the error locator.
+func rsErrorLocations(f *galoisField, locator *gfPoly) ([]int, error) {
+	n := locator.degree()
+	if n == 1 {
+		return []int{locator.coef(1)}, nil
+	}
+	var locations []int
+	for i := 1; i < 256 && len(locations) < n; i++ {
+		if locator.eval(i) == 0 {
+			locations = append(locations, f.inv(i))
+		}
+	}
+	if len(locations) != n {
+		return nil, errRSDecode
+	}
+	return locations, nil
+}
+
+// rsErrorMagnitudes returns the values of the errors at `locations` (Forney).
+func rsErrorMagnitudes(f *galoisField, evaluator *gfPoly, locations []int) []int {
+	magnitudes := make([]int, len(locations))
+	for i, loc := range locations {
+		xiInverse := f.inv(loc)
+		denominator := 1
+		for j, other := range locations {
+			if i == j {
+				continue
+			}
+			term := f.mul(other, xiInverse)
+			denominator = f.mul(denominator, term^1)
+		}
+		magnitudes[i] = f.mul(evaluator.eval(xiInverse), f.inv(denominator))
+		if f.base != 0 {
+			magnitudes[i] = f.mul(magnitudes[i], xiInverse)
+		}
+	}
+	return magnitudes
+}
@@ -0,0 +1,108 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package barcode
+
+import (
+	"io"
+
+	"github.com/TheLinker/unipdf/v3/model"
+)
+
+// SplitOptions define how the separator pages are found by Split.
+type SplitOptions struct {
+	Options
+
+	// IsSeparator reports whether the barcode `b` marks a separator page.
+	// If not set, all the pages with a barcode are separator pages.
+	IsSeparator func(b *PageBarcode) bool
+
+	// KeepSeparators keeps the separator pages as the first pages of the
+	// sections. The separator pages are dropped by default.
+	KeepSeparators bool
+
+	// FromImages reads the barcodes from the images drawn on the pages with
+	// ReadPageImages instead of rendering the pages with ReadPage, which is
+	// faster for the scanned documents.
+	FromImages bool
+}
+
+// Section is a part of a document split at its separator pages.
+type Section struct {
+	// Separator are the barcodes of the separator page starting the section,
+	// accepted by IsSeparator. It is empty for the pages before the first
+	// separator page.
+	Separator []*PageBarcode
+
+	// Pages are the numbers of the pages of the section, starting from 1.
+	Pages []int
+}
+
+// Split splits the document `reader` at its separator pages, the pages with a
+// barcode accepted by opts.IsSeparator, typically the separator sheets of the
+// scanned batches of documents. The sections without pages are omitted.
+func Split(reader *model.PdfReader, opts SplitOptions) ([]*Section, error) {
+	numPages, err := reader.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+
+	var sections []*Section
+	current := &Section{}
+	for num := 1; num <= numPages; num++ {
+		page, err := reader.GetPage(num)
+		if err != nil {
+			return nil, err
+		}
+		var barcodes []*PageBarcode
+		if opts.FromImages {
+			barcodes, err = ReadPageImages(page, opts.Options)
+		} else {
+			barcodes, err = ReadPage(page, opts.Options)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var separator []*PageBarcode
+		for _, b := range barcodes {
+			if opts.IsSeparator == nil || opts.IsSeparator(b) {
+				separator = append(separator, b)
+			}
+		}
+		if len(separator) == 0 {
+			current.Pages = append(current.Pages, num)
+			continue
+		}
+
+		if len(current.Pages) > 0 {
+			sections = append(sections, current)
+		}
+		current = &Section{Separator: separator}
+		if opts.KeepSeparators {
+			current.Pages = append(current.Pages, num)
+		}
+	}
+	if len(current.Pages) > 0 {
+		sections = append(sections, current)
+	}
+	return sections, nil
+}
+
+// Write writes the pages of the section, read from `reader`, to `w` as a new
+// document.
+func (s *Section) Write(reader *model.PdfReader, w io.Writer) error {
+	writer := model.NewPdfWriter()
+	for _, num := range s.Pages {
+		page, err := reader.GetPage(num)
+		if err != nil {
+			return err
+		}
+		if err := writer.AddPage(page); err != nil {
+			return err
+		}
+	}
+	return writer.Write(w)
+}
@@ -0,0 +1,104 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package barcode_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/boombuler/barcode/qr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TheLinker/unipdf/v3/creator"
+	"github.com/TheLinker/unipdf/v3/extractor/barcode"
+	"github.com/TheLinker/unipdf/v3/model"
+)
+
+// batchDocument returns a document of 5 pages whose pages 2 and 4 are
+// separator sheets with a QR code drawn at (100, 100) from the top left
+// corner of the page, 144 points wide.
+func batchDocument(t *testing.T) *model.PdfReader {
+	c := creator.New()
+	for num := 1; num <= 5; num++ {
+		c.NewPage()
+		if num%2 == 1 {
+			continue
+		}
+		code, err := qr.Encode("SEPARATOR", qr.M, qr.Auto)
+		require.NoError(t, err)
+		img, err := c.NewImageFromGoImage(drawCode(code, 4, 16, 0))
+		require.NoError(t, err)
+		img.ScaleToWidth(144)
+		img.SetPos(100, 100)
+		require.NoError(t, c.Draw(img))
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	return reader
+}
+
+func TestReadPage(t *testing.T) {
+	reader := batchDocument(t)
+	page, err := reader.GetPage(2)
+	require.NoError(t, err)
+	mbox, err := page.GetMediaBox()
+	require.NoError(t, err)
+
+	check := func(barcodes []*barcode.PageBarcode) {
+		require.Len(t, barcodes, 1)
+		assert.Equal(t, "SEPARATOR", barcodes[0].Value)
+		bbox := barcodes[0].BBox
+		// The QR code is drawn within its quiet zone.
+		assert.True(t, bbox.Llx > 100 && bbox.Urx < 244, "bbox: %v", bbox)
+		assert.True(t, bbox.Ury < mbox.Ury-100 && bbox.Lly > mbox.Ury-244, "bbox: %v", bbox)
+		// 21 modules and the quiet zone of 4 modules on each side.
+		assert.InDelta(t, 144*21/29.0, bbox.Width(), 2)
+	}
+
+	barcodes, err := barcode.ReadPage(page, barcode.Options{})
+	require.NoError(t, err)
+	check(barcodes)
+
+	barcodes, err = barcode.ReadPageImages(page, barcode.Options{})
+	require.NoError(t, err)
+	check(barcodes)
+}
+
+func TestSplit(t *testing.T) {
+	reader := batchDocument(t)
+
+	sections, err := barcode.Split(reader, barcode.SplitOptions{FromImages: true})
+	require.NoError(t, err)
+	require.Len(t, sections, 3)
+	assert.Empty(t, sections[0].Separator)
+	assert.Equal(t, []int{1}, sections[0].Pages)
+	assert.Equal(t, []int{3}, sections[1].Pages)
+	require.Len(t, sections[1].Separator, 1)
+	assert.Equal(t, "SEPARATOR", sections[1].Separator[0].Value)
+	assert.Equal(t, []int{5}, sections[2].Pages)
+
+	sections, err = barcode.Split(reader, barcode.SplitOptions{
+		KeepSeparators: true,
+		IsSeparator: func(b *barcode.PageBarcode) bool {
+			return b.Format == barcode.FormatCode128
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, sections, 1)
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, sections[0].Pages)
+
+	var buf bytes.Buffer
+	require.NoError(t, sections[0].Write(reader, &buf))
+	section, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	numPages, err := section.GetNumPages()
+	require.NoError(t, err)
+	assert.Equal(t, 5, numPages)
+}
@@ -28,6 +28,12 @@ type Extractor struct {
 
 	// textCount is an incrementing number used to identify XYTest objects.
 	textCount int64
+
+	// opIndex is the index of the page content stream operation being processed.
+	opIndex int
+
+	// mediaBox is the media box of the page, used for layout analysis.
+	mediaBox model.PdfRectangle
 }
 
 // New returns an Extractor instance for extracting content from the input PDF page.
@@ -48,5 +54,8 @@ func New(page *model.PdfPage) (*Extractor, error) {
 		fontCache:   map[string]fontEntry{},
 		formResults: map[string]textResult{},
 	}
+	if mediaBox, err := page.GetMediaBox(); err == nil {
+		e.mediaBox = *mediaBox
+	}
 	return e, nil
 }
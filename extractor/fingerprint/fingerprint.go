@@ -0,0 +1,219 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+/*
+Package fingerprint computes the fingerprints of the PDF pages used to detect
+the duplicate and near-duplicate pages across documents, including the pages
+of documents saved again by other applications or with the images compressed
+differently.
+
+A fingerprint combines a hash of the normalized text of the page extracted
+with extractor, a MinHash signature of the text which estimates the share of
+the text common to two pages, and a perceptual hash of the page rendered at a
+low resolution with render.ImageDevice, which is robust to the compression
+artifacts and small shifts of the page content.
+
+Example:
+
+	a, err := fingerprint.Page(page1, fingerprint.Options{})
+	if err != nil {
+		return err
+	}
+	b, err := fingerprint.Page(page2, fingerprint.Options{})
+	if err != nil {
+		return err
+	}
+	if fingerprint.Compare(a, b).Score >= 0.9 {
+		fmt.Println("the pages are near-duplicates")
+	}
+
+The fingerprints can be stored with their String representation and read back
+with Parse.
+*/
+package fingerprint
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"math/bits"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// signatureSize is the number of hashes of the MinHash signatures of the text.
+const signatureSize = 32
+
+// shingleSize is the number of words of the shingles of the MinHash signatures.
+const shingleSize = 3
+
+// Fingerprint is the fingerprint of a page.
+type Fingerprint struct {
+	// TextHash is the hash of the normalized text of the page, 0 if the page has no text.
+	TextHash uint64
+	// TextSignature is the MinHash signature of the word shingles of the normalized text, nil
+	// if the page has no text.
+	TextSignature []uint64
+
+	// ImageHash is the perceptual hash of the rendered page, valid if HasImage is true.
+	ImageHash uint64
+	HasImage  bool
+}
+
+// Similarity is the similarity of two fingerprints. The similarities are between 0 (different)
+// and 1 (identical).
+type Similarity struct {
+	// Text is the estimated share of the text common to the pages, -1 if neither page has text.
+	Text float64
+	// Image is the similarity of the rendered pages, -1 if either fingerprint has no image hash.
+	Image float64
+	// Score is the mean of the text and image similarities.
+	Score float64
+}
+
+// New returns the fingerprint of a page with the text `text` and rendered as `img`, nil if
+// the page is not rendered.
+func New(text string, img image.Image) *Fingerprint {
+	f := &Fingerprint{}
+	words := normalizeText(text)
+	if len(words) > 0 {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words, " ")))
+		f.TextHash = h.Sum64()
+		f.TextSignature = minHash(words)
+	}
+	if img != nil {
+		f.ImageHash = perceptualHash(img)
+		f.HasImage = true
+	}
+	return f
+}
+
+// normalizeText returns the words of `text` in the NFKC normal form and in lower case, without
+// punctuation, so that the text extracted from the pages is the same regardless of the layout
+// and the fonts of the pages.
+func normalizeText(text string) []string {
+	text = strings.ToLower(norm.NFKC.String(text))
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// minHash returns the MinHash signature of the shingles of `words`.
+func minHash(words []string) []uint64 {
+	signature := make([]uint64, signatureSize)
+	for i := range signature {
+		signature[i] = ^uint64(0)
+	}
+
+	n := len(words) - shingleSize + 1
+	if n < 1 {
+		n = 1
+	}
+	for i := 0; i < n; i++ {
+		end := i + shingleSize
+		if end > len(words) {
+			end = len(words)
+		}
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:end], " ")))
+		shingle := h.Sum64()
+		for j := range signature {
+			if v := mix(shingle ^ uint64(j+1)*0x9e3779b97f4a7c15); v < signature[j] {
+				signature[j] = v
+			}
+		}
+	}
+	return signature
+}
+
+// mix is the finalizer of the SplitMix64 generator, used as the hash functions of minHash.
+func mix(x uint64) uint64 {
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// Compare returns the similarity of the fingerprints `a` and `b`.
+func Compare(a, b *Fingerprint) Similarity {
+	s := Similarity{Text: -1, Image: -1}
+	switch {
+	case a.TextSignature == nil && b.TextSignature == nil:
+	case a.TextHash == b.TextHash:
+		s.Text = 1
+	case len(a.TextSignature) != len(b.TextSignature):
+		s.Text = 0
+	default:
+		equal := 0
+		for i, v := range a.TextSignature {
+			if v == b.TextSignature[i] {
+				equal++
+			}
+		}
+		s.Text = float64(equal) / float64(len(a.TextSignature))
+	}
+	if a.HasImage && b.HasImage {
+		s.Image = 1 - float64(bits.OnesCount64(a.ImageHash^b.ImageHash))/64
+	}
+
+	var sum float64
+	var n int
+	for _, v := range []float64{s.Text, s.Image} {
+		if v >= 0 {
+			sum += v
+			n++
+		}
+	}
+	if n > 0 {
+		s.Score = sum / float64(n)
+	}
+	return s
+}
+
+// String returns the hexadecimal representation of the fingerprint, parsed by Parse.
+func (f *Fingerprint) String() string {
+	var flags byte
+	if f.HasImage {
+		flags = 1
+	}
+	data := make([]byte, 17, 17+8*len(f.TextSignature))
+	data[0] = flags
+	binary.BigEndian.PutUint64(data[1:], f.ImageHash)
+	binary.BigEndian.PutUint64(data[9:], f.TextHash)
+	for _, v := range f.TextSignature {
+		data = append(data, make([]byte, 8)...)
+		binary.BigEndian.PutUint64(data[len(data)-8:], v)
+	}
+	return hex.EncodeToString(data)
+}
+
+// Parse parses the fingerprint `s` returned by Fingerprint.String.
+func Parse(s string) (*Fingerprint, error) {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 17 || (len(data)-17)%8 != 0 || data[0] > 1 {
+		return nil, errors.New("invalid fingerprint")
+	}
+
+	f := &Fingerprint{
+		HasImage:  data[0] == 1,
+		ImageHash: binary.BigEndian.Uint64(data[1:]),
+		TextHash:  binary.BigEndian.Uint64(data[9:]),
+	}
+	for data = data[17:]; len(data) > 0; data = data[8:] {
+		f.TextSignature = append(f.TextSignature, binary.BigEndian.Uint64(data))
+	}
+	if f.TextSignature != nil && len(f.TextSignature) != signatureSize {
+		return nil, fmt.Errorf("invalid fingerprint text signature size %d", len(f.TextSignature))
+	}
+	return f, nil
+}
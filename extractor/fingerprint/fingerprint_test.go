@@ -0,0 +1,151 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package fingerprint
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/creator"
+	"github.com/TheLinker/unipdf/v3/model"
+)
+
+const sampleText = `The quick brown fox jumps over the lazy dog. Pack my box with five dozen
+liquor jugs. How vexingly quick daft zebras jump! Sphinx of black quartz, judge my vow.
+The five boxing wizards jump quickly. Jackdaws love my big sphinx of quartz.`
+
+func TestTextFingerprint(t *testing.T) {
+	f := New(sampleText, nil)
+	require.Len(t, f.TextSignature, signatureSize)
+	assert.False(t, f.HasImage)
+
+	// The layout, the case and the punctuation of the text do not change the fingerprint.
+	reflowed := New("THE QUICK BROWN FOX   jumps over the lazy dog\nPack my box with five dozen liquor jugs "+
+		"how vexingly quick daft zebras jump sphinx of black quartz judge my vow the five boxing wizards "+
+		"jump quickly jackdaws love my big sphinx of quartz", nil)
+	assert.Equal(t, f.TextHash, reflowed.TextHash)
+	s := Compare(f, reflowed)
+	assert.Equal(t, 1.0, s.Text)
+	assert.Equal(t, -1.0, s.Image)
+	assert.Equal(t, 1.0, s.Score)
+
+	// A word changed.
+	edited := New(sampleText+" Amazingly few discotheques provide jukeboxes.", nil)
+	assert.NotEqual(t, f.TextHash, edited.TextHash)
+	s = Compare(f, edited)
+	assert.True(t, s.Text > 0.6 && s.Text < 1, "%v", s.Text)
+
+	other := New("Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor.", nil)
+	assert.True(t, Compare(f, other).Text < 0.2)
+	assert.Equal(t, 0.0, Compare(f, New("", nil)).Text)
+	assert.Equal(t, -1.0, Compare(New("", nil), New("", nil)).Text)
+}
+
+// testImage returns an image with a dark rectangle at `x`, `y` and a gradient.
+func testImage(x, y int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 300, 400))
+	for j := 0; j < 400; j++ {
+		for i := 0; i < 300; i++ {
+			c := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+			if i >= x && i < x+100 && j >= y && j < y+60 {
+				c = color.RGBA{R: 20, G: 40, B: 120, A: 255}
+			} else if j > 300 {
+				v := uint8(255 - i/3)
+				c = color.RGBA{R: v, G: v, B: v, A: 255}
+			}
+			img.Set(i, j, c)
+		}
+	}
+	return img
+}
+
+func TestImageFingerprint(t *testing.T) {
+	original := testImage(40, 50)
+	f := New("", original)
+	assert.True(t, f.HasImage)
+	assert.Nil(t, f.TextSignature)
+
+	// The compression artifacts and the scaling do not change the hash much.
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, original, &jpeg.Options{Quality: 20}))
+	compressed, err := jpeg.Decode(&buf)
+	require.NoError(t, err)
+	s := Compare(f, New("", compressed))
+	assert.True(t, s.Image >= 0.9, "%v", s.Image)
+	assert.Equal(t, -1.0, s.Text)
+
+	small := image.NewRGBA(image.Rect(0, 0, 75, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 75; x++ {
+			small.Set(x, y, original.At(x*4+2, y*4+2))
+		}
+	}
+	assert.True(t, Compare(f, New("", small)).Image >= 0.9)
+
+	// The rectangle at another position.
+	moved := New("", testImage(180, 220))
+	assert.True(t, Compare(f, moved).Image < 0.8, "%v", Compare(f, moved).Image)
+}
+
+func TestParse(t *testing.T) {
+	for _, f := range []*Fingerprint{
+		New(sampleText, testImage(10, 10)),
+		New(sampleText, nil),
+		New("", testImage(10, 10)),
+		New("", nil),
+	} {
+		parsed, err := Parse(f.String())
+		require.NoError(t, err)
+		assert.Equal(t, f, parsed)
+	}
+	_, err := Parse("00ff")
+	assert.Error(t, err)
+}
+
+// imagePage returns a page with the image `img` encoded by `encoder`.
+func imagePage(t *testing.T, img image.Image, encoder core.StreamEncoder) *model.PdfPage {
+	c := creator.New()
+	cimg, err := c.NewImageFromGoImage(img)
+	require.NoError(t, err)
+	cimg.SetEncoder(encoder)
+	cimg.ScaleToWidth(300)
+	cimg.SetPos(100, 100)
+	require.NoError(t, c.Draw(cimg))
+
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	return page
+}
+
+func TestPageFingerprint(t *testing.T) {
+	dct := core.NewDCTEncoder()
+	dct.Quality = 30
+	a, err := Page(imagePage(t, testImage(40, 50), core.NewFlateEncoder()), Options{})
+	require.NoError(t, err)
+	b, err := Page(imagePage(t, testImage(40, 50), dct), Options{})
+	require.NoError(t, err)
+	c, err := Page(imagePage(t, testImage(180, 220), core.NewFlateEncoder()), Options{})
+	require.NoError(t, err)
+
+	assert.True(t, a.HasImage)
+	assert.True(t, Compare(a, b).Score >= 0.9, "%v", Compare(a, b))
+	assert.True(t, Compare(a, c).Score < 0.8, "%v", Compare(a, c))
+
+	d, err := Page(imagePage(t, testImage(40, 50), core.NewFlateEncoder()), Options{SkipImage: true})
+	require.NoError(t, err)
+	assert.False(t, d.HasImage)
+}
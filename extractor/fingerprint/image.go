@@ -0,0 +1,110 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package fingerprint
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// hashSize is the size of the grayscale image transformed by perceptualHash and dctSize the size
+// of the low frequencies of the transform kept in the hash.
+const (
+	hashSize = 32
+	dctSize  = 8
+)
+
+// perceptualHash returns the perceptual hash of `img`: the image is reduced to a 32x32 grayscale
+// image whose 8x8 lowest frequencies of the discrete cosine transform are compared to their
+// median. The transparent pixels are white.
+func perceptualHash(img image.Image) uint64 {
+	pixels := grayThumbnail(img)
+
+	// The transform of the rows then of the columns of the 8x8 lowest frequencies.
+	var cosines [dctSize][hashSize]float64
+	for u := 0; u < dctSize; u++ {
+		for x := 0; x < hashSize; x++ {
+			cosines[u][x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / (2 * hashSize))
+		}
+	}
+	var rows [hashSize][dctSize]float64
+	for y := 0; y < hashSize; y++ {
+		for u := 0; u < dctSize; u++ {
+			var sum float64
+			for x := 0; x < hashSize; x++ {
+				sum += pixels[y][x] * cosines[u][x]
+			}
+			rows[y][u] = sum
+		}
+	}
+	coefficients := make([]float64, 0, dctSize*dctSize)
+	for v := 0; v < dctSize; v++ {
+		for u := 0; u < dctSize; u++ {
+			var sum float64
+			for y := 0; y < hashSize; y++ {
+				sum += rows[y][u] * cosines[v][y]
+			}
+			coefficients = append(coefficients, sum)
+		}
+	}
+
+	// The mean intensity of the image, first coefficient, is not part of the median.
+	sorted := append([]float64(nil), coefficients[1:]...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+
+	var hash uint64
+	for i, c := range coefficients {
+		if c > median {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash
+}
+
+// grayThumbnail returns the intensities of `img` reduced to hashSize x hashSize pixels by
+// averaging the pixels of the image.
+func grayThumbnail(img image.Image) [hashSize][hashSize]float64 {
+	var pixels [hashSize][hashSize]float64
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return pixels
+	}
+
+	for j := 0; j < hashSize; j++ {
+		y0, y1 := cellRange(j, h)
+		for i := 0; i < hashSize; i++ {
+			x0, x1 := cellRange(i, w)
+			var sum float64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+					// The colors are premultiplied by the alpha: composite with white.
+					white := float64(0xffff - a)
+					sum += 0.299*(float64(r)+white) + 0.587*(float64(g)+white) + 0.114*(float64(b)+white)
+				}
+			}
+			pixels[j][i] = sum / float64((x1-x0)*(y1-y0)) / 0xffff
+		}
+	}
+	return pixels
+}
+
+// cellRange returns the range of the pixels of the cell `i` of an image dimension `size` split
+// in hashSize cells. The cells have at least a pixel.
+func cellRange(i, size int) (int, int) {
+	start := i * size / hashSize
+	end := (i + 1) * size / hashSize
+	if end <= start {
+		end = start + 1
+	}
+	if end > size {
+		start, end = size-1, size
+	}
+	return start, end
+}
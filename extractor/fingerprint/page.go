@@ -0,0 +1,80 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package fingerprint
+
+import (
+	"image"
+	"math"
+
+	"github.com/TheLinker/unipdf/v3/extractor"
+	"github.com/TheLinker/unipdf/v3/model"
+	"github.com/TheLinker/unipdf/v3/render"
+)
+
+// defaultResolution is the resolution in DPI of the pages rendered by Page.
+const defaultResolution = 36
+
+// Options are the options of the fingerprints of the pages.
+type Options struct {
+	// Resolution is the resolution in DPI the pages are rendered at. The default is 36 DPI.
+	Resolution float64
+
+	// SkipText and SkipImage skip the text extraction and the rendering of the pages, e.g. to
+	// fingerprint the scanned documents without text faster.
+	SkipText  bool
+	SkipImage bool
+}
+
+// Page returns the fingerprint of `page`.
+func Page(page *model.PdfPage, opts Options) (*Fingerprint, error) {
+	var text string
+	if !opts.SkipText {
+		ext, err := extractor.New(page)
+		if err != nil {
+			return nil, err
+		}
+		if text, err = ext.ExtractText(); err != nil {
+			return nil, err
+		}
+	}
+
+	var img image.Image
+	if !opts.SkipImage {
+		mbox, err := page.GetMediaBox()
+		if err != nil {
+			return nil, err
+		}
+		resolution := opts.Resolution
+		if resolution <= 0 {
+			resolution = defaultResolution
+		}
+		device := render.NewImageDevice()
+		device.OutputWidth = int(math.Max(math.Round((mbox.Llx+mbox.Width())*resolution/72), hashSize))
+		if img, err = device.Render(page); err != nil {
+			return nil, err
+		}
+	}
+	return New(text, img), nil
+}
+
+// Document returns the fingerprints of the pages of the document of `reader`.
+func Document(reader *model.PdfReader, opts Options) ([]*Fingerprint, error) {
+	numPages, err := reader.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+	fingerprints := make([]*Fingerprint, numPages)
+	for i := range fingerprints {
+		page, err := reader.GetPage(i + 1)
+		if err != nil {
+			return nil, err
+		}
+		if fingerprints[i], err = Page(page, opts); err != nil {
+			return nil, err
+		}
+	}
+	return fingerprints, nil
+}
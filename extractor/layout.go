@@ -0,0 +1,499 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/TheLinker/unipdf/v3/common"
+	"github.com/TheLinker/unipdf/v3/contentstream"
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/internal/transform"
+	"github.com/TheLinker/unipdf/v3/model"
+)
+
+// LayoutBlockType represents the type of a block of page content identified by layout analysis.
+type LayoutBlockType int
+
+// Layout block types.
+const (
+	LayoutParagraph LayoutBlockType = iota
+	LayoutHeading
+	LayoutListItem
+	LayoutTable
+	LayoutTableCell
+	LayoutFigure
+	LayoutHeader
+	LayoutFooter
+)
+
+// String returns a string representation of the block type.
+func (t LayoutBlockType) String() string {
+	switch t {
+	case LayoutParagraph:
+		return "Paragraph"
+	case LayoutHeading:
+		return "Heading"
+	case LayoutListItem:
+		return "ListItem"
+	case LayoutTable:
+		return "Table"
+	case LayoutTableCell:
+		return "TableCell"
+	case LayoutFigure:
+		return "Figure"
+	case LayoutHeader:
+		return "Header"
+	case LayoutFooter:
+		return "Footer"
+	}
+	return "Unknown"
+}
+
+// LayoutBlock represents a block of page content identified by layout analysis: a paragraph, a
+// heading, a list item, a table, a figure, or a running header or footer.
+type LayoutBlock struct {
+	Type LayoutBlockType
+
+	// BBox is the bounding box of the block in device coordinates.
+	BBox model.PdfRectangle
+
+	// Text is the text of the block, with the lines separated by line breaks.
+	Text string
+
+	// FontSize is the dominant height of the text of the block.
+	FontSize float64
+
+	// Ops contains the indexes of the page content stream operations which draw the block, in
+	// increasing order. The indexes refer to the operations obtained by parsing the page content
+	// streams (PdfPage.GetAllContentStreams) with contentstream.ContentStreamParser. Each operation
+	// belongs to at most one block. Table blocks have no operations, they belong to the cells.
+	Ops []int
+
+	// Rows contains the cells of table blocks, row by row.
+	Rows [][]*LayoutBlock
+
+	marks []textMark
+}
+
+// PageLayout represents the layout of the content of a page.
+type PageLayout struct {
+	// Blocks contains the blocks of the page in reading order.
+	Blocks []*LayoutBlock
+
+	// BodyFontSize is the most common height of the text of the page.
+	BodyFontSize float64
+}
+
+// Layout analysis parameters. Distances are in units of text height.
+const (
+	layoutHeadingRatio    = 1.15 // Minimum ratio of the height of headings to the body text height.
+	layoutMaxHeadingLines = 3    // Maximum number of lines of headings.
+	layoutSpaceGap        = 0.15 // Minimum gap between words.
+	layoutColumnGap       = 1.5  // Minimum gap between table columns.
+	layoutParagraphGap    = 0.8  // Maximum gap between the lines of a paragraph.
+	layoutRowGap          = 2.0  // Maximum gap between the rows of a table.
+	layoutSizeTolerance   = 0.1  // Relative height difference of the lines of a block.
+	layoutMarginRatio     = 0.08 // Height of the header and footer bands relative to the page height.
+)
+
+// reListItem matches the start of list items: bullets and enumeration labels.
+var reListItem = regexp.MustCompile(`^\s*(?:[•◦▪▫‣⁃○●■□–*-]|\(?(?:\d{1,3}|[a-zA-Z]|[ivxIVX]{1,4})[.)])\s`)
+
+// ExtractPageLayout returns the layout of the page content of `e` (an Extractor for a page). The
+// text is divided into paragraphs, headings (by text height), list items, tables and running
+// headers and footers, and the images are reported as figures.
+func (e *Extractor) ExtractPageLayout() (*PageLayout, error) {
+	pt, _, _, err := e.extractPageText(e.contents, e.resources, transform.IdentityMatrix(), 0)
+	if err != nil {
+		return nil, err
+	}
+	procBuf(pt)
+	operations, err := contentstream.NewContentStreamParser(e.contents).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	textOps := map[int]struct{}{}
+	for _, mark := range pt.marks {
+		textOps[mark.opIndex] = struct{}{}
+	}
+	figures, err := e.layoutFigures(*operations, textOps)
+	if err != nil {
+		return nil, err
+	}
+	return newPageLayout(pt.marks, figures, e.mediaBox), nil
+}
+
+// layoutFigures returns the figure blocks of the page content stream `operations`: the images and
+// the form XObjects containing images but no text. `textOps` contains the indexes of the
+// operations drawing text.
+func (e *Extractor) layoutFigures(operations contentstream.ContentStreamOperations,
+	textOps map[int]struct{}) ([]*LayoutBlock, error) {
+	opIndices := make(map[*contentstream.ContentStreamOperation]int, len(operations))
+	for i, op := range operations {
+		opIndices[op] = i
+	}
+	unitSquare := model.PdfRectangle{Urx: 1, Ury: 1}
+
+	var figures []*LayoutBlock
+	processor := contentstream.NewContentStreamProcessor(operations)
+	processor.AddHandler(contentstream.HandlerConditionEnumAllOperands, "",
+		func(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState,
+			resources *model.PdfPageResources) error {
+			i := opIndices[op]
+			if _, ok := textOps[i]; ok {
+				return nil
+			}
+
+			var bbox model.PdfRectangle
+			switch op.Operand {
+			case "BI":
+				bbox = transformRect(gs.CTM, unitSquare)
+			case "Do":
+				if len(op.Params) != 1 {
+					return nil
+				}
+				name, ok := core.GetName(op.Params[0])
+				if !ok {
+					return nil
+				}
+				_, xtype := resources.GetXObjectByName(*name)
+				switch xtype {
+				case model.XObjectTypeImage:
+					bbox = transformRect(gs.CTM, unitSquare)
+				case model.XObjectTypeForm:
+					xform, err := resources.GetXObjectFormByName(*name)
+					if err != nil {
+						common.Log.Debug("ERROR: Unable to load form XObject %s: %v", *name, err)
+						return nil
+					}
+					if xform == nil || !hasImages(xform.Resources) {
+						return nil
+					}
+					boxArr, ok := core.GetArray(xform.BBox)
+					if !ok {
+						return nil
+					}
+					box, err := core.GetNumbersAsFloat(boxArr.Elements())
+					if err != nil || len(box) != 4 {
+						return nil
+					}
+					ctm := gs.CTM
+					if matrix, ok := core.GetArray(xform.Matrix); ok {
+						if m, err := core.GetNumbersAsFloat(matrix.Elements()); err == nil && len(m) == 6 {
+							ctm = ctm.Mult(transform.NewMatrix(m[0], m[1], m[2], m[3], m[4], m[5]))
+						}
+					}
+					bbox = transformRect(ctm, model.PdfRectangle{Llx: box[0], Lly: box[1], Urx: box[2], Ury: box[3]})
+				default:
+					return nil
+				}
+			default:
+				return nil
+			}
+
+			figures = append(figures, &LayoutBlock{
+				Type: LayoutFigure,
+				BBox: bbox,
+				Ops:  []int{i},
+			})
+			return nil
+		})
+
+	if err := processor.Process(e.resources); err != nil {
+		common.Log.Debug("ERROR: Processing: err=%v", err)
+		return nil, err
+	}
+	return figures, nil
+}
+
+// hasImages returns true if the resources `resources` contain image XObjects.
+func hasImages(resources *model.PdfPageResources) bool {
+	if resources == nil {
+		return false
+	}
+	xobjects, ok := core.GetDict(resources.XObject)
+	if !ok {
+		return false
+	}
+	for _, name := range xobjects.Keys() {
+		if _, xtype := resources.GetXObjectByName(name); xtype == model.XObjectTypeImage {
+			return true
+		}
+	}
+	return false
+}
+
+// transformRect returns the bounding box of rectangle `r` transformed by `m`.
+func transformRect(m transform.Matrix, r model.PdfRectangle) model.PdfRectangle {
+	bbox := model.PdfRectangle{
+		Llx: math.Inf(1), Lly: math.Inf(1),
+		Urx: math.Inf(-1), Ury: math.Inf(-1),
+	}
+	for _, p := range [][2]float64{{r.Llx, r.Lly}, {r.Urx, r.Lly}, {r.Llx, r.Ury}, {r.Urx, r.Ury}} {
+		x := m[0]*p[0] + m[3]*p[1] + m[6]
+		y := m[1]*p[0] + m[4]*p[1] + m[7]
+		bbox.Llx = math.Min(bbox.Llx, x)
+		bbox.Lly = math.Min(bbox.Lly, y)
+		bbox.Urx = math.Max(bbox.Urx, x)
+		bbox.Ury = math.Max(bbox.Ury, y)
+	}
+	return bbox
+}
+
+// layoutLine represents a line of text.
+type layoutLine struct {
+	marks    []textMark         // Non-space marks of the line sorted by x.
+	bbox     model.PdfRectangle // Bounding box of the marks.
+	height   float64            // Dominant height of the marks.
+	baseline float64            // Baseline of the first mark.
+	segments [][]textMark       // Parts of the line separated by column gaps.
+}
+
+// layoutText returns the text of the marks `marks`, with spaces inserted between words.
+func layoutText(marks []textMark, height float64) string {
+	var sb strings.Builder
+	for i, mark := range marks {
+		if i > 0 && mark.bbox.Llx-marks[i-1].bbox.Urx > layoutSpaceGap*height {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(mark.text)
+	}
+	return sb.String()
+}
+
+// dominantHeight returns the most common height of the marks `marks`, weighting each height by
+// the number of marks.
+func dominantHeight(marks []textMark) float64 {
+	counts := map[float64]int{}
+	var best float64
+	for _, mark := range marks {
+		h := math.Round(mark.height*2) / 2
+		counts[h]++
+		if counts[h] > counts[best] || (counts[h] == counts[best] && h > best) {
+			best = h
+		}
+	}
+	return best
+}
+
+// layoutLines groups the non-space marks `marks` into lines, ordered from top to bottom.
+func layoutLines(marks []textMark) []*layoutLine {
+	var visible []textMark
+	for _, mark := range marks {
+		if !isTextSpace(mark.text) {
+			visible = append(visible, mark)
+		}
+	}
+	sort.SliceStable(visible, func(i, j int) bool {
+		return visible[i].bbox.Lly > visible[j].bbox.Lly
+	})
+
+	var lines []*layoutLine
+	var line *layoutLine
+	for _, mark := range visible {
+		if line != nil && math.Abs(mark.bbox.Lly-line.baseline) <= 0.4*math.Max(line.height, mark.height) {
+			line.marks = append(line.marks, mark)
+			line.height = math.Max(line.height, mark.height)
+			continue
+		}
+		line = &layoutLine{
+			marks:    []textMark{mark},
+			height:   mark.height,
+			baseline: mark.bbox.Lly,
+		}
+		lines = append(lines, line)
+	}
+
+	for _, line := range lines {
+		sort.SliceStable(line.marks, func(i, j int) bool {
+			return line.marks[i].bbox.Llx < line.marks[j].bbox.Llx
+		})
+		line.height = dominantHeight(line.marks)
+		line.bbox = line.marks[0].bbox
+		start := 0
+		for i, mark := range line.marks {
+			line.bbox = rectUnion(line.bbox, mark.bbox)
+			if i > 0 && mark.bbox.Llx-line.marks[i-1].bbox.Urx > layoutColumnGap*line.height {
+				line.segments = append(line.segments, line.marks[start:i])
+				start = i
+			}
+		}
+		line.segments = append(line.segments, line.marks[start:])
+	}
+	return lines
+}
+
+// marksBBox returns the bounding box of the marks `marks`.
+func marksBBox(marks []textMark) model.PdfRectangle {
+	bbox := marks[0].bbox
+	for _, mark := range marks[1:] {
+		bbox = rectUnion(bbox, mark.bbox)
+	}
+	return bbox
+}
+
+// columnsMatch returns true if lines `a` and `b` have the same number of segments, and each
+// segment of `b` is horizontally aligned with the corresponding segment of `a`.
+func columnsMatch(a, b *layoutLine) bool {
+	if len(a.segments) < 2 || len(a.segments) != len(b.segments) {
+		return false
+	}
+	for i := range a.segments {
+		ra, rb := marksBBox(a.segments[i]), marksBBox(b.segments[i])
+		if ra.Urx < rb.Llx || rb.Urx < ra.Llx {
+			return false
+		}
+	}
+	return true
+}
+
+// newPageLayout returns the layout of the page with text marks `marks`, figures `figures` and
+// media box `mediaBox`.
+func newPageLayout(marks []textMark, figures []*LayoutBlock, mediaBox model.PdfRectangle) *PageLayout {
+	layout := &PageLayout{}
+	lines := layoutLines(marks)
+	var all []textMark
+	for _, line := range lines {
+		all = append(all, line.marks...)
+	}
+	layout.BodyFontSize = dominantHeight(all)
+
+	var headers, content, footers []*LayoutBlock
+	var leaves []*LayoutBlock
+
+	// Running headers and footers are the lines in the top and bottom margins of the page.
+	band := layoutMarginRatio * (mediaBox.Ury - mediaBox.Lly)
+	var rest []*layoutLine
+	for _, line := range lines {
+		var blocks *[]*LayoutBlock
+		var typ LayoutBlockType
+		switch {
+		case band <= 0:
+		case line.bbox.Lly >= mediaBox.Ury-band:
+			blocks, typ = &headers, LayoutHeader
+		case line.bbox.Ury <= mediaBox.Lly+band:
+			blocks, typ = &footers, LayoutFooter
+		}
+		if blocks == nil {
+			rest = append(rest, line)
+			continue
+		}
+		if n := len(*blocks); n > 0 {
+			(*blocks)[n-1].marks = append((*blocks)[n-1].marks, line.marks...)
+			continue
+		}
+		block := &LayoutBlock{Type: typ, marks: line.marks}
+		*blocks = append(*blocks, block)
+		leaves = append(leaves, block)
+	}
+	lines = rest
+
+	// Tables are runs of lines with aligned columns.
+	var cur *LayoutBlock
+	var prev *layoutLine
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		j := i + 1
+		for j < len(lines) && columnsMatch(lines[i], lines[j]) &&
+			lines[j-1].bbox.Lly-lines[j].bbox.Ury <= layoutRowGap*lines[j].height {
+			j++
+		}
+		if j-i >= 2 {
+			table := &LayoutBlock{Type: LayoutTable, BBox: lines[i].bbox}
+			for _, row := range lines[i:j] {
+				var cells []*LayoutBlock
+				for _, segment := range row.segments {
+					cell := &LayoutBlock{Type: LayoutTableCell, marks: segment}
+					cells = append(cells, cell)
+					leaves = append(leaves, cell)
+				}
+				table.Rows = append(table.Rows, cells)
+				table.BBox = rectUnion(table.BBox, row.bbox)
+			}
+			content = append(content, table)
+			cur, prev = nil, nil
+			i = j - 1
+			continue
+		}
+
+		typ := LayoutParagraph
+		if layout.BodyFontSize > 0 && line.height >= layoutHeadingRatio*layout.BodyFontSize {
+			typ = LayoutHeading
+		}
+		text := layoutText(line.marks, line.height)
+		item := typ == LayoutParagraph && reListItem.MatchString(text)
+		if item {
+			typ = LayoutListItem
+		}
+
+		if cur != nil && !item && prev != nil &&
+			prev.bbox.Lly-line.bbox.Ury <= layoutParagraphGap*line.height &&
+			math.Abs(prev.height-line.height) <= layoutSizeTolerance*line.height &&
+			cur.BBox.Llx <= line.bbox.Urx && line.bbox.Llx <= cur.BBox.Urx &&
+			(cur.Type == typ || (cur.Type == LayoutListItem && typ == LayoutParagraph)) &&
+			!(typ == LayoutHeading && strings.Count(cur.Text, "\n")+1 >= layoutMaxHeadingLines) {
+			cur.marks = append(cur.marks, line.marks...)
+			cur.Text += "\n" + text
+			cur.BBox = rectUnion(cur.BBox, line.bbox)
+			prev = line
+			continue
+		}
+		cur = &LayoutBlock{Type: typ, BBox: line.bbox, Text: text, marks: line.marks}
+		content = append(content, cur)
+		leaves = append(leaves, cur)
+		prev = line
+	}
+
+	// Each operation belongs to the block containing most of its marks.
+	type owner struct {
+		block *LayoutBlock
+		count int
+	}
+	owners := map[int]owner{}
+	for _, block := range leaves {
+		counts := map[int]int{}
+		for _, mark := range block.marks {
+			counts[mark.opIndex]++
+		}
+		for op, count := range counts {
+			if count > owners[op].count {
+				owners[op] = owner{block, count}
+			}
+		}
+	}
+	for op, owner := range owners {
+		owner.block.Ops = append(owner.block.Ops, op)
+	}
+	for _, block := range leaves {
+		sort.Ints(block.Ops)
+		block.BBox = marksBBox(block.marks)
+		block.FontSize = dominantHeight(block.marks)
+		if block.Text == "" {
+			block.Text = layoutBlockText(block.marks)
+		}
+	}
+
+	content = append(content, figures...)
+	sort.SliceStable(content, func(i, j int) bool {
+		return content[i].BBox.Ury > content[j].BBox.Ury
+	})
+	layout.Blocks = append(append(headers, content...), footers...)
+	return layout
+}
+
+// layoutBlockText returns the text of the marks `marks` sorted in lines.
+func layoutBlockText(marks []textMark) string {
+	var texts []string
+	for _, line := range layoutLines(marks) {
+		texts = append(texts, layoutText(line.marks, line.height))
+	}
+	return strings.Join(texts, "\n")
+}
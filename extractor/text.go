@@ -76,6 +76,16 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 		return pageText, state.numChars, state.numMisses, err
 	}
 
+	// The text marks are associated with the index of the page content stream operation that
+	// produced them (the Do operator for text in form XObjects).
+	var opIndices map[*contentstream.ContentStreamOperation]int
+	if level == 0 {
+		opIndices = make(map[*contentstream.ContentStreamOperation]int, len(*operations))
+		for i, op := range *operations {
+			opIndices[op] = i
+		}
+	}
+
 	processor := contentstream.NewContentStreamProcessor(*operations)
 
 	processor.AddHandler(contentstream.HandlerConditionEnumAllOperands, "",
@@ -83,6 +93,9 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 			resources *model.PdfPageResources) error {
 
 			operand := op.Operand
+			if level == 0 {
+				e.opIndex = opIndices[op]
+			}
 
 			switch operand {
 			case "q":
@@ -345,7 +358,12 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 					e.formResults[name.String()] = formResult
 				}
 
-				pageText.marks = append(pageText.marks, formResult.pageText.marks...)
+				for _, mark := range formResult.pageText.marks {
+					if level == 0 {
+						mark.opIndex = e.opIndex
+					}
+					pageText.marks = append(pageText.marks, mark)
+				}
 				state.numChars += formResult.numChars
 				state.numMisses += formResult.numMisses
 			}
@@ -841,6 +859,7 @@ type textMark struct {
 	trm           transform.Matrix   // The current text rendering matrix (TRM above).
 	end           transform.Point    // The end of character device coordinates.
 	count         int64              // To help with reading debug logs.
+	opIndex       int                // Index of the page content stream operation that drew the mark.
 }
 
 // newTextMark returns a textMark for text `text` rendered with text rendering matrix (TRM) `trm`
@@ -884,6 +903,7 @@ func (to *textObject) newTextMark(text string, trm transform.Matrix, end transfo
 		trm:           trm,
 		end:           end,
 		count:         to.e.textCount,
+		opIndex:       to.e.opIndex,
 	}
 	if !isTextSpace(tm.text) && tm.Width() == 0.0 {
 		common.Log.Debug("ERROR: Zero width text. tm=%s\n\tm=%#v", tm, tm)
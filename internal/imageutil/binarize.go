@@ -0,0 +1,385 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package imageutil
+
+import (
+	"image"
+	"math"
+)
+
+// BinarizationMethod is the method used to compute the thresholds of the binarization.
+type BinarizationMethod int
+
+const (
+	// BinarizeTriangle uses a single threshold for the whole image, computed from its histogram
+	// with the triangle method.
+	BinarizeTriangle BinarizationMethod = iota
+	// BinarizeOtsu divides the image into tiles and computes the threshold of each tile with the
+	// Otsu method. The thresholds are interpolated between the centers of the tiles.
+	BinarizeOtsu
+	// BinarizeSauvola computes the threshold of each pixel from the mean 'm' and the standard
+	// deviation 's' of its neighbourhood: m * (1 + k * (s/R - 1)).
+	BinarizeSauvola
+	// BinarizeNiblack computes the threshold of each pixel from the mean 'm' and the standard
+	// deviation 's' of its neighbourhood: m + k * s.
+	BinarizeNiblack
+)
+
+const (
+	defaultWindowSize   = 31
+	defaultOtsuTileSize = 128
+	defaultSauvolaK     = 0.34
+	defaultSauvolaR     = 128
+	defaultNiblackK     = -0.2
+	backgroundTileSize  = 64
+	minContrast         = 16
+	minRelativeContrast = 0.25
+	minBackgroundValue  = 16
+)
+
+// BinarizationParams are the parameters of the binarization of the gray and color images.
+// The zero value uses a global triangle threshold.
+type BinarizationParams struct {
+	// Method is the thresholding method.
+	Method BinarizationMethod
+	// WindowSize is the size in pixels of the neighbourhood of the pixels for the Sauvola and
+	// Niblack methods (default: 31) and the size of the tiles for the Otsu method (default: 128).
+	WindowSize int
+	// K is the sensitivity of the Sauvola (default: 0.34) and the Niblack (default: -0.2) methods.
+	K float64
+	// R is the dynamic range of the standard deviation for the Sauvola method (default: 128).
+	R float64
+	// NormalizeBackground enables the normalization of the background prior to the thresholding,
+	// which evens out the lighting of the images: the background is estimated over 64x64 tiles and
+	// the pixels are scaled so that the background becomes white. The global threshold of the
+	// normalized images is computed with the Otsu method.
+	NormalizeBackground bool
+}
+
+// Binarize gets the binary (black/white) image from the given image 'i' using the 'params'
+// thresholding method. The pixels darker than their threshold are black (0) and the others are
+// white (255).
+func Binarize(i image.Image, params BinarizationParams) *image.Gray {
+	gray := ImgToGray(i)
+	if params.NormalizeBackground {
+		gray = NormalizeBackground(gray, backgroundTileSize)
+	}
+	switch params.Method {
+	case BinarizeOtsu:
+		size := params.WindowSize
+		if size <= 0 {
+			size = defaultOtsuTileSize
+		}
+		return binarizeOtsuTiled(gray, size)
+	case BinarizeSauvola:
+		k, r := params.K, params.R
+		if k == 0 {
+			k = defaultSauvolaK
+		}
+		if r <= 0 {
+			r = defaultSauvolaR
+		}
+		return binarizeLocal(gray, params.WindowSize, func(mean, deviation float64) float64 {
+			return mean * (1 + k*(deviation/r-1))
+		})
+	case BinarizeNiblack:
+		k := params.K
+		if k == 0 {
+			k = defaultNiblackK
+		}
+		return binarizeLocal(gray, params.WindowSize, func(mean, deviation float64) float64 {
+			return mean + k*deviation
+		})
+	default:
+		histogram := GrayImageHistogram(gray)
+		if params.NormalizeBackground {
+			// The triangle threshold lies at the foot of the peak of the background values, which
+			// is too close to the normalized background.
+			return ImgToBinary(gray, AutoThresholdOtsu(histogram))
+		}
+		return ImgToBinary(gray, AutoThresholdTriangle(histogram))
+	}
+}
+
+// AutoThresholdOtsu returns the threshold value on the base of provided histogram, computed with
+// the Otsu method which maximizes the variance between the classes of the pixels.
+func AutoThresholdOtsu(histogram [256]int) uint8 {
+	var total, sum float64
+	for i, n := range histogram {
+		total += float64(n)
+		sum += float64(i * n)
+	}
+	if total == 0 {
+		return 0
+	}
+
+	var weightB, sumB, maxVariance float64
+	var threshold int
+	for i, n := range histogram {
+		weightB += float64(n)
+		if weightB == 0 {
+			continue
+		}
+		weightF := total - weightB
+		if weightF == 0 {
+			break
+		}
+		sumB += float64(i * n)
+		meanB := sumB / weightB
+		meanF := (sum - sumB) / weightF
+		variance := weightB * weightF * (meanB - meanF) * (meanB - meanF)
+		if variance > maxVariance {
+			maxVariance = variance
+			threshold = i
+		}
+	}
+	// The pixels up to the threshold belong to the dark class.
+	return uint8(threshold + 1)
+}
+
+// NormalizeBackground returns the gray image 'img' with its background evened out. The background
+// of each 'tileSize' square tile is estimated as the mean of its bright pixels, and is interpolated
+// between the centers of the tiles. The pixels are scaled so that the background becomes white.
+func NormalizeBackground(img *image.Gray, tileSize int) *image.Gray {
+	bounds := img.Bounds()
+	if tileSize <= 0 {
+		tileSize = backgroundTileSize
+	}
+	tiles := newTileGrid(bounds, tileSize)
+	tiles.extrapolate = true
+	for ty := 0; ty < tiles.rows; ty++ {
+		for tx := 0; tx < tiles.cols; tx++ {
+			histogram := tileHistogram(img, tiles.tile(tx, ty))
+			// The background is made of the bright pixels of the tiles with a foreground and
+			// of all the pixels of the other ones.
+			th, ok := splitHistogram(histogram)
+			if !ok {
+				th = 0
+			}
+			bg := histogramMean(histogram, th, 255)
+			if bg < minBackgroundValue {
+				bg = minBackgroundValue
+			}
+			tiles.set(tx, ty, bg)
+		}
+	}
+
+	d := image.NewGray(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			bg := tiles.interpolate(x, y)
+			if bg < minBackgroundValue {
+				bg = minBackgroundValue
+			}
+			v := float64(img.GrayAt(x, y).Y) * 255 / bg
+			if v > 255 {
+				v = 255
+			}
+			d.Pix[d.PixOffset(x, y)] = uint8(v)
+		}
+	}
+	return d
+}
+
+// binarizeOtsuTiled binarizes the gray image 'img' with the Otsu thresholds of its 'tileSize'
+// square tiles. The tiles with a low contrast are considered as background.
+func binarizeOtsuTiled(img *image.Gray, tileSize int) *image.Gray {
+	bounds := img.Bounds()
+	tiles := newTileGrid(bounds, tileSize)
+	for ty := 0; ty < tiles.rows; ty++ {
+		for tx := 0; tx < tiles.cols; tx++ {
+			histogram := tileHistogram(img, tiles.tile(tx, ty))
+			th, ok := splitHistogram(histogram)
+			if !ok {
+				// Set the threshold below the tile values, so that the tile is white, while keeping
+				// the threshold close to the neighbour tiles ones for the interpolation.
+				tiles.set(tx, ty, histogramMean(histogram, 0, 255)-minContrast)
+				continue
+			}
+			tiles.set(tx, ty, float64(th))
+		}
+	}
+
+	d := image.NewGray(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			d.Pix[d.PixOffset(x, y)] = blackOrWhiteF(img.GrayAt(x, y).Y, tiles.interpolate(x, y))
+		}
+	}
+	return d
+}
+
+// binarizeLocal binarizes the gray image 'img' with the thresholds computed by 'threshold' from
+// the mean and the standard deviation of the 'size' square neighbourhood of each pixel.
+func binarizeLocal(img *image.Gray, size int, threshold func(mean, deviation float64) float64) *image.Gray {
+	if size <= 0 {
+		size = defaultWindowSize
+	}
+	half := size / 2
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	// The integral images of the values and of the squared values give the sums over any
+	// rectangle in constant time.
+	stride := w + 1
+	sums := make([]float64, stride*(h+1))
+	squares := make([]float64, stride*(h+1))
+	for y := 0; y < h; y++ {
+		var rowSum, rowSquare float64
+		for x := 0; x < w; x++ {
+			v := float64(img.Pix[img.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)])
+			rowSum += v
+			rowSquare += v * v
+			i := (y+1)*stride + x + 1
+			sums[i] = sums[i-stride] + rowSum
+			squares[i] = squares[i-stride] + rowSquare
+		}
+	}
+	rect := func(integral []float64, x0, y0, x1, y1 int) float64 {
+		return integral[y1*stride+x1] - integral[y0*stride+x1] - integral[y1*stride+x0] + integral[y0*stride+x0]
+	}
+
+	d := image.NewGray(bounds)
+	for y := 0; y < h; y++ {
+		y0, y1 := maxInt(y-half, 0), minInt(y+half+1, h)
+		for x := 0; x < w; x++ {
+			x0, x1 := maxInt(x-half, 0), minInt(x+half+1, w)
+			n := float64((x1 - x0) * (y1 - y0))
+			mean := rect(sums, x0, y0, x1, y1) / n
+			variance := rect(squares, x0, y0, x1, y1)/n - mean*mean
+			if variance < 0 {
+				variance = 0
+			}
+			th := threshold(mean, math.Sqrt(variance))
+			px, py := bounds.Min.X+x, bounds.Min.Y+y
+			d.Pix[d.PixOffset(px, py)] = blackOrWhiteF(img.GrayAt(px, py).Y, th)
+		}
+	}
+	return d
+}
+
+// blackOrWhiteF returns black for the values 'c' lower than 'threshold' and white otherwise.
+func blackOrWhiteF(c uint8, threshold float64) uint8 {
+	if float64(c) < threshold {
+		return 0
+	}
+	return 255
+}
+
+// tileHistogram returns the histogram of the 'r' area of the gray image 'img'.
+func tileHistogram(img *image.Gray, r image.Rectangle) (histogram [256]int) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for _, pix := range img.Pix[img.PixOffset(r.Min.X, y):img.PixOffset(r.Max.X, y)] {
+			histogram[pix]++
+		}
+	}
+	return histogram
+}
+
+// splitHistogram returns the Otsu threshold of the histogram, and whether the classes of the
+// values it separates are contrasted enough to be the foreground and the background.
+func splitHistogram(histogram [256]int) (uint8, bool) {
+	th := AutoThresholdOtsu(histogram)
+	if th == 0 {
+		return 0, false
+	}
+	dark, bright := histogramMean(histogram, 0, th-1), histogramMean(histogram, th, 255)
+	contrast := bright - dark
+	return th, contrast >= minContrast && contrast >= minRelativeContrast*bright
+}
+
+// histogramMean returns the mean of the values of the histogram within the range ['from', 'to'].
+func histogramMean(histogram [256]int, from, to uint8) float64 {
+	var sum, count int
+	for i := int(from); i <= int(to); i++ {
+		sum += i * histogram[i]
+		count += histogram[i]
+	}
+	if count == 0 {
+		return 0
+	}
+	return float64(sum) / float64(count)
+}
+
+// tileGrid holds the values computed for the square tiles of an image and interpolates them
+// bilinearly between the centers of the tiles. The values beyond the centers of the border tiles
+// are either extrapolated or constant.
+type tileGrid struct {
+	bounds      image.Rectangle
+	size        int
+	cols, rows  int
+	values      []float64
+	extrapolate bool
+}
+
+func newTileGrid(bounds image.Rectangle, size int) *tileGrid {
+	cols := (bounds.Dx() + size - 1) / size
+	rows := (bounds.Dy() + size - 1) / size
+	return &tileGrid{
+		bounds: bounds,
+		size:   size,
+		cols:   cols,
+		rows:   rows,
+		values: make([]float64, cols*rows),
+	}
+}
+
+// tile returns the area of the image covered by the tile at column 'tx' and row 'ty'.
+func (g *tileGrid) tile(tx, ty int) image.Rectangle {
+	min := g.bounds.Min.Add(image.Pt(tx*g.size, ty*g.size))
+	return image.Rectangle{Min: min, Max: min.Add(image.Pt(g.size, g.size))}.Intersect(g.bounds)
+}
+
+func (g *tileGrid) set(tx, ty int, v float64) {
+	g.values[ty*g.cols+tx] = v
+}
+
+// interpolate returns the value at the pixel 'x', 'y' interpolated between the centers of the
+// four closest tiles.
+func (g *tileGrid) interpolate(x, y int) float64 {
+	tx0, tx1, ax := g.position(x-g.bounds.Min.X, g.cols)
+	ty0, ty1, ay := g.position(y-g.bounds.Min.Y, g.rows)
+	top := g.values[ty0*g.cols+tx0]*(1-ax) + g.values[ty0*g.cols+tx1]*ax
+	bottom := g.values[ty1*g.cols+tx0]*(1-ax) + g.values[ty1*g.cols+tx1]*ax
+	return top*(1-ay) + bottom*ay
+}
+
+// position returns the indexes of the tiles whose centers surround the coordinate 'c' and the
+// weight of the second tile.
+func (g *tileGrid) position(c, n int) (int, int, float64) {
+	if n == 1 {
+		return 0, 0, 0
+	}
+	f := (float64(c)+0.5)/float64(g.size) - 0.5
+	i := int(math.Floor(f))
+	switch {
+	case i < 0:
+		if !g.extrapolate {
+			return 0, 0, 0
+		}
+		i = 0
+	case i >= n-1:
+		if !g.extrapolate {
+			return n - 1, n - 1, 0
+		}
+		i = n - 2
+	}
+	return i, i + 1, f - float64(i)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,114 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package imageutil
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// unevenPage returns a gray image of text blocks, lit from the right: the background goes from
+// dark gray on the left to white on the right, and the text is darker than its background.
+func unevenPage() (*image.Gray, []image.Rectangle) {
+	const width, height = 800, 200
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	background := func(x int) uint8 {
+		return uint8(40 + 210*x/width)
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetGray(x, y, color.Gray{Y: background(x)})
+		}
+	}
+
+	var text []image.Rectangle
+	for y := 20; y < height-20; y += 30 {
+		for x := 10; x < width-40; x += 45 {
+			r := image.Rect(x, y, x+30, y+6)
+			for py := r.Min.Y; py < r.Max.Y; py++ {
+				for px := r.Min.X; px < r.Max.X; px++ {
+					img.SetGray(px, py, color.Gray{Y: background(px) / 3})
+				}
+			}
+			text = append(text, r)
+		}
+	}
+	return img, text
+}
+
+// errorRates returns the ratio of the text pixels which are not black and the ratio of the
+// background pixels which are not white.
+func errorRates(bw *image.Gray, text []image.Rectangle) (textErrors, backgroundErrors float64) {
+	isText := func(x, y int) bool {
+		for _, r := range text {
+			if image.Pt(x, y).In(r) {
+				return true
+			}
+		}
+		return false
+	}
+	var nText, nBackground, eText, eBackground int
+	bounds := bw.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			v := bw.GrayAt(x, y).Y
+			if isText(x, y) {
+				nText++
+				if v != 0 {
+					eText++
+				}
+			} else {
+				nBackground++
+				if v != 255 {
+					eBackground++
+				}
+			}
+		}
+	}
+	return float64(eText) / float64(nText), float64(eBackground) / float64(nBackground)
+}
+
+func TestBinarize(t *testing.T) {
+	img, text := unevenPage()
+
+	// The global threshold makes the dark part of the page black.
+	_, backgroundErrors := errorRates(Binarize(img, BinarizationParams{}), text)
+	assert.True(t, backgroundErrors > 0.05, "background errors: %f", backgroundErrors)
+
+	for name, params := range map[string]BinarizationParams{
+		"sauvola":    {Method: BinarizeSauvola},
+		"otsu":       {Method: BinarizeOtsu, WindowSize: 64},
+		"normalized": {NormalizeBackground: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			bw := Binarize(img, params)
+			for _, v := range bw.Pix {
+				if v != 0 && v != 255 {
+					t.Fatalf("not a black and white pixel: %d", v)
+				}
+			}
+			textErrors, backgroundErrors := errorRates(bw, text)
+			assert.True(t, textErrors < 0.05, "text errors: %f", textErrors)
+			assert.True(t, backgroundErrors < 0.02, "background errors: %f", backgroundErrors)
+		})
+	}
+}
+
+func TestAutoThresholdOtsu(t *testing.T) {
+	var histogram [256]int
+	histogram[40] = 100
+	histogram[50] = 50
+	histogram[200] = 300
+	histogram[220] = 100
+
+	th := AutoThresholdOtsu(histogram)
+	assert.True(t, th > 50 && th <= 200, "threshold: %d", th)
+
+	assert.Equal(t, uint8(0), AutoThresholdOtsu([256]int{}))
+}
@@ -136,11 +136,12 @@ func IsGrayImgBlackAndWhite(i *image.Gray) bool {
 	return isGrayBlackWhite(i)
 }
 
+// blackOrWhite returns black for the values 'c' lower than 'threshold' and white otherwise.
 func blackOrWhite(c, threshold uint8) uint8 {
 	if c < threshold {
-		return 255
+		return 0
 	}
-	return 0
+	return 255
 }
 
 func gray16ImageToBlackWhite(img *image.Gray16, th uint8) *image.Gray {
@@ -988,7 +988,7 @@ func (b *Bitmap) setPadBits(val int) {
 	fullBytes := b.Width / 8
 	mask := rmaskByte[endbits]
 	if val == 0 {
-		mask ^= mask
+		mask = ^mask
 	}
 
 	var bIndex int
@@ -0,0 +1,295 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package bitmap
+
+import (
+	"image"
+	"math"
+
+	"github.com/TheLinker/unipdf/v3/internal/jbig2/errors"
+)
+
+const (
+	// skewMaxWidth is the width above which the bitmap is reduced before searching its skew.
+	skewMaxWidth = 1200
+	// skewMinPixels is the number of 'ON' pixels below which the skew is not searched.
+	skewMinPixels = 100
+)
+
+// FindSkew finds the skew angle of the text lines of the bitmap 'b', in degrees, within the range
+// [-maxAngle, maxAngle]. The angle is positive when the lines slope down to the right.
+// The angle is found with the projection profile method: the sum of the squared differences of
+// the pixel counts of adjacent lines is maximal for the lines being aligned with the angle.
+// The search is performed with a 'step' precision (0.1 degree if not positive).
+// The returned 'confidence' is the ratio of the score of the angle found to the mean score of
+// all the angles tried - the values close to 1 mean the bitmap has no discernible lines.
+func (b *Bitmap) FindSkew(maxAngle, step float64) (angle, confidence float64, err error) {
+	const processName = "FindSkew"
+	if maxAngle <= 0 || maxAngle >= 45 {
+		return 0, 0, errors.Errorf(processName, "invalid max angle: %f", maxAngle)
+	}
+	if step <= 0 {
+		step = 0.1
+	}
+
+	s := b
+	s.setPadBits(0)
+	for s.Width > skewMaxWidth {
+		if s, err = reduceRankBinaryCascade(s, 1); err != nil {
+			return 0, 0, errors.Wrap(err, processName, "reduce")
+		}
+	}
+	if s.CountPixels() < skewMinPixels {
+		return 0, 0, nil
+	}
+
+	// Coarse sweep followed by the refinement around the best angle.
+	coarse := step * 5
+	var sum float64
+	var n int
+	best, bestScore := 0.0, -1.0
+	for a := -maxAngle; a <= maxAngle+coarse/2; a += coarse {
+		score := s.skewScore(a)
+		sum += score
+		n++
+		if score > bestScore {
+			best, bestScore = a, score
+		}
+	}
+	center := best
+	for a := center - coarse; a <= center+coarse+step/2; a += step {
+		if math.Abs(a) > maxAngle {
+			continue
+		}
+		if score := s.skewScore(a); score > bestScore {
+			best, bestScore = a, score
+		}
+	}
+
+	mean := sum / float64(n)
+	if mean == 0 {
+		return 0, 0, nil
+	}
+	return math.Round(best/step) * step, bestScore / mean, nil
+}
+
+// skewScore returns the projection profile score of the bitmap for the lines sloping with the
+// angle 'angle' in degrees.
+func (b *Bitmap) skewScore(angle float64) float64 {
+	tangent := math.Tan(angle * math.Pi / 180)
+	shifts := make([]int, b.Width)
+	for x := range shifts {
+		shifts[x] = int(math.Round(float64(x) * tangent))
+	}
+	offset := int(math.Ceil(float64(b.Width)*math.Abs(tangent))) + 1
+	counts := make([]int, b.Height+2*offset)
+
+	for y := 0; y < b.Height; y++ {
+		row := b.Data[y*b.RowStride : (y+1)*b.RowStride]
+		for i, v := range row {
+			if v == 0 {
+				continue
+			}
+			for bit := 0; bit < 8; bit++ {
+				if v&(0x80>>uint(bit)) == 0 {
+					continue
+				}
+				x := i*8 + bit
+				if x >= b.Width {
+					break
+				}
+				counts[y-shifts[x]+offset]++
+			}
+		}
+	}
+
+	var score float64
+	for i := 1; i < len(counts); i++ {
+		d := float64(counts[i] - counts[i-1])
+		score += d * d
+	}
+	return score
+}
+
+// RotateShear returns the bitmap 'b' rotated about its center by the angle 'angle' in degrees,
+// positive angles rotating the content clockwise (the direction of the positive y axis pointing
+// down). The rotation is made of three shears, the uncovered areas are set 'OFF' and the
+// dimensions of the bitmap are unchanged. The bitmap skewed by the angle found by FindSkew is
+// deskewed by rotating it by the opposite angle.
+func (b *Bitmap) RotateShear(angle float64) (*Bitmap, error) {
+	const processName = "RotateShear"
+	if angle == 0 {
+		d, err := copyBitmap(nil, b)
+		if err != nil {
+			return nil, errors.Wrap(err, processName, "")
+		}
+		return d, nil
+	}
+	phi := angle * math.Pi / 180
+	a, s := -math.Tan(phi/2), math.Sin(phi)
+
+	d, err := b.shearHorizontal(a)
+	if err != nil {
+		return nil, errors.Wrap(err, processName, "first shear")
+	}
+	if d, err = d.shearVertical(s); err != nil {
+		return nil, errors.Wrap(err, processName, "second shear")
+	}
+	if d, err = d.shearHorizontal(a); err != nil {
+		return nil, errors.Wrap(err, processName, "third shear")
+	}
+	return d, nil
+}
+
+// shearHorizontal returns the bitmap 'b' sheared horizontally about its center line: each row is
+// shifted by 'tangent' times its distance to the center.
+func (b *Bitmap) shearHorizontal(tangent float64) (*Bitmap, error) {
+	const processName = "shearHorizontal"
+	d := New(b.Width, b.Height)
+	center := float64(b.Height) / 2
+	shift := func(y int) int {
+		return int(math.Round(tangent * (float64(y) + 0.5 - center)))
+	}
+	for y0 := 0; y0 < b.Height; {
+		dx := shift(y0)
+		y1 := y0 + 1
+		for y1 < b.Height && shift(y1) == dx {
+			y1++
+		}
+		if err := d.RasterOperation(dx, y0, b.Width, y1-y0, PixSrc, b, 0, y0); err != nil {
+			return nil, errors.Wrap(err, processName, "")
+		}
+		y0 = y1
+	}
+	return d, nil
+}
+
+// shearVertical returns the bitmap 'b' sheared vertically about its center column: each column
+// is shifted by 'tangent' times its distance to the center.
+func (b *Bitmap) shearVertical(tangent float64) (*Bitmap, error) {
+	const processName = "shearVertical"
+	d := New(b.Width, b.Height)
+	center := float64(b.Width) / 2
+	shift := func(x int) int {
+		return int(math.Round(tangent * (float64(x) + 0.5 - center)))
+	}
+	for x0 := 0; x0 < b.Width; {
+		dy := shift(x0)
+		x1 := x0 + 1
+		for x1 < b.Width && shift(x1) == dy {
+			x1++
+		}
+		if err := d.RasterOperation(x0, dy, x1-x0, b.Height, PixSrc, b, x0, 0); err != nil {
+			return nil, errors.Wrap(err, processName, "")
+		}
+		x0 = x1
+	}
+	return d, nil
+}
+
+// RemoveComponents removes from the bitmap 'b' the connected components (with 8-connectivity)
+// for which the 'remove' function returns true. The function is provided with the bounding box
+// and the number of 'ON' pixels of each component. The number of removed components is returned.
+func (b *Bitmap) RemoveComponents(remove func(box image.Rectangle, pixels int) bool) (int, error) {
+	const processName = "RemoveComponents"
+	bms := &Bitmaps{}
+	if _, err := b.ConnComponents(bms, 8); err != nil {
+		return 0, errors.Wrap(err, processName, "")
+	}
+	var removed int
+	for i, comp := range bms.Values {
+		if i >= len(bms.Boxes) {
+			break
+		}
+		box := bms.Boxes[i]
+		if !remove(*box, comp.CountPixels()) {
+			continue
+		}
+		if err := b.RasterOperation(box.Min.X, box.Min.Y, box.Dx(), box.Dy(), PixNotSrcAndDst, comp, 0, 0); err != nil {
+			return removed, errors.Wrapf(err, processName, "component: %d", i)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// RemoveSpeckles removes from the bitmap 'b' the connected components which fit in a 'size'×'size'
+// square, like the scanning noise. The number of removed components is returned.
+func (b *Bitmap) RemoveSpeckles(size int) (int, error) {
+	if size <= 0 {
+		return 0, nil
+	}
+	return b.RemoveComponents(func(box image.Rectangle, _ int) bool {
+		return box.Dx() <= size && box.Dy() <= size
+	})
+}
+
+// RemoveBorders removes from the bitmap 'b' the 'ON' pixels connected to its edges, like the dark
+// borders around the scanned pages. The pixels are set 'OFF' so that the dimensions of the bitmap
+// are unchanged. The number of removed pixels is returned.
+func (b *Bitmap) RemoveBorders() (int, error) {
+	const processName = "RemoveBorders"
+	if b.Width == 0 || b.Height == 0 {
+		return 0, nil
+	}
+	b.setPadBits(0)
+
+	// The seed consists of the 'ON' pixels of the edges.
+	seed := New(b.Width, b.Height)
+	edges := []image.Rectangle{
+		image.Rect(0, 0, b.Width, 1),
+		image.Rect(0, b.Height-1, b.Width, b.Height),
+		image.Rect(0, 0, 1, b.Height),
+		image.Rect(b.Width-1, 0, b.Width, b.Height),
+	}
+	for _, r := range edges {
+		if err := seed.RasterOperation(r.Min.X, r.Min.Y, r.Dx(), r.Dy(), PixSrc, b, r.Min.X, r.Min.Y); err != nil {
+			return 0, errors.Wrap(err, processName, "seed")
+		}
+	}
+	if seed.Zero() {
+		return 0, nil
+	}
+
+	border, err := seedFillBinary(nil, seed, b, 8)
+	if err != nil {
+		return 0, errors.Wrap(err, processName, "")
+	}
+	count := b.CountPixels()
+	if err = b.RasterOperation(0, 0, b.Width, b.Height, PixNotSrcAndDst, border, 0, 0); err != nil {
+		return 0, errors.Wrap(err, processName, "subtract")
+	}
+	return count - b.CountPixels(), nil
+}
+
+// RemovePunchHoles removes from the bitmap 'b' the punch holes of the scanned pages: the round,
+// filled components close to the edges whose size is within 1.5% and 6% of the smallest
+// dimension of the bitmap. The number of removed components is returned.
+func (b *Bitmap) RemovePunchHoles() (int, error) {
+	dim := b.Width
+	if b.Height < dim {
+		dim = b.Height
+	}
+	minSize, maxSize := float64(dim)*0.015, float64(dim)*0.06
+	marginX, marginY := float64(b.Width)*0.12, float64(b.Height)*0.12
+
+	return b.RemoveComponents(func(box image.Rectangle, pixels int) bool {
+		w, h := float64(box.Dx()), float64(box.Dy())
+		if w < minSize || h < minSize || w > maxSize || h > maxSize {
+			return false
+		}
+		if ratio := w / h; ratio < 0.7 || ratio > 1.4 {
+			return false
+		}
+		// A filled disc covers pi/4 of its bounding box.
+		if fill := float64(pixels) / (w * h); fill < 0.6 || fill > 0.95 {
+			return false
+		}
+		cx, cy := float64(box.Min.X)+w/2, float64(box.Min.Y)+h/2
+		return cx < marginX || cx > float64(b.Width)-marginX ||
+			cy < marginY || cy > float64(b.Height)-marginY
+	})
+}
@@ -0,0 +1,116 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package bitmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// textPage returns a bitmap with the lines of 'words' like a page of text.
+func textPage(t *testing.T, width, height int) *Bitmap {
+	bm := New(width, height)
+	for y := height / 10; y < height*9/10; y += 24 {
+		for x := width / 10; x < width*9/10-40; x += 50 {
+			require.NoError(t, bm.RasterOperation(x, y, 40, 10, PixSet, nil, 0, 0))
+		}
+	}
+	return bm
+}
+
+// TestSkew tests the skew detection and the rotation of the bitmaps.
+func TestSkew(t *testing.T) {
+	page := textPage(t, 800, 1000)
+
+	angle, confidence, err := page.FindSkew(5, 0.1)
+	require.NoError(t, err)
+	assert.InDelta(t, 0, angle, 0.05)
+	assert.True(t, confidence > 2)
+
+	for _, skew := range []float64{3, -1.5} {
+		skewed, err := page.RotateShear(skew)
+		require.NoError(t, err)
+		assert.Equal(t, page.Width, skewed.Width)
+		assert.Equal(t, page.Height, skewed.Height)
+
+		angle, _, err = skewed.FindSkew(5, 0.1)
+		require.NoError(t, err)
+		assert.InDelta(t, skew, angle, 0.15)
+
+		deskewed, err := skewed.RotateShear(-angle)
+		require.NoError(t, err)
+		angle, _, err = deskewed.FindSkew(5, 0.1)
+		require.NoError(t, err)
+		assert.InDelta(t, 0, angle, 0.15)
+	}
+
+	// Empty bitmaps have no skew.
+	angle, confidence, err = New(100, 100).FindSkew(5, 0.1)
+	require.NoError(t, err)
+	assert.Zero(t, angle)
+	assert.Zero(t, confidence)
+
+	_, _, err = page.FindSkew(50, 0.1)
+	assert.Error(t, err)
+}
+
+// TestRemoveSpeckles tests the removal of the small components.
+func TestRemoveSpeckles(t *testing.T) {
+	bm := New(100, 100)
+	require.NoError(t, bm.RasterOperation(10, 10, 30, 5, PixSet, nil, 0, 0))
+	require.NoError(t, bm.SetPixel(60, 60, 1))
+	require.NoError(t, bm.RasterOperation(70, 20, 2, 2, PixSet, nil, 0, 0))
+	require.NoError(t, bm.RasterOperation(80, 80, 4, 4, PixSet, nil, 0, 0))
+
+	removed, err := bm.RemoveSpeckles(2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	assert.Equal(t, 30*5+16, bm.CountPixels())
+	assert.False(t, bm.GetPixel(60, 60))
+	assert.True(t, bm.GetPixel(80, 80))
+}
+
+// TestRemoveBorders tests the removal of the pixels connected to the edges.
+func TestRemoveBorders(t *testing.T) {
+	bm := New(100, 100)
+	require.NoError(t, bm.RasterOperation(0, 0, 100, 5, PixSet, nil, 0, 0))
+	require.NoError(t, bm.RasterOperation(95, 0, 5, 100, PixSet, nil, 0, 0))
+	require.NoError(t, bm.RasterOperation(20, 20, 30, 30, PixSet, nil, 0, 0))
+
+	removed, err := bm.RemoveBorders()
+	require.NoError(t, err)
+	assert.Equal(t, 100*5+5*95, removed)
+	assert.Equal(t, 30*30, bm.CountPixels())
+}
+
+// TestRemovePunchHoles tests the removal of the punch holes close to the edges.
+func TestRemovePunchHoles(t *testing.T) {
+	bm := New(1000, 1000)
+	disc := func(cx, cy, r int) {
+		for y := -r; y <= r; y++ {
+			for x := -r; x <= r; x++ {
+				if x*x+y*y <= r*r {
+					require.NoError(t, bm.SetPixel(cx+x, cy+y, 1))
+				}
+			}
+		}
+	}
+	disc(50, 300, 15)
+	disc(50, 700, 15)
+	// Discs away from the edges are content.
+	disc(500, 500, 15)
+	// Squares are not holes.
+	require.NoError(t, bm.RasterOperation(40, 500, 30, 30, PixSet, nil, 0, 0))
+
+	removed, err := bm.RemovePunchHoles()
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	assert.False(t, bm.GetPixel(50, 300))
+	assert.True(t, bm.GetPixel(500, 500))
+	assert.True(t, bm.GetPixel(50, 510))
+}
@@ -0,0 +1,166 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package optcontent determines the visibility of the optional content of the documents in unidoc
+// internally.
+package optcontent
+
+import (
+	"github.com/TheLinker/unipdf/v3/core"
+)
+
+// maxExpressionDepth is the maximum nesting depth of the visibility expressions evaluated.
+const maxExpressionDepth = 32
+
+// Visibility is the visibility of the optional content groups in the default viewing configuration
+// of a document (section 8.11.4.3).
+type Visibility struct {
+	off map[*core.PdfObjectDictionary]bool
+}
+
+// NewVisibility loads the states of the optional content groups from the default configuration of
+// the optional content properties `ocProperties`. Returns nil if `ocProperties` is nil, i.e. if
+// the document has no optional content.
+func NewVisibility(ocProperties *core.PdfObjectDictionary) *Visibility {
+	if ocProperties == nil {
+		return nil
+	}
+	v := &Visibility{off: make(map[*core.PdfObjectDictionary]bool)}
+	config, ok := core.GetDict(ocProperties.Get("D"))
+	if !ok {
+		return v
+	}
+
+	groups := func(obj core.PdfObject) []*core.PdfObjectDictionary {
+		arr, _ := core.GetArray(obj)
+		var dicts []*core.PdfObjectDictionary
+		for _, elem := range arr.Elements() {
+			if dict, ok := core.GetDict(elem); ok {
+				dicts = append(dicts, dict)
+			}
+		}
+		return dicts
+	}
+	if state, _ := core.GetNameVal(config.Get("BaseState")); state == "OFF" {
+		for _, group := range groups(ocProperties.Get("OCGs")) {
+			v.off[group] = true
+		}
+		for _, group := range groups(config.Get("ON")) {
+			delete(v.off, group)
+		}
+	} else {
+		for _, group := range groups(config.Get("OFF")) {
+			v.off[group] = true
+		}
+	}
+	return v
+}
+
+// Visible returns true if the content associated with the optional content group or membership
+// dictionary `obj` is visible. The content is visible if `v` is nil or if `obj` is not a
+// dictionary. The visibility of a membership dictionary is given by its visibility expression
+// (VE) if any, by its visibility policy (P) otherwise.
+func (v *Visibility) Visible(obj core.PdfObject) bool {
+	dict, ok := core.GetDict(obj)
+	if v == nil || !ok {
+		return true
+	}
+	if t, _ := core.GetNameVal(dict.Get("Type")); t != "OCMD" {
+		return !v.off[dict]
+	}
+
+	if ve, ok := core.GetArray(dict.Get("VE")); ok {
+		if visible, ok := v.evaluate(ve, 0); ok {
+			return visible
+		}
+	}
+
+	var groups []core.PdfObject
+	if arr, ok := core.GetArray(dict.Get("OCGs")); ok {
+		groups = arr.Elements()
+	} else if ocg := dict.Get("OCGs"); ocg != nil {
+		groups = []core.PdfObject{ocg}
+	}
+	if len(groups) == 0 {
+		return true
+	}
+	on := 0
+	for _, group := range groups {
+		if g, ok := core.GetDict(group); ok && !v.off[g] {
+			on++
+		}
+	}
+	switch policy, _ := core.GetNameVal(dict.Get("P")); policy {
+	case "AllOn":
+		return on == len(groups)
+	case "AnyOff":
+		return on < len(groups)
+	case "AllOff":
+		return on == 0
+	}
+	return on > 0
+}
+
+// HiddenGroups returns the optional content groups hidden in the default configuration.
+func (v *Visibility) HiddenGroups() []*core.PdfObjectDictionary {
+	if v == nil {
+		return nil
+	}
+	groups := make([]*core.PdfObjectDictionary, 0, len(v.off))
+	for group := range v.off {
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// evaluate evaluates the visibility expression `ve` at the nesting depth `depth`. The bool flag is
+// false if the expression is invalid.
+func (v *Visibility) evaluate(ve *core.PdfObjectArray, depth int) (bool, bool) {
+	if depth >= maxExpressionDepth || ve.Len() < 2 {
+		return false, false
+	}
+	op, _ := core.GetNameVal(ve.Get(0))
+	operands := ve.Elements()[1:]
+	if op == "Not" && len(operands) != 1 {
+		return false, false
+	}
+
+	values := make([]bool, len(operands))
+	for i, operand := range operands {
+		if arr, ok := core.GetArray(operand); ok {
+			visible, ok := v.evaluate(arr, depth+1)
+			if !ok {
+				return false, false
+			}
+			values[i] = visible
+			continue
+		}
+		group, ok := core.GetDict(operand)
+		if !ok {
+			return false, false
+		}
+		values[i] = !v.off[group]
+	}
+
+	switch op {
+	case "Not":
+		return !values[0], true
+	case "And":
+		for _, value := range values {
+			if !value {
+				return false, true
+			}
+		}
+		return true, true
+	case "Or":
+		for _, value := range values {
+			if value {
+				return true, true
+			}
+		}
+		return false, true
+	}
+	return false, false
+}
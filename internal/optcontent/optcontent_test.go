@@ -0,0 +1,85 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package optcontent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/TheLinker/unipdf/v3/core"
+)
+
+// makeGroup returns an optional content group named `name`.
+func makeGroup(name string) *core.PdfObjectDictionary {
+	group := core.MakeDict()
+	group.Set("Type", core.MakeName("OCG"))
+	group.Set("Name", core.MakeString(name))
+	return group
+}
+
+// makeMembership returns a membership dictionary of `groups` with the visibility policy `policy`.
+func makeMembership(policy string, groups ...core.PdfObject) *core.PdfObjectDictionary {
+	ocmd := core.MakeDict()
+	ocmd.Set("Type", core.MakeName("OCMD"))
+	ocmd.Set("OCGs", core.MakeArray(groups...))
+	if policy != "" {
+		ocmd.Set("P", core.MakeName(policy))
+	}
+	return ocmd
+}
+
+func TestVisibility(t *testing.T) {
+	on, off := makeGroup("on"), makeGroup("off")
+	config := core.MakeDict()
+	config.Set("OFF", core.MakeArray(off))
+	ocProperties := core.MakeDict()
+	ocProperties.Set("OCGs", core.MakeArray(on, off))
+	ocProperties.Set("D", config)
+
+	v := NewVisibility(ocProperties)
+	require.True(t, v.Visible(on))
+	require.False(t, v.Visible(off))
+	require.True(t, v.Visible(nil))
+	require.Equal(t, []*core.PdfObjectDictionary{off}, v.HiddenGroups())
+
+	// Visibility policies.
+	require.True(t, v.Visible(makeMembership("", on, off)))
+	require.False(t, v.Visible(makeMembership("AllOn", on, off)))
+	require.True(t, v.Visible(makeMembership("AnyOff", on, off)))
+	require.False(t, v.Visible(makeMembership("AllOff", on, off)))
+	require.False(t, v.Visible(makeMembership("AnyOn", off)))
+	require.True(t, v.Visible(makeMembership("")))
+
+	// The visibility expressions are used instead of the policies.
+	ocmd := makeMembership("AnyOn", on, off)
+	ocmd.Set("VE", core.MakeArray(core.MakeName("And"), on, off))
+	require.False(t, v.Visible(ocmd))
+	ocmd.Set("VE", core.MakeArray(core.MakeName("Or"), on,
+		core.MakeArray(core.MakeName("Not"), on)))
+	require.True(t, v.Visible(ocmd))
+	ocmd.Set("VE", core.MakeArray(core.MakeName("And"), on,
+		core.MakeArray(core.MakeName("Not"), off)))
+	require.True(t, v.Visible(ocmd))
+	ocmd.Set("VE", core.MakeArray(core.MakeName("Not"), on))
+	require.False(t, v.Visible(ocmd))
+	// The policy is used if the expression is invalid.
+	ocmd.Set("VE", core.MakeArray(core.MakeName("Not"), on, off))
+	require.True(t, v.Visible(ocmd))
+
+	// The base state of the groups is off.
+	config.Set("BaseState", core.MakeName("OFF"))
+	config.Set("ON", core.MakeArray(on))
+	v = NewVisibility(ocProperties)
+	require.True(t, v.Visible(on))
+	require.False(t, v.Visible(off))
+
+	// The content is visible in the documents without optional content.
+	v = NewVisibility(nil)
+	require.Nil(t, v)
+	require.True(t, v.Visible(off))
+	require.Empty(t, v.HiddenGroups())
+}
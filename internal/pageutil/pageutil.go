@@ -0,0 +1,70 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package pageutil provides functions for the page dictionaries and their resources in unidoc
+// internally.
+package pageutil
+
+import (
+	"github.com/TheLinker/unipdf/v3/core"
+)
+
+// InheritableAttributes are the attributes of the pages which can be inherited from the ancestor
+// page tree nodes (section 7.7.3.4).
+var InheritableAttributes = []core.PdfObjectName{"Resources", "MediaBox", "CropBox", "Rotate"}
+
+// InheritedAttribute returns the attribute `key` of the page `dict` or of its closest ancestor
+// page tree node defining it, nil if not defined.
+func InheritedAttribute(dict *core.PdfObjectDictionary, key core.PdfObjectName) core.PdfObject {
+	visited := make(map[*core.PdfObjectDictionary]struct{})
+	for dict != nil {
+		if _, ok := visited[dict]; ok {
+			break
+		}
+		visited[dict] = struct{}{}
+		if obj := dict.Get(key); obj != nil {
+			return obj
+		}
+		dict, _ = core.GetDict(dict.Get("Parent"))
+	}
+	return nil
+}
+
+// InheritedResources returns the resources of the page `dict`, which can be inherited from the
+// ancestor page tree nodes.
+func InheritedResources(dict *core.PdfObjectDictionary) *core.PdfObjectDictionary {
+	resources, _ := core.GetDict(InheritedAttribute(dict, "Resources"))
+	return resources
+}
+
+// InheritAttributes sets the inheritable attributes of the page `dict` inherited from its
+// ancestors, e.g. before the page is moved to another page tree.
+func InheritAttributes(dict *core.PdfObjectDictionary) {
+	for _, key := range InheritableAttributes {
+		if dict.Get(key) != nil {
+			continue
+		}
+		if obj := InheritedAttribute(dict, key); obj != nil {
+			dict.Set(key, obj)
+		}
+	}
+}
+
+// Properties returns the property list referenced by the marked content operator operand `obj`,
+// which is either a name of the Properties of `resources` or an inline dictionary.
+func Properties(obj core.PdfObject, resources *core.PdfObjectDictionary) core.PdfObject {
+	name, ok := core.GetName(obj)
+	if !ok {
+		return obj
+	}
+	if resources == nil {
+		return nil
+	}
+	props, ok := core.GetDict(resources.Get("Properties"))
+	if !ok {
+		return nil
+	}
+	return props.Get(*name)
+}
@@ -0,0 +1,54 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package pageutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/TheLinker/unipdf/v3/core"
+)
+
+func TestInheritedAttribute(t *testing.T) {
+	resources := core.MakeDict()
+	root := core.MakeDict()
+	root.Set("Resources", resources)
+	root.Set("Rotate", core.MakeInteger(90))
+	node := core.MakeDict()
+	node.Set("Parent", root)
+	node.Set("Rotate", core.MakeInteger(180))
+	page := core.MakeDict()
+	page.Set("Parent", node)
+
+	require.Equal(t, resources, InheritedResources(page))
+	require.Equal(t, core.MakeInteger(180), InheritedAttribute(page, "Rotate"))
+	require.Nil(t, InheritedAttribute(page, "MediaBox"))
+
+	// The cycles of the page tree are not followed.
+	root.Set("Parent", page)
+	require.Nil(t, InheritedAttribute(page, "MediaBox"))
+
+	InheritAttributes(page)
+	require.Equal(t, resources, page.Get("Resources"))
+	require.Equal(t, core.MakeInteger(180), page.Get("Rotate"))
+	require.Nil(t, page.Get("MediaBox"))
+}
+
+func TestProperties(t *testing.T) {
+	props := core.MakeDict()
+	props.Set("OC", core.MakeName("OCG"))
+	properties := core.MakeDict()
+	properties.Set("P0", props)
+	resources := core.MakeDict()
+	resources.Set("Properties", properties)
+
+	require.Equal(t, props, Properties(core.MakeName("P0"), resources))
+	require.Nil(t, Properties(core.MakeName("P1"), resources))
+	require.Nil(t, Properties(core.MakeName("P0"), nil))
+	inline := core.MakeDict()
+	require.Equal(t, inline, Properties(inline, resources))
+}
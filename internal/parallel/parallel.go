@@ -0,0 +1,53 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package parallel runs independent tasks, such as the encoding of streams, concurrently in unidoc
+// internally.
+package parallel
+
+import "sync"
+
+// Run calls `task` for the indices 0 to n-1 with up to `workers` goroutines. The tasks are run
+// sequentially in order if `workers` is 1 or less, stopping at the first error.
+// The tasks must be independent and store their results by index, so that the results do not
+// depend on the number of workers. The error returned is the error of the task of the lowest index.
+func Run(n, workers int, task func(i int) error) error {
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			if err := task(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	errs := make([]error, n)
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				errs[i] = task(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package parallel
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	for _, workers := range []int{0, 1, 4, 100} {
+		results := make([]int, 50)
+		err := Run(len(results), workers, func(i int) error {
+			results[i] = i * i
+			return nil
+		})
+		require.NoError(t, err)
+		for i, r := range results {
+			require.Equal(t, i*i, r, "workers %d", workers)
+		}
+	}
+}
+
+func TestRunError(t *testing.T) {
+	for _, workers := range []int{1, 4} {
+		err := Run(20, workers, func(i int) error {
+			if i == 7 || i == 15 {
+				return fmt.Errorf("task %d", i)
+			}
+			return nil
+		})
+		require.Equal(t, errors.New("task 7"), err, "workers %d", workers)
+	}
+}
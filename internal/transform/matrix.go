@@ -10,6 +10,7 @@ import (
 	"math"
 
 	"github.com/TheLinker/unipdf/v3/common"
+	"github.com/TheLinker/unipdf/v3/core"
 )
 
 // Matrix is a linear transform matrix in homogenous coordinates.
@@ -57,6 +58,20 @@ func NewMatrix(a, b, c, d, tx, ty float64) Matrix {
 	return m
 }
 
+// NewMatrixFromObjects returns the affine transform matrix of the six numbers `objs`, such as the
+// operands of the cm and Tm operators or the elements of a Matrix entry. The bool flag is false if
+// `objs` are not six numbers.
+func NewMatrixFromObjects(objs []core.PdfObject) (Matrix, bool) {
+	if len(objs) != 6 {
+		return Matrix{}, false
+	}
+	v, err := core.GetNumbersAsFloat(objs)
+	if err != nil {
+		return Matrix{}, false
+	}
+	return NewMatrix(v[0], v[1], v[2], v[3], v[4], v[5]), true
+}
+
 // String returns a string describing `m`.
 func (m Matrix) String() string {
 	a, b, c, d, tx, ty := m[0], m[1], m[3], m[4], m[6], m[7]
@@ -0,0 +1,75 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package transform
+
+import (
+	"math"
+)
+
+// Rect is an axis aligned rectangle, such as a bounding box in user space.
+type Rect struct {
+	Llx, Lly, Urx, Ury float64
+}
+
+// EmptyRect returns a rectangle which does not contain any points, to be extended to the points
+// of a bounding box with Extend.
+func EmptyRect() Rect {
+	return Rect{Llx: math.Inf(1), Lly: math.Inf(1), Urx: math.Inf(-1), Ury: math.Inf(-1)}
+}
+
+// IsEmpty returns true if `r` does not have an area.
+func (r Rect) IsEmpty() bool {
+	return r.Urx <= r.Llx || r.Ury <= r.Lly
+}
+
+// Extend returns `r` extended to contain the point (`x`, `y`).
+func (r Rect) Extend(x, y float64) Rect {
+	return Rect{
+		Llx: math.Min(r.Llx, x),
+		Lly: math.Min(r.Lly, y),
+		Urx: math.Max(r.Urx, x),
+		Ury: math.Max(r.Ury, y),
+	}
+}
+
+// Expand returns `r` expanded by `d` in every direction. The rectangles which do not contain any
+// points are returned unchanged.
+func (r Rect) Expand(d float64) Rect {
+	if r.Urx < r.Llx || r.Ury < r.Lly {
+		return r
+	}
+	return Rect{Llx: r.Llx - d, Lly: r.Lly - d, Urx: r.Urx + d, Ury: r.Ury + d}
+}
+
+// Union returns the smallest rectangle containing both `r` and `o`.
+func (r Rect) Union(o Rect) Rect {
+	return Rect{
+		Llx: math.Min(r.Llx, o.Llx),
+		Lly: math.Min(r.Lly, o.Lly),
+		Urx: math.Max(r.Urx, o.Urx),
+		Ury: math.Max(r.Ury, o.Ury),
+	}
+}
+
+// Intersect returns the intersection of `r` and `o`.
+func (r Rect) Intersect(o Rect) Rect {
+	return Rect{
+		Llx: math.Max(r.Llx, o.Llx),
+		Lly: math.Max(r.Lly, o.Lly),
+		Urx: math.Min(r.Urx, o.Urx),
+		Ury: math.Min(r.Ury, o.Ury),
+	}
+}
+
+// Overlaps returns true if `r` and `o` have a common area.
+func (r Rect) Overlaps(o Rect) bool {
+	return o.Llx < r.Urx && o.Urx > r.Llx && o.Lly < r.Ury && o.Ury > r.Lly
+}
+
+// Touches returns true if `r` and `o` have a common point, which can be on their edges.
+func (r Rect) Touches(o Rect) bool {
+	return o.Llx <= r.Urx && o.Urx >= r.Llx && o.Lly <= r.Ury && o.Ury >= r.Lly
+}
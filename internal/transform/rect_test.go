@@ -0,0 +1,51 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/TheLinker/unipdf/v3/core"
+)
+
+func TestRect(t *testing.T) {
+	r := EmptyRect()
+	require.True(t, r.IsEmpty())
+	require.Equal(t, r, r.Expand(1))
+	r = r.Extend(10, 20).Extend(0, 0)
+	require.Equal(t, Rect{0, 0, 10, 20}, r)
+	require.False(t, r.IsEmpty())
+	require.Equal(t, Rect{-1, -1, 11, 21}, r.Expand(1))
+
+	o := Rect{10, 5, 15, 10}
+	require.Equal(t, Rect{0, 0, 15, 20}, r.Union(o))
+	require.Equal(t, Rect{10, 5, 10, 10}, r.Intersect(o))
+	require.True(t, r.Intersect(o).IsEmpty())
+	require.False(t, r.Overlaps(o))
+	require.True(t, r.Touches(o))
+	require.False(t, r.Touches(Rect{11, 5, 15, 10}))
+	require.True(t, r.Overlaps(Rect{9, 5, 15, 10}))
+
+	// The lines have no area but touch the rectangles they cross.
+	line := EmptyRect().Extend(-5, 10).Extend(5, 10)
+	require.True(t, line.IsEmpty())
+	require.True(t, r.Touches(line))
+}
+
+func TestNewMatrixFromObjects(t *testing.T) {
+	m, ok := NewMatrixFromObjects([]core.PdfObject{core.MakeInteger(2), core.MakeFloat(0),
+		core.MakeFloat(0), core.MakeFloat(3), core.MakeInteger(10), core.MakeFloat(20.5)})
+	require.True(t, ok)
+	require.Equal(t, NewMatrix(2, 0, 0, 3, 10, 20.5), m)
+
+	_, ok = NewMatrixFromObjects([]core.PdfObject{core.MakeInteger(1), core.MakeInteger(0)})
+	require.False(t, ok)
+	_, ok = NewMatrixFromObjects([]core.PdfObject{core.MakeName("a"), core.MakeFloat(0),
+		core.MakeFloat(0), core.MakeFloat(1), core.MakeFloat(0), core.MakeFloat(0)})
+	require.False(t, ok)
+}
@@ -27,6 +27,12 @@ type PdfAppender struct {
 	pages    []*PdfPage
 	acroForm *PdfAcroForm
 
+	// Document presentation entries of the catalog.
+	viewerPreferences *ViewerPreferences
+	pageMode          PageMode
+	pageLayout        PageLayout
+	openAction        *OpenAction
+
 	xrefs          core.XrefTable
 	xrefOffset     int64
 	greatestObjNum int
@@ -465,7 +471,12 @@ func (a *PdfAppender) ReplacePage(pageNum int, page *PdfPage) {
 
 // Sign signs a specific page with a digital signature.
 // The signature field parameter must have a valid signature dictionary
-// specified by its V field.
+// specified by its V field. The field can either be a new signature field,
+// which is added to the specified page, or an existing unsigned signature
+// field of the document (e.g. created using AddSignatureField).
+// If the field has a seed value dictionary, the signature must satisfy its
+// required constraints. If the field has a lock dictionary, the fields it
+// specifies are marked as read-only.
 func (a *PdfAppender) Sign(pageNum int, field *PdfFieldSignature) error {
 	if field == nil {
 		return errors.New("signature field cannot be nil")
@@ -475,7 +486,80 @@ func (a *PdfAppender) Sign(pageNum int, field *PdfFieldSignature) error {
 	if signature == nil {
 		return errors.New("signature dictionary cannot be nil")
 	}
+	sv, err := field.GetSeedValue()
+	if err != nil {
+		return err
+	}
+	if sv != nil {
+		if err := sv.CheckSignature(signature); err != nil {
+			return err
+		}
+	}
+	lock, err := field.GetLock()
+	if err != nil {
+		return err
+	}
+
+	// Add signature field to the form.
+	if a.acroForm == a.roReader.AcroForm {
+		a.acroForm = a.Reader.AcroForm
+	}
+	acroForm := a.acroForm
+	if acroForm == nil {
+		acroForm = NewPdfAcroForm()
+	}
+
+	var exists bool
+	for _, f := range acroForm.AllFields() {
+		if f == field.PdfField {
+			exists = true
+			break
+		}
+	}
+	if !exists {
+		if err := a.addFieldToPage(pageNum, acroForm, field); err != nil {
+			return err
+		}
+	}
+	if lock != nil {
+		lockFields(acroForm, field, lock)
+	}
+
+	acroForm.SigFlags = core.MakeInteger(3)
+	a.ReplaceAcroForm(acroForm)
+	return nil
+}
+
+// AddSignatureField adds an unsigned signature field to the specified page.
+// The field can be created using NewPdfFieldSignature with a nil signature.
+// It can be signed at a later time by loading the output document, setting
+// the V field of the signature field and passing it to Sign.
+func (a *PdfAppender) AddSignatureField(pageNum int, field *PdfFieldSignature) error {
+	if field == nil {
+		return errors.New("signature field cannot be nil")
+	}
+	if field.V != nil {
+		return errors.New("signature field must be unsigned")
+	}
+
+	if a.acroForm == a.roReader.AcroForm {
+		a.acroForm = a.Reader.AcroForm
+	}
+	acroForm := a.acroForm
+	if acroForm == nil {
+		acroForm = NewPdfAcroForm()
+	}
+	if err := a.addFieldToPage(pageNum, acroForm, field); err != nil {
+		return err
+	}
+
+	a.ReplaceAcroForm(acroForm)
+	return nil
+}
 
+// addFieldToPage adds the signature field to the specified page and appends
+// it to the fields of the form.
+func (a *PdfAppender) addFieldToPage(pageNum int, acroForm *PdfAcroForm, field *PdfFieldSignature) error {
 	// Get a copy of the selected page.
 	pageIndex := pageNum - 1
 	if pageIndex < 0 || pageIndex > len(a.pages)-1 {
@@ -490,27 +574,37 @@ func (a *PdfAppender) Sign(pageNum int, field *PdfFieldSignature) error {
 	}
 	page.AddAnnotation(field.PdfAnnotationWidget.PdfAnnotation)
 
-	// Add signature field to the form.
-	if a.acroForm == a.roReader.AcroForm {
-		a.acroForm = a.Reader.AcroForm
-	}
-	acroForm := a.acroForm
-	if acroForm == nil {
-		acroForm = NewPdfAcroForm()
-	}
-	acroForm.SigFlags = core.MakeInteger(3)
-
 	fields := append(acroForm.AllFields(), field.PdfField)
 	acroForm.Fields = &fields
-	a.ReplaceAcroForm(acroForm)
 
 	// Replace original page.
 	a.UpdatePage(page)
 	a.pages[pageIndex] = page
-
 	return nil
 }
 
+// lockFields marks the form fields locked by the lock dictionary of the
+// specified signature field as read-only.
+func lockFields(acroForm *PdfAcroForm, sigField *PdfFieldSignature, lock *PdfSignatureFieldLock) {
+	for _, f := range acroForm.AllFields() {
+		if f == sigField.PdfField {
+			continue
+		}
+		name, err := f.FullName()
+		if err != nil {
+			common.Log.Debug("ERROR: unable to get field name: %v", err)
+			continue
+		}
+		if lock.IsLocked(name) {
+			var flags FieldFlag
+			if f.Ff != nil {
+				flags = FieldFlag(*f.Ff)
+			}
+			f.SetFlag(flags.Set(FieldFlagReadOnly))
+		}
+	}
+}
+
 // ReplaceAcroForm replaces the acrobat form. It appends a new form to the Pdf which
 // replaces the original AcroForm.
 func (a *PdfAppender) ReplaceAcroForm(acroForm *PdfAcroForm) {
@@ -520,6 +614,26 @@ func (a *PdfAppender) ReplaceAcroForm(acroForm *PdfAcroForm) {
 	a.acroForm = acroForm
 }
 
+// SetViewerPreferences sets the viewer preferences of the document.
+func (a *PdfAppender) SetViewerPreferences(prefs *ViewerPreferences) {
+	a.viewerPreferences = prefs
+}
+
+// SetPageMode sets the page mode used when the document is opened.
+func (a *PdfAppender) SetPageMode(mode PageMode) {
+	a.pageMode = mode
+}
+
+// SetPageLayout sets the page layout used when the document is opened.
+func (a *PdfAppender) SetPageLayout(layout PageLayout) {
+	a.pageLayout = layout
+}
+
+// SetOpenAction sets the action performed when the document is opened.
+func (a *PdfAppender) SetOpenAction(action *OpenAction) {
+	a.openAction = action
+}
+
 // Write writes the Appender output to io.Writer.
 // It can only be called once and further invocations will result in an error.
 func (a *PdfAppender) Write(w io.Writer) error {
@@ -558,6 +672,25 @@ func (a *PdfAppender) Write(w io.Writer) error {
 		return errors.New("missing catalog")
 	}
 
+	// Set the document presentation entries.
+	if a.viewerPreferences != nil {
+		writer.SetViewerPreferences(a.viewerPreferences)
+	}
+	writer.SetPageMode(a.pageMode)
+	writer.SetPageLayout(a.pageLayout)
+	if a.openAction != nil {
+		pages := make([]core.PdfObject, len(a.pages))
+		for i, page := range a.pages {
+			pages[i] = page.GetPageAsIndirectObject()
+		}
+		obj, err := a.openAction.toPdfObject(pages)
+		if err != nil {
+			return err
+		}
+		writer.catalog.Set("OpenAction", obj)
+		a.updateObjectsDeep(obj, nil)
+	}
+
 	// Add the keys which are not set.
 	for _, key := range catalog.Keys() {
 		if writer.catalog.Get(key) == nil {
@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
@@ -1819,3 +1820,237 @@ func TestSignatureAppearanceWithTimestamp(t *testing.T) {
 	}
 	validateFile(t, tempFile("appender-signature-appearance-with-timestamp.pdf"))
 }
+
+func TestAppenderTimestampSignLocalTSA(t *testing.T) {
+	tsa, err := sighandler.NewLocalTimestampAuthority()
+	require.NoError(t, err)
+
+	genTime := time.Now().Add(-time.Minute).Truncate(time.Second)
+	tsa.Now = func() time.Time { return genTime }
+
+	server := httptest.NewServer(tsa)
+	defer server.Close()
+
+	clients := map[string]sighandler.TimestampClient{
+		"local": tsa,
+		"http":  sighandler.NewHTTPTimestampClient(server.URL),
+	}
+	for name, client := range clients {
+		t.Run(name, func(t *testing.T) {
+			f, err := os.Open(testPdfFile1)
+			require.NoError(t, err)
+			defer f.Close()
+
+			reader, err := model.NewPdfReader(f)
+			require.NoError(t, err)
+
+			appender, err := model.NewPdfAppender(reader)
+			require.NoError(t, err)
+
+			handler, err := sighandler.NewDocTimeStampWithOpts(client, crypto.SHA256, nil)
+			require.NoError(t, err)
+
+			signature := model.NewPdfSignature(handler)
+			signature.SetName("Test Appender")
+			signature.SetDate(time.Now(), "")
+			require.NoError(t, signature.Initialize())
+
+			sigField := model.NewPdfFieldSignature(signature)
+			sigField.T = core.MakeString("Signature1")
+			sigField.Rect = core.MakeArray(
+				core.MakeInteger(0),
+				core.MakeInteger(0),
+				core.MakeInteger(0),
+				core.MakeInteger(0),
+			)
+			require.NoError(t, appender.Sign(1, sigField))
+
+			buf := bytes.NewBuffer(nil)
+			require.NoError(t, appender.Write(buf))
+			data := buf.Bytes()
+
+			// Validate with the TSA root as trust anchor.
+			reader, err = model.NewPdfReader(bytes.NewReader(data))
+			require.NoError(t, err)
+
+			handler, err = sighandler.NewDocTimeStampWithOpts(nil, 0, &sighandler.DocTimeStampOpts{
+				TrustedRoots: tsa.TrustedRoots(),
+			})
+			require.NoError(t, err)
+
+			res, err := reader.ValidateSignatures([]model.SignatureHandler{handler})
+			require.NoError(t, err)
+			require.Len(t, res, 1)
+			require.Empty(t, res[0].Errors)
+			require.True(t, res[0].IsSigned)
+			require.True(t, res[0].IsVerified)
+			require.True(t, res[0].IsTrusted)
+			require.True(t, genTime.Equal(res[0].GeneralizedTime))
+
+			// Validate with an unrelated trust anchor.
+			other, err := sighandler.NewLocalTimestampAuthority()
+			require.NoError(t, err)
+
+			handler, err = sighandler.NewDocTimeStampWithOpts(nil, 0, &sighandler.DocTimeStampOpts{
+				TrustedRoots: other.TrustedRoots(),
+			})
+			require.NoError(t, err)
+
+			res, err = reader.ValidateSignatures([]model.SignatureHandler{handler})
+			require.NoError(t, err)
+			require.Len(t, res, 1)
+			require.True(t, res[0].IsVerified)
+			require.False(t, res[0].IsTrusted)
+			require.NotEmpty(t, res[0].Errors)
+
+			// Tamper with the signed data.
+			idx := bytes.LastIndex(data, []byte("/Name"))
+			require.True(t, idx > 0)
+			tampered := append([]byte(nil), data...)
+			copy(tampered[idx:], "/Nbme")
+
+			reader, err = model.NewPdfReader(bytes.NewReader(tampered))
+			require.NoError(t, err)
+
+			res, err = reader.ValidateSignatures([]model.SignatureHandler{handler})
+			require.NoError(t, err)
+			require.Len(t, res, 1)
+			require.False(t, res[0].IsVerified)
+		})
+	}
+}
+
+func TestAppenderSignatureFieldSeedValue(t *testing.T) {
+	f, err := os.Open(testPdfAcroFormFile1)
+	require.NoError(t, err)
+	defer f.Close()
+
+	reader, err := model.NewPdfReader(f)
+	require.NoError(t, err)
+
+	fields := reader.AcroForm.AllFields()
+	require.NotEmpty(t, fields)
+	lockedName, err := fields[0].FullName()
+	require.NoError(t, err)
+
+	appender, err := model.NewPdfAppender(reader)
+	require.NoError(t, err)
+
+	// Create an unsigned signature field with seed values and a lock dictionary.
+	sv := model.NewPdfSignatureFieldSeed()
+	sv.SetFlags(model.SignatureSeedFlagSubFilter | model.SignatureSeedFlagReasons |
+		model.SignatureSeedFlagDigestMethod)
+	sv.SubFilter = core.MakeArray(core.MakeName("adbe.pkcs7.detached"))
+	sv.DigestMethod = core.MakeArray(core.MakeName("SHA1"), core.MakeName("SHA256"))
+	sv.Reasons = core.MakeArray(core.MakeString("Approved"))
+	sv.SetMDP(0)
+
+	sigField := model.NewPdfFieldSignature(nil)
+	sigField.T = core.MakeString("CustomerSignature")
+	sigField.Rect = core.MakeArray(
+		core.MakeInteger(50),
+		core.MakeInteger(50),
+		core.MakeInteger(250),
+		core.MakeInteger(100),
+	)
+	sigField.SetSeedValue(sv)
+	sigField.SetLock(model.NewPdfSignatureFieldLock(model.SignatureFieldLockActionInclude, lockedName))
+	require.NoError(t, appender.AddSignatureField(1, sigField))
+
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, appender.Write(buf))
+
+	// Load the prepared document and sign the field.
+	loadSignatureField := func() (*model.PdfReader, *model.PdfFieldSignature) {
+		reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+
+		var sigField *model.PdfFieldSignature
+		for _, field := range reader.AcroForm.AllFields() {
+			if sf, ok := field.GetContext().(*model.PdfFieldSignature); ok && field.PartialName() == "CustomerSignature" {
+				sigField = sf
+			}
+		}
+		require.NotNil(t, sigField)
+		require.Nil(t, sigField.V)
+		sv, err := sigField.GetSeedValue()
+		require.NoError(t, err)
+		require.NotNil(t, sv)
+		require.Equal(t, 1, sv.Reasons.Len())
+		lock, err := sigField.GetLock()
+		require.NoError(t, err)
+		require.NotNil(t, lock)
+		require.True(t, lock.IsLocked(lockedName))
+		return reader, sigField
+	}
+
+	pfxData, err := ioutil.ReadFile(testPKS12Key)
+	require.NoError(t, err)
+	privateKey, cert, err := pkcs12.Decode(pfxData, testPKS12KeyPassword)
+	require.NoError(t, err)
+
+	newSignature := func(reason string) *model.PdfSignature {
+		handler, err := sighandler.NewAdobePKCS7Detached(privateKey.(*rsa.PrivateKey), cert)
+		require.NoError(t, err)
+
+		signature := model.NewPdfSignature(handler)
+		signature.SetName("Test Appender")
+		signature.SetReason(reason)
+		signature.SetDate(time.Now(), "")
+		require.NoError(t, signature.Initialize())
+		return signature
+	}
+
+	// Signing with a reason not allowed by the seed value must fail.
+	reader, sigField = loadSignatureField()
+	appender, err = model.NewPdfAppender(reader)
+	require.NoError(t, err)
+	sigField.V = newSignature("Rejected")
+	require.Error(t, appender.Sign(1, sigField))
+
+	// Signing with a disallowed subfilter must fail.
+	reader, sigField = loadSignatureField()
+	appender, err = model.NewPdfAppender(reader)
+	require.NoError(t, err)
+	handler, err := sighandler.NewAdobeX509RSASHA1(privateKey.(*rsa.PrivateKey), cert)
+	require.NoError(t, err)
+	signature := model.NewPdfSignature(handler)
+	signature.SetReason("Approved")
+	require.NoError(t, signature.Initialize())
+	sigField.V = signature
+	require.Error(t, appender.Sign(1, sigField))
+
+	// Signing according to the seed value constraints.
+	reader, sigField = loadSignatureField()
+	appender, err = model.NewPdfAppender(reader)
+	require.NoError(t, err)
+	sigField.V = newSignature("Approved")
+	require.NoError(t, appender.Sign(1, sigField))
+
+	outBuf := bytes.NewBuffer(nil)
+	require.NoError(t, appender.Write(outBuf))
+
+	reader, err = model.NewPdfReader(bytes.NewReader(outBuf.Bytes()))
+	require.NoError(t, err)
+
+	var sigFields int
+	for _, field := range reader.AcroForm.AllFields() {
+		name, err := field.FullName()
+		require.NoError(t, err)
+		if name == lockedName {
+			require.True(t, field.Flags().Has(model.FieldFlagReadOnly))
+		}
+		if _, ok := field.GetContext().(*model.PdfFieldSignature); ok {
+			sigFields++
+		}
+	}
+	require.Equal(t, 1, sigFields)
+
+	handler, err = sighandler.NewAdobePKCS7Detached(nil, nil)
+	require.NoError(t, err)
+	res, err := reader.ValidateSignatures([]model.SignatureHandler{handler})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.True(t, res[0].IsSigned)
+	require.Equal(t, "Approved", res[0].Reason)
+}
@@ -0,0 +1,154 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"github.com/TheLinker/unipdf/v3/common"
+	"github.com/TheLinker/unipdf/v3/core"
+)
+
+// AFRelationship specifies the relationship between an associated file and
+// the object referring to it.
+// See section 14.13 "Associated Files" (Table 43 - p. 116 ISO 32000-2:2017).
+type AFRelationship string
+
+// Associated file relationships.
+const (
+	AFRelationshipSource           AFRelationship = "Source"
+	AFRelationshipData             AFRelationship = "Data"
+	AFRelationshipAlternative      AFRelationship = "Alternative"
+	AFRelationshipSupplement       AFRelationship = "Supplement"
+	AFRelationshipEncryptedPayload AFRelationship = "EncryptedPayload"
+	AFRelationshipFormData         AFRelationship = "FormData"
+	AFRelationshipSchema           AFRelationship = "Schema"
+	AFRelationshipUnspecified      AFRelationship = "Unspecified"
+)
+
+// GetAFRelationship returns the relationship between the file and the object
+// referring to it as an associated file. The default relationship
+// (Unspecified) is returned if not specified.
+func (f *PdfFilespec) GetAFRelationship() AFRelationship {
+	if rel, ok := core.GetNameVal(f.AFRelationship); ok {
+		return AFRelationship(rel)
+	}
+	return AFRelationshipUnspecified
+}
+
+// SetAFRelationship sets the relationship between the file and the object
+// referring to it as an associated file.
+func (f *PdfFilespec) SetAFRelationship(rel AFRelationship) {
+	if rel == "" {
+		f.AFRelationship = nil
+		return
+	}
+	f.AFRelationship = core.MakeName(string(rel))
+}
+
+// newAssociatedFilesFromPdfObject loads the file specifications of the
+// specified associated files array.
+func newAssociatedFilesFromPdfObject(obj core.PdfObject) ([]*PdfFilespec, error) {
+	if obj == nil {
+		return nil, nil
+	}
+	arr, ok := core.GetArray(obj)
+	if !ok {
+		common.Log.Debug("ERROR: AF not an array (%T)", obj)
+		return nil, core.ErrTypeError
+	}
+
+	var files []*PdfFilespec
+	for _, elem := range arr.Elements() {
+		fs, err := NewPdfFilespecFromObj(core.ResolveReference(elem))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, fs)
+	}
+	return files, nil
+}
+
+// makeAssociatedFiles returns an associated files array containing the
+// specified file specifications, or nil if there are none.
+func makeAssociatedFiles(files []*PdfFilespec) core.PdfObject {
+	if len(files) == 0 {
+		return nil
+	}
+	arr := core.MakeArray()
+	for _, fs := range files {
+		arr.Append(fs.ToPdfObject())
+	}
+	return arr
+}
+
+// GetObjectAssociatedFiles returns the files associated with the specified
+// dictionary object through its AF entry. It can be used for objects which
+// do not have a typed model, such as structure elements and annotations.
+func GetObjectAssociatedFiles(obj core.PdfObject) ([]*PdfFilespec, error) {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return nil, core.ErrTypeError
+	}
+	return newAssociatedFilesFromPdfObject(dict.Get("AF"))
+}
+
+// SetObjectAssociatedFiles sets the files associated with the specified
+// dictionary object (e.g. a structure element). The AF entry is removed if
+// `files` is empty.
+func SetObjectAssociatedFiles(dict *core.PdfObjectDictionary, files []*PdfFilespec) {
+	if obj := makeAssociatedFiles(files); obj != nil {
+		dict.Set("AF", obj)
+		return
+	}
+	dict.Remove("AF")
+}
+
+// GetAssociatedFiles returns the files associated with the page.
+func (p *PdfPage) GetAssociatedFiles() ([]*PdfFilespec, error) {
+	return newAssociatedFilesFromPdfObject(p.AF)
+}
+
+// SetAssociatedFiles sets the files associated with the page.
+func (p *PdfPage) SetAssociatedFiles(files []*PdfFilespec) {
+	p.AF = makeAssociatedFiles(files)
+}
+
+// GetAssociatedFiles returns the files associated with the form XObject.
+func (xform *XObjectForm) GetAssociatedFiles() ([]*PdfFilespec, error) {
+	return newAssociatedFilesFromPdfObject(xform.AF)
+}
+
+// SetAssociatedFiles sets the files associated with the form XObject.
+func (xform *XObjectForm) SetAssociatedFiles(files []*PdfFilespec) {
+	xform.AF = makeAssociatedFiles(files)
+}
+
+// GetAssociatedFiles returns the files associated with the image XObject.
+func (ximg *XObjectImage) GetAssociatedFiles() ([]*PdfFilespec, error) {
+	return newAssociatedFilesFromPdfObject(ximg.AF)
+}
+
+// SetAssociatedFiles sets the files associated with the image XObject.
+func (ximg *XObjectImage) SetAssociatedFiles(files []*PdfFilespec) {
+	ximg.AF = makeAssociatedFiles(files)
+}
+
+// GetAssociatedFiles returns the files associated with the document.
+func (r *PdfReader) GetAssociatedFiles() ([]*PdfFilespec, error) {
+	return newAssociatedFilesFromPdfObject(r.catalog.Get("AF"))
+}
+
+// SetAssociatedFiles sets the files associated with the document.
+// PDF/A-3 requires the associated files to be embedded files, which are also
+// added to the embedded files name tree (see AddEmbeddedFile).
+func (w *PdfWriter) SetAssociatedFiles(files []*PdfFilespec) error {
+	obj := makeAssociatedFiles(files)
+	if obj == nil {
+		w.catalog.Remove("AF")
+		return nil
+	}
+	w.catalog.Set("AF", obj)
+	return w.addObjects(obj)
+}
@@ -0,0 +1,115 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/TheLinker/unipdf/v3/core"
+)
+
+// newAssociatedFile returns a file specification embedding a file with the
+// specified name and content, having the specified relationship.
+func newAssociatedFile(t *testing.T, name, content string, rel AFRelationship) *PdfFilespec {
+	ef := NewPdfEmbeddedFile([]byte(content))
+	fs, err := NewPdfFilespecFromEmbeddedFile(name, ef)
+	require.NoError(t, err)
+	fs.SetAFRelationship(rel)
+	return fs
+}
+
+// requireAssociatedFile checks the name, content and relationship of the
+// single associated file in `files`.
+func requireAssociatedFile(t *testing.T, files []*PdfFilespec, name, content string, rel AFRelationship) {
+	require.Len(t, files, 1)
+	require.Equal(t, name, files[0].GetFileName())
+	require.Equal(t, rel, files[0].GetAFRelationship())
+	ef, err := files[0].GetEmbeddedFile()
+	require.NoError(t, err)
+	require.Equal(t, content, string(ef.Content))
+}
+
+func TestAssociatedFiles(t *testing.T) {
+	xform := NewXObjectForm()
+	xform.BBox = core.MakeArrayFromFloats([]float64{0, 0, 100, 100})
+	xform.Stream = []byte("0 0 100 100 re f")
+	xform.SetAssociatedFiles([]*PdfFilespec{
+		newAssociatedFile(t, "chart.csv", "a,b\n1,2", AFRelationshipData),
+	})
+
+	page := NewPdfPage()
+	page.MediaBox = &PdfRectangle{Llx: 0, Lly: 0, Urx: 612, Ury: 792}
+	require.NoError(t, page.Resources.SetXObjectFormByName("Fm1", xform))
+	require.NoError(t, page.AddContentStreamByString("/Fm1 Do"))
+	page.SetAssociatedFiles([]*PdfFilespec{
+		newAssociatedFile(t, "page.xml", "<page/>", AFRelationshipSource),
+	})
+
+	invoice := newAssociatedFile(t, "invoice.xml", "<invoice/>", AFRelationshipAlternative)
+	writer := NewPdfWriter()
+	require.NoError(t, writer.AddPage(page))
+	require.NoError(t, writer.AddEmbeddedFile("invoice.xml", invoice))
+	require.NoError(t, writer.SetAssociatedFiles([]*PdfFilespec{invoice}))
+
+	var buf bytes.Buffer
+	require.NoError(t, writer.Write(&buf))
+	reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	files, err := reader.GetAssociatedFiles()
+	require.NoError(t, err)
+	requireAssociatedFile(t, files, "invoice.xml", "<invoice/>", AFRelationshipAlternative)
+
+	// Copy the pages to a new document, the page and XObject associated files
+	// are preserved.
+	writer = NewPdfWriter()
+	readPage, err := reader.GetPage(1)
+	require.NoError(t, err)
+	require.NoError(t, writer.AddPage(readPage))
+	buf.Reset()
+	require.NoError(t, writer.Write(&buf))
+	reader, err = NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	files, err = reader.GetAssociatedFiles()
+	require.NoError(t, err)
+	require.Empty(t, files)
+
+	readPage, err = reader.GetPage(1)
+	require.NoError(t, err)
+	files, err = readPage.GetAssociatedFiles()
+	require.NoError(t, err)
+	requireAssociatedFile(t, files, "page.xml", "<page/>", AFRelationshipSource)
+
+	readForm, err := readPage.Resources.GetXObjectFormByName("Fm1")
+	require.NoError(t, err)
+	files, err = readForm.GetAssociatedFiles()
+	require.NoError(t, err)
+	requireAssociatedFile(t, files, "chart.csv", "a,b\n1,2", AFRelationshipData)
+}
+
+func TestObjectAssociatedFiles(t *testing.T) {
+	elem := core.MakeDict()
+	elem.Set("Type", core.MakeName("StructElem"))
+	elem.Set("S", core.MakeName("Table"))
+
+	files, err := GetObjectAssociatedFiles(elem)
+	require.NoError(t, err)
+	require.Empty(t, files)
+
+	fs := newAssociatedFile(t, "table.csv", "1,2,3", AFRelationshipSupplement)
+	SetObjectAssociatedFiles(elem, []*PdfFilespec{fs})
+	files, err = GetObjectAssociatedFiles(elem)
+	require.NoError(t, err)
+	requireAssociatedFile(t, files, "table.csv", "1,2,3", AFRelationshipSupplement)
+
+	SetObjectAssociatedFiles(elem, nil)
+	require.Nil(t, elem.Get("AF"))
+	require.Equal(t, AFRelationshipUnspecified, NewPdfFilespec().GetAFRelationship())
+}
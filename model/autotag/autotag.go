@@ -0,0 +1,348 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package autotag implements the automatic tagging of untagged documents. The layout of the pages
+// is analyzed with the extractor package: the text is divided into headings (by font size),
+// paragraphs, lists and tables, the images become figures, and the running headers and footers
+// are marked as artifacts. Marked content is inserted into the page content streams and a
+// structure tree is generated, producing a best-effort accessible document which should then be
+// reviewed, e.g. with the pdfua package.
+//
+// Example:
+//
+//	var pages []*model.PdfPage
+//	for i := 1; i <= numPages; i++ {
+//	    page, err := reader.GetPage(i)
+//	    if err != nil {
+//	        return err
+//	    }
+//	    pages = append(pages, page)
+//	}
+//	tree, err := autotag.Tag(pages, &autotag.Options{Lang: "en-US"})
+//	if err != nil {
+//	    return err
+//	}
+//	writer := model.NewPdfWriter()
+//	for _, page := range pages {
+//	    if err := writer.AddPage(page); err != nil {
+//	        return err
+//	    }
+//	}
+//	if err := tree.Apply(&writer); err != nil {
+//	    return err
+//	}
+package autotag
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/extractor"
+	"github.com/TheLinker/unipdf/v3/model"
+)
+
+// ErrTagged is returned when tagging pages which already contain tagged content.
+var ErrTagged = errors.New("page already tagged")
+
+// Options defines the options of the tagging.
+type Options struct {
+	// Lang is the natural language of the document, e.g. "en-US".
+	Lang string
+
+	// FigureAlt is the alternate description of the figures. If empty, the figures have no
+	// alternate description, which has to be added when reviewing the document.
+	FigureAlt string
+}
+
+// StructTree is the structure tree generated for the tagged pages.
+type StructTree struct {
+	// Root is the structure tree root.
+	Root *core.PdfIndirectObject
+
+	// Lang is the natural language of the document.
+	Lang string
+}
+
+// Apply sets the structure tree, the marked information and the natural language of the
+// document written by `w`. The tagged pages are expected to be added to `w`.
+// The document title should be set with model.SetPdfTitle and displayed with the
+// DisplayDocTitle viewer preference.
+func (t *StructTree) Apply(w *model.PdfWriter) error {
+	if err := w.SetStructTreeRoot(t.Root); err != nil {
+		return err
+	}
+	markInfo := core.MakeDict()
+	markInfo.Set("Marked", core.MakeBool(true))
+	if err := w.SetMarkInfo(markInfo); err != nil {
+		return err
+	}
+	w.SetLang(t.Lang)
+	return nil
+}
+
+// Tag tags the pages `pages`: marked content is inserted into the content streams and annotations
+// of the pages, and the structure tree referencing them is returned. Pages which already contain
+// tagged content are not supported (ErrTagged).
+func Tag(pages []*model.PdfPage, opts *Options) (*StructTree, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	t := &tagger{
+		opts: opts,
+		root: core.MakeIndirectObject(core.MakeDict()),
+		nums: core.MakeArray(),
+	}
+	t.document = t.newElem("Document", nil, nil)
+	t.document.dict.Set("P", t.root)
+
+	layouts := make([]*extractor.PageLayout, len(pages))
+	for i, page := range pages {
+		if page.StructParents != nil {
+			return nil, fmt.Errorf("page %d: %v", i+1, ErrTagged)
+		}
+		ex, err := extractor.New(page)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %v", i+1, err)
+		}
+		layout, err := ex.ExtractPageLayout()
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %v", i+1, err)
+		}
+		layouts[i] = layout
+	}
+	t.setHeadingLevels(layouts)
+
+	for i, page := range pages {
+		if err := t.tagPage(page, layouts[i]); err != nil {
+			return nil, fmt.Errorf("page %d: %v", i+1, err)
+		}
+	}
+
+	root := t.root.PdfObject.(*core.PdfObjectDictionary)
+	root.Set("Type", core.MakeName("StructTreeRoot"))
+	root.Set("K", t.document.obj)
+	parentTree := core.MakeDict()
+	parentTree.Set("Nums", t.nums)
+	root.Set("ParentTree", parentTree)
+	root.Set("ParentTreeNextKey", core.MakeInteger(t.nextKey))
+	return &StructTree{Root: t.root, Lang: opts.Lang}, nil
+}
+
+// tagger generates the structure tree of the tagged pages.
+type tagger struct {
+	opts     *Options
+	root     *core.PdfIndirectObject
+	document *structElem
+
+	// nums contains the entries of the parent tree, and nextKey is the next key of the tree.
+	nums    *core.PdfObjectArray
+	nextKey int64
+
+	// headingLevels maps the font sizes of the headings to heading levels.
+	headingLevels map[float64]int
+	lastLevel     int
+}
+
+// structElem is a structure element being generated.
+type structElem struct {
+	obj  *core.PdfIndirectObject
+	dict *core.PdfObjectDictionary
+	kids *core.PdfObjectArray
+}
+
+// newElem returns a new structure element of type `typ`, child of `parent` (if not nil), whose
+// content is on page `page` (if not nil).
+func (t *tagger) newElem(typ string, parent *structElem, page *core.PdfIndirectObject) *structElem {
+	elem := &structElem{dict: core.MakeDict(), kids: core.MakeArray()}
+	elem.obj = core.MakeIndirectObject(elem.dict)
+	elem.dict.Set("Type", core.MakeName("StructElem"))
+	elem.dict.Set("S", core.MakeName(typ))
+	if parent != nil {
+		elem.dict.Set("P", parent.obj)
+		parent.kids.Append(elem.obj)
+	}
+	if page != nil {
+		elem.dict.Set("Pg", page)
+	}
+	elem.dict.Set("K", elem.kids)
+	return elem
+}
+
+// setAttributes sets the attributes of owner `owner` of the element.
+func (e *structElem) setAttributes(owner string, attrs map[string]core.PdfObject) {
+	dict := core.MakeDict()
+	dict.Set("O", core.MakeName(owner))
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		dict.Set(core.PdfObjectName(key), attrs[key])
+	}
+	e.dict.Set("A", dict)
+}
+
+// headingSize returns the font size of heading `block` used to determine its level.
+func headingSize(block *extractor.LayoutBlock) float64 {
+	return math.Round(block.FontSize*2) / 2
+}
+
+// setHeadingLevels assigns the heading levels to the font sizes of the headings of the pages, the
+// largest font size being level 1.
+func (t *tagger) setHeadingLevels(layouts []*extractor.PageLayout) {
+	sizes := map[float64]struct{}{}
+	for _, layout := range layouts {
+		for _, block := range layout.Blocks {
+			if block.Type == extractor.LayoutHeading {
+				sizes[headingSize(block)] = struct{}{}
+			}
+		}
+	}
+	var sorted []float64
+	for size := range sizes {
+		sorted = append(sorted, size)
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(sorted)))
+
+	t.headingLevels = map[float64]int{}
+	for i, size := range sorted {
+		level := i + 1
+		if level > 6 {
+			level = 6
+		}
+		t.headingLevels[size] = level
+	}
+}
+
+// headingType returns the structure type of heading `block`. The levels do not skip any level
+// with respect to the previous heading.
+func (t *tagger) headingType(block *extractor.LayoutBlock) string {
+	level := t.headingLevels[headingSize(block)]
+	if level > t.lastLevel+1 {
+		level = t.lastLevel + 1
+	}
+	t.lastLevel = level
+	return fmt.Sprintf("H%d", level)
+}
+
+// tagPage tags the content of page `page` with layout `layout`.
+func (t *tagger) tagPage(page *model.PdfPage, layout *extractor.PageLayout) error {
+	pageObj := page.GetPageAsIndirectObject()
+	targets := map[int]*target{}
+	setTarget := func(tg *target, ops []int) {
+		for _, op := range ops {
+			targets[op] = tg
+		}
+	}
+	leaf := func(typ string, parent *structElem, block *extractor.LayoutBlock) *structElem {
+		elem := t.newElem(typ, parent, pageObj)
+		setTarget(&target{elem: elem, tag: typ}, block.Ops)
+		return elem
+	}
+
+	var list *structElem
+	for _, block := range layout.Blocks {
+		if block.Type != extractor.LayoutListItem {
+			list = nil
+		}
+		if block.Type != extractor.LayoutTable && len(block.Ops) == 0 {
+			continue
+		}
+
+		switch block.Type {
+		case extractor.LayoutHeader, extractor.LayoutFooter:
+			props := core.MakeDict()
+			props.Set("Type", core.MakeName("Pagination"))
+			props.Set("Subtype", core.MakeName(block.Type.String()))
+			setTarget(&target{props: props}, block.Ops)
+		case extractor.LayoutHeading:
+			leaf(t.headingType(block), t.document, block)
+		case extractor.LayoutListItem:
+			if list == nil {
+				list = t.newElem("L", t.document, pageObj)
+			}
+			item := t.newElem("LI", list, pageObj)
+			leaf("LBody", item, block)
+		case extractor.LayoutTable:
+			table := t.newElem("Table", t.document, pageObj)
+			for i, row := range block.Rows {
+				tr := t.newElem("TR", table, pageObj)
+				for _, cell := range row {
+					if i == 0 {
+						th := leaf("TH", tr, cell)
+						th.setAttributes("Table", map[string]core.PdfObject{
+							"Scope": core.MakeName("Column"),
+						})
+						continue
+					}
+					leaf("TD", tr, cell)
+				}
+			}
+		case extractor.LayoutFigure:
+			figure := leaf("Figure", t.document, block)
+			if t.opts.FigureAlt != "" {
+				figure.dict.Set("Alt", core.MakeString(t.opts.FigureAlt))
+			}
+			figure.setAttributes("Layout", map[string]core.PdfObject{
+				"BBox": core.MakeArrayFromFloats([]float64{
+					block.BBox.Llx, block.BBox.Lly, block.BBox.Urx, block.BBox.Ury,
+				}),
+			})
+		default:
+			leaf("P", t.document, block)
+		}
+	}
+
+	// Marked content.
+	parents := core.MakeArray()
+	if err := t.tagContent(page, targets, parents); err != nil {
+		return err
+	}
+	if parents.Len() > 0 {
+		page.StructParents = core.MakeInteger(t.nextKey)
+		t.nums.Append(core.MakeInteger(t.nextKey), parents)
+		t.nextKey++
+	}
+
+	// Annotations.
+	annotations, err := page.GetAnnotations()
+	if err != nil {
+		return err
+	}
+	for _, annot := range annotations {
+		annotObj, ok := core.GetIndirect(annot.GetContainingPdfObject())
+		if !ok {
+			continue
+		}
+		var typ string
+		switch annot.GetContext().(type) {
+		case *model.PdfAnnotationPopup:
+			continue
+		case *model.PdfAnnotationLink:
+			typ = "Link"
+		case *model.PdfAnnotationWidget:
+			typ = "Form"
+		default:
+			typ = "Annot"
+		}
+
+		elem := t.newElem(typ, t.document, pageObj)
+		objr := core.MakeDict()
+		objr.Set("Type", core.MakeName("OBJR"))
+		objr.Set("Obj", annotObj)
+		objr.Set("Pg", pageObj)
+		elem.kids.Append(objr)
+
+		annot.StructParent = core.MakeInteger(t.nextKey)
+		t.nums.Append(core.MakeInteger(t.nextKey), elem.obj)
+		t.nextKey++
+		page.Tabs = core.MakeName("S")
+	}
+	return nil
+}
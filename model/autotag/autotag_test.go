@@ -0,0 +1,153 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package autotag_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/creator"
+	"github.com/TheLinker/unipdf/v3/model"
+	"github.com/TheLinker/unipdf/v3/model/autotag"
+	"github.com/TheLinker/unipdf/v3/model/pdfua"
+)
+
+// createDocument returns the pages of an untagged document with a header and footer, headings,
+// paragraphs, a list, a table and an image.
+func createDocument(t *testing.T) []*model.PdfPage {
+	c := creator.New()
+	c.DrawHeader(func(header *creator.Block, args creator.HeaderFunctionArgs) {
+		p := c.NewParagraph("Annual report")
+		p.SetPos(50, 20)
+		header.Draw(p)
+	})
+	c.DrawFooter(func(footer *creator.Block, args creator.FooterFunctionArgs) {
+		p := c.NewParagraph("Page 1")
+		p.SetPos(50, footer.Height()-30)
+		footer.Draw(p)
+	})
+
+	draw := func(text string, size float64) {
+		p := c.NewParagraph(text)
+		p.SetFontSize(size)
+		p.SetMargins(0, 0, 10, 10)
+		require.NoError(t, c.Draw(p))
+	}
+	draw("Introduction", 24)
+	draw("This report describes the activity of the year.", 10)
+	draw("Results", 18)
+	draw("• First result", 10)
+	draw("• Second result", 10)
+	draw("Sales", 14)
+
+	table := c.NewTable(3)
+	for _, text := range []string{"Region", "Q1", "Q2", "North", "10", "12", "South", "8", "9"} {
+		p := c.NewParagraph(text)
+		p.SetFontSize(10)
+		require.NoError(t, table.NewCell().SetContent(p))
+	}
+	require.NoError(t, c.Draw(table))
+
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	for i := 0; i < 20; i++ {
+		img.Set(i, i, color.RGBA{R: 255, A: 255})
+	}
+	cimg, err := c.NewImageFromGoImage(img)
+	require.NoError(t, err)
+	cimg.SetMargins(0, 0, 20, 0)
+	require.NoError(t, c.Draw(cimg))
+
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	return []*model.PdfPage{page}
+}
+
+// structTypes returns the types of the structure elements of the tree with root `root`.
+func structTypes(root core.PdfObject) []string {
+	var types []string
+	var visit func(obj core.PdfObject)
+	visit = func(obj core.PdfObject) {
+		switch t := core.TraceToDirectObject(obj).(type) {
+		case *core.PdfObjectArray:
+			for _, kid := range t.Elements() {
+				visit(kid)
+			}
+		case *core.PdfObjectDictionary:
+			if typ, ok := core.GetNameVal(t.Get("S")); ok {
+				types = append(types, typ)
+			}
+			visit(t.Get("K"))
+		}
+	}
+	if dict, ok := core.GetDict(root); ok {
+		visit(dict.Get("K"))
+	}
+	return types
+}
+
+func TestTag(t *testing.T) {
+	pages := createDocument(t)
+	tree, err := autotag.Tag(pages, &autotag.Options{Lang: "en-US", FigureAlt: "Chart"})
+	require.NoError(t, err)
+
+	types := structTypes(tree.Root)
+	require.Equal(t, []string{
+		"Document", "H1", "P", "H2", "L", "LI", "LBody", "LI", "LBody", "H3",
+		"Table", "TR", "TH", "TH", "TH", "TR", "TD", "TD", "TD", "TR", "TD", "TD", "TD",
+		"Figure",
+	}, types)
+
+	// All the content is tagged consistently with the structure tree.
+	catalog := core.MakeDict()
+	catalog.Set("StructTreeRoot", tree.Root)
+	catalog.Set("Lang", core.MakeString(tree.Lang))
+	doc := &model.StandardDocument{Catalog: catalog}
+	for _, page := range pages {
+		page.ToPdfObject()
+		doc.Pages = append(doc.Pages, page.GetPageAsIndirectObject())
+	}
+	for _, v := range pdfua.Validate(doc) {
+		switch v.Rule {
+		case pdfua.RuleMarked, pdfua.RuleMetadata, pdfua.RuleTitle, pdfua.RuleFont:
+		default:
+			t.Errorf("unexpected violation: %s", v)
+		}
+	}
+
+	// Tagging twice is not supported.
+	_, err = autotag.Tag(pages, nil)
+	require.Error(t, err)
+
+	// Write and read back.
+	w := model.NewPdfWriter()
+	for _, page := range pages {
+		require.NoError(t, w.AddPage(page))
+	}
+	require.NoError(t, tree.Apply(&w))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	written, err := reader.GetStandardDocument()
+	require.NoError(t, err)
+	require.Equal(t, types, structTypes(written.Catalog.Get("StructTreeRoot")))
+	lang, _ := core.GetStringVal(written.Catalog.Get("Lang"))
+	require.Equal(t, "en-US", lang)
+	markInfo, ok := core.GetDict(written.Catalog.Get("MarkInfo"))
+	require.True(t, ok)
+	marked, _ := core.GetBoolVal(markInfo.Get("Marked"))
+	require.True(t, marked)
+}
@@ -0,0 +1,166 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package autotag
+
+import (
+	"github.com/TheLinker/unipdf/v3/contentstream"
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/model"
+)
+
+// target is the marked content of content stream operations: the content of a structure element
+// (`elem` set) or an artifact with properties `props` (if not nil).
+type target struct {
+	elem  *structElem
+	tag   string
+	props *core.PdfObjectDictionary
+}
+
+// artifact is the target of the content which does not belong to a layout block, like the
+// decorative paths.
+var artifact = &target{}
+
+// Content stream operators.
+var (
+	pathOperators = map[string]struct{}{
+		"m": {}, "l": {}, "c": {}, "v": {}, "y": {}, "h": {}, "re": {}, "W": {}, "W*": {},
+	}
+	paintingOperators = map[string]struct{}{
+		"Tj": {}, "TJ": {}, "'": {}, "\"": {},
+		"S": {}, "s": {}, "f": {}, "F": {}, "f*": {}, "B": {}, "B*": {}, "b": {}, "b*": {},
+		"Do": {}, "sh": {}, "BI": {},
+	}
+	// boundaryOperators are the operators which cannot be crossed by the inserted marked content.
+	boundaryOperators = map[string]struct{}{
+		"BT": {}, "ET": {}, "q": {}, "Q": {}, "BMC": {}, "BDC": {}, "EMC": {}, "BX": {}, "EX": {},
+	}
+)
+
+// tagContent inserts marked content into the content stream of page `page`: the painting
+// operations are marked as the content of the structure elements in `targets` (by operation
+// index), or as artifacts. The structure elements of the marked content are appended to
+// `parents`, the index being the MCID.
+func (t *tagger) tagContent(page *model.PdfPage, targets map[int]*target, parents *core.PdfObjectArray) error {
+	contents, err := page.GetAllContentStreams()
+	if err != nil {
+		return err
+	}
+	ops, err := contentstream.NewContentStreamParser(contents).Parse()
+	if err != nil {
+		return err
+	}
+
+	var out contentstream.ContentStreamOperations
+	var open *target
+	var pending contentstream.ContentStreamOperations
+	var marked []bool // Existing marked content, true for artifacts.
+	inArtifact := func() bool {
+		for _, a := range marked {
+			if a {
+				return true
+			}
+		}
+		return false
+	}
+	flush := func() {
+		out = append(out, pending...)
+		pending = nil
+	}
+	closeOpen := func() {
+		if open != nil {
+			out = append(out, &contentstream.ContentStreamOperation{Operand: "EMC"})
+			open = nil
+		}
+	}
+
+	for i, op := range *ops {
+		if _, ok := pathOperators[op.Operand]; ok {
+			pending = append(pending, op)
+			continue
+		}
+		if op.Operand == "n" {
+			flush()
+			out = append(out, op)
+			continue
+		}
+
+		if _, ok := paintingOperators[op.Operand]; ok {
+			tg := targets[i]
+			if tg == nil {
+				tg = artifact
+			}
+			if inArtifact() {
+				tg = nil
+			}
+			if tg != open {
+				closeOpen()
+				if tg != nil {
+					out = append(out, beginMarkedContent(tg, parents))
+					open = tg
+				}
+			}
+			flush()
+			out = append(out, op)
+			continue
+		}
+
+		flush()
+		if _, ok := boundaryOperators[op.Operand]; ok {
+			closeOpen()
+			switch op.Operand {
+			case "BMC", "BDC":
+				var tag core.PdfObjectName
+				if len(op.Params) > 0 {
+					if name, ok := core.GetName(op.Params[0]); ok {
+						tag = *name
+					}
+				}
+				if op.Operand == "BDC" && len(op.Params) == 2 {
+					if props, ok := core.GetDict(op.Params[1]); ok && props.Get("MCID") != nil {
+						return ErrTagged
+					}
+				}
+				marked = append(marked, tag == "Artifact")
+			case "EMC":
+				if n := len(marked); n > 0 {
+					marked = marked[:n-1]
+				}
+			}
+		}
+		out = append(out, op)
+	}
+	flush()
+	closeOpen()
+
+	return page.SetContentStreams([]string{string(out.Bytes())}, core.NewFlateEncoder())
+}
+
+// beginMarkedContent returns the operation beginning the marked content of target `tg`. The
+// structure element of the content is added to `parents`.
+func beginMarkedContent(tg *target, parents *core.PdfObjectArray) *contentstream.ContentStreamOperation {
+	if tg.elem == nil {
+		if tg.props == nil {
+			return &contentstream.ContentStreamOperation{
+				Operand: "BMC",
+				Params:  []core.PdfObject{core.MakeName("Artifact")},
+			}
+		}
+		return &contentstream.ContentStreamOperation{
+			Operand: "BDC",
+			Params:  []core.PdfObject{core.MakeName("Artifact"), tg.props},
+		}
+	}
+
+	mcid := int64(parents.Len())
+	parents.Append(tg.elem.obj)
+	tg.elem.kids.Append(core.MakeInteger(mcid))
+	props := core.MakeDict()
+	props.Set("MCID", core.MakeInteger(mcid))
+	return &contentstream.ContentStreamOperation{
+		Operand: "BDC",
+		Params:  []core.PdfObject{core.MakeName(tg.tag), props},
+	}
+}
@@ -0,0 +1,439 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/TheLinker/unipdf/v3/core"
+)
+
+// CollectionView specifies how the embedded files of a portable collection
+// are initially presented.
+type CollectionView string
+
+// Collection views.
+const (
+	CollectionViewDetails CollectionView = "D"
+	CollectionViewTile    CollectionView = "T"
+	CollectionViewHidden  CollectionView = "H"
+	CollectionViewCustom  CollectionView = "C"
+)
+
+// CollectionFieldType specifies the type of data of a collection field.
+type CollectionFieldType string
+
+// Collection field types. The text, date and number fields are provided by
+// the collection items of the embedded files, the other fields are provided
+// by the file specifications and the embedded file streams.
+const (
+	CollectionFieldText           CollectionFieldType = "S"
+	CollectionFieldDate           CollectionFieldType = "D"
+	CollectionFieldNumber         CollectionFieldType = "N"
+	CollectionFieldFileName       CollectionFieldType = "F"
+	CollectionFieldDescription    CollectionFieldType = "Desc"
+	CollectionFieldModDate        CollectionFieldType = "ModDate"
+	CollectionFieldCreationDate   CollectionFieldType = "CreationDate"
+	CollectionFieldSize           CollectionFieldType = "Size"
+	CollectionFieldCompressedSize CollectionFieldType = "CompressedSize"
+)
+
+// PdfCollectionField represents a collection field dictionary, describing a
+// field of the collection schema.
+// See section 12.3.5 "Collections" (Table 156 - p. 371 PDF32000_2008).
+type PdfCollectionField struct {
+	// Key is the key of the field in the schema and the collection items.
+	Key string
+
+	// Subtype is the type of data of the field.
+	Subtype CollectionFieldType
+
+	// Name is the name of the field displayed by the viewer application.
+	Name string
+
+	// Order is the relative order of the field in the user interface.
+	Order int
+
+	// Visible specifies whether the field is initially visible.
+	Visible bool
+
+	// Editable specifies whether the field values can be edited.
+	Editable bool
+}
+
+// NewPdfCollectionField returns a new visible collection field.
+func NewPdfCollectionField(key string, subtype CollectionFieldType, name string) *PdfCollectionField {
+	return &PdfCollectionField{
+		Key:     key,
+		Subtype: subtype,
+		Name:    name,
+		Visible: true,
+	}
+}
+
+// ToPdfObject returns a PDF object representation of the collection field.
+func (f *PdfCollectionField) ToPdfObject() core.PdfObject {
+	dict := core.MakeDict()
+	dict.Set("Type", core.MakeName("CollectionField"))
+	dict.Set("Subtype", core.MakeName(string(f.Subtype)))
+	dict.Set("N", core.MakeEncodedString(f.Name, true))
+	dict.Set("O", core.MakeInteger(int64(f.Order)))
+	dict.Set("V", core.MakeBool(f.Visible))
+	dict.Set("E", core.MakeBool(f.Editable))
+	return dict
+}
+
+// PdfCollectionSort represents a collection sort dictionary, which specifies
+// the order in which the embedded files are presented.
+// See section 12.3.5 "Collections" (Table 158 - p. 372 PDF32000_2008).
+type PdfCollectionSort struct {
+	// Keys are the schema field keys used for sorting, by decreasing priority.
+	Keys []string
+
+	// Ascending specifies the sort order of each key. If it contains a single
+	// value, the value applies to all the keys. If empty, the keys are sorted
+	// in ascending order.
+	Ascending []bool
+}
+
+// ToPdfObject returns a PDF object representation of the collection sort.
+func (s *PdfCollectionSort) ToPdfObject() core.PdfObject {
+	dict := core.MakeDict()
+	dict.Set("Type", core.MakeName("CollectionSort"))
+	if len(s.Keys) == 1 {
+		dict.Set("S", core.MakeName(s.Keys[0]))
+	} else {
+		keys := core.MakeArray()
+		for _, key := range s.Keys {
+			keys.Append(core.MakeName(key))
+		}
+		dict.Set("S", keys)
+	}
+
+	switch len(s.Ascending) {
+	case 0:
+	case 1:
+		dict.Set("A", core.MakeBool(s.Ascending[0]))
+	default:
+		ascending := core.MakeArray()
+		for _, a := range s.Ascending {
+			ascending.Append(core.MakeBool(a))
+		}
+		dict.Set("A", ascending)
+	}
+	return dict
+}
+
+// PdfCollection represents a collection dictionary, which turns the document
+// into a portable collection (PDF portfolio) of its embedded files.
+// See section 12.3.5 "Collections" (Table 155 - p. 370 PDF32000_2008).
+type PdfCollection struct {
+	// Schema contains the fields of the collection items, displayed by
+	// the viewer application.
+	Schema []*PdfCollectionField
+
+	// InitialDocument is the name of the embedded file initially presented.
+	// If empty, the document containing the collection is presented.
+	InitialDocument string
+
+	// View specifies how the collection is initially presented.
+	View CollectionView
+
+	// Sort specifies the order in which the embedded files are presented.
+	Sort *PdfCollectionSort
+}
+
+// NewPdfCollection returns a new collection presented in details mode.
+func NewPdfCollection() *PdfCollection {
+	return &PdfCollection{View: CollectionViewDetails}
+}
+
+// GetField returns the schema field having the specified key, or nil if the
+// schema does not contain such a field.
+func (c *PdfCollection) GetField(key string) *PdfCollectionField {
+	for _, field := range c.Schema {
+		if field.Key == key {
+			return field
+		}
+	}
+	return nil
+}
+
+// newPdfCollectionFromPdfObject loads the collection from the specified
+// dictionary object.
+func newPdfCollectionFromPdfObject(obj core.PdfObject) (*PdfCollection, error) {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return nil, ErrTypeCheck
+	}
+
+	c := &PdfCollection{}
+	if str, ok := core.GetString(dict.Get("D")); ok {
+		c.InitialDocument = str.Decoded()
+	}
+	view, _ := core.GetNameVal(dict.Get("View"))
+	c.View = CollectionView(view)
+	if c.View == "" {
+		c.View = CollectionViewDetails
+	}
+
+	if schema, ok := core.GetDict(dict.Get("Schema")); ok {
+		for _, key := range schema.Keys() {
+			fieldDict, ok := core.GetDict(schema.Get(key))
+			if !ok {
+				continue
+			}
+			field := &PdfCollectionField{Key: string(key), Visible: true}
+			subtype, _ := core.GetNameVal(fieldDict.Get("Subtype"))
+			field.Subtype = CollectionFieldType(subtype)
+			if name, ok := core.GetString(fieldDict.Get("N")); ok {
+				field.Name = name.Decoded()
+			}
+			if order, ok := core.GetIntVal(fieldDict.Get("O")); ok {
+				field.Order = order
+			}
+			if visible, ok := core.GetBoolVal(fieldDict.Get("V")); ok {
+				field.Visible = visible
+			}
+			field.Editable, _ = core.GetBoolVal(fieldDict.Get("E"))
+			c.Schema = append(c.Schema, field)
+		}
+		sort.SliceStable(c.Schema, func(i, j int) bool {
+			return c.Schema[i].Order < c.Schema[j].Order
+		})
+	}
+
+	if sortDict, ok := core.GetDict(dict.Get("Sort")); ok {
+		s := &PdfCollectionSort{}
+		if key, ok := core.GetNameVal(sortDict.Get("S")); ok {
+			s.Keys = []string{key}
+		} else if keys, ok := core.GetArray(sortDict.Get("S")); ok {
+			for _, obj := range keys.Elements() {
+				if key, ok := core.GetNameVal(obj); ok {
+					s.Keys = append(s.Keys, key)
+				}
+			}
+		}
+		if a, ok := core.GetBoolVal(sortDict.Get("A")); ok {
+			s.Ascending = []bool{a}
+		} else if arr, ok := core.GetArray(sortDict.Get("A")); ok {
+			for _, obj := range arr.Elements() {
+				a, _ := core.GetBoolVal(obj)
+				s.Ascending = append(s.Ascending, a)
+			}
+		}
+		c.Sort = s
+	}
+	return c, nil
+}
+
+// ToPdfObject returns a PDF object representation of the collection.
+func (c *PdfCollection) ToPdfObject() core.PdfObject {
+	dict := core.MakeDict()
+	dict.Set("Type", core.MakeName("Collection"))
+	if len(c.Schema) > 0 {
+		schema := core.MakeDict()
+		schema.Set("Type", core.MakeName("CollectionSchema"))
+		for _, field := range c.Schema {
+			schema.Set(core.PdfObjectName(field.Key), field.ToPdfObject())
+		}
+		dict.Set("Schema", schema)
+	}
+	if c.InitialDocument != "" {
+		dict.Set("D", core.MakeEncodedString(c.InitialDocument, true))
+	}
+	if c.View != "" {
+		dict.Set("View", core.MakeName(string(c.View)))
+	}
+	if c.Sort != nil && len(c.Sort.Keys) > 0 {
+		dict.Set("Sort", c.Sort.ToPdfObject())
+	}
+	return dict
+}
+
+// PdfCollectionItemValue represents the value of a collection item field,
+// which is a text string, a date or a number, with an optional prefix
+// displayed before the value.
+type PdfCollectionItemValue struct {
+	Value  core.PdfObject
+	Prefix string
+}
+
+// Text returns the value of a text field.
+func (v *PdfCollectionItemValue) Text() (string, bool) {
+	str, ok := core.GetString(v.Value)
+	if !ok {
+		return "", false
+	}
+	return str.Decoded(), true
+}
+
+// Number returns the value of a number field.
+func (v *PdfCollectionItemValue) Number() (float64, bool) {
+	val, err := core.GetNumberAsFloat(core.TraceToDirectObject(v.Value))
+	return val, err == nil
+}
+
+// Date returns the value of a date field.
+func (v *PdfCollectionItemValue) Date() (time.Time, bool) {
+	str, ok := core.GetStringVal(v.Value)
+	if !ok {
+		return time.Time{}, false
+	}
+	date, err := NewPdfDate(str)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return date.ToGoTime(), true
+}
+
+// PdfCollectionItem represents a collection item dictionary, containing the
+// values of the collection schema fields for an embedded file.
+// See section 12.3.5 "Collections" (Table 157 - p. 372 PDF32000_2008).
+type PdfCollectionItem struct {
+	// Fields contains the field values, keyed by schema field key.
+	Fields map[string]*PdfCollectionItemValue
+}
+
+// NewPdfCollectionItem returns a new empty collection item.
+func NewPdfCollectionItem() *PdfCollectionItem {
+	return &PdfCollectionItem{Fields: map[string]*PdfCollectionItemValue{}}
+}
+
+// SetText sets the value of a text field.
+func (ci *PdfCollectionItem) SetText(key, val string) {
+	ci.Fields[key] = &PdfCollectionItemValue{Value: core.MakeEncodedString(val, true)}
+}
+
+// SetNumber sets the value of a number field.
+func (ci *PdfCollectionItem) SetNumber(key string, val float64) {
+	ci.Fields[key] = &PdfCollectionItemValue{Value: core.MakeFloat(val)}
+}
+
+// SetDate sets the value of a date field.
+func (ci *PdfCollectionItem) SetDate(key string, val time.Time) error {
+	date, err := NewPdfDateFromTime(val)
+	if err != nil {
+		return err
+	}
+	ci.Fields[key] = &PdfCollectionItemValue{Value: date.ToPdfObject()}
+	return nil
+}
+
+// Get returns the value of the field having the specified key, or nil if
+// the collection item does not contain the field.
+func (ci *PdfCollectionItem) Get(key string) *PdfCollectionItemValue {
+	return ci.Fields[key]
+}
+
+// newPdfCollectionItemFromPdfObject loads the collection item from the
+// specified dictionary object.
+func newPdfCollectionItemFromPdfObject(obj core.PdfObject) (*PdfCollectionItem, error) {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return nil, ErrTypeCheck
+	}
+
+	ci := NewPdfCollectionItem()
+	for _, key := range dict.Keys() {
+		if key == "Type" {
+			continue
+		}
+		val := core.TraceToDirectObject(dict.Get(key))
+		subitem, ok := val.(*core.PdfObjectDictionary)
+		if !ok {
+			ci.Fields[string(key)] = &PdfCollectionItemValue{Value: val}
+			continue
+		}
+
+		// Collection subitem dictionary.
+		item := &PdfCollectionItemValue{Value: core.TraceToDirectObject(subitem.Get("D"))}
+		if prefix, ok := core.GetString(subitem.Get("P")); ok {
+			item.Prefix = prefix.Decoded()
+		}
+		ci.Fields[string(key)] = item
+	}
+	return ci, nil
+}
+
+// ToPdfObject returns a PDF object representation of the collection item.
+func (ci *PdfCollectionItem) ToPdfObject() core.PdfObject {
+	dict := core.MakeDict()
+	dict.Set("Type", core.MakeName("CollectionItem"))
+
+	keys := make([]string, 0, len(ci.Fields))
+	for key := range ci.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		item := ci.Fields[key]
+		if item.Prefix == "" {
+			dict.Set(core.PdfObjectName(key), item.Value)
+			continue
+		}
+		subitem := core.MakeDict()
+		subitem.Set("Type", core.MakeName("CollectionSubitem"))
+		subitem.Set("D", item.Value)
+		subitem.Set("P", core.MakeEncodedString(item.Prefix, true))
+		dict.Set(core.PdfObjectName(key), subitem)
+	}
+	return dict
+}
+
+// GetCollectionItem returns the collection item of the file specification.
+// A nil value is returned if the file specification has no collection item.
+func (f *PdfFilespec) GetCollectionItem() (*PdfCollectionItem, error) {
+	if f.CI == nil {
+		return nil, nil
+	}
+	return newPdfCollectionItemFromPdfObject(f.CI)
+}
+
+// SetCollectionItem sets the collection item of the file specification.
+func (f *PdfFilespec) SetCollectionItem(ci *PdfCollectionItem) {
+	if ci == nil {
+		f.CI = nil
+		return
+	}
+	f.CI = ci.ToPdfObject()
+}
+
+// GetCollection returns the collection of the document. A nil value is
+// returned if the document is not a portable collection.
+func (r *PdfReader) GetCollection() (*PdfCollection, error) {
+	obj := r.catalog.Get("Collection")
+	if obj == nil {
+		return nil, nil
+	}
+	return newPdfCollectionFromPdfObject(obj)
+}
+
+// SetCollection turns the output file into a portable collection of its
+// embedded files. The pages of the output file are used as the cover sheet,
+// presented by viewer applications which do not support collections.
+func (w *PdfWriter) SetCollection(collection *PdfCollection) error {
+	if collection == nil {
+		w.catalog.Remove("Collection")
+		return nil
+	}
+	for _, field := range collection.Schema {
+		if field.Key == "" || field.Key == "Type" {
+			return errors.New("invalid collection field key")
+		}
+	}
+
+	// Collections require PDF 1.7.
+	if w.majorVersion == 1 && w.minorVersion < 7 {
+		w.minorVersion = 7
+	}
+
+	obj := collection.ToPdfObject()
+	w.catalog.Set("Collection", obj)
+	return w.addObjects(obj)
+}
@@ -0,0 +1,135 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/TheLinker/unipdf/v3/core"
+)
+
+func TestCollectionWriteRead(t *testing.T) {
+	writer := NewPdfWriter()
+	cover := NewPdfPage()
+	cover.MediaBox = &PdfRectangle{Llx: 0, Lly: 0, Urx: 612, Ury: 792}
+	require.NoError(t, writer.AddPage(cover))
+
+	modDate := time.Date(2019, 5, 8, 10, 30, 0, 0, time.UTC)
+	files := []struct {
+		Name    string
+		Content string
+		Author  string
+		Pages   float64
+	}{
+		{"report.pdf", "%PDF-1.4 report", "Alice", 12},
+		{"données.txt", "plain text", "Bob", 1},
+	}
+	for _, file := range files {
+		ef := NewPdfEmbeddedFile([]byte(file.Content))
+		ef.Subtype = "text/plain"
+		ef.ModDate = modDate
+		fs, err := NewPdfFilespecFromEmbeddedFile(file.Name, ef)
+		require.NoError(t, err)
+
+		ci := NewPdfCollectionItem()
+		ci.SetText("Author", file.Author)
+		ci.SetNumber("Pages", file.Pages)
+		ci.Fields["Pages"].Prefix = "#"
+		fs.SetCollectionItem(ci)
+		require.NoError(t, writer.AddEmbeddedFile(file.Name, fs))
+	}
+	require.Error(t, writer.AddEmbeddedFile("report.pdf", NewPdfFilespec()))
+
+	collection := NewPdfCollection()
+	collection.InitialDocument = "report.pdf"
+	collection.View = CollectionViewTile
+	author := NewPdfCollectionField("Author", CollectionFieldText, "Author")
+	pages := NewPdfCollectionField("Pages", CollectionFieldNumber, "Pages")
+	pages.Order = 2
+	name := NewPdfCollectionField("Name", CollectionFieldFileName, "File name")
+	name.Order = 1
+	name.Editable = true
+	collection.Schema = []*PdfCollectionField{author, pages, name}
+	collection.Sort = &PdfCollectionSort{Keys: []string{"Author", "Name"}, Ascending: []bool{false, true}}
+	require.NoError(t, writer.SetCollection(collection))
+
+	var buf bytes.Buffer
+	require.NoError(t, writer.Write(&buf))
+
+	reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, core.Version{Major: 1, Minor: 7}, reader.PdfVersion())
+
+	readCollection, err := reader.GetCollection()
+	require.NoError(t, err)
+	require.Equal(t, "report.pdf", readCollection.InitialDocument)
+	require.Equal(t, CollectionViewTile, readCollection.View)
+	require.Equal(t, []*PdfCollectionField{author, name, pages}, readCollection.Schema)
+	require.Equal(t, collection.Sort, readCollection.Sort)
+
+	embedded, err := reader.GetEmbeddedFiles()
+	require.NoError(t, err)
+	require.Len(t, embedded, len(files))
+	for _, file := range files {
+		fs, ok := embedded[file.Name]
+		require.True(t, ok)
+		require.Equal(t, file.Name, fs.GetFileName())
+
+		ef, err := fs.GetEmbeddedFile()
+		require.NoError(t, err)
+		require.Equal(t, file.Content, string(ef.Content))
+		require.Equal(t, "text/plain", ef.Subtype)
+		require.True(t, modDate.Equal(ef.ModDate))
+		require.Len(t, ef.CheckSum, 16)
+
+		ci, err := fs.GetCollectionItem()
+		require.NoError(t, err)
+		text, ok := ci.Get("Author").Text()
+		require.True(t, ok)
+		require.Equal(t, file.Author, text)
+		number, ok := ci.Get("Pages").Number()
+		require.True(t, ok)
+		require.Equal(t, file.Pages, number)
+		require.Equal(t, "#", ci.Get("Pages").Prefix)
+	}
+}
+
+func TestNameTreeEntries(t *testing.T) {
+	leaf := func(keys ...string) *core.PdfObjectDictionary {
+		names := core.MakeArray()
+		for _, key := range keys {
+			names.Append(core.MakeString(key), core.MakeString("value "+key))
+		}
+		dict := core.MakeDict()
+		dict.Set("Names", names)
+		return dict
+	}
+	root := core.MakeDict()
+	root.Set("Kids", core.MakeArray(
+		core.MakeIndirectObject(leaf("a", "b")),
+		core.MakeIndirectObject(leaf("c")),
+	))
+
+	entries, err := nameTreeEntries(root)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	for _, key := range []string{"a", "b", "c"} {
+		val, ok := core.GetStringVal(entries[key])
+		require.True(t, ok)
+		require.Equal(t, "value "+key, val)
+	}
+
+	// Loops are detected.
+	kid := core.MakeIndirectObject(core.MakeDict())
+	kid.PdfObject.(*core.PdfObjectDictionary).Set("Kids", core.MakeArray(kid))
+	root.Set("Kids", core.MakeArray(kid))
+	_, err = nameTreeEntries(root)
+	require.Error(t, err)
+}
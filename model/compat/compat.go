@@ -0,0 +1,212 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+/*
+Package compat converts documents to a target PDF version when writing, so
+that the output does not only declare the version in its header but also
+only uses the features available in that version.
+
+For targets prior to PDF 1.5, object streams and cross reference streams
+are not used, optional content is flattened (hidden content is removed and
+visible content is kept unconditionally) and JPX images are refused, as
+they cannot be transcoded. Encryption algorithms which are not supported by
+the target version are replaced by the strongest supported one, unless
+Options.RefuseEncryptionDowngrade is set.
+
+For PDF 2.0, the deprecated entries are removed, the document information is
+moved to an XMP metadata stream, the file identifier is generated if missing
+and the encryption is upgraded to AES-256.
+
+Example:
+
+	w := model.NewPdfWriter()
+	...
+	w.SetStandard(compat.New(compat.Options{Version: core.Version{Major: 1, Minor: 4}}))
+	err := w.Write(file)
+*/
+package compat
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/model"
+)
+
+// Options define the version conversion options.
+type Options struct {
+	// Version is the target PDF version.
+	Version core.Version
+
+	// RefuseEncryptionDowngrade causes the conversion to fail instead of
+	// replacing the encryption algorithm by a weaker one supported by the
+	// target version.
+	RefuseEncryptionDowngrade bool
+
+	// Now returns the time used as the metadata date.
+	// If not set, time.Now is used.
+	Now func() time.Time
+}
+
+// Converter converts documents to a target PDF version.
+// It implements the model.StandardApplier interface.
+type Converter struct {
+	opts Options
+}
+
+// New returns a new version converter using the specified options.
+func New(opts Options) *Converter {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Converter{opts: opts}
+}
+
+// ApplyStandard converts the document to the target version.
+// Implements model.StandardApplier interface.
+func (c *Converter) ApplyStandard(doc *model.StandardDocument) error {
+	target := c.opts.Version
+	if !isValidVersion(target) {
+		return fmt.Errorf("invalid target version: %s", target)
+	}
+
+	if doc.Encrypted {
+		algo, err := c.encryptionAlgorithm(doc.Encryption)
+		if err != nil {
+			return err
+		}
+		doc.Encryption = algo
+	}
+
+	contents := collectContents(doc)
+	if before(target, 1, 5) {
+		if err := checkJPXImages(contents, target); err != nil {
+			return err
+		}
+		if err := flattenOptionalContent(doc, contents); err != nil {
+			return err
+		}
+	}
+	if !before(target, 2, 0) {
+		if err := c.convertToPdf20(doc, contents); err != nil {
+			return err
+		}
+	}
+
+	doc.Version = target
+	return nil
+}
+
+// encryptionAlgorithm returns the encryption algorithm replacing `algo`
+// for the target version.
+func (c *Converter) encryptionAlgorithm(algo model.EncryptionAlgorithm) (model.EncryptionAlgorithm, error) {
+	target := c.opts.Version
+	if !before(target, 2, 0) {
+		// PDF 2.0 deprecates all the security handlers except AES-256.
+		return model.AES_256bit, nil
+	}
+	if !before(target, algorithmVersion(algo).Major, algorithmVersion(algo).Minor) {
+		return algo, nil
+	}
+	if c.opts.RefuseEncryptionDowngrade {
+		return algo, fmt.Errorf("encryption algorithm requires PDF %s, target version is %s",
+			algorithmVersion(algo), target)
+	}
+
+	for _, candidate := range []model.EncryptionAlgorithm{model.AES_128bit, model.RC4_128bit} {
+		v := algorithmVersion(candidate)
+		if !before(target, v.Major, v.Minor) {
+			return candidate, nil
+		}
+	}
+	return algo, fmt.Errorf("encryption is not supported for PDF %s", target)
+}
+
+// algorithmVersion returns the minimum PDF version supporting the
+// encryption algorithm.
+func algorithmVersion(algo model.EncryptionAlgorithm) core.Version {
+	switch algo {
+	case model.AES_128bit:
+		return core.Version{Major: 1, Minor: 6}
+	case model.AES_256bit:
+		return core.Version{Major: 2, Minor: 0}
+	}
+	return core.Version{Major: 1, Minor: 4}
+}
+
+// convertToPdf20 removes the entries deprecated in PDF 2.0, moves the
+// document information to the XMP metadata and generates the file
+// identifier, which is required in PDF 2.0.
+func (c *Converter) convertToPdf20(doc *model.StandardDocument, contents []*content) error {
+	removeDeprecatedEntries(doc, contents)
+
+	if doc.ID == nil {
+		id, err := newFileID()
+		if err != nil {
+			return err
+		}
+		doc.ID = id
+	}
+	if doc.Catalog.Get("Metadata") == nil {
+		stream, err := doc.NewMetadataStream(c.opts.Now())
+		if err != nil {
+			return err
+		}
+		doc.Catalog.Set("Metadata", stream)
+	}
+
+	// Only the creation and modification dates of the document
+	// information dictionary are not deprecated.
+	if doc.Info != nil {
+		for _, key := range doc.Info.Keys() {
+			if key != "CreationDate" && key != "ModDate" {
+				doc.Info.Remove(key)
+			}
+		}
+	}
+	return nil
+}
+
+// newFileID generates a new file identifier array.
+func newFileID() (*core.PdfObjectArray, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	return core.MakeArray(core.MakeHexString(string(id)), core.MakeHexString(string(id))), nil
+}
+
+// isValidVersion returns true if `v` is a valid PDF version.
+func isValidVersion(v core.Version) bool {
+	return v.Major == 1 && v.Minor >= 0 && v.Minor <= 7 || v.Major == 2 && v.Minor == 0
+}
+
+// before returns true if the version `v` is prior to the version
+// specified by `major` and `minor`.
+func before(v core.Version, major, minor int) bool {
+	return v.Major < major || v.Major == major && v.Minor < minor
+}
+
+// checkJPXImages returns an error if the contents use JPX images, which
+// require PDF 1.5 and cannot be transcoded to other formats.
+func checkJPXImages(contents []*content, target core.Version) error {
+	for _, stream := range xobjects(contents) {
+		if name, _ := core.GetNameVal(stream.Get("Subtype")); name != "Image" {
+			continue
+		}
+		filters := []core.PdfObject{stream.Get("Filter")}
+		if arr, ok := core.GetArray(stream.Get("Filter")); ok {
+			filters = arr.Elements()
+		}
+		for _, filter := range filters {
+			if name, _ := core.GetNameVal(filter); name == core.StreamEncodingFilterNameJPX {
+				return fmt.Errorf("JPX images are not supported in PDF %s: %v", target, core.ErrNoJPXDecode)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package compat_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/core/security"
+	"github.com/TheLinker/unipdf/v3/model"
+	"github.com/TheLinker/unipdf/v3/model/compat"
+	"github.com/TheLinker/unipdf/v3/model/optimize"
+)
+
+// newWriter returns a writer containing a single page with the specified
+// content and resources.
+func newWriter(t *testing.T, content string, resources *model.PdfPageResources) *model.PdfWriter {
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Llx: 0, Lly: 0, Urx: 612, Ury: 792}
+	page.Resources = resources
+	require.NoError(t, page.AddContentStreamByString(content))
+
+	w := model.NewPdfWriter()
+	require.NoError(t, w.AddPage(page))
+	return &w
+}
+
+// write writes the document and returns the reader of the output.
+func write(t *testing.T, w *model.PdfWriter) ([]byte, *model.PdfReader) {
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	return buf.Bytes(), reader
+}
+
+func TestConvertPdf14(t *testing.T) {
+	visible := core.MakeIndirectObject(core.MakeDict())
+	visible.PdfObject.(*core.PdfObjectDictionary).Set("Type", core.MakeName("OCG"))
+	hidden := core.MakeIndirectObject(core.MakeDict())
+	hidden.PdfObject.(*core.PdfObjectDictionary).Set("Type", core.MakeName("OCG"))
+
+	props := core.MakeDict()
+	props.Set("OC1", visible)
+	props.Set("OC2", hidden)
+	resources := model.NewPdfPageResources()
+	resources.Properties = props
+	resources.ProcSet = core.MakeArray(core.MakeName("PDF"))
+
+	content := "/OC /OC1 BDC 0 0 10 10 re f EMC\n/OC /OC2 BDC 20 20 30 30 re f EMC\n/Span BMC 40 40 5 5 re f EMC"
+	w := newWriter(t, content, resources)
+
+	config := core.MakeDict()
+	config.Set("OFF", core.MakeArray(hidden))
+	ocProperties := core.MakeDict()
+	ocProperties.Set("OCGs", core.MakeArray(visible, hidden))
+	ocProperties.Set("D", config)
+	require.NoError(t, w.SetOCProperties(ocProperties))
+
+	w.SetVersion(1, 7)
+	w.SetOptimizer(&optimize.ObjectStreams{})
+	w.SetStandard(compat.New(compat.Options{Version: core.Version{Major: 1, Minor: 4}}))
+	data, reader := write(t, w)
+
+	require.True(t, bytes.HasPrefix(data, []byte("%PDF-1.4\n")))
+	require.NotContains(t, string(data), "/ObjStm")
+	require.NotContains(t, string(data), "/XRef")
+	require.Equal(t, core.Version{Major: 1, Minor: 4}, reader.PdfVersion())
+
+	doc, err := reader.GetStandardDocument()
+	require.NoError(t, err)
+	require.Nil(t, doc.Catalog.Get("OCProperties"))
+
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	contents, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	require.Contains(t, contents, "0 0 10 10 re")
+	require.NotContains(t, contents, "20 20 30 30 re")
+	require.NotContains(t, contents, "BDC")
+	require.Contains(t, contents, "/Span BMC")
+}
+
+func TestConvertEncryption(t *testing.T) {
+	testcases := []struct {
+		Name      string
+		Algorithm model.EncryptionAlgorithm
+		Version   core.Version
+		Refuse    bool
+		V         int
+	}{
+		{"AES-128 to 1.4", model.AES_128bit, core.Version{Major: 1, Minor: 4}, false, 2},
+		{"AES-256 to 1.7", model.AES_256bit, core.Version{Major: 1, Minor: 7}, false, 4},
+		{"RC4 to 2.0", model.RC4_128bit, core.Version{Major: 2, Minor: 0}, false, 5},
+		{"AES-128 to 1.4 refused", model.AES_128bit, core.Version{Major: 1, Minor: 4}, true, 0},
+		{"RC4 to 1.3 refused", model.RC4_128bit, core.Version{Major: 1, Minor: 3}, false, 0},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.Name, func(t *testing.T) {
+			w := newWriter(t, "0 0 10 10 re f", model.NewPdfPageResources())
+			require.NoError(t, w.Encrypt([]byte("user"), []byte("owner"), &model.EncryptOptions{
+				Permissions: security.PermOwner,
+				Algorithm:   tcase.Algorithm,
+			}))
+			w.SetStandard(compat.New(compat.Options{
+				Version:                   tcase.Version,
+				RefuseEncryptionDowngrade: tcase.Refuse,
+			}))
+			if tcase.V == 0 {
+				var buf bytes.Buffer
+				require.Error(t, w.Write(&buf))
+				return
+			}
+
+			_, reader := write(t, w)
+			require.Equal(t, tcase.Version, reader.PdfVersion())
+			trailer, err := reader.GetTrailer()
+			require.NoError(t, err)
+			encrypt, ok := core.GetDict(trailer.Get("Encrypt"))
+			require.True(t, ok)
+			v, ok := core.GetIntVal(encrypt.Get("V"))
+			require.True(t, ok)
+			require.Equal(t, tcase.V, v)
+
+			ok, err = reader.Decrypt([]byte("user"))
+			require.NoError(t, err)
+			require.True(t, ok)
+			page, err := reader.GetPage(1)
+			require.NoError(t, err)
+			contents, err := page.GetAllContentStreams()
+			require.NoError(t, err)
+			require.Contains(t, contents, "0 0 10 10 re")
+		})
+	}
+}
+
+func TestConvertPdf20(t *testing.T) {
+	model.SetPdfTitle("Compatibility")
+	defer model.SetPdfTitle("")
+	resources := model.NewPdfPageResources()
+	resources.ProcSet = core.MakeArray(core.MakeName("PDF"))
+	w := newWriter(t, "0 0 10 10 re f", resources)
+
+	w.SetStandard(compat.New(compat.Options{Version: core.Version{Major: 2, Minor: 0}}))
+	data, reader := write(t, w)
+	require.True(t, bytes.HasPrefix(data, []byte("%PDF-2.0\n")))
+
+	trailer, err := reader.GetTrailer()
+	require.NoError(t, err)
+	require.NotNil(t, trailer.Get("ID"))
+
+	info, ok := core.GetDict(trailer.Get("Info"))
+	require.True(t, ok)
+	require.Nil(t, info.Get("Title"))
+	require.Nil(t, info.Get("Producer"))
+
+	doc, err := reader.GetStandardDocument()
+	require.NoError(t, err)
+	metadata, ok := core.GetStream(doc.Catalog.Get("Metadata"))
+	require.True(t, ok)
+	xmp, err := core.DecodeStream(metadata)
+	require.NoError(t, err)
+	require.Contains(t, string(xmp), "Compatibility")
+
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	require.Nil(t, page.Resources.ProcSet)
+}
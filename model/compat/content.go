@@ -0,0 +1,216 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package compat
+
+import (
+	"bytes"
+
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/internal/pageutil"
+	"github.com/TheLinker/unipdf/v3/model"
+)
+
+// content represents an object having a content stream: a page, a form
+// XObject, a tiling pattern or an annotation appearance stream.
+type content struct {
+	// page is set for pages and stream is set for all other content types.
+	page   *core.PdfObjectDictionary
+	stream *core.PdfObjectStream
+
+	resources *core.PdfObjectDictionary
+}
+
+// data returns the decoded content stream data.
+func (c *content) data() ([]byte, error) {
+	if c.stream != nil {
+		return core.DecodeStream(c.stream)
+	}
+
+	var streams []core.PdfObject
+	contents := core.TraceToDirectObject(c.page.Get("Contents"))
+	if arr, ok := contents.(*core.PdfObjectArray); ok {
+		streams = arr.Elements()
+	} else if contents != nil {
+		streams = []core.PdfObject{contents}
+	}
+
+	var buf bytes.Buffer
+	for _, obj := range streams {
+		stream, ok := core.GetStream(obj)
+		if !ok {
+			continue
+		}
+		data, err := core.DecodeStream(stream)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// setData replaces the content stream data. Page contents consisting of
+// multiple streams are replaced by the first stream.
+func (c *content) setData(data []byte) error {
+	stream := c.stream
+	if stream == nil {
+		contents := c.page.Get("Contents")
+		if arr, ok := core.GetArray(contents); ok {
+			if arr.Len() > 0 {
+				contents = arr.Get(0)
+			}
+		}
+		var ok bool
+		if stream, ok = core.GetStream(contents); !ok {
+			created, err := core.MakeStream(data, core.NewFlateEncoder())
+			if err != nil {
+				return err
+			}
+			c.page.Set("Contents", created)
+			return nil
+		}
+		c.page.Set("Contents", contents)
+	}
+
+	encoder := core.NewFlateEncoder()
+	encoded, err := encoder.EncodeBytes(data)
+	if err != nil {
+		return err
+	}
+	stream.Remove("DecodeParms")
+	stream.Merge(encoder.MakeStreamDict())
+	stream.Set("Length", core.MakeInteger(int64(len(encoded))))
+	stream.Stream = encoded
+	return nil
+}
+
+// collectContents returns the objects having content streams, which are
+// used by the pages of the document.
+func collectContents(doc *model.StandardDocument) []*content {
+	var contents []*content
+	visited := map[*core.PdfObjectStream]bool{}
+
+	var addStream func(stream *core.PdfObjectStream, parentResources *core.PdfObjectDictionary)
+	addResources := func(resources *core.PdfObjectDictionary) {
+		if resources == nil {
+			return
+		}
+		for _, category := range []core.PdfObjectName{"XObject", "Pattern"} {
+			dict, ok := core.GetDict(resources.Get(category))
+			if !ok {
+				continue
+			}
+			for _, key := range dict.Keys() {
+				stream, ok := core.GetStream(dict.Get(key))
+				if !ok {
+					continue
+				}
+				subtype, _ := core.GetNameVal(stream.Get("Subtype"))
+				patternType, _ := core.GetIntVal(stream.Get("PatternType"))
+				if subtype == "Form" || patternType == 1 {
+					addStream(stream, resources)
+				}
+			}
+		}
+	}
+	addStream = func(stream *core.PdfObjectStream, parentResources *core.PdfObjectDictionary) {
+		if visited[stream] {
+			return
+		}
+		visited[stream] = true
+
+		resources, ok := core.GetDict(stream.Get("Resources"))
+		if !ok {
+			resources = parentResources
+		}
+		contents = append(contents, &content{stream: stream, resources: resources})
+		addResources(resources)
+	}
+
+	for _, page := range doc.Pages {
+		dict, ok := core.GetDict(page)
+		if !ok {
+			continue
+		}
+		resources := pageutil.InheritedResources(dict)
+		contents = append(contents, &content{page: dict, resources: resources})
+		addResources(resources)
+
+		annots, _ := core.GetArray(dict.Get("Annots"))
+		for _, annot := range annots.Elements() {
+			annotDict, ok := core.GetDict(annot)
+			if !ok {
+				continue
+			}
+			for _, stream := range appearanceStreams(annotDict) {
+				addStream(stream, nil)
+			}
+		}
+	}
+	return contents
+}
+
+// appearanceStreams returns the appearance streams of the annotation.
+func appearanceStreams(annot *core.PdfObjectDictionary) []*core.PdfObjectStream {
+	ap, ok := core.GetDict(annot.Get("AP"))
+	if !ok {
+		return nil
+	}
+
+	var streams []*core.PdfObjectStream
+	for _, key := range ap.Keys() {
+		obj := ap.Get(key)
+		if stream, ok := core.GetStream(obj); ok {
+			streams = append(streams, stream)
+			continue
+		}
+		states, ok := core.GetDict(obj)
+		if !ok {
+			continue
+		}
+		for _, state := range states.Keys() {
+			if stream, ok := core.GetStream(states.Get(state)); ok {
+				streams = append(streams, stream)
+			}
+		}
+	}
+	return streams
+}
+
+// resourceDicts returns the distinct resource dictionaries of the contents.
+func resourceDicts(contents []*content) []*core.PdfObjectDictionary {
+	var dicts []*core.PdfObjectDictionary
+	seen := map[*core.PdfObjectDictionary]bool{}
+	for _, c := range contents {
+		if c.resources != nil && !seen[c.resources] {
+			seen[c.resources] = true
+			dicts = append(dicts, c.resources)
+		}
+	}
+	return dicts
+}
+
+// xobjects returns the distinct XObjects referenced by the resources
+// of the contents.
+func xobjects(contents []*content) []*core.PdfObjectStream {
+	var streams []*core.PdfObjectStream
+	seen := map[*core.PdfObjectStream]bool{}
+	for _, resources := range resourceDicts(contents) {
+		dict, ok := core.GetDict(resources.Get("XObject"))
+		if !ok {
+			continue
+		}
+		for _, key := range dict.Keys() {
+			stream, ok := core.GetStream(dict.Get(key))
+			if ok && !seen[stream] {
+				seen[stream] = true
+				streams = append(streams, stream)
+			}
+		}
+	}
+	return streams
+}
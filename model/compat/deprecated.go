@@ -0,0 +1,38 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package compat
+
+import (
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/model"
+)
+
+// removeDeprecatedEntries removes the entries deprecated in PDF 2.0 from the
+// catalog and from the resources used by the document contents:
+//   - the ProcSet entries of the resource dictionaries
+//   - the Name entries of the XObject and font dictionaries
+//   - the NeedAppearances entry of the interactive form dictionary.
+func removeDeprecatedEntries(doc *model.StandardDocument, contents []*content) {
+	for _, resources := range resourceDicts(contents) {
+		resources.Remove("ProcSet")
+
+		for _, category := range []core.PdfObjectName{"XObject", "Font"} {
+			dict, ok := core.GetDict(resources.Get(category))
+			if !ok {
+				continue
+			}
+			for _, key := range dict.Keys() {
+				if resource, ok := core.GetDict(dict.Get(key)); ok {
+					resource.Remove("Name")
+				}
+			}
+		}
+	}
+
+	if acroForm, ok := core.GetDict(doc.Catalog.Get("AcroForm")); ok {
+		acroForm.Remove("NeedAppearances")
+	}
+}
@@ -0,0 +1,146 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package compat
+
+import (
+	"github.com/TheLinker/unipdf/v3/common"
+	"github.com/TheLinker/unipdf/v3/contentstream"
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/internal/optcontent"
+	"github.com/TheLinker/unipdf/v3/internal/pageutil"
+	"github.com/TheLinker/unipdf/v3/model"
+)
+
+// flattenOptionalContent removes the optional content from the document:
+// the hidden content is removed and the visible content is kept as regular
+// content.
+func flattenOptionalContent(doc *model.StandardDocument, contents []*content) error {
+	ocProperties, ok := core.GetDict(doc.Catalog.Get("OCProperties"))
+	if !ok {
+		return nil
+	}
+	v := optcontent.NewVisibility(ocProperties)
+
+	for _, c := range contents {
+		data, err := c.data()
+		if err != nil {
+			return err
+		}
+		ops, err := contentstream.NewContentStreamParser(string(data)).Parse()
+		if err != nil {
+			common.Log.Debug("ERROR: unable to parse content stream: %v", err)
+			return err
+		}
+		out, changed := filterOperations(v, *ops, c.resources)
+		if !changed {
+			continue
+		}
+		if err := c.setData(out.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	// Remove the hidden annotations.
+	for _, page := range doc.Pages {
+		dict, ok := core.GetDict(page)
+		if !ok {
+			continue
+		}
+		annots, ok := core.GetArray(dict.Get("Annots"))
+		if !ok {
+			continue
+		}
+		kept := core.MakeArray()
+		for _, annot := range annots.Elements() {
+			if annotDict, ok := core.GetDict(annot); ok {
+				if !v.Visible(annotDict.Get("OC")) {
+					continue
+				}
+				annotDict.Remove("OC")
+			}
+			kept.Append(annot)
+		}
+		dict.Set("Annots", kept)
+	}
+
+	for _, stream := range xobjects(contents) {
+		stream.Remove("OC")
+	}
+	doc.Catalog.Remove("OCProperties")
+	return nil
+}
+
+// filterOperations removes the content hidden according to `v` and the marked
+// content sequences associating content with optional content from `ops`.
+// The returned flag is true if any operations were removed.
+func filterOperations(v *optcontent.Visibility, ops contentstream.ContentStreamOperations,
+	resources *core.PdfObjectDictionary) (contentstream.ContentStreamOperations, bool) {
+	type markedContent struct {
+		optional bool
+		hidden   bool
+	}
+	var (
+		out     contentstream.ContentStreamOperations
+		stack   []markedContent
+		hidden  int
+		changed bool
+	)
+
+	for _, op := range ops {
+		switch op.Operand {
+		case "BMC":
+			stack = append(stack, markedContent{})
+		case "BDC":
+			if len(op.Params) != 2 {
+				stack = append(stack, markedContent{})
+				break
+			}
+			if tag, _ := core.GetNameVal(op.Params[0]); tag != "OC" {
+				stack = append(stack, markedContent{})
+				break
+			}
+			mc := markedContent{
+				optional: true,
+				hidden:   !v.Visible(pageutil.Properties(op.Params[1], resources)),
+			}
+			stack = append(stack, mc)
+			if mc.hidden {
+				hidden++
+			}
+			changed = true
+			continue
+		case "EMC":
+			if n := len(stack); n > 0 {
+				mc := stack[n-1]
+				stack = stack[:n-1]
+				if mc.optional {
+					if mc.hidden {
+						hidden--
+					}
+					continue
+				}
+			}
+		case "Do":
+			if hidden == 0 && len(op.Params) == 1 && resources != nil {
+				name, _ := core.GetName(op.Params[0])
+				xobjects, _ := core.GetDict(resources.Get("XObject"))
+				if name != nil && xobjects != nil {
+					if stream, ok := core.GetStream(xobjects.Get(*name)); ok && !v.Visible(stream.Get("OC")) {
+						changed = true
+						continue
+					}
+				}
+			}
+		}
+
+		if hidden > 0 {
+			changed = true
+			continue
+		}
+		out = append(out, op)
+	}
+	return out, changed
+}
@@ -0,0 +1,315 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"crypto/md5"
+	"errors"
+	"sort"
+	"time"
+	"unicode"
+
+	"github.com/TheLinker/unipdf/v3/common"
+	"github.com/TheLinker/unipdf/v3/core"
+)
+
+// PdfEmbeddedFile represents an embedded file stream, containing the contents
+// of a file embedded in the PDF document.
+// See section 7.11.4 "Embedded File Streams" (p. 104 PDF32000_2008).
+type PdfEmbeddedFile struct {
+	// Content is the (decoded) content of the embedded file.
+	Content []byte
+
+	// Subtype is the MIME type of the embedded file (e.g. application/pdf).
+	Subtype string
+
+	// Creation and modification dates of the file. The zero values are not
+	// written.
+	CreationDate time.Time
+	ModDate      time.Time
+
+	// CheckSum is the MD5 checksum of the file content.
+	CheckSum []byte
+}
+
+// NewPdfEmbeddedFile returns a new embedded file having the specified content.
+func NewPdfEmbeddedFile(content []byte) *PdfEmbeddedFile {
+	sum := md5.Sum(content)
+	return &PdfEmbeddedFile{
+		Content:  content,
+		CheckSum: sum[:],
+	}
+}
+
+// newPdfEmbeddedFileFromPdfObject loads the embedded file from the specified
+// stream object.
+func newPdfEmbeddedFileFromPdfObject(obj core.PdfObject) (*PdfEmbeddedFile, error) {
+	stream, ok := core.GetStream(obj)
+	if !ok {
+		return nil, core.ErrTypeError
+	}
+	content, err := core.DecodeStream(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &PdfEmbeddedFile{Content: content}
+	file.Subtype, _ = core.GetNameVal(stream.Get("Subtype"))
+
+	if params, ok := core.GetDict(stream.Get("Params")); ok {
+		getDate := func(key core.PdfObjectName) time.Time {
+			str, ok := core.GetStringVal(params.Get(key))
+			if !ok {
+				return time.Time{}
+			}
+			date, err := NewPdfDate(str)
+			if err != nil {
+				common.Log.Debug("ERROR: invalid embedded file %s: %v", key, err)
+				return time.Time{}
+			}
+			return date.ToGoTime()
+		}
+		file.CreationDate = getDate("CreationDate")
+		file.ModDate = getDate("ModDate")
+		if sum, ok := core.GetString(params.Get("CheckSum")); ok {
+			file.CheckSum = sum.Bytes()
+		}
+	}
+	return file, nil
+}
+
+// ToPdfObject returns a flate encoded stream containing the embedded file.
+func (f *PdfEmbeddedFile) ToPdfObject() (core.PdfObject, error) {
+	stream, err := core.MakeStream(f.Content, core.NewFlateEncoder())
+	if err != nil {
+		return nil, err
+	}
+	stream.Set("Type", core.MakeName("EmbeddedFile"))
+	if f.Subtype != "" {
+		stream.Set("Subtype", core.MakeName(f.Subtype))
+	}
+
+	params := core.MakeDict()
+	params.Set("Size", core.MakeInteger(int64(len(f.Content))))
+	setDate := func(key core.PdfObjectName, t time.Time) error {
+		if t.IsZero() {
+			return nil
+		}
+		date, err := NewPdfDateFromTime(t)
+		if err != nil {
+			return err
+		}
+		params.Set(key, date.ToPdfObject())
+		return nil
+	}
+	if err := setDate("CreationDate", f.CreationDate); err != nil {
+		return nil, err
+	}
+	if err := setDate("ModDate", f.ModDate); err != nil {
+		return nil, err
+	}
+	if len(f.CheckSum) > 0 {
+		params.Set("CheckSum", core.MakeStringFromBytes(f.CheckSum))
+	}
+	stream.Set("Params", params)
+	return stream, nil
+}
+
+// NewPdfFilespecFromEmbeddedFile returns a new file specification, which
+// embeds the specified file under the specified file name.
+func NewPdfFilespecFromEmbeddedFile(name string, file *PdfEmbeddedFile) (*PdfFilespec, error) {
+	if file == nil {
+		return nil, errors.New("embedded file required")
+	}
+	stream, err := file.ToPdfObject()
+	if err != nil {
+		return nil, err
+	}
+
+	ef := core.MakeDict()
+	ef.Set("F", stream)
+	ef.Set("UF", stream)
+
+	fs := NewPdfFilespec()
+	fs.F = core.MakeString(name)
+	fs.UF = core.MakeEncodedString(name, true)
+	fs.EF = ef
+	return fs, nil
+}
+
+// GetFileName returns the file name of the file specification. The Unicode
+// file name is preferred if present.
+func (f *PdfFilespec) GetFileName() string {
+	for _, obj := range []core.PdfObject{f.UF, f.F, f.Unix, f.DOS, f.Mac} {
+		if str, ok := core.GetString(obj); ok && str.Str() != "" {
+			return str.Decoded()
+		}
+	}
+	return ""
+}
+
+// GetEmbeddedFile returns the embedded file referred by the file
+// specification. A nil value is returned if the file is not embedded.
+func (f *PdfFilespec) GetEmbeddedFile() (*PdfEmbeddedFile, error) {
+	ef, ok := core.GetDict(f.EF)
+	if !ok {
+		return nil, nil
+	}
+	for _, key := range []core.PdfObjectName{"UF", "F", "Unix", "DOS", "Mac"} {
+		if obj := ef.Get(key); obj != nil {
+			return newPdfEmbeddedFileFromPdfObject(obj)
+		}
+	}
+	return nil, nil
+}
+
+// GetEmbeddedFiles returns the file specifications of the embedded files
+// name tree of the document, keyed by name.
+// See section 7.7.4 "Name Dictionary" (p. 87 PDF32000_2008).
+func (r *PdfReader) GetEmbeddedFiles() (map[string]*PdfFilespec, error) {
+	names, ok := core.GetDict(r.catalog.Get("Names"))
+	if !ok {
+		return nil, nil
+	}
+	entries, err := nameTreeEntries(names.Get("EmbeddedFiles"))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]*PdfFilespec, len(entries))
+	for name, obj := range entries {
+		fs, err := NewPdfFilespecFromObj(core.ResolveReference(obj))
+		if err != nil {
+			common.Log.Debug("ERROR: invalid embedded file %s: %v", name, err)
+			continue
+		}
+		files[name] = fs
+	}
+	return files, nil
+}
+
+// AddEmbeddedFile adds the file specification to the embedded files name
+// tree of the output file, under the specified name.
+func (w *PdfWriter) AddEmbeddedFile(name string, fs *PdfFilespec) error {
+	if fs == nil {
+		return errors.New("file specification required")
+	}
+	if w.embeddedFiles == nil {
+		w.embeddedFiles = map[string]*PdfFilespec{}
+	}
+	if _, ok := w.embeddedFiles[name]; ok {
+		return errors.New("duplicate embedded file name")
+	}
+	w.embeddedFiles[name] = fs
+	return nil
+}
+
+// setEmbeddedFiles sets the embedded files name tree of the catalog.
+func (w *PdfWriter) setEmbeddedFiles() error {
+	if len(w.embeddedFiles) == 0 {
+		return nil
+	}
+
+	// The names dictionary may have been set from an input file, so a copy
+	// is made instead of altering it. The embedded files it contains are kept.
+	names := core.MakeDict()
+	entries := map[string]core.PdfObject{}
+	if dict, ok := core.GetDict(w.catalog.Get("Names")); ok {
+		for _, key := range dict.Keys() {
+			names.Set(key, dict.Get(key))
+		}
+		var err error
+		if entries, err = nameTreeEntries(dict.Get("EmbeddedFiles")); err != nil {
+			return err
+		}
+	}
+	for name, fs := range w.embeddedFiles {
+		entries[name] = fs.ToPdfObject()
+	}
+	names.Set("EmbeddedFiles", makeNameTree(entries))
+	w.catalog.Set("Names", names)
+	return w.addObjects(names)
+}
+
+// nameTreeEntries returns the entries of the name tree having the specified
+// root node, keyed by name.
+// See section 7.9.6 "Name Trees" (p. 88 PDF32000_2008).
+func nameTreeEntries(root core.PdfObject) (map[string]core.PdfObject, error) {
+	entries := map[string]core.PdfObject{}
+	visited := map[*core.PdfObjectDictionary]bool{}
+
+	var traverse func(obj core.PdfObject, depth int) error
+	traverse = func(obj core.PdfObject, depth int) error {
+		node, ok := core.GetDict(obj)
+		if !ok {
+			return nil
+		}
+		if visited[node] {
+			return errors.New("name tree loop")
+		}
+		if depth > 32 {
+			return errors.New("name tree too deep")
+		}
+		visited[node] = true
+
+		if names, ok := core.GetArray(node.Get("Names")); ok {
+			elems := names.Elements()
+			for i := 0; i+1 < len(elems); i += 2 {
+				key, ok := core.GetString(elems[i])
+				if !ok {
+					common.Log.Debug("ERROR: invalid name tree key: %v", elems[i])
+					continue
+				}
+				entries[key.Decoded()] = elems[i+1]
+			}
+		}
+		if kids, ok := core.GetArray(node.Get("Kids")); ok {
+			for _, kid := range kids.Elements() {
+				if err := traverse(kid, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := traverse(root, 0); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// makeNameTree returns a name tree containing the specified entries in a
+// single node. The keys are sorted in lexical order of their encoded bytes,
+// as required by the specification.
+func makeNameTree(entries map[string]core.PdfObject) *core.PdfObjectDictionary {
+	type entry struct {
+		key *core.PdfObjectString
+		val core.PdfObject
+	}
+	sorted := make([]entry, 0, len(entries))
+	for key, val := range entries {
+		str := core.MakeString(key)
+		for _, r := range key {
+			if r > unicode.MaxASCII {
+				str = core.MakeEncodedString(key, true)
+				break
+			}
+		}
+		sorted = append(sorted, entry{key: str, val: val})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].key.Str() < sorted[j].key.Str()
+	})
+
+	names := core.MakeArray()
+	for _, e := range sorted {
+		names.Append(e.key, e.val)
+	}
+	tree := core.MakeDict()
+	tree.Set("Names", names)
+	return tree
+}
@@ -440,9 +440,16 @@ type PdfFieldSignature struct {
 	V    *PdfSignature
 	Lock *core.PdfIndirectObject
 	SV   *core.PdfIndirectObject
+
+	// Lock and seed value dictionaries loaded by GetLock and GetSeedValue,
+	// or set by SetLock and SetSeedValue.
+	lock *PdfSignatureFieldLock
+	sv   *PdfSignatureFieldSeed
 }
 
 // NewPdfFieldSignature returns an initialized signature field.
+// The signature parameter can be nil for creating an unsigned signature field,
+// which can be signed at a later time.
 func NewPdfFieldSignature(signature *PdfSignature) *PdfFieldSignature {
 	field := &PdfFieldSignature{}
 	field.PdfField = NewPdfField()
@@ -471,6 +478,12 @@ func (sig *PdfFieldSignature) ToPdfObject() core.PdfObject {
 
 	d := container.PdfObject.(*core.PdfObjectDictionary)
 	d.SetIfNotNil("FT", core.MakeName("Sig"))
+	if sig.lock != nil && sig.lock.container == sig.Lock {
+		sig.lock.ToPdfObject()
+	}
+	if sig.sv != nil && sig.sv.container == sig.SV {
+		sig.sv.ToPdfObject()
+	}
 	d.SetIfNotNil("Lock", sig.Lock)
 	d.SetIfNotNil("SV", sig.SV)
 	if sig.V != nil {
@@ -480,6 +493,78 @@ func (sig *PdfFieldSignature) ToPdfObject() core.PdfObject {
 	return container
 }
 
+// GetLock returns the signature field lock dictionary referenced by the Lock
+// field, or nil if the field has no lock dictionary.
+func (sig *PdfFieldSignature) GetLock() (*PdfSignatureFieldLock, error) {
+	if sig.Lock == nil {
+		return nil, nil
+	}
+	if sig.lock != nil && sig.lock.container == sig.Lock {
+		return sig.lock, nil
+	}
+
+	lock, err := newPdfSignatureFieldLockFromObject(sig.Lock)
+	if err != nil {
+		return nil, err
+	}
+	sig.lock = lock
+	return lock, nil
+}
+
+// SetLock sets the signature field lock dictionary of the field. The Lock
+// field is set to the indirect object containing the dictionary, or to nil
+// if the lock parameter is nil.
+func (sig *PdfFieldSignature) SetLock(lock *PdfSignatureFieldLock) {
+	sig.lock = lock
+	sig.Lock = nil
+	if lock == nil {
+		return
+	}
+
+	ind, ok := lock.container.(*core.PdfIndirectObject)
+	if !ok {
+		ind = core.MakeIndirectObject(lock.container)
+		lock.container = ind
+	}
+	sig.Lock = ind
+}
+
+// GetSeedValue returns the signature field seed value dictionary referenced
+// by the SV field, or nil if the field has no seed value dictionary.
+func (sig *PdfFieldSignature) GetSeedValue() (*PdfSignatureFieldSeed, error) {
+	if sig.SV == nil {
+		return nil, nil
+	}
+	if sig.sv != nil && sig.sv.container == sig.SV {
+		return sig.sv, nil
+	}
+
+	sv, err := newPdfSignatureFieldSeedFromObject(sig.SV)
+	if err != nil {
+		return nil, err
+	}
+	sig.sv = sv
+	return sv, nil
+}
+
+// SetSeedValue sets the signature field seed value dictionary of the field.
+// The SV field is set to the indirect object containing the dictionary, or to
+// nil if the sv parameter is nil.
+func (sig *PdfFieldSignature) SetSeedValue(sv *PdfSignatureFieldSeed) {
+	sig.sv = sv
+	sig.SV = nil
+	if sv == nil {
+		return
+	}
+
+	ind, ok := sv.container.(*core.PdfIndirectObject)
+	if !ok {
+		ind = core.MakeIndirectObject(sv.container)
+		sv.container = ind
+	}
+	sig.SV = ind
+}
+
 // NewPdfField returns an initialized PdfField.
 func NewPdfField() *PdfField {
 	return &PdfField{
@@ -45,6 +45,8 @@ type PdfFilespec struct {
 	Desc core.PdfObject // Descriptive text associated with the file specification
 	CI   core.PdfObject // A collection item dictionary, which shall be used to create the user interface for portable collections
 
+	AFRelationship core.PdfObject // The relationship between the associated file and the referring object (PDF 2.0)
+
 	container core.PdfObject
 }
 
@@ -87,6 +89,7 @@ func (f *PdfFilespec) ToPdfObject() core.PdfObject {
 	d.SetIfNotNil("RF", f.RF)
 	d.SetIfNotNil("Desc", f.Desc)
 	d.SetIfNotNil("CI", f.CI)
+	d.SetIfNotNil("AFRelationship", f.AFRelationship)
 
 	return f.container
 }
@@ -167,6 +170,9 @@ func NewPdfFilespecFromObj(obj core.PdfObject) (*PdfFilespec, error) {
 	if obj := dict.Get("CI"); obj != nil {
 		fs.CI = obj
 	}
+	if obj := dict.Get("AFRelationship"); obj != nil {
+		fs.AFRelationship = obj
+	}
 	return fs, nil
 }
 
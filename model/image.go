@@ -56,6 +56,13 @@ func (img *Image) AlphaMap(mapFunc AlphaMapFunc) {
 // If provided image has more color components, then it would be converted into binary image using
 // histogram auto threshold function.
 func (img *Image) ConvertToBinary() error {
+	return img.ConvertToBinaryWithParams(core.BinarizationParams{})
+}
+
+// ConvertToBinaryWithParams converts current image into binary (bi-level) format, using the
+// 'params' thresholding method for the gray and color images. The local thresholding methods
+// should be used for the unevenly lit images, like the phone captured documents.
+func (img *Image) ConvertToBinaryWithParams(params core.BinarizationParams) error {
 	// check if  given image is already a binary image (1 bit per component - 1 color component - the size of the data
 	// is equal to the multiplication of width and height.
 	if img.ColorComponents == 1 && img.BitsPerComponent == 1 {
@@ -68,8 +75,7 @@ func (img *Image) ConvertToBinary() error {
 	gray := imageutil.ImgToGray(i)
 	// check if 'img' is already a binary image.
 	if !imageutil.IsGrayImgBlackAndWhite(gray) {
-		threshold := imageutil.AutoThresholdTriangle(imageutil.GrayImageHistogram(gray))
-		gray = imageutil.ImgToBinary(i, threshold)
+		gray = imageutil.Binarize(gray, params)
 	}
 	// use JBIG2 bitmap as the temporary binary data converter - by default it uses
 	tmpBM := bitmap.New(int(img.Width), int(img.Height))
@@ -0,0 +1,508 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package optimize
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TheLinker/unipdf/v3/common"
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/internal/pageutil"
+)
+
+// Categories of objects of a SizeBreakdown.
+const (
+	categoryOther = iota
+	categoryImages
+	categoryFonts
+	categoryContent
+	categoryMetadata
+)
+
+// documentSnapshot are the properties of the objects of a document at a step of a Chain, copied
+// as the following optimizers can modify the objects in place.
+type documentSnapshot struct {
+	objects map[core.PdfObject]struct{}
+	sizes   SizeBreakdown
+
+	// The images and the fonts by resource path, and the resource paths in the document order,
+	// the first path of each resource only.
+	images     map[string]*resourceSnapshot
+	imagePaths []string
+	fonts      map[string]*resourceSnapshot
+	fontPaths  []string
+}
+
+// resourceSnapshot is an image or a font of a documentSnapshot.
+type resourceSnapshot struct {
+	name    string
+	page    int
+	obj     core.PdfObject // The image stream or the font dictionary.
+	program core.PdfObject // The font program.
+	size    int64
+	image   ImageStats
+}
+
+// takeSnapshot returns the snapshot of `objects`.
+func takeSnapshot(objects []core.PdfObject) *documentSnapshot {
+	s := &documentSnapshot{
+		objects: make(map[core.PdfObject]struct{}, len(objects)),
+		images:  make(map[string]*resourceSnapshot),
+		fonts:   make(map[string]*resourceSnapshot),
+	}
+	for _, obj := range objects {
+		s.objects[obj] = struct{}{}
+	}
+
+	sizes := objectSizes(objects)
+	categories := objectCategories(objects)
+	for obj, size := range sizes {
+		switch categories[obj] {
+		case categoryImages:
+			s.sizes.Images += size
+		case categoryFonts:
+			s.sizes.Fonts += size
+		case categoryContent:
+			s.sizes.Content += size
+		case categoryMetadata:
+			s.sizes.Metadata += size
+		default:
+			s.sizes.Other += size
+		}
+	}
+	s.sizes.Objects = len(sizes)
+
+	s.findResources(objects, sizes)
+	return s
+}
+
+// objectSizes returns the estimated sizes of the top level objects of `objects` in the written
+// document. The size of an object stream is shared by the objects it contains.
+func objectSizes(objects []core.PdfObject) map[core.PdfObject]int64 {
+	const overhead = 40 // Object header, footer and cross reference entry.
+
+	sizes := make(map[core.PdfObject]int64, len(objects))
+	inObjectStreams := make(map[core.PdfObject]struct{})
+	for _, obj := range objects {
+		objStm, ok := obj.(*core.PdfObjectStreams)
+		if !ok {
+			continue
+		}
+		var data []string
+		var total int64
+		for _, o := range objStm.Elements() {
+			inObjectStreams[o] = struct{}{}
+			if ind, ok := o.(*core.PdfIndirectObject); ok {
+				data = append(data, ind.PdfObject.WriteString())
+				total += int64(len(data[len(data)-1]))
+			}
+		}
+		encoded, err := core.NewFlateEncoder().EncodeBytes([]byte(strings.Join(data, " ")))
+		if err != nil || total == 0 {
+			continue
+		}
+		compressed := int64(len(encoded) + overhead)
+		for _, o := range objStm.Elements() {
+			if ind, ok := o.(*core.PdfIndirectObject); ok {
+				sizes[o] += compressed * int64(len(ind.PdfObject.WriteString())) / total
+			}
+		}
+	}
+
+	for _, obj := range objects {
+		if _, ok := inObjectStreams[obj]; ok {
+			continue
+		}
+		switch t := obj.(type) {
+		case *core.PdfIndirectObject:
+			sizes[obj] = int64(len(t.PdfObject.WriteString()) + overhead)
+		case *core.PdfObjectStream:
+			sizes[obj] = int64(len(t.PdfObjectDictionary.WriteString()) + len(t.Stream) + overhead)
+		}
+	}
+	return sizes
+}
+
+// Keys of the dictionaries referencing the objects of the categories.
+var (
+	fontKeys = map[core.PdfObjectName]struct{}{
+		"FontDescriptor": {}, "FontFile": {}, "FontFile2": {}, "FontFile3": {}, "ToUnicode": {},
+		"Widths": {}, "W": {}, "W2": {}, "CIDToGIDMap": {}, "DescendantFonts": {}, "CIDSet": {},
+	}
+	imageKeys = map[core.PdfObjectName]struct{}{
+		"SMask": {}, "Mask": {},
+	}
+)
+
+// objectCategories returns the categories of the top level objects of `objects`.
+func objectCategories(objects []core.PdfObject) map[core.PdfObject]int {
+	categories := make(map[core.PdfObject]int)
+	referenced := make(map[core.PdfObject]int)
+	mark := func(obj core.PdfObject, category int) {
+		referenced[obj] = category
+		if arr, ok := core.GetArray(obj); ok {
+			for _, o := range arr.Elements() {
+				referenced[o] = category
+			}
+		}
+	}
+
+	for _, obj := range objects {
+		var dict *core.PdfObjectDictionary
+		switch t := obj.(type) {
+		case *core.PdfIndirectObject:
+			dict, _ = t.PdfObject.(*core.PdfObjectDictionary)
+		case *core.PdfObjectStream:
+			dict = t.PdfObjectDictionary
+		}
+		if dict == nil {
+			continue
+		}
+
+		typ, _ := core.GetName(dict.Get("Type"))
+		subtype, _ := core.GetName(dict.Get("Subtype"))
+		switch {
+		case subtype != nil && *subtype == "Image":
+			categories[obj] = categoryImages
+		case subtype != nil && *subtype == "Form":
+			categories[obj] = categoryContent
+		case typ != nil && (*typ == "Font" || *typ == "FontDescriptor"):
+			categories[obj] = categoryFonts
+		case typ != nil && *typ == "Metadata":
+			categories[obj] = categoryMetadata
+		case typ == nil && (dict.Get("Producer") != nil || dict.Get("CreationDate") != nil):
+			// Document information dictionary.
+			categories[obj] = categoryMetadata
+		}
+		if typ != nil && *typ == "Page" {
+			mark(dict.Get("Contents"), categoryContent)
+		}
+		for _, key := range dict.Keys() {
+			if _, ok := fontKeys[key]; ok {
+				mark(dict.Get(key), categoryFonts)
+			} else if _, ok := imageKeys[key]; ok {
+				mark(dict.Get(key), categoryImages)
+			} else if key == "Metadata" {
+				mark(dict.Get(key), categoryMetadata)
+			}
+		}
+	}
+
+	for obj, category := range referenced {
+		if _, ok := categories[obj]; !ok {
+			categories[obj] = category
+		}
+	}
+	return categories
+}
+
+// findResources finds the images and the fonts used by the pages of `objects`.
+func (s *documentSnapshot) findResources(objects []core.PdfObject, sizes map[core.PdfObject]int64) {
+	var catalog *core.PdfObjectDictionary
+	for _, obj := range objects {
+		if dict, ok := core.GetDict(obj); ok {
+			if typ, ok := core.GetName(dict.Get("Type")); ok && *typ == "Catalog" {
+				catalog = dict
+				break
+			}
+		}
+	}
+	if catalog == nil {
+		return
+	}
+
+	var pages []*core.PdfObjectDictionary
+	visited := make(map[*core.PdfObjectDictionary]struct{})
+	var walkPages func(node *core.PdfObjectDictionary)
+	walkPages = func(node *core.PdfObjectDictionary) {
+		if _, ok := visited[node]; ok {
+			return
+		}
+		visited[node] = struct{}{}
+		if typ, ok := core.GetName(node.Get("Type")); ok && *typ == "Page" {
+			pages = append(pages, node)
+			return
+		}
+		if kids, ok := core.GetArray(node.Get("Kids")); ok {
+			for _, kid := range kids.Elements() {
+				if dict, ok := core.GetDict(kid); ok {
+					walkPages(dict)
+				}
+			}
+		}
+	}
+	if root, ok := core.GetDict(catalog.Get("Pages")); ok {
+		walkPages(root)
+	}
+
+	seen := make(map[core.PdfObject]struct{})
+	imageByStream := make(map[*core.PdfObjectStream]*imageInfo)
+	var walkResources func(resources *core.PdfObjectDictionary, page int, prefix string, depth int)
+	walkResources = func(resources *core.PdfObjectDictionary, page int, prefix string, depth int) {
+		if resources == nil || depth > 10 {
+			return
+		}
+		if xobjects, ok := core.GetDict(resources.Get("XObject")); ok {
+			for _, name := range xobjects.Keys() {
+				stream, ok := core.GetStream(xobjects.Get(name))
+				if !ok {
+					continue
+				}
+				path := fmt.Sprintf("%s/XObject/%s", prefix, name)
+				subtype, _ := core.GetName(stream.Get("Subtype"))
+				if subtype != nil && *subtype == "Form" {
+					res, _ := core.GetDict(stream.Get("Resources"))
+					walkResources(res, page, path, depth+1)
+					continue
+				}
+				if subtype == nil || *subtype != "Image" {
+					continue
+				}
+				res := &resourceSnapshot{name: string(name), page: page, obj: stream, size: sizes[stream]}
+				res.image = ImageStats{Size: sizes[stream], Filter: filterName(stream)}
+				res.image.Width, _ = core.GetIntVal(stream.Get("Width"))
+				res.image.Height, _ = core.GetIntVal(stream.Get("Height"))
+				s.images[path] = res
+				if _, ok := seen[stream]; !ok {
+					seen[stream] = struct{}{}
+					s.imagePaths = append(s.imagePaths, path)
+					imageByStream[stream] = &imageInfo{Width: res.image.Width, Height: res.image.Height, Stream: stream}
+				}
+			}
+		}
+		if fonts, ok := core.GetDict(resources.Get("Font")); ok {
+			for _, name := range fonts.Keys() {
+				obj := fonts.Get(name)
+				font, ok := core.GetDict(obj)
+				if !ok {
+					continue
+				}
+				path := fmt.Sprintf("%s/Font/%s", prefix, name)
+				res := &resourceSnapshot{name: string(name), page: page, obj: font}
+				if baseFont, ok := core.GetName(font.Get("BaseFont")); ok {
+					res.name = string(*baseFont)
+				}
+				res.size, res.program = fontSize(obj, sizes)
+				s.fonts[path] = res
+				if _, ok := seen[font]; !ok {
+					seen[font] = struct{}{}
+					s.fontPaths = append(s.fontPaths, path)
+				}
+			}
+		}
+	}
+	for i, page := range pages {
+		resources := pageutil.InheritedResources(page)
+		walkResources(resources, i+1, fmt.Sprintf("%d", i+1), 0)
+	}
+
+	if err := computeImagePPI(objects, imageByStream); err != nil {
+		common.Log.Debug("ERROR: unable to compute the resolution of the images: %v", err)
+	}
+	for _, res := range s.images {
+		if img, ok := imageByStream[res.obj.(*core.PdfObjectStream)]; ok {
+			res.image.PPI = img.PPI
+		}
+	}
+}
+
+// fontSize returns the size of the objects of the font `obj` and its font program.
+func fontSize(obj core.PdfObject, sizes map[core.PdfObject]int64) (int64, core.PdfObject) {
+	var size int64
+	var program core.PdfObject
+	visited := make(map[core.PdfObject]struct{})
+	var walk func(obj core.PdfObject, depth int)
+	walk = func(obj core.PdfObject, depth int) {
+		if _, ok := visited[obj]; ok || depth > 4 {
+			return
+		}
+		visited[obj] = struct{}{}
+		size += sizes[obj]
+
+		var dict *core.PdfObjectDictionary
+		switch t := core.ResolveReference(obj).(type) {
+		case *core.PdfIndirectObject:
+			dict, _ = t.PdfObject.(*core.PdfObjectDictionary)
+			if arr, ok := t.PdfObject.(*core.PdfObjectArray); ok {
+				for _, o := range arr.Elements() {
+					walk(o, depth+1)
+				}
+			}
+		case *core.PdfObjectStream:
+			dict = t.PdfObjectDictionary
+		case *core.PdfObjectDictionary:
+			dict = t
+		case *core.PdfObjectArray:
+			for _, o := range t.Elements() {
+				walk(o, depth+1)
+			}
+		}
+		if dict == nil {
+			return
+		}
+		for _, key := range dict.Keys() {
+			if _, ok := fontKeys[key]; !ok {
+				continue
+			}
+			if key == "FontFile" || key == "FontFile2" || key == "FontFile3" {
+				program = dict.Get(key)
+			}
+			walk(dict.Get(key), depth+1)
+		}
+	}
+	walk(obj, 0)
+	return size, program
+}
+
+// filterName returns the names of the filters of `stream`.
+func filterName(stream *core.PdfObjectStream) string {
+	switch t := core.TraceToDirectObject(stream.Get("Filter")).(type) {
+	case *core.PdfObjectName:
+		return string(*t)
+	case *core.PdfObjectArray:
+		var names []string
+		for _, o := range t.Elements() {
+			if name, ok := core.GetName(o); ok {
+				names = append(names, string(*name))
+			}
+		}
+		return strings.Join(names, " ")
+	}
+	return ""
+}
+
+// compareSnapshots returns the report of the optimizer `name` which changed the document
+// `before` to `after`.
+func compareSnapshots(name string, before, after *documentSnapshot) *StepReport {
+	step := &StepReport{
+		Name:       name,
+		SizeBefore: before.sizes.Total(),
+		SizeAfter:  after.sizes.Total(),
+	}
+	for obj := range before.objects {
+		if _, ok := after.objects[obj]; !ok {
+			step.ObjectsRemoved++
+		}
+	}
+	for obj := range after.objects {
+		if _, ok := before.objects[obj]; !ok {
+			step.ObjectsAdded++
+		}
+	}
+
+	for _, path := range before.imagePaths {
+		b, a := before.images[path], after.images[path]
+		if a == nil {
+			continue
+		}
+		_, existed := before.objects[a.obj]
+		merged := a.obj != b.obj && existed
+		if !merged && a.image == b.image {
+			continue
+		}
+		step.Images = append(step.Images, ImageChange{
+			Name:   b.name,
+			Page:   b.page,
+			Before: b.image,
+			After:  a.image,
+			Merged: merged,
+		})
+	}
+
+	for _, path := range before.fontPaths {
+		b, a := before.fonts[path], after.fonts[path]
+		if a == nil {
+			continue
+		}
+		_, existed := before.objects[a.program]
+		merged := a.program != nil && a.program != b.program && existed
+		if !merged && a.size == b.size {
+			continue
+		}
+		step.Fonts = append(step.Fonts, FontChange{
+			Name:       b.name,
+			Page:       b.page,
+			SizeBefore: b.size,
+			SizeAfter:  a.size,
+			Merged:     merged,
+		})
+	}
+	return step
+}
+
+// copyObjects returns a deep copy of `objects`.
+func copyObjects(objects []core.PdfObject) []core.PdfObject {
+	copies := make(map[core.PdfObject]core.PdfObject)
+	result := make([]core.PdfObject, len(objects))
+	for i, obj := range objects {
+		result[i] = copyObject(obj, copies)
+	}
+	return result
+}
+
+// copyObject returns a deep copy of `obj`. `copies` maps the copied objects to their copies.
+func copyObject(obj core.PdfObject, copies map[core.PdfObject]core.PdfObject) core.PdfObject {
+	if c, ok := copies[obj]; ok {
+		return c
+	}
+	switch t := obj.(type) {
+	case *core.PdfObjectArray:
+		c := &core.PdfObjectArray{}
+		copies[obj] = c
+		for _, o := range t.Elements() {
+			c.Append(copyObject(o, copies))
+		}
+		return c
+	case *core.PdfObjectStreams:
+		c := &core.PdfObjectStreams{PdfObjectReference: t.PdfObjectReference}
+		copies[obj] = c
+		for _, o := range t.Elements() {
+			c.Append(copyObject(o, copies))
+		}
+		return c
+	case *core.PdfObjectStream:
+		c := &core.PdfObjectStream{}
+		*c = *t
+		copies[obj] = c
+		c.PdfObjectDictionary = copyObject(t.PdfObjectDictionary, copies).(*core.PdfObjectDictionary)
+		return c
+	case *core.PdfObjectDictionary:
+		c := core.MakeDict()
+		copies[obj] = c
+		for _, key := range t.Keys() {
+			c.Set(key, copyObject(t.Get(key), copies))
+		}
+		return c
+	case *core.PdfIndirectObject:
+		c := &core.PdfIndirectObject{PdfObjectReference: t.PdfObjectReference}
+		copies[obj] = c
+		c.PdfObject = copyObject(t.PdfObject, copies)
+		return c
+	case *core.PdfObjectString:
+		c := *t
+		copies[obj] = &c
+		return &c
+	case *core.PdfObjectName:
+		c := *t
+		copies[obj] = &c
+		return &c
+	case *core.PdfObjectInteger:
+		c := *t
+		copies[obj] = &c
+		return &c
+	case *core.PdfObjectFloat:
+		c := *t
+		copies[obj] = &c
+		return &c
+	case *core.PdfObjectBool:
+		c := *t
+		copies[obj] = &c
+		return &c
+	}
+	// The other objects, such as the null objects, are not modified by the optimizers.
+	return obj
+}
@@ -6,6 +6,9 @@
 package optimize
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/TheLinker/unipdf/v3/core"
 	"github.com/TheLinker/unipdf/v3/model"
 )
@@ -14,6 +17,11 @@ import (
 // It implements interface model.Optimizer.
 type Chain struct {
 	optimizers []model.Optimizer
+
+	report bool
+	dryRun bool
+	// lastReport is the report of the last optimization.
+	lastReport *Report
 }
 
 // Append appends optimizers to the chain.
@@ -21,8 +29,29 @@ func (c *Chain) Append(optimizers ...model.Optimizer) {
 	c.optimizers = append(c.optimizers, optimizers...)
 }
 
+// SetReport sets whether the chain reports the effect of each optimizer, see Report.
+func (c *Chain) SetReport(report bool) {
+	c.report = report
+}
+
+// SetDryRun sets whether the chain only estimates the savings of the optimizers: the optimizers
+// work on a copy of the objects and Optimize returns the objects unchanged. A dry run produces
+// a report.
+func (c *Chain) SetDryRun(dryRun bool) {
+	c.dryRun = dryRun
+}
+
+// Report returns the report of the last optimization or nil if the chain neither reports nor
+// makes a dry run.
+func (c *Chain) Report() *Report {
+	return c.lastReport
+}
+
 // Optimize optimizes PDF objects to decrease PDF size.
 func (c *Chain) Optimize(objects []core.PdfObject) (optimizedObjects []core.PdfObject, err error) {
+	if c.report || c.dryRun {
+		return c.optimizeWithReport(objects)
+	}
 	optimizedObjects = objects
 	for _, optimizer := range c.optimizers {
 		optimizedObjects, err = optimizer.Optimize(optimizedObjects)
@@ -32,3 +61,40 @@ func (c *Chain) Optimize(objects []core.PdfObject) (optimizedObjects []core.PdfO
 	}
 	return optimizedObjects, nil
 }
+
+// optimizeWithReport optimizes `objects` like Optimize and compares the objects before and after
+// each optimizer to build the report.
+func (c *Chain) optimizeWithReport(objects []core.PdfObject) ([]core.PdfObject, error) {
+	report := &Report{DryRun: c.dryRun}
+	c.lastReport = report
+
+	optimizedObjects := objects
+	if c.dryRun {
+		optimizedObjects = copyObjects(objects)
+	}
+	before := takeSnapshot(optimizedObjects)
+	report.Input = before.sizes
+	report.Output = before.sizes
+
+	for _, optimizer := range c.optimizers {
+		var err error
+		optimizedObjects, err = optimizer.Optimize(optimizedObjects)
+		if err != nil {
+			if c.dryRun {
+				return objects, err
+			}
+			return optimizedObjects, err
+		}
+		after := takeSnapshot(optimizedObjects)
+		name := fmt.Sprintf("%T", optimizer)
+		name = name[strings.LastIndex(name, ".")+1:]
+		report.Steps = append(report.Steps, compareSnapshots(name, before, after))
+		report.Output = after.sizes
+		before = after
+	}
+
+	if c.dryRun {
+		return objects, nil
+	}
+	return optimizedObjects, nil
+}
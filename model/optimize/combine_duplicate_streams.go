@@ -22,7 +22,7 @@ func (dup *CombineDuplicateStreams) Optimize(objects []core.PdfObject) (optimize
 	toDelete := make(map[core.PdfObject]struct{})
 	streamsByHash := make(map[string][]*core.PdfObjectStream)
 	for _, obj := range objects {
-		if stream, isStreamObj := obj.(*core.PdfObjectStream); isStreamObj {
+		if stream, isStreamObj := obj.(*core.PdfObjectStream); isStreamObj && !stream.IsStreamed() {
 			hasher := md5.New()
 			hasher.Write([]byte(stream.Stream))
 			hash := string(hasher.Sum(nil))
@@ -0,0 +1,702 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package optimize
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/TheLinker/unipdf/v3/common"
+	"github.com/TheLinker/unipdf/v3/contentstream"
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/internal/pageutil"
+)
+
+// CombineRepeatedContent moves the content stream fragments repeated on several pages, such as
+// the headers, footers and logos of the converted documents, to shared form XObjects drawn with
+// the Do operator.
+// The moved fragments are sequences of operations which do not change the graphics state of the
+// operations following them (q ... Q blocks, text objects, paths and XObjects) and which use the
+// same resources on all the pages.
+// It implements interface model.Optimizer.
+type CombineRepeatedContent struct {
+	// MinPages is the minimum number of pages a fragment must be repeated on to be moved (default 2).
+	MinPages int
+
+	// MinSize is the minimum size in bytes of the moved fragments (default 64). The smaller
+	// fragments are not worth the overhead of the form XObjects.
+	MinSize int
+}
+
+// Defaults of CombineRepeatedContent.
+const (
+	defaultRepeatedContentMinPages = 2
+	defaultRepeatedContentMinSize  = 64
+)
+
+// contentPage is a page whose content is analyzed by CombineRepeatedContent.
+type contentPage struct {
+	dict         *core.PdfObjectDictionary
+	contents     core.PdfObject
+	resources    *core.PdfObjectDictionary
+	newResources bool // The page has no resources yet.
+	mediaBox     [4]float64
+	units        []*contentUnit
+}
+
+// contentUnit is a sequence of operations at the top level of a content stream: a q ... Q block,
+// a text object, a marked content sequence, a path or a single operation.
+type contentUnit struct {
+	ops      contentstream.ContentStreamOperations
+	data     []byte
+	key      string            // The data and the resources used by the operations.
+	movable  bool              // The unit does not change the graphics state after it.
+	ctm      [6]float64        // The CTM before the unit.
+	bindings []resourceBinding // The resources used by the operations.
+}
+
+// resourceBinding is a resource used by a content stream.
+type resourceBinding struct {
+	category core.PdfObjectName
+	name     core.PdfObjectName
+	value    core.PdfObject
+}
+
+// repeatedFragment is a sequence of units moved to a form XObject.
+type repeatedFragment struct {
+	units []*contentUnit
+	key   string
+	size  int
+}
+
+// sharedForm is a form XObject shared by the pages.
+type sharedForm struct {
+	name     core.PdfObjectName
+	stream   *core.PdfObjectStream
+	content  []byte
+	bbox     [4]float64
+	bindings []resourceBinding
+}
+
+// Optimize optimizes PDF objects to decrease PDF size.
+func (rc *CombineRepeatedContent) Optimize(objects []core.PdfObject) (optimizedObjects []core.PdfObject, err error) {
+	minPages := rc.MinPages
+	if minPages < 2 {
+		minPages = defaultRepeatedContentMinPages
+	}
+	minSize := rc.MinSize
+	if minSize <= 0 {
+		minSize = defaultRepeatedContentMinSize
+	}
+
+	pages := findContentPages(objects)
+	if len(pages) < minPages {
+		return objects, nil
+	}
+
+	// Count the pages the movable units are found on.
+	unitPages := make(map[string]map[int]struct{})
+	for i, page := range pages {
+		for _, unit := range page.units {
+			if unit.movable {
+				addPage(unitPages, unit.key, i)
+			}
+		}
+	}
+
+	// The fragments are the longest runs of repeated units repeated on the same pages, or the
+	// repeated units of the runs which are not.
+	pageRuns := make([][]*repeatedFragment, len(pages))
+	runPages := make(map[string]map[int]struct{})
+	for i, page := range pages {
+		var run *repeatedFragment
+		for _, unit := range page.units {
+			if !unit.movable || len(unitPages[unit.key]) < minPages {
+				run = nil
+				continue
+			}
+			if run == nil {
+				run = &repeatedFragment{}
+				pageRuns[i] = append(pageRuns[i], run)
+			}
+			run.units = append(run.units, unit)
+			run.key += unit.key + "\x00"
+			run.size += len(unit.data)
+		}
+		for _, run := range pageRuns[i] {
+			addPage(runPages, run.key, i)
+		}
+	}
+
+	fragments := make([]map[*contentUnit]*repeatedFragment, len(pages))
+	forms := make(map[string]*sharedForm)
+	var formKeys []string
+	addFragment := func(i int, fragment *repeatedFragment) {
+		if fragment.size < minSize {
+			return
+		}
+		form, ok := forms[fragment.key]
+		if !ok {
+			form = &sharedForm{
+				bbox:     [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)},
+				bindings: fragmentBindings(fragment),
+			}
+			for _, unit := range fragment.units {
+				form.content = append(form.content, unit.data...)
+			}
+			forms[fragment.key] = form
+			formKeys = append(formKeys, fragment.key)
+		}
+		form.bbox = unionBox(form.bbox, userSpaceBox(pages[i].mediaBox, fragment.units[0].ctm))
+		if fragments[i] == nil {
+			fragments[i] = make(map[*contentUnit]*repeatedFragment)
+		}
+		fragments[i][fragment.units[0]] = fragment
+	}
+	for i, runs := range pageRuns {
+		for _, run := range runs {
+			if len(runPages[run.key]) >= minPages {
+				addFragment(i, run)
+				continue
+			}
+			for _, unit := range run.units {
+				addFragment(i, &repeatedFragment{
+					units: []*contentUnit{unit},
+					key:   unit.key + "\x00",
+					size:  len(unit.data),
+				})
+			}
+		}
+	}
+	if len(forms) == 0 {
+		return objects, nil
+	}
+
+	// Create the form XObjects with names unused by the pages.
+	usedNames := make(map[core.PdfObjectName]struct{})
+	for _, page := range pages {
+		if xobjects, ok := core.GetDict(page.resources.Get("XObject")); ok {
+			for _, name := range xobjects.Keys() {
+				usedNames[name] = struct{}{}
+			}
+		}
+	}
+	var newObjects []core.PdfObject
+	for _, key := range formKeys {
+		form := forms[key]
+		for n := 1; ; n++ {
+			form.name = core.PdfObjectName(fmt.Sprintf("Fm%d", n))
+			if _, used := usedNames[form.name]; !used {
+				break
+			}
+		}
+		usedNames[form.name] = struct{}{}
+
+		form.stream, err = makeFormStream(form.content, form.bbox, form.bindings)
+		if err != nil {
+			return nil, err
+		}
+		newObjects = append(newObjects, form.stream)
+	}
+
+	// Rewrite the pages.
+	replaced := make(map[core.PdfObject]struct{})
+	for i, page := range pages {
+		if len(fragments[i]) == 0 {
+			continue
+		}
+		var ops contentstream.ContentStreamOperations
+		xobjects := pageXObjects(page)
+		for j := 0; j < len(page.units); j++ {
+			unit := page.units[j]
+			fragment, ok := fragments[i][unit]
+			if !ok {
+				ops = append(ops, unit.ops...)
+				continue
+			}
+			form := forms[fragment.key]
+			xobjects.Set(form.name, form.stream)
+			ops = append(ops, &contentstream.ContentStreamOperation{
+				Operand: "Do",
+				Params:  []core.PdfObject{core.MakeName(string(form.name))},
+			})
+			j += len(fragment.units) - 1
+		}
+
+		stream, err := core.MakeStream(ops.Bytes(), core.NewFlateEncoder())
+		if err != nil {
+			return nil, err
+		}
+		replaced[page.contents] = struct{}{}
+		if arr, ok := core.GetArray(page.contents); ok {
+			for _, obj := range arr.Elements() {
+				replaced[core.ResolveReference(obj)] = struct{}{}
+			}
+		}
+		page.dict.Set("Contents", stream)
+		if page.newResources {
+			page.dict.Set("Resources", page.resources)
+		}
+		newObjects = append(newObjects, stream)
+	}
+
+	// Remove the replaced content streams which are not used anymore.
+	referenced := make(map[core.PdfObject]struct{})
+	visited := make(map[core.PdfObject]struct{})
+	for _, obj := range objects {
+		if _, ok := replaced[obj]; !ok {
+			findReferences(obj, replaced, referenced, visited)
+		}
+	}
+	optimizedObjects = make([]core.PdfObject, 0, len(objects)+len(newObjects))
+	for _, obj := range objects {
+		if _, ok := replaced[obj]; ok {
+			if _, ok := referenced[obj]; !ok {
+				continue
+			}
+		}
+		optimizedObjects = append(optimizedObjects, obj)
+	}
+	return append(optimizedObjects, newObjects...), nil
+}
+
+// findContentPages returns the pages of `objects` with their content split into units.
+func findContentPages(objects []core.PdfObject) []*contentPage {
+	var pages []*contentPage
+	for _, obj := range objects {
+		ind, ok := obj.(*core.PdfIndirectObject)
+		if !ok {
+			continue
+		}
+		dict, ok := core.GetDict(ind.PdfObject)
+		if !ok {
+			continue
+		}
+		if name, ok := core.GetName(dict.Get("Type")); !ok || *name != "Page" {
+			continue
+		}
+
+		page := &contentPage{dict: dict, contents: dict.Get("Contents")}
+		var streams []*core.PdfObjectStream
+		switch t := core.TraceToDirectObject(page.contents).(type) {
+		case *core.PdfObjectStream:
+			streams = append(streams, t)
+		case *core.PdfObjectArray:
+			for _, obj := range t.Elements() {
+				if stream, ok := core.GetStream(obj); ok {
+					streams = append(streams, stream)
+				}
+			}
+		}
+		if len(streams) == 0 {
+			continue
+		}
+		var content []string
+		for _, stream := range streams {
+			data, err := core.DecodeStream(stream)
+			if err != nil {
+				common.Log.Debug("ERROR: unable to decode content stream: %v", err)
+				content = nil
+				break
+			}
+			content = append(content, string(data))
+		}
+		if content == nil {
+			continue
+		}
+		ops, err := contentstream.NewContentStreamParser(strings.Join(content, "\n")).Parse()
+		if err != nil {
+			common.Log.Debug("ERROR: unable to parse content stream: %v", err)
+			continue
+		}
+
+		page.resources = pageutil.InheritedResources(dict)
+		if page.resources == nil {
+			page.resources = core.MakeDict()
+			page.newResources = true
+		}
+		page.mediaBox = [4]float64{0, 0, 612, 792}
+		if arr, ok := core.GetArray(pageutil.InheritedAttribute(dict, "MediaBox")); ok {
+			if box, err := arr.ToFloat64Array(); err == nil && len(box) == 4 {
+				copy(page.mediaBox[:], box)
+			}
+		}
+		page.units = splitContentUnits(*ops, page.resources)
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+// pageXObjects returns the XObject resources of `page`, adding them if needed.
+func pageXObjects(page *contentPage) *core.PdfObjectDictionary {
+	xobjects, ok := core.GetDict(page.resources.Get("XObject"))
+	if !ok {
+		xobjects = core.MakeDict()
+		page.resources.Set("XObject", xobjects)
+	}
+	return xobjects
+}
+
+// Operations changing the graphics state.
+var graphicsStateOperands = map[string]struct{}{
+	"w": {}, "J": {}, "j": {}, "M": {}, "d": {}, "ri": {}, "i": {}, "gs": {}, "cm": {},
+	"CS": {}, "cs": {}, "SC": {}, "SCN": {}, "sc": {}, "scn": {}, "G": {}, "g": {}, "RG": {}, "rg": {}, "K": {}, "k": {},
+	"Tc": {}, "Tw": {}, "Tz": {}, "TL": {}, "Tf": {}, "Tr": {}, "Ts": {}, "\"": {},
+	"W": {}, "W*": {}, "d0": {}, "d1": {}, "BX": {}, "EX": {},
+}
+
+// Path construction and painting operations.
+var (
+	pathOperands = map[string]struct{}{
+		"m": {}, "l": {}, "c": {}, "v": {}, "y": {}, "h": {}, "re": {}, "W": {}, "W*": {},
+	}
+	paintOperands = map[string]struct{}{
+		"S": {}, "s": {}, "f": {}, "F": {}, "f*": {}, "B": {}, "B*": {}, "b": {}, "b*": {}, "n": {},
+	}
+)
+
+// splitContentUnits splits `ops` into units and finds their resources in `resources`.
+func splitContentUnits(ops contentstream.ContentStreamOperations, resources *core.PdfObjectDictionary) []*contentUnit {
+	var units []*contentUnit
+	ctm := [6]float64{1, 0, 0, 1, 0, 0}
+	for i := 0; i < len(ops); {
+		end := unitEnd(ops, i)
+		unit := &contentUnit{ops: ops[i:end], ctm: ctm}
+		unit.data = unit.ops.Bytes()
+		unit.movable = end > i+1 || isMovableOperation(ops[i])
+		if end-i == 1 && ops[i].Operand == "cm" {
+			if m, err := core.GetNumbersAsFloat(ops[i].Params); err == nil && len(m) == 6 {
+				ctm = multiplyMatrices([6]float64{m[0], m[1], m[2], m[3], m[4], m[5]}, ctm)
+			}
+		}
+		if unit.movable {
+			unit.movable = checkUnitOperations(unit, resources)
+		}
+		if unit.movable {
+			var keys []string
+			for _, b := range unit.bindings {
+				keys = append(keys, fmt.Sprintf("%s/%s=%s", b.category, b.name, resourceKey(b.value, 0)))
+			}
+			sort.Strings(keys)
+			unit.key = string(unit.data) + "\x01" + strings.Join(keys, ",")
+		}
+		units = append(units, unit)
+		i = end
+	}
+	return units
+}
+
+// unitEnd returns the end of the unit starting with ops[start]. The unbalanced blocks end with
+// the content.
+func unitEnd(ops contentstream.ContentStreamOperations, start int) int {
+	switch ops[start].Operand {
+	case "q", "BT", "BMC", "BDC":
+		depth := 0
+		for i := start; i < len(ops); i++ {
+			switch ops[i].Operand {
+			case "q", "BT", "BMC", "BDC":
+				depth++
+			case "Q", "ET", "EMC":
+				depth--
+			}
+			if depth == 0 {
+				return i + 1
+			}
+		}
+		return len(ops)
+	}
+	if _, ok := pathOperands[ops[start].Operand]; ok {
+		for i := start; i < len(ops); i++ {
+			if _, ok := paintOperands[ops[i].Operand]; ok {
+				return i + 1
+			}
+			if _, ok := pathOperands[ops[i].Operand]; !ok {
+				return i
+			}
+		}
+		return len(ops)
+	}
+	return start + 1
+}
+
+// isMovableOperation reports whether the single operation `op` can be moved to a form XObject.
+func isMovableOperation(op *contentstream.ContentStreamOperation) bool {
+	switch op.Operand {
+	case "Do", "sh", "BI":
+		return true
+	}
+	return false
+}
+
+// checkUnitOperations checks that the operations of `unit` are balanced, do not change the
+// graphics state after the unit and do not belong to the logical structure, and finds the
+// resources they use in `resources`.
+func checkUnitOperations(unit *contentUnit, resources *core.PdfObjectDictionary) bool {
+	stateDepth, textDepth, markedDepth := 0, 0, 0
+	for _, op := range unit.ops {
+		switch op.Operand {
+		case "q":
+			stateDepth++
+		case "Q":
+			stateDepth--
+		case "BT":
+			textDepth++
+		case "ET":
+			textDepth--
+		case "BMC":
+			markedDepth++
+		case "BDC":
+			markedDepth++
+			if len(op.Params) == 2 {
+				if props, ok := op.Params[1].(*core.PdfObjectDictionary); ok && props.Get("MCID") != nil {
+					return false
+				}
+			}
+		case "EMC":
+			markedDepth--
+		}
+		if stateDepth < 0 || textDepth < 0 || markedDepth < 0 {
+			return false
+		}
+		if _, ok := graphicsStateOperands[op.Operand]; ok && stateDepth == 0 {
+			return false
+		}
+		if !findOperationResources(unit, op, resources) {
+			return false
+		}
+	}
+	return stateDepth == 0 && textDepth == 0 && markedDepth == 0
+}
+
+// findOperationResources adds the resources used by `op` to the bindings of `unit`. It returns
+// false if a resource is missing.
+func findOperationResources(unit *contentUnit, op *contentstream.ContentStreamOperation, resources *core.PdfObjectDictionary) bool {
+	var category core.PdfObjectName
+	var nameObj core.PdfObject
+	switch op.Operand {
+	case "Do":
+		category = "XObject"
+	case "Tf":
+		category = "Font"
+	case "gs":
+		category = "ExtGState"
+	case "sh":
+		category = "Shading"
+	case "CS", "cs":
+		category = "ColorSpace"
+	case "SCN", "scn":
+		category = "Pattern"
+		if len(op.Params) > 0 {
+			nameObj = op.Params[len(op.Params)-1]
+		}
+	case "BDC", "DP":
+		category = "Properties"
+		if len(op.Params) == 2 {
+			nameObj = op.Params[1]
+		}
+	case "BI":
+		category = "ColorSpace"
+		if len(op.Params) == 1 {
+			if img, ok := op.Params[0].(*contentstream.ContentStreamInlineImage); ok {
+				nameObj = img.ColorSpace
+			}
+		}
+	default:
+		return true
+	}
+	if nameObj == nil && len(op.Params) > 0 && op.Operand != "BI" && op.Operand != "BDC" && op.Operand != "DP" {
+		nameObj = op.Params[0]
+	}
+	name, ok := nameObj.(*core.PdfObjectName)
+	if !ok {
+		return true
+	}
+	if category == "ColorSpace" {
+		switch *name {
+		case "DeviceGray", "DeviceRGB", "DeviceCMYK", "Pattern", "G", "RGB", "CMYK":
+			return true
+		}
+	}
+
+	dict, ok := core.GetDict(resources.Get(category))
+	if !ok {
+		return false
+	}
+	value := dict.Get(*name)
+	if value == nil {
+		return false
+	}
+	for _, b := range unit.bindings {
+		if b.category == category && b.name == *name {
+			return true
+		}
+	}
+	unit.bindings = append(unit.bindings, resourceBinding{category: category, name: *name, value: value})
+	return true
+}
+
+// resourceKey returns a key identifying the resource `obj`: the equal resources, such as the
+// identical graphics state parameter dictionaries added to each page, have the same key. The
+// streams and the deeply nested objects are identified by their address.
+func resourceKey(obj core.PdfObject, depth int) string {
+	if depth > 10 {
+		return fmt.Sprintf("%p", obj)
+	}
+	switch t := obj.(type) {
+	case *core.PdfIndirectObject:
+		return resourceKey(t.PdfObject, depth+1)
+	case *core.PdfObjectDictionary:
+		keys := append([]core.PdfObjectName(nil), t.Keys()...)
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+		var buf strings.Builder
+		buf.WriteString("<<")
+		for _, key := range keys {
+			buf.WriteString(key.WriteString())
+			buf.WriteString(" ")
+			buf.WriteString(resourceKey(t.Get(key), depth+1))
+			buf.WriteString(" ")
+		}
+		buf.WriteString(">>")
+		return buf.String()
+	case *core.PdfObjectArray:
+		var buf strings.Builder
+		buf.WriteString("[")
+		for _, o := range t.Elements() {
+			buf.WriteString(resourceKey(o, depth+1))
+			buf.WriteString(" ")
+		}
+		buf.WriteString("]")
+		return buf.String()
+	case *core.PdfObjectStream:
+		return fmt.Sprintf("%p", obj)
+	case nil:
+		return "null"
+	}
+	return obj.WriteString()
+}
+
+// fragmentBindings returns the resources used by `fragment`.
+func fragmentBindings(fragment *repeatedFragment) []resourceBinding {
+	var bindings []resourceBinding
+	seen := make(map[string]struct{})
+	for _, unit := range fragment.units {
+		for _, b := range unit.bindings {
+			key := string(b.category) + "/" + string(b.name)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			bindings = append(bindings, b)
+		}
+	}
+	return bindings
+}
+
+// makeFormStream returns a form XObject drawing `content` with the resources `bindings`.
+func makeFormStream(content []byte, bbox [4]float64, bindings []resourceBinding) (*core.PdfObjectStream, error) {
+	stream, err := core.MakeStream(content, core.NewFlateEncoder())
+	if err != nil {
+		return nil, err
+	}
+	resources := core.MakeDict()
+	for _, b := range bindings {
+		dict, ok := core.GetDict(resources.Get(b.category))
+		if !ok {
+			dict = core.MakeDict()
+			resources.Set(b.category, dict)
+		}
+		dict.Set(b.name, b.value)
+	}
+
+	stream.Set("Type", core.MakeName("XObject"))
+	stream.Set("Subtype", core.MakeName("Form"))
+	stream.Set("FormType", core.MakeInteger(1))
+	stream.Set("BBox", core.MakeArrayFromFloats(bbox[:]))
+	stream.Set("Resources", resources)
+	return stream, nil
+}
+
+// addPage adds the page `i` to the pages of `key` in `pages`.
+func addPage(pages map[string]map[int]struct{}, key string, i int) {
+	if pages[key] == nil {
+		pages[key] = make(map[int]struct{})
+	}
+	pages[key][i] = struct{}{}
+}
+
+// findReferences adds the objects of `targets` referenced by `obj` to `found`.
+func findReferences(obj core.PdfObject, targets, found, visited map[core.PdfObject]struct{}) {
+	if _, ok := visited[obj]; ok {
+		return
+	}
+	visited[obj] = struct{}{}
+	switch t := obj.(type) {
+	case *core.PdfIndirectObject:
+		findReferences(t.PdfObject, targets, found, visited)
+	case *core.PdfObjectStream:
+		findReferences(t.PdfObjectDictionary, targets, found, visited)
+	case *core.PdfObjectStreams:
+		for _, o := range t.Elements() {
+			findReferences(o, targets, found, visited)
+		}
+	case *core.PdfObjectArray:
+		for _, o := range t.Elements() {
+			markReference(o, targets, found)
+			findReferences(o, targets, found, visited)
+		}
+	case *core.PdfObjectDictionary:
+		for _, key := range t.Keys() {
+			o := t.Get(key)
+			markReference(o, targets, found)
+			findReferences(o, targets, found, visited)
+		}
+	}
+}
+
+// markReference adds `obj` to `found` if it is one of `targets`.
+func markReference(obj core.PdfObject, targets, found map[core.PdfObject]struct{}) {
+	if _, ok := targets[obj]; ok {
+		found[obj] = struct{}{}
+	}
+}
+
+// multiplyMatrices returns the product `a` × `b` of the affine transforms [a b c d e f].
+func multiplyMatrices(a, b [6]float64) [6]float64 {
+	return [6]float64{
+		a[0]*b[0] + a[1]*b[2],
+		a[0]*b[1] + a[1]*b[3],
+		a[2]*b[0] + a[3]*b[2],
+		a[2]*b[1] + a[3]*b[3],
+		a[4]*b[0] + a[5]*b[2] + b[4],
+		a[4]*b[1] + a[5]*b[3] + b[5],
+	}
+}
+
+// userSpaceBox returns the bounding box in the user space of the CTM `m` of the page box `box`.
+func userSpaceBox(box [4]float64, m [6]float64) [4]float64 {
+	det := m[0]*m[3] - m[1]*m[2]
+	if det == 0 {
+		return box
+	}
+	result := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, p := range [][2]float64{{box[0], box[1]}, {box[2], box[1]}, {box[0], box[3]}, {box[2], box[3]}} {
+		x, y := p[0]-m[4], p[1]-m[5]
+		u := (x*m[3] - y*m[2]) / det
+		v := (y*m[0] - x*m[1]) / det
+		result = unionBox(result, [4]float64{u, v, u, v})
+	}
+	return result
+}
+
+// unionBox returns the union of the boxes `a` and `b`.
+func unionBox(a, b [4]float64) [4]float64 {
+	return [4]float64{
+		math.Min(a[0], b[0]), math.Min(a[1], b[1]),
+		math.Max(a[2], b[2]), math.Max(a[3], b[3]),
+	}
+}
@@ -0,0 +1,148 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package optimize_test
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/creator"
+	"github.com/TheLinker/unipdf/v3/extractor"
+	"github.com/TheLinker/unipdf/v3/model"
+	"github.com/TheLinker/unipdf/v3/model/optimize"
+)
+
+// statementDocument returns a document whose pages have the same header, logo and footer, and
+// different bodies, written with `optimizer`.
+func statementDocument(t *testing.T, optimizer model.Optimizer) []byte {
+	logo := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for i := range logo.Pix {
+		logo.Pix[i] = uint8(i * 7)
+	}
+
+	c := creator.New()
+	img, err := c.NewImageFromGoImage(logo)
+	require.NoError(t, err)
+	img.ScaleToWidth(40)
+	img.SetPos(500, 30)
+
+	for num := 1; num <= 6; num++ {
+		c.NewPage()
+		header := c.NewStyledParagraph()
+		header.Append("ACME Corporation - Monthly statement of account\n")
+		header.Append("1 Main Street, Springfield\nCustomer service: 555-0100\n")
+		header.Append("Account number: 0123456789 - Statement period: January 2019")
+		header.SetPos(50, 40)
+		require.NoError(t, c.Draw(header))
+		require.NoError(t, c.Draw(img))
+
+		line := c.NewLine(50, 120, 545, 120)
+		line.SetColor(creator.ColorRGBFrom8bit(200, 0, 0))
+		require.NoError(t, c.Draw(line))
+
+		body := c.NewParagraph(fmt.Sprintf("Transactions of page %d", num))
+		body.SetPos(50, 200)
+		require.NoError(t, c.Draw(body))
+
+		footer := c.NewParagraph("ACME Corporation, 1 Main Street, Springfield - Confidential")
+		footer.SetPos(50, 800)
+		require.NoError(t, c.Draw(footer))
+	}
+	c.SetOptimizer(optimizer)
+
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+	return buf.Bytes()
+}
+
+func TestCombineRepeatedContent(t *testing.T) {
+	original := statementDocument(t, optimize.New(optimize.Options{}))
+	optimized := statementDocument(t, optimize.New(optimize.Options{CombineRepeatedContent: true}))
+	assert.True(t, len(optimized) < len(original), "%d >= %d", len(optimized), len(original))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(optimized))
+	require.NoError(t, err)
+	for num := 1; num <= 6; num++ {
+		page, err := reader.GetPage(num)
+		require.NoError(t, err)
+		content, err := page.GetAllContentStreams()
+		require.NoError(t, err)
+		assert.True(t, strings.Contains(content, "Do"))
+		assert.False(t, strings.Contains(content, "ACME"), content)
+
+		ext, err := extractor.New(page)
+		require.NoError(t, err)
+		text, err := ext.ExtractText()
+		require.NoError(t, err)
+		assert.Contains(t, text, "ACME Corporation - Monthly statement of account")
+		assert.Contains(t, text, fmt.Sprintf("Transactions of page %d", num))
+		assert.Contains(t, text, "Springfield - Confidential")
+	}
+}
+
+func TestCombineRepeatedContentGraphicsState(t *testing.T) {
+	// The repeated color and clipping operations change the graphics state of the following
+	// operations: only the path painting is moved.
+	content := `1 0 0 RG
+0 0 1 rg
+10 10 m 200 10 l 200 200 l 10 200 l h B
+20 20 180 180 re W n
+%s
+`
+	objects, err := parseIndirectObjects(fmt.Sprintf(`
+1 0 obj
+<< /Type /Page /MediaBox [0 0 300 300] /Contents 2 0 R /Resources << >> >>
+endobj
+2 0 obj
+<< /Length %d >>
+stream
+%s
+endstream
+endobj
+3 0 obj
+<< /Type /Page /MediaBox [0 0 300 300] /Contents 4 0 R /Resources << >> >>
+endobj
+4 0 obj
+<< /Length %d >>
+stream
+%s
+endstream
+endobj
+`, len(content)+5, fmt.Sprintf(content, "0 g"), len(content)+5, fmt.Sprintf(content, "1 g")))
+	require.NoError(t, err)
+	require.Len(t, objects, 4)
+	for i := 0; i < 4; i += 2 {
+		page, ok := core.GetDict(objects[i])
+		require.True(t, ok)
+		page.Set("Contents", objects[i+1])
+	}
+
+	optimizer := &optimize.CombineRepeatedContent{MinSize: 10}
+	optimized, err := optimizer.Optimize(objects)
+	require.NoError(t, err)
+	// The content streams are replaced and a form XObject is added.
+	require.Len(t, optimized, 5)
+
+	var form string
+	for _, obj := range optimized[2:] {
+		decoded, err := core.DecodeStream(obj.(*core.PdfObjectStream))
+		require.NoError(t, err)
+		if form == "" {
+			form = string(decoded)
+			continue
+		}
+		assert.True(t, strings.HasPrefix(string(decoded), "1 0 0 RG\n0 0 1 rg\n/Fm1 Do\n20 20 180 180 re\nW\nn\n"),
+			string(decoded))
+	}
+	assert.Equal(t, "10 10 m\n200 10 l\n200 200 l\n10 200 l\nh\nB\n", form)
+}
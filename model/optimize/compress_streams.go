@@ -7,32 +7,58 @@ package optimize
 
 import (
 	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/internal/parallel"
 )
 
 // CompressStreams compresses uncompressed streams.
 // It implements interface model.Optimizer.
 type CompressStreams struct {
+	// Workers is the number of streams Flate encoded concurrently. The streams are compressed
+	// sequentially if not set.
+	Workers int
 }
 
 // Optimize optimizes PDF objects to decrease PDF size.
 func (c *CompressStreams) Optimize(objects []core.PdfObject) (optimizedObjects []core.PdfObject, err error) {
 	optimizedObjects = make([]core.PdfObject, len(objects))
 	copy(optimizedObjects, objects)
+
+	var streams []*core.PdfObjectStream
+	processed := make(map[*core.PdfObjectStream]struct{})
 	for _, obj := range objects {
 		stream, isStreamObj := core.GetStream(obj)
 		if !isStreamObj {
 			continue
 		}
+		if _, found := processed[stream]; found {
+			continue
+		}
+		processed[stream] = struct{}{}
+		if stream.IsStreamed() {
+			// The data of the streamed streams is not held in memory.
+			continue
+		}
 		if _, found := core.GetName(stream.PdfObjectDictionary.Get("Filter")); found {
 			continue
 		}
+		streams = append(streams, stream)
+	}
+
+	// Encode the streams concurrently, then update them in order.
+	encoded := make([][]byte, len(streams))
+	err = parallel.Run(len(streams), c.Workers, func(i int) error {
 		encoder := core.NewFlateEncoder() // Most mainstream compressor and probably most robust.
-		var data []byte
-		data, err = encoder.EncodeBytes(stream.Stream)
-		if err != nil {
-			return optimizedObjects, err
-		}
-		dict := encoder.MakeStreamDict()
+		data, err := encoder.EncodeBytes(streams[i].Stream)
+		encoded[i] = data
+		return err
+	})
+	if err != nil {
+		return optimizedObjects, err
+	}
+
+	for i, stream := range streams {
+		data := encoded[i]
+		dict := core.NewFlateEncoder().MakeStreamDict()
 		// compare compressed and uncompressed sizes
 		if len(data)+len(dict.WriteString()) < len(stream.Stream) {
 			stream.Stream = data
@@ -8,6 +8,7 @@ package optimize
 import (
 	"github.com/TheLinker/unipdf/v3/common"
 	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/internal/parallel"
 	"github.com/TheLinker/unipdf/v3/model"
 )
 
@@ -16,6 +17,10 @@ import (
 // It implements interface model.Optimizer.
 type Image struct {
 	ImageQuality int
+
+	// Workers is the number of images encoded concurrently with DCT. The images are encoded
+	// sequentially if not set.
+	Workers int
 }
 
 // imageInfo is information about an image.
@@ -44,6 +49,10 @@ func findImages(objects []core.PdfObject) []*imageInfo {
 			continue
 		}
 		streamProcessed[stream] = struct{}{}
+		if stream.IsStreamed() {
+			// The data of the streamed images is not held in memory.
+			continue
+		}
 		subTypeValue := stream.PdfObjectDictionary.Get(subTypeKey)
 		subType, ok := core.GetName(subTypeValue)
 		if !ok || string(*subType) != "Image" {
@@ -95,20 +104,23 @@ func (i *Image) Optimize(objects []core.PdfObject) (optimizedObjects []core.PdfO
 		imageMasks[obj] = struct{}{}
 	}
 
-	for index, img := range images {
+	// Encode the images concurrently, then replace them in order.
+	encoded := make([]*core.PdfObjectStream, len(images))
+	err = parallel.Run(len(images), i.Workers, func(index int) error {
+		img := images[index]
 		stream := img.Stream
 		if _, isMask := imageMasks[stream]; isMask {
-			continue
+			return nil
 		}
 		streamEncoder, err := core.NewEncoderFromStream(stream)
 		if err != nil {
 			common.Log.Warning("Error get encoder for the image stream %s")
-			continue
+			return nil
 		}
 		data, err := streamEncoder.DecodeStream(stream)
 		if err != nil {
 			common.Log.Warning("Error decode the image stream %s")
-			continue
+			return nil
 		}
 		encoder := core.NewDCTEncoder()
 		encoder.ColorComponents = img.ColorComponents
@@ -118,11 +130,11 @@ func (i *Image) Optimize(objects []core.PdfObject) (optimizedObjects []core.PdfO
 		encoder.Height = img.Height
 		streamData, err := encoder.EncodeBytes(data)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		originalSize := len(stream.Stream)
 		if originalSize < len(streamData) {
-			continue
+			return nil
 		}
 		newStream := &core.PdfObjectStream{Stream: streamData}
 		newStream.PdfObjectReference = stream.PdfObjectReference
@@ -132,7 +144,18 @@ func (i *Image) Optimize(objects []core.PdfObject) (optimizedObjects []core.PdfO
 		newStream.PdfObjectDictionary.Set(core.PdfObjectName("Filter"), &fn)
 		ln := core.PdfObjectInteger(int64(len(streamData)))
 		newStream.PdfObjectDictionary.Set(core.PdfObjectName("Length"), &ln)
-		replaceTable[stream] = newStream
+		encoded[index] = newStream
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for index, newStream := range encoded {
+		if newStream == nil {
+			continue
+		}
+		replaceTable[images[index].Stream] = newStream
 		images[index].Stream = newStream
 	}
 	optimizedObjects = make([]core.PdfObject, len(objects))
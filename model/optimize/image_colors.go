@@ -0,0 +1,261 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package optimize
+
+import (
+	"sort"
+
+	"github.com/TheLinker/unipdf/v3/common"
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/internal/parallel"
+	"github.com/TheLinker/unipdf/v3/model"
+)
+
+// ImageColors optimizes images by reducing their colors: the DeviceRGB images whose pixels are all
+// gray are converted to DeviceGray and the images with few colors are converted to Indexed color
+// spaces with 1, 2, 4 or 8-bit palettes. The reduced images are Flate encoded with PNG predictors
+// and replace the original images only when they are smaller.
+// It implements interface model.Optimizer.
+type ImageColors struct {
+	// GrayTolerance is the maximum difference between the color components of a pixel for the
+	// pixel to be considered gray. The default 0 only accepts the exactly gray pixels.
+	GrayTolerance int
+
+	// MaxPaletteColors is the maximum number of colors of the images converted to Indexed color
+	// spaces, up to 256 (default).
+	MaxPaletteColors int
+
+	// Workers is the number of images reduced concurrently. The images are reduced sequentially
+	// if not set.
+	Workers int
+}
+
+// Optimize optimizes PDF objects to decrease PDF size.
+func (i *ImageColors) Optimize(objects []core.PdfObject) (optimizedObjects []core.PdfObject, err error) {
+	images := findImages(objects)
+	if len(images) == 0 {
+		return objects, nil
+	}
+
+	maxColors := i.MaxPaletteColors
+	if maxColors <= 0 || maxColors > 256 {
+		maxColors = 256
+	}
+
+	replaceTable := make(map[core.PdfObject]core.PdfObject)
+	imageMasks := make(map[core.PdfObject]struct{})
+	for _, img := range images {
+		obj := img.Stream.PdfObjectDictionary.Get(core.PdfObjectName("SMask"))
+		imageMasks[obj] = struct{}{}
+	}
+
+	// Reduce and encode the images concurrently, then replace them in order.
+	encoded := make([]*core.PdfObjectStream, len(images))
+	err = parallel.Run(len(images), i.Workers, func(index int) error {
+		img := images[index]
+		stream := img.Stream
+		if _, isMask := imageMasks[stream]; isMask {
+			return nil
+		}
+		if img.BitsPerComponent != 8 || stream.PdfObjectDictionary.Get("Decode") != nil {
+			return nil
+		}
+		// The color key masks are ranges of the color components of the original color space.
+		if _, isColorKey := core.GetArray(stream.PdfObjectDictionary.Get("Mask")); isColorKey {
+			return nil
+		}
+		if isMask, ok := core.GetBool(stream.PdfObjectDictionary.Get("ImageMask")); ok && bool(*isMask) {
+			return nil
+		}
+		streamEncoder, err := core.NewEncoderFromStream(stream)
+		if err != nil {
+			common.Log.Debug("ERROR: unable to get encoder for the image stream: %v", err)
+			return nil
+		}
+		data, err := streamEncoder.DecodeStream(stream)
+		if err != nil {
+			common.Log.Debug("ERROR: unable to decode the image stream: %v", err)
+			return nil
+		}
+		if img.Width <= 0 || img.Height <= 0 || len(data) != img.Width*img.Height*img.ColorComponents {
+			return nil
+		}
+
+		reduced := reduceImageColors(data, img.ColorComponents, i.GrayTolerance, maxColors)
+		if reduced == nil {
+			return nil
+		}
+
+		encoder := core.NewFlateEncoder()
+		encoder.Predictor = 15
+		encoder.BitsPerComponent = reduced.bitsPerComponent
+		encoder.Colors = 1
+		encoder.Columns = img.Width
+		streamData, err := encoder.EncodeBytes(packSamples(reduced.samples, img.Width, reduced.bitsPerComponent))
+		if err != nil {
+			return err
+		}
+		if len(streamData) >= len(stream.Stream) {
+			return nil
+		}
+
+		newStream := &core.PdfObjectStream{Stream: streamData}
+		newStream.PdfObjectReference = stream.PdfObjectReference
+		newStream.PdfObjectDictionary = core.MakeDict()
+		newStream.PdfObjectDictionary.Merge(stream.PdfObjectDictionary)
+		newStream.PdfObjectDictionary.Merge(encoder.MakeStreamDict())
+		newStream.PdfObjectDictionary.Set("ColorSpace", reduced.colorspace.ToPdfObject())
+		newStream.PdfObjectDictionary.Set("BitsPerComponent", core.MakeInteger(int64(reduced.bitsPerComponent)))
+		newStream.PdfObjectDictionary.Set("Length", core.MakeInteger(int64(len(streamData))))
+		encoded[index] = newStream
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for index, newStream := range encoded {
+		if newStream == nil {
+			continue
+		}
+		replaceTable[images[index].Stream] = newStream
+		images[index].Stream = newStream
+	}
+	optimizedObjects = make([]core.PdfObject, len(objects))
+	copy(optimizedObjects, objects)
+	replaceObjectsInPlace(optimizedObjects, replaceTable)
+	return optimizedObjects, nil
+}
+
+// reducedImage is an image with reduced colors.
+type reducedImage struct {
+	colorspace       model.PdfColorspace
+	bitsPerComponent int
+	samples          []byte // One sample per pixel.
+}
+
+// reduceImageColors reduces the colors of the 8-bit image `data` with `components` color
+// components per pixel. It returns nil if the colors of the image cannot be reduced.
+func reduceImageColors(data []byte, components, grayTolerance, maxColors int) *reducedImage {
+	// Convert the effectively gray RGB images to gray.
+	gray := components == 1
+	if components == 3 {
+		gray = true
+		for p := 0; p < len(data) && gray; p += 3 {
+			r, g, b := int(data[p]), int(data[p+1]), int(data[p+2])
+			gray = absInt(r-g) <= grayTolerance && absInt(g-b) <= grayTolerance && absInt(r-b) <= grayTolerance
+		}
+		if gray {
+			samples := make([]byte, len(data)/3)
+			for p := range samples {
+				samples[p] = byte((int(data[3*p]) + int(data[3*p+1]) + int(data[3*p+2]) + 1) / 3)
+			}
+			data, components = samples, 1
+		}
+	}
+
+	// Count the colors of the image.
+	index := make(map[uint32]int)
+	for p := 0; p < len(data) && len(index) <= maxColors; p += components {
+		index[colorKey(data[p:p+components])] = 0
+	}
+	if len(index) > maxColors {
+		if gray {
+			return &reducedImage{colorspace: model.NewPdfColorspaceDeviceGray(), bitsPerComponent: 8, samples: data}
+		}
+		return nil
+	}
+
+	bpc := 8
+	for _, bits := range []int{1, 2, 4} {
+		if len(index) <= 1<<uint(bits) {
+			bpc = bits
+			break
+		}
+	}
+
+	if gray {
+		// The gray levels of a DeviceGray image with less than 8 bits per component are the
+		// multiples of 255/(2^bpc-1) and do not need a palette.
+		step := 255 / (1<<uint(bpc) - 1)
+		scaled := true
+		for key := range index {
+			scaled = scaled && int(key)%step == 0
+		}
+		if scaled || bpc == 8 {
+			samples := make([]byte, len(data))
+			for p, v := range data {
+				samples[p] = byte(int(v) / step)
+			}
+			return &reducedImage{colorspace: model.NewPdfColorspaceDeviceGray(), bitsPerComponent: bpc, samples: samples}
+		}
+	}
+
+	// Sort the palette for a deterministic output.
+	keys := make([]uint32, 0, len(index))
+	for key := range index {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	lookup := make([]byte, 0, len(keys)*components)
+	for i, key := range keys {
+		index[key] = i
+		for c := components - 1; c >= 0; c-- {
+			lookup = append(lookup, byte(key>>uint(8*c)))
+		}
+	}
+
+	samples := make([]byte, len(data)/components)
+	for p := range samples {
+		samples[p] = byte(index[colorKey(data[p*components:(p+1)*components])])
+	}
+
+	cs := model.NewPdfColorspaceSpecialIndexed()
+	if components == 1 {
+		cs.Base = model.NewPdfColorspaceDeviceGray()
+	} else {
+		cs.Base = model.NewPdfColorspaceDeviceRGB()
+	}
+	cs.HiVal = len(keys) - 1
+	cs.Lookup = core.MakeString(string(lookup))
+	return &reducedImage{colorspace: cs, bitsPerComponent: bpc, samples: samples}
+}
+
+// colorKey returns the key of the color with 8-bit components `c`.
+func colorKey(c []byte) uint32 {
+	var key uint32
+	for _, v := range c {
+		key = key<<8 | uint32(v)
+	}
+	return key
+}
+
+// packSamples packs the one byte per pixel `samples` of an image `width` pixels wide to
+// `bpc` bits per sample, each row starting at a byte boundary.
+func packSamples(samples []byte, width, bpc int) []byte {
+	if bpc == 8 {
+		return samples
+	}
+	rowLength := (width*bpc + 7) / 8
+	rows := len(samples) / width
+	packed := make([]byte, rowLength*rows)
+	for y := 0; y < rows; y++ {
+		row := packed[y*rowLength : (y+1)*rowLength]
+		for x, v := range samples[y*width : (y+1)*width] {
+			bit := x * bpc
+			row[bit/8] |= v << uint(8-bpc-bit%8)
+		}
+	}
+	return packed
+}
+
+// absInt returns the absolute value of `x`.
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,164 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package optimize_test
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/model"
+	"github.com/TheLinker/unipdf/v3/model/optimize"
+)
+
+// makeRGBImage returns a Flate encoded DeviceRGB image stream `width` pixels wide with the
+// colors of `pixel`.
+func makeRGBImage(t *testing.T, width, height int, pixel func(x, y int) color.RGBA) *core.PdfObjectStream {
+	data := make([]byte, 0, width*height*3)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := pixel(x, y)
+			data = append(data, c.R, c.G, c.B)
+		}
+	}
+	encoded, err := core.NewFlateEncoder().EncodeBytes(data)
+	require.NoError(t, err)
+
+	dict := core.MakeDict()
+	dict.Set("Type", core.MakeName("XObject"))
+	dict.Set("Subtype", core.MakeName("Image"))
+	dict.Set("Width", core.MakeInteger(int64(width)))
+	dict.Set("Height", core.MakeInteger(int64(height)))
+	dict.Set("ColorSpace", core.MakeName("DeviceRGB"))
+	dict.Set("BitsPerComponent", core.MakeInteger(8))
+	dict.Set("Filter", core.MakeName("FlateDecode"))
+	dict.Set("Length", core.MakeInteger(int64(len(encoded))))
+	return &core.PdfObjectStream{PdfObjectDictionary: dict, Stream: encoded}
+}
+
+// checkImageColors optimizes `stream` with `optimizer` and checks that the optimized image has
+// the color space `csName` with `bpc` bits per component and the colors of `pixel`.
+func checkImageColors(t *testing.T, optimizer *optimize.ImageColors, stream *core.PdfObjectStream,
+	csName string, bpc int, pixel func(x, y int) color.RGBA) {
+	objects, err := optimizer.Optimize([]core.PdfObject{stream})
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	optimized, ok := core.GetStream(objects[0])
+	require.True(t, ok)
+
+	ximg, err := model.NewXObjectImageFromStream(optimized)
+	require.NoError(t, err)
+	assert.Equal(t, csName, ximg.ColorSpace.String())
+	assert.Equal(t, int64(bpc), *ximg.BitsPerComponent)
+	if csName == "DeviceRGB" {
+		assert.Equal(t, stream, optimized)
+		return
+	}
+	assert.True(t, len(optimized.Stream) < len(stream.Stream))
+
+	img, err := ximg.ToImage()
+	require.NoError(t, err)
+	rgb, err := ximg.ColorSpace.ImageToRGB(*img)
+	require.NoError(t, err)
+	goimg, err := rgb.ToGoImage()
+	require.NoError(t, err)
+	for y := 0; y < int(img.Height); y++ {
+		for x := 0; x < int(img.Width); x++ {
+			expected := pixel(x, y)
+			r, g, b, _ := goimg.At(x, y).RGBA()
+			require.Equal(t, []uint8{expected.R, expected.G, expected.B}, []uint8{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8)},
+				"pixel (%d, %d)", x, y)
+		}
+	}
+}
+
+func TestImageColors(t *testing.T) {
+	gradient := func(x, y int) color.RGBA {
+		v := uint8(x + y)
+		return color.RGBA{R: v, G: v, B: v, A: 255}
+	}
+	blackWhite := func(x, y int) color.RGBA {
+		if (x/8+y/8)%2 == 0 {
+			return color.RGBA{A: 255}
+		}
+		return color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	}
+	grays := func(x, y int) color.RGBA {
+		v := []uint8{10, 100, 200}[(x/4+y/4)%3]
+		return color.RGBA{R: v, G: v, B: v, A: 255}
+	}
+	fourColors := func(x, y int) color.RGBA {
+		return []color.RGBA{
+			{R: 255, A: 255},
+			{G: 128, A: 255},
+			{B: 200, A: 255},
+			{R: 10, G: 20, B: 30, A: 255},
+		}[(x/5+y/3)%4]
+	}
+	manyColors := func(x, y int) color.RGBA {
+		return color.RGBA{R: uint8(x * 7), G: uint8(y * 13), B: uint8(x * y), A: 255}
+	}
+
+	testcases := []struct {
+		name      string
+		optimizer *optimize.ImageColors
+		pixel     func(x, y int) color.RGBA
+		csName    string
+		bpc       int
+	}{
+		{"gradient", &optimize.ImageColors{}, gradient, "DeviceGray", 8},
+		{"black and white", &optimize.ImageColors{}, blackWhite, "DeviceGray", 1},
+		{"grays", &optimize.ImageColors{}, grays, "Indexed", 2},
+		{"four colors", &optimize.ImageColors{}, fourColors, "Indexed", 2},
+		{"many colors", &optimize.ImageColors{}, manyColors, "DeviceRGB", 8},
+		{"palette limit", &optimize.ImageColors{MaxPaletteColors: 3}, fourColors, "DeviceRGB", 8},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			stream := makeRGBImage(t, 64, 48, tc.pixel)
+			checkImageColors(t, tc.optimizer, stream, tc.csName, tc.bpc, tc.pixel)
+		})
+	}
+}
+
+func TestImageColorsGrayTolerance(t *testing.T) {
+	noisy := func(x, y int) color.RGBA {
+		v := uint8(x*3 + y)
+		return color.RGBA{R: v, G: v + uint8(x%3), B: v, A: 255}
+	}
+	stream := makeRGBImage(t, 64, 48, noisy)
+	checkImageColors(t, &optimize.ImageColors{}, stream, "DeviceRGB", 8, noisy)
+
+	gray := func(x, y int) color.RGBA {
+		c := noisy(x, y)
+		v := uint8((int(c.R) + int(c.G) + int(c.B) + 1) / 3)
+		return color.RGBA{R: v, G: v, B: v, A: 255}
+	}
+	checkImageColors(t, &optimize.ImageColors{GrayTolerance: 2}, stream, "DeviceGray", 8, gray)
+}
+
+func TestImageColorsColorKeyMask(t *testing.T) {
+	grays := func(x, y int) color.RGBA {
+		v := []uint8{10, 100, 200}[(x/4+y/4)%3]
+		return color.RGBA{R: v, G: v, B: v, A: 255}
+	}
+
+	// The images with color key masks are not converted.
+	stream := makeRGBImage(t, 64, 48, grays)
+	stream.PdfObjectDictionary.Set("Mask", core.MakeArrayFromIntegers([]int{100, 100, 100, 100, 100, 100}))
+	checkImageColors(t, &optimize.ImageColors{}, stream, "DeviceRGB", 8, grays)
+	mask, ok := core.GetArray(stream.PdfObjectDictionary.Get("Mask"))
+	require.True(t, ok)
+	require.Equal(t, 6, mask.Len())
+
+	// The images with stencil masks are.
+	stream = makeRGBImage(t, 64, 48, grays)
+	stream.PdfObjectDictionary.Set("Mask", core.MakeIndirectObject(core.MakeDict()))
+	checkImageColors(t, &optimize.ImageColors{}, stream, "Indexed", 2, grays)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/TheLinker/unipdf/v3/common"
 	"github.com/TheLinker/unipdf/v3/contentstream"
 	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/internal/parallel"
 	"github.com/TheLinker/unipdf/v3/model"
 	"golang.org/x/image/draw"
 )
@@ -22,6 +23,10 @@ import (
 // It implements interface model.Optimizer.
 type ImagePPI struct {
 	ImageUpperPPI float64
+
+	// Workers is the number of images scaled and re-encoded concurrently. The images are scaled
+	// sequentially if not set.
+	Workers int
 }
 
 func scaleImage(stream *core.PdfObjectStream, scale float64) error {
@@ -95,6 +100,55 @@ func (i *ImagePPI) Optimize(objects []core.PdfObject) (optimizedObjects []core.P
 	for _, img := range images {
 		imageByStream[img.Stream] = img
 	}
+	if err := computeImagePPI(objects, imageByStream); err != nil {
+		return nil, err
+	}
+
+	var scaled []*imageInfo
+	masks := make(map[*core.PdfObjectStream]struct{})
+	workers := i.Workers
+	for _, img := range images {
+		if _, isMask := imageMasks[img.Stream]; isMask {
+			continue
+		}
+		if img.PPI <= i.ImageUpperPPI {
+			continue
+		}
+		if mask, hasMask := core.GetStream(img.Stream.PdfObjectDictionary.Get(core.PdfObjectName("SMask"))); hasMask {
+			if _, shared := masks[mask]; shared {
+				// The images sharing masks are scaled sequentially.
+				workers = 1
+			}
+			masks[mask] = struct{}{}
+		}
+		scaled = append(scaled, img)
+	}
+
+	// The images are scaled in place.
+	err = parallel.Run(len(scaled), workers, func(index int) error {
+		img := scaled[index]
+		scale := i.ImageUpperPPI / img.PPI
+		if err := scaleImage(img.Stream, scale); err != nil {
+			common.Log.Debug("Error scale image keep original image: %s", err)
+		} else {
+			if mask, hasMask := core.GetStream(img.Stream.PdfObjectDictionary.Get(core.PdfObjectName("SMask"))); hasMask {
+				if err := scaleImage(mask, scale); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return objects, nil
+}
+
+// computeImagePPI sets the PPI of the images of `imageByStream` to the maximum resolution they are
+// drawn at on the pages of `objects`.
+func computeImagePPI(objects []core.PdfObject, imageByStream map[*core.PdfObjectStream]*imageInfo) error {
 	var catalog *core.PdfObjectDictionary
 	for _, obj := range objects {
 		if dict, isDict := core.GetDict(obj); catalog == nil && isDict {
@@ -104,15 +158,15 @@ func (i *ImagePPI) Optimize(objects []core.PdfObject) (optimizedObjects []core.P
 		}
 	}
 	if catalog == nil {
-		return objects, nil
+		return nil
 	}
 	pages, hasPages := core.GetDict(catalog.Get(core.PdfObjectName("Pages")))
 	if !hasPages {
-		return objects, nil
+		return nil
 	}
 	kids, hasKids := core.GetArray(pages.Get(core.PdfObjectName("Kids")))
 	if !hasKids {
-		return objects, nil
+		return nil
 	}
 	imageByName := make(map[string]*imageInfo)
 
@@ -121,8 +175,13 @@ func (i *ImagePPI) Optimize(objects []core.PdfObject) (optimizedObjects []core.P
 		if !ok {
 			continue
 		}
-		contents, hasContents := core.GetArray(page.Get("Contents"))
-		if !hasContents {
+		// The contents are a stream or an array of streams.
+		var contents []core.PdfObject
+		if arr, isArray := core.GetArray(page.Get("Contents")); isArray {
+			contents = arr.Elements()
+		} else if stream, isStream := core.GetStream(page.Get("Contents")); isStream {
+			contents = []core.PdfObject{stream}
+		} else {
 			continue
 		}
 		resources, hasResources := core.GetDict(page.Get("Resources"))
@@ -141,21 +200,21 @@ func (i *ImagePPI) Optimize(objects []core.PdfObject) (optimizedObjects []core.P
 				}
 			}
 		}
-		for _, obj := range contents.Elements() {
+		for _, obj := range contents {
 			if stream, isStream := core.GetStream(obj); isStream {
 				streamEncoder, err := core.NewEncoderFromStream(stream)
 				if err != nil {
-					return nil, err
+					return err
 				}
 				data, err := streamEncoder.DecodeStream(stream)
 				if err != nil {
-					return nil, err
+					return err
 				}
 
 				p := contentstream.NewContentStreamParser(string(data))
 				operations, err := p.Parse()
 				if err != nil {
-					return nil, err
+					return err
 				}
 				scaleX, scaleY := 1.0, 1.0
 				for _, operation := range *operations {
@@ -196,25 +255,5 @@ func (i *ImagePPI) Optimize(objects []core.PdfObject) (optimizedObjects []core.P
 			}
 		}
 	}
-
-	for _, img := range images {
-		if _, isMask := imageMasks[img.Stream]; isMask {
-			continue
-		}
-		if img.PPI <= i.ImageUpperPPI {
-			continue
-		}
-		scale := i.ImageUpperPPI / img.PPI
-		if err := scaleImage(img.Stream, scale); err != nil {
-			common.Log.Debug("Error scale image keep original image: %s", err)
-		} else {
-			if mask, hasMask := core.GetStream(img.Stream.PdfObjectDictionary.Get(core.PdfObjectName("SMask"))); hasMask {
-				if err := scaleImage(mask, scale); err != nil {
-					return nil, err
-				}
-			}
-		}
-	}
-
-	return objects, nil
+	return nil
 }
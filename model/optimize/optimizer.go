@@ -12,16 +12,26 @@ import (
 // New creates a optimizers chain from options.
 func New(options Options) *Chain {
 	chain := new(Chain)
+	chain.SetReport(options.Report)
+	chain.SetDryRun(options.DryRun)
 	if options.ImageUpperPPI > 0 {
 		imageOptimizer := new(ImagePPI)
 		imageOptimizer.ImageUpperPPI = options.ImageUpperPPI
+		imageOptimizer.Workers = options.Workers
 		chain.Append(imageOptimizer)
 	}
 	if options.ImageQuality > 0 {
 		imageOptimizer := new(Image)
 		imageOptimizer.ImageQuality = options.ImageQuality
+		imageOptimizer.Workers = options.Workers
 		chain.Append(imageOptimizer)
 	}
+	if options.ReduceImageColors {
+		chain.Append(&ImageColors{Workers: options.Workers})
+	}
+	if options.CombineRepeatedContent {
+		chain.Append(new(CombineRepeatedContent))
+	}
 	if options.CombineDuplicateDirectObjects {
 		chain.Append(new(CombineDuplicateDirectObjects))
 	}
@@ -35,7 +45,7 @@ func New(options Options) *Chain {
 		chain.Append(new(ObjectStreams))
 	}
 	if options.CompressStreams {
-		chain.Append(new(CompressStreams))
+		chain.Append(&CompressStreams{Workers: options.Workers})
 	}
 	return chain
 }
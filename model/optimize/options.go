@@ -14,4 +14,18 @@ type Options struct {
 	UseObjectStreams                bool
 	CombineIdenticalIndirectObjects bool
 	CompressStreams                 bool
+	ReduceImageColors               bool
+	CombineRepeatedContent          bool
+
+	// Workers is the number of streams encoded concurrently by the image optimizers and the
+	// stream compression. The streams are encoded sequentially if not set.
+	// The streams are processed independently and replaced in the order of the objects, so that
+	// the optimized document is identical for any number of workers, whether set here or on the
+	// Workers fields of the optimizers.
+	Workers int
+
+	// Report makes the chain report the effect of each optimizer, see Chain.Report.
+	Report bool
+	// DryRun makes the chain estimate the savings without modifying the document.
+	DryRun bool
 }
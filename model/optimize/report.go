@@ -0,0 +1,198 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package optimize
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/TheLinker/unipdf/v3/model"
+)
+
+// Report describes the effect of the optimization of a document by a Chain. The sizes are
+// estimations of the sizes of the objects in the written document.
+type Report struct {
+	// DryRun tells that the optimized objects were discarded and the document written unchanged.
+	DryRun bool
+
+	// Input and Output are the sizes of the document before and after the optimization.
+	Input  SizeBreakdown
+	Output SizeBreakdown
+
+	// Steps are the reports of the optimizers of the chain, in order.
+	Steps []*StepReport
+}
+
+// SizeBreakdown is the size in bytes of the objects of a document by category.
+type SizeBreakdown struct {
+	Images   int64 // Image XObjects and their masks.
+	Fonts    int64 // Font dictionaries, descriptors, programs, widths and ToUnicode CMaps.
+	Content  int64 // Page content streams and form XObjects.
+	Metadata int64 // XMP metadata streams and document information dictionary.
+	Other    int64 // Document structure, annotations, outlines, etc.
+
+	// Objects is the number of objects of the document.
+	Objects int
+}
+
+// StepReport is the report of an optimizer of a Chain.
+type StepReport struct {
+	// Name is the type name of the optimizer, e.g. "ImagePPI".
+	Name string
+
+	// SizeBefore and SizeAfter are the sizes of the document before and after the step.
+	SizeBefore int64
+	SizeAfter  int64
+
+	// ObjectsRemoved is the number of objects removed or merged into other objects.
+	ObjectsRemoved int
+	// ObjectsAdded is the number of objects added, e.g. shared objects replacing duplicates.
+	ObjectsAdded int
+
+	// Images are the images modified by the step.
+	Images []ImageChange
+	// Fonts are the fonts modified by the step.
+	Fonts []FontChange
+}
+
+// ImageChange is an image modified by an optimizer.
+type ImageChange struct {
+	// Name is the resource name of the image and Page the number of the first page using it.
+	Name string
+	Page int
+
+	Before ImageStats
+	After  ImageStats
+
+	// Merged tells that the image was replaced by an identical image.
+	Merged bool
+}
+
+// ImageStats are the properties of an image.
+type ImageStats struct {
+	Width  int
+	Height int
+	// PPI is the highest resolution the image is drawn at on the pages, 0 if unknown.
+	PPI float64
+	// Size is the size of the image stream.
+	Size int64
+	// Filter is the name of the encoding filter of the image stream.
+	Filter string
+}
+
+// FontChange is a font modified by an optimizer.
+type FontChange struct {
+	// Name is the base font of the font and Page the number of the first page using it.
+	Name string
+	Page int
+
+	// SizeBefore and SizeAfter are the sizes of the objects of the font.
+	SizeBefore int64
+	SizeAfter  int64
+
+	// Merged tells that the font program was replaced by an identical font program.
+	Merged bool
+}
+
+// Total returns the size of the document.
+func (b SizeBreakdown) Total() int64 {
+	return b.Images + b.Fonts + b.Content + b.Metadata + b.Other
+}
+
+// String returns a description of the size breakdown.
+func (b SizeBreakdown) String() string {
+	return fmt.Sprintf("%d bytes in %d objects (images %d, fonts %d, content %d, metadata %d, other %d)",
+		b.Total(), b.Objects, b.Images, b.Fonts, b.Content, b.Metadata, b.Other)
+}
+
+// Saved returns the number of bytes saved by the step.
+func (s *StepReport) Saved() int64 {
+	return s.SizeBefore - s.SizeAfter
+}
+
+// Saved returns the number of bytes saved by the optimization.
+func (r *Report) Saved() int64 {
+	return r.Input.Total() - r.Output.Total()
+}
+
+// String returns a human readable description of the report.
+func (r *Report) String() string {
+	var buf bytes.Buffer
+	if r.DryRun {
+		buf.WriteString("Dry run, the document is not modified.\n")
+	}
+	fmt.Fprintf(&buf, "Input:  %s\n", r.Input)
+	fmt.Fprintf(&buf, "Output: %s\n", r.Output)
+	percent := 0.0
+	if total := r.Input.Total(); total > 0 {
+		percent = 100 * float64(r.Saved()) / float64(total)
+	}
+	fmt.Fprintf(&buf, "Saved:  %d bytes (%.1f%%)\n", r.Saved(), percent)
+
+	for _, step := range r.Steps {
+		fmt.Fprintf(&buf, "%s: saved %d bytes, %d objects removed, %d objects added\n",
+			step.Name, step.Saved(), step.ObjectsRemoved, step.ObjectsAdded)
+		for _, img := range step.Images {
+			if img.Merged {
+				fmt.Fprintf(&buf, "  image %s (page %d): merged with an identical image\n", img.Name, img.Page)
+				continue
+			}
+			fmt.Fprintf(&buf, "  image %s (page %d): %s -> %s\n", img.Name, img.Page, img.Before, img.After)
+		}
+		for _, font := range step.Fonts {
+			merged := ""
+			if font.Merged {
+				merged = ", merged with an identical font program"
+			}
+			fmt.Fprintf(&buf, "  font %s (page %d): %d -> %d bytes%s\n",
+				font.Name, font.Page, font.SizeBefore, font.SizeAfter, merged)
+		}
+	}
+	return buf.String()
+}
+
+// String returns a description of the image properties.
+func (s ImageStats) String() string {
+	parts := []string{fmt.Sprintf("%dx%d", s.Width, s.Height)}
+	if s.PPI > 0 {
+		parts = append(parts, fmt.Sprintf("%.0f PPI", s.PPI))
+	}
+	if s.Filter != "" {
+		parts = append(parts, s.Filter)
+	}
+	parts = append(parts, fmt.Sprintf("%d bytes", s.Size))
+	return strings.Join(parts, " ")
+}
+
+// DryRun estimates the savings of the optimization of the pages of `reader` with `options`
+// without producing the output: the pages are written to ioutil.Discard with a dry run Chain.
+func DryRun(reader *model.PdfReader, options Options) (*Report, error) {
+	numPages, err := reader.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+
+	writer := model.NewPdfWriter()
+	for i := 1; i <= numPages; i++ {
+		page, err := reader.GetPage(i)
+		if err != nil {
+			return nil, err
+		}
+		if err := writer.AddPage(page); err != nil {
+			return nil, err
+		}
+	}
+
+	options.DryRun = true
+	chain := New(options)
+	writer.SetOptimizer(chain)
+	if err := writer.Write(ioutil.Discard); err != nil {
+		return nil, err
+	}
+	return chain.Report(), nil
+}
@@ -0,0 +1,128 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package optimize_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TheLinker/unipdf/v3/creator"
+	"github.com/TheLinker/unipdf/v3/model"
+	"github.com/TheLinker/unipdf/v3/model/optimize"
+)
+
+// imageDocument returns a creator of a document with a 400x400 pixels image drawn 100 points wide
+// on two pages.
+func imageDocument(t *testing.T) *creator.Creator {
+	goimg := image.NewRGBA(image.Rect(0, 0, 400, 400))
+	for y := 0; y < 400; y++ {
+		for x := 0; x < 400; x++ {
+			goimg.Set(x, y, color.RGBA{R: uint8(x * 255 / 400), G: uint8(y * 255 / 400), B: uint8((x + y) % 7), A: 255})
+		}
+	}
+
+	c := creator.New()
+	for i := 0; i < 2; i++ {
+		// The images are encoded separately on each page.
+		img, err := c.NewImageFromGoImage(goimg)
+		require.NoError(t, err)
+		img.ScaleToWidth(100)
+		img.SetPos(50, 50)
+		c.NewPage()
+		require.NoError(t, c.Draw(img))
+	}
+	return c
+}
+
+func TestReport(t *testing.T) {
+	c := imageDocument(t)
+	chain := optimize.New(optimize.Options{ImageUpperPPI: 100, CombineDuplicateStreams: true, Report: true})
+	c.SetOptimizer(chain)
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+
+	report := chain.Report()
+	require.NotNil(t, report)
+	assert.False(t, report.DryRun)
+	require.Len(t, report.Steps, 2)
+	assert.True(t, report.Input.Images > report.Input.Content)
+	assert.True(t, report.Saved() > 0)
+	assert.Equal(t, report.Input.Total()-report.Steps[0].Saved()-report.Steps[1].Saved(), report.Output.Total())
+
+	ppi := report.Steps[0]
+	assert.Equal(t, "ImagePPI", ppi.Name)
+	assert.True(t, ppi.Saved() > 0)
+	require.Len(t, ppi.Images, 2)
+	for i, img := range ppi.Images {
+		assert.Equal(t, i+1, img.Page)
+		assert.False(t, img.Merged)
+		assert.Equal(t, 400, img.Before.Width)
+		assert.InDelta(t, 288, img.Before.PPI, 1)
+		assert.Equal(t, 139, img.After.Width)
+		assert.InDelta(t, 100, img.After.PPI, 1)
+		assert.True(t, img.After.Size < img.Before.Size)
+	}
+
+	duplicates := report.Steps[1]
+	assert.Equal(t, "CombineDuplicateStreams", duplicates.Name)
+	assert.True(t, duplicates.ObjectsRemoved > 0)
+	require.Len(t, duplicates.Images, 1)
+	assert.Equal(t, 2, duplicates.Images[0].Page)
+	assert.True(t, duplicates.Images[0].Merged)
+	assert.Contains(t, report.String(), "merged with an identical image")
+}
+
+func TestDryRun(t *testing.T) {
+	var original bytes.Buffer
+	require.NoError(t, imageDocument(t).Write(&original))
+
+	options := optimize.Options{ImageUpperPPI: 100, CombineDuplicateStreams: true}
+	reader, err := model.NewPdfReader(bytes.NewReader(original.Bytes()))
+	require.NoError(t, err)
+	report, err := optimize.DryRun(reader, options)
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.True(t, report.Saved() > 0)
+	require.Len(t, report.Steps, 2)
+	assert.Len(t, report.Steps[0].Images, 2)
+
+	// A dry run chain writes the document unchanged.
+	c := imageDocument(t)
+	options.DryRun = true
+	chain := optimize.New(options)
+	c.SetOptimizer(chain)
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+	assert.Equal(t, original.Len(), buf.Len())
+	require.NotNil(t, chain.Report())
+	assert.Equal(t, report.Steps[0].Images[0].After.Width, chain.Report().Steps[0].Images[0].After.Width)
+}
+
+func TestWorkers(t *testing.T) {
+	write := func(workers int) []byte {
+		c := imageDocument(t)
+		c.SetOptimizer(optimize.New(optimize.Options{
+			ImageUpperPPI:    100,
+			ImageQuality:     80,
+			CompressStreams:  true,
+			UseObjectStreams: true,
+			Workers:          workers,
+		}))
+		var buf bytes.Buffer
+		require.NoError(t, c.Write(&buf))
+		return buf.Bytes()
+	}
+
+	// The output is the same with concurrent encoding.
+	sequential := write(0)
+	assert.Equal(t, sequential, write(1))
+	assert.Equal(t, sequential, write(4))
+}
@@ -0,0 +1,146 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"github.com/TheLinker/unipdf/v3/common"
+	"github.com/TheLinker/unipdf/v3/core"
+)
+
+// PdfOutputIntent represents an output intent dictionary, describing the
+// colour characteristics of the output device on which the document is
+// intended to be rendered (section 14.11.5 "Output Intents" PDF32000_2008).
+type PdfOutputIntent struct {
+	// S is the output intent subtype (e.g. GTS_PDFX or GTS_PDFA1).
+	S *core.PdfObjectName
+
+	OutputCondition           *core.PdfObjectString
+	OutputConditionIdentifier *core.PdfObjectString
+	RegistryName              *core.PdfObjectString
+	Info                      *core.PdfObjectString
+
+	// DestOutputProfile is the ICC profile stream characterizing the
+	// output condition.
+	DestOutputProfile *core.PdfObjectStream
+
+	container *core.PdfIndirectObject
+}
+
+// NewPdfOutputIntent returns a new output intent of the specified subtype.
+func NewPdfOutputIntent(subtype string) *PdfOutputIntent {
+	return &PdfOutputIntent{
+		S:         core.MakeName(subtype),
+		container: core.MakeIndirectObject(core.MakeDict()),
+	}
+}
+
+// SetDestOutputProfile sets the ICC profile of the output intent. The number
+// of color components `n` of the profile must be specified.
+func (oi *PdfOutputIntent) SetDestOutputProfile(profile []byte, n int) error {
+	stream, err := core.MakeStream(profile, core.NewFlateEncoder())
+	if err != nil {
+		return err
+	}
+	stream.PdfObjectDictionary.Set("N", core.MakeInteger(int64(n)))
+	oi.DestOutputProfile = stream
+	return nil
+}
+
+// DestOutputProfileComponents returns the number of color components of the
+// output intent profile, or 0 if the output intent does not have a profile.
+func (oi *PdfOutputIntent) DestOutputProfileComponents() int {
+	if oi.DestOutputProfile == nil {
+		return 0
+	}
+	n, ok := core.GetIntVal(oi.DestOutputProfile.Get("N"))
+	if !ok {
+		return 0
+	}
+	return n
+}
+
+// newPdfOutputIntentFromObject loads an output intent from the specified object.
+func newPdfOutputIntentFromObject(obj core.PdfObject) (*PdfOutputIntent, error) {
+	oi := &PdfOutputIntent{}
+	if ind, ok := core.GetIndirect(obj); ok {
+		oi.container = ind
+	} else {
+		oi.container = core.MakeIndirectObject(obj)
+	}
+
+	d, ok := core.GetDict(obj)
+	if !ok {
+		common.Log.Debug("ERROR: OutputIntent not a dictionary (%T)", obj)
+		return nil, ErrTypeCheck
+	}
+
+	if s, ok := core.GetName(d.Get("S")); ok {
+		oi.S = s
+	} else {
+		common.Log.Debug("ERROR: OutputIntent missing S")
+		return nil, ErrRequiredAttributeMissing
+	}
+	if str, ok := core.GetString(d.Get("OutputCondition")); ok {
+		oi.OutputCondition = str
+	}
+	if str, ok := core.GetString(d.Get("OutputConditionIdentifier")); ok {
+		oi.OutputConditionIdentifier = str
+	}
+	if str, ok := core.GetString(d.Get("RegistryName")); ok {
+		oi.RegistryName = str
+	}
+	if str, ok := core.GetString(d.Get("Info")); ok {
+		oi.Info = str
+	}
+	if stream, ok := core.GetStream(d.Get("DestOutputProfile")); ok {
+		oi.DestOutputProfile = stream
+	}
+
+	return oi, nil
+}
+
+// GetContainingPdfObject implements interface PdfModel.
+func (oi *PdfOutputIntent) GetContainingPdfObject() core.PdfObject {
+	return oi.container
+}
+
+// ToPdfObject implements interface PdfModel.
+func (oi *PdfOutputIntent) ToPdfObject() core.PdfObject {
+	d, ok := core.GetDict(oi.container.PdfObject)
+	if !ok {
+		d = core.MakeDict()
+		oi.container.PdfObject = d
+	}
+	d.Clear()
+
+	d.Set("Type", core.MakeName("OutputIntent"))
+	d.SetIfNotNil("S", oi.S)
+	d.SetIfNotNil("OutputCondition", oi.OutputCondition)
+	d.SetIfNotNil("OutputConditionIdentifier", oi.OutputConditionIdentifier)
+	d.SetIfNotNil("RegistryName", oi.RegistryName)
+	d.SetIfNotNil("Info", oi.Info)
+	d.SetIfNotNil("DestOutputProfile", oi.DestOutputProfile)
+
+	return oi.container
+}
+
+// GetOutputIntents returns the output intents of the document catalog.
+func (r *PdfReader) GetOutputIntents() ([]*PdfOutputIntent, error) {
+	arr, ok := core.GetArray(r.catalog.Get("OutputIntents"))
+	if !ok {
+		return nil, nil
+	}
+
+	var intents []*PdfOutputIntent
+	for _, obj := range arr.Elements() {
+		oi, err := newPdfOutputIntentFromObject(core.ResolveReference(obj))
+		if err != nil {
+			return nil, err
+		}
+		intents = append(intents, oi)
+	}
+	return intents, nil
+}
@@ -52,6 +52,7 @@ type PdfPage struct {
 	PresSteps            core.PdfObject
 	UserUnit             core.PdfObject
 	VP                   core.PdfObject
+	AF                   core.PdfObject // Associated files (PDF 2.0).
 	Annots               core.PdfObject
 
 	annotations []*PdfAnnotation
@@ -270,6 +271,9 @@ func (r *PdfReader) newPdfPageFromDict(p *core.PdfObjectDictionary) (*PdfPage, e
 	if obj := d.Get("VP"); obj != nil {
 		page.VP = obj
 	}
+	if obj := d.Get("AF"); obj != nil {
+		page.AF = obj
+	}
 	if obj := d.Get("Annots"); obj != nil {
 		page.Annots = obj
 	}
@@ -489,6 +493,7 @@ func (p *PdfPage) GetPageDict() *core.PdfObjectDictionary {
 	d.SetIfNotNil("PresSteps", p.PresSteps)
 	d.SetIfNotNil("UserUnit", p.UserUnit)
 	d.SetIfNotNil("VP", p.VP)
+	d.SetIfNotNil("AF", p.AF)
 
 	if p.annotations != nil {
 		arr := core.MakeArray()
@@ -0,0 +1,350 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package pdfua
+
+import (
+	"github.com/TheLinker/unipdf/v3/common"
+	"github.com/TheLinker/unipdf/v3/contentstream"
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/internal/pageutil"
+)
+
+// pageInfo contains the information collected from the content of a page.
+type pageInfo struct {
+	num    int
+	dict   *core.PdfObjectDictionary
+	objNum int64
+
+	// mcids contains the MCIDs of the marked content of the page, in content
+	// order, and mcidIndex maps them to their position.
+	mcids     []int
+	mcidIndex map[int]int
+
+	// untagged is the number of painting operators which are neither tagged
+	// nor marked as artifacts, and untaggedOp is the first of them.
+	untagged   int
+	untaggedOp string
+}
+
+// loc returns the location of the page.
+func (p *pageInfo) loc() location {
+	return location{page: p.num, mcid: NoMCID, objNum: p.objNum}
+}
+
+// paintingOperators contains the operators which produce visible content.
+var paintingOperators = map[string]struct{}{
+	"Tj": {}, "TJ": {}, "'": {}, "\"": {},
+	"S": {}, "s": {}, "f": {}, "F": {}, "f*": {}, "B": {}, "B*": {}, "b": {}, "b*": {},
+	"Do": {}, "sh": {}, "BI": {},
+}
+
+// analyzePage collects the marked content of the page and checks the fonts
+// used by the page.
+func (v *validator) analyzePage(page *pageInfo) {
+	page.mcidIndex = map[int]int{}
+
+	var content []byte
+	switch t := core.TraceToDirectObject(page.dict.Get("Contents")).(type) {
+	case *core.PdfObjectStream:
+		content = decodeStream(t)
+	case *core.PdfObjectArray:
+		for _, obj := range t.Elements() {
+			if stream, ok := core.GetStream(obj); ok {
+				content = append(content, decodeStream(stream)...)
+				content = append(content, ' ')
+			}
+		}
+	}
+	a := &contentAnalyzer{
+		v:       v,
+		page:    page,
+		visited: map[*core.PdfObjectStream]struct{}{},
+	}
+	a.analyze(content, pageutil.InheritedResources(page.dict), false)
+}
+
+// contentAnalyzer analyzes the content streams of a page and of the form
+// XObjects it uses.
+type contentAnalyzer struct {
+	v       *validator
+	page    *pageInfo
+	visited map[*core.PdfObjectStream]struct{}
+}
+
+// analyze analyzes the content stream `content`. The marked content is only
+// collected for page content streams (`form` false), the marked content of
+// form XObjects being covered by the marked content of the Do operators.
+func (a *contentAnalyzer) analyze(content []byte, resources *core.PdfObjectDictionary, form bool) {
+	if len(content) == 0 {
+		return
+	}
+	ops, err := contentstream.NewContentStreamParser(string(content)).Parse()
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to parse content stream: %v", err)
+	}
+	if ops == nil {
+		return
+	}
+
+	type markedContent struct {
+		tagged   bool
+		artifact bool
+	}
+	var stack []markedContent
+	covered := func() bool {
+		for _, mc := range stack {
+			if mc.tagged || mc.artifact {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, op := range *ops {
+		switch op.Operand {
+		case "BMC", "BDC":
+			var mc markedContent
+			if len(op.Params) > 0 {
+				tag, _ := core.GetNameVal(op.Params[0])
+				mc.artifact = tag == "Artifact"
+			}
+			if op.Operand == "BDC" && len(op.Params) == 2 && !form {
+				if props, ok := core.GetDict(pageutil.Properties(op.Params[1], resources)); ok {
+					if mcid, ok := core.GetIntVal(props.Get("MCID")); ok {
+						mc.tagged = true
+						if _, ok := a.page.mcidIndex[mcid]; !ok {
+							a.page.mcidIndex[mcid] = len(a.page.mcids)
+							a.page.mcids = append(a.page.mcids, mcid)
+						}
+					}
+				}
+			}
+			stack = append(stack, mc)
+			continue
+		case "EMC":
+			if n := len(stack); n > 0 {
+				stack = stack[:n-1]
+			}
+			continue
+		case "Tf":
+			if len(op.Params) == 2 {
+				if name, ok := core.GetName(op.Params[0]); ok {
+					a.checkFont(*name, resources)
+				}
+			}
+		case "Do":
+			if len(op.Params) == 1 {
+				if name, ok := core.GetName(op.Params[0]); ok {
+					a.analyzeXObject(*name, resources)
+				}
+			}
+		}
+
+		if _, ok := paintingOperators[op.Operand]; ok && !form && !covered() {
+			if a.page.untagged == 0 {
+				a.page.untaggedOp = op.Operand
+			}
+			a.page.untagged++
+		}
+	}
+}
+
+// checkFont checks the font resource `name`.
+func (a *contentAnalyzer) checkFont(name core.PdfObjectName, resources *core.PdfObjectDictionary) {
+	if resources == nil {
+		return
+	}
+	fonts, ok := core.GetDict(resources.Get("Font"))
+	if !ok {
+		return
+	}
+	obj := fonts.Get(name)
+	font, ok := core.GetDict(obj)
+	if !ok {
+		return
+	}
+	if _, ok := a.v.fonts[font]; ok {
+		return
+	}
+	a.v.fonts[font] = struct{}{}
+
+	loc := a.page.loc()
+	if num := objectNumber(obj); num > 0 {
+		loc.objNum = num
+	}
+	a.v.validateFont(font, string(name), loc)
+}
+
+// analyzeXObject analyzes the content of the form XObject `name`.
+func (a *contentAnalyzer) analyzeXObject(name core.PdfObjectName, resources *core.PdfObjectDictionary) {
+	if resources == nil {
+		return
+	}
+	xobjects, ok := core.GetDict(resources.Get("XObject"))
+	if !ok {
+		return
+	}
+	stream, ok := core.GetStream(xobjects.Get(name))
+	if !ok {
+		return
+	}
+	if subtype, _ := core.GetNameVal(stream.Get("Subtype")); subtype != "Form" {
+		return
+	}
+	if _, ok := a.visited[stream]; ok {
+		return
+	}
+	a.visited[stream] = struct{}{}
+
+	formResources, ok := core.GetDict(stream.Get("Resources"))
+	if !ok {
+		formResources = resources
+	}
+	a.analyze(decodeStream(stream), formResources, true)
+}
+
+// validatePage checks that the page content is tagged consistently with the
+// structure tree, and that the annotations of the page are tagged.
+func (v *validator) validatePage(page *pageInfo, tree *structTree) {
+	if page.untagged > 0 {
+		v.report(RuleTaggedContent, page.loc(),
+			"%d content operations neither tagged nor marked as artifact (first: %s)",
+			page.untagged, page.untaggedOp)
+	}
+	v.validateAnnotations(page, tree)
+	if tree == nil {
+		return
+	}
+
+	// Marked content referenced by the structure tree.
+	refs := tree.mcids[page.num]
+	referenced := make(map[int]*structElem, len(refs))
+	for _, ref := range refs {
+		referenced[ref.mcid] = ref.elem
+		if _, ok := page.mcidIndex[ref.mcid]; !ok {
+			v.report(RuleStructure, location{page: page.num, mcid: ref.mcid, objNum: ref.elem.objNum},
+				"structure element references marked content missing from the page")
+		}
+	}
+	for _, mcid := range page.mcids {
+		if _, ok := referenced[mcid]; !ok {
+			v.report(RuleTaggedContent, location{page: page.num, mcid: mcid, objNum: page.objNum},
+				"marked content not referenced by the structure tree")
+		}
+	}
+
+	// Reading order: the logical order of the structure tree is expected to
+	// follow the order of the content.
+	last := -1
+	for _, ref := range refs {
+		index, ok := page.mcidIndex[ref.mcid]
+		if !ok {
+			continue
+		}
+		if index < last {
+			v.report(RuleReadingOrder, location{page: page.num, mcid: ref.mcid, objNum: ref.elem.objNum},
+				"logical structure order differs from content order")
+			break
+		}
+		last = index
+	}
+
+	// Parent tree.
+	if len(page.mcids) == 0 {
+		return
+	}
+	key, ok := core.GetIntVal(page.dict.Get("StructParents"))
+	if !ok {
+		v.report(RuleParentTree, page.loc(), "page with marked content has no StructParents entry")
+		return
+	}
+	parents, ok := core.GetArray(tree.parentTree[key])
+	if !ok {
+		v.report(RuleParentTree, page.loc(), "parent tree entry %d of the page missing", key)
+		return
+	}
+	for _, mcid := range page.mcids {
+		elem, ok := referenced[mcid]
+		if !ok {
+			continue
+		}
+		var parent *core.PdfObjectDictionary
+		if mcid < parents.Len() {
+			parent, _ = core.GetDict(parents.Get(mcid))
+		}
+		if parent != elem.dict {
+			v.report(RuleParentTree, location{page: page.num, mcid: mcid, objNum: elem.objNum},
+				"parent tree entry does not match the structure element referencing the marked content")
+		}
+	}
+}
+
+// validateAnnotations checks that the annotations of the page are tagged and
+// have alternate descriptions.
+func (v *validator) validateAnnotations(page *pageInfo, tree *structTree) {
+	annots, ok := core.GetArray(page.dict.Get("Annots"))
+	if !ok || annots.Len() == 0 {
+		return
+	}
+	if tabs, _ := core.GetNameVal(page.dict.Get("Tabs")); tabs != "S" {
+		v.report(RuleAnnotation, page.loc(), "page with annotations does not have Tabs set to S")
+	}
+
+	const flagHidden = 1 << 1
+	for _, obj := range annots.Elements() {
+		annot, ok := core.GetDict(obj)
+		if !ok {
+			continue
+		}
+		subtype, _ := core.GetNameVal(annot.Get("Subtype"))
+		flags, _ := core.GetIntVal(annot.Get("F"))
+		if subtype == "Popup" || flags&flagHidden != 0 {
+			continue
+		}
+
+		loc := page.loc()
+		if num := objectNumber(obj); num > 0 {
+			loc.objNum = num
+		}
+		if subtype != "Widget" && !hasText(annot, "Contents") {
+			v.report(RuleAnnotation, loc, "%s annotation without alternate description (Contents)", subtype)
+		}
+		if tree == nil {
+			continue
+		}
+
+		elem, tagged := tree.annots[annot]
+		switch {
+		case !tagged:
+			v.report(RuleAnnotation, loc, "%s annotation not tagged", subtype)
+			continue
+		case subtype == "Widget" && elem.typ != "Form":
+			v.report(RuleAnnotation, loc, "Widget annotation not within a Form structure element")
+		case subtype == "Link" && elem.typ != "Link":
+			v.report(RuleAnnotation, loc, "Link annotation not within a Link structure element")
+		}
+
+		key, ok := core.GetIntVal(annot.Get("StructParent"))
+		if !ok {
+			v.report(RuleParentTree, loc, "tagged annotation has no StructParent entry")
+			continue
+		}
+		if parent, _ := core.GetDict(tree.parentTree[key]); parent != elem.dict {
+			v.report(RuleParentTree, loc, "parent tree entry does not match the structure element of the annotation")
+		}
+	}
+}
+
+// decodeStream returns the decoded data of the stream, or nil if it cannot
+// be decoded.
+func decodeStream(stream *core.PdfObjectStream) []byte {
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to decode stream: %v", err)
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,88 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package pdfua
+
+import (
+	"github.com/TheLinker/unipdf/v3/core"
+)
+
+// unicodeOrderings contains the character collections of the CID fonts
+// which can be mapped to Unicode without a ToUnicode CMap.
+var unicodeOrderings = map[string]struct{}{
+	"GB1": {}, "CNS1": {}, "Japan1": {}, "Korea1": {},
+}
+
+// validateFont checks that the font is embedded and that its character codes
+// can be mapped to Unicode.
+func (v *validator) validateFont(font *core.PdfObjectDictionary, name string, loc location) {
+	subtype, _ := core.GetNameVal(font.Get("Subtype"))
+	_, hasToUnicode := core.GetStream(font.Get("ToUnicode"))
+
+	switch subtype {
+	case "Type3":
+		// Type 3 glyphs are defined in the document.
+		if !hasToUnicode && font.Get("Encoding") == nil {
+			v.report(RuleFont, loc, "font %s has no Unicode mapping", name)
+		}
+		return
+	case "Type0":
+		var descendant *core.PdfObjectDictionary
+		if arr, ok := core.GetArray(font.Get("DescendantFonts")); ok && arr.Len() > 0 {
+			descendant, _ = core.GetDict(arr.Get(0))
+		}
+		if descendant == nil {
+			v.report(RuleFont, loc, "font %s has no descendant font", name)
+			return
+		}
+		if !isEmbedded(descendant) {
+			v.report(RuleFont, loc, "font %s is not embedded", name)
+		}
+		if hasToUnicode {
+			return
+		}
+		encoding, _ := core.GetNameVal(font.Get("Encoding"))
+		var registry, ordering string
+		if info, ok := core.GetDict(descendant.Get("CIDSystemInfo")); ok {
+			registry, _ = core.GetStringVal(info.Get("Registry"))
+			ordering, _ = core.GetStringVal(info.Get("Ordering"))
+		}
+		_, unicodeOrdering := unicodeOrderings[ordering]
+		if encoding == "Identity-H" || encoding == "Identity-V" || registry != "Adobe" || !unicodeOrdering {
+			v.report(RuleFont, loc, "font %s has no Unicode mapping (ToUnicode)", name)
+		}
+		return
+	}
+
+	// Simple fonts.
+	if !isEmbedded(font) {
+		v.report(RuleFont, loc, "font %s is not embedded", name)
+	}
+	if hasToUnicode {
+		return
+	}
+	const flagSymbolic = 1 << 2
+	var flags int
+	if descriptor, ok := core.GetDict(font.Get("FontDescriptor")); ok {
+		flags, _ = core.GetIntVal(descriptor.Get("Flags"))
+	}
+	if flags&flagSymbolic != 0 || font.Get("Encoding") == nil {
+		v.report(RuleFont, loc, "font %s has no Unicode mapping (ToUnicode)", name)
+	}
+}
+
+// isEmbedded returns true if the font program of the font is embedded.
+func isEmbedded(font *core.PdfObjectDictionary) bool {
+	descriptor, ok := core.GetDict(font.Get("FontDescriptor"))
+	if !ok {
+		return false
+	}
+	for _, key := range []core.PdfObjectName{"FontFile", "FontFile2", "FontFile3"} {
+		if _, ok := core.GetStream(descriptor.Get(key)); ok {
+			return true
+		}
+	}
+	return false
+}
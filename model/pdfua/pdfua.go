@@ -0,0 +1,238 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package pdfua implements a checker for the machine-checkable requirements
+// of the PDF/UA-1 accessibility standard (ISO 14289-1). The checker validates
+// the structure tree of tagged documents against their content and reports
+// the rule failures with the page and marked content references.
+//
+// Example:
+//
+//	violations, err := pdfua.ValidateReader(reader)
+//	if err != nil {
+//	    return err
+//	}
+//	for _, v := range violations {
+//	    fmt.Println(v)
+//	}
+package pdfua
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/TheLinker/unipdf/v3/common"
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/model"
+)
+
+// Rule identifies the PDF/UA requirement which is violated.
+type Rule string
+
+// PDF/UA rules checked by the validator.
+const (
+	RuleMarked        Rule = "marked"
+	RuleMetadata      Rule = "metadata"
+	RuleTitle         Rule = "title"
+	RuleLang          Rule = "lang"
+	RuleStructure     Rule = "structure"
+	RuleTaggedContent Rule = "tagged-content"
+	RuleParentTree    Rule = "parent-tree"
+	RuleReadingOrder  Rule = "reading-order"
+	RuleFigure        Rule = "figure"
+	RuleTable         Rule = "table"
+	RuleHeading       Rule = "heading"
+	RuleFont          Rule = "font"
+	RuleAnnotation    Rule = "annotation"
+)
+
+// NoMCID is the MCID of violations which do not refer to marked content.
+const NoMCID = -1
+
+// Violation describes a PDF/UA rule failure and its location.
+type Violation struct {
+	// Rule is the violated requirement.
+	Rule Rule
+
+	// Description describes the violation.
+	Description string
+
+	// Page is the number of the page containing the violation (starting
+	// from 1), or 0 for document level violations.
+	Page int
+
+	// MCID is the marked content identifier of the offending content on the
+	// page, or NoMCID if the violation does not refer to marked content.
+	MCID int
+
+	// ObjectNumber is the number of the closest indirect object containing
+	// the violation (e.g. a structure element), or 0 if not known.
+	ObjectNumber int64
+}
+
+// String returns a string representation of the violation.
+func (v Violation) String() string {
+	var loc []string
+	if v.Page > 0 {
+		loc = append(loc, fmt.Sprintf("page %d", v.Page))
+	}
+	if v.MCID != NoMCID {
+		loc = append(loc, fmt.Sprintf("MCID %d", v.MCID))
+	}
+	if v.ObjectNumber > 0 {
+		loc = append(loc, fmt.Sprintf("obj %d", v.ObjectNumber))
+	}
+	if len(loc) == 0 {
+		return fmt.Sprintf("[%s] %s", v.Rule, v.Description)
+	}
+	return fmt.Sprintf("[%s] %s (%s)", v.Rule, v.Description, strings.Join(loc, ", "))
+}
+
+// ValidateReader validates the document loaded by `reader` against PDF/UA-1
+// and returns the violations found.
+func ValidateReader(reader *model.PdfReader) ([]Violation, error) {
+	doc, err := reader.GetStandardDocument()
+	if err != nil {
+		return nil, err
+	}
+	return Validate(doc), nil
+}
+
+// Validate validates the document against PDF/UA-1 and returns the
+// violations found.
+func Validate(doc *model.StandardDocument) []Violation {
+	v := &validator{
+		pageNums: map[*core.PdfObjectDictionary]int{},
+		pages:    make([]*pageInfo, len(doc.Pages)),
+		fonts:    map[*core.PdfObjectDictionary]struct{}{},
+	}
+	for i, page := range doc.Pages {
+		dict, ok := core.GetDict(page)
+		if !ok {
+			continue
+		}
+		v.pageNums[dict] = i + 1
+		v.pages[i] = &pageInfo{
+			num:    i + 1,
+			dict:   dict,
+			objNum: page.ObjectNumber,
+		}
+	}
+
+	if doc.Catalog == nil {
+		v.report(RuleStructure, location{mcid: NoMCID}, "document catalog missing")
+		return v.violations
+	}
+	v.validateDocument(doc)
+	for _, page := range v.pages {
+		if page != nil {
+			v.analyzePage(page)
+		}
+	}
+	tree := v.loadStructTree(doc.Catalog)
+	if tree != nil {
+		v.validateStructTree(tree)
+	}
+	for _, page := range v.pages {
+		if page != nil {
+			v.validatePage(page, tree)
+		}
+	}
+	return v.violations
+}
+
+// location identifies the position of a violation.
+type location struct {
+	page   int
+	mcid   int
+	objNum int64
+}
+
+// validator checks the document and collects the violations.
+type validator struct {
+	pageNums   map[*core.PdfObjectDictionary]int
+	pages      []*pageInfo
+	fonts      map[*core.PdfObjectDictionary]struct{}
+	violations []Violation
+}
+
+// report adds a violation at the specified location.
+func (v *validator) report(rule Rule, loc location, format string, args ...interface{}) {
+	violation := Violation{
+		Rule:         rule,
+		Description:  fmt.Sprintf(format, args...),
+		Page:         loc.page,
+		MCID:         loc.mcid,
+		ObjectNumber: loc.objNum,
+	}
+	common.Log.Trace("PDF/UA violation: %s", violation)
+	v.violations = append(v.violations, violation)
+}
+
+var (
+	reDcTitle     = regexp.MustCompile(`<dc:title>\s*<rdf:Alt>\s*<rdf:li[^>]*>\s*[^<\s]`)
+	rePdfuaidPart = regexp.MustCompile(`pdfuaid:part(?:\s*=\s*["']|>)\s*1\s*["'<]`)
+)
+
+func (v *validator) validateDocument(doc *model.StandardDocument) {
+	catalog := doc.Catalog
+	root := location{mcid: NoMCID}
+
+	// Tagged PDF.
+	var marked, suspects bool
+	if markInfo, ok := core.GetDict(catalog.Get("MarkInfo")); ok {
+		marked, _ = core.GetBoolVal(markInfo.Get("Marked"))
+		suspects, _ = core.GetBoolVal(markInfo.Get("Suspects"))
+	}
+	if !marked {
+		v.report(RuleMarked, root, "MarkInfo Marked entry not set to true")
+	}
+	if suspects {
+		v.report(RuleMarked, root, "MarkInfo Suspects entry set to true")
+	}
+
+	// Natural language.
+	if lang, _ := core.GetStringVal(catalog.Get("Lang")); strings.TrimSpace(lang) == "" {
+		v.report(RuleLang, root, "document natural language (Lang) missing")
+	}
+
+	// Metadata and title.
+	var xmp []byte
+	if stream, ok := core.GetStream(catalog.Get("Metadata")); ok {
+		data, err := core.DecodeStream(stream)
+		if err != nil {
+			common.Log.Debug("ERROR: Unable to decode metadata: %v", err)
+		}
+		xmp = data
+	}
+	if xmp == nil {
+		v.report(RuleMetadata, root, "XMP metadata missing")
+	} else if !rePdfuaidPart.Match(xmp) {
+		v.report(RuleMetadata, root, "XMP metadata missing PDF/UA identification (pdfuaid:part 1)")
+	}
+	if !reDcTitle.Match(xmp) {
+		v.report(RuleTitle, root, "document title (dc:title) missing in XMP metadata")
+	}
+	var displayTitle bool
+	if prefs, ok := core.GetDict(catalog.Get("ViewerPreferences")); ok {
+		displayTitle, _ = core.GetBoolVal(prefs.Get("DisplayDocTitle"))
+	}
+	if !displayTitle {
+		v.report(RuleTitle, root, "ViewerPreferences DisplayDocTitle not set to true")
+	}
+}
+
+// objectNumber returns the object number of the indirect object `obj`, or 0
+// if `obj` is a direct object.
+func objectNumber(obj core.PdfObject) int64 {
+	switch t := core.ResolveReference(obj).(type) {
+	case *core.PdfIndirectObject:
+		return t.ObjectNumber
+	case *core.PdfObjectStream:
+		return t.ObjectNumber
+	}
+	return 0
+}
@@ -0,0 +1,181 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package pdfua_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/model"
+	"github.com/TheLinker/unipdf/v3/model/pdfua"
+)
+
+const xmpUA = `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:pdfuaid="http://www.aiim.org/pdfua/ns/id/">
+<dc:title><rdf:Alt><rdf:li xml:lang="x-default">Report</rdf:li></rdf:Alt></dc:title>
+<pdfuaid:part>1</pdfuaid:part>
+</rdf:Description></rdf:RDF></x:xmpmeta>`
+
+// testDocument is a tagged document being built for the tests.
+type testDocument struct {
+	doc      *model.StandardDocument
+	page     *core.PdfObjectDictionary
+	root     *core.PdfObjectDictionary
+	parents  *core.PdfObjectArray
+	document *core.PdfObjectDictionary
+}
+
+// newTestDocument returns a document having a single page with the specified
+// content and an empty structure tree.
+func newTestDocument(t *testing.T, content string) *testDocument {
+	contents, err := core.MakeStream([]byte(content), nil)
+	require.NoError(t, err)
+	page := core.MakeDict()
+	page.Set("Type", core.MakeName("Page"))
+	page.Set("MediaBox", core.MakeArrayFromIntegers([]int{0, 0, 612, 792}))
+	page.Set("Resources", core.MakeDict())
+	page.Set("Contents", contents)
+	page.Set("StructParents", core.MakeInteger(0))
+
+	metadata, err := core.MakeStream([]byte(xmpUA), nil)
+	require.NoError(t, err)
+	markInfo := core.MakeDict()
+	markInfo.Set("Marked", core.MakeBool(true))
+	prefs := model.NewViewerPreferences()
+	prefs.DisplayDocTitle = true
+
+	td := &testDocument{
+		page:     page,
+		root:     core.MakeDict(),
+		parents:  core.MakeArray(),
+		document: core.MakeDict(),
+	}
+	td.document.Set("S", core.MakeName("Document"))
+	td.document.Set("K", core.MakeArray())
+	td.root.Set("Type", core.MakeName("StructTreeRoot"))
+	td.root.Set("K", td.document)
+	parentTree := core.MakeDict()
+	parentTree.Set("Nums", core.MakeArray(core.MakeInteger(0), td.parents))
+	td.root.Set("ParentTree", parentTree)
+
+	catalog := core.MakeDict()
+	catalog.Set("Type", core.MakeName("Catalog"))
+	catalog.Set("MarkInfo", markInfo)
+	catalog.Set("Lang", core.MakeString("en-US"))
+	catalog.Set("Metadata", metadata)
+	catalog.Set("ViewerPreferences", prefs.ToPdfObject())
+	catalog.Set("StructTreeRoot", td.root)
+
+	td.doc = &model.StandardDocument{
+		Catalog: catalog,
+		Pages:   []*core.PdfIndirectObject{core.MakeIndirectObject(page)},
+	}
+	return td
+}
+
+// addElem adds a structure element of type `typ` to `parent`, referencing
+// the marked content `mcid` (if not negative) of the page.
+func (td *testDocument) addElem(parent *core.PdfObjectDictionary, typ string, mcid int) *core.PdfObjectDictionary {
+	elem := core.MakeDict()
+	elem.Set("Type", core.MakeName("StructElem"))
+	elem.Set("S", core.MakeName(typ))
+	elem.Set("P", parent)
+	elem.Set("Pg", td.page)
+	elem.Set("K", core.MakeArray())
+	if mcid >= 0 {
+		elem.Set("K", core.MakeInteger(int64(mcid)))
+		for td.parents.Len() <= mcid {
+			td.parents.Append(core.MakeNull())
+		}
+		td.parents.Set(mcid, elem)
+	}
+	kids, _ := core.GetArray(parent.Get("K"))
+	kids.Append(elem)
+	return elem
+}
+
+// violation is a simplified violation used for comparisons.
+type violation struct {
+	Rule pdfua.Rule
+	Page int
+	MCID int
+}
+
+func simplify(violations []pdfua.Violation) []violation {
+	var out []violation
+	for _, v := range violations {
+		out = append(out, violation{Rule: v.Rule, Page: v.Page, MCID: v.MCID})
+	}
+	return out
+}
+
+func TestValidateConforming(t *testing.T) {
+	td := newTestDocument(t, `/H1 <</MCID 0>> BDC 0 0 10 10 re f EMC
+/Artifact BMC 0 0 612 20 re f EMC
+/Figure <</MCID 1>> BDC 20 20 50 50 re f EMC`)
+	td.addElem(td.document, "H1", 0)
+	figure := td.addElem(td.document, "Figure", 1)
+	figure.Set("Alt", core.MakeString("Company logo"))
+
+	violations := pdfua.Validate(td.doc)
+	require.Empty(t, violations, "%v", violations)
+}
+
+func TestValidateViolations(t *testing.T) {
+	td := newTestDocument(t, `/P <</MCID 0>> BDC BT /F1 12 Tf (Hello) Tj ET EMC
+/Figure <</MCID 1>> BDC 20 20 50 50 re f EMC
+/Span <</MCID 2>> BDC 0 0 5 5 re f EMC
+10 10 5 5 re f`)
+	font := core.MakeDict()
+	font.Set("Type", core.MakeName("Font"))
+	font.Set("Subtype", core.MakeName("Type1"))
+	font.Set("BaseFont", core.MakeName("Helvetica"))
+	fonts := core.MakeDict()
+	fonts.Set("F1", font)
+	resources, _ := core.GetDict(td.page.Get("Resources"))
+	resources.Set("Font", fonts)
+	td.doc.Catalog.Remove("Lang")
+
+	// The figure precedes the heading in the logical order and references
+	// marked content missing from the page. The heading levels are skipped.
+	td.addElem(td.document, "Figure", 1)
+	td.addElem(td.document, "H2", 0)
+	td.addElem(td.document, "H4", 3)
+	table := td.addElem(td.document, "Table", -1)
+	row := td.addElem(table, "TR", -1)
+	td.addElem(row, "TD", -1)
+
+	// Unmapped custom type.
+	td.addElem(td.document, "Custom", -1)
+
+	link := core.MakeDict()
+	link.Set("Type", core.MakeName("Annot"))
+	link.Set("Subtype", core.MakeName("Link"))
+	link.Set("Rect", core.MakeArrayFromIntegers([]int{0, 0, 10, 10}))
+	td.page.Set("Annots", core.MakeArray(link))
+
+	violations := simplify(pdfua.Validate(td.doc))
+	expected := []violation{
+		{pdfua.RuleLang, 0, pdfua.NoMCID},
+		{pdfua.RuleStructure, 1, pdfua.NoMCID},
+		{pdfua.RuleFigure, 1, pdfua.NoMCID},
+		{pdfua.RuleHeading, 1, pdfua.NoMCID},
+		{pdfua.RuleHeading, 1, pdfua.NoMCID},
+		{pdfua.RuleTable, 1, pdfua.NoMCID},
+		{pdfua.RuleFont, 1, pdfua.NoMCID},
+		{pdfua.RuleFont, 1, pdfua.NoMCID},
+		{pdfua.RuleTaggedContent, 1, pdfua.NoMCID},
+		{pdfua.RuleAnnotation, 1, pdfua.NoMCID},
+		{pdfua.RuleAnnotation, 1, pdfua.NoMCID},
+		{pdfua.RuleAnnotation, 1, pdfua.NoMCID},
+		{pdfua.RuleStructure, 1, 3},
+		{pdfua.RuleTaggedContent, 1, 2},
+		{pdfua.RuleReadingOrder, 1, 0},
+	}
+	require.ElementsMatch(t, expected, violations, "%v", violations)
+}
@@ -0,0 +1,418 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package pdfua
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TheLinker/unipdf/v3/common"
+	"github.com/TheLinker/unipdf/v3/core"
+)
+
+// maxTreeDepth limits the depth of the traversed trees, guarding against
+// malformed documents.
+const maxTreeDepth = 256
+
+// standardTypes contains the standard structure types.
+// See section 14.8.4 "Standard Structure Types" (p. 615 PDF32000_2008).
+var standardTypes = map[string]struct{}{
+	"Document": {}, "Part": {}, "Art": {}, "Sect": {}, "Div": {}, "BlockQuote": {},
+	"Caption": {}, "TOC": {}, "TOCI": {}, "Index": {}, "NonStruct": {}, "Private": {},
+	"H": {}, "H1": {}, "H2": {}, "H3": {}, "H4": {}, "H5": {}, "H6": {}, "P": {},
+	"L": {}, "LI": {}, "Lbl": {}, "LBody": {},
+	"Table": {}, "TR": {}, "TH": {}, "TD": {}, "THead": {}, "TBody": {}, "TFoot": {},
+	"Span": {}, "Quote": {}, "Note": {}, "Reference": {}, "BibEntry": {}, "Code": {},
+	"Link": {}, "Annot": {}, "Ruby": {}, "RB": {}, "RT": {}, "RP": {},
+	"Warichu": {}, "WT": {}, "WP": {}, "Figure": {}, "Formula": {}, "Form": {},
+}
+
+// structElem represents a structure element of the structure tree.
+type structElem struct {
+	dict   *core.PdfObjectDictionary
+	objNum int64
+
+	// typ is the standard structure type of the element, after applying the
+	// role map.
+	typ string
+
+	page   int
+	parent *structElem
+	kids   []*structElem
+}
+
+// loc returns the location of the element.
+func (e *structElem) loc() location {
+	return location{page: e.page, mcid: NoMCID, objNum: e.objNum}
+}
+
+// mcidRef is a reference from a structure element to marked content.
+type mcidRef struct {
+	mcid int
+	elem *structElem
+}
+
+// structTree contains the structure tree of the document.
+type structTree struct {
+	// elems contains the structure elements in (depth-first) logical order.
+	elems []*structElem
+
+	// mcids contains the references to the marked content of each page, in
+	// logical order.
+	mcids map[int][]mcidRef
+
+	// annots maps the annotations to the structure elements referencing them.
+	annots map[*core.PdfObjectDictionary]*structElem
+
+	// parentTree contains the entries of the parent tree, keyed by
+	// StructParent(s) value.
+	parentTree map[int]core.PdfObject
+}
+
+// loadStructTree loads the structure tree of the document, reporting the
+// malformed elements.
+func (v *validator) loadStructTree(catalog *core.PdfObjectDictionary) *structTree {
+	root, ok := core.GetDict(catalog.Get("StructTreeRoot"))
+	if !ok {
+		v.report(RuleStructure, location{mcid: NoMCID}, "structure tree root (StructTreeRoot) missing")
+		return nil
+	}
+
+	l := &treeLoader{
+		v:          v,
+		roleMap:    map[string]string{},
+		visited:    map[*core.PdfObjectDictionary]struct{}{},
+		referenced: map[[2]int]struct{}{},
+		tree: &structTree{
+			mcids:  map[int][]mcidRef{},
+			annots: map[*core.PdfObjectDictionary]*structElem{},
+		},
+	}
+	if roleMap, ok := core.GetDict(root.Get("RoleMap")); ok {
+		for _, key := range roleMap.Keys() {
+			if name, ok := core.GetNameVal(roleMap.Get(key)); ok {
+				l.roleMap[string(key)] = name
+			}
+		}
+	}
+
+	parentTree, err := numberTreeEntries(root.Get("ParentTree"))
+	if err != nil {
+		v.report(RuleParentTree, location{mcid: NoMCID, objNum: objectNumber(root.Get("ParentTree"))},
+			"invalid parent tree: %v", err)
+	}
+	l.tree.parentTree = parentTree
+
+	forEachKid(root.Get("K"), func(kid core.PdfObject) {
+		l.loadElem(kid, nil, 0, 0)
+	})
+	if len(l.tree.elems) == 0 {
+		v.report(RuleStructure, location{mcid: NoMCID, objNum: objectNumber(catalog.Get("StructTreeRoot"))},
+			"structure tree is empty")
+	}
+	return l.tree
+}
+
+// treeLoader loads the structure elements.
+type treeLoader struct {
+	v       *validator
+	roleMap map[string]string
+	visited map[*core.PdfObjectDictionary]struct{}
+	tree    *structTree
+
+	// referenced contains the page numbers and MCIDs of the referenced
+	// marked content.
+	referenced map[[2]int]struct{}
+}
+
+// standardType maps the structure type `typ` to a standard type through the
+// role map. The returned flag is false if the type cannot be mapped.
+func (l *treeLoader) standardType(typ string) (string, bool) {
+	for i := 0; i < len(l.roleMap)+1; i++ {
+		if _, ok := standardTypes[typ]; ok {
+			return typ, true
+		}
+		mapped, ok := l.roleMap[typ]
+		if !ok {
+			return typ, false
+		}
+		typ = mapped
+	}
+	return typ, false
+}
+
+func (l *treeLoader) loadElem(obj core.PdfObject, parent *structElem, page, depth int) {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return
+	}
+	if _, ok := l.visited[dict]; ok || depth > maxTreeDepth {
+		common.Log.Debug("ERROR: structure tree loop or too deep")
+		return
+	}
+	l.visited[dict] = struct{}{}
+
+	elem := &structElem{
+		dict:   dict,
+		objNum: objectNumber(obj),
+		page:   page,
+		parent: parent,
+	}
+	if parent != nil && elem.objNum == 0 {
+		elem.objNum = parent.objNum
+	}
+	if pg, ok := core.GetDict(dict.Get("Pg")); ok {
+		elem.page = l.v.pageNums[pg]
+	}
+
+	rawType, _ := core.GetNameVal(dict.Get("S"))
+	typ, ok := l.standardType(rawType)
+	if !ok {
+		l.v.report(RuleStructure, elem.loc(), "structure type %q is not standard and not role mapped", rawType)
+	}
+	elem.typ = typ
+
+	if parent != nil {
+		parent.kids = append(parent.kids, elem)
+	}
+	l.tree.elems = append(l.tree.elems, elem)
+
+	forEachKid(dict.Get("K"), func(kid core.PdfObject) {
+		l.loadKid(kid, elem, depth)
+	})
+}
+
+// loadKid loads the kid `obj` of the structure element `elem`, which is
+// either a marked content identifier, a marked content reference, an object
+// reference or a structure element.
+func (l *treeLoader) loadKid(obj core.PdfObject, elem *structElem, depth int) {
+	if mcid, ok := core.GetIntVal(obj); ok {
+		l.addMCID(elem, elem.page, mcid)
+		return
+	}
+	kid, ok := core.GetDict(obj)
+	if !ok {
+		return
+	}
+
+	switch typ, _ := core.GetNameVal(kid.Get("Type")); typ {
+	case "MCR":
+		if kid.Get("Stm") != nil {
+			// Marked content of form XObjects is not tracked.
+			return
+		}
+		page := elem.page
+		if pg, ok := core.GetDict(kid.Get("Pg")); ok {
+			page = l.v.pageNums[pg]
+		}
+		mcid, ok := core.GetIntVal(kid.Get("MCID"))
+		if !ok {
+			l.v.report(RuleStructure, elem.loc(), "marked content reference without MCID")
+			return
+		}
+		l.addMCID(elem, page, mcid)
+	case "OBJR":
+		annot, ok := core.GetDict(kid.Get("Obj"))
+		if !ok {
+			l.v.report(RuleStructure, elem.loc(), "object reference without object")
+			return
+		}
+		if _, isAnnot := core.GetName(annot.Get("Subtype")); isAnnot && annot.Get("Rect") != nil {
+			l.tree.annots[annot] = elem
+		}
+	default:
+		l.loadElem(obj, elem, elem.page, depth+1)
+	}
+}
+
+// addMCID records the reference from the element to the marked content
+// having the specified MCID on the specified page.
+func (l *treeLoader) addMCID(elem *structElem, page, mcid int) {
+	loc := location{page: page, mcid: mcid, objNum: elem.objNum}
+	if page == 0 {
+		l.v.report(RuleStructure, loc, "marked content reference without page")
+		return
+	}
+	key := [2]int{page, mcid}
+	if _, ok := l.referenced[key]; ok {
+		l.v.report(RuleStructure, loc, "marked content referenced by multiple structure elements")
+		return
+	}
+	l.referenced[key] = struct{}{}
+	l.tree.mcids[page] = append(l.tree.mcids[page], mcidRef{mcid: mcid, elem: elem})
+}
+
+// forEachKid calls `fn` for the kid `obj`, or for each kid if `obj` is an
+// array.
+func forEachKid(obj core.PdfObject, fn func(kid core.PdfObject)) {
+	if obj == nil {
+		return
+	}
+	if arr, ok := core.GetArray(obj); ok {
+		for _, kid := range arr.Elements() {
+			fn(kid)
+		}
+		return
+	}
+	fn(obj)
+}
+
+// numberTreeEntries returns the entries of the number tree having the
+// specified root node, keyed by number.
+// See section 7.9.7 "Number Trees" (p. 91 PDF32000_2008).
+func numberTreeEntries(root core.PdfObject) (map[int]core.PdfObject, error) {
+	entries := map[int]core.PdfObject{}
+	visited := map[*core.PdfObjectDictionary]struct{}{}
+
+	var traverse func(obj core.PdfObject, depth int) error
+	traverse = func(obj core.PdfObject, depth int) error {
+		node, ok := core.GetDict(obj)
+		if !ok {
+			return nil
+		}
+		if _, ok := visited[node]; ok {
+			return fmt.Errorf("number tree loop")
+		}
+		if depth > maxTreeDepth {
+			return fmt.Errorf("number tree too deep")
+		}
+		visited[node] = struct{}{}
+
+		if nums, ok := core.GetArray(node.Get("Nums")); ok {
+			elems := nums.Elements()
+			for i := 0; i+1 < len(elems); i += 2 {
+				key, ok := core.GetIntVal(elems[i])
+				if !ok {
+					return fmt.Errorf("invalid number tree key %v", elems[i])
+				}
+				entries[key] = elems[i+1]
+			}
+		}
+		if kids, ok := core.GetArray(node.Get("Kids")); ok {
+			for _, kid := range kids.Elements() {
+				if err := traverse(kid, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := traverse(root, 0); err != nil {
+		return entries, err
+	}
+	return entries, nil
+}
+
+// validateStructTree checks the structure elements.
+func (v *validator) validateStructTree(tree *structTree) {
+	headingLevel := -1
+	var numbered, unnumbered bool
+	for _, elem := range tree.elems {
+		switch elem.typ {
+		case "Figure", "Formula":
+			if !hasText(elem.dict, "Alt") && !hasText(elem.dict, "ActualText") {
+				v.report(RuleFigure, elem.loc(), "%s structure element without alternate description (Alt)", elem.typ)
+			}
+		case "Table":
+			v.validateTable(elem)
+		case "TR":
+			if p := elem.parent; p == nil || !oneOf(p.typ, "Table", "THead", "TBody", "TFoot") {
+				v.report(RuleTable, elem.loc(), "TR structure element not within a table")
+			}
+		case "TH", "TD":
+			if p := elem.parent; p == nil || p.typ != "TR" {
+				v.report(RuleTable, elem.loc(), "%s structure element not within a table row (TR)", elem.typ)
+			}
+		case "H":
+			unnumbered = true
+		case "H1", "H2", "H3", "H4", "H5", "H6":
+			numbered = true
+			level := int(elem.typ[1] - '0')
+			switch {
+			case headingLevel < 0 && level != 1:
+				v.report(RuleHeading, elem.loc(), "first numbered heading is %s, expected H1", elem.typ)
+			case headingLevel > 0 && level > headingLevel+1:
+				v.report(RuleHeading, elem.loc(), "heading level skipped (H%d followed by %s)", headingLevel, elem.typ)
+			}
+			headingLevel = level
+		}
+	}
+	if numbered && unnumbered {
+		v.report(RuleHeading, location{mcid: NoMCID}, "numbered (Hn) and unnumbered (H) headings are mixed")
+	}
+}
+
+// validateTable checks that the table has header cells, and that the header
+// cells are associated with the data cells.
+func (v *validator) validateTable(table *structElem) {
+	var headers []*structElem
+	var hasHeadersAttr bool
+
+	var walk func(elem *structElem)
+	walk = func(elem *structElem) {
+		for _, kid := range elem.kids {
+			switch kid.typ {
+			case "Table":
+				// Nested tables are checked separately.
+				continue
+			case "TH":
+				headers = append(headers, kid)
+			case "TD":
+				if hasAttribute(kid.dict, "Table", "Headers") {
+					hasHeadersAttr = true
+				}
+			}
+			walk(kid)
+		}
+	}
+	walk(table)
+
+	if len(headers) == 0 {
+		v.report(RuleTable, table.loc(), "table without header cells (TH)")
+		return
+	}
+	if hasHeadersAttr {
+		return
+	}
+	for _, th := range headers {
+		if !hasAttribute(th.dict, "Table", "Scope") {
+			v.report(RuleTable, th.loc(), "table header cell without Scope attribute")
+		}
+	}
+}
+
+// hasText returns true if the entry `key` of `dict` is a non-empty string.
+func hasText(dict *core.PdfObjectDictionary, key core.PdfObjectName) bool {
+	str, ok := core.GetString(dict.Get(key))
+	return ok && strings.TrimSpace(str.Decoded()) != ""
+}
+
+// hasAttribute returns true if the structure element has the attribute
+// `key` of the attribute owner `owner`.
+func hasAttribute(dict *core.PdfObjectDictionary, owner, key core.PdfObjectName) bool {
+	found := false
+	forEachKid(dict.Get("A"), func(obj core.PdfObject) {
+		attrs, ok := core.GetDict(obj)
+		if !ok {
+			// Revision numbers.
+			return
+		}
+		if o, _ := core.GetNameVal(attrs.Get("O")); o == string(owner) && attrs.Get(key) != nil {
+			found = true
+		}
+	})
+	return found
+}
+
+// oneOf returns true if `s` is one of `values`.
+func oneOf(s string, values ...string) bool {
+	for _, val := range values {
+		if s == val {
+			return true
+		}
+	}
+	return false
+}
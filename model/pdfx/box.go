@@ -0,0 +1,78 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package pdfx
+
+import (
+	"math"
+
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/internal/pageutil"
+)
+
+// boxTolerance is the tolerance used when comparing page box coordinates.
+const boxTolerance = 0.01
+
+// box represents a page boundary rectangle.
+type box struct {
+	Llx, Lly, Urx, Ury float64
+}
+
+// pageBox returns the normalized page box with the specified name. The
+// MediaBox and CropBox entries are inherited from the parent page tree nodes.
+func pageBox(dict *core.PdfObjectDictionary, name core.PdfObjectName) (box, bool) {
+	obj := dict.Get(name)
+	if name == "MediaBox" || name == "CropBox" {
+		obj = pageutil.InheritedAttribute(dict, name)
+	}
+
+	arr, ok := core.GetArray(obj)
+	if !ok || arr.Len() != 4 {
+		return box{}, false
+	}
+	coords, err := arr.ToFloat64Array()
+	if err != nil {
+		return box{}, false
+	}
+	return box{
+		Llx: math.Min(coords[0], coords[2]),
+		Lly: math.Min(coords[1], coords[3]),
+		Urx: math.Max(coords[0], coords[2]),
+		Ury: math.Max(coords[1], coords[3]),
+	}, true
+}
+
+// contains returns true if the box `b` contains the box `o`.
+func (b box) contains(o box) bool {
+	return o.Llx >= b.Llx-boxTolerance && o.Lly >= b.Lly-boxTolerance &&
+		o.Urx <= b.Urx+boxTolerance && o.Ury <= b.Ury+boxTolerance
+}
+
+// overlaps returns true if the boxes `b` and `o` have a common area.
+func (b box) overlaps(o box) bool {
+	return o.Llx < b.Urx && o.Urx > b.Llx && o.Lly < b.Ury && o.Ury > b.Lly
+}
+
+// intersect returns the intersection of the boxes `b` and `o`.
+func (b box) intersect(o box) box {
+	r := box{
+		Llx: math.Max(b.Llx, o.Llx),
+		Lly: math.Max(b.Lly, o.Lly),
+		Urx: math.Min(b.Urx, o.Urx),
+		Ury: math.Min(b.Ury, o.Ury),
+	}
+	if r.Urx < r.Llx {
+		r.Urx = r.Llx
+	}
+	if r.Ury < r.Lly {
+		r.Ury = r.Lly
+	}
+	return r
+}
+
+// toPdfObject returns the box as a rectangle array.
+func (b box) toPdfObject() core.PdfObject {
+	return core.MakeArrayFromFloats([]float64{b.Llx, b.Lly, b.Urx, b.Ury})
+}
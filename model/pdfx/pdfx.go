@@ -0,0 +1,349 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package pdfx implements the PDF/X-1a and PDF/X-4 graphic exchange standards
+// (ISO 15930). It provides a standard applier which can be set on the
+// model.PdfWriter in order to produce PDF/X output, and a validator which
+// reports the conformance violations of existing documents.
+//
+// Example:
+//
+//	w := model.NewPdfWriter()
+//	w.SetStandard(pdfx.New(pdfx.Options{
+//	    Version: pdfx.VersionX1a2003,
+//	    OutputIntent: pdfx.OutputIntent{
+//	        OutputConditionIdentifier: "FOGRA39",
+//	        RegistryName:              "http://www.color.org",
+//	        Profile:                   iccData,
+//	    },
+//	    Title: "Brochure",
+//	}))
+package pdfx
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/model"
+)
+
+// Version represents a PDF/X conformance level.
+type Version int
+
+const (
+	// VersionX1a2001 is PDF/X-1a:2001 (ISO 15930-1), based on PDF 1.3.
+	VersionX1a2001 Version = iota
+	// VersionX1a2003 is PDF/X-1a:2003 (ISO 15930-4), based on PDF 1.4.
+	VersionX1a2003
+	// VersionX4 is PDF/X-4 (ISO 15930-7), based on PDF 1.6.
+	VersionX4
+)
+
+// String returns the name of the PDF/X version. Implements fmt.Stringer.
+func (v Version) String() string {
+	switch v {
+	case VersionX1a2001:
+		return "PDF/X-1a:2001"
+	case VersionX1a2003:
+		return "PDF/X-1a:2003"
+	case VersionX4:
+		return "PDF/X-4"
+	}
+	return fmt.Sprintf("unknown PDF/X version (%d)", int(v))
+}
+
+// infoVersion returns the value of the GTS_PDFXVersion document information entry.
+func (v Version) infoVersion() string {
+	if v == VersionX1a2001 {
+		return "PDF/X-1:2001"
+	}
+	return v.String()
+}
+
+// pdfVersion returns the highest PDF version allowed by the PDF/X version.
+func (v Version) pdfVersion() core.Version {
+	switch v {
+	case VersionX1a2001:
+		return core.Version{Major: 1, Minor: 3}
+	case VersionX1a2003:
+		return core.Version{Major: 1, Minor: 4}
+	}
+	return core.Version{Major: 1, Minor: 6}
+}
+
+// isX1a returns true if the version is one of the PDF/X-1a versions.
+func (v Version) isX1a() bool {
+	return v == VersionX1a2001 || v == VersionX1a2003
+}
+
+// OutputIntent describes the intended printing condition of a PDF/X document.
+type OutputIntent struct {
+	// OutputConditionIdentifier identifies the printing condition, preferably
+	// using a name from the registry specified by RegistryName (e.g. FOGRA39).
+	OutputConditionIdentifier string
+
+	// OutputCondition is a human readable description of the printing condition.
+	OutputCondition string
+
+	// RegistryName is the registry in which the condition is defined
+	// (e.g. http://www.color.org).
+	RegistryName string
+
+	// Info contains additional information about the printing condition.
+	Info string
+
+	// Profile is the ICC output profile of the printing condition. Required
+	// for PDF/X-4 and for unregistered printing conditions.
+	Profile []byte
+
+	// ProfileComponents is the number of color components of the profile.
+	// Defaults to 4 (CMYK).
+	ProfileComponents int
+}
+
+// Options define the PDF/X output settings.
+type Options struct {
+	// Version is the PDF/X conformance level of the output.
+	Version Version
+
+	// OutputIntent is the intended printing condition.
+	OutputIntent OutputIntent
+
+	// Title is set as the document title. Required by PDF/X if the document
+	// does not have a title.
+	Title string
+
+	// Trapped specifies whether the document has been trapped.
+	Trapped bool
+
+	// Bleed is the width of the bleed area (in points) added around the trim
+	// box of the pages which do not have a BleedBox. The bleed box is limited
+	// to the media box of the page.
+	Bleed float64
+
+	// Now returns the time used for the document dates.
+	// If not set, time.Now is used.
+	Now func() time.Time
+}
+
+// Standard converts documents to PDF/X when writing.
+// Implements the model.StandardApplier interface.
+type Standard struct {
+	opts Options
+}
+
+// New returns a new PDF/X standard applier with the specified options.
+func New(opts Options) *Standard {
+	return &Standard{opts: opts}
+}
+
+// ConformanceError is returned when the written document does not conform to
+// the PDF/X version, e.g. because the document contains RGB content or
+// transparency which cannot be converted automatically.
+type ConformanceError struct {
+	Version    Version
+	Violations []Violation
+}
+
+// Error implements the error interface.
+func (e *ConformanceError) Error() string {
+	lines := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		lines[i] = v.String()
+	}
+	return fmt.Sprintf("document does not conform to %s (%d violations):\n%s",
+		e.Version, len(e.Violations), strings.Join(lines, "\n"))
+}
+
+// ApplyStandard converts the document to the PDF/X version specified in the
+// options. The output intent, the required document information entries,
+// the file identifier and the page boxes are added and the document is then
+// validated. A ConformanceError is returned if violations remain.
+//
+// The fonts are not embedded by the conversion: the documents must be created
+// with embedded fonts, e.g. loaded with model.NewPdfFontFromTTFFile. A
+// ConformanceError reporting the fonts which are not embedded is returned
+// before the document is modified.
+// Implements the model.StandardApplier interface.
+func (s *Standard) ApplyStandard(doc *model.StandardDocument) error {
+	version := s.opts.Version
+	if doc.Encrypted {
+		return errors.New("encryption is not allowed in PDF/X documents")
+	}
+	if doc.Catalog == nil || doc.Info == nil {
+		return errors.New("document catalog and information dictionary required")
+	}
+	if violations := validateRule(doc, version, RuleFontEmbedding); len(violations) > 0 {
+		return &ConformanceError{Version: version, Violations: violations}
+	}
+
+	// PDF version.
+	maxVersion := version.pdfVersion()
+	if version == VersionX1a2001 || compareVersions(doc.Version, maxVersion) > 0 {
+		doc.Version = maxVersion
+	}
+
+	// Output intent.
+	intent, err := s.outputIntent()
+	if err != nil {
+		return err
+	}
+	doc.Catalog.Set("OutputIntents", core.MakeArray(intent.ToPdfObject()))
+
+	// Document information.
+	now := time.Now
+	if s.opts.Now != nil {
+		now = s.opts.Now
+	}
+	date, err := model.NewPdfDateFromTime(now())
+	if err != nil {
+		return err
+	}
+	doc.Info.Set("GTS_PDFXVersion", core.MakeString(version.infoVersion()))
+	if version == VersionX1a2001 {
+		doc.Info.Set("GTS_PDFXConformance", core.MakeString(version.String()))
+	}
+	if s.opts.Title != "" {
+		doc.Info.Set("Title", core.MakeString(s.opts.Title))
+	}
+	trapped := "False"
+	if s.opts.Trapped {
+		trapped = "True"
+	}
+	doc.Info.Set("Trapped", core.MakeName(trapped))
+	if doc.Info.Get("CreationDate") == nil {
+		doc.Info.Set("CreationDate", date.ToPdfObject())
+	}
+	doc.Info.Set("ModDate", date.ToPdfObject())
+
+	// File identifier.
+	if doc.ID == nil {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return err
+		}
+		doc.ID = core.MakeArray(core.MakeHexString(string(id)), core.MakeHexString(string(id)))
+	}
+
+	// Page boxes.
+	for _, page := range doc.Pages {
+		if err := s.applyPageBoxes(page); err != nil {
+			return err
+		}
+	}
+
+	// XMP metadata.
+	if version == VersionX4 {
+		metadata, err := doc.NewMetadataStream(now(),
+			model.XMPProperty{Prefix: "xmpMM", Name: "RenditionClass", Value: "default"},
+			model.XMPProperty{Prefix: "xmpMM", Name: "VersionID", Value: "1"},
+			model.XMPProperty{
+				Prefix:    "pdfxid",
+				Namespace: "http://www.npes.org/pdfx/ns/id/",
+				Name:      "GTS_PDFXVersion",
+				Value:     version.infoVersion(),
+			})
+		if err != nil {
+			return err
+		}
+		doc.Catalog.Set("Metadata", metadata)
+	}
+
+	if violations := Validate(doc, version); len(violations) > 0 {
+		return &ConformanceError{Version: version, Violations: violations}
+	}
+	return nil
+}
+
+// outputIntent creates the GTS_PDFX output intent from the options.
+func (s *Standard) outputIntent() (*model.PdfOutputIntent, error) {
+	opts := s.opts.OutputIntent
+	if opts.OutputConditionIdentifier == "" {
+		return nil, errors.New("output condition identifier required")
+	}
+	if len(opts.Profile) == 0 && opts.RegistryName == "" {
+		return nil, errors.New("ICC profile required for unregistered output conditions")
+	}
+
+	intent := model.NewPdfOutputIntent("GTS_PDFX")
+	intent.OutputConditionIdentifier = core.MakeString(opts.OutputConditionIdentifier)
+	if opts.OutputCondition != "" {
+		intent.OutputCondition = core.MakeString(opts.OutputCondition)
+	}
+	if opts.RegistryName != "" {
+		intent.RegistryName = core.MakeString(opts.RegistryName)
+	}
+	if opts.Info != "" {
+		intent.Info = core.MakeString(opts.Info)
+	}
+	if len(opts.Profile) > 0 {
+		n := opts.ProfileComponents
+		if n == 0 {
+			n = 4
+		}
+		if err := intent.SetDestOutputProfile(opts.Profile, n); err != nil {
+			return nil, err
+		}
+	}
+	return intent, nil
+}
+
+// applyPageBoxes adds the TrimBox and BleedBox entries to the page if missing.
+func (s *Standard) applyPageBoxes(page *core.PdfIndirectObject) error {
+	dict, ok := core.GetDict(page)
+	if !ok {
+		return errors.New("invalid page object (not a dict)")
+	}
+
+	mediaBox, ok := pageBox(dict, "MediaBox")
+	if !ok {
+		return errors.New("page missing MediaBox")
+	}
+	trimBox, hasTrim := pageBox(dict, "TrimBox")
+	if !hasTrim {
+		if artBox, hasArt := pageBox(dict, "ArtBox"); hasArt {
+			trimBox = artBox
+		} else {
+			trimBox = mediaBox
+			if cropBox, ok := pageBox(dict, "CropBox"); ok {
+				trimBox = cropBox.intersect(mediaBox)
+			}
+			dict.Set("TrimBox", trimBox.toPdfObject())
+		}
+	}
+	if _, hasBleed := pageBox(dict, "BleedBox"); !hasBleed {
+		bleedBox := trimBox
+		if s.opts.Bleed > 0 {
+			bleedBox = box{
+				Llx: trimBox.Llx - s.opts.Bleed,
+				Lly: trimBox.Lly - s.opts.Bleed,
+				Urx: trimBox.Urx + s.opts.Bleed,
+				Ury: trimBox.Ury + s.opts.Bleed,
+			}.intersect(mediaBox)
+		}
+		dict.Set("BleedBox", bleedBox.toPdfObject())
+	}
+	return nil
+}
+
+// compareVersions compares the PDF versions `a` and `b`, returning -1, 0 or 1.
+func compareVersions(a, b core.Version) int {
+	switch {
+	case a.Major != b.Major:
+		if a.Major < b.Major {
+			return -1
+		}
+		return 1
+	case a.Minor < b.Minor:
+		return -1
+	case a.Minor > b.Minor:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,232 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package pdfx_test
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/model"
+	"github.com/TheLinker/unipdf/v3/model/pdfx"
+)
+
+// writePdfX writes a single page document with the specified content using
+// the PDF/X standard applier.
+func writePdfX(t *testing.T, opts pdfx.Options, content string) ([]byte, error) {
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Llx: 0, Lly: 0, Urx: 612, Ury: 792}
+	page.TrimBox = &model.PdfRectangle{Llx: 18, Lly: 18, Urx: 594, Ury: 774}
+	require.NoError(t, page.AddContentStreamByString(content))
+
+	w := model.NewPdfWriter()
+	require.NoError(t, w.AddPage(page))
+	w.SetStandard(pdfx.New(opts))
+
+	var buf bytes.Buffer
+	err := w.Write(&buf)
+	return buf.Bytes(), err
+}
+
+func TestPdfXWrite(t *testing.T) {
+	testcases := []struct {
+		Name    string
+		Opts    pdfx.Options
+		Content string
+		Rules   []pdfx.Rule
+	}{
+		{
+			Name: "X-1a CMYK",
+			Opts: pdfx.Options{
+				Version: pdfx.VersionX1a2001,
+				OutputIntent: pdfx.OutputIntent{
+					OutputConditionIdentifier: "FOGRA39",
+					RegistryName:              "http://www.color.org",
+				},
+				Title: "PDF/X-1a",
+				Bleed: 9,
+			},
+			Content: "0 1 0 0 k 10 10 100 100 re f",
+		},
+		{
+			Name: "X-1a RGB",
+			Opts: pdfx.Options{
+				Version: pdfx.VersionX1a2003,
+				OutputIntent: pdfx.OutputIntent{
+					OutputConditionIdentifier: "FOGRA39",
+					RegistryName:              "http://www.color.org",
+				},
+				Title: "PDF/X-1a",
+			},
+			Content: "1 0 0 rg 10 10 100 100 re f",
+			Rules:   []pdfx.Rule{pdfx.RuleColorSpace},
+		},
+		{
+			Name: "X-4 RGB without default color space",
+			Opts: pdfx.Options{
+				Version: pdfx.VersionX4,
+				OutputIntent: pdfx.OutputIntent{
+					OutputConditionIdentifier: "Custom",
+					Profile:                   []byte("icc profile data"),
+				},
+				Title: "PDF/X-4",
+			},
+			Content: "/DeviceRGB cs 1 0 0 sc 10 10 100 100 re f",
+			Rules:   []pdfx.Rule{pdfx.RuleColorSpace},
+		},
+		{
+			Name: "X-4 gray",
+			Opts: pdfx.Options{
+				Version: pdfx.VersionX4,
+				OutputIntent: pdfx.OutputIntent{
+					OutputConditionIdentifier: "Custom",
+					Profile:                   []byte("icc profile data"),
+				},
+				Title: "PDF/X-4",
+			},
+			Content: "0.5 g 10 10 100 100 re f",
+		},
+		{
+			Name: "X-4 missing title",
+			Opts: pdfx.Options{
+				Version: pdfx.VersionX4,
+				OutputIntent: pdfx.OutputIntent{
+					OutputConditionIdentifier: "Custom",
+					Profile:                   []byte("icc profile data"),
+				},
+			},
+			Content: "0 g 10 10 100 100 re f",
+			Rules:   []pdfx.Rule{pdfx.RuleInfo},
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.Name, func(t *testing.T) {
+			data, err := writePdfX(t, tcase.Opts, tcase.Content)
+			if len(tcase.Rules) > 0 {
+				require.Error(t, err)
+				cerr, ok := err.(*pdfx.ConformanceError)
+				require.True(t, ok)
+				var rules []pdfx.Rule
+				for _, v := range cerr.Violations {
+					rules = append(rules, v.Rule)
+					if v.Rule == pdfx.RuleColorSpace {
+						require.Equal(t, 1, v.Page)
+					}
+				}
+				require.Equal(t, tcase.Rules, rules)
+				return
+			}
+			require.NoError(t, err)
+
+			reader, err := model.NewPdfReader(bytes.NewReader(data))
+			require.NoError(t, err)
+			violations, err := pdfx.ValidateReader(reader, tcase.Opts.Version)
+			require.NoError(t, err)
+			require.Empty(t, violations)
+
+			intents, err := reader.GetOutputIntents()
+			require.NoError(t, err)
+			require.Len(t, intents, 1)
+			require.Equal(t, "GTS_PDFX", intents[0].S.String())
+
+			page, err := reader.GetPage(1)
+			require.NoError(t, err)
+			require.NotNil(t, page.TrimBox)
+			require.NotNil(t, page.BleedBox)
+			require.Equal(t, tcase.Opts.Bleed, page.TrimBox.Llx-page.BleedBox.Llx)
+
+			if tcase.Opts.Version == pdfx.VersionX4 {
+				// The document and instance IDs of the metadata are UUIDs.
+				doc, err := reader.GetStandardDocument()
+				require.NoError(t, err)
+				metadata, ok := core.GetStream(doc.Catalog.Get("Metadata"))
+				require.True(t, ok)
+				xmp, err := core.DecodeStream(metadata)
+				require.NoError(t, err)
+				uuid := `uuid:[0-9a-f]{8}-[0-9a-f]{4}-3[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}`
+				for _, key := range []string{"DocumentID", "InstanceID"} {
+					re := regexp.MustCompile("<xmpMM:" + key + ">" + uuid + "</xmpMM:" + key + ">")
+					require.Regexp(t, re, string(xmp))
+				}
+			}
+		})
+	}
+}
+
+// TestPdfXFontNotEmbedded checks that the documents using fonts which are not embedded are not
+// converted.
+func TestPdfXFontNotEmbedded(t *testing.T) {
+	font, err := model.NewStandard14Font(model.HelveticaName)
+	require.NoError(t, err)
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Llx: 0, Lly: 0, Urx: 612, Ury: 792}
+	page.Resources = model.NewPdfPageResources()
+	require.NoError(t, page.Resources.SetFontByName("F1", font.ToPdfObject()))
+	require.NoError(t, page.AddContentStreamByString("BT /F1 12 Tf 0 g 100 100 Td (Text) Tj ET"))
+
+	w := model.NewPdfWriter()
+	require.NoError(t, w.AddPage(page))
+	w.SetStandard(pdfx.New(pdfx.Options{
+		Version: pdfx.VersionX1a2003,
+		OutputIntent: pdfx.OutputIntent{
+			OutputConditionIdentifier: "FOGRA39",
+			RegistryName:              "http://www.color.org",
+		},
+		Title: "PDF/X-1a",
+	}))
+	var buf bytes.Buffer
+	err = w.Write(&buf)
+	require.Error(t, err)
+	cerr, ok := err.(*pdfx.ConformanceError)
+	require.True(t, ok)
+	require.Len(t, cerr.Violations, 1)
+	require.Equal(t, pdfx.RuleFontEmbedding, cerr.Violations[0].Rule)
+	require.Equal(t, 1, cerr.Violations[0].Page)
+	require.Equal(t, "/Resources/Font/F1", cerr.Violations[0].Path)
+}
+
+func TestPdfXValidateReader(t *testing.T) {
+	// Plain document without any of the PDF/X requirements.
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Llx: 0, Lly: 0, Urx: 612, Ury: 792}
+	require.NoError(t, page.AddContentStreamByString("1 0 0 rg 0 0 10 10 re f"))
+
+	gs := core.MakeDict()
+	gs.Set("ca", core.MakeFloat(0.5))
+	page.Resources = model.NewPdfPageResources()
+	require.NoError(t, page.AddExtGState("GS0", gs))
+
+	w := model.NewPdfWriter()
+	require.NoError(t, w.AddPage(page))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	violations, err := pdfx.ValidateReader(reader, pdfx.VersionX1a2003)
+	require.NoError(t, err)
+
+	found := map[pdfx.Rule]pdfx.Violation{}
+	for _, v := range violations {
+		found[v.Rule] = v
+	}
+	for _, rule := range []pdfx.Rule{
+		pdfx.RuleFileID, pdfx.RuleInfo, pdfx.RuleOutputIntent,
+		pdfx.RulePageBoxes, pdfx.RuleColorSpace, pdfx.RuleTransparency,
+	} {
+		require.Contains(t, found, rule)
+	}
+
+	// Page level violations are located by page and object number.
+	transparency := found[pdfx.RuleTransparency]
+	require.Equal(t, 1, transparency.Page)
+	require.NotZero(t, transparency.ObjectNumber)
+	require.Equal(t, "/Resources/ExtGState/GS0", transparency.Path)
+}
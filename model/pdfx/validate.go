@@ -0,0 +1,820 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package pdfx
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/TheLinker/unipdf/v3/common"
+	"github.com/TheLinker/unipdf/v3/contentstream"
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/internal/pageutil"
+	"github.com/TheLinker/unipdf/v3/model"
+)
+
+// Rule identifies the PDF/X requirement which is violated.
+type Rule string
+
+// PDF/X rules checked by the validator.
+const (
+	RuleVersion          Rule = "version"
+	RuleEncryption       Rule = "encryption"
+	RuleFileID           Rule = "file-id"
+	RuleInfo             Rule = "info"
+	RuleOutputIntent     Rule = "output-intent"
+	RuleMetadata         Rule = "metadata"
+	RulePageBoxes        Rule = "page-boxes"
+	RuleColorSpace       Rule = "color-space"
+	RuleTransparency     Rule = "transparency"
+	RuleTransferFunction Rule = "transfer-function"
+	RuleCompression      Rule = "compression"
+	RuleFontEmbedding    Rule = "font-embedding"
+	RuleXObject          Rule = "xobject"
+	RuleAnnotation       Rule = "annotation"
+	RuleAction           Rule = "action"
+)
+
+// Violation describes a PDF/X conformance violation and its location.
+type Violation struct {
+	// Rule is the violated requirement.
+	Rule Rule
+
+	// Description describes the violation.
+	Description string
+
+	// Page is the number of the page containing the violation (starting
+	// from 1), or 0 for document level violations.
+	Page int
+
+	// ObjectNumber is the number of the closest indirect object containing
+	// the violation, or 0 if not known (e.g. for documents being written).
+	ObjectNumber int64
+
+	// Path is the path of the offending object relative to the page or the
+	// document catalog (e.g. /Resources/XObject/Im1).
+	Path string
+}
+
+// String returns a string representation of the violation.
+func (v Violation) String() string {
+	var loc []string
+	if v.Page > 0 {
+		loc = append(loc, fmt.Sprintf("page %d", v.Page))
+	}
+	if v.ObjectNumber > 0 {
+		loc = append(loc, fmt.Sprintf("obj %d", v.ObjectNumber))
+	}
+	if v.Path != "" {
+		loc = append(loc, v.Path)
+	}
+	if len(loc) == 0 {
+		return fmt.Sprintf("[%s] %s", v.Rule, v.Description)
+	}
+	return fmt.Sprintf("[%s] %s (%s)", v.Rule, v.Description, strings.Join(loc, ", "))
+}
+
+// ValidateReader validates the document loaded by `reader` against the
+// specified PDF/X version and returns the violations found.
+func ValidateReader(reader *model.PdfReader, version Version) ([]Violation, error) {
+	doc, err := reader.GetStandardDocument()
+	if err != nil {
+		return nil, err
+	}
+	return Validate(doc, version), nil
+}
+
+// Validate validates the document against the specified PDF/X version and
+// returns the violations found.
+func Validate(doc *model.StandardDocument, version Version) []Violation {
+	return validateRule(doc, version, "")
+}
+
+// validateRule validates the document against the specified PDF/X version and returns the
+// violations of `rule` found, or all the violations found if `rule` is empty.
+func validateRule(doc *model.StandardDocument, version Version, rule Rule) []Violation {
+	v := &validator{
+		version: version,
+		rule:    rule,
+		visited: map[core.PdfObject]struct{}{},
+	}
+	v.validateDocument(doc)
+	for i, page := range doc.Pages {
+		v.page = i + 1
+		v.validatePage(page)
+	}
+	return v.violations
+}
+
+// location tracks the position of the validated objects.
+type location struct {
+	objNum int64
+	path   string
+}
+
+// child returns the location of the object `obj` found under the entry `name`.
+func (l location) child(name string, obj core.PdfObject) location {
+	c := location{objNum: l.objNum, path: l.path + name}
+	switch t := core.ResolveReference(obj).(type) {
+	case *core.PdfIndirectObject:
+		if t.ObjectNumber > 0 {
+			c.objNum = t.ObjectNumber
+		}
+	case *core.PdfObjectStream:
+		if t.ObjectNumber > 0 {
+			c.objNum = t.ObjectNumber
+		}
+	}
+	return c
+}
+
+// validator checks the document objects and collects the violations.
+type validator struct {
+	version          Version
+	rule             Rule // if set, only the violations of the rule are reported
+	intentComponents int
+	page             int
+	visited          map[core.PdfObject]struct{}
+	violations       []Violation
+}
+
+// report adds a violation at the specified location.
+func (v *validator) report(rule Rule, loc location, format string, args ...interface{}) {
+	if v.rule != "" && rule != v.rule {
+		return
+	}
+	violation := Violation{
+		Rule:         rule,
+		Description:  fmt.Sprintf(format, args...),
+		Page:         v.page,
+		ObjectNumber: loc.objNum,
+		Path:         loc.path,
+	}
+	common.Log.Trace("PDF/X violation: %s", violation)
+	v.violations = append(v.violations, violation)
+}
+
+// visit returns false if the object has already been validated.
+func (v *validator) visit(obj core.PdfObject) bool {
+	obj = core.ResolveReference(obj)
+	if _, ok := v.visited[obj]; ok {
+		return false
+	}
+	v.visited[obj] = struct{}{}
+	return true
+}
+
+func (v *validator) validateDocument(doc *model.StandardDocument) {
+	root := location{}
+
+	// PDF version.
+	if compareVersions(doc.Version, v.version.pdfVersion()) > 0 {
+		v.report(RuleVersion, root, "PDF version %s exceeds the maximum allowed version %s",
+			doc.Version, v.version.pdfVersion())
+	}
+
+	// Encryption and file identifier.
+	if doc.Encrypted {
+		v.report(RuleEncryption, root, "document is encrypted")
+	}
+	if doc.ID == nil || doc.ID.Len() < 2 {
+		v.report(RuleFileID, root, "trailer ID entry missing")
+	}
+
+	v.validateInfo(doc.Info)
+	if doc.Catalog == nil {
+		v.report(RuleInfo, root, "document catalog missing")
+		return
+	}
+	v.validateOutputIntents(doc.Catalog)
+	if v.version == VersionX4 {
+		v.validateMetadata(doc.Catalog)
+	}
+
+	// Document level actions.
+	if doc.Catalog.Get("AA") != nil {
+		v.report(RuleAction, root.child("/AA", doc.Catalog.Get("AA")), "additional actions not allowed")
+	}
+	if obj := doc.Catalog.Get("OpenAction"); obj != nil {
+		v.validateAction(obj, root.child("/OpenAction", obj))
+	}
+	if names, ok := core.GetDict(doc.Catalog.Get("Names")); ok && names.Get("JavaScript") != nil {
+		v.report(RuleAction, root.child("/Names/JavaScript", names.Get("JavaScript")), "JavaScript not allowed")
+	}
+}
+
+func (v *validator) validateInfo(info *core.PdfObjectDictionary) {
+	loc := location{path: "/Info"}
+	if info == nil {
+		v.report(RuleInfo, loc, "document information dictionary missing")
+		return
+	}
+
+	if val, _ := core.GetStringVal(info.Get("GTS_PDFXVersion")); val != v.version.infoVersion() {
+		v.report(RuleInfo, loc, "GTS_PDFXVersion is %q, expected %q", val, v.version.infoVersion())
+	}
+	if v.version == VersionX1a2001 {
+		if val, _ := core.GetStringVal(info.Get("GTS_PDFXConformance")); val != v.version.String() {
+			v.report(RuleInfo, loc, "GTS_PDFXConformance is %q, expected %q", val, v.version.String())
+		}
+	}
+	if val, _ := core.GetStringVal(info.Get("Title")); val == "" {
+		v.report(RuleInfo, loc, "Title missing")
+	}
+	for _, key := range []core.PdfObjectName{"CreationDate", "ModDate"} {
+		if _, ok := core.GetString(info.Get(key)); !ok {
+			v.report(RuleInfo, loc, "%s missing", key)
+		}
+	}
+	if val, _ := core.GetNameVal(info.Get("Trapped")); val != "True" && val != "False" {
+		v.report(RuleInfo, loc, "Trapped must be True or False")
+	}
+}
+
+func (v *validator) validateOutputIntents(catalog *core.PdfObjectDictionary) {
+	loc := location{}.child("/OutputIntents", catalog.Get("OutputIntents"))
+	arr, _ := core.GetArray(catalog.Get("OutputIntents"))
+
+	var intent *core.PdfObjectDictionary
+	intentLoc := loc
+	if arr != nil {
+		for i, obj := range arr.Elements() {
+			d, ok := core.GetDict(obj)
+			if !ok {
+				continue
+			}
+			if s, _ := core.GetNameVal(d.Get("S")); s != "GTS_PDFX" {
+				continue
+			}
+			if intent != nil {
+				v.report(RuleOutputIntent, loc.child(fmt.Sprintf("[%d]", i), obj), "multiple GTS_PDFX output intents")
+				continue
+			}
+			intent = d
+			intentLoc = loc.child(fmt.Sprintf("[%d]", i), obj)
+		}
+	}
+	if intent == nil {
+		v.report(RuleOutputIntent, loc, "GTS_PDFX output intent missing")
+		return
+	}
+
+	if val, _ := core.GetStringVal(intent.Get("OutputConditionIdentifier")); val == "" {
+		v.report(RuleOutputIntent, intentLoc, "OutputConditionIdentifier missing")
+	}
+	profile, hasProfile := core.GetStream(intent.Get("DestOutputProfile"))
+	if !hasProfile {
+		if v.version == VersionX4 {
+			v.report(RuleOutputIntent, intentLoc, "DestOutputProfile missing")
+		} else if val, _ := core.GetStringVal(intent.Get("RegistryName")); val == "" {
+			v.report(RuleOutputIntent, intentLoc, "DestOutputProfile required for unregistered output conditions")
+		}
+		return
+	}
+	n, _ := core.GetIntVal(profile.Get("N"))
+	v.intentComponents = n
+	if v.version.isX1a() && n != 1 && n != 4 {
+		v.report(RuleOutputIntent, intentLoc.child("/DestOutputProfile", profile), "output profile must be CMYK or gray (N=%d)", n)
+	}
+}
+
+func (v *validator) validateMetadata(catalog *core.PdfObjectDictionary) {
+	loc := location{}.child("/Metadata", catalog.Get("Metadata"))
+	stream, ok := core.GetStream(catalog.Get("Metadata"))
+	if !ok {
+		v.report(RuleMetadata, loc, "XMP metadata missing")
+		return
+	}
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		v.report(RuleMetadata, loc, "XMP metadata cannot be decoded: %v", err)
+		return
+	}
+	if !bytes.Contains(data, []byte("GTS_PDFXVersion")) || !bytes.Contains(data, []byte(v.version.infoVersion())) {
+		v.report(RuleMetadata, loc, "XMP metadata missing pdfxid:GTS_PDFXVersion %s", v.version.infoVersion())
+	}
+}
+
+func (v *validator) validatePage(page *core.PdfIndirectObject) {
+	loc := location{objNum: page.ObjectNumber}
+	dict, ok := core.GetDict(page)
+	if !ok {
+		return
+	}
+
+	// Page boxes.
+	mediaBox, hasMedia := pageBox(dict, "MediaBox")
+	trimBox, hasTrim := pageBox(dict, "TrimBox")
+	artBox, hasArt := pageBox(dict, "ArtBox")
+	bleedBox, hasBleed := pageBox(dict, "BleedBox")
+	switch {
+	case !hasMedia:
+		v.report(RulePageBoxes, loc, "MediaBox missing")
+	case !hasTrim && !hasArt:
+		v.report(RulePageBoxes, loc, "TrimBox or ArtBox required")
+	case hasTrim && hasArt:
+		v.report(RulePageBoxes, loc, "TrimBox and ArtBox must not both be present")
+	}
+	if !hasTrim {
+		trimBox = artBox
+	}
+	if hasMedia {
+		if (hasTrim || hasArt) && !mediaBox.contains(trimBox) {
+			v.report(RulePageBoxes, loc, "TrimBox/ArtBox not contained in MediaBox")
+		}
+		if hasBleed && !mediaBox.contains(bleedBox) {
+			v.report(RulePageBoxes, loc, "BleedBox not contained in MediaBox")
+		}
+	}
+	if hasBleed && (hasTrim || hasArt) && !bleedBox.contains(trimBox) {
+		v.report(RulePageBoxes, loc, "TrimBox/ArtBox not contained in BleedBox")
+	}
+
+	// Transparency group.
+	v.validateGroup(dict.Get("Group"), loc)
+
+	// Actions.
+	if dict.Get("AA") != nil {
+		v.report(RuleAction, loc.child("/AA", dict.Get("AA")), "additional actions not allowed")
+	}
+
+	// Resources and contents.
+	resources := pageutil.InheritedResources(dict)
+	v.validateResources(resources, loc.child("/Resources", dict.Get("Resources")))
+	var content []byte
+	contentLoc := loc.child("/Contents", dict.Get("Contents"))
+	switch t := core.TraceToDirectObject(dict.Get("Contents")).(type) {
+	case *core.PdfObjectStream:
+		content = v.decodeStream(t, contentLoc)
+	case *core.PdfObjectArray:
+		for _, obj := range t.Elements() {
+			if stream, ok := core.GetStream(obj); ok {
+				content = append(content, v.decodeStream(stream, contentLoc)...)
+				content = append(content, ' ')
+			}
+		}
+	}
+	v.validateContent(content, resources, contentLoc)
+
+	// Annotations.
+	annots, _ := core.GetArray(dict.Get("Annots"))
+	if annots == nil {
+		return
+	}
+	for i, obj := range annots.Elements() {
+		annot, ok := core.GetDict(obj)
+		if !ok {
+			continue
+		}
+		annotLoc := loc.child(fmt.Sprintf("/Annots[%d]", i), obj)
+		v.validateAnnotation(annot, trimBox, hasTrim || hasArt, annotLoc)
+	}
+}
+
+func (v *validator) validateAnnotation(annot *core.PdfObjectDictionary, trimBox box, hasTrim bool, loc location) {
+	subtype, _ := core.GetNameVal(annot.Get("Subtype"))
+	flags, _ := core.GetIntVal(annot.Get("F"))
+	const flagPrint = 1 << 2
+
+	if flags&flagPrint != 0 && hasTrim && subtype != "PrinterMark" && subtype != "TrapNet" {
+		if rect, ok := pageBox(annot, "Rect"); ok && rect.overlaps(trimBox) {
+			v.report(RuleAnnotation, loc, "printable %s annotation inside the TrimBox", subtype)
+		}
+	}
+	if obj := annot.Get("A"); obj != nil {
+		v.validateAction(obj, loc.child("/A", obj))
+	}
+	if annot.Get("AA") != nil {
+		v.report(RuleAction, loc.child("/AA", annot.Get("AA")), "additional actions not allowed")
+	}
+
+	// Appearance streams.
+	ap, ok := core.GetDict(annot.Get("AP"))
+	if !ok {
+		return
+	}
+	for _, key := range ap.Keys() {
+		obj := ap.Get(key)
+		apLoc := loc.child("/AP/"+string(key), obj)
+		if stream, ok := core.GetStream(obj); ok {
+			v.validateForm(stream, nil, apLoc)
+			continue
+		}
+		if states, ok := core.GetDict(obj); ok {
+			for _, state := range states.Keys() {
+				if stream, ok := core.GetStream(states.Get(state)); ok {
+					v.validateForm(stream, nil, apLoc.child("/"+string(state), stream))
+				}
+			}
+		}
+	}
+}
+
+// forbiddenActions contains the action types which are not allowed in PDF/X.
+var forbiddenActions = map[string]struct{}{
+	"JavaScript": {},
+	"Launch":     {},
+	"Sound":      {},
+	"Movie":      {},
+	"ResetForm":  {},
+	"ImportData": {},
+	"Hide":       {},
+	"Rendition":  {},
+}
+
+func (v *validator) validateAction(obj core.PdfObject, loc location) {
+	if !v.visit(obj) {
+		return
+	}
+	action, ok := core.GetDict(obj)
+	if !ok {
+		return
+	}
+	s, _ := core.GetNameVal(action.Get("S"))
+	if _, forbidden := forbiddenActions[s]; forbidden {
+		v.report(RuleAction, loc, "%s action not allowed", s)
+	}
+
+	switch t := core.TraceToDirectObject(action.Get("Next")).(type) {
+	case *core.PdfObjectDictionary:
+		v.validateAction(action.Get("Next"), loc.child("/Next", action.Get("Next")))
+	case *core.PdfObjectArray:
+		for i, next := range t.Elements() {
+			v.validateAction(next, loc.child(fmt.Sprintf("/Next[%d]", i), next))
+		}
+	}
+}
+
+func (v *validator) validateGroup(obj core.PdfObject, loc location) {
+	group, ok := core.GetDict(obj)
+	if !ok || !v.version.isX1a() {
+		return
+	}
+	if s, _ := core.GetNameVal(group.Get("S")); s == "Transparency" {
+		v.report(RuleTransparency, loc.child("/Group", obj), "transparency group not allowed")
+	}
+}
+
+func (v *validator) validateResources(resources *core.PdfObjectDictionary, loc location) {
+	if resources == nil || !v.visit(resources) {
+		return
+	}
+
+	forEntry := func(key core.PdfObjectName, fn func(name string, obj core.PdfObject, loc location)) {
+		dict, ok := core.GetDict(resources.Get(key))
+		if !ok {
+			return
+		}
+		dictLoc := loc.child("/"+string(key), resources.Get(key))
+		for _, name := range dict.Keys() {
+			obj := dict.Get(name)
+			fn(string(name), obj, dictLoc.child("/"+string(name), obj))
+		}
+	}
+
+	forEntry("ColorSpace", func(name string, obj core.PdfObject, loc location) {
+		v.validateColorSpace(obj, resources, loc)
+	})
+	forEntry("ExtGState", func(name string, obj core.PdfObject, loc location) {
+		v.validateExtGState(obj, loc)
+	})
+	forEntry("Shading", func(name string, obj core.PdfObject, loc location) {
+		v.validateShading(obj, resources, loc)
+	})
+	forEntry("Pattern", func(name string, obj core.PdfObject, loc location) {
+		v.validatePattern(obj, resources, loc)
+	})
+	forEntry("Font", func(name string, obj core.PdfObject, loc location) {
+		v.validateFont(obj, resources, loc)
+	})
+	forEntry("XObject", func(name string, obj core.PdfObject, loc location) {
+		stream, ok := core.GetStream(obj)
+		if !ok {
+			return
+		}
+		subtype, _ := core.GetNameVal(stream.Get("Subtype"))
+		switch subtype {
+		case "Image":
+			v.validateImage(stream, resources, loc)
+		case "Form":
+			if _, isPS := core.GetName(stream.Get("Subtype2")); isPS {
+				v.report(RuleXObject, loc, "PostScript XObject not allowed")
+			}
+			v.validateForm(stream, resources, loc)
+		case "PS":
+			v.report(RuleXObject, loc, "PostScript XObject not allowed")
+		}
+	})
+}
+
+func (v *validator) validateColorSpace(obj core.PdfObject, resources *core.PdfObjectDictionary, loc location) {
+	switch t := core.TraceToDirectObject(obj).(type) {
+	case *core.PdfObjectName:
+		v.validateDeviceColorSpace(string(*t), resources, loc)
+	case *core.PdfObjectArray:
+		if t.Len() == 0 || !v.visit(t) {
+			return
+		}
+		family, _ := core.GetNameVal(t.Get(0))
+		switch family {
+		case "CalGray", "CalRGB", "Lab", "ICCBased":
+			if v.version.isX1a() {
+				v.report(RuleColorSpace, loc, "%s color space not allowed", family)
+			}
+		case "Indexed", "Pattern":
+			if t.Len() > 1 {
+				v.validateColorSpace(t.Get(1), resources, loc)
+			}
+		case "Separation", "DeviceN":
+			if t.Len() > 2 {
+				v.validateColorSpace(t.Get(2), resources, loc)
+			}
+		default:
+			v.validateDeviceColorSpace(family, resources, loc)
+		}
+	}
+}
+
+// validateDeviceColorSpace checks the use of the device color space `name`.
+// PDF/X-1a does not allow RGB. PDF/X-4 allows device color spaces matching
+// the output intent or having a default color space in the resources.
+func (v *validator) validateDeviceColorSpace(name string, resources *core.PdfObjectDictionary, loc location) {
+	hasDefault := func(key core.PdfObjectName) bool {
+		if resources == nil {
+			return false
+		}
+		cs, ok := core.GetDict(resources.Get("ColorSpace"))
+		return ok && cs.Get(key) != nil
+	}
+
+	switch name {
+	case "DeviceRGB", "RGB":
+		if v.version.isX1a() {
+			v.report(RuleColorSpace, loc, "DeviceRGB color space not allowed")
+		} else if v.intentComponents != 3 && !hasDefault("DefaultRGB") {
+			v.report(RuleColorSpace, loc, "DeviceRGB color space used without DefaultRGB")
+		}
+	case "DeviceCMYK", "CMYK":
+		if v.version == VersionX4 && v.intentComponents != 4 && v.intentComponents != 0 && !hasDefault("DefaultCMYK") {
+			v.report(RuleColorSpace, loc, "DeviceCMYK color space used without DefaultCMYK")
+		}
+	}
+}
+
+func (v *validator) validateExtGState(obj core.PdfObject, loc location) {
+	gs, ok := core.GetDict(obj)
+	if !ok || !v.visit(obj) {
+		return
+	}
+
+	if tr := gs.Get("TR"); tr != nil {
+		if name, _ := core.GetNameVal(tr); name != "Identity" {
+			v.report(RuleTransferFunction, loc, "transfer functions not allowed")
+		}
+	}
+	if tr := gs.Get("TR2"); tr != nil {
+		if name, _ := core.GetNameVal(tr); name != "Default" && name != "Identity" {
+			v.report(RuleTransferFunction, loc, "transfer functions not allowed")
+		}
+	}
+
+	if !v.version.isX1a() {
+		return
+	}
+	if smask := gs.Get("SMask"); smask != nil {
+		if name, _ := core.GetNameVal(smask); name != "None" {
+			v.report(RuleTransparency, loc, "soft mask not allowed")
+		}
+	}
+	for _, key := range []core.PdfObjectName{"CA", "ca"} {
+		if val, err := core.GetNumberAsFloat(core.TraceToDirectObject(gs.Get(key))); err == nil && val < 1 {
+			v.report(RuleTransparency, loc, "constant alpha %s=%g not allowed", key, val)
+		}
+	}
+	if bm := gs.Get("BM"); bm != nil {
+		mode, _ := core.GetNameVal(bm)
+		if arr, ok := core.GetArray(bm); ok && arr.Len() > 0 {
+			mode, _ = core.GetNameVal(arr.Get(0))
+		}
+		if mode != "Normal" && mode != "Compatible" {
+			v.report(RuleTransparency, loc, "blend mode %s not allowed", mode)
+		}
+	}
+}
+
+func (v *validator) validateShading(obj core.PdfObject, resources *core.PdfObjectDictionary, loc location) {
+	if !v.visit(obj) {
+		return
+	}
+	var shading *core.PdfObjectDictionary
+	switch t := core.TraceToDirectObject(obj).(type) {
+	case *core.PdfObjectDictionary:
+		shading = t
+	case *core.PdfObjectStream:
+		shading = t.PdfObjectDictionary
+	default:
+		return
+	}
+	v.validateColorSpace(shading.Get("ColorSpace"), resources, loc)
+}
+
+func (v *validator) validatePattern(obj core.PdfObject, resources *core.PdfObjectDictionary, loc location) {
+	switch t := core.TraceToDirectObject(obj).(type) {
+	case *core.PdfObjectStream:
+		// Tiling pattern.
+		v.validateForm(t, resources, loc)
+	case *core.PdfObjectDictionary:
+		// Shading pattern.
+		if !v.visit(t) {
+			return
+		}
+		if sh := t.Get("Shading"); sh != nil {
+			v.validateShading(sh, resources, loc.child("/Shading", sh))
+		}
+		if gs := t.Get("ExtGState"); gs != nil {
+			v.validateExtGState(gs, loc.child("/ExtGState", gs))
+		}
+	}
+}
+
+func (v *validator) validateFont(obj core.PdfObject, resources *core.PdfObjectDictionary, loc location) {
+	font, ok := core.GetDict(obj)
+	if !ok || !v.visit(obj) {
+		return
+	}
+
+	subtype, _ := core.GetNameVal(font.Get("Subtype"))
+	switch subtype {
+	case "Type3":
+		fontResources, ok := core.GetDict(font.Get("Resources"))
+		if ok {
+			v.validateResources(fontResources, loc.child("/Resources", font.Get("Resources")))
+		} else {
+			fontResources = resources
+		}
+		procs, ok := core.GetDict(font.Get("CharProcs"))
+		if !ok {
+			return
+		}
+		for _, name := range procs.Keys() {
+			if stream, ok := core.GetStream(procs.Get(name)); ok {
+				procLoc := loc.child("/CharProcs/"+string(name), stream)
+				v.validateContent(v.decodeStream(stream, procLoc), fontResources, procLoc)
+			}
+		}
+		return
+	case "Type0":
+		descendants, ok := core.GetArray(font.Get("DescendantFonts"))
+		if !ok || descendants.Len() == 0 {
+			v.report(RuleFontEmbedding, loc, "DescendantFonts missing")
+			return
+		}
+		font, ok = core.GetDict(descendants.Get(0))
+		if !ok {
+			return
+		}
+		loc = loc.child("/DescendantFonts[0]", descendants.Get(0))
+	}
+
+	baseFont, _ := core.GetNameVal(font.Get("BaseFont"))
+	descriptor, ok := core.GetDict(font.Get("FontDescriptor"))
+	if !ok {
+		v.report(RuleFontEmbedding, loc, "font %s not embedded", baseFont)
+		return
+	}
+	for _, key := range []core.PdfObjectName{"FontFile", "FontFile2", "FontFile3"} {
+		if _, ok := core.GetStream(descriptor.Get(key)); ok {
+			return
+		}
+	}
+	v.report(RuleFontEmbedding, loc, "font %s not embedded", baseFont)
+}
+
+func (v *validator) validateImage(stream *core.PdfObjectStream, resources *core.PdfObjectDictionary, loc location) {
+	if !v.visit(stream) {
+		return
+	}
+
+	if cs := stream.Get("ColorSpace"); cs != nil {
+		v.validateColorSpace(cs, resources, loc)
+	}
+	if v.version.isX1a() {
+		if hasFilter(stream, core.StreamEncodingFilterNameJPX) {
+			v.report(RuleCompression, loc, "JPXDecode filter not allowed")
+		}
+		if stream.Get("SMask") != nil {
+			v.report(RuleTransparency, loc, "image soft mask not allowed")
+		}
+		if val, ok := core.GetIntVal(stream.Get("SMaskInData")); ok && val != 0 {
+			v.report(RuleTransparency, loc, "image SMaskInData not allowed")
+		}
+	}
+	if stream.Get("OPI") != nil {
+		v.report(RuleXObject, loc, "OPI not allowed")
+	}
+	if alts := stream.Get("Alternates"); alts != nil {
+		v.report(RuleXObject, loc, "alternate images not allowed")
+	}
+}
+
+func (v *validator) validateForm(stream *core.PdfObjectStream, resources *core.PdfObjectDictionary, loc location) {
+	if !v.visit(stream) {
+		return
+	}
+
+	v.validateGroup(stream.Get("Group"), loc)
+	if stream.Get("OPI") != nil {
+		v.report(RuleXObject, loc, "OPI not allowed")
+	}
+	if formResources, ok := core.GetDict(stream.Get("Resources")); ok {
+		resources = formResources
+		v.validateResources(resources, loc.child("/Resources", stream.Get("Resources")))
+	}
+	v.validateContent(v.decodeStream(stream, loc), resources, loc)
+}
+
+// validateContent checks the color operators and inline images of the
+// content stream. Each device color space is checked once per stream.
+func (v *validator) validateContent(content []byte, resources *core.PdfObjectDictionary, loc location) {
+	if len(content) == 0 {
+		return
+	}
+	ops, err := contentstream.NewContentStreamParser(string(content)).Parse()
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to parse content stream: %v", err)
+	}
+	if ops == nil {
+		return
+	}
+
+	checked := map[string]bool{}
+	check := func(name string) {
+		switch name {
+		case "RGB":
+			name = "DeviceRGB"
+		case "CMYK":
+			name = "DeviceCMYK"
+		}
+		if !checked[name] {
+			checked[name] = true
+			v.validateDeviceColorSpace(name, resources, loc)
+		}
+	}
+
+	for _, op := range *ops {
+		switch op.Operand {
+		case "rg", "RG":
+			check("DeviceRGB")
+		case "k", "K":
+			check("DeviceCMYK")
+		case "cs", "CS":
+			if len(op.Params) == 1 {
+				if name, ok := core.GetNameVal(op.Params[0]); ok {
+					check(name)
+				}
+			}
+		case "BI":
+			if len(op.Params) != 1 {
+				continue
+			}
+			img, ok := op.Params[0].(*contentstream.ContentStreamInlineImage)
+			if !ok || img.ColorSpace == nil {
+				continue
+			}
+			if name, ok := core.GetNameVal(img.ColorSpace); ok {
+				check(name)
+			}
+		}
+	}
+}
+
+// decodeStream returns the decoded data of `stream`, reporting a violation
+// if the stream cannot be decoded.
+func (v *validator) decodeStream(stream *core.PdfObjectStream, loc location) []byte {
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to decode stream: %v", err)
+		v.report(RuleCompression, loc, "stream cannot be decoded: %v", err)
+		return nil
+	}
+	return data
+}
+
+// hasFilter returns true if the stream is encoded using the filter `name`.
+func hasFilter(stream *core.PdfObjectStream, name string) bool {
+	switch t := core.TraceToDirectObject(stream.Get("Filter")).(type) {
+	case *core.PdfObjectName:
+		return string(*t) == name
+	case *core.PdfObjectArray:
+		for _, obj := range t.Elements() {
+			if val, _ := core.GetNameVal(obj); val == name {
+				return true
+			}
+		}
+	}
+	return false
+}
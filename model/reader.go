@@ -39,6 +39,13 @@ type PdfReader struct {
 	// For tracking traversal (cache).
 	traversed map[core.PdfObject]struct{}
 	rs        io.ReadSeeker
+
+	// Report of the recovery of the damaged documents, set when recovering the document.
+	recovery *RecoveryReport
+
+	// Range reader of the remote documents and their linearization parameters, if linearized.
+	rangeReader   *core.RangeReader
+	linearization *core.Linearization
 }
 
 // NewPdfReader returns a new PdfReader for an input io.ReadSeeker interface. Can be used to read PDF from
@@ -113,6 +120,43 @@ func NewPdfReaderLazy(rs io.ReadSeeker) (*PdfReader, error) {
 	return pdfReader, nil
 }
 
+// NewPdfReaderStreamed creates a new PdfReader for `rs` like NewPdfReader, except that the data
+// of the streams larger than `streamedSize` bytes, e.g. embedded videos or large images, is not
+// loaded into memory but read from `rs` when it is needed: such streams are decoded with
+// core.NewDecodedStreamReader and copied from `rs` when written, so `rs` must remain open while
+// the document is used.
+func NewPdfReaderStreamed(rs io.ReadSeeker, streamedSize int64) (*PdfReader, error) {
+	pdfReader := &PdfReader{
+		rs:           rs,
+		traversed:    map[core.PdfObject]struct{}{},
+		modelManager: newModelManager(),
+		isLazy:       false,
+	}
+
+	// Create the parser, loads the cross reference table and trailer.
+	parser, err := core.NewParser(rs)
+	if err != nil {
+		return nil, err
+	}
+	parser.SetStreamedSize(streamedSize)
+	pdfReader.parser = parser
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return nil, err
+	}
+
+	// Load pdf doc structure if not encrypted.
+	if !isEncrypted {
+		err = pdfReader.loadStructure()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return pdfReader, nil
+}
+
 // PdfVersion returns version of the PDF file.
 func (r *PdfReader) PdfVersion() core.Version {
 	return r.parser.PdfVersion()
@@ -129,6 +173,67 @@ func (r *PdfReader) GetEncryptionMethod() string {
 	return crypter.String()
 }
 
+// NewPdfReaderRemote creates a new PdfReader in lazy-loading mode for the document of `size`
+// bytes read from `r`, e.g. a document in object storage read with HTTP range requests, so that
+// parts of the document such as its first page or its metadata are read without reading the whole
+// document. The data is read by blocks, which are cached and read ahead, with a core.RangeReader
+// with the options `opts`, the default options being used if nil.
+// For linearized documents, the first page section is fetched with a single read of `r` and the
+// data of the pages located by the hint tables is fetched when the pages are accessed with GetPage.
+func NewPdfReaderRemote(r io.ReaderAt, size int64, opts *core.RangeReaderOptions) (*PdfReader, error) {
+	rangeReader := core.NewRangeReader(r, size, opts)
+	pdfReader := &PdfReader{
+		rs:           rangeReader,
+		traversed:    map[core.PdfObject]struct{}{},
+		modelManager: newModelManager(),
+		isLazy:       true,
+		rangeReader:  rangeReader,
+	}
+
+	// Create the parser, loads the cross reference table and trailer.
+	parser, err := core.NewParser(rangeReader)
+	if err != nil {
+		return nil, err
+	}
+	pdfReader.parser = parser
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return nil, err
+	}
+
+	// Load pdf doc structure if not encrypted.
+	if !isEncrypted {
+		pdfReader.loadLinearization()
+		err = pdfReader.loadStructure()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return pdfReader, nil
+}
+
+// loadLinearization loads the linearization parameters of the remote documents, prefetching their
+// first page section.
+func (r *PdfReader) loadLinearization() {
+	if r.rangeReader == nil {
+		return
+	}
+	lin, err := r.parser.GetLinearization()
+	if err != nil {
+		common.Log.Debug("ERROR: Failed loading linearization parameters: %v", err)
+		return
+	}
+	if lin == nil {
+		return
+	}
+	r.linearization = lin
+	if err := r.rangeReader.Prefetch(0, lin.FirstPageEnd); err != nil {
+		common.Log.Debug("ERROR: Failed prefetching first page section: %v", err)
+	}
+}
+
 // Decrypt decrypts the PDF file with a specified password.  Also tries to
 // decrypt with an empty password.  Returns true if successful,
 // false otherwise.
@@ -141,6 +246,7 @@ func (r *PdfReader) Decrypt(password []byte) (bool, error) {
 		return false, nil
 	}
 
+	r.loadLinearization()
 	err = r.loadStructure()
 	if err != nil {
 		common.Log.Debug("ERROR: Fail to load structure (%s)", err)
@@ -167,95 +273,147 @@ func (r *PdfReader) loadStructure() error {
 		return fmt.Errorf("file need to be decrypted first")
 	}
 
+	root, catalog, err := r.loadCatalog()
+	if err != nil {
+		if r.recovery == nil {
+			return err
+		}
+		common.Log.Debug("ERROR: Catalog not found (%v) - creating it", err)
+		root, catalog = nil, r.recoverCatalog()
+	}
+
+	ppages, pages, err := r.loadPageTree(catalog)
+	if err != nil {
+		if r.recovery == nil {
+			return err
+		}
+		common.Log.Debug("ERROR: Invalid page tree (%v) - rebuilding it", err)
+		ppages, pages = r.rebuildPageTree(catalog)
+	}
+	pageCount, _ := core.GetIntVal(pages.Get("Count"))
+
+	r.root = root
+	r.catalog = catalog
+	r.pages = pages
+	r.pagesContainer = ppages
+	r.pageCount = pageCount
+	r.pageList = []*core.PdfIndirectObject{}
+
+	traversedPageNodes := map[core.PdfObject]struct{}{}
+	err = r.buildPageList(ppages, nil, traversedPageNodes)
+	if err != nil {
+		if r.recovery == nil {
+			return err
+		}
+		common.Log.Debug("ERROR: Invalid page tree (%v)", err)
+		r.pageList = nil
+		r.PageList = nil
+	}
+	if r.recovery != nil {
+		if len(r.pageList) == 0 && !r.recovery.PageTreeRebuilt {
+			common.Log.Debug("ERROR: No page in the page tree - rebuilding it")
+			ppages, pages = r.rebuildPageTree(catalog)
+			r.pages = pages
+			r.pagesContainer = ppages
+			r.pageList = nil
+			r.PageList = nil
+			r.recovery.LostPages = 0
+			if err := r.buildPageList(ppages, nil, map[core.PdfObject]struct{}{}); err != nil {
+				return err
+			}
+		}
+		r.pageCount = len(r.pageList)
+		pages.Set("Count", core.MakeInteger(int64(r.pageCount)))
+		r.recovery.Pages = r.pageCount
+	}
+	common.Log.Trace("---")
+	common.Log.Trace("TOC")
+	common.Log.Trace("Pages")
+	common.Log.Trace("%d: %s", len(r.pageList), r.pageList)
+
+	// Outlines.
+	r.outlineTree, err = r.loadOutlines()
+	if err != nil {
+		common.Log.Debug("ERROR: Failed to build outline tree (%s)", err)
+		if r.recovery == nil {
+			return err
+		}
+	}
+
+	// Load interactive forms and fields.
+	r.AcroForm, err = r.loadForms()
+	if err != nil {
+		if r.recovery == nil {
+			return err
+		}
+		common.Log.Debug("ERROR: Failed to load the forms (%s)", err)
+	}
+
+	return nil
+}
+
+// loadCatalog returns the reference to the catalog of the document and the catalog.
+func (r *PdfReader) loadCatalog() (*core.PdfObjectReference, *core.PdfObjectDictionary, error) {
 	trailerDict := r.parser.GetTrailer()
 	if trailerDict == nil {
-		return fmt.Errorf("missing trailer")
+		return nil, nil, fmt.Errorf("missing trailer")
 	}
 
 	// Catalog.
 	root, ok := trailerDict.Get("Root").(*core.PdfObjectReference)
 	if !ok {
-		return fmt.Errorf("invalid Root (trailer: %s)", trailerDict)
+		return nil, nil, fmt.Errorf("invalid Root (trailer: %s)", trailerDict)
 	}
 	oc, err := r.parser.LookupByReference(*root)
 	if err != nil {
 		common.Log.Debug("ERROR: Failed to read root element catalog: %s", err)
-		return err
+		return nil, nil, err
 	}
 	pcatalog, ok := oc.(*core.PdfIndirectObject)
 	if !ok {
 		common.Log.Debug("ERROR: Missing catalog: (root %q) (trailer %s)", oc, *trailerDict)
-		return errors.New("missing catalog")
+		return nil, nil, errors.New("missing catalog")
 	}
 	catalog, ok := (*pcatalog).PdfObject.(*core.PdfObjectDictionary)
 	if !ok {
 		common.Log.Debug("ERROR: Invalid catalog (%s)", pcatalog.PdfObject)
-		return errors.New("invalid catalog")
+		return nil, nil, errors.New("invalid catalog")
 	}
 	common.Log.Trace("Catalog: %s", catalog)
+	return root, catalog, nil
+}
 
-	// Pages.
+// loadPageTree returns the root node of the page tree of `catalog` and its dictionary.
+func (r *PdfReader) loadPageTree(catalog *core.PdfObjectDictionary) (*core.PdfIndirectObject, *core.PdfObjectDictionary, error) {
 	pagesRef, ok := catalog.Get("Pages").(*core.PdfObjectReference)
 	if !ok {
-		return errors.New("pages in catalog should be a reference")
+		return nil, nil, errors.New("pages in catalog should be a reference")
 	}
 	op, err := r.parser.LookupByReference(*pagesRef)
 	if err != nil {
 		common.Log.Debug("ERROR: Failed to read pages")
-		return err
+		return nil, nil, err
 	}
 	ppages, ok := op.(*core.PdfIndirectObject)
 	if !ok {
 		common.Log.Debug("ERROR: Pages object invalid")
 		common.Log.Debug("op: %p", ppages)
-		return errors.New("pages object invalid")
+		return nil, nil, errors.New("pages object invalid")
 	}
 	pages, ok := ppages.PdfObject.(*core.PdfObjectDictionary)
 	if !ok {
 		common.Log.Debug("ERROR: Pages object invalid (%s)", ppages)
-		return errors.New("pages object invalid")
+		return nil, nil, errors.New("pages object invalid")
 	}
-	pageCount, ok := core.GetInt(pages.Get("Count"))
-	if !ok {
+	if _, ok := core.GetInt(pages.Get("Count")); !ok {
 		common.Log.Debug("ERROR: Pages count object invalid")
-		return errors.New("pages count invalid")
+		return nil, nil, errors.New("pages count invalid")
 	}
 	if _, ok = core.GetName(pages.Get("Type")); !ok {
 		common.Log.Debug("Pages dict Type field not set. Setting Type to Pages.")
 		pages.Set("Type", core.MakeName("Pages"))
 	}
-
-	r.root = root
-	r.catalog = catalog
-	r.pages = pages
-	r.pagesContainer = ppages
-	r.pageCount = int(*pageCount)
-	r.pageList = []*core.PdfIndirectObject{}
-
-	traversedPageNodes := map[core.PdfObject]struct{}{}
-	err = r.buildPageList(ppages, nil, traversedPageNodes)
-	if err != nil {
-		return err
-	}
-	common.Log.Trace("---")
-	common.Log.Trace("TOC")
-	common.Log.Trace("Pages")
-	common.Log.Trace("%d: %s", len(r.pageList), r.pageList)
-
-	// Outlines.
-	r.outlineTree, err = r.loadOutlines()
-	if err != nil {
-		common.Log.Debug("ERROR: Failed to build outline tree (%s)", err)
-		return err
-	}
-
-	// Load interactive forms and fields.
-	r.AcroForm, err = r.loadForms()
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return ppages, pages, nil
 }
 
 func (r *PdfReader) loadOutlines() (*PdfOutlineTreeNode, error) {
@@ -748,17 +906,34 @@ func (r *PdfReader) buildPageList(node *core.PdfIndirectObject, parent *core.Pdf
 		}
 	}
 	common.Log.Trace("Kids: %s", kids)
+	// The pages and the page tree nodes salvaged when recovering a damaged document.
+	var salvaged []core.PdfObject
+	numPages := len(r.pageList)
 	for idx, child := range kids.Elements() {
 		child, ok := core.GetIndirect(child)
 		if !ok {
 			common.Log.Debug("ERROR: Page not indirect object - (%s)", child)
+			if r.recovery != nil {
+				r.recovery.LostPages++
+				continue
+			}
 			return errors.New("page not indirect object")
 		}
 		kids.Set(idx, child)
 		err = r.buildPageList(child, node, traversedPageNodes)
 		if err != nil {
+			if r.recovery != nil {
+				common.Log.Debug("ERROR: Skipping page tree node (%v)", err)
+				r.recovery.LostPages++
+				continue
+			}
 			return err
 		}
+		salvaged = append(salvaged, child)
+	}
+	if r.recovery != nil {
+		nodeDict.Set("Kids", core.MakeArray(salvaged...))
+		nodeDict.Set("Count", core.MakeInteger(int64(len(r.pageList)-numPages)))
 	}
 
 	return nil
@@ -825,6 +1000,15 @@ func (r *PdfReader) GetPage(pageNumber int) (*PdfPage, error) {
 		return nil, fmt.Errorf("page numbering must start at 1")
 	}
 	page := r.PageList[idx]
+
+	// Fetch the data of the page of the linearized remote documents with a single read.
+	if r.linearization != nil {
+		if offset, length, ok := r.linearization.PageRange(pageNumber); ok {
+			if err := r.rangeReader.Prefetch(offset, length); err != nil {
+				common.Log.Debug("ERROR: Failed prefetching page %d: %v", pageNumber, err)
+			}
+		}
+	}
 	return page, nil
 }
 
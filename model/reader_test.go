@@ -7,8 +7,14 @@ package model
 
 import (
 	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -47,3 +53,137 @@ func TestReaderLazy(t *testing.T) {
 	err = writer.Write(&buf)
 	require.NoError(t, err)
 }
+
+// httpRangeReaderAt reads a remote document with HTTP range requests, counting the requests and
+// the bytes read.
+type httpRangeReaderAt struct {
+	url       string
+	requests  int
+	bytesRead int64
+}
+
+// ReadAt implements io.ReaderAt interface.
+func (r *httpRangeReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	req, err := http.NewRequest(http.MethodGet, r.url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+int64(len(p))-1))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return 0, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	r.requests++
+	n, err := io.ReadFull(resp.Body, p)
+	r.bytesRead += int64(n)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// newRemoteTestReader serves `data` with range requests, returning a reader of the remote
+// document and a function closing the server.
+func newRemoteTestReader(t *testing.T, data []byte) (*PdfReader, *httpRangeReaderAt, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.ServeContent(w, req, "document.pdf", time.Time{}, bytes.NewReader(data))
+	}))
+	remote := &httpRangeReaderAt{url: server.URL}
+	reader, err := NewPdfReaderRemote(remote, int64(len(data)), &core.RangeReaderOptions{BlockSize: 4096})
+	require.NoError(t, err)
+	return reader, remote, server.Close
+}
+
+// requirePageContent checks that the content of the page `pageNum` of `reader` is the content of
+// the page of `expected`.
+func requirePageContent(t *testing.T, expected, reader *PdfReader, pageNum int) {
+	expectedPage, err := expected.GetPage(pageNum)
+	require.NoError(t, err)
+	expectedContent, err := expectedPage.GetAllContentStreams()
+	require.NoError(t, err)
+	page, err := reader.GetPage(pageNum)
+	require.NoError(t, err)
+	content, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	require.Equal(t, expectedContent, content, pageNum)
+}
+
+func TestReaderRemote(t *testing.T) {
+	// Pages with large content streams.
+	w := NewPdfWriter()
+	for i := 0; i < 10; i++ {
+		var b bytes.Buffer
+		fmt.Fprintf(&b, "BT /F1 12 Tf 72 720 Td (Page %d) Tj ET\n", i+1)
+		for j := 0; j < 1000; j++ {
+			fmt.Fprintf(&b, "%% %d %x\n", j, bytes.Repeat([]byte{byte(i*j + j)}, 20))
+		}
+		stream, err := core.MakeStream(b.Bytes(), core.NewRawEncoder())
+		require.NoError(t, err)
+		page := NewPdfPage()
+		page.Contents = stream
+		require.NoError(t, w.AddPage(page))
+	}
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	data := buf.Bytes()
+
+	local, err := NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	reader, remote, closeServer := newRemoteTestReader(t, data)
+	defer closeServer()
+	require.Nil(t, reader.linearization)
+	numPages, err := reader.GetNumPages()
+	require.NoError(t, err)
+	require.Equal(t, 10, numPages)
+
+	// The first page is read without reading the whole document.
+	requirePageContent(t, local, reader, 1)
+	require.True(t, remote.bytesRead < int64(len(data))/3, "%d/%d", remote.bytesRead, len(data))
+	requirePageContent(t, local, reader, 10)
+	require.True(t, remote.bytesRead < int64(len(data))/2, "%d/%d", remote.bytesRead, len(data))
+}
+
+func TestReaderRemoteLinearized(t *testing.T) {
+	data, err := ioutil.ReadFile(`../fjson/testdata/advancedform.pdf`)
+	require.NoError(t, err)
+	local, err := NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	reader, _, closeServer := newRemoteTestReader(t, data)
+	defer closeServer()
+
+	require.NotNil(t, reader.linearization)
+	numPages, err := reader.GetNumPages()
+	require.NoError(t, err)
+	require.Equal(t, reader.linearization.NumPages, numPages)
+	for i := 1; i <= numPages; i++ {
+		_, _, ok := reader.linearization.PageRange(i)
+		require.True(t, ok, i)
+		requirePageContent(t, local, reader, i)
+	}
+	require.Len(t, reader.AcroForm.AllFields(), len(local.AcroForm.AllFields()))
+}
+
+func TestReaderRemoteInvalidLinearization(t *testing.T) {
+	data, err := ioutil.ReadFile(`../fjson/testdata/advancedform.pdf`)
+	require.NoError(t, err)
+	// Oversized number of pages, the padding following the dictionary keeping the offsets.
+	data = bytes.Replace(data,
+		[]byte("/N 12/T 262899/H [ 615 551]>>\rendobj\r          "),
+		[]byte("/N 2000000000/T 262899/H [ 615 551]>>\rendobj\r  "), 1)
+	local, err := NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	reader, _, closeServer := newRemoteTestReader(t, data)
+	defer closeServer()
+
+	// The document is read without the linearization parameters.
+	require.Nil(t, reader.linearization)
+	numPages, err := reader.GetNumPages()
+	require.NoError(t, err)
+	require.Equal(t, 12, numPages)
+	requirePageContent(t, local, reader, 1)
+	requirePageContent(t, local, reader, numPages)
+}
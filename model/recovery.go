@@ -0,0 +1,162 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/TheLinker/unipdf/v3/common"
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/internal/pageutil"
+)
+
+// RecoveryReport reports the recovery of a damaged document by NewPdfReaderWithRecovery.
+type RecoveryReport struct {
+	// Repairs are the repairs of the file made by the parser.
+	core.Repairs
+
+	// CatalogCreated is set if no catalog was found and a catalog was created for the pages.
+	CatalogCreated bool
+
+	// PageTreeRebuilt is set if the page tree was damaged and was rebuilt from the page objects
+	// found in the file.
+	PageTreeRebuilt bool
+
+	// Pages is the number of pages salvaged.
+	Pages int
+
+	// LostPages is the number of the pages and of the page tree nodes which could not be loaded.
+	LostPages int
+}
+
+// Damaged returns true if the document was damaged, i.e. if repairs were made to load it.
+func (r *RecoveryReport) Damaged() bool {
+	return r.XrefRebuilt || r.TrailerRebuilt || len(r.Streams) > 0 || r.CatalogCreated ||
+		r.PageTreeRebuilt || r.LostPages > 0
+}
+
+// String returns a description of the recovery.
+func (r *RecoveryReport) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Pages salvaged: %d\n", r.Pages)
+	if r.LostPages > 0 {
+		fmt.Fprintf(&buf, "Pages or page tree nodes lost: %d\n", r.LostPages)
+	}
+	if r.XrefRebuilt {
+		buf.WriteString("Cross-reference table rebuilt\n")
+	}
+	if r.TrailerRebuilt {
+		buf.WriteString("Trailer rebuilt\n")
+	}
+	if r.CatalogFound {
+		buf.WriteString("Catalog located by its type\n")
+	}
+	if r.CatalogCreated {
+		buf.WriteString("Catalog created\n")
+	}
+	if r.PageTreeRebuilt {
+		buf.WriteString("Page tree rebuilt\n")
+	}
+	for _, num := range r.Streams {
+		fmt.Fprintf(&buf, "Length of stream %d corrected\n", num)
+	}
+	return buf.String()
+}
+
+// NewPdfReaderWithRecovery returns a new PdfReader for `rs` like NewPdfReader, recovering the
+// truncated and the damaged documents: the cross-reference table and the trailer are rebuilt
+// when they cannot be loaded, the catalog is located by its /Type /Catalog entry, the page tree
+// is rebuilt from the page objects found in the file when it is damaged, the streams with a
+// wrong /Length are read up to their endstream keyword and the pages which cannot be loaded are
+// skipped. The repairs made are reported by GetRecoveryReport.
+func NewPdfReaderWithRecovery(rs io.ReadSeeker) (*PdfReader, error) {
+	pdfReader := &PdfReader{
+		rs:           rs,
+		traversed:    map[core.PdfObject]struct{}{},
+		modelManager: newModelManager(),
+		recovery:     &RecoveryReport{},
+	}
+
+	// Create the parser, loads or rebuilds the cross reference table and trailer.
+	parser, err := core.NewParserWithRecovery(rs)
+	if err != nil {
+		return nil, err
+	}
+	pdfReader.parser = parser
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return nil, err
+	}
+
+	// Load pdf doc structure if not encrypted.
+	if !isEncrypted {
+		err = pdfReader.loadStructure()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return pdfReader, nil
+}
+
+// GetRecoveryReport returns the report of the recovery of the document, nil if the reader was
+// not created by NewPdfReaderWithRecovery.
+func (r *PdfReader) GetRecoveryReport() *RecoveryReport {
+	if r.recovery == nil {
+		return nil
+	}
+	report := *r.recovery
+	report.Repairs = r.parser.GetRepairs()
+	return &report
+}
+
+// recoverCatalog returns a new catalog for the document whose catalog is not found.
+func (r *PdfReader) recoverCatalog() *core.PdfObjectDictionary {
+	catalog := core.MakeDict()
+	catalog.Set("Type", core.MakeName("Catalog"))
+	r.recovery.CatalogCreated = true
+	return catalog
+}
+
+// rebuildPageTree rebuilds the page tree of `catalog` from the page objects found in the file,
+// ordered by object number, and returns the root node of the tree and its dictionary.
+func (r *PdfReader) rebuildPageTree(catalog *core.PdfObjectDictionary) (*core.PdfIndirectObject, *core.PdfObjectDictionary) {
+	pages := core.MakeDict()
+	pages.Set("Type", core.MakeName("Pages"))
+	ppages := core.MakeIndirectObject(pages)
+
+	var kids []core.PdfObject
+	for _, objNum := range r.parser.GetObjectNums() {
+		obj, err := r.parser.LookupByNumber(objNum)
+		if err != nil {
+			continue
+		}
+		ind, ok := obj.(*core.PdfIndirectObject)
+		if !ok {
+			continue
+		}
+		dict, ok := core.GetDict(ind.PdfObject)
+		if !ok {
+			continue
+		}
+		if name, ok := core.GetName(dict.Get("Type")); !ok || *name != "Page" {
+			continue
+		}
+		pageutil.InheritAttributes(dict)
+		dict.Set("Parent", ppages)
+		kids = append(kids, ind)
+	}
+	common.Log.Debug("Page tree rebuilt with %d pages", len(kids))
+
+	pages.Set("Kids", core.MakeArray(kids...))
+	pages.Set("Count", core.MakeInteger(int64(len(kids))))
+	catalog.Set("Pages", ppages)
+	r.recovery.PageTreeRebuilt = true
+	return ppages, pages
+}
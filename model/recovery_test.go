@@ -0,0 +1,129 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/TheLinker/unipdf/v3/core"
+)
+
+// recoveryTestDocument returns a document with 3 pages whose uncompressed content is
+// "0.<page number> g".
+func recoveryTestDocument(t *testing.T) []byte {
+	w := NewPdfWriter()
+	for i := 1; i <= 3; i++ {
+		page := NewPdfPage()
+		require.NoError(t, page.SetContentStreams([]string{fmt.Sprintf("0.%d g", i)}, core.NewRawEncoder()))
+		require.NoError(t, w.AddPage(page))
+	}
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	return buf.Bytes()
+}
+
+// checkRecoveredPages checks that the pages of `reader` are the pages of the test document.
+func checkRecoveredPages(t *testing.T, reader *PdfReader) {
+	numPages, err := reader.GetNumPages()
+	require.NoError(t, err)
+	require.Equal(t, 3, numPages)
+	for i := 1; i <= numPages; i++ {
+		page, err := reader.GetPage(i)
+		require.NoError(t, err)
+		content, err := page.GetAllContentStreams()
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("0.%d g", i), strings.TrimSpace(content))
+	}
+}
+
+func TestRecoveryTruncated(t *testing.T) {
+	data := recoveryTestDocument(t)
+	// Truncate the file before the cross-reference table and the trailer.
+	data = data[:bytes.LastIndex(data, []byte("endobj"))+len("endobj")]
+
+	_, err := NewPdfReader(bytes.NewReader(data))
+	require.Error(t, err)
+
+	reader, err := NewPdfReaderWithRecovery(bytes.NewReader(data))
+	require.NoError(t, err)
+	checkRecoveredPages(t, reader)
+
+	report := reader.GetRecoveryReport()
+	require.True(t, report.Damaged())
+	require.True(t, report.XrefRebuilt)
+	require.True(t, report.TrailerRebuilt)
+	require.True(t, report.CatalogFound)
+	require.False(t, report.PageTreeRebuilt)
+	require.Equal(t, 3, report.Pages)
+}
+
+func TestRecoveryPageTree(t *testing.T) {
+	data := recoveryTestDocument(t)
+	// Damage the page tree.
+	data = bytes.Replace(data, []byte("/Kids"), []byte("/Kidz"), 1)
+
+	_, err := NewPdfReader(bytes.NewReader(data))
+	require.Error(t, err)
+
+	reader, err := NewPdfReaderWithRecovery(bytes.NewReader(data))
+	require.NoError(t, err)
+	checkRecoveredPages(t, reader)
+
+	report := reader.GetRecoveryReport()
+	require.True(t, report.PageTreeRebuilt)
+	require.False(t, report.XrefRebuilt)
+	require.Equal(t, 3, report.Pages)
+
+	// The document is written with the rebuilt page tree.
+	var buf bytes.Buffer
+	w := NewPdfWriter()
+	for _, page := range reader.PageList {
+		require.NoError(t, w.AddPage(page))
+	}
+	require.NoError(t, w.Write(&buf))
+	reader, err = NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	checkRecoveredPages(t, reader)
+}
+
+func TestRecoveryStreamLength(t *testing.T) {
+	data := recoveryTestDocument(t)
+	// Make the length of the content streams wrong, keeping the offsets of the objects.
+	data = bytes.Replace(data, []byte("/Length 5>>"), []byte("/Length 2>>"), -1)
+	require.Equal(t, 3, bytes.Count(data, []byte("/Length 2>>")))
+
+	reader, err := NewPdfReaderWithRecovery(bytes.NewReader(data))
+	require.NoError(t, err)
+	checkRecoveredPages(t, reader)
+
+	report := reader.GetRecoveryReport()
+	require.Len(t, report.Streams, 3)
+	require.False(t, report.XrefRebuilt)
+	require.Contains(t, report.String(), "Length of stream")
+}
+
+func TestRecoveryLostPage(t *testing.T) {
+	data := recoveryTestDocument(t)
+	// Replace the second page by an invalid object.
+	first := bytes.Index(data, []byte("/Type /Page/"))
+	second := first + 1 + bytes.Index(data[first+1:], []byte("/Type /Page/"))
+	copy(data[second:], "/Type /Junk/")
+
+	reader, err := NewPdfReaderWithRecovery(bytes.NewReader(data))
+	require.NoError(t, err)
+	numPages, err := reader.GetNumPages()
+	require.NoError(t, err)
+	require.Equal(t, 2, numPages)
+
+	report := reader.GetRecoveryReport()
+	require.Equal(t, 1, report.LostPages)
+	require.Equal(t, 2, report.Pages)
+}
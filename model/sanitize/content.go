@@ -0,0 +1,402 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package sanitize
+
+import (
+	"math"
+
+	"github.com/TheLinker/unipdf/v3/common"
+	"github.com/TheLinker/unipdf/v3/contentstream"
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/internal/pageutil"
+	"github.com/TheLinker/unipdf/v3/internal/transform"
+	"github.com/TheLinker/unipdf/v3/model"
+)
+
+// apply returns the point (`x`, `y`) transformed by `m`, i.e. (a*x + c*y + e, b*x + d*y + f) for
+// the matrix [a b c d e f].
+func apply(m transform.Matrix, x, y float64) (float64, float64) {
+	return x*m[0] + y*m[3] + m[6], x*m[1] + y*m[4] + m[7]
+}
+
+// transformRect returns the bounding box of `r` transformed by `m`.
+func transformRect(r transform.Rect, m transform.Matrix) transform.Rect {
+	t := transform.EmptyRect()
+	for _, p := range [][2]float64{{r.Llx, r.Lly}, {r.Urx, r.Lly}, {r.Llx, r.Ury}, {r.Urx, r.Ury}} {
+		t = t.Extend(apply(m, p[0], p[1]))
+	}
+	return t
+}
+
+// textState are the text parameters of the graphics state.
+type textState struct {
+	font     *model.PdfFont
+	size     float64 // Tf
+	charSp   float64 // Tc
+	wordSp   float64 // Tw
+	scale    float64 // Tz / 100
+	leading  float64 // TL
+	rise     float64 // Ts
+	rendMode int     // Tr
+}
+
+// graphicsState are the parameters of the graphics state used by the content filter.
+type graphicsState struct {
+	ctm       transform.Matrix
+	lineWidth float64
+	text      textState
+}
+
+// contentFilter removes the hidden content, the invisible text and the content outside of the
+// crop box from content streams.
+type contentFilter struct {
+	s *sanitizer
+	// resources are the resources of the content, not nil.
+	resources *core.PdfObjectDictionary
+
+	// crop is the visible area of the page in the user space of the content, nil if the content
+	// outside of the crop box is kept.
+	crop *transform.Rect
+
+	// Numbers of operations removed.
+	hidden, invisible, outside int
+}
+
+// filter returns `ops` without the removed operations.
+func (f *contentFilter) filter(ops contentstream.ContentStreamOperations) contentstream.ContentStreamOperations {
+	opts := f.s.opts
+	var out contentstream.ContentStreamOperations
+	gs := graphicsState{ctm: transform.IdentityMatrix(), lineWidth: 1, text: textState{scale: 1}}
+	var stack []graphicsState
+	var tm, tlm transform.Matrix
+
+	// The current path, buffered to be removed with its painting operation.
+	var path contentstream.ContentStreamOperations
+	pathBox := transform.EmptyRect()
+	clip := false
+	// hiddenDepth is the nesting level of the marked content in the hidden content.
+	hiddenDepth := 0
+
+	for _, op := range ops {
+		if hiddenDepth > 0 {
+			switch op.Operand {
+			case "BMC", "BDC":
+				hiddenDepth++
+			case "EMC":
+				hiddenDepth--
+			}
+			continue
+		}
+
+		switch op.Operand {
+		case "BDC":
+			if opts.HiddenLayers && f.hiddenMarkedContent(op) {
+				hiddenDepth = 1
+				f.hidden++
+				continue
+			}
+		case "q":
+			stack = append(stack, gs)
+		case "Q":
+			if len(stack) > 0 {
+				gs = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "cm":
+			if m, ok := transform.NewMatrixFromObjects(op.Params); ok {
+				gs.ctm = gs.ctm.Mult(m)
+			}
+		case "w":
+			if len(op.Params) == 1 {
+				if v, err := core.GetNumberAsFloat(op.Params[0]); err == nil {
+					gs.lineWidth = v
+				}
+			}
+		case "BT":
+			tm, tlm = transform.IdentityMatrix(), transform.IdentityMatrix()
+		case "Tf":
+			if len(op.Params) == 2 {
+				if name, ok := core.GetName(op.Params[0]); ok {
+					gs.text.font = f.font(*name)
+				}
+				gs.text.size, _ = core.GetNumberAsFloat(op.Params[1])
+			}
+		case "Tc", "Tw", "Tz", "TL", "Ts", "Tr":
+			if len(op.Params) == 1 {
+				if v, err := core.GetNumberAsFloat(op.Params[0]); err == nil {
+					gs.text.set(op.Operand, v)
+				}
+			}
+		case "Td", "TD":
+			if params, err := core.GetNumbersAsFloat(op.Params); err == nil && len(params) == 2 {
+				if op.Operand == "TD" {
+					gs.text.leading = -params[1]
+				}
+				tlm = tlm.Mult(transform.TranslationMatrix(params[0], params[1]))
+				tm = tlm
+			}
+		case "Tm":
+			if m, ok := transform.NewMatrixFromObjects(op.Params); ok {
+				tm, tlm = m, m
+			}
+		case "T*":
+			tlm = tlm.Mult(transform.TranslationMatrix(0, -gs.text.leading))
+			tm = tlm
+		case "Tj", "TJ", "'", `"`:
+			if op.Operand == `"` && len(op.Params) == 3 {
+				gs.text.wordSp, _ = core.GetNumberAsFloat(op.Params[0])
+				gs.text.charSp, _ = core.GetNumberAsFloat(op.Params[1])
+			}
+			if op.Operand != "Tj" && op.Operand != "TJ" {
+				tlm = tlm.Mult(transform.TranslationMatrix(0, -gs.text.leading))
+				tm = tlm
+			}
+
+			advance := f.advance(op, gs.text)
+			removed := false
+			if opts.InvisibleText && gs.text.rendMode == 3 {
+				f.invisible++
+				removed = true
+			} else if f.crop != nil && !textBox(gs.ctm.Mult(tm), advance, gs.text).Touches(*f.crop) {
+				f.outside++
+				removed = true
+			}
+			tm = tm.Mult(transform.TranslationMatrix(advance, 0))
+			if removed {
+				out = append(out, textMoveOperations(op, advance, gs.text)...)
+				continue
+			}
+		case "m", "l", "c", "v", "y", "re", "h":
+			if f.crop != nil {
+				path = append(path, op)
+				pathBox = extendPath(pathBox, op, gs.ctm)
+				continue
+			}
+		case "W", "W*":
+			if f.crop != nil {
+				path = append(path, op)
+				clip = true
+				continue
+			}
+		case "S", "s", "f", "F", "f*", "B", "B*", "b", "b*", "n":
+			if f.crop != nil {
+				path = append(path, op)
+				margin := gs.lineWidth*math.Sqrt(math.Abs(gs.ctm[0]*gs.ctm[4]-gs.ctm[1]*gs.ctm[3]))/2 + 1
+				box := pathBox.Expand(margin)
+				// The clipping paths are kept as they hide the following content.
+				if clip || op.Operand != "n" && box.Touches(*f.crop) {
+					out = append(out, path...)
+				} else if op.Operand != "n" {
+					f.outside++
+				}
+				path, pathBox, clip = nil, transform.EmptyRect(), false
+				continue
+			}
+		case "Do":
+			if len(op.Params) != 1 {
+				break
+			}
+			name, ok := core.GetName(op.Params[0])
+			if !ok {
+				break
+			}
+			xobjects, ok := core.GetDict(f.resources.Get("XObject"))
+			if !ok {
+				break
+			}
+			stream, ok := core.GetStream(xobjects.Get(*name))
+			if !ok {
+				break
+			}
+			if opts.HiddenLayers && !f.s.visibility.Visible(stream.Get("OC")) {
+				f.hidden++
+				continue
+			}
+			if f.crop != nil && !transformRect(xobjectBox(stream), gs.ctm).Touches(*f.crop) {
+				f.outside++
+				continue
+			}
+		case "BI":
+			if f.crop != nil && !transformRect(transform.Rect{Urx: 1, Ury: 1}, gs.ctm).Touches(*f.crop) {
+				f.outside++
+				continue
+			}
+		}
+		out = append(out, op)
+	}
+	return append(out, path...)
+}
+
+// set sets the text parameter of the operator `operand` to `v`.
+func (t *textState) set(operand string, v float64) {
+	switch operand {
+	case "Tc":
+		t.charSp = v
+	case "Tw":
+		t.wordSp = v
+	case "Tz":
+		t.scale = v / 100
+	case "TL":
+		t.leading = v
+	case "Ts":
+		t.rise = v
+	case "Tr":
+		t.rendMode = int(v)
+	}
+}
+
+// hiddenMarkedContent returns true if the marked content started by the BDC operation `op` is
+// optional content hidden by default.
+func (f *contentFilter) hiddenMarkedContent(op *contentstream.ContentStreamOperation) bool {
+	if len(op.Params) != 2 {
+		return false
+	}
+	if tag, ok := core.GetName(op.Params[0]); !ok || *tag != "OC" {
+		return false
+	}
+	return !f.s.visibility.Visible(pageutil.Properties(op.Params[1], f.resources))
+}
+
+// font returns the font `name` of the resources, nil if not found.
+func (f *contentFilter) font(name core.PdfObjectName) *model.PdfFont {
+	fonts, ok := core.GetDict(f.resources.Get("Font"))
+	if !ok || fonts.Get(name) == nil {
+		return nil
+	}
+	obj := core.ResolveReference(fonts.Get(name))
+	if font, ok := f.s.fonts[obj]; ok {
+		return font
+	}
+	font, err := model.NewPdfFontFromPdfObject(obj)
+	if err != nil {
+		common.Log.Debug("ERROR: unable to load font %s: %v", name, err)
+	}
+	f.s.fonts[obj] = font
+	return font
+}
+
+// advance returns the horizontal displacement in text space of the text shown by `op`.
+func (f *contentFilter) advance(op *contentstream.ContentStreamOperation, ts textState) float64 {
+	var params []core.PdfObject
+	switch op.Operand {
+	case "TJ":
+		if len(op.Params) == 1 {
+			arr, _ := core.GetArray(op.Params[0])
+			params = arr.Elements()
+		}
+	default:
+		if len(op.Params) > 0 {
+			params = op.Params[len(op.Params)-1:]
+		}
+	}
+
+	var tx float64
+	for _, param := range params {
+		if v, err := core.GetNumberAsFloat(param); err == nil {
+			tx -= v / 1000 * ts.size
+			continue
+		}
+		data, ok := core.GetStringBytes(param)
+		if !ok {
+			continue
+		}
+		if ts.font == nil {
+			// Without the font the widths of the glyphs are approximated.
+			for _, b := range data {
+				tx += 0.5*ts.size + ts.charSp
+				if b == ' ' {
+					tx += ts.wordSp
+				}
+			}
+			continue
+		}
+		for _, code := range ts.font.BytesToCharcodes(data) {
+			metrics, _ := ts.font.GetCharMetrics(code)
+			tx += metrics.Wx/1000*ts.size + ts.charSp
+			if code == ' ' && !ts.font.IsCID() {
+				tx += ts.wordSp
+			}
+		}
+	}
+	return tx * ts.scale
+}
+
+// textMoveOperations returns the operations replacing the text showing operation `op`, which
+// moves the text position like `op` without showing the text.
+func textMoveOperations(op *contentstream.ContentStreamOperation, advance float64, ts textState) contentstream.ContentStreamOperations {
+	var ops contentstream.ContentStreamOperations
+	switch op.Operand {
+	case "'":
+		ops = append(ops, &contentstream.ContentStreamOperation{Operand: "T*"})
+	case `"`:
+		if len(op.Params) == 3 {
+			ops = append(ops,
+				&contentstream.ContentStreamOperation{Operand: "Tw", Params: op.Params[:1]},
+				&contentstream.ContentStreamOperation{Operand: "Tc", Params: op.Params[1:2]})
+		}
+		ops = append(ops, &contentstream.ContentStreamOperation{Operand: "T*"})
+	}
+	if advance != 0 && ts.size*ts.scale != 0 {
+		adjustment := core.MakeArray(core.MakeFloat(-advance * 1000 / (ts.size * ts.scale)))
+		ops = append(ops, &contentstream.ContentStreamOperation{Operand: "TJ", Params: []core.PdfObject{adjustment}})
+	}
+	return ops
+}
+
+// textBox returns the bounding box in user space of a text `advance` wide shown with the text
+// rendering matrix `m`. The box is larger than the glyphs as their bounding boxes are unknown.
+func textBox(m transform.Matrix, advance float64, ts textState) transform.Rect {
+	size := math.Abs(ts.size)
+	box := transform.Rect{
+		Llx: math.Min(0, advance) - size,
+		Lly: ts.rise - size,
+		Urx: math.Max(0, advance) + size,
+		Ury: ts.rise + 2*size,
+	}
+	return transformRect(box, m)
+}
+
+// extendPath returns `box` extended by the points of the path construction operation `op` in
+// user space.
+func extendPath(box transform.Rect, op *contentstream.ContentStreamOperation,
+	ctm transform.Matrix) transform.Rect {
+	params, err := core.GetNumbersAsFloat(op.Params)
+	if err != nil {
+		return box
+	}
+	if op.Operand == "re" {
+		if len(params) == 4 {
+			x, y, w, h := params[0], params[1], params[2], params[3]
+			r := transform.Rect{Llx: x, Lly: y, Urx: x + w, Ury: y + h}
+			box = box.Union(transformRect(r, ctm))
+		}
+		return box
+	}
+	for i := 0; i+1 < len(params); i += 2 {
+		box = box.Extend(apply(ctm, params[i], params[i+1]))
+	}
+	return box
+}
+
+// xobjectBox returns the bounding box of the XObject `stream` in the space of the content
+// drawing it.
+func xobjectBox(stream *core.PdfObjectStream) transform.Rect {
+	if subtype, _ := core.GetNameVal(stream.Get("Subtype")); subtype != "Form" {
+		return transform.Rect{Urx: 1, Ury: 1}
+	}
+	bboxArr, _ := core.GetArray(stream.Get("BBox"))
+	bbox, err := core.GetNumbersAsFloat(bboxArr.Elements())
+	if err != nil || len(bbox) != 4 {
+		// Without a bounding box the form is considered visible.
+		return transform.Rect{Llx: math.Inf(-1), Lly: math.Inf(-1), Urx: math.Inf(1), Ury: math.Inf(1)}
+	}
+	box := transform.EmptyRect().Extend(bbox[0], bbox[1]).Extend(bbox[2], bbox[3])
+	matrix, _ := core.GetArray(stream.Get("Matrix"))
+	if m, ok := transform.NewMatrixFromObjects(matrix.Elements()); ok {
+		box = transformRect(box, m)
+	}
+	return box
+}
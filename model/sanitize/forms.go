@@ -0,0 +1,91 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package sanitize
+
+import (
+	"fmt"
+
+	"github.com/TheLinker/unipdf/v3/core"
+)
+
+// Field flag of the push buttons.
+const pushButtonFlag = 1 << 16
+
+// sanitizeAcroForm removes the values of the form fields and the XFA forms of the interactive form
+// dictionary `acroForm`.
+func (s *sanitizer) sanitizeAcroForm(acroForm *core.PdfObjectDictionary) {
+	if (s.opts.FormData || s.opts.JavaScript) && acroForm.Get("XFA") != nil {
+		acroForm.Remove("XFA")
+		category := CategoryFormData
+		if !s.opts.FormData {
+			category = CategoryJavaScript
+		}
+		s.report.add(category, 0, "XFA form")
+	}
+	if !s.opts.FormData {
+		return
+	}
+
+	needAppearances := false
+	visited := make(map[*core.PdfObjectDictionary]bool)
+	var walk func(obj core.PdfObject, parentName, fieldType string, flags int64)
+	walk = func(obj core.PdfObject, parentName, fieldType string, flags int64) {
+		field, ok := core.GetDict(obj)
+		if !ok || visited[field] {
+			return
+		}
+		visited[field] = true
+
+		name := parentName
+		if partial, ok := core.GetStringVal(field.Get("T")); ok {
+			if name != "" {
+				name += "."
+			}
+			name += partial
+		}
+		if ft, ok := core.GetNameVal(field.Get("FT")); ok {
+			fieldType = ft
+		}
+		if ff, ok := core.GetIntVal(field.Get("Ff")); ok {
+			flags = int64(ff)
+		}
+
+		removed := false
+		for _, key := range []core.PdfObjectName{"V", "RV", "I"} {
+			if field.Get(key) != nil {
+				field.Remove(key)
+				removed = true
+			}
+		}
+		if removed {
+			s.report.add(CategoryFormData, 0, fmt.Sprintf("field %q value", name))
+		}
+
+		switch {
+		case fieldType == "Btn" && flags&pushButtonFlag == 0:
+			if field.Get("AS") != nil {
+				field.Set("AS", core.MakeName("Off"))
+			}
+		case fieldType == "Tx" || fieldType == "Ch" || fieldType == "Sig":
+			if field.Get("AP") != nil {
+				field.Remove("AP")
+				needAppearances = true
+			}
+		}
+
+		kids, _ := core.GetArray(field.Get("Kids"))
+		for _, kid := range kids.Elements() {
+			walk(kid, name, fieldType, flags)
+		}
+	}
+	fields, _ := core.GetArray(acroForm.Get("Fields"))
+	for _, field := range fields.Elements() {
+		walk(field, "", "", 0)
+	}
+	if needAppearances {
+		acroForm.Set("NeedAppearances", core.MakeBool(true))
+	}
+}
@@ -0,0 +1,24 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package sanitize
+
+import (
+	"sort"
+
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/internal/optcontent"
+)
+
+// hiddenGroupNames returns the sorted names of the optional content groups hidden by `v`.
+func hiddenGroupNames(v *optcontent.Visibility) []string {
+	var names []string
+	for _, group := range v.HiddenGroups() {
+		name, _ := core.GetStringVal(group.Get("Name"))
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,111 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package sanitize
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Category is a category of hidden information removed by the sanitizer.
+type Category int
+
+// Categories of removed information.
+const (
+	// CategoryMetadata is the document information dictionary, the XMP metadata streams and the
+	// page piece dictionaries.
+	CategoryMetadata Category = iota
+	// CategoryRevisions are the previous revisions of the incrementally updated documents.
+	CategoryRevisions
+	// CategoryAnnotations are the comments, the markup annotations and their popups.
+	CategoryAnnotations
+	// CategoryHiddenLayers is the content of the optional content groups hidden by default.
+	CategoryHiddenLayers
+	// CategoryEmbeddedFiles are the embedded files, the associated files and the file attachment
+	// annotations.
+	CategoryEmbeddedFiles
+	// CategoryJavaScript are the document level scripts and the JavaScript actions.
+	CategoryJavaScript
+	// CategoryFormData are the values of the form fields.
+	CategoryFormData
+	// CategoryInvisibleText is the text drawn with the invisible text rendering mode.
+	CategoryInvisibleText
+	// CategoryOutsideCropBox is the content drawn outside the visible area of the pages.
+	CategoryOutsideCropBox
+	// CategoryUnreferencedObjects are the objects not used by the document.
+	CategoryUnreferencedObjects
+)
+
+// String returns the name of the category.
+func (c Category) String() string {
+	switch c {
+	case CategoryMetadata:
+		return "metadata"
+	case CategoryRevisions:
+		return "previous revisions"
+	case CategoryAnnotations:
+		return "annotations"
+	case CategoryHiddenLayers:
+		return "hidden layers"
+	case CategoryEmbeddedFiles:
+		return "embedded files"
+	case CategoryJavaScript:
+		return "JavaScript"
+	case CategoryFormData:
+		return "form data"
+	case CategoryInvisibleText:
+		return "invisible text"
+	case CategoryOutsideCropBox:
+		return "content outside the crop box"
+	case CategoryUnreferencedObjects:
+		return "unreferenced objects"
+	}
+	return fmt.Sprintf("category %d", int(c))
+}
+
+// Removal is an item removed by the sanitizer.
+type Removal struct {
+	Category Category
+
+	// Page is the number of the page of the item, 0 for the document level items.
+	Page int
+
+	// Description describes the item, e.g. "Text annotation" or "/Author".
+	Description string
+}
+
+// Report lists the items removed by the sanitizer.
+type Report struct {
+	Removed []Removal
+}
+
+// add adds the item `description` of `category` on the page `page` to the report.
+func (r *Report) add(category Category, page int, description string) {
+	r.Removed = append(r.Removed, Removal{Category: category, Page: page, Description: description})
+}
+
+// Count returns the number of items of `category` removed.
+func (r *Report) Count(category Category) int {
+	count := 0
+	for _, removal := range r.Removed {
+		if removal.Category == category {
+			count++
+		}
+	}
+	return count
+}
+
+// String returns a human readable description of the report.
+func (r *Report) String() string {
+	var buf bytes.Buffer
+	for _, removal := range r.Removed {
+		if removal.Page > 0 {
+			fmt.Fprintf(&buf, "page %d: ", removal.Page)
+		}
+		fmt.Fprintf(&buf, "%s: %s\n", removal.Category, removal.Description)
+	}
+	return buf.String()
+}
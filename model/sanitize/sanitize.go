@@ -0,0 +1,857 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+/*
+Package sanitize removes the hidden information from documents before they are
+shared: the metadata, the previous revisions, the comments, the hidden layers,
+the embedded files, the scripts, the form data, the invisible text, the content
+outside of the visible area of the pages and the unreferenced objects.
+
+The sanitized document is a complete rewrite of the input with PdfWriter: only
+the pages and the document level structures which are kept are written, so
+the previous revisions of incrementally updated documents and the objects not
+used by the document are always removed. The other categories are removed
+according to the Options. The Report lists the removed items.
+
+Example:
+
+	report, err := sanitize.Sanitize(input, output, sanitize.DefaultOptions())
+	if err != nil {
+		return err
+	}
+	fmt.Print(report)
+
+The digital signatures are invalidated by the rewrite: the signature values are
+removed with the form data.
+*/
+package sanitize
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/TheLinker/unipdf/v3/common"
+	"github.com/TheLinker/unipdf/v3/contentstream"
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/internal/optcontent"
+	"github.com/TheLinker/unipdf/v3/internal/transform"
+	"github.com/TheLinker/unipdf/v3/model"
+)
+
+// Options select the categories of information removed by Sanitize.
+type Options struct {
+	// Metadata removes the document information dictionary, the XMP metadata streams, the page
+	// piece dictionaries and the page modification dates.
+	Metadata bool
+	// Annotations removes the comments: the markup annotations and their popups. The links and
+	// the form field widgets are kept.
+	Annotations bool
+	// HiddenLayers removes the content of the optional content groups hidden in the default
+	// configuration and the optional content properties. The visible content is kept.
+	HiddenLayers bool
+	// EmbeddedFiles removes the embedded files, the associated files, the collection and the
+	// file attachment annotations.
+	EmbeddedFiles bool
+	// JavaScript removes the document level scripts, the JavaScript actions and the XFA forms.
+	JavaScript bool
+	// FormData removes the values of the form fields, including the signatures, and the XFA
+	// forms. The field appearances showing the values are removed.
+	FormData bool
+	// InvisibleText removes the text drawn with the invisible rendering mode, such as the OCR
+	// text of the scanned pages.
+	InvisibleText bool
+	// OutsideCropBox removes the content drawn outside the crop box of the pages and reduces
+	// their media box to their crop box.
+	OutsideCropBox bool
+}
+
+// DefaultOptions returns the options removing all the categories of hidden information.
+func DefaultOptions() Options {
+	return Options{
+		Metadata:       true,
+		Annotations:    true,
+		HiddenLayers:   true,
+		EmbeddedFiles:  true,
+		JavaScript:     true,
+		FormData:       true,
+		InvisibleText:  true,
+		OutsideCropBox: true,
+	}
+}
+
+// Catalog entries copied to the sanitized document. The entries of the removed categories are
+// removed from the copy.
+var catalogEntries = []core.PdfObjectName{
+	"PageLabels", "Names", "Dests", "ViewerPreferences", "PageLayout", "PageMode", "Outlines",
+	"Threads", "OpenAction", "AA", "URI", "AcroForm", "Metadata", "StructTreeRoot", "MarkInfo",
+	"Lang", "OCProperties", "OutputIntents", "PieceInfo", "Extensions", "Collection", "AF",
+}
+
+// Subtypes of the comment annotations.
+var commentSubtypes = map[string]bool{
+	"Text": true, "FreeText": true, "Line": true, "Square": true, "Circle": true, "Polygon": true,
+	"PolyLine": true, "Highlight": true, "Underline": true, "Squiggly": true, "StrikeOut": true,
+	"Caret": true, "Stamp": true, "Ink": true, "Popup": true, "Sound": true, "Redact": true,
+	"Projection": true,
+}
+
+// sanitizer holds the state of the sanitization of a document.
+type sanitizer struct {
+	opts   Options
+	report *Report
+
+	visibility *optcontent.Visibility
+	fonts      map[core.PdfObject]*model.PdfFont
+
+	// page is the number of the page being sanitized, 0 for the document level objects.
+	page int
+
+	// removed are the dictionaries removed from the document, whose references are pruned.
+	removed map[*core.PdfObjectDictionary]bool
+	// visited are the objects already sanitized.
+	visited map[core.PdfObject]bool
+}
+
+// Sanitize writes to `w` the document read from `rs` without the hidden information selected
+// by `opts`, and returns the report of the removed information. Encrypted documents are
+// decrypted with an empty password and written without encryption.
+func Sanitize(rs io.ReadSeeker, w io.Writer, opts Options) (*Report, error) {
+	s := &sanitizer{
+		opts:    opts,
+		report:  &Report{},
+		fonts:   make(map[core.PdfObject]*model.PdfFont),
+		removed: make(map[*core.PdfObjectDictionary]bool),
+		visited: make(map[core.PdfObject]bool),
+	}
+
+	revisions, err := countRevisions(rs)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := model.NewPdfReader(rs)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := reader.IsEncrypted()
+	if err != nil {
+		return nil, err
+	}
+	if encrypted {
+		ok, err := reader.Decrypt(nil)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, errors.New("unable to decrypt the document with an empty password")
+		}
+	}
+
+	for i := 1; i < revisions; i++ {
+		s.report.add(CategoryRevisions, 0, fmt.Sprintf("revision %d", i))
+	}
+	s.removeUnreferenced(reader)
+
+	doc, err := reader.GetStandardDocument()
+	if err != nil {
+		return nil, err
+	}
+	if err := core.ResolveReferencesDeep(doc.Catalog, nil); err != nil {
+		return nil, err
+	}
+	if s.opts.HiddenLayers {
+		ocProperties, _ := core.GetDict(doc.Catalog.Get("OCProperties"))
+		s.visibility = optcontent.NewVisibility(ocProperties)
+	}
+
+	numPages, err := reader.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+	pages := make([]*model.PdfPage, numPages)
+	for i := range pages {
+		if pages[i], err = reader.GetPage(i + 1); err != nil {
+			return nil, err
+		}
+		// The pages are sanitized through their models.
+		s.visited[pages[i].GetPageDict()] = true
+	}
+	for i, page := range pages {
+		s.page = i + 1
+		if err := s.sanitizePage(page); err != nil {
+			return nil, err
+		}
+	}
+	s.page = 0
+
+	entries := s.sanitizeCatalog(doc.Catalog)
+	var info *core.PdfObjectDictionary
+	if !s.opts.Metadata {
+		info = doc.Info
+	} else if doc.Info != nil {
+		for _, key := range doc.Info.Keys() {
+			s.report.add(CategoryMetadata, 0, fmt.Sprintf("document information /%s", key))
+		}
+	}
+
+	// Remove the references to the removed objects.
+	pruned := make(map[core.PdfObject]bool)
+	for _, page := range pages {
+		s.prune(page.GetPageDict(), "", pruned)
+	}
+	for _, key := range entries.Keys() {
+		s.prune(entries.Get(key), key, pruned)
+	}
+
+	writer := model.NewPdfWriter()
+	version := reader.PdfVersion()
+	writer.SetVersion(version.Major, version.Minor)
+	for _, page := range pages {
+		if err := writer.AddPage(page); err != nil {
+			return nil, err
+		}
+	}
+	writer.SetStandard(&catalogCopier{entries: entries, info: info})
+	if err := writer.Write(w); err != nil {
+		return nil, err
+	}
+	return s.report, nil
+}
+
+// countRevisions returns the number of revisions of the document `rs`: the number of its end of
+// file markers, the first page section of the linearized documents excepted.
+func countRevisions(rs io.ReadSeeker) (int, error) {
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	data, err := ioutil.ReadAll(rs)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	count := bytes.Count(data, []byte("%%EOF"))
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	if count > 1 && bytes.Contains(head, []byte("/Linearized")) {
+		count--
+	}
+	return count, nil
+}
+
+// removeUnreferenced reports the objects of the document of `reader` which are not referenced
+// from its trailer.
+func (s *sanitizer) removeUnreferenced(reader *model.PdfReader) {
+	trailer, err := reader.GetTrailer()
+	if err != nil {
+		return
+	}
+	used := make(map[int64]bool)
+	visited := make(map[core.PdfObject]bool)
+	var walk func(obj core.PdfObject)
+	walk = func(obj core.PdfObject) {
+		if visited[obj] {
+			return
+		}
+		visited[obj] = true
+		switch t := obj.(type) {
+		case *core.PdfObjectReference:
+			if used[t.ObjectNumber] {
+				return
+			}
+			used[t.ObjectNumber] = true
+			if o, err := reader.GetIndirectObjectByNumber(int(t.ObjectNumber)); err == nil {
+				walk(o)
+			}
+		case *core.PdfIndirectObject:
+			used[t.ObjectNumber] = true
+			walk(t.PdfObject)
+		case *core.PdfObjectStream:
+			used[t.ObjectNumber] = true
+			walk(t.PdfObjectDictionary)
+		case *core.PdfObjectDictionary:
+			for _, key := range t.Keys() {
+				walk(t.Get(key))
+			}
+		case *core.PdfObjectArray:
+			for _, o := range t.Elements() {
+				walk(o)
+			}
+		}
+	}
+	walk(trailer)
+
+	for _, num := range reader.GetObjectNums() {
+		if used[int64(num)] {
+			continue
+		}
+		obj, err := reader.GetIndirectObjectByNumber(num)
+		if err != nil {
+			continue
+		}
+		if stream, ok := obj.(*core.PdfObjectStream); ok {
+			// The object streams and the cross reference streams are not referenced.
+			if typ, _ := core.GetNameVal(stream.Get("Type")); typ == "ObjStm" || typ == "XRef" {
+				continue
+			}
+		}
+		s.report.add(CategoryUnreferencedObjects, 0, fmt.Sprintf("object %d", num))
+	}
+}
+
+// sanitizePage sanitizes `page`.
+func (s *sanitizer) sanitizePage(page *model.PdfPage) error {
+	if s.opts.Metadata {
+		if page.Metadata != nil {
+			page.Metadata = nil
+			s.report.add(CategoryMetadata, s.page, "XMP metadata")
+		}
+		if page.PieceInfo != nil {
+			page.PieceInfo = nil
+			s.report.add(CategoryMetadata, s.page, "page-piece dictionary")
+		}
+		if page.LastModified != nil {
+			page.LastModified = nil
+			s.report.add(CategoryMetadata, s.page, "modification date")
+		}
+	}
+	if s.opts.EmbeddedFiles && page.AF != nil {
+		page.AF = nil
+		s.report.add(CategoryEmbeddedFiles, s.page, "associated files")
+	}
+	if s.opts.JavaScript {
+		if aa, ok := core.GetDict(page.AA); ok {
+			s.removeAdditionalActions(aa)
+			if len(aa.Keys()) == 0 {
+				page.AA = nil
+			}
+		}
+	}
+	s.sanitizeAnnotations(page)
+
+	resources := core.MakeDict()
+	if page.Resources != nil {
+		resources, _ = core.GetDict(page.Resources.ToPdfObject())
+	}
+	if err := s.sanitizeContent(page, resources); err != nil {
+		return err
+	}
+	if err := s.sanitizeResources(resources); err != nil {
+		return err
+	}
+
+	dict := page.GetPageDict()
+	for _, key := range dict.Keys() {
+		if key != "Parent" {
+			s.sanitizeObject(dict.Get(key))
+		}
+	}
+	return nil
+}
+
+// sanitizeAnnotations removes the annotations of `page` of the removed categories.
+func (s *sanitizer) sanitizeAnnotations(page *model.PdfPage) {
+	annots, ok := core.GetArray(page.Annots)
+	if !ok {
+		return
+	}
+	kept := core.MakeArray()
+	for _, obj := range annots.Elements() {
+		annot, ok := core.GetDict(obj)
+		if !ok {
+			continue
+		}
+		subtype, _ := core.GetNameVal(annot.Get("Subtype"))
+		description := fmt.Sprintf("%s annotation", subtype)
+		switch {
+		case s.opts.Annotations && commentSubtypes[subtype]:
+			s.removeDict(annot, CategoryAnnotations, description)
+		case s.opts.EmbeddedFiles && subtype == "FileAttachment":
+			s.removeDict(annot, CategoryEmbeddedFiles, description)
+		case s.opts.HiddenLayers && !s.visibility.Visible(annot.Get("OC")):
+			s.removeDict(annot, CategoryHiddenLayers, description)
+		default:
+			kept.Append(obj)
+		}
+	}
+	if kept.Len() == 0 {
+		page.Annots = nil
+	} else {
+		page.Annots = kept
+	}
+}
+
+// removeDict removes the dictionary `dict` of `category` from the document.
+func (s *sanitizer) removeDict(dict *core.PdfObjectDictionary, category Category, description string) {
+	s.removed[dict] = true
+	s.report.add(category, s.page, description)
+}
+
+// sanitizeContent removes the hidden content, the invisible text and the content outside the crop
+// box from the content streams of `page` with the resources `resources`.
+func (s *sanitizer) sanitizeContent(page *model.PdfPage, resources *core.PdfObjectDictionary) error {
+	if !s.opts.HiddenLayers && !s.opts.InvisibleText && !s.opts.OutsideCropBox {
+		return nil
+	}
+	content, err := page.GetAllContentStreams()
+	if err != nil {
+		return err
+	}
+	ops, err := contentstream.NewContentStreamParser(content).Parse()
+	if err != nil {
+		return err
+	}
+
+	f := &contentFilter{s: s, resources: resources}
+	if s.opts.OutsideCropBox {
+		mbox, err := page.GetMediaBox()
+		if err != nil {
+			return err
+		}
+		box := *mbox
+		if page.CropBox != nil {
+			box = intersectBoxes(box, *page.CropBox)
+		}
+		f.crop = &transform.Rect{Llx: box.Llx, Lly: box.Lly, Urx: box.Urx, Ury: box.Ury}
+		if box != *mbox {
+			page.MediaBox = &box
+			page.CropBox = nil
+			for _, b := range []**model.PdfRectangle{&page.BleedBox, &page.TrimBox, &page.ArtBox} {
+				if *b != nil {
+					clipped := intersectBoxes(**b, box)
+					*b = &clipped
+				}
+			}
+			s.report.add(CategoryOutsideCropBox, s.page, "media box reduced to the crop box")
+		}
+	}
+
+	filtered := f.filter(*ops)
+	if f.hidden+f.invisible+f.outside == 0 {
+		return nil
+	}
+	s.reportOperations(f)
+	return page.SetContentStreams([]string{string(filtered.Bytes())}, core.NewFlateEncoder())
+}
+
+// reportOperations reports the operations removed by the content filter `f`.
+func (s *sanitizer) reportOperations(f *contentFilter) {
+	if f.hidden > 0 {
+		s.report.add(CategoryHiddenLayers, s.page, fmt.Sprintf("%d hidden content operations", f.hidden))
+	}
+	if f.invisible > 0 {
+		s.report.add(CategoryInvisibleText, s.page, fmt.Sprintf("%d invisible text operations", f.invisible))
+	}
+	if f.outside > 0 {
+		s.report.add(CategoryOutsideCropBox, s.page, fmt.Sprintf("%d operations outside the crop box", f.outside))
+	}
+}
+
+// intersectBoxes returns the intersection of the boxes `a` and `b`, `a` if they do not intersect.
+func intersectBoxes(a, b model.PdfRectangle) model.PdfRectangle {
+	a, b = normalizeBox(a), normalizeBox(b)
+	c := model.PdfRectangle{
+		Llx: maxFloat(a.Llx, b.Llx), Lly: maxFloat(a.Lly, b.Lly),
+		Urx: minFloat(a.Urx, b.Urx), Ury: minFloat(a.Ury, b.Ury),
+	}
+	if c.Llx >= c.Urx || c.Lly >= c.Ury {
+		return a
+	}
+	return c
+}
+
+// normalizeBox returns the box `b` with its lower left corner before its upper right corner.
+func normalizeBox(b model.PdfRectangle) model.PdfRectangle {
+	return model.PdfRectangle{
+		Llx: minFloat(b.Llx, b.Urx), Lly: minFloat(b.Lly, b.Ury),
+		Urx: maxFloat(b.Llx, b.Urx), Ury: maxFloat(b.Lly, b.Ury),
+	}
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// sanitizeResources removes the hidden XObjects of `resources` and sanitizes the content of the
+// form XObjects.
+func (s *sanitizer) sanitizeResources(resources *core.PdfObjectDictionary) error {
+	if resources == nil || s.visited[resources] {
+		return nil
+	}
+	s.visited[resources] = true
+	xobjects, ok := core.GetDict(resources.Get("XObject"))
+	if !ok {
+		return nil
+	}
+	for _, name := range append([]core.PdfObjectName(nil), xobjects.Keys()...) {
+		stream, ok := core.GetStream(xobjects.Get(name))
+		if !ok {
+			continue
+		}
+		if s.opts.HiddenLayers && !s.visibility.Visible(stream.Get("OC")) {
+			xobjects.Remove(name)
+			s.report.add(CategoryHiddenLayers, s.page, fmt.Sprintf("XObject /%s", name))
+			continue
+		}
+		if subtype, _ := core.GetNameVal(stream.Get("Subtype")); subtype != "Form" || s.visited[stream] {
+			continue
+		}
+		s.visited[stream] = true
+		formResources, ok := core.GetDict(stream.Get("Resources"))
+		if !ok {
+			formResources = resources
+		}
+		if err := s.sanitizeForm(stream, formResources); err != nil {
+			return err
+		}
+		if err := s.sanitizeResources(formResources); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sanitizeForm removes the hidden content and the invisible text of the form XObject `stream`
+// with the resources `resources`.
+func (s *sanitizer) sanitizeForm(stream *core.PdfObjectStream, resources *core.PdfObjectDictionary) error {
+	if !s.opts.HiddenLayers && !s.opts.InvisibleText {
+		return nil
+	}
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: unable to decode form XObject: %v", err)
+		return nil
+	}
+	ops, err := contentstream.NewContentStreamParser(string(data)).Parse()
+	if err != nil {
+		return err
+	}
+	f := &contentFilter{s: s, resources: resources}
+	filtered := f.filter(*ops)
+	if f.hidden+f.invisible == 0 {
+		return nil
+	}
+	s.reportOperations(f)
+
+	encoder := core.NewFlateEncoder()
+	encoded, err := encoder.EncodeBytes(filtered.Bytes())
+	if err != nil {
+		return err
+	}
+	stream.Stream = encoded
+	stream.Remove("DecodeParms")
+	stream.Set("Filter", core.MakeName(encoder.GetFilterName()))
+	stream.Set("Length", core.MakeInteger(int64(len(encoded))))
+	return nil
+}
+
+// sanitizeObject removes the information of the removed categories from `obj` and the objects it
+// references.
+func (s *sanitizer) sanitizeObject(obj core.PdfObject) {
+	obj = core.ResolveReference(obj)
+	if obj == nil || s.visited[obj] {
+		return
+	}
+	s.visited[obj] = true
+
+	var dict *core.PdfObjectDictionary
+	switch t := obj.(type) {
+	case *core.PdfIndirectObject:
+		s.sanitizeObject(t.PdfObject)
+		return
+	case *core.PdfObjectArray:
+		for _, o := range t.Elements() {
+			s.sanitizeObject(o)
+		}
+		return
+	case *core.PdfObjectStream:
+		dict = t.PdfObjectDictionary
+	case *core.PdfObjectDictionary:
+		dict = t
+	default:
+		return
+	}
+	if s.visited[dict] && dict != obj {
+		return
+	}
+	s.visited[dict] = true
+	s.sanitizeDict(dict)
+	for _, key := range dict.Keys() {
+		if key != "Parent" {
+			s.sanitizeObject(dict.Get(key))
+		}
+	}
+}
+
+// sanitizeDict removes the entries of the removed categories from `dict`.
+func (s *sanitizer) sanitizeDict(dict *core.PdfObjectDictionary) {
+	if s.opts.Metadata {
+		if dict.Get("Metadata") != nil {
+			dict.Remove("Metadata")
+			s.report.add(CategoryMetadata, s.page, "XMP metadata")
+		}
+		if dict.Get("PieceInfo") != nil {
+			dict.Remove("PieceInfo")
+			s.report.add(CategoryMetadata, s.page, "page-piece dictionary")
+		}
+	}
+	if s.opts.EmbeddedFiles {
+		if dict.Get("AF") != nil {
+			dict.Remove("AF")
+			s.report.add(CategoryEmbeddedFiles, s.page, "associated files")
+		}
+		if dict.Get("EF") != nil {
+			dict.Remove("EF")
+			name, _ := core.GetStringVal(dict.Get("UF"))
+			if name == "" {
+				name, _ = core.GetStringVal(dict.Get("F"))
+			}
+			s.report.add(CategoryEmbeddedFiles, s.page, fmt.Sprintf("embedded file %q", name))
+		}
+	}
+	if s.opts.HiddenLayers {
+		dict.Remove("OC")
+	}
+	if s.opts.JavaScript {
+		s.removeJavaScript(dict)
+	}
+}
+
+// isJavaScriptAction returns true if `obj` is a JavaScript action.
+func isJavaScriptAction(obj core.PdfObject) bool {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return false
+	}
+	s, _ := core.GetNameVal(dict.Get("S"))
+	return s == "JavaScript"
+}
+
+// removeJavaScript removes the JavaScript actions of the action entries of `dict`.
+func (s *sanitizer) removeJavaScript(dict *core.PdfObjectDictionary) {
+	for _, key := range []core.PdfObjectName{"A", "OpenAction", "Next"} {
+		obj := dict.Get(key)
+		if isJavaScriptAction(obj) {
+			dict.Remove(key)
+			s.report.add(CategoryJavaScript, s.page, fmt.Sprintf("JavaScript action /%s", key))
+			continue
+		}
+		if arr, ok := core.GetArray(obj); ok && key == "Next" {
+			kept := core.MakeArray()
+			for _, action := range arr.Elements() {
+				if isJavaScriptAction(action) {
+					s.report.add(CategoryJavaScript, s.page, "JavaScript action /Next")
+					continue
+				}
+				kept.Append(action)
+			}
+			dict.Set(key, kept)
+		}
+	}
+	if aa, ok := core.GetDict(dict.Get("AA")); ok {
+		s.removeAdditionalActions(aa)
+		if len(aa.Keys()) == 0 {
+			dict.Remove("AA")
+		}
+	}
+	if action, _ := core.GetNameVal(dict.Get("S")); action == "Rendition" && dict.Get("JS") != nil {
+		dict.Remove("JS")
+		s.report.add(CategoryJavaScript, s.page, "rendition action script")
+	}
+}
+
+// removeAdditionalActions removes the JavaScript actions of the additional actions `aa`.
+func (s *sanitizer) removeAdditionalActions(aa *core.PdfObjectDictionary) {
+	for _, key := range append([]core.PdfObjectName(nil), aa.Keys()...) {
+		if isJavaScriptAction(aa.Get(key)) {
+			aa.Remove(key)
+			s.report.add(CategoryJavaScript, s.page, fmt.Sprintf("JavaScript additional action /%s", key))
+		}
+	}
+}
+
+// sanitizeCatalog returns the sanitized entries of the document catalog `catalog` copied to the
+// sanitized document.
+func (s *sanitizer) sanitizeCatalog(catalog *core.PdfObjectDictionary) *core.PdfObjectDictionary {
+	entries := core.MakeDict()
+	for _, key := range catalogEntries {
+		if obj := catalog.Get(key); obj != nil {
+			entries.Set(key, obj)
+		}
+	}
+
+	if s.opts.Metadata {
+		for _, key := range []core.PdfObjectName{"Metadata", "PieceInfo"} {
+			if entries.Get(key) != nil {
+				entries.Remove(key)
+				s.report.add(CategoryMetadata, 0, fmt.Sprintf("catalog /%s", key))
+			}
+		}
+	}
+	if s.opts.HiddenLayers && s.visibility != nil {
+		entries.Remove("OCProperties")
+		for _, name := range hiddenGroupNames(s.visibility) {
+			s.report.add(CategoryHiddenLayers, 0, fmt.Sprintf("layer %q", name))
+		}
+	}
+	if s.opts.EmbeddedFiles && entries.Get("Collection") != nil {
+		entries.Remove("Collection")
+		s.report.add(CategoryEmbeddedFiles, 0, "portable collection")
+	}
+
+	if names, ok := core.GetDict(entries.Get("Names")); ok {
+		// The name dictionary is copied to keep the names of the removed categories in the
+		// input document.
+		copied := core.MakeDict()
+		for _, key := range names.Keys() {
+			copied.Set(key, names.Get(key))
+		}
+		if s.opts.JavaScript && copied.Get("JavaScript") != nil {
+			for _, name := range nameTreeNames(copied.Get("JavaScript")) {
+				s.report.add(CategoryJavaScript, 0, fmt.Sprintf("document JavaScript %q", name))
+			}
+			copied.Remove("JavaScript")
+		}
+		if s.opts.EmbeddedFiles && copied.Get("EmbeddedFiles") != nil {
+			for _, name := range nameTreeNames(copied.Get("EmbeddedFiles")) {
+				s.report.add(CategoryEmbeddedFiles, 0, fmt.Sprintf("embedded file %q", name))
+			}
+			copied.Remove("EmbeddedFiles")
+		}
+		entries.Set("Names", copied)
+	}
+
+	if acroForm, ok := core.GetDict(entries.Get("AcroForm")); ok {
+		s.sanitizeAcroForm(acroForm)
+	}
+	for _, key := range entries.Keys() {
+		s.sanitizeObject(entries.Get(key))
+	}
+	s.sanitizeDict(entries)
+	return entries
+}
+
+// nameTreeNames returns the names of the name tree `obj`.
+func nameTreeNames(obj core.PdfObject) []string {
+	var names []string
+	visited := make(map[core.PdfObject]bool)
+	var walk func(obj core.PdfObject)
+	walk = func(obj core.PdfObject) {
+		node, ok := core.GetDict(obj)
+		if !ok || visited[node] {
+			return
+		}
+		visited[node] = true
+		arr, _ := core.GetArray(node.Get("Names"))
+		elements := arr.Elements()
+		for i := 0; i+1 < len(elements); i += 2 {
+			name, _ := core.GetStringVal(elements[i])
+			names = append(names, name)
+		}
+		kids, _ := core.GetArray(node.Get("Kids"))
+		for _, kid := range kids.Elements() {
+			walk(kid)
+		}
+	}
+	walk(obj)
+	return names
+}
+
+// prune removes the references to the removed dictionaries from `obj`, the value of the entry
+// `key` of its parent, and the objects it references.
+func (s *sanitizer) prune(obj core.PdfObject, key core.PdfObjectName, visited map[core.PdfObject]bool) {
+	obj = core.ResolveReference(obj)
+	if obj == nil || visited[obj] {
+		return
+	}
+	visited[obj] = true
+
+	switch t := obj.(type) {
+	case *core.PdfIndirectObject:
+		s.prune(t.PdfObject, key, visited)
+	case *core.PdfObjectStream:
+		s.prune(t.PdfObjectDictionary, key, visited)
+	case *core.PdfObjectArray:
+		elements := t.Elements()
+		kept := make([]core.PdfObject, 0, len(elements))
+		for _, o := range elements {
+			if !s.isRemoved(o) {
+				kept = append(kept, o)
+			} else if key == "Nums" || key == "Names" {
+				// The number and name trees are arrays of key and value pairs.
+				kept = append(kept, core.MakeNull())
+			}
+		}
+		t.Clear()
+		t.Append(kept...)
+		for _, o := range kept {
+			s.prune(o, "", visited)
+		}
+	case *core.PdfObjectDictionary:
+		for _, k := range append([]core.PdfObjectName(nil), t.Keys()...) {
+			if s.isRemoved(t.Get(k)) {
+				t.Remove(k)
+			} else if k != "Parent" {
+				s.prune(t.Get(k), k, visited)
+			}
+		}
+	}
+}
+
+// isRemoved returns true if `obj` is a removed dictionary or an object reference to a removed
+// dictionary.
+func (s *sanitizer) isRemoved(obj core.PdfObject) bool {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return false
+	}
+	if s.removed[dict] {
+		return true
+	}
+	if typ, _ := core.GetNameVal(dict.Get("Type")); typ == "OBJR" {
+		target, ok := core.GetDict(dict.Get("Obj"))
+		return ok && s.removed[target]
+	}
+	return false
+}
+
+// catalogCopier copies the sanitized catalog entries and document information to the written
+// document. It implements the model.StandardApplier interface.
+type catalogCopier struct {
+	entries *core.PdfObjectDictionary
+	info    *core.PdfObjectDictionary
+}
+
+// ApplyStandard copies the catalog entries and document information to `doc`.
+func (c *catalogCopier) ApplyStandard(doc *model.StandardDocument) error {
+	for _, key := range c.entries.Keys() {
+		doc.Catalog.Set(key, c.entries.Get(key))
+	}
+	for _, key := range doc.Info.Keys() {
+		doc.Info.Remove(key)
+	}
+	if c.info != nil {
+		for _, key := range c.info.Keys() {
+			doc.Info.Set(key, c.info.Get(key))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package sanitize_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/model"
+	"github.com/TheLinker/unipdf/v3/model/sanitize"
+)
+
+// pageContent is the content of the page of the test document: visible text, invisible text,
+// text of a hidden layer and text outside the crop box.
+const pageContent = `BT /F1 12 Tf 10 10 Td (Visible) Tj ET
+BT /F1 12 Tf 3 Tr 10 30 Td (Invisible) Tj ET
+/OC /oc1 BDC BT /F1 12 Tf 10 50 Td (Layer) Tj ET EMC
+BT /F1 12 Tf 0 Tr 250 250 Td (Outside) Tj ET
+`
+
+// testDocument returns a document with hidden information of all the categories, updated
+// incrementally once.
+func testDocument() []byte {
+	objects := []string{
+		`<< /Type /Catalog /Pages 2 0 R /OpenAction 8 0 R /Metadata 12 0 R
+/Names << /EmbeddedFiles << /Names [(secret.txt) 9 0 R] >> >>
+/OCProperties << /OCGs [10 0 R] /D << /OFF [10 0 R] >> >>
+/AcroForm << /Fields [14 0 R] >> >>`,
+		`<< /Type /Pages /Kids [3 0 R] /Count 1 >>`,
+		`<< /Type /Page /Parent 2 0 R /MediaBox [0 0 300 300] /CropBox [0 0 200 200]
+/Resources << /Font << /F1 5 0 R >> /Properties << /oc1 10 0 R >> >>
+/Contents 4 0 R /Annots [6 0 R 14 0 R] >>`,
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(pageContent), pageContent),
+		`<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>`,
+		`<< /Type /Annot /Subtype /Text /Rect [10 100 30 120] /Contents (Internal note) >>`,
+		`<< /Author (Jane Doe) /Title (Draft) >>`,
+		`<< /S /JavaScript /JS (app.alert\("hello"\);) >>`,
+		`<< /Type /Filespec /F (secret.txt) /EF << /F 11 0 R >> >>`,
+		`<< /Type /OCG /Name (Notes) >>`,
+		"<< /Type /EmbeddedFile /Length 6 >>\nstream\nsecret\nendstream",
+		"<< /Type /Metadata /Subtype /XML /Length 5 >>\nstream\n<x/>\n\nendstream",
+		`(unreferenced)`,
+		`<< /FT /Tx /T (name) /V (John) /Subtype /Widget /Rect [10 150 100 170] >>`,
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 7 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(objects)+1, xref)
+
+	// Incremental update changing the document information.
+	offset := buf.Len()
+	buf.WriteString("7 0 obj\n<< /Author (Jane Doe) /Title (Final) >>\nendobj\n")
+	update := buf.Len()
+	fmt.Fprintf(&buf, "xref\n7 1\n%010d 00000 n \n", offset)
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 7 0 R /Prev %d >>\nstartxref\n%d\n%%%%EOF\n",
+		len(objects)+1, xref, update)
+	return buf.Bytes()
+}
+
+func TestSanitize(t *testing.T) {
+	var out bytes.Buffer
+	report, err := sanitize.Sanitize(bytes.NewReader(testDocument()), &out, sanitize.DefaultOptions())
+	require.NoError(t, err)
+
+	require.Equal(t, 1, report.Count(sanitize.CategoryRevisions))
+	require.Equal(t, 1, report.Count(sanitize.CategoryUnreferencedObjects))
+	require.Equal(t, 1, report.Count(sanitize.CategoryAnnotations))
+	require.Equal(t, 1, report.Count(sanitize.CategoryJavaScript))
+	require.Equal(t, 1, report.Count(sanitize.CategoryEmbeddedFiles))
+	require.Equal(t, 1, report.Count(sanitize.CategoryInvisibleText))
+	require.Equal(t, 1, report.Count(sanitize.CategoryFormData))
+	// The document information entries and the XMP metadata.
+	require.Equal(t, 3, report.Count(sanitize.CategoryMetadata))
+	// The hidden layer and its content.
+	require.Equal(t, 2, report.Count(sanitize.CategoryHiddenLayers))
+	// The media box and the text outside the crop box.
+	require.Equal(t, 2, report.Count(sanitize.CategoryOutsideCropBox))
+	require.Contains(t, report.String(), "page 1: annotations: Text annotation")
+
+	data := out.Bytes()
+	require.Equal(t, 1, bytes.Count(data, []byte("%%EOF")))
+	for _, s := range []string{"Jane Doe", "JavaScript", "secret", "unreferenced", "John", "Internal note"} {
+		require.NotContains(t, string(data), s)
+	}
+
+	reader, err := model.NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	doc, err := reader.GetStandardDocument()
+	require.NoError(t, err)
+	for _, key := range []core.PdfObjectName{"OpenAction", "Metadata", "OCProperties"} {
+		require.Nil(t, doc.Catalog.Get(key), key)
+	}
+
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	content, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	require.Contains(t, content, "(Visible)")
+	for _, s := range []string{"(Invisible)", "(Layer)", "(Outside)"} {
+		require.NotContains(t, content, s)
+	}
+	mbox, err := page.GetMediaBox()
+	require.NoError(t, err)
+	require.Equal(t, model.PdfRectangle{Urx: 200, Ury: 200}, *mbox)
+
+	annots, err := page.GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annots, 1)
+	require.IsType(t, &model.PdfAnnotationWidget{}, annots[0].GetContext())
+}
+
+func TestSanitizeNoOptions(t *testing.T) {
+	var out bytes.Buffer
+	report, err := sanitize.Sanitize(bytes.NewReader(testDocument()), &out, sanitize.Options{})
+	require.NoError(t, err)
+
+	// The previous revisions and the unreferenced objects are always removed.
+	require.Len(t, report.Removed, 2)
+	require.Equal(t, 1, report.Count(sanitize.CategoryRevisions))
+	require.Equal(t, 1, report.Count(sanitize.CategoryUnreferencedObjects))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(out.Bytes()))
+	require.NoError(t, err)
+	doc, err := reader.GetStandardDocument()
+	require.NoError(t, err)
+	require.NotNil(t, doc.Catalog.Get("OpenAction"))
+	title, _ := core.GetStringVal(doc.Info.Get("Title"))
+	require.Equal(t, "Final", title)
+
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	content, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	for _, s := range []string{"(Visible)", "(Invisible)", "(Layer)", "(Outside)"} {
+		require.True(t, strings.Contains(content, s), s)
+	}
+}
@@ -0,0 +1,315 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+/*
+Package scan cleans up the scanned pages: it detects and corrects the skew of
+the pages, removes the speckles left by the scanning noise, the dark borders
+around the pages and the punch holes.
+
+The cleanup applies to the bi-level (1 bit per pixel) images, either
+model.Image values or the image XObjects of the pages of an existing
+document. The dimensions of the images are kept, so that the pages layout is
+unchanged: the removed borders and the areas uncovered by the deskewing are
+set to white.
+
+Example:
+
+	opts := scan.Options{Deskew: true, DespeckleSize: 2, RemoveBorders: true}
+	for _, page := range reader.PageList {
+		if _, err := scan.CleanPage(page, opts); err != nil {
+			return err
+		}
+	}
+*/
+package scan
+
+import (
+	"errors"
+
+	"github.com/TheLinker/unipdf/v3/common"
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/internal/jbig2/bitmap"
+	"github.com/TheLinker/unipdf/v3/model"
+)
+
+const (
+	defaultMaxSkewAngle      = 5
+	defaultMinSkewAngle      = 0.1
+	defaultMinSkewConfidence = 1.5
+)
+
+// Options define the cleanup operations applied to the scanned images.
+type Options struct {
+	// Deskew enables the detection and the correction of the skew.
+	Deskew bool
+
+	// MaxSkewAngle is the largest skew angle searched, in degrees.
+	// It must be lower than 45. If not set, 5 degrees are searched.
+	MaxSkewAngle float64
+
+	// MinSkewAngle is the smallest skew angle which is corrected, in degrees.
+	// If not set, the skews of 0.1 degree and more are corrected.
+	MinSkewAngle float64
+
+	// MinSkewConfidence is the confidence below which the skew found is not
+	// corrected. The confidence is the ratio of the score of the angle found
+	// to the mean score of the angles searched: the images without text lines
+	// score close to 1. If not set, 1.5 is used.
+	MinSkewConfidence float64
+
+	// DespeckleSize is the size in pixels of the largest speckles removed.
+	// The speckles are the connected components which fit in a square of that
+	// size. The speckles are not removed if not set.
+	DespeckleSize int
+
+	// RemoveBorders enables the removal of the dark areas connected to the
+	// edges of the images.
+	RemoveBorders bool
+
+	// RemovePunchHoles enables the removal of the punch holes close to the
+	// edges of the images.
+	RemovePunchHoles bool
+
+	// Encoder is the encoder of the cleaned image XObjects.
+	// If not set, the images are Flate encoded.
+	Encoder core.StreamEncoder
+}
+
+// Result describes the cleanup of an image.
+type Result struct {
+	// Name is the resource name of the image XObject. It is empty for the
+	// images cleaned with CleanImage.
+	Name core.PdfObjectName
+
+	// Skew is the skew angle found, in degrees. It is positive when the text
+	// lines slope down to the right.
+	Skew float64
+
+	// Deskewed reports whether the skew has been corrected.
+	Deskewed bool
+
+	// Speckles is the number of speckles removed.
+	Speckles int
+
+	// BorderPixels is the number of border pixels removed.
+	BorderPixels int
+
+	// PunchHoles is the number of punch holes removed.
+	PunchHoles int
+}
+
+// Changed reports whether the cleanup modified the image.
+func (r *Result) Changed() bool {
+	return r.Deskewed || r.Speckles > 0 || r.BorderPixels > 0 || r.PunchHoles > 0
+}
+
+// CleanImage cleans the bi-level image `img` in place. The image samples are
+// interpreted like the DeviceGray samples: 0 is black and 1 is white.
+func CleanImage(img *model.Image, opts Options) (*Result, error) {
+	return cleanImage(img, true, opts)
+}
+
+// CleanXObjectImage cleans the bi-level image XObject `ximg` in place. The
+// images which are not bi-level, the images with a soft mask and the images
+// which cannot be decoded are left unchanged and a nil result is returned.
+func CleanXObjectImage(ximg *model.XObjectImage, opts Options) (*Result, error) {
+	if ximg.BitsPerComponent == nil || *ximg.BitsPerComponent != 1 || ximg.SMask != nil {
+		return nil, nil
+	}
+	isMask, _ := core.GetBoolVal(ximg.ImageMask)
+	if !isMask && (ximg.ColorSpace == nil || ximg.ColorSpace.GetNumComponents() != 1) {
+		return nil, nil
+	}
+	if _, ok := ximg.ColorSpace.(*model.PdfColorspaceSpecialIndexed); ok && !isMask {
+		return nil, nil
+	}
+
+	img, err := ximg.ToImage()
+	if err != nil {
+		common.Log.Debug("ERROR: unable to decode image: %v", err)
+		return nil, nil
+	}
+
+	// The image masks paint the 0 samples and the gray images are black for
+	// the 0 samples, unless the decode array inverts the samples.
+	inkIsZero := true
+	if decode, ok := core.GetArray(ximg.Decode); ok {
+		if values, err := decode.ToFloat64Array(); err == nil && len(values) == 2 && values[0] > values[1] {
+			inkIsZero = false
+		}
+	}
+
+	res, err := cleanImage(img, inkIsZero, opts)
+	if err != nil || !res.Changed() {
+		return res, err
+	}
+
+	encoder := opts.Encoder
+	if encoder == nil {
+		encoder = core.NewFlateEncoder()
+	}
+	ximg.Filter = encoder
+	if err = ximg.SetImage(img, ximg.ColorSpace); err != nil {
+		return nil, err
+	}
+	if isMask {
+		// The image masks have no color space.
+		ximg.ColorSpace = nil
+	}
+	ximg.ToPdfObject()
+	return res, nil
+}
+
+// CleanPage cleans the bi-level image XObjects of `page`, including the ones
+// of the form XObjects drawn on the page. The image XObjects are updated in
+// place. The results of the images modified are returned.
+func CleanPage(page *model.PdfPage, opts Options) ([]*Result, error) {
+	if page.Resources == nil {
+		return nil, nil
+	}
+	visited := make(map[*core.PdfObjectStream]struct{})
+	return cleanResources(page.Resources, opts, visited)
+}
+
+// cleanResources cleans the image XObjects of `resources`, visiting the
+// form XObjects recursively.
+func cleanResources(resources *model.PdfPageResources, opts Options,
+	visited map[*core.PdfObjectStream]struct{}) ([]*Result, error) {
+	xobjects, ok := core.GetDict(resources.XObject)
+	if !ok {
+		return nil, nil
+	}
+
+	var results []*Result
+	for _, name := range xobjects.Keys() {
+		stream, xtype := resources.GetXObjectByName(name)
+		if stream == nil {
+			continue
+		}
+		if _, ok := visited[stream]; ok {
+			continue
+		}
+		visited[stream] = struct{}{}
+
+		switch xtype {
+		case model.XObjectTypeImage:
+			ximg, err := model.NewXObjectImageFromStream(stream)
+			if err != nil {
+				common.Log.Debug("ERROR: invalid image XObject %s: %v", name, err)
+				continue
+			}
+			res, err := CleanXObjectImage(ximg, opts)
+			if err != nil {
+				return nil, err
+			}
+			if res != nil && res.Changed() {
+				res.Name = name
+				results = append(results, res)
+			}
+		case model.XObjectTypeForm:
+			xform, err := model.NewXObjectFormFromStream(stream)
+			if err != nil {
+				common.Log.Debug("ERROR: invalid form XObject %s: %v", name, err)
+				continue
+			}
+			if xform.Resources == nil {
+				continue
+			}
+			formResults, err := cleanResources(xform.Resources, opts, visited)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, formResults...)
+		}
+	}
+	return results, nil
+}
+
+// cleanImage cleans the bi-level image `img` in place. The 'ON' pixels of the
+// processed bitmap are the ink of the image: the 0 samples if `inkIsZero` is
+// true and the 1 samples otherwise.
+func cleanImage(img *model.Image, inkIsZero bool, opts Options) (*Result, error) {
+	if img.BitsPerComponent != 1 || img.ColorComponents != 1 {
+		return nil, errors.New("not a bi-level image")
+	}
+
+	// The image rows are byte aligned, like the bitmap rows.
+	width, height := int(img.Width), int(img.Height)
+	data := make([]byte, height*((width+7)/8))
+	if len(img.Data) < len(data) {
+		return nil, errors.New("image data too short")
+	}
+	copy(data, img.Data)
+	bm, err := bitmap.NewWithData(width, height, data)
+	if err != nil {
+		return nil, err
+	}
+	if inkIsZero {
+		bm.InverseData()
+	}
+
+	res := &Result{}
+	if opts.RemoveBorders {
+		if res.BorderPixels, err = bm.RemoveBorders(); err != nil {
+			return nil, err
+		}
+	}
+	if opts.RemovePunchHoles {
+		if res.PunchHoles, err = bm.RemovePunchHoles(); err != nil {
+			return nil, err
+		}
+	}
+	if opts.DespeckleSize > 0 {
+		if res.Speckles, err = bm.RemoveSpeckles(opts.DespeckleSize); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Deskew {
+		if bm, err = deskew(bm, res, opts); err != nil {
+			return nil, err
+		}
+	}
+	if !res.Changed() {
+		return res, nil
+	}
+
+	if inkIsZero {
+		bm.InverseData()
+	}
+	img.Data = bm.Data
+	return res, nil
+}
+
+// deskew finds the skew of `bm` and returns the deskewed bitmap if the skew
+// is corrected according to `opts`.
+func deskew(bm *bitmap.Bitmap, res *Result, opts Options) (*bitmap.Bitmap, error) {
+	maxAngle := opts.MaxSkewAngle
+	if maxAngle <= 0 {
+		maxAngle = defaultMaxSkewAngle
+	}
+	minAngle := opts.MinSkewAngle
+	if minAngle <= 0 {
+		minAngle = defaultMinSkewAngle
+	}
+	minConfidence := opts.MinSkewConfidence
+	if minConfidence <= 0 {
+		minConfidence = defaultMinSkewConfidence
+	}
+
+	angle, confidence, err := bm.FindSkew(maxAngle, 0)
+	if err != nil {
+		return nil, err
+	}
+	res.Skew = angle
+	if confidence < minConfidence || angle < minAngle && angle > -minAngle {
+		return bm, nil
+	}
+	deskewed, err := bm.RotateShear(-angle)
+	if err != nil {
+		return nil, err
+	}
+	res.Deskewed = true
+	return deskewed, nil
+}
@@ -0,0 +1,101 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package scan_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/internal/jbig2/bitmap"
+	"github.com/TheLinker/unipdf/v3/model"
+	"github.com/TheLinker/unipdf/v3/model/scan"
+)
+
+// scannedPage returns a bi-level image of a page of text skewed by `skew`
+// degrees, with speckles and a dark border on its left edge.
+func scannedPage(t *testing.T, skew float64) *model.Image {
+	const width, height = 800, 1000
+	bm := bitmap.New(width, height)
+	for y := 100; y < 900; y += 24 {
+		for x := 80; x < 680; x += 50 {
+			require.NoError(t, bm.RasterOperation(x, y, 40, 10, bitmap.PixSet, nil, 0, 0))
+		}
+	}
+	bm, err := bm.RotateShear(skew)
+	require.NoError(t, err)
+
+	for _, pt := range [][2]int{{30, 40}, {400, 960}, {750, 20}} {
+		require.NoError(t, bm.SetPixel(pt[0], pt[1], 1))
+	}
+	require.NoError(t, bm.RasterOperation(0, 0, 6, height, bitmap.PixSet, nil, 0, 0))
+
+	// The bitmap 'ON' pixels are black, which is 0 in the gray images.
+	bm.InverseData()
+	return &model.Image{
+		Width:            width,
+		Height:           height,
+		BitsPerComponent: 1,
+		ColorComponents:  1,
+		Data:             bm.Data,
+	}
+}
+
+func TestCleanImage(t *testing.T) {
+	img := scannedPage(t, 2)
+	res, err := scan.CleanImage(img, scan.Options{
+		Deskew:        true,
+		DespeckleSize: 2,
+		RemoveBorders: true,
+	})
+	require.NoError(t, err)
+	assert.True(t, res.Changed())
+	assert.True(t, res.Deskewed)
+	assert.InDelta(t, 2, res.Skew, 0.2)
+	assert.Equal(t, 3, res.Speckles)
+	assert.Equal(t, 6*1000, res.BorderPixels)
+
+	// The cleaned page has no skew left.
+	res, err = scan.CleanImage(img, scan.Options{Deskew: true})
+	require.NoError(t, err)
+	assert.False(t, res.Deskewed)
+	assert.InDelta(t, 0, res.Skew, 0.2)
+
+	// Only the bi-level images are cleaned.
+	gray := &model.Image{Width: 1, Height: 1, BitsPerComponent: 8, ColorComponents: 1, Data: []byte{0}}
+	_, err = scan.CleanImage(gray, scan.Options{Deskew: true})
+	assert.Error(t, err)
+}
+
+func TestCleanPage(t *testing.T) {
+	img := scannedPage(t, -1.5)
+	ximg, err := model.NewXObjectImageFromImage(img, nil, core.NewFlateEncoder())
+	require.NoError(t, err)
+
+	page := model.NewPdfPage()
+	require.NoError(t, page.Resources.SetXObjectImageByName("Im1", ximg))
+
+	results, err := scan.CleanPage(page, scan.Options{Deskew: true, RemoveBorders: true})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, core.PdfObjectName("Im1"), results[0].Name)
+	assert.True(t, results[0].Deskewed)
+	assert.InDelta(t, -1.5, results[0].Skew, 0.2)
+
+	// The image XObject has been updated in place.
+	ximg, err = page.Resources.GetXObjectImageByName("Im1")
+	require.NoError(t, err)
+	cleaned, err := ximg.ToImage()
+	require.NoError(t, err)
+	assert.Equal(t, int64(800), cleaned.Width)
+	assert.Equal(t, int64(1000), cleaned.Height)
+
+	results, err = scan.CleanPage(page, scan.Options{Deskew: true, RemoveBorders: true})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
@@ -7,6 +7,7 @@ package sighandler
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/rsa"
 	"crypto/x509"
 	"errors"
@@ -103,6 +104,16 @@ func (a *adobePKCS7Detached) getCertificate(sig *model.PdfSignature) (*x509.Cert
 	return certs[0], nil
 }
 
+// Certificate returns the certificate used for signing.
+func (a *adobePKCS7Detached) Certificate() *x509.Certificate {
+	return a.certificate
+}
+
+// DigestMethod returns the hash algorithm used for computing the signature digest.
+func (a *adobePKCS7Detached) DigestMethod() crypto.Hash {
+	return crypto.SHA1
+}
+
 // NewDigest creates a new digest.
 func (a *adobePKCS7Detached) NewDigest(sig *model.PdfSignature) (model.Hasher, error) {
 	return bytes.NewBuffer(nil), nil
@@ -102,6 +102,16 @@ func (a *adobeX509RSASHA1) getCertificate(sig *model.PdfSignature) (*x509.Certif
 	return certs[0], nil
 }
 
+// Certificate returns the certificate used for signing.
+func (a *adobeX509RSASHA1) Certificate() *x509.Certificate {
+	return a.certificate
+}
+
+// DigestMethod returns the hash algorithm used for computing the signature digest.
+func (a *adobeX509RSASHA1) DigestMethod() crypto.Hash {
+	return crypto.SHA1
+}
+
 // NewDigest creates a new digest.
 func (a *adobeX509RSASHA1) NewDigest(sig *model.PdfSignature) (model.Hasher, error) {
 	certificate, err := a.getCertificate(sig)
@@ -8,14 +8,14 @@ package sighandler
 import (
 	"bytes"
 	"crypto"
+	"crypto/rand"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"net/http"
+	"math/big"
 	"time"
 
 	"github.com/TheLinker/unipdf/v3/core"
@@ -24,19 +24,54 @@ import (
 	"github.com/unidoc/timestamp"
 )
 
+// DocTimeStampOpts defines options for configuring the DocTimeStamp signature handler.
+type DocTimeStampOpts struct {
+	// SignatureSize is the size reserved for the time-stamp token in the
+	// signature Contents field. If not set, 8192 bytes are reserved.
+	SignatureSize int
+
+	// TrustedRoots is the pool of root certificates used for verifying the
+	// certificate chain of the TSA. If nil, the certificate chain is not
+	// verified and the validation result is not marked as trusted.
+	TrustedRoots *x509.CertPool
+}
+
 // docTimeStamp DocTimeStamp signature handler.
 type docTimeStamp struct {
-	timestampServerURL string
-	hashAlgorithm      crypto.Hash
+	client        TimestampClient
+	hashAlgorithm crypto.Hash
+	opts          DocTimeStampOpts
 }
 
 // NewDocTimeStamp creates a new DocTimeStamp signature handler.
 // The timestampServerURL parameter can be empty string for the signature validation.
 // The hashAlgorithm parameter can be crypto.SHA1, crypto.SHA256, crypto.SHA384, crypto.SHA512.
 func NewDocTimeStamp(timestampServerURL string, hashAlgorithm crypto.Hash) (model.SignatureHandler, error) {
+	var client TimestampClient
+	if timestampServerURL != "" {
+		client = NewHTTPTimestampClient(timestampServerURL)
+	}
+	return NewDocTimeStampWithOpts(client, hashAlgorithm, nil)
+}
+
+// NewDocTimeStampWithOpts creates a new DocTimeStamp signature handler which
+// obtains time-stamp tokens using the specified client.
+// The client parameter can be nil for the signature validation.
+// The hashAlgorithm parameter can be crypto.SHA1, crypto.SHA256, crypto.SHA384, crypto.SHA512.
+// The opts parameter can be nil, in which case the default options are used.
+func NewDocTimeStampWithOpts(client TimestampClient, hashAlgorithm crypto.Hash,
+	opts *DocTimeStampOpts) (model.SignatureHandler, error) {
+	if opts == nil {
+		opts = &DocTimeStampOpts{}
+	}
+	if opts.SignatureSize <= 0 {
+		opts.SignatureSize = 8192
+	}
+
 	return &docTimeStamp{
-		timestampServerURL: timestampServerURL,
-		hashAlgorithm:      hashAlgorithm,
+		client:        client,
+		hashAlgorithm: hashAlgorithm,
+		opts:          *opts,
 	}, nil
 }
 
@@ -52,7 +87,17 @@ func (a *docTimeStamp) InitSignature(sig *model.PdfSignature) error {
 		return err
 	}
 	digest.Write([]byte("calculate the Contents field size"))
-	return handler.Sign(sig, digest)
+	if err := handler.Sign(sig, digest); err != nil {
+		return err
+	}
+
+	// Reserve additional space if the token does not fit in the configured
+	// signature size, as tokens may vary slightly in size between requests.
+	if tokenLen := len(sig.Contents.Bytes()); tokenLen > handler.opts.SignatureSize {
+		handler.opts.SignatureSize = tokenLen + 1024
+		sig.Contents = core.MakeHexString(string(make([]byte, handler.opts.SignatureSize)))
+	}
+	return nil
 }
 
 func (a *docTimeStamp) getCertificate(sig *model.PdfSignature) (*x509.Certificate, error) {
@@ -83,11 +128,18 @@ func (a *docTimeStamp) getCertificate(sig *model.PdfSignature) (*x509.Certificat
 	return certs[0], nil
 }
 
+// DigestMethod returns the hash algorithm used for computing the message imprint.
+func (a *docTimeStamp) DigestMethod() crypto.Hash {
+	return a.hashAlgorithm
+}
+
 // NewDigest creates a new digest.
 func (a *docTimeStamp) NewDigest(sig *model.PdfSignature) (model.Hasher, error) {
 	return bytes.NewBuffer(nil), nil
 }
 
+// timestampInfo represents the TSTInfo structure of a time-stamp token
+// (RFC 3161 section 2.4.2).
 type timestampInfo struct {
 	Version        int
 	Policy         asn1.RawValue
@@ -97,6 +149,23 @@ type timestampInfo struct {
 	}
 	SerialNumber    asn1.RawValue
 	GeneralizedTime time.Time
+	Accuracy        struct {
+		Seconds      int64 `asn1:"optional"`
+		Milliseconds int64 `asn1:"tag:0,optional"`
+		Microseconds int64 `asn1:"tag:1,optional"`
+	} `asn1:"optional"`
+	Ordering bool     `asn1:"optional,default:false"`
+	Nonce    *big.Int `asn1:"optional"`
+}
+
+// timestampResponse represents a time-stamp response (RFC 3161 section 2.4.2).
+type timestampResponse struct {
+	Status struct {
+		Status       int
+		StatusString []asn1.RawValue `asn1:"optional"`
+		FailInfo     asn1.BitString  `asn1:"optional"`
+	}
+	TimeStampToken asn1.RawValue `asn1:"optional"`
 }
 
 func getHashForOID(oid asn1.ObjectIdentifier) (crypto.Hash, error) {
@@ -115,44 +184,123 @@ func getHashForOID(oid asn1.ObjectIdentifier) (crypto.Hash, error) {
 	return crypto.Hash(0), pkcs7.ErrUnsupportedAlgorithm
 }
 
-// Validate validates PdfSignature.
-func (a *docTimeStamp) Validate(sig *model.PdfSignature, digest model.Hasher) (model.SignatureValidationResult, error) {
-	signed := sig.Contents.Bytes()
-	p7, err := pkcs7.Parse(signed)
+// parseTimestampToken parses the specified time-stamp token and returns
+// the PKCS7 container along with the TSTInfo structure it encapsulates.
+func parseTimestampToken(token []byte) (*pkcs7.PKCS7, *timestampInfo, error) {
+	p7, err := pkcs7.Parse(token)
 	if err != nil {
-		return model.SignatureValidationResult{}, err
+		return nil, nil, err
 	}
 
-	if err = p7.Verify(); err != nil {
-		return model.SignatureValidationResult{}, err
+	var tsInfo timestampInfo
+	if _, err = asn1.Unmarshal(p7.Content, &tsInfo); err != nil {
+		return nil, nil, err
 	}
+	return p7, &tsInfo, nil
+}
 
-	var tsInfo timestampInfo
+// verifyTSACertificate checks that the TSA certificate is suitable for
+// time-stamping as required by RFC 3161 section 2.3: the extended key usage
+// extension must be critical and must contain only the time stamping usage.
+func verifyTSACertificate(cert *x509.Certificate) error {
+	if cert == nil {
+		return errors.New("TSA certificate not found")
+	}
 
-	_, err = asn1.Unmarshal(p7.Content, &tsInfo)
-	if err != nil {
-		return model.SignatureValidationResult{}, err
+	var ekuCritical bool
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidExtKeyUsage) {
+			ekuCritical = ext.Critical
+			break
+		}
+	}
+	if !ekuCritical {
+		return errors.New("TSA certificate extended key usage extension is not critical")
+	}
+	if len(cert.ExtKeyUsage) != 1 || cert.ExtKeyUsage[0] != x509.ExtKeyUsageTimeStamping ||
+		len(cert.UnknownExtKeyUsage) > 0 {
+		return errors.New("TSA certificate extended key usage must only contain time stamping")
 	}
+	return nil
+}
 
-	hAlg, err := getHashForOID(tsInfo.MessageImprint.HashAlgorithm.Algorithm)
+// Validate validates PdfSignature.
+func (a *docTimeStamp) Validate(sig *model.PdfSignature, digest model.Hasher) (model.SignatureValidationResult, error) {
+	p7, tsInfo, err := parseTimestampToken(sig.Contents.Bytes())
 	if err != nil {
 		return model.SignatureValidationResult{}, err
 	}
-	h := hAlg.New()
-	buffer := digest.(*bytes.Buffer)
 
-	h.Write(buffer.Bytes())
-	sm := h.Sum(nil)
 	res := model.SignatureValidationResult{
 		IsSigned:        true,
-		IsVerified:      bytes.Equal(sm, tsInfo.MessageImprint.HashedMessage),
+		IsVerified:      true,
 		GeneralizedTime: tsInfo.GeneralizedTime,
 	}
+	addError := func(err error) {
+		res.Errors = append(res.Errors, err.Error())
+	}
+
+	// Verify token signature.
+	if err = p7.Verify(); err != nil {
+		res.IsVerified = false
+		addError(err)
+	}
+
+	// Verify message imprint.
+	imprintAlg := tsInfo.MessageImprint.HashAlgorithm.Algorithm
+	if hAlg, err := getHashForOID(imprintAlg); err != nil {
+		res.IsVerified = false
+		addError(fmt.Errorf("unsupported timestamp message imprint hash algorithm %s: %v", imprintAlg, err))
+	} else {
+		h := hAlg.New()
+		buffer := digest.(*bytes.Buffer)
+		h.Write(buffer.Bytes())
+		if !bytes.Equal(h.Sum(nil), tsInfo.MessageImprint.HashedMessage) {
+			res.IsVerified = false
+			addError(errors.New("timestamp message imprint does not match the signed data"))
+		}
+	}
+
+	// Verify TSA certificate.
+	cert := p7.GetOnlySigner()
+	if err = verifyTSACertificate(cert); err != nil {
+		res.IsVerified = false
+		addError(err)
+		return res, nil
+	}
+	if genTime := tsInfo.GeneralizedTime; genTime.Before(cert.NotBefore) || genTime.After(cert.NotAfter) {
+		res.IsVerified = false
+		addError(fmt.Errorf("timestamp generation time %s is outside of TSA certificate validity", genTime))
+	}
+
+	// Verify TSA certificate chain.
+	if a.opts.TrustedRoots != nil {
+		intermediates := x509.NewCertPool()
+		for _, c := range p7.Certificates {
+			intermediates.AddCert(c)
+		}
+		_, err = cert.Verify(x509.VerifyOptions{
+			Roots:         a.opts.TrustedRoots,
+			Intermediates: intermediates,
+			CurrentTime:   tsInfo.GeneralizedTime,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+		})
+		if err != nil {
+			addError(err)
+		} else {
+			res.IsTrusted = res.IsVerified
+		}
+	}
+
 	return res, nil
 }
 
 // Sign sets the Contents fields for the PdfSignature.
 func (a *docTimeStamp) Sign(sig *model.PdfSignature, digest model.Hasher) error {
+	if a.client == nil {
+		return errors.New("timestamp client not set")
+	}
+
 	buffer := digest.(*bytes.Buffer)
 	h := a.hashAlgorithm.New()
 
@@ -160,11 +308,17 @@ func (a *docTimeStamp) Sign(sig *model.PdfSignature, digest model.Hasher) error
 		return err
 	}
 
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return err
+	}
+
 	s := h.Sum(nil)
 	r := timestamp.Request{
 		HashAlgorithm:   a.hashAlgorithm,
 		HashedMessage:   s,
 		Certificates:    true,
+		Nonce:           nonce,
 		Extensions:      nil,
 		ExtraExtensions: nil,
 	}
@@ -173,32 +327,42 @@ func (a *docTimeStamp) Sign(sig *model.PdfSignature, digest model.Hasher) error
 		return err
 	}
 
-	resp, err := http.Post(a.timestampServerURL, "application/timestamp-query", bytes.NewBuffer(data))
+	body, err := a.client.GetTimestampResponse(data)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
+	var resp timestampResponse
+	if _, err = asn1.Unmarshal(body, &resp); err != nil {
 		return err
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("http status code not ok (got %d)", resp.StatusCode)
+	if status := resp.Status.Status; status != timestamp.Granted && status != timestamp.GrantedWithMods {
+		return fmt.Errorf("timestamp request rejected (status %d)", status)
 	}
-
-	var ci struct {
-		Version asn1.RawValue
-		Content asn1.RawValue
+	token := resp.TimeStampToken.FullBytes
+	if len(token) == 0 {
+		return errors.New("timestamp response does not contain a token")
 	}
 
-	_, err = asn1.Unmarshal(body, &ci)
+	// Check that the token corresponds to the request.
+	_, tsInfo, err := parseTimestampToken(token)
 	if err != nil {
 		return err
 	}
+	if !bytes.Equal(tsInfo.MessageImprint.HashedMessage, s) {
+		return errors.New("timestamp response message imprint does not match the request")
+	}
+	if tsInfo.Nonce == nil || tsInfo.Nonce.Cmp(nonce) != 0 {
+		return errors.New("timestamp response nonce does not match the request")
+	}
+
+	if len(token) < a.opts.SignatureSize {
+		padded := make([]byte, a.opts.SignatureSize)
+		copy(padded, token)
+		token = padded
+	}
 
-	sig.Contents = core.MakeHexString(string(ci.Content.FullBytes))
+	sig.Contents = core.MakeHexString(string(token))
 	return nil
 }
 
@@ -0,0 +1,192 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package sighandler
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/unidoc/timestamp"
+)
+
+var (
+	// oidExtKeyUsage is the object identifier of the extended key usage certificate extension.
+	oidExtKeyUsage = asn1.ObjectIdentifier{2, 5, 29, 37}
+
+	// oidExtKeyUsageTimeStamping is the object identifier of the time stamping extended key usage.
+	oidExtKeyUsageTimeStamping = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 8}
+
+	// oidAnyPolicy is the default policy used by the local time-stamp authority.
+	oidAnyPolicy = asn1.ObjectIdentifier{2, 5, 29, 32, 0}
+)
+
+// LocalTimestampAuthority is an in-process RFC 3161 time-stamp authority.
+// It implements the TimestampClient interface, so it can be passed directly
+// to NewDocTimeStampWithOpts, and the http.Handler interface, so it can be
+// served as a stand-in for a remote TSA server (e.g. using net/http/httptest).
+type LocalTimestampAuthority struct {
+	// Certificate is the TSA signing certificate. It must include the
+	// critical time stamping extended key usage.
+	Certificate *x509.Certificate
+
+	// Signer is the private key corresponding to the TSA certificate.
+	Signer crypto.Signer
+
+	// Root is the certificate of the authority which issued the TSA certificate.
+	// It is only set for authorities created using NewLocalTimestampAuthority.
+	Root *x509.Certificate
+
+	// Policy is the TSA policy under which the tokens are issued.
+	// If not set, the anyPolicy identifier (2.5.29.32.0) is used.
+	Policy asn1.ObjectIdentifier
+
+	// Now returns the time embedded in the time-stamp tokens.
+	// If not set, time.Now is used.
+	Now func() time.Time
+}
+
+// NewLocalTimestampAuthority creates a new in-process time-stamp authority.
+// A self-signed root certificate and a TSA certificate issued by it are
+// generated. The root certificate can be used as a trust anchor when
+// validating the issued time-stamp tokens (see TrustedRoots).
+func NewLocalTimestampAuthority() (*LocalTimestampAuthority, error) {
+	now := time.Now().Add(-time.Hour)
+
+	// Generate root certificate.
+	rootKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "UniPDF Local TSA Root"},
+		NotBefore:             now,
+		NotAfter:              now.AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	rootData, err := x509.CreateCertificate(rand.Reader, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		return nil, err
+	}
+	root, err := x509.ParseCertificate(rootData)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate TSA certificate. RFC 3161 requires the extended key usage
+	// extension to be critical and to contain only the time stamping usage.
+	tsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	ekuData, err := asn1.Marshal([]asn1.ObjectIdentifier{oidExtKeyUsageTimeStamping})
+	if err != nil {
+		return nil, err
+	}
+	tsaTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "UniPDF Local TSA"},
+		NotBefore:    now,
+		NotAfter:     now.AddDate(10, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtraExtensions: []pkix.Extension{
+			{Id: oidExtKeyUsage, Critical: true, Value: ekuData},
+		},
+	}
+	tsaData, err := x509.CreateCertificate(rand.Reader, tsaTemplate, root, &tsaKey.PublicKey, rootKey)
+	if err != nil {
+		return nil, err
+	}
+	tsaCert, err := x509.ParseCertificate(tsaData)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalTimestampAuthority{
+		Certificate: tsaCert,
+		Signer:      tsaKey,
+		Root:        root,
+	}, nil
+}
+
+// TrustedRoots returns a certificate pool containing the root certificate
+// of the authority.
+func (tsa *LocalTimestampAuthority) TrustedRoots() *x509.CertPool {
+	pool := x509.NewCertPool()
+	if tsa.Root != nil {
+		pool.AddCert(tsa.Root)
+	} else if tsa.Certificate != nil {
+		pool.AddCert(tsa.Certificate)
+	}
+	return pool
+}
+
+// GetTimestampResponse processes the DER encoded time-stamp request and
+// returns a DER encoded time-stamp response.
+func (tsa *LocalTimestampAuthority) GetTimestampResponse(reqData []byte) ([]byte, error) {
+	if tsa.Certificate == nil || tsa.Signer == nil {
+		return nil, errors.New("timestamp authority certificate and signer must be set")
+	}
+
+	req, err := timestamp.ParseRequest(reqData)
+	if err != nil {
+		return timestamp.CreateErrorResponse(timestamp.Rejection, timestamp.BadDataFormat)
+	}
+
+	genTime := time.Now
+	if tsa.Now != nil {
+		genTime = tsa.Now
+	}
+	policy := tsa.Policy
+	if policy == nil {
+		policy = oidAnyPolicy
+	}
+
+	ts := timestamp.Timestamp{
+		HashAlgorithm:     req.HashAlgorithm,
+		HashedMessage:     req.HashedMessage,
+		Time:              genTime().UTC(),
+		Nonce:             req.Nonce,
+		Policy:            policy,
+		AddTSACertificate: req.Certificates,
+	}
+	return ts.CreateResponse(tsa.Certificate, tsa.Signer)
+}
+
+// ServeHTTP implements the http.Handler interface, serving time-stamp
+// requests over the HTTP transport described in RFC 3161 section 3.4.
+func (tsa *LocalTimestampAuthority) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	reqData, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	respData, err := tsa.GetTimestampResponse(reqData)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/timestamp-reply")
+	w.Write(respData)
+}
@@ -0,0 +1,70 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package sighandler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// TimestampClient represents a RFC 3161 time-stamp authority (TSA) client.
+// It is used by the DocTimeStamp signature handler for obtaining time-stamp
+// tokens and can be implemented by custom transports (e.g. authenticated
+// TSA services or in-process authorities used for testing).
+type TimestampClient interface {
+	// GetTimestampResponse sends the DER encoded time-stamp request to the
+	// TSA and returns the DER encoded time-stamp response (TimeStampResp).
+	GetTimestampResponse(req []byte) ([]byte, error)
+}
+
+// HTTPTimestampClient is a TimestampClient which sends time-stamp requests
+// to a TSA server using the HTTP transport described in RFC 3161 section 3.4.
+type HTTPTimestampClient struct {
+	// URL is the address of the TSA server.
+	URL string
+
+	// HTTPClient is used for performing the requests.
+	// If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+}
+
+// NewHTTPTimestampClient returns a new HTTP time-stamp client for the TSA
+// server located at the specified URL.
+func NewHTTPTimestampClient(url string) *HTTPTimestampClient {
+	return &HTTPTimestampClient{URL: url}
+}
+
+// GetTimestampResponse sends the DER encoded time-stamp request to the TSA
+// server and returns its DER encoded response.
+func (c *HTTPTimestampClient) GetTimestampResponse(req []byte) ([]byte, error) {
+	if c.URL == "" {
+		return nil, errors.New("timestamp server URL not set")
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Post(c.URL, "application/timestamp-query", bytes.NewReader(req))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http status code not ok (got %d)", resp.StatusCode)
+	}
+	return body, nil
+}
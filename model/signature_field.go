@@ -0,0 +1,765 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/TheLinker/unipdf/v3/common"
+	"github.com/TheLinker/unipdf/v3/core"
+)
+
+// SignatureFieldLockAction represents the action of a signature field lock dictionary,
+// which indicates the set of fields locked when the signature field is signed.
+type SignatureFieldLockAction string
+
+// Signature field lock actions.
+const (
+	// SignatureFieldLockActionAll locks all fields in the document.
+	SignatureFieldLockActionAll SignatureFieldLockAction = "All"
+
+	// SignatureFieldLockActionInclude locks the fields specified in the Fields array.
+	SignatureFieldLockActionInclude SignatureFieldLockAction = "Include"
+
+	// SignatureFieldLockActionExclude locks all fields except those specified in the Fields array.
+	SignatureFieldLockActionExclude SignatureFieldLockAction = "Exclude"
+)
+
+// PdfSignatureFieldLock represents a signature field lock dictionary, which specifies the form
+// fields to be locked when the signature field is signed.
+// (Section 12.7.4.5, Table 233 - Entries in a signature field lock dictionary p. 454 in PDF32000_2008).
+type PdfSignatureFieldLock struct {
+	Type   *core.PdfObjectName
+	Action *core.PdfObjectName
+	Fields *core.PdfObjectArray
+
+	// P specifies the access permissions granted for the document (PDF 2.0).
+	P *core.PdfObjectInteger
+
+	container core.PdfObject
+}
+
+// NewPdfSignatureFieldLock returns a new signature field lock dictionary which applies the
+// specified action to the fields with the provided fully qualified names.
+func NewPdfSignatureFieldLock(action SignatureFieldLockAction, fieldNames ...string) *PdfSignatureFieldLock {
+	lock := &PdfSignatureFieldLock{
+		Type:      core.MakeName("SigFieldLock"),
+		Action:    core.MakeName(string(action)),
+		container: core.MakeIndirectObject(core.MakeDict()),
+	}
+	if action != SignatureFieldLockActionAll {
+		lock.Fields = core.MakeArray()
+		for _, name := range fieldNames {
+			lock.Fields.Append(core.MakeString(name))
+		}
+	}
+	return lock
+}
+
+// newPdfSignatureFieldLockFromObject loads a signature field lock dictionary from the specified object.
+func newPdfSignatureFieldLockFromObject(obj core.PdfObject) (*PdfSignatureFieldLock, error) {
+	d, ok := core.GetDict(obj)
+	if !ok {
+		return nil, ErrTypeCheck
+	}
+
+	lock := &PdfSignatureFieldLock{container: obj}
+	lock.Type, _ = core.GetName(d.Get("Type"))
+	lock.Action, ok = core.GetName(d.Get("Action"))
+	if !ok {
+		common.Log.Debug("ERROR: Signature field lock Action attribute invalid or missing")
+		return nil, ErrRequiredAttributeMissing
+	}
+	lock.Fields, _ = core.GetArray(d.Get("Fields"))
+	lock.P, _ = core.GetInt(d.Get("P"))
+	return lock, nil
+}
+
+// GetContainingPdfObject implements interface PdfModel.
+func (lock *PdfSignatureFieldLock) GetContainingPdfObject() core.PdfObject {
+	return lock.container
+}
+
+// ToPdfObject implements interface PdfModel.
+func (lock *PdfSignatureFieldLock) ToPdfObject() core.PdfObject {
+	d, _ := core.GetDict(lock.container)
+	d.Clear()
+	d.SetIfNotNil("Type", lock.Type)
+	d.SetIfNotNil("Action", lock.Action)
+	d.SetIfNotNil("Fields", lock.Fields)
+	d.SetIfNotNil("P", lock.P)
+	return lock.container
+}
+
+// IsLocked returns true if the field with the specified fully qualified name
+// is locked by the lock dictionary.
+func (lock *PdfSignatureFieldLock) IsLocked(fieldName string) bool {
+	if lock.Action == nil {
+		return false
+	}
+
+	var listed bool
+	if lock.Fields != nil {
+		for _, obj := range lock.Fields.Elements() {
+			if name, ok := core.GetString(obj); ok && name.Decoded() == fieldName {
+				listed = true
+				break
+			}
+		}
+	}
+
+	switch SignatureFieldLockAction(*lock.Action) {
+	case SignatureFieldLockActionAll:
+		return true
+	case SignatureFieldLockActionInclude:
+		return listed
+	case SignatureFieldLockActionExclude:
+		return !listed
+	}
+	return false
+}
+
+// SignatureSeedFlag represents the flags of a signature field seed value dictionary (Ff entry).
+// A set flag marks the corresponding seed value entry as a required constraint.
+type SignatureSeedFlag uint32
+
+// Signature field seed value flags.
+const (
+	SignatureSeedFlagFilter SignatureSeedFlag = 1 << iota
+	SignatureSeedFlagSubFilter
+	SignatureSeedFlagV
+	SignatureSeedFlagReasons
+	SignatureSeedFlagLegalAttestation
+	SignatureSeedFlagAddRevInfo
+	SignatureSeedFlagDigestMethod
+	SignatureSeedFlagLockDocument
+	SignatureSeedFlagAppearanceFilter
+)
+
+// PdfSignatureFieldSeed represents a signature field seed value dictionary, containing
+// information that constrains the properties of a signature applied to the field.
+// (Section 12.7.4.5, Table 234 - Entries in a signature field seed value dictionary p. 455 in PDF32000_2008).
+type PdfSignatureFieldSeed struct {
+	Type             *core.PdfObjectName
+	Ff               *core.PdfObjectInteger
+	Filter           *core.PdfObjectName
+	SubFilter        *core.PdfObjectArray
+	DigestMethod     *core.PdfObjectArray
+	V                *core.PdfObjectFloat
+	Cert             *PdfSignatureFieldSeedCertificate
+	Reasons          *core.PdfObjectArray
+	MDP              *core.PdfObjectDictionary
+	TimeStamp        *core.PdfObjectDictionary
+	LegalAttestation *core.PdfObjectArray
+	AddRevInfo       *core.PdfObjectBool
+
+	// PDF 2.0 entries.
+	LockDocument     *core.PdfObjectName
+	AppearanceFilter *core.PdfObjectString
+
+	container core.PdfObject
+}
+
+// NewPdfSignatureFieldSeed returns a new empty signature field seed value dictionary.
+func NewPdfSignatureFieldSeed() *PdfSignatureFieldSeed {
+	return &PdfSignatureFieldSeed{
+		Type:      core.MakeName("SV"),
+		container: core.MakeIndirectObject(core.MakeDict()),
+	}
+}
+
+// newPdfSignatureFieldSeedFromObject loads a signature field seed value dictionary from the specified object.
+func newPdfSignatureFieldSeedFromObject(obj core.PdfObject) (*PdfSignatureFieldSeed, error) {
+	d, ok := core.GetDict(obj)
+	if !ok {
+		return nil, ErrTypeCheck
+	}
+
+	sv := &PdfSignatureFieldSeed{container: obj}
+	sv.Type, _ = core.GetName(d.Get("Type"))
+	sv.Ff, _ = core.GetInt(d.Get("Ff"))
+	sv.Filter, _ = core.GetName(d.Get("Filter"))
+	sv.SubFilter, _ = core.GetArray(d.Get("SubFilter"))
+	sv.DigestMethod, _ = core.GetArray(d.Get("DigestMethod"))
+	if v, err := core.GetNumberAsFloat(core.TraceToDirectObject(d.Get("V"))); err == nil {
+		sv.V = core.MakeFloat(v)
+	}
+	if obj := d.Get("Cert"); obj != nil {
+		cert, err := newPdfSignatureFieldSeedCertificateFromObject(obj)
+		if err != nil {
+			return nil, err
+		}
+		sv.Cert = cert
+	}
+	sv.Reasons, _ = core.GetArray(d.Get("Reasons"))
+	sv.MDP, _ = core.GetDict(d.Get("MDP"))
+	sv.TimeStamp, _ = core.GetDict(d.Get("TimeStamp"))
+	sv.LegalAttestation, _ = core.GetArray(d.Get("LegalAttestation"))
+	sv.AddRevInfo, _ = core.GetBool(d.Get("AddRevInfo"))
+	sv.LockDocument, _ = core.GetName(d.Get("LockDocument"))
+	sv.AppearanceFilter, _ = core.GetString(d.Get("AppearanceFilter"))
+	return sv, nil
+}
+
+// GetContainingPdfObject implements interface PdfModel.
+func (sv *PdfSignatureFieldSeed) GetContainingPdfObject() core.PdfObject {
+	return sv.container
+}
+
+// ToPdfObject implements interface PdfModel.
+func (sv *PdfSignatureFieldSeed) ToPdfObject() core.PdfObject {
+	d, _ := core.GetDict(sv.container)
+	d.Clear()
+	d.SetIfNotNil("Type", sv.Type)
+	d.SetIfNotNil("Ff", sv.Ff)
+	d.SetIfNotNil("Filter", sv.Filter)
+	d.SetIfNotNil("SubFilter", sv.SubFilter)
+	d.SetIfNotNil("DigestMethod", sv.DigestMethod)
+	d.SetIfNotNil("V", sv.V)
+	if sv.Cert != nil {
+		d.Set("Cert", sv.Cert.ToPdfObject())
+	}
+	d.SetIfNotNil("Reasons", sv.Reasons)
+	d.SetIfNotNil("MDP", sv.MDP)
+	d.SetIfNotNil("TimeStamp", sv.TimeStamp)
+	d.SetIfNotNil("LegalAttestation", sv.LegalAttestation)
+	d.SetIfNotNil("AddRevInfo", sv.AddRevInfo)
+	d.SetIfNotNil("LockDocument", sv.LockDocument)
+	d.SetIfNotNil("AppearanceFilter", sv.AppearanceFilter)
+	return sv.container
+}
+
+// Flags returns the seed value flags.
+func (sv *PdfSignatureFieldSeed) Flags() SignatureSeedFlag {
+	if sv.Ff == nil {
+		return 0
+	}
+	return SignatureSeedFlag(*sv.Ff)
+}
+
+// SetFlags sets the seed value flags.
+func (sv *PdfSignatureFieldSeed) SetFlags(flags SignatureSeedFlag) {
+	sv.Ff = core.MakeInteger(int64(flags))
+}
+
+// SetMDP sets the MDP dictionary of the seed value dictionary. A permission value
+// of 0 requires an approval signature, while values 1 to 3 require a certification
+// signature with the corresponding DocMDP access permissions.
+func (sv *PdfSignatureFieldSeed) SetMDP(permission int64) {
+	mdp := core.MakeDict()
+	mdp.Set("P", core.MakeInteger(permission))
+	sv.MDP = mdp
+}
+
+// SetTimeStamp sets the time-stamp server used for time-stamping the signature.
+// If required is true, the signature must be time-stamped.
+func (sv *PdfSignatureFieldSeed) SetTimeStamp(url string, required bool) {
+	ts := core.MakeDict()
+	ts.Set("URL", core.MakeString(url))
+	if required {
+		ts.Set("Ff", core.MakeInteger(1))
+	} else {
+		ts.Set("Ff", core.MakeInteger(0))
+	}
+	sv.TimeStamp = ts
+}
+
+// signatureHandlerCertificate is implemented by signature handlers which can
+// provide the certificate used for signing ahead of the signing process.
+type signatureHandlerCertificate interface {
+	Certificate() *x509.Certificate
+}
+
+// signatureHandlerDigestMethod is implemented by signature handlers which can
+// provide the hash algorithm used for computing the signature digest.
+type signatureHandlerDigestMethod interface {
+	DigestMethod() crypto.Hash
+}
+
+// digestMethodNames maps hash algorithms to seed value digest method names.
+var digestMethodNames = map[crypto.Hash]string{
+	crypto.SHA1:      "SHA1",
+	crypto.SHA256:    "SHA256",
+	crypto.SHA384:    "SHA384",
+	crypto.SHA512:    "SHA512",
+	crypto.RIPEMD160: "RIPEMD160",
+}
+
+// CheckSignature checks whether the specified signature satisfies the required
+// constraints of the seed value dictionary. Only the entries marked as required
+// by the seed value flags are enforced, the rest being treated as hints.
+// The signing certificate and digest method are obtained from the signature
+// handler, if it is able to provide them, or from the Cert entry of the signature.
+func (sv *PdfSignatureFieldSeed) CheckSignature(sig *PdfSignature) error {
+	if sig == nil {
+		return errors.New("signature cannot be nil")
+	}
+	flags := sv.Flags()
+
+	// Filter.
+	if flags&SignatureSeedFlagFilter != 0 && sv.Filter != nil {
+		if sig.Filter == nil || *sig.Filter != *sv.Filter {
+			return fmt.Errorf("signature filter must be %s", *sv.Filter)
+		}
+	}
+
+	// SubFilter.
+	if flags&SignatureSeedFlagSubFilter != 0 && sv.SubFilter != nil && sv.SubFilter.Len() > 0 {
+		var subFilter string
+		if sig.SubFilter != nil {
+			subFilter = string(*sig.SubFilter)
+		}
+		if !containsName(sv.SubFilter, subFilter) {
+			return fmt.Errorf("signature subfilter %q not allowed by seed value", subFilter)
+		}
+	}
+
+	// DigestMethod.
+	if flags&SignatureSeedFlagDigestMethod != 0 && sv.DigestMethod != nil && sv.DigestMethod.Len() > 0 {
+		handler, ok := sig.Handler.(signatureHandlerDigestMethod)
+		if !ok {
+			return errors.New("unable to determine the digest method of the signature handler")
+		}
+		name := digestMethodNames[handler.DigestMethod()]
+		if !containsName(sv.DigestMethod, name) {
+			return fmt.Errorf("signature digest method %q not allowed by seed value", name)
+		}
+	}
+
+	// Reasons.
+	if flags&SignatureSeedFlagReasons != 0 && sv.Reasons != nil && sv.Reasons.Len() > 0 {
+		var reason string
+		if sig.Reason != nil {
+			reason = sig.Reason.Decoded()
+		}
+
+		reasons := make([]string, 0, sv.Reasons.Len())
+		for _, obj := range sv.Reasons.Elements() {
+			if str, ok := core.GetString(obj); ok {
+				reasons = append(reasons, str.Decoded())
+			}
+		}
+		if len(reasons) == 1 && reasons[0] == "." {
+			// A single period indicates that no reason should be specified.
+			if reason != "" {
+				return errors.New("signature reason not allowed by seed value")
+			}
+		} else if !containsString(reasons, reason) {
+			return fmt.Errorf("signature reason %q not allowed by seed value", reason)
+		}
+	}
+
+	// MDP.
+	if sv.MDP != nil {
+		if p, ok := core.GetIntVal(sv.MDP.Get("P")); ok {
+			docMDP, hasDocMDP := sig.docMDPPermission()
+			switch {
+			case p == 0 && hasDocMDP:
+				return errors.New("seed value requires an approval signature")
+			case p > 0 && !hasDocMDP:
+				return errors.New("seed value requires a certification signature")
+			case p > 0 && docMDP != p:
+				return fmt.Errorf("seed value requires DocMDP permission %d (got %d)", p, docMDP)
+			}
+		}
+	}
+
+	// Certificate.
+	if sv.Cert != nil && sv.Cert.Flags() != 0 {
+		cert, err := sig.signingCertificate()
+		if err != nil {
+			return err
+		}
+		if err := sv.Cert.CheckCertificate(cert); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// docMDPPermission returns the DocMDP access permissions of the signature and
+// true if the signature is a certification signature.
+func (sig *PdfSignature) docMDPPermission() (int, bool) {
+	if sig.Reference == nil {
+		return 0, false
+	}
+	for _, obj := range sig.Reference.Elements() {
+		ref, ok := core.GetDict(obj)
+		if !ok {
+			continue
+		}
+		if method, _ := core.GetNameVal(ref.Get("TransformMethod")); method != "DocMDP" {
+			continue
+		}
+
+		// The default permission value is 2.
+		permission := 2
+		if params, ok := core.GetDict(ref.Get("TransformParams")); ok {
+			if p, ok := core.GetIntVal(params.Get("P")); ok {
+				permission = p
+			}
+		}
+		return permission, true
+	}
+	return 0, false
+}
+
+// signingCertificate returns the certificate used for signing the signature.
+func (sig *PdfSignature) signingCertificate() (*x509.Certificate, error) {
+	if handler, ok := sig.Handler.(signatureHandlerCertificate); ok {
+		if cert := handler.Certificate(); cert != nil {
+			return cert, nil
+		}
+	}
+
+	var certData []byte
+	switch certObj := core.TraceToDirectObject(sig.Cert).(type) {
+	case *core.PdfObjectString:
+		certData = certObj.Bytes()
+	case *core.PdfObjectArray:
+		if certObj.Len() > 0 {
+			if certStr, ok := core.GetString(certObj.Get(0)); ok {
+				certData = certStr.Bytes()
+			}
+		}
+	}
+	if len(certData) == 0 {
+		return nil, errors.New("unable to determine the signing certificate")
+	}
+	return x509.ParseCertificate(certData)
+}
+
+// SignatureSeedCertFlag represents the flags of a certificate seed value dictionary (Ff entry).
+// A set flag marks the corresponding certificate seed value entry as a required constraint.
+type SignatureSeedCertFlag uint32
+
+// Certificate seed value flags.
+const (
+	SignatureSeedCertFlagSubject SignatureSeedCertFlag = 1 << iota
+	SignatureSeedCertFlagIssuer
+	SignatureSeedCertFlagOID
+	SignatureSeedCertFlagSubjectDN
+	SignatureSeedCertFlagReserved
+	SignatureSeedCertFlagKeyUsage
+	SignatureSeedCertFlagURL
+)
+
+// PdfSignatureFieldSeedCertificate represents a certificate seed value dictionary, containing
+// information about the characteristics of the certificate that shall be used when signing.
+// (Section 12.7.4.5, Table 235 - Entries in a certificate seed value dictionary p. 457 in PDF32000_2008).
+type PdfSignatureFieldSeedCertificate struct {
+	Type      *core.PdfObjectName
+	Ff        *core.PdfObjectInteger
+	Subject   *core.PdfObjectArray
+	SubjectDN *core.PdfObjectArray
+	KeyUsage  *core.PdfObjectArray
+	Issuer    *core.PdfObjectArray
+	OID       *core.PdfObjectArray
+	URL       *core.PdfObjectString
+	URLType   *core.PdfObjectName
+
+	container core.PdfObject
+}
+
+// NewPdfSignatureFieldSeedCertificate returns a new empty certificate seed value dictionary.
+func NewPdfSignatureFieldSeedCertificate() *PdfSignatureFieldSeedCertificate {
+	return &PdfSignatureFieldSeedCertificate{
+		Type:      core.MakeName("SVCert"),
+		container: core.MakeDict(),
+	}
+}
+
+// newPdfSignatureFieldSeedCertificateFromObject loads a certificate seed value dictionary from the specified object.
+func newPdfSignatureFieldSeedCertificateFromObject(obj core.PdfObject) (*PdfSignatureFieldSeedCertificate, error) {
+	d, ok := core.GetDict(obj)
+	if !ok {
+		return nil, ErrTypeCheck
+	}
+
+	cert := &PdfSignatureFieldSeedCertificate{container: obj}
+	cert.Type, _ = core.GetName(d.Get("Type"))
+	cert.Ff, _ = core.GetInt(d.Get("Ff"))
+	cert.Subject, _ = core.GetArray(d.Get("Subject"))
+	cert.SubjectDN, _ = core.GetArray(d.Get("SubjectDN"))
+	cert.KeyUsage, _ = core.GetArray(d.Get("KeyUsage"))
+	cert.Issuer, _ = core.GetArray(d.Get("Issuer"))
+	cert.OID, _ = core.GetArray(d.Get("OID"))
+	cert.URL, _ = core.GetString(d.Get("URL"))
+	cert.URLType, _ = core.GetName(d.Get("URLType"))
+	return cert, nil
+}
+
+// GetContainingPdfObject implements interface PdfModel.
+func (sc *PdfSignatureFieldSeedCertificate) GetContainingPdfObject() core.PdfObject {
+	return sc.container
+}
+
+// ToPdfObject implements interface PdfModel.
+func (sc *PdfSignatureFieldSeedCertificate) ToPdfObject() core.PdfObject {
+	d, _ := core.GetDict(sc.container)
+	d.Clear()
+	d.SetIfNotNil("Type", sc.Type)
+	d.SetIfNotNil("Ff", sc.Ff)
+	d.SetIfNotNil("Subject", sc.Subject)
+	d.SetIfNotNil("SubjectDN", sc.SubjectDN)
+	d.SetIfNotNil("KeyUsage", sc.KeyUsage)
+	d.SetIfNotNil("Issuer", sc.Issuer)
+	d.SetIfNotNil("OID", sc.OID)
+	d.SetIfNotNil("URL", sc.URL)
+	d.SetIfNotNil("URLType", sc.URLType)
+	return sc.container
+}
+
+// Flags returns the certificate seed value flags.
+func (sc *PdfSignatureFieldSeedCertificate) Flags() SignatureSeedCertFlag {
+	if sc.Ff == nil {
+		return 0
+	}
+	return SignatureSeedCertFlag(*sc.Ff)
+}
+
+// SetFlags sets the certificate seed value flags.
+func (sc *PdfSignatureFieldSeedCertificate) SetFlags(flags SignatureSeedCertFlag) {
+	sc.Ff = core.MakeInteger(int64(flags))
+}
+
+// AddSubject adds a certificate which is acceptable for signing.
+func (sc *PdfSignatureFieldSeedCertificate) AddSubject(cert *x509.Certificate) {
+	if sc.Subject == nil {
+		sc.Subject = core.MakeArray()
+	}
+	sc.Subject.Append(core.MakeString(string(cert.Raw)))
+}
+
+// AddIssuer adds an issuer certificate. The signing certificate must be issued
+// by one of the specified issuers.
+func (sc *PdfSignatureFieldSeedCertificate) AddIssuer(cert *x509.Certificate) {
+	if sc.Issuer == nil {
+		sc.Issuer = core.MakeArray()
+	}
+	sc.Issuer.Append(core.MakeString(string(cert.Raw)))
+}
+
+// AddSubjectDN adds an acceptable subject distinguished name. The map keys are
+// attribute names (e.g. CN, O, OU, C, ST, L, serialNumber, email) or dotted
+// attribute type object identifiers.
+func (sc *PdfSignatureFieldSeedCertificate) AddSubjectDN(attrs map[string]string) {
+	if sc.SubjectDN == nil {
+		sc.SubjectDN = core.MakeArray()
+	}
+	dn := core.MakeDict()
+	for key, val := range attrs {
+		dn.Set(core.PdfObjectName(key), core.MakeString(val))
+	}
+	sc.SubjectDN.Append(dn)
+}
+
+// AddKeyUsage adds an acceptable key usage pattern. The pattern is a string of
+// up to 9 characters which correspond to the key usage bits of the certificate
+// (digitalSignature, nonRepudiation, keyEncipherment, dataEncipherment,
+// keyAgreement, keyCertSign, cRLSign, encipherOnly, decipherOnly). Each
+// character must be '1' (usage required), '0' (usage must not be set)
+// or 'X' (usage is irrelevant).
+func (sc *PdfSignatureFieldSeedCertificate) AddKeyUsage(pattern string) {
+	if sc.KeyUsage == nil {
+		sc.KeyUsage = core.MakeArray()
+	}
+	sc.KeyUsage.Append(core.MakeString(pattern))
+}
+
+// AddOID adds an acceptable certificate policy object identifier.
+func (sc *PdfSignatureFieldSeedCertificate) AddOID(oid asn1.ObjectIdentifier) {
+	if sc.OID == nil {
+		sc.OID = core.MakeArray()
+	}
+	sc.OID.Append(core.MakeString(oid.String()))
+}
+
+// subjectDNAttributes maps subject distinguished name attribute names to their object identifiers.
+var subjectDNAttributes = map[string]string{
+	"CN":           "2.5.4.3",
+	"serialNumber": "2.5.4.5",
+	"C":            "2.5.4.6",
+	"L":            "2.5.4.7",
+	"ST":           "2.5.4.8",
+	"O":            "2.5.4.10",
+	"OU":           "2.5.4.11",
+	"email":        "1.2.840.113549.1.9.1",
+}
+
+// CheckCertificate checks whether the specified certificate satisfies the
+// required constraints of the certificate seed value dictionary.
+func (sc *PdfSignatureFieldSeedCertificate) CheckCertificate(cert *x509.Certificate) error {
+	if cert == nil {
+		return errors.New("certificate cannot be nil")
+	}
+	flags := sc.Flags()
+
+	// Subject.
+	if flags&SignatureSeedCertFlagSubject != 0 && sc.Subject != nil && sc.Subject.Len() > 0 {
+		var found bool
+		for _, obj := range sc.Subject.Elements() {
+			if str, ok := core.GetString(obj); ok && bytes.Equal(str.Bytes(), cert.Raw) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("signing certificate not allowed by seed value")
+		}
+	}
+
+	// Issuer.
+	if flags&SignatureSeedCertFlagIssuer != 0 && sc.Issuer != nil && sc.Issuer.Len() > 0 {
+		var found bool
+		for _, obj := range sc.Issuer.Elements() {
+			str, ok := core.GetString(obj)
+			if !ok {
+				continue
+			}
+			issuer, err := x509.ParseCertificate(str.Bytes())
+			if err != nil {
+				common.Log.Debug("ERROR: invalid seed value issuer certificate: %v", err)
+				continue
+			}
+			if bytes.Equal(issuer.Raw, cert.Raw) || cert.CheckSignatureFrom(issuer) == nil {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("signing certificate issuer not allowed by seed value")
+		}
+	}
+
+	// OID.
+	if flags&SignatureSeedCertFlagOID != 0 && sc.OID != nil && sc.OID.Len() > 0 {
+		var found bool
+		for _, obj := range sc.OID.Elements() {
+			str, ok := core.GetString(obj)
+			if !ok {
+				continue
+			}
+			for _, policy := range cert.PolicyIdentifiers {
+				if policy.String() == str.Decoded() {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			return errors.New("signing certificate policy not allowed by seed value")
+		}
+	}
+
+	// SubjectDN.
+	if flags&SignatureSeedCertFlagSubjectDN != 0 && sc.SubjectDN != nil && sc.SubjectDN.Len() > 0 {
+		var found bool
+		for _, obj := range sc.SubjectDN.Elements() {
+			if dn, ok := core.GetDict(obj); ok && matchSubjectDN(cert, dn) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("signing certificate subject not allowed by seed value")
+		}
+	}
+
+	// KeyUsage.
+	if flags&SignatureSeedCertFlagKeyUsage != 0 && sc.KeyUsage != nil && sc.KeyUsage.Len() > 0 {
+		var found bool
+		for _, obj := range sc.KeyUsage.Elements() {
+			if str, ok := core.GetString(obj); ok && matchKeyUsage(cert.KeyUsage, str.Decoded()) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("signing certificate key usage not allowed by seed value")
+		}
+	}
+
+	return nil
+}
+
+// matchSubjectDN returns true if all the attributes of the specified distinguished
+// name dictionary are present in the certificate subject.
+func matchSubjectDN(cert *x509.Certificate, dn *core.PdfObjectDictionary) bool {
+	for _, key := range dn.Keys() {
+		val, ok := core.GetString(dn.Get(key))
+		if !ok {
+			return false
+		}
+		oid, ok := subjectDNAttributes[string(key)]
+		if !ok {
+			oid = string(key)
+		}
+
+		var found bool
+		for _, attr := range cert.Subject.Names {
+			if attr.Type.String() != oid {
+				continue
+			}
+			if s, ok := attr.Value.(string); ok && strings.EqualFold(s, val.Decoded()) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// matchKeyUsage returns true if the key usage matches the specified pattern.
+func matchKeyUsage(usage x509.KeyUsage, pattern string) bool {
+	for i, c := range pattern {
+		isSet := usage&(1<<uint(i)) != 0
+		switch c {
+		case '0':
+			if isSet {
+				return false
+			}
+		case '1':
+			if !isSet {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// containsName returns true if the array contains a name or string object with the specified value.
+func containsName(arr *core.PdfObjectArray, val string) bool {
+	for _, obj := range arr.Elements() {
+		if name, ok := core.GetNameVal(obj); ok && name == val {
+			return true
+		}
+		if str, ok := core.GetStringVal(obj); ok && str == val {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString returns true if the list contains the specified string.
+func containsString(list []string, val string) bool {
+	for _, s := range list {
+		if s == val {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,304 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/TheLinker/unipdf/v3/core"
+)
+
+// StandardApplier is the interface that converts a document to a PDF standard
+// (e.g. PDF/X) prior to writing.
+//
+// ApplyStandard receives the document structure and modifies it in place.
+// An error must be returned if the document cannot be made conformant.
+type StandardApplier interface {
+	ApplyStandard(doc *StandardDocument) error
+}
+
+// StandardDocument provides access to the document level objects which are
+// relevant when applying or validating PDF standards. It is used by the
+// StandardApplier implementations and can be obtained for read documents
+// using PdfReader.GetStandardDocument.
+type StandardDocument struct {
+	// Catalog is the document catalog dictionary.
+	Catalog *core.PdfObjectDictionary
+
+	// Info is the document information dictionary.
+	Info *core.PdfObjectDictionary
+
+	// ID is the file identifier array of the trailer (can be nil).
+	ID *core.PdfObjectArray
+
+	// Pages contains the page objects of the document in order.
+	Pages []*core.PdfIndirectObject
+
+	// Version is the PDF version of the document.
+	Version core.Version
+
+	// Encrypted specifies whether the document is encrypted.
+	Encrypted bool
+
+	// Encryption is the algorithm used for encrypting the document. When
+	// writing, the document is encrypted again if the applier changes it,
+	// provided that the passwords were specified using PdfWriter.Encrypt.
+	Encryption EncryptionAlgorithm
+}
+
+// XMPProperty is a simple property added to the XMP metadata created by
+// StandardDocument.NewMetadataStream.
+type XMPProperty struct {
+	// Prefix and Namespace are the prefix and the URI of the namespace of
+	// the property. The namespace is declared unless it is a namespace of the
+	// properties of the document information (dc, xmp, pdf and xmpMM).
+	Prefix    string
+	Namespace string
+
+	// Name is the local name of the property.
+	Name string
+
+	// Value is the text value of the property.
+	Value string
+}
+
+// xmpNamespaces are the namespaces of the XMP metadata created by
+// StandardDocument.NewMetadataStream.
+var xmpNamespaces = [][2]string{
+	{"dc", "http://purl.org/dc/elements/1.1/"},
+	{"xmp", "http://ns.adobe.com/xap/1.0/"},
+	{"pdf", "http://ns.adobe.com/pdf/1.3/"},
+	{"xmpMM", "http://ns.adobe.com/xap/1.0/mm/"},
+}
+
+// NewMetadataStream creates an XMP metadata stream (section 14.3.2)
+// mirroring the entries of the document information dictionary, followed by
+// the properties `props`. The dates missing from the document information
+// and the metadata date are set to `now`. The document and instance IDs are
+// UUIDs derived from the file identifier.
+func (doc *StandardDocument) NewMetadataStream(now time.Time,
+	props ...XMPProperty) (*core.PdfObjectStream, error) {
+	const dateFormat = "2006-01-02T15:04:05-07:00"
+
+	infoVal := func(key core.PdfObjectName) string {
+		if doc.Info == nil {
+			return ""
+		}
+		if val, ok := core.GetStringVal(doc.Info.Get(key)); ok {
+			return val
+		}
+		val, _ := core.GetNameVal(doc.Info.Get(key))
+		return val
+	}
+	infoDate := func(key core.PdfObjectName) string {
+		if date, err := NewPdfDate(infoVal(key)); err == nil {
+			return date.ToGoTime().Format(dateFormat)
+		}
+		return now.Format(dateFormat)
+	}
+
+	var body bytes.Buffer
+	writeProp := func(name, format, val string) {
+		var escaped bytes.Buffer
+		xml.EscapeText(&escaped, []byte(val))
+		fmt.Fprintf(&body, "   <%s>"+format+"</%s>\n", name, escaped.String(), name)
+	}
+	writeProp("dc:format", "%s", "application/pdf")
+	if title := infoVal("Title"); title != "" {
+		writeProp("dc:title", `<rdf:Alt><rdf:li xml:lang="x-default">%s</rdf:li></rdf:Alt>`, title)
+	}
+	if author := infoVal("Author"); author != "" {
+		writeProp("dc:creator", "<rdf:Seq><rdf:li>%s</rdf:li></rdf:Seq>", author)
+	}
+	if subject := infoVal("Subject"); subject != "" {
+		writeProp("dc:description", `<rdf:Alt><rdf:li xml:lang="x-default">%s</rdf:li></rdf:Alt>`, subject)
+	}
+	writeProp("xmp:CreateDate", "%s", infoDate("CreationDate"))
+	writeProp("xmp:ModifyDate", "%s", infoDate("ModDate"))
+	writeProp("xmp:MetadataDate", "%s", now.Format(dateFormat))
+	if creator := infoVal("Creator"); creator != "" {
+		writeProp("xmp:CreatorTool", "%s", creator)
+	}
+	if producer := infoVal("Producer"); producer != "" {
+		writeProp("pdf:Producer", "%s", producer)
+	}
+	if keywords := infoVal("Keywords"); keywords != "" {
+		writeProp("pdf:Keywords", "%s", keywords)
+	}
+	if trapped := infoVal("Trapped"); trapped != "" {
+		writeProp("pdf:Trapped", "%s", trapped)
+	}
+	if doc.ID != nil && doc.ID.Len() == 2 {
+		documentID, _ := core.GetStringVal(doc.ID.Get(0))
+		instanceID, _ := core.GetStringVal(doc.ID.Get(1))
+		writeProp("xmpMM:DocumentID", "%s", xmpUUID(documentID))
+		writeProp("xmpMM:InstanceID", "%s", xmpUUID(instanceID))
+	}
+
+	namespaces := append([][2]string(nil), xmpNamespaces...)
+	declared := map[string]bool{}
+	for _, ns := range namespaces {
+		declared[ns[0]] = true
+	}
+	for _, prop := range props {
+		if !declared[prop.Prefix] {
+			declared[prop.Prefix] = true
+			namespaces = append(namespaces, [2]string{prop.Prefix, prop.Namespace})
+		}
+		writeProp(prop.Prefix+":"+prop.Name, "%s", prop.Value)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	buf.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	buf.WriteString(" <rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+	buf.WriteString("  <rdf:Description rdf:about=\"\"")
+	for _, ns := range namespaces {
+		fmt.Fprintf(&buf, "\n    xmlns:%s=\"%s\"", ns[0], ns[1])
+	}
+	buf.WriteString(">\n")
+	buf.Write(body.Bytes())
+	buf.WriteString("  </rdf:Description>\n </rdf:RDF>\n</x:xmpmeta>\n<?xpacket end=\"w\"?>")
+
+	// Metadata streams are left uncompressed so that they can be read by
+	// applications which are not PDF aware.
+	stream, err := core.MakeStream(buf.Bytes(), nil)
+	if err != nil {
+		return nil, err
+	}
+	stream.Set("Type", core.MakeName("Metadata"))
+	stream.Set("Subtype", core.MakeName("XML"))
+	return stream, nil
+}
+
+// xmpUUID returns the UUID URI of the file identifier string `id`, an
+// RFC 4122 name-based UUID (version 3) computed from the MD5 hash of `id`.
+func xmpUUID(id string) string {
+	u := md5.Sum([]byte(id))
+	u[6] = u[6]&0x0f | 0x30
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("uuid:%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// SetStandard sets the standard applier used to convert the document to a
+// PDF standard before writing.
+func (w *PdfWriter) SetStandard(applier StandardApplier) {
+	w.standard = applier
+}
+
+// GetStandard returns the current standard applier.
+func (w *PdfWriter) GetStandard() StandardApplier {
+	return w.standard
+}
+
+// applyStandard runs the standard applier over the writer objects.
+// The version set by the applier is binding: object streams and cross
+// reference streams are not used for versions prior to 1.5.
+func (w *PdfWriter) applyStandard() error {
+	info, ok := core.GetDict(w.infoObj)
+	if !ok {
+		return errors.New("invalid info object")
+	}
+
+	pagesDict, ok := core.GetDict(w.pages)
+	if !ok {
+		return errors.New("invalid Pages obj (not a dict)")
+	}
+	kids, ok := core.GetArray(pagesDict.Get("Kids"))
+	if !ok {
+		return errors.New("invalid Pages Kids obj (not an array)")
+	}
+	var pages []*core.PdfIndirectObject
+	for _, kid := range kids.Elements() {
+		if page, ok := core.GetIndirect(kid); ok {
+			pages = append(pages, page)
+		}
+	}
+
+	doc := &StandardDocument{
+		Catalog:   w.catalog,
+		Info:      info,
+		ID:        w.ids,
+		Pages:     pages,
+		Version:   core.Version{Major: w.majorVersion, Minor: w.minorVersion},
+		Encrypted: w.crypter != nil,
+	}
+	if w.encryptOpts != nil {
+		doc.Encryption = w.encryptOpts.Algorithm
+	}
+	if err := w.standard.ApplyStandard(doc); err != nil {
+		return err
+	}
+
+	w.ids = doc.ID
+	if doc.Encrypted && w.encryptOpts != nil && doc.Encryption != w.encryptOpts.Algorithm {
+		if err := w.reencrypt(doc.Encryption); err != nil {
+			return err
+		}
+	}
+	w.majorVersion = doc.Version.Major
+	w.minorVersion = doc.Version.Minor
+	w.versionLocked = true
+
+	// Add any objects introduced by the applier.
+	if err := w.addObjects(w.catalog); err != nil {
+		return err
+	}
+	return w.addObjects(info)
+}
+
+// reencrypt replaces the encryption of the output file, using the
+// passwords and permissions previously specified using Encrypt.
+func (w *PdfWriter) reencrypt(algo EncryptionAlgorithm) error {
+	if w.encryptPass == nil {
+		return errors.New("encryption passwords not available")
+	}
+	if w.encryptObj != nil {
+		delete(w.objectsMap, w.encryptObj)
+		for i, obj := range w.objects {
+			if obj == w.encryptObj {
+				w.objects = append(w.objects[:i], w.objects[i+1:]...)
+				break
+			}
+		}
+	}
+
+	opts := *w.encryptOpts
+	opts.Algorithm = algo
+	return w.Encrypt(w.encryptPass.user, w.encryptPass.owner, &opts)
+}
+
+// GetStandardDocument returns the document structure used for validating
+// the document against PDF standards.
+func (r *PdfReader) GetStandardDocument() (*StandardDocument, error) {
+	trailer, err := r.GetTrailer()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := r.IsEncrypted()
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &StandardDocument{
+		Catalog:   r.catalog,
+		Pages:     r.pageList,
+		Version:   r.PdfVersion(),
+		Encrypted: encrypted,
+	}
+	if info, ok := core.GetDict(trailer.Get("Info")); ok {
+		doc.Info = info
+	}
+	if id, ok := core.GetArray(trailer.Get("ID")); ok {
+		doc.ID = id
+	}
+	return doc, nil
+}
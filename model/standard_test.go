@@ -0,0 +1,60 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/TheLinker/unipdf/v3/core"
+)
+
+func TestStandardDocumentNewMetadataStream(t *testing.T) {
+	info := core.MakeDict()
+	info.Set("Title", core.MakeString("Q&A"))
+	info.Set("Author", core.MakeString("Author"))
+	info.Set("CreationDate", core.MakeString("D:20200102030405Z"))
+	info.Set("Trapped", core.MakeName("False"))
+	id := core.MakeArray(core.MakeHexString("0123456789abcdef"), core.MakeHexString("fedcba9876543210"))
+	doc := &StandardDocument{Catalog: core.MakeDict(), Info: info, ID: id}
+
+	now := time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)
+	stream, err := doc.NewMetadataStream(now,
+		XMPProperty{Prefix: "xmpMM", Name: "VersionID", Value: "1"},
+		XMPProperty{Prefix: "ex", Namespace: "http://example.com/ns/", Name: "Name", Value: "a<b"})
+	require.NoError(t, err)
+	require.Equal(t, "Metadata", stream.Get("Type").String())
+	require.Equal(t, "XML", stream.Get("Subtype").String())
+	require.Nil(t, stream.Get("Filter"))
+
+	xmp := string(stream.Stream)
+	for _, s := range []string{
+		`<rdf:li xml:lang="x-default">Q&amp;A</rdf:li>`,
+		`<dc:creator><rdf:Seq><rdf:li>Author</rdf:li></rdf:Seq></dc:creator>`,
+		`<xmp:CreateDate>2020-01-02T03:04:05+00:00</xmp:CreateDate>`,
+		`<xmp:ModifyDate>2021-02-03T04:05:06+00:00</xmp:ModifyDate>`,
+		`<pdf:Trapped>False</pdf:Trapped>`,
+		`<xmpMM:VersionID>1</xmpMM:VersionID>`,
+		`xmlns:ex="http://example.com/ns/"`,
+		`<ex:Name>a&lt;b</ex:Name>`,
+	} {
+		require.Contains(t, xmp, s)
+	}
+	require.Equal(t, 1, strings.Count(xmp, "xmlns:xmpMM="))
+
+	// The document and instance IDs are RFC 4122 UUIDs.
+	re := regexp.MustCompile(`<xmpMM:(DocumentID|InstanceID)>uuid:([0-9a-f-]+)</xmpMM:`)
+	matches := re.FindAllStringSubmatch(xmp, -1)
+	require.Len(t, matches, 2)
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-3[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	require.Regexp(t, uuid, matches[0][2])
+	require.Regexp(t, uuid, matches[1][2])
+	require.NotEqual(t, matches[0][2], matches[1][2])
+}
@@ -0,0 +1,534 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package transparency
+
+import (
+	"math"
+
+	"github.com/TheLinker/unipdf/v3/common"
+	"github.com/TheLinker/unipdf/v3/contentstream"
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/internal/transform"
+	"github.com/TheLinker/unipdf/v3/model"
+)
+
+// maxFormDepth limits the nesting of the analyzed form XObjects.
+const maxFormDepth = 16
+
+// paintKind represents the type of a painting operation.
+type paintKind int
+
+const (
+	paintPath paintKind = iota
+	paintText
+	paintImage
+	paintForm
+	paintShading
+)
+
+// paintOp represents a painting operation of a content stream.
+type paintOp struct {
+	// index is the index of the painting operation and start is the index
+	// of the first operation of the painted object (e.g. the first path
+	// construction operation).
+	index int
+	start int
+
+	kind        paintKind
+	bbox        transform.Rect
+	transparent bool
+
+	// fill and stroke specify whether a path is filled and/or stroked.
+	fill   bool
+	stroke bool
+
+	// vector specifies whether the operation can be replaced by an opaque
+	// vector equivalent, using the colors composited with the page background.
+	vector      bool
+	fillColor   model.PdfColor
+	strokeColor model.PdfColor
+}
+
+// graphicsState tracks the graphics state parameters which are not
+// tracked by the content stream processor.
+type graphicsState struct {
+	fillAlpha   float64
+	strokeAlpha float64
+	blendMode   string
+	softMask    bool
+	lineWidth   float64
+	textRender  int
+	clipped     bool
+}
+
+// transparent returns true if painting with the fill and/or stroke colors
+// in the graphics state involves transparency.
+func (gs graphicsState) transparent(fill, stroke bool) bool {
+	if gs.softMask || gs.blendMode != "Normal" && gs.blendMode != "Compatible" {
+		return true
+	}
+	return fill && gs.fillAlpha < 1 || stroke && gs.strokeAlpha < 1
+}
+
+// isSeparableBlendMode returns true for the blend modes, which produce the
+// same result as the Normal blend mode when compositing over a white backdrop.
+func isSeparableBlendMode(mode string) bool {
+	switch mode {
+	case "Normal", "Compatible", "Multiply", "Darken":
+		return true
+	}
+	return false
+}
+
+// analysis contains the result of a content stream analysis.
+type analysis struct {
+	ops    contentstream.ContentStreamOperations
+	paints []*paintOp
+
+	// ctm contains the transformation matrix after each operation and
+	// insertable specifies whether content can be inserted after each
+	// operation, i.e. outside of text objects, paths and clipped regions.
+	ctm        []transform.Matrix
+	insertable []bool
+
+	// Text render modes set prior to each text showing operation.
+	textRender map[int]int
+}
+
+// hasTransparency returns true if any painting operation is transparent.
+func (a *analysis) hasTransparency() bool {
+	for _, p := range a.paints {
+		if p.transparent {
+			return true
+		}
+	}
+	return false
+}
+
+// analyzer performs the content stream analysis.
+type analyzer struct {
+	pageBox transform.Rect
+	visited map[*core.PdfObjectStream]bool
+	depth   int
+}
+
+// analyze processes the content stream operations `ops`, identifying the
+// painting operations, their bounding boxes and whether they are transparent.
+func (z *analyzer) analyze(ops contentstream.ContentStreamOperations, resources *model.PdfPageResources) (*analysis, error) {
+	a := &analysis{
+		ops:        ops,
+		ctm:        make([]transform.Matrix, len(ops)),
+		insertable: make([]bool, len(ops)),
+		textRender: map[int]int{},
+	}
+	index := make(map[*contentstream.ContentStreamOperation]int, len(ops))
+	for i, op := range ops {
+		index[op] = i
+	}
+
+	state := graphicsState{fillAlpha: 1, strokeAlpha: 1, blendMode: "Normal", lineWidth: 1}
+	var stack []graphicsState
+	var (
+		inText    bool
+		inPath    bool
+		pathClip  bool
+		pathStart int
+		pathBox   = transform.EmptyRect()
+		tm, tlm   = transform.IdentityMatrix(), transform.IdentityMatrix()
+		fontSize  = 1.0
+		leading   float64
+	)
+
+	addPoints := func(ctm transform.Matrix, coords ...float64) {
+		for i := 0; i+1 < len(coords); i += 2 {
+			x, y := ctm.Transform(coords[i], coords[i+1])
+			pathBox = pathBox.Extend(x, y)
+		}
+	}
+	textBox := func(ctm transform.Matrix, numChars int) transform.Rect {
+		width := float64(numChars) * fontSize
+		m := ctm.Mult(tm)
+		r := transform.EmptyRect()
+		for _, pt := range [][2]float64{{0, -0.3 * fontSize}, {width, -0.3 * fontSize}, {0, fontSize}, {width, fontSize}} {
+			x, y := m.Transform(pt[0], pt[1])
+			r = r.Extend(x, y)
+		}
+		tm = tm.Mult(transform.TranslationMatrix(width, 0))
+		return r
+	}
+	nextLine := func() {
+		tlm = tlm.Mult(transform.TranslationMatrix(0, -leading))
+		tm = tlm
+	}
+
+	processor := contentstream.NewContentStreamProcessor(ops)
+	processor.AddHandler(contentstream.HandlerConditionEnumAllOperands, "",
+		func(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState, resources *model.PdfPageResources) error {
+			i, ok := index[op]
+			if !ok {
+				return nil
+			}
+			a.ctm[i] = gs.CTM
+
+			addPaint := func(p *paintOp) {
+				p.index = i
+				if p.start == 0 && p.kind != paintPath {
+					p.start = i
+				}
+				a.paints = append(a.paints, p)
+			}
+
+			switch op.Operand {
+			case "q":
+				stack = append(stack, state)
+			case "Q":
+				if n := len(stack); n > 0 {
+					state = stack[n-1]
+					stack = stack[:n-1]
+				}
+			case "w":
+				if len(op.Params) == 1 {
+					if w, err := core.GetNumberAsFloat(op.Params[0]); err == nil {
+						state.lineWidth = w
+					}
+				}
+			case "gs":
+				if len(op.Params) != 1 || resources == nil {
+					break
+				}
+				name, ok := core.GetName(op.Params[0])
+				if !ok {
+					break
+				}
+				obj, ok := resources.GetExtGState(*name)
+				if !ok {
+					break
+				}
+				if dict, ok := core.GetDict(obj); ok {
+					applyExtGState(&state, dict)
+				}
+			case "Tr":
+				if len(op.Params) == 1 {
+					if mode, ok := core.GetIntVal(op.Params[0]); ok {
+						state.textRender = mode
+					}
+				}
+			case "W", "W*":
+				state.clipped = true
+				pathClip = true
+
+			// Path construction.
+			case "m", "l", "c", "v", "y", "re", "h":
+				if !inPath {
+					inPath = true
+					pathClip = false
+					pathStart = i
+					pathBox = transform.EmptyRect()
+				}
+				coords, err := core.GetNumbersAsFloat(op.Params)
+				if err != nil {
+					break
+				}
+				if op.Operand == "re" && len(coords) == 4 {
+					x, y, w, h := coords[0], coords[1], coords[2], coords[3]
+					addPoints(gs.CTM, x, y, x+w, y, x, y+h, x+w, y+h)
+				} else {
+					addPoints(gs.CTM, coords...)
+				}
+
+			// Path painting.
+			case "S", "s", "f", "F", "f*", "B", "B*", "b", "b*":
+				fill := op.Operand != "S" && op.Operand != "s"
+				stroke := !fill || op.Operand[0] == 'B' || op.Operand[0] == 'b'
+				bbox := pathBox
+				if stroke {
+					w := state.lineWidth * (gs.CTM.ScalingFactorX() + gs.CTM.ScalingFactorY()) / 2
+					bbox = bbox.Expand(math.Max(w, 1) / 2)
+				}
+				p := &paintOp{
+					start:       pathStart,
+					kind:        paintPath,
+					bbox:        bbox,
+					fill:        fill,
+					stroke:      stroke,
+					transparent: state.transparent(fill, stroke),
+				}
+				// Paths which are also used for clipping are not replaced, as the
+				// clipping would be undone when restoring the graphics state.
+				if p.transparent && !pathClip && !state.softMask && isSeparableBlendMode(state.blendMode) {
+					p.vector = true
+					if fill {
+						p.fillColor, p.vector = compositeOverWhite(gs.ColorspaceNonStroking, gs.ColorNonStroking, state.fillAlpha)
+					}
+					if stroke && p.vector {
+						p.strokeColor, p.vector = compositeOverWhite(gs.ColorspaceStroking, gs.ColorStroking, state.strokeAlpha)
+					}
+				}
+				addPaint(p)
+				inPath = false
+			case "n":
+				inPath = false
+
+			// Text.
+			case "BT":
+				inText = true
+				tm, tlm = transform.IdentityMatrix(), transform.IdentityMatrix()
+			case "ET":
+				inText = false
+			case "Tf":
+				if len(op.Params) == 2 {
+					if size, err := core.GetNumberAsFloat(op.Params[1]); err == nil {
+						fontSize = math.Abs(size)
+					}
+				}
+			case "TL":
+				if len(op.Params) == 1 {
+					if tl, err := core.GetNumberAsFloat(op.Params[0]); err == nil {
+						leading = tl
+					}
+				}
+			case "Td", "TD":
+				coords, err := core.GetNumbersAsFloat(op.Params)
+				if err != nil || len(coords) != 2 {
+					break
+				}
+				if op.Operand == "TD" {
+					leading = -coords[1]
+				}
+				tlm = tlm.Mult(transform.TranslationMatrix(coords[0], coords[1]))
+				tm = tlm
+			case "Tm":
+				m, ok := transform.NewMatrixFromObjects(op.Params)
+				if !ok {
+					break
+				}
+				tlm, tm = m, m
+			case "T*":
+				nextLine()
+			case "Tj", "TJ", "'", "\"":
+				if op.Operand == "'" || op.Operand == "\"" {
+					nextLine()
+				}
+				a.textRender[i] = state.textRender
+				numChars := 0
+				for _, param := range op.Params {
+					numChars += countChars(param)
+				}
+				p := &paintOp{
+					kind: paintText,
+					bbox: textBox(gs.CTM, numChars),
+				}
+				if state.textRender != 3 {
+					p.transparent = state.transparent(true, true)
+				}
+				addPaint(p)
+
+			// XObjects, inline images and shadings.
+			case "Do":
+				if len(op.Params) != 1 || resources == nil {
+					break
+				}
+				name, ok := core.GetName(op.Params[0])
+				if !ok {
+					break
+				}
+				stream, xtype := resources.GetXObjectByName(*name)
+				switch xtype {
+				case model.XObjectTypeImage:
+					p := &paintOp{
+						kind:        paintImage,
+						bbox:        unitSquare(gs.CTM),
+						transparent: state.transparent(true, false) || hasSoftMask(stream),
+					}
+					addPaint(p)
+				case model.XObjectTypeForm:
+					p, err := z.analyzeForm(stream, resources, gs.CTM, state)
+					if err != nil {
+						return err
+					}
+					addPaint(p)
+				}
+			case "BI":
+				addPaint(&paintOp{
+					kind:        paintImage,
+					bbox:        unitSquare(gs.CTM),
+					transparent: state.transparent(true, false),
+				})
+			case "sh":
+				addPaint(&paintOp{
+					kind:        paintShading,
+					bbox:        z.pageBox,
+					transparent: state.transparent(true, false),
+				})
+			}
+
+			a.insertable[i] = !inText && !inPath && !state.clipped
+			return nil
+		})
+
+	if err := processor.Process(resources); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// analyzeForm returns the painting operation corresponding to the form
+// XObject contained in `stream`. The form is transparent if the graphics
+// state is transparent or if the form content contains transparency.
+func (z *analyzer) analyzeForm(stream *core.PdfObjectStream, resources *model.PdfPageResources,
+	ctm transform.Matrix, state graphicsState) (*paintOp, error) {
+	xform, err := model.NewXObjectFormFromStream(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	m := ctm
+	if mArr, ok := core.GetArray(xform.Matrix); ok {
+		if mf, ok := transform.NewMatrixFromObjects(mArr.Elements()); ok {
+			m = ctm.Mult(mf)
+		}
+	}
+	p := &paintOp{
+		kind:        paintForm,
+		bbox:        z.pageBox,
+		transparent: state.transparent(true, true),
+	}
+	if bArr, ok := core.GetArray(xform.BBox); ok {
+		if bf, err := bArr.ToFloat64Array(); err == nil && len(bf) == 4 {
+			p.bbox = transform.EmptyRect()
+			for _, pt := range [][2]float64{{bf[0], bf[1]}, {bf[2], bf[1]}, {bf[0], bf[3]}, {bf[2], bf[3]}} {
+				x, y := m.Transform(pt[0], pt[1])
+				p.bbox = p.bbox.Extend(x, y)
+			}
+		}
+	}
+	if p.transparent || z.visited[stream] || z.depth >= maxFormDepth {
+		// Forms referencing themselves are treated as transparent, so that
+		// they are rasterized rather than processed infinitely.
+		p.transparent = true
+		return p, nil
+	}
+
+	content, err := xform.GetContentStream()
+	if err != nil {
+		return nil, err
+	}
+	ops, err := contentstream.NewContentStreamParser(string(content)).Parse()
+	if err != nil {
+		common.Log.Debug("ERROR: unable to parse form content stream: %v", err)
+		p.transparent = true
+		return p, nil
+	}
+	formResources := xform.Resources
+	if formResources == nil {
+		formResources = resources
+	}
+
+	z.visited[stream] = true
+	z.depth++
+	fa, err := z.analyze(*ops, formResources)
+	z.depth--
+	delete(z.visited, stream)
+	if err != nil {
+		return nil, err
+	}
+	p.transparent = fa.hasTransparency()
+	return p, nil
+}
+
+// applyExtGState updates the graphics state with the transparency related
+// entries of the graphics state parameter dictionary.
+func applyExtGState(state *graphicsState, dict *core.PdfObjectDictionary) {
+	if val, err := core.GetNumberAsFloat(core.TraceToDirectObject(dict.Get("ca"))); err == nil {
+		state.fillAlpha = val
+	}
+	if val, err := core.GetNumberAsFloat(core.TraceToDirectObject(dict.Get("CA"))); err == nil {
+		state.strokeAlpha = val
+	}
+	if val, err := core.GetNumberAsFloat(core.TraceToDirectObject(dict.Get("LW"))); err == nil {
+		state.lineWidth = val
+	}
+	if bm := dict.Get("BM"); bm != nil {
+		if arr, ok := core.GetArray(bm); ok && arr.Len() > 0 {
+			bm = arr.Get(0)
+		}
+		if mode, ok := core.GetNameVal(bm); ok {
+			state.blendMode = mode
+		}
+	}
+	if smask := dict.Get("SMask"); smask != nil {
+		name, isName := core.GetNameVal(smask)
+		state.softMask = !isName || name != "None"
+	}
+}
+
+// compositeOverWhite returns the color resulting from painting `color` with
+// constant alpha `alpha` over a white backdrop. Only device color spaces are
+// supported. The returned flag is false if the color cannot be composited.
+func compositeOverWhite(cs model.PdfColorspace, color model.PdfColor, alpha float64) (model.PdfColor, bool) {
+	switch c := color.(type) {
+	case *model.PdfColorDeviceGray:
+		if _, ok := cs.(*model.PdfColorspaceDeviceGray); ok {
+			return model.NewPdfColorDeviceGray(alpha*c.Val() + 1 - alpha), true
+		}
+	case *model.PdfColorDeviceRGB:
+		if _, ok := cs.(*model.PdfColorspaceDeviceRGB); ok {
+			return model.NewPdfColorDeviceRGB(
+				alpha*c.R()+1-alpha,
+				alpha*c.G()+1-alpha,
+				alpha*c.B()+1-alpha,
+			), true
+		}
+	case *model.PdfColorDeviceCMYK:
+		if _, ok := cs.(*model.PdfColorspaceDeviceCMYK); ok {
+			return model.NewPdfColorDeviceCMYK(
+				alpha*c.C(),
+				alpha*c.M(),
+				alpha*c.Y(),
+				alpha*c.K(),
+			), true
+		}
+	}
+	return nil, false
+}
+
+// hasSoftMask returns true if the image XObject has a soft mask.
+func hasSoftMask(stream *core.PdfObjectStream) bool {
+	if stream == nil {
+		return false
+	}
+	if stream.Get("SMask") != nil {
+		return true
+	}
+	val, ok := core.GetIntVal(stream.Get("SMaskInData"))
+	return ok && val != 0
+}
+
+// countChars returns the number of bytes of the strings contained in the
+// text showing operator parameter `obj`.
+func countChars(obj core.PdfObject) int {
+	switch t := obj.(type) {
+	case *core.PdfObjectString:
+		return len(t.Bytes())
+	case *core.PdfObjectArray:
+		n := 0
+		for _, elem := range t.Elements() {
+			n += countChars(elem)
+		}
+		return n
+	}
+	return 0
+}
+
+// unitSquare returns the bounding box of the unit square transformed by `m`.
+func unitSquare(m transform.Matrix) transform.Rect {
+	r := transform.EmptyRect()
+	for _, pt := range [][2]float64{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+		x, y := m.Transform(pt[0], pt[1])
+		r = r.Extend(x, y)
+	}
+	return r
+}
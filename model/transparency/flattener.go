@@ -0,0 +1,577 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+/*
+Package transparency provides a transparency flattener, which removes the
+transparency (soft masks, constant alpha and blend modes) from the content
+of PDF pages, as required by PDF/X-1a and by output devices which do not
+support the PDF 1.4 transparency model.
+
+Transparent regions are identified by processing the page content streams.
+Paths painted using constant alpha, which do not overlap previously painted
+content, are replaced by opaque vector equivalents (atomic regions) having
+the colors composited with the white page background. All other transparent
+regions are rasterized at the configured resolution and replaced by images,
+while the remaining non-transparent text and vector graphics are kept as
+they are.
+
+Example:
+
+	flattener := transparency.New(transparency.Options{Resolution: 300})
+	for _, page := range pages {
+		if _, err := flattener.FlattenPage(page); err != nil {
+			return err
+		}
+	}
+*/
+package transparency
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"sort"
+
+	"github.com/TheLinker/unipdf/v3/contentstream"
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/internal/transform"
+	"github.com/TheLinker/unipdf/v3/model"
+	"github.com/TheLinker/unipdf/v3/render"
+)
+
+// DefaultResolution is the default resolution (DPI) of the rasterized regions.
+const DefaultResolution = 300
+
+// Options define the flattening options.
+type Options struct {
+	// Resolution represents the resolution (DPI) used for rasterizing the
+	// transparent regions. If not set, DefaultResolution is used.
+	Resolution float64
+
+	// Colorspace specifies the color space of the images replacing the
+	// rasterized regions. DeviceRGB, DeviceCMYK and DeviceGray are supported.
+	// If not set, DeviceRGB is used.
+	Colorspace model.PdfColorspace
+
+	// Encoder is used for encoding the images replacing the rasterized
+	// regions. If not set, the images are flate encoded.
+	Encoder core.StreamEncoder
+
+	// RasterizeAll disables the vector replacement of transparent paths,
+	// causing all the transparent regions to be rasterized.
+	RasterizeAll bool
+}
+
+// Result contains information about the flattening of a page.
+type Result struct {
+	// VectorRegions is the number of transparent paths replaced by opaque
+	// vector equivalents.
+	VectorRegions int
+
+	// RasterRegions is the number of transparent regions replaced by images.
+	RasterRegions int
+}
+
+// Flattener removes the transparency from the content of PDF pages.
+type Flattener struct {
+	opts Options
+}
+
+// New returns a new transparency flattener using the specified options.
+func New(opts Options) *Flattener {
+	if opts.Resolution <= 0 {
+		opts.Resolution = DefaultResolution
+	}
+	if opts.Colorspace == nil {
+		opts.Colorspace = model.NewPdfColorspaceDeviceRGB()
+	}
+	return &Flattener{opts: opts}
+}
+
+// region represents a group of overlapping transparent painting operations,
+// which is rasterized as a whole.
+type region struct {
+	bbox   transform.Rect
+	last   int
+	insert int
+}
+
+// FlattenPage removes the transparency from the content of the page.
+// The content streams and the resources of the page are replaced.
+// The resources are copied, so the resources shared with other pages are
+// not modified.
+func (f *Flattener) FlattenPage(page *model.PdfPage) (*Result, error) {
+	if page == nil {
+		return nil, errors.New("page not specified")
+	}
+	mbox, err := page.GetMediaBox()
+	if err != nil {
+		return nil, err
+	}
+	contents, err := page.GetAllContentStreams()
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := contentstream.NewContentStreamParser(contents).Parse()
+	if err != nil {
+		return nil, err
+	}
+	ops := *parsed
+
+	z := &analyzer{
+		pageBox: transform.Rect{Llx: mbox.Llx, Lly: mbox.Lly, Urx: mbox.Urx, Ury: mbox.Ury},
+		visited: map[*core.PdfObjectStream]bool{},
+	}
+	a, err := z.analyze(ops, page.Resources)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{}
+	resources, err := copyResources(page.Resources)
+	if err != nil {
+		return nil, err
+	}
+	if !a.hasTransparency() {
+		page.Resources = resources
+		removeTransparencyGroup(page)
+		return res, nil
+	}
+
+	// Classify the transparent painting operations. Paths which are
+	// transparent only due to constant alpha are replaced by vector
+	// equivalents, provided that they do not overlap the previously painted
+	// content. Everything else is rasterized.
+	var vectors, rasters []*paintOp
+	for i, p := range a.paints {
+		if !p.transparent {
+			continue
+		}
+		vector := p.vector && !f.opts.RasterizeAll
+		for j := 0; vector && j < i; j++ {
+			if a.paints[j].bbox.Overlaps(p.bbox) {
+				vector = false
+			}
+		}
+		if vector {
+			vectors = append(vectors, p)
+		} else {
+			rasters = append(rasters, p)
+		}
+	}
+
+	regions := f.groupRegions(a, rasters)
+	edits := map[int]*opEdit{}
+	edit := func(i int) *opEdit {
+		e, ok := edits[i]
+		if !ok {
+			e = &opEdit{}
+			edits[i] = e
+		}
+		return e
+	}
+
+	// Replace the transparent paths by opaque vector equivalents.
+	for _, p := range vectors {
+		before := []*contentstream.ContentStreamOperation{{Operand: "q"}}
+		if p.fill {
+			before = append(before, colorOperation(p.fillColor, false))
+		}
+		if p.stroke {
+			before = append(before, colorOperation(p.strokeColor, true))
+		}
+		e := edit(p.start)
+		e.before = append(e.before, before...)
+		edit(p.index).after = append(edit(p.index).after, &contentstream.ContentStreamOperation{Operand: "Q"})
+		res.VectorRegions++
+	}
+
+	// Rasterize the transparent regions. The images of the regions which
+	// cannot be inserted in the content stream are appended at the end.
+	var tail []*contentstream.ContentStreamOperation
+	for _, r := range regions {
+		inserted, err := f.rasterizeRegion(page, mbox, ops, a, r, resources)
+		if err != nil {
+			return nil, err
+		}
+		if inserted == nil {
+			continue
+		}
+		if r.insert < 0 {
+			tail = append(tail, inserted...)
+		} else {
+			edit(r.insert).after = append(edit(r.insert).after, inserted...)
+		}
+		res.RasterRegions++
+	}
+	for _, p := range rasters {
+		edit(p.index).remove = p
+	}
+
+	// Assemble the new content stream.
+	var out contentstream.ContentStreamOperations
+	for i, op := range ops {
+		e, ok := edits[i]
+		if !ok {
+			out = append(out, op)
+			continue
+		}
+		out = append(out, e.before...)
+		out = append(out, removeOperation(op, e.remove, a)...)
+		out = append(out, e.after...)
+	}
+	if len(tail) > 0 {
+		// Wrap the content, so that the images are drawn using the
+		// default graphics state.
+		out = *out.WrapIfNeeded()
+		out = append(out, tail...)
+	}
+
+	sanitizeExtGStates(resources)
+	pruneXObjects(resources, out)
+	page.Resources = resources
+	removeTransparencyGroup(page)
+
+	if err := page.SetContentStreams([]string{out.String()}, core.NewFlateEncoder()); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// opEdit represents the modifications of a content stream operation.
+type opEdit struct {
+	before []*contentstream.ContentStreamOperation
+	after  []*contentstream.ContentStreamOperation
+	remove *paintOp
+}
+
+// groupRegions merges the overlapping bounding boxes of the rasterized
+// painting operations into regions and determines the position where
+// each region image is inserted in the content stream.
+func (f *Flattener) groupRegions(a *analysis, rasters []*paintOp) []*region {
+	var regions []*region
+	for _, p := range rasters {
+		bbox := p.bbox
+		if bbox.IsEmpty() {
+			continue
+		}
+		regions = append(regions, &region{bbox: bbox, last: p.index})
+	}
+
+	for merged := true; merged; {
+		merged = false
+		for i := 0; i < len(regions) && !merged; i++ {
+			for j := i + 1; j < len(regions); j++ {
+				if !regions[i].bbox.Overlaps(regions[j].bbox) {
+					continue
+				}
+				regions[i].bbox = regions[i].bbox.Union(regions[j].bbox)
+				if regions[j].last > regions[i].last {
+					regions[i].last = regions[j].last
+				}
+				regions = append(regions[:j], regions[j+1:]...)
+				merged = true
+				break
+			}
+		}
+	}
+
+	for _, r := range regions {
+		r.insert = -1
+		for i := r.last; i < len(a.insertable); i++ {
+			if a.insertable[i] {
+				r.insert = i
+				break
+			}
+		}
+	}
+	sort.Slice(regions, func(i, j int) bool {
+		return regions[i].last < regions[j].last
+	})
+	return regions
+}
+
+// rasterizeRegion renders the page content up to the insertion position of
+// region `r` and returns the operations which draw the image of the region.
+// The image XObject is added to `resources`.
+func (f *Flattener) rasterizeRegion(page *model.PdfPage, mbox *model.PdfRectangle,
+	ops contentstream.ContentStreamOperations, a *analysis, r *region,
+	resources *model.PdfPageResources) ([]*contentstream.ContentStreamOperation, error) {
+	end := len(ops)
+	if r.insert >= 0 {
+		end = r.insert + 1
+	}
+
+	// Render the page content painted before the insertion position.
+	tmp := model.NewPdfPage()
+	tmp.MediaBox = mbox
+	tmp.Resources = page.Resources
+	prefix := ops[:end]
+	if err := tmp.SetContentStreams([]string{prefix.String()}, nil); err != nil {
+		return nil, err
+	}
+
+	width := mbox.Llx + mbox.Width()
+	height := mbox.Lly + mbox.Height()
+	scale := f.opts.Resolution / 72
+	device := render.NewImageDevice()
+	device.OutputWidth = int(math.Round(width * scale))
+	img, err := device.Render(tmp)
+	if err != nil {
+		return nil, err
+	}
+
+	// Calculate the pixel aligned region bounds.
+	bounds := img.Bounds()
+	pageBox := transform.Rect{Llx: mbox.Llx, Lly: mbox.Lly, Urx: mbox.Urx, Ury: mbox.Ury}
+	bbox := r.bbox.Intersect(pageBox)
+	crop := image.Rect(
+		int(math.Floor(bbox.Llx*scale)), int(math.Floor((height-bbox.Ury)*scale)),
+		int(math.Ceil(bbox.Urx*scale)), int(math.Ceil((height-bbox.Lly)*scale)),
+	).Intersect(bounds)
+	if crop.Empty() {
+		return nil, nil
+	}
+	x0 := float64(crop.Min.X) / scale
+	x1 := float64(crop.Max.X) / scale
+	y0 := height - float64(crop.Max.Y)/scale
+	y1 := height - float64(crop.Min.Y)/scale
+
+	cropped := image.NewRGBA(image.Rect(0, 0, crop.Dx(), crop.Dy()))
+	draw.Draw(cropped, cropped.Bounds(), img, crop.Min, draw.Src)
+
+	ximg, err := f.newImageXObject(cropped)
+	if err != nil {
+		return nil, err
+	}
+	name := resources.GenerateXObjectName()
+	if err := resources.SetXObjectImageByName(name, ximg); err != nil {
+		return nil, err
+	}
+
+	// Map the unit square to the region bounds in the default user space,
+	// taking into account the transformation matrix at the insertion position.
+	m := transform.NewMatrix(x1-x0, 0, 0, y1-y0, x0, y0)
+	if r.insert >= 0 {
+		inv, ok := invertMatrix(a.ctm[r.insert])
+		if !ok {
+			return nil, fmt.Errorf("non-invertible transformation matrix at operation %d", r.insert)
+		}
+		m = inv.Mult(m)
+	}
+
+	return []*contentstream.ContentStreamOperation{
+		{Operand: "q"},
+		{Operand: "cm", Params: []core.PdfObject{
+			core.MakeFloat(m[0]), core.MakeFloat(m[1]),
+			core.MakeFloat(m[3]), core.MakeFloat(m[4]),
+			core.MakeFloat(m[6]), core.MakeFloat(m[7]),
+		}},
+		{Operand: "Do", Params: []core.PdfObject{core.MakeName(string(name))}},
+		{Operand: "Q"},
+	}, nil
+}
+
+// newImageXObject creates an image XObject from `img`, using the color space
+// and the encoder specified by the flattener options.
+func (f *Flattener) newImageXObject(img image.Image) (*model.XObjectImage, error) {
+	var (
+		mimg *model.Image
+		err  error
+	)
+	switch f.opts.Colorspace.(type) {
+	case *model.PdfColorspaceDeviceGray:
+		mimg, err = model.ImageHandling.NewGrayImageFromGoImage(img)
+	case *model.PdfColorspaceDeviceCMYK:
+		mimg = newCMYKImage(img)
+	case *model.PdfColorspaceDeviceRGB:
+		mimg, err = model.ImageHandling.NewImageFromGoImage(img)
+	default:
+		return nil, fmt.Errorf("unsupported colorspace: %s", f.opts.Colorspace.String())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	encoder := f.opts.Encoder
+	if encoder == nil {
+		encoder = core.NewFlateEncoder()
+	}
+	return model.NewXObjectImageFromImage(mimg, f.opts.Colorspace, encoder)
+}
+
+// newCMYKImage converts `img` to a CMYK image, using the naive conversion
+// from the RGB color space.
+func newCMYKImage(img image.Image) *model.Image {
+	b := img.Bounds()
+	data := make([]byte, 0, 4*b.Dx()*b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			c, m, yy := 1-float64(r)/0xffff, 1-float64(g)/0xffff, 1-float64(bl)/0xffff
+			k := math.Min(c, math.Min(m, yy))
+			if k < 1 {
+				c, m, yy = (c-k)/(1-k), (m-k)/(1-k), (yy-k)/(1-k)
+			} else {
+				c, m, yy = 0, 0, 0
+			}
+			data = append(data,
+				byte(c*255+0.5), byte(m*255+0.5), byte(yy*255+0.5), byte(k*255+0.5))
+		}
+	}
+	return &model.Image{
+		Width:            int64(b.Dx()),
+		Height:           int64(b.Dy()),
+		BitsPerComponent: 8,
+		ColorComponents:  4,
+		Data:             data,
+	}
+}
+
+// removeOperation returns the operations replacing the rasterized painting
+// operation `op`. Paths are discarded without painting, text is shown using
+// the invisible render mode and everything else is removed.
+func removeOperation(op *contentstream.ContentStreamOperation, p *paintOp, a *analysis) []*contentstream.ContentStreamOperation {
+	if p == nil {
+		return []*contentstream.ContentStreamOperation{op}
+	}
+	switch p.kind {
+	case paintPath:
+		return []*contentstream.ContentStreamOperation{{Operand: "n"}}
+	case paintText:
+		return []*contentstream.ContentStreamOperation{
+			{Operand: "Tr", Params: []core.PdfObject{core.MakeInteger(3)}},
+			op,
+			{Operand: "Tr", Params: []core.PdfObject{core.MakeInteger(int64(a.textRender[p.index]))}},
+		}
+	}
+	return nil
+}
+
+// colorOperation returns the operation setting the fill or stroke color
+// to the specified device color.
+func colorOperation(color model.PdfColor, stroke bool) *contentstream.ContentStreamOperation {
+	var operand string
+	var vals []float64
+	switch c := color.(type) {
+	case *model.PdfColorDeviceGray:
+		operand, vals = "g", []float64{c.Val()}
+	case *model.PdfColorDeviceRGB:
+		operand, vals = "rg", []float64{c.R(), c.G(), c.B()}
+	case *model.PdfColorDeviceCMYK:
+		operand, vals = "k", []float64{c.C(), c.M(), c.Y(), c.K()}
+	}
+	if stroke {
+		operand = map[string]string{"g": "G", "rg": "RG", "k": "K"}[operand]
+	}
+
+	op := &contentstream.ContentStreamOperation{Operand: operand}
+	for _, val := range vals {
+		op.Params = append(op.Params, core.MakeFloat(val))
+	}
+	return op
+}
+
+// invertMatrix returns the inverse of the affine transformation matrix `m`.
+func invertMatrix(m transform.Matrix) (transform.Matrix, bool) {
+	a, b, c, d, tx, ty := m[0], m[1], m[3], m[4], m[6], m[7]
+	det := a*d - b*c
+	if math.Abs(det) < 1e-12 {
+		return transform.Matrix{}, false
+	}
+	return transform.NewMatrix(
+		d/det, -b/det,
+		-c/det, a/det,
+		(c*ty-d*tx)/det, (b*tx-a*ty)/det,
+	), true
+}
+
+// copyResources returns a copy of the page resources, in which the
+// ExtGState and XObject dictionaries can be modified without affecting
+// other pages.
+func copyResources(resources *model.PdfPageResources) (*model.PdfPageResources, error) {
+	if resources == nil {
+		return model.NewPdfPageResources(), nil
+	}
+	dict, ok := core.GetDict(resources.ToPdfObject())
+	if !ok {
+		return nil, core.ErrTypeError
+	}
+	copied, err := model.NewPdfPageResourcesFromDict(dict)
+	if err != nil {
+		return nil, err
+	}
+	copied.ExtGState = copyDict(copied.ExtGState)
+	copied.XObject = copyDict(copied.XObject)
+	return copied, nil
+}
+
+// copyDict returns a shallow copy of the dictionary `obj`.
+func copyDict(obj core.PdfObject) core.PdfObject {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return obj
+	}
+	copied := core.MakeDict()
+	copied.Merge(dict)
+	return copied
+}
+
+// sanitizeExtGStates replaces the graphics state parameter dictionaries of
+// the resources by copies, which do not contain transparency entries.
+func sanitizeExtGStates(resources *model.PdfPageResources) {
+	states, ok := core.GetDict(resources.ExtGState)
+	if !ok {
+		return
+	}
+	for _, key := range states.Keys() {
+		dict, ok := core.GetDict(states.Get(key))
+		if !ok {
+			continue
+		}
+		copied := core.MakeDict()
+		copied.Merge(dict)
+		for _, name := range []core.PdfObjectName{"CA", "ca", "BM", "SMask", "AIS"} {
+			copied.Remove(name)
+		}
+		states.Set(key, copied)
+	}
+}
+
+// pruneXObjects removes the XObjects which are not referenced by the
+// content stream operations `ops` from the resources.
+func pruneXObjects(resources *model.PdfPageResources, ops contentstream.ContentStreamOperations) {
+	xobjects, ok := core.GetDict(resources.XObject)
+	if !ok {
+		return
+	}
+	used := map[core.PdfObjectName]bool{}
+	for _, op := range ops {
+		if op.Operand != "Do" || len(op.Params) != 1 {
+			continue
+		}
+		if name, ok := core.GetName(op.Params[0]); ok {
+			used[*name] = true
+		}
+	}
+	for _, key := range xobjects.Keys() {
+		if !used[key] {
+			xobjects.Remove(key)
+		}
+	}
+}
+
+// removeTransparencyGroup removes the transparency group attributes of the page.
+func removeTransparencyGroup(page *model.PdfPage) {
+	group, ok := core.GetDict(page.Group)
+	if !ok {
+		return
+	}
+	if s, ok := core.GetNameVal(group.Get("S")); ok && s == "Transparency" {
+		page.Group = nil
+	}
+}
@@ -0,0 +1,101 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package transparency_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/model"
+	"github.com/TheLinker/unipdf/v3/model/pdfx"
+	"github.com/TheLinker/unipdf/v3/model/transparency"
+)
+
+// newTransparentPage returns a page which contains an opaque rectangle,
+// overlapped by a semi-transparent one, and an isolated semi-transparent
+// rectangle.
+func newTransparentPage(t *testing.T) *model.PdfPage {
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Llx: 0, Lly: 0, Urx: 612, Ury: 792}
+	page.Resources = model.NewPdfPageResources()
+
+	gs := core.MakeDict()
+	gs.Set("ca", core.MakeFloat(0.5))
+	gs.Set("LW", core.MakeFloat(2))
+	require.NoError(t, page.AddExtGState("GS0", gs))
+
+	group := core.MakeDict()
+	group.Set("S", core.MakeName("Transparency"))
+	page.Group = group
+
+	content := `0 0 1 rg 100 100 200 200 re f
+q /GS0 gs 1 0 0 rg 150 150 100 100 re f
+0 1 0 0 k 400 400 50 50 re f Q
+0 g 400 100 50 50 re f`
+	require.NoError(t, page.AddContentStreamByString(content))
+	return page
+}
+
+func TestFlattenPage(t *testing.T) {
+	page := newTransparentPage(t)
+
+	flattener := transparency.New(transparency.Options{Resolution: 72})
+	res, err := flattener.FlattenPage(page)
+	require.NoError(t, err)
+	require.Equal(t, 1, res.VectorRegions)
+	require.Equal(t, 1, res.RasterRegions)
+	require.Nil(t, page.Group)
+
+	contents, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	require.Contains(t, contents, "Do")
+	// The isolated rectangle is painted using the composited color.
+	require.Contains(t, contents, "0 0.5 0 0 k")
+
+	// The opaque content outside the transparent regions is preserved.
+	require.Contains(t, contents, "400 100 50 50 re")
+
+	w := model.NewPdfWriter()
+	require.NoError(t, w.AddPage(page))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	violations, err := pdfx.ValidateReader(reader, pdfx.VersionX1a2003)
+	require.NoError(t, err)
+	for _, v := range violations {
+		require.NotEqual(t, pdfx.RuleTransparency, v.Rule, v.String())
+	}
+}
+
+func TestFlattenPageRasterizeAll(t *testing.T) {
+	page := newTransparentPage(t)
+
+	flattener := transparency.New(transparency.Options{
+		Resolution:   36,
+		Colorspace:   model.NewPdfColorspaceDeviceCMYK(),
+		RasterizeAll: true,
+	})
+	res, err := flattener.FlattenPage(page)
+	require.NoError(t, err)
+	require.Equal(t, 0, res.VectorRegions)
+	require.Equal(t, 2, res.RasterRegions)
+
+	contents, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(contents, " Do"))
+
+	// The image XObjects use the specified color space.
+	ximg, err := page.Resources.GetXObjectImageByName("XObj1")
+	require.NoError(t, err)
+	require.NotNil(t, ximg)
+	require.Equal(t, "DeviceCMYK", ximg.ColorSpace.String())
+}
@@ -0,0 +1,388 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+
+	"github.com/TheLinker/unipdf/v3/common"
+	"github.com/TheLinker/unipdf/v3/core"
+)
+
+// PageMode specifies how the document is displayed when opened.
+// See section 7.7.2 "Document Catalog" (Table 28 - p. 74 PDF32000_2008).
+type PageMode string
+
+// Page modes.
+const (
+	PageModeUseNone        PageMode = "UseNone"
+	PageModeUseOutlines    PageMode = "UseOutlines"
+	PageModeUseThumbs      PageMode = "UseThumbs"
+	PageModeFullScreen     PageMode = "FullScreen"
+	PageModeUseOC          PageMode = "UseOC"
+	PageModeUseAttachments PageMode = "UseAttachments"
+)
+
+// PageLayout specifies the page layout used when the document is opened.
+// See section 7.7.2 "Document Catalog" (Table 28 - p. 73 PDF32000_2008).
+type PageLayout string
+
+// Page layouts.
+const (
+	PageLayoutSinglePage     PageLayout = "SinglePage"
+	PageLayoutOneColumn      PageLayout = "OneColumn"
+	PageLayoutTwoColumnLeft  PageLayout = "TwoColumnLeft"
+	PageLayoutTwoColumnRight PageLayout = "TwoColumnRight"
+	PageLayoutTwoPageLeft    PageLayout = "TwoPageLeft"
+	PageLayoutTwoPageRight   PageLayout = "TwoPageRight"
+)
+
+// PrintScaling specifies the page scaling option selected in the print
+// dialog when the document is printed.
+type PrintScaling string
+
+// Print scaling options.
+const (
+	PrintScalingNone       PrintScaling = "None"
+	PrintScalingAppDefault PrintScaling = "AppDefault"
+)
+
+// Duplex specifies the paper handling option used when the document is
+// printed.
+type Duplex string
+
+// Duplex options.
+const (
+	DuplexSimplex       Duplex = "Simplex"
+	DuplexFlipShortEdge Duplex = "DuplexFlipShortEdge"
+	DuplexFlipLongEdge  Duplex = "DuplexFlipLongEdge"
+)
+
+// ReadingDirection specifies the predominant reading order of the text.
+type ReadingDirection string
+
+// Reading directions.
+const (
+	ReadingDirectionL2R ReadingDirection = "L2R"
+	ReadingDirectionR2L ReadingDirection = "R2L"
+)
+
+// ViewerPreferences represents the viewer preferences dictionary, which
+// controls the way the document is presented on the screen or in print.
+// The unset (zero value) entries are not written, in which case viewer
+// applications use the default values.
+// See section 12.2 "Viewer Preferences" (Table 150 - p. 362 PDF32000_2008).
+type ViewerPreferences struct {
+	// Window and user interface options.
+	HideToolbar     bool
+	HideMenubar     bool
+	HideWindowUI    bool
+	FitWindow       bool
+	CenterWindow    bool
+	DisplayDocTitle bool
+
+	// NonFullScreenPageMode specifies how to display the document on
+	// exiting full-screen mode.
+	NonFullScreenPageMode PageMode
+
+	// Direction represents the predominant reading order of the text.
+	Direction ReadingDirection
+
+	// Names of the page boundaries (e.g. CropBox, TrimBox) used when
+	// viewing and printing the document.
+	ViewArea  string
+	ViewClip  string
+	PrintArea string
+	PrintClip string
+
+	// Print dialog options.
+	PrintScaling      PrintScaling
+	Duplex            Duplex
+	PickTrayByPDFSize bool
+
+	// PrintPageRange contains the page ranges used to initialize the print
+	// dialog, as pairs of first and last page numbers (starting at 1).
+	PrintPageRange [][2]int
+
+	// NumCopies is the number of copies to be printed.
+	NumCopies int
+}
+
+// NewViewerPreferences returns a new viewer preferences object having all
+// the entries set to their default values.
+func NewViewerPreferences() *ViewerPreferences {
+	return &ViewerPreferences{}
+}
+
+// newViewerPreferencesFromPdfObject loads the viewer preferences from the
+// specified dictionary object.
+func newViewerPreferencesFromPdfObject(obj core.PdfObject) (*ViewerPreferences, error) {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return nil, ErrTypeCheck
+	}
+
+	getBool := func(key core.PdfObjectName) bool {
+		val, _ := core.GetBoolVal(dict.Get(key))
+		return val
+	}
+	getName := func(key core.PdfObjectName) string {
+		val, _ := core.GetNameVal(dict.Get(key))
+		return val
+	}
+
+	prefs := &ViewerPreferences{
+		HideToolbar:           getBool("HideToolbar"),
+		HideMenubar:           getBool("HideMenubar"),
+		HideWindowUI:          getBool("HideWindowUI"),
+		FitWindow:             getBool("FitWindow"),
+		CenterWindow:          getBool("CenterWindow"),
+		DisplayDocTitle:       getBool("DisplayDocTitle"),
+		NonFullScreenPageMode: PageMode(getName("NonFullScreenPageMode")),
+		Direction:             ReadingDirection(getName("Direction")),
+		ViewArea:              getName("ViewArea"),
+		ViewClip:              getName("ViewClip"),
+		PrintArea:             getName("PrintArea"),
+		PrintClip:             getName("PrintClip"),
+		PrintScaling:          PrintScaling(getName("PrintScaling")),
+		Duplex:                Duplex(getName("Duplex")),
+		PickTrayByPDFSize:     getBool("PickTrayByPDFSize"),
+	}
+	if numCopies, ok := core.GetIntVal(dict.Get("NumCopies")); ok {
+		prefs.NumCopies = numCopies
+	}
+	if arr, ok := core.GetArray(dict.Get("PrintPageRange")); ok {
+		vals, err := arr.ToIntegerArray()
+		if err != nil || len(vals)%2 != 0 {
+			common.Log.Debug("ERROR: invalid PrintPageRange: %v", arr)
+		} else {
+			for i := 0; i < len(vals); i += 2 {
+				prefs.PrintPageRange = append(prefs.PrintPageRange, [2]int{vals[i], vals[i+1]})
+			}
+		}
+	}
+	return prefs, nil
+}
+
+// ToPdfObject returns a PDF object representation of the viewer preferences.
+func (vp *ViewerPreferences) ToPdfObject() core.PdfObject {
+	dict := core.MakeDict()
+	setBool := func(key core.PdfObjectName, val bool) {
+		if val {
+			dict.Set(key, core.MakeBool(true))
+		}
+	}
+	setName := func(key core.PdfObjectName, val string) {
+		if val != "" {
+			dict.Set(key, core.MakeName(val))
+		}
+	}
+
+	setBool("HideToolbar", vp.HideToolbar)
+	setBool("HideMenubar", vp.HideMenubar)
+	setBool("HideWindowUI", vp.HideWindowUI)
+	setBool("FitWindow", vp.FitWindow)
+	setBool("CenterWindow", vp.CenterWindow)
+	setBool("DisplayDocTitle", vp.DisplayDocTitle)
+	setName("NonFullScreenPageMode", string(vp.NonFullScreenPageMode))
+	setName("Direction", string(vp.Direction))
+	setName("ViewArea", vp.ViewArea)
+	setName("ViewClip", vp.ViewClip)
+	setName("PrintArea", vp.PrintArea)
+	setName("PrintClip", vp.PrintClip)
+	setName("PrintScaling", string(vp.PrintScaling))
+	setName("Duplex", string(vp.Duplex))
+	setBool("PickTrayByPDFSize", vp.PickTrayByPDFSize)
+	if len(vp.PrintPageRange) > 0 {
+		arr := core.MakeArray()
+		for _, r := range vp.PrintPageRange {
+			arr.Append(core.MakeInteger(int64(r[0])), core.MakeInteger(int64(r[1])))
+		}
+		dict.Set("PrintPageRange", arr)
+	}
+	if vp.NumCopies > 0 {
+		dict.Set("NumCopies", core.MakeInteger(int64(vp.NumCopies)))
+	}
+	return dict
+}
+
+// OpenAction represents the action performed when the document is opened:
+// either displaying a destination or performing an action. If both are
+// set, the action takes precedence.
+type OpenAction struct {
+	// Dest is the destination displayed when the document is opened.
+	// When writing, if the page object of the destination is not set, it is
+	// resolved based on the page index of the destination.
+	Dest *OutlineDest
+
+	// Action is the action performed when the document is opened.
+	Action *PdfAction
+}
+
+// NewOpenActionDest returns an open action which displays the page having
+// the specified index (starting at 0), using the specified magnification mode
+// (e.g. Fit or XYZ) and position.
+func NewOpenActionDest(page int64, mode string, x, y, zoom float64) *OpenAction {
+	return &OpenAction{
+		Dest: &OutlineDest{Page: page, Mode: mode, X: x, Y: y, Zoom: zoom},
+	}
+}
+
+// NewOpenActionFromAction returns an open action which performs the
+// specified action.
+func NewOpenActionFromAction(action *PdfAction) *OpenAction {
+	return &OpenAction{Action: action}
+}
+
+// toPdfObject returns the PDF representation of the open action. The page
+// objects of the document are used for resolving destination pages.
+func (oa *OpenAction) toPdfObject(pages []core.PdfObject) (core.PdfObject, error) {
+	if oa.Action != nil {
+		if ctx := oa.Action.GetContext(); ctx != nil {
+			return ctx.ToPdfObject(), nil
+		}
+		return oa.Action.ToPdfObject(), nil
+	}
+	if oa.Dest == nil {
+		return nil, errors.New("open action destination or action required")
+	}
+
+	dest := *oa.Dest
+	if dest.PageObj == nil {
+		if dest.Page < 0 || dest.Page >= int64(len(pages)) {
+			return nil, errors.New("open action destination page out of range")
+		}
+		pageObj, ok := core.GetIndirect(pages[dest.Page])
+		if !ok {
+			return nil, ErrTypeCheck
+		}
+		dest.PageObj = pageObj
+	}
+	return dest.ToPdfObject(), nil
+}
+
+// GetViewerPreferences returns the viewer preferences of the document.
+// A nil value is returned if the document does not specify viewer preferences.
+func (r *PdfReader) GetViewerPreferences() (*ViewerPreferences, error) {
+	obj := core.ResolveReference(r.catalog.Get("ViewerPreferences"))
+	if obj == nil {
+		return nil, nil
+	}
+	return newViewerPreferencesFromPdfObject(obj)
+}
+
+// GetPageMode returns the page mode of the document.
+// The default page mode (UseNone) is returned if not specified.
+func (r *PdfReader) GetPageMode() PageMode {
+	if mode, ok := core.GetNameVal(r.catalog.Get("PageMode")); ok {
+		return PageMode(mode)
+	}
+	return PageModeUseNone
+}
+
+// GetPageLayout returns the page layout of the document.
+// The default page layout (SinglePage) is returned if not specified.
+func (r *PdfReader) GetPageLayout() PageLayout {
+	if layout, ok := core.GetNameVal(r.catalog.Get("PageLayout")); ok {
+		return PageLayout(layout)
+	}
+	return PageLayoutSinglePage
+}
+
+// GetOpenAction returns the action performed when the document is opened.
+// A nil value is returned if the document does not specify an open action.
+func (r *PdfReader) GetOpenAction() (*OpenAction, error) {
+	obj := r.catalog.Get("OpenAction")
+	if obj == nil {
+		return nil, nil
+	}
+
+	if ref, ok := obj.(*core.PdfObjectReference); ok {
+		resolved, err := r.parser.LookupByReference(*ref)
+		if err != nil {
+			return nil, err
+		}
+		obj = resolved
+	}
+	if _, ok := core.GetArray(obj); ok {
+		dest, err := newOutlineDestFromPdfObject(obj, r)
+		if err != nil {
+			return nil, err
+		}
+		return &OpenAction{Dest: dest}, nil
+	}
+
+	container, ok := obj.(*core.PdfIndirectObject)
+	if !ok {
+		dict, isDict := obj.(*core.PdfObjectDictionary)
+		if !isDict {
+			return nil, ErrTypeCheck
+		}
+		container = core.MakeIndirectObject(dict)
+	}
+	action, err := r.newPdfActionFromIndirectObject(container)
+	if err != nil {
+		return nil, err
+	}
+	return &OpenAction{Action: action}, nil
+}
+
+// SetViewerPreferences sets the viewer preferences of the output file.
+func (w *PdfWriter) SetViewerPreferences(prefs *ViewerPreferences) {
+	if prefs == nil {
+		w.catalog.Remove("ViewerPreferences")
+		return
+	}
+	w.catalog.Set("ViewerPreferences", prefs.ToPdfObject())
+}
+
+// SetPageMode sets the page mode used when the output file is opened.
+func (w *PdfWriter) SetPageMode(mode PageMode) {
+	setCatalogName(w.catalog, "PageMode", string(mode))
+}
+
+// SetPageLayout sets the page layout used when the output file is opened.
+func (w *PdfWriter) SetPageLayout(layout PageLayout) {
+	setCatalogName(w.catalog, "PageLayout", string(layout))
+}
+
+// SetOpenAction sets the action performed when the output file is opened.
+// The destination pages are resolved when the file is written.
+func (w *PdfWriter) SetOpenAction(action *OpenAction) {
+	w.openAction = action
+}
+
+// setOpenAction sets the open action entry of the catalog.
+func (w *PdfWriter) setOpenAction() error {
+	if w.openAction == nil {
+		return nil
+	}
+
+	pagesDict, ok := core.GetDict(w.pages)
+	if !ok {
+		return errors.New("invalid Pages obj (not a dict)")
+	}
+	kids, ok := core.GetArray(pagesDict.Get("Kids"))
+	if !ok {
+		return errors.New("invalid Pages Kids obj (not an array)")
+	}
+	obj, err := w.openAction.toPdfObject(kids.Elements())
+	if err != nil {
+		return err
+	}
+	w.catalog.Set("OpenAction", obj)
+	return w.addObjects(obj)
+}
+
+// setCatalogName sets the name entry of the catalog or removes it if
+// the value is empty.
+func setCatalogName(catalog *core.PdfObjectDictionary, key core.PdfObjectName, val string) {
+	if val == "" {
+		catalog.Remove(key)
+		return
+	}
+	catalog.Set(key, core.MakeName(val))
+}
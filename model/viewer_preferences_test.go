@@ -0,0 +1,97 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/TheLinker/unipdf/v3/core"
+)
+
+func TestViewerPreferencesWriter(t *testing.T) {
+	writer := NewPdfWriter()
+	for i := 0; i < 2; i++ {
+		page := NewPdfPage()
+		page.MediaBox = &PdfRectangle{Llx: 0, Lly: 0, Urx: 612, Ury: 792}
+		require.NoError(t, writer.AddPage(page))
+	}
+
+	prefs := NewViewerPreferences()
+	prefs.HideToolbar = true
+	prefs.DisplayDocTitle = true
+	prefs.PrintScaling = PrintScalingNone
+	prefs.Duplex = DuplexFlipLongEdge
+	prefs.PrintPageRange = [][2]int{{1, 1}, {2, 2}}
+	prefs.NumCopies = 2
+	writer.SetViewerPreferences(prefs)
+	writer.SetPageMode(PageModeUseOutlines)
+	writer.SetPageLayout(PageLayoutTwoColumnLeft)
+	writer.SetOpenAction(NewOpenActionDest(1, "XYZ", 0, 792, 1.5))
+
+	var buf bytes.Buffer
+	require.NoError(t, writer.Write(&buf))
+
+	reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	readPrefs, err := reader.GetViewerPreferences()
+	require.NoError(t, err)
+	require.Equal(t, prefs, readPrefs)
+	require.Equal(t, PageModeUseOutlines, reader.GetPageMode())
+	require.Equal(t, PageLayoutTwoColumnLeft, reader.GetPageLayout())
+
+	openAction, err := reader.GetOpenAction()
+	require.NoError(t, err)
+	require.NotNil(t, openAction.Dest)
+	require.Equal(t, int64(1), openAction.Dest.Page)
+	require.Equal(t, "XYZ", openAction.Dest.Mode)
+	require.Equal(t, 1.5, openAction.Dest.Zoom)
+}
+
+func TestViewerPreferencesAppender(t *testing.T) {
+	f, err := os.Open("./testdata/pages3.pdf")
+	require.NoError(t, err)
+	defer f.Close()
+
+	reader, err := NewPdfReader(f)
+	require.NoError(t, err)
+	require.Equal(t, PageModeUseNone, reader.GetPageMode())
+	require.Equal(t, PageLayoutSinglePage, reader.GetPageLayout())
+
+	appender, err := NewPdfAppender(reader)
+	require.NoError(t, err)
+
+	prefs := NewViewerPreferences()
+	prefs.HideMenubar = true
+	appender.SetViewerPreferences(prefs)
+	appender.SetPageMode(PageModeFullScreen)
+	action := NewPdfActionJavaScript()
+	action.JS = core.MakeString("app.alert('Opened');")
+	appender.SetOpenAction(NewOpenActionFromAction(action.PdfAction))
+
+	var buf bytes.Buffer
+	require.NoError(t, appender.Write(&buf))
+
+	reader, err = NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	readPrefs, err := reader.GetViewerPreferences()
+	require.NoError(t, err)
+	require.True(t, readPrefs.HideMenubar)
+	require.False(t, readPrefs.HideToolbar)
+	require.Equal(t, PageModeFullScreen, reader.GetPageMode())
+
+	openAction, err := reader.GetOpenAction()
+	require.NoError(t, err)
+	require.NotNil(t, openAction.Action)
+	actionType, ok := core.GetNameVal(openAction.Action.S)
+	require.True(t, ok)
+	require.Equal(t, ActionTypeJavaScript, PdfActionType(actionType))
+}
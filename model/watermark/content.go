@@ -0,0 +1,421 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package watermark
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"github.com/TheLinker/unipdf/v3/common"
+	"github.com/TheLinker/unipdf/v3/contentstream"
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/internal/pageutil"
+	"github.com/TheLinker/unipdf/v3/internal/transform"
+	"github.com/TheLinker/unipdf/v3/model"
+)
+
+// maxFormDepth is the nesting level of the form XObjects whose text is extracted.
+const maxFormDepth = 5
+
+// minAngle is the smallest rotation of the rotated content, in degrees.
+const minAngle = 1
+
+// graphicsState is the part of the graphics state used by the detection.
+type graphicsState struct {
+	ctm   transform.Matrix
+	alpha float64
+	font  *model.PdfFont
+	size  float64
+}
+
+// pageScanner detects the watermark candidates of the content of a page.
+type pageScanner struct {
+	opts Options
+	// resources are the resources of the page, not nil.
+	resources *core.PdfObjectDictionary
+	// rotation is the rotation of the page, clockwise in degrees.
+	rotation float64
+	// contentHash is the hash of the content of the page.
+	contentHash uint64
+	fonts       map[core.PdfObject]*model.PdfFont
+
+	// candidates are the artifact and rotated text candidates of the page.
+	candidates []*Candidate
+	// xobjects are the XObjects drawn with transparency or rotation, by their key.
+	xobjects map[string][]*Candidate
+}
+
+// scanPage returns the scanner of `page` after the detection of the candidates of its content.
+func scanPage(page *model.PdfPage, opts Options) (*pageScanner, error) {
+	content, err := page.GetAllContentStreams()
+	if err != nil {
+		return nil, err
+	}
+	ops, err := contentstream.NewContentStreamParser(content).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &pageScanner{
+		opts:        opts,
+		resources:   core.MakeDict(),
+		contentHash: hashContent(content),
+		fonts:       make(map[core.PdfObject]*model.PdfFont),
+		xobjects:    make(map[string][]*Candidate),
+	}
+	if page.Resources != nil {
+		if dict, ok := core.GetDict(page.Resources.ToPdfObject()); ok {
+			s.resources = dict
+		}
+	}
+	if page.Rotate != nil {
+		s.rotation = float64(*page.Rotate)
+	}
+	s.scan(*ops)
+	return s, nil
+}
+
+// scan detects the candidates of the content stream operations `ops`.
+func (s *pageScanner) scan(ops contentstream.ContentStreamOperations) {
+	gs := graphicsState{ctm: transform.IdentityMatrix(), alpha: 1}
+	var stack []graphicsState
+	tm := transform.IdentityMatrix()
+
+	// artifact is the open watermark artifact and depth the nesting level of the marked content
+	// in it.
+	var artifact *Candidate
+	depth := 0
+	painted := false
+	// text is the rotated text of the current text object.
+	var text *Candidate
+
+	paint := func(str string, angle, alpha float64) {
+		if !painted {
+			artifact.Angle = angle
+			painted = true
+		}
+		artifact.Text += str
+		artifact.Opacity = math.Min(artifact.Opacity, alpha)
+	}
+
+	for i, op := range ops {
+		if artifact != nil {
+			artifact.ops = append(artifact.ops, i)
+		}
+
+		switch op.Operand {
+		case "BMC", "BDC":
+			if artifact != nil {
+				depth++
+			} else if op.Operand == "BDC" && s.watermarkArtifact(op) {
+				artifact = s.newCandidate(KindArtifact, i)
+				depth, painted = 1, false
+			}
+		case "EMC":
+			if artifact != nil {
+				if depth--; depth == 0 {
+					s.candidates = append(s.candidates, artifact)
+					artifact = nil
+				}
+			}
+		case "q":
+			stack = append(stack, gs)
+		case "Q":
+			if len(stack) > 0 {
+				gs = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "cm":
+			if m, ok := transform.NewMatrixFromObjects(op.Params); ok {
+				gs.ctm.Concat(m)
+			}
+		case "gs":
+			if len(op.Params) == 1 {
+				name, _ := core.GetName(op.Params[0])
+				if alpha, ok := s.extGStateAlpha(name); ok {
+					gs.alpha = alpha
+				}
+			}
+		case "BT":
+			tm = transform.IdentityMatrix()
+			text = nil
+		case "ET":
+			if text != nil {
+				s.candidates = append(s.candidates, text)
+				text = nil
+			}
+		case "Tf":
+			if len(op.Params) == 2 {
+				name, _ := core.GetName(op.Params[0])
+				gs.font = s.font(s.resources, name)
+				gs.size, _ = core.GetNumberAsFloat(op.Params[1])
+			}
+		case "Tm":
+			if m, ok := transform.NewMatrixFromObjects(op.Params); ok {
+				tm = m
+			}
+		case "Tj", "TJ", "'", `"`:
+			str := decodeText(op, gs.font)
+			trm := gs.ctm.Mult(tm)
+			angle := s.angle(trm)
+			if artifact != nil {
+				paint(str, angle, gs.alpha)
+				break
+			}
+			size := gs.size * trm.ScalingFactorY()
+			if !rotated(angle) || size < s.opts.MinFontSize {
+				break
+			}
+			if text == nil {
+				text = s.newCandidate(KindRotatedText, -1)
+				text.Angle, text.FontSize, text.Opacity = angle, size, gs.alpha
+			}
+			text.Text += str
+			text.ops = append(text.ops, i)
+		case "Do":
+			if len(op.Params) != 1 {
+				break
+			}
+			name, ok := core.GetName(op.Params[0])
+			if !ok {
+				break
+			}
+			xobjects, _ := core.GetDict(s.resources.Get("XObject"))
+			if xobjects == nil {
+				break
+			}
+			stream, ok := core.GetStream(xobjects.Get(*name))
+			if !ok {
+				break
+			}
+
+			m := gs.ctm
+			alpha := gs.alpha
+			var str string
+			if subtype, _ := core.GetNameVal(stream.Get("Subtype")); subtype == "Form" {
+				matrix, _ := core.GetArray(stream.Get("Matrix"))
+				if formMatrix, ok := transform.NewMatrixFromObjects(matrix.Elements()); ok {
+					m = gs.ctm.Mult(formMatrix)
+				}
+				alpha = math.Min(alpha, s.formAlpha(stream))
+				str = s.formText(stream, s.resources, 0)
+			}
+			angle := s.angle(m)
+			if artifact != nil {
+				paint(str, angle, alpha)
+				break
+			}
+			if !rotated(angle) && alpha >= 1 {
+				break
+			}
+			c := s.newCandidate(KindRepeatedXObject, i)
+			c.Name, c.Text, c.Angle, c.Opacity = *name, str, angle, alpha
+			key := xobjectKey(stream)
+			s.xobjects[key] = append(s.xobjects[key], c)
+		case "sh", "BI", "S", "s", "f", "F", "f*", "B", "B*", "b", "b*":
+			if artifact != nil {
+				paint("", s.angle(gs.ctm), gs.alpha)
+			}
+		}
+	}
+}
+
+// newCandidate returns a candidate of `kind` of the page starting with the operation at index
+// `i`, if not negative.
+func (s *pageScanner) newCandidate(kind Kind, i int) *Candidate {
+	c := &Candidate{Kind: kind, Opacity: 1, contentHash: s.contentHash}
+	if i >= 0 {
+		c.ops = []int{i}
+	}
+	return c
+}
+
+// watermarkArtifact returns true if the BDC operation `op` starts the marked content of a
+// watermark artifact.
+func (s *pageScanner) watermarkArtifact(op *contentstream.ContentStreamOperation) bool {
+	if len(op.Params) != 2 {
+		return false
+	}
+	if tag, ok := core.GetName(op.Params[0]); !ok || *tag != "Artifact" {
+		return false
+	}
+	props, ok := core.GetDict(pageutil.Properties(op.Params[1], s.resources))
+	if !ok {
+		return false
+	}
+	subtype, _ := core.GetNameVal(props.Get("Subtype"))
+	return subtype == "Watermark"
+}
+
+// extGStateAlpha returns the fill opacity set by the graphics state parameter dictionary `name`
+// of the page resources.
+func (s *pageScanner) extGStateAlpha(name *core.PdfObjectName) (float64, bool) {
+	extGStates, _ := core.GetDict(s.resources.Get("ExtGState"))
+	if name == nil || extGStates == nil {
+		return 0, false
+	}
+	extGState, ok := core.GetDict(extGStates.Get(*name))
+	if !ok {
+		return 0, false
+	}
+	alpha, err := core.GetNumberAsFloat(core.ResolveReference(extGState.Get("ca")))
+	return alpha, err == nil
+}
+
+// formAlpha returns the lowest opacity set by the graphics state parameter dictionaries of the
+// form XObject `stream`, 1 if it sets none.
+func (s *pageScanner) formAlpha(stream *core.PdfObjectStream) float64 {
+	alpha := 1.0
+	resources, _ := core.GetDict(stream.Get("Resources"))
+	if resources == nil {
+		return alpha
+	}
+	extGStates, _ := core.GetDict(resources.Get("ExtGState"))
+	if extGStates == nil {
+		return alpha
+	}
+	for _, name := range extGStates.Keys() {
+		extGState, ok := core.GetDict(extGStates.Get(name))
+		if !ok {
+			continue
+		}
+		for _, key := range []core.PdfObjectName{"ca", "CA"} {
+			if v, err := core.GetNumberAsFloat(core.ResolveReference(extGState.Get(key))); err == nil {
+				alpha = math.Min(alpha, v)
+			}
+		}
+	}
+	return alpha
+}
+
+// formText returns the text drawn by the form XObject `stream` drawn with the resources
+// `parent`, at the nesting level `depth`.
+func (s *pageScanner) formText(stream *core.PdfObjectStream, parent *core.PdfObjectDictionary,
+	depth int) string {
+	if depth >= maxFormDepth {
+		return ""
+	}
+	resources, _ := core.GetDict(stream.Get("Resources"))
+	if resources == nil {
+		resources = parent
+	}
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: unable to decode form XObject: %v", err)
+		return ""
+	}
+	ops, err := contentstream.NewContentStreamParser(string(data)).Parse()
+	if err != nil {
+		common.Log.Debug("ERROR: unable to parse form XObject: %v", err)
+		return ""
+	}
+
+	var text string
+	var font *model.PdfFont
+	for _, op := range *ops {
+		switch op.Operand {
+		case "Tf":
+			if len(op.Params) == 2 {
+				name, _ := core.GetName(op.Params[0])
+				font = s.font(resources, name)
+			}
+		case "Tj", "TJ", "'", `"`:
+			text += decodeText(op, font)
+		case "Do":
+			if len(op.Params) != 1 {
+				break
+			}
+			name, ok := core.GetName(op.Params[0])
+			xobjects, _ := core.GetDict(resources.Get("XObject"))
+			if !ok || xobjects == nil {
+				break
+			}
+			if form, ok := core.GetStream(xobjects.Get(*name)); ok && form != stream {
+				if subtype, _ := core.GetNameVal(form.Get("Subtype")); subtype == "Form" {
+					text += s.formText(form, resources, depth+1)
+				}
+			}
+		}
+	}
+	return text
+}
+
+// font returns the font `name` of `resources`, nil if it is not found.
+func (s *pageScanner) font(resources *core.PdfObjectDictionary, name *core.PdfObjectName) *model.PdfFont {
+	fonts, _ := core.GetDict(resources.Get("Font"))
+	if name == nil || fonts == nil {
+		return nil
+	}
+	obj := fonts.Get(*name)
+	if obj == nil {
+		return nil
+	}
+	if font, ok := s.fonts[obj]; ok {
+		return font
+	}
+	font, err := model.NewPdfFontFromPdfObject(obj)
+	if err != nil {
+		common.Log.Debug("ERROR: unable to load font /%s: %v", *name, err)
+	}
+	s.fonts[obj] = font
+	return font
+}
+
+// angle returns the counterclockwise rotation of the content drawn with the transform `m` as
+// displayed on the page, between -180 and 180 degrees.
+func (s *pageScanner) angle(m transform.Matrix) float64 {
+	angle := math.Atan2(m[1], m[0])*180/math.Pi - s.rotation
+	angle = math.Mod(angle, 360)
+	if angle > 180 {
+		angle -= 360
+	} else if angle <= -180 {
+		angle += 360
+	}
+	return angle
+}
+
+// rotated returns true if `angle` is the angle of rotated content.
+func rotated(angle float64) bool {
+	return math.Abs(angle) >= minAngle
+}
+
+// decodeText returns the text of the text showing operation `op` drawn with `font`.
+func decodeText(op *contentstream.ContentStreamOperation, font *model.PdfFont) string {
+	if font == nil || len(op.Params) == 0 {
+		return ""
+	}
+	var text string
+	decode := func(obj core.PdfObject) {
+		if str, ok := core.GetString(obj); ok {
+			s, _, _ := font.CharcodeBytesToUnicode(str.Bytes())
+			text += s
+		}
+	}
+	last := op.Params[len(op.Params)-1]
+	if op.Operand == "TJ" {
+		arr, _ := core.GetArray(last)
+		for _, obj := range arr.Elements() {
+			decode(obj)
+		}
+	} else {
+		decode(last)
+	}
+	return text
+}
+
+// xobjectKey returns the key identifying the XObject `stream` across the pages, the XObjects
+// repeated by copy having the same key.
+func xobjectKey(stream *core.PdfObjectStream) string {
+	h := fnv.New64a()
+	subtype, _ := core.GetNameVal(stream.Get("Subtype"))
+	h.Write([]byte(subtype))
+	if bbox := stream.Get("BBox"); bbox != nil {
+		h.Write([]byte(bbox.WriteString()))
+	}
+	h.Write(stream.Stream)
+	return fmt.Sprintf("%x", h.Sum64())
+}
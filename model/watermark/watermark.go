@@ -0,0 +1,301 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+/*
+Package watermark detects the watermarks stamped on the pages, such as the
+"DRAFT" or "COPY" marks, and removes the selected ones.
+
+The candidates detected are:
+  - the Watermark annotations,
+  - the marked content of the watermark artifacts (/Artifact marked content
+    with the /Watermark subtype),
+  - the XObjects drawn on every page with transparency or rotation,
+  - the large rotated text.
+
+The detection is heuristic: the candidates are reviewed before they are
+removed, e.g. by their Text. The removal edits the annotations and the
+content streams of the pages, which are then written with PdfWriter.
+
+Example:
+
+	candidates, err := watermark.Detect(pages, watermark.Options{})
+	if err != nil {
+		return err
+	}
+	var selected []*watermark.Candidate
+	for _, c := range candidates {
+		if strings.Contains(c.Text, "DRAFT") {
+			selected = append(selected, c)
+		}
+	}
+	if err := watermark.Remove(pages, selected); err != nil {
+		return err
+	}
+*/
+package watermark
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+
+	"github.com/TheLinker/unipdf/v3/contentstream"
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/model"
+)
+
+// defaultMinFontSize is the default size of the smallest rotated text detected.
+const defaultMinFontSize = 36
+
+// Kind is the kind of a watermark candidate.
+type Kind int
+
+// Kinds of watermark candidates.
+const (
+	// KindAnnotation is a Watermark annotation.
+	KindAnnotation Kind = iota
+	// KindArtifact is the marked content of a watermark artifact.
+	KindArtifact
+	// KindRepeatedXObject is an XObject drawn on every page with transparency or rotation.
+	KindRepeatedXObject
+	// KindRotatedText is large rotated text.
+	KindRotatedText
+)
+
+// String returns the name of the kind.
+func (k Kind) String() string {
+	switch k {
+	case KindAnnotation:
+		return "annotation"
+	case KindArtifact:
+		return "artifact"
+	case KindRepeatedXObject:
+		return "repeated XObject"
+	case KindRotatedText:
+		return "rotated text"
+	}
+	return fmt.Sprintf("kind %d", int(k))
+}
+
+// Options define the detection of the watermarks.
+type Options struct {
+	// MinFontSize is the size of the smallest rotated text detected, in points.
+	// If not set, the text of 36 points and more is detected.
+	MinFontSize float64
+}
+
+// Candidate is a watermark candidate of a page.
+type Candidate struct {
+	Kind Kind
+
+	// Page is the number of the page of the candidate, starting at 1.
+	Page int
+
+	// Text is the text of the candidate, empty if it has no text or the text is not known.
+	Text string
+
+	// Name is the resource name of the XObject of the KindRepeatedXObject candidates.
+	Name core.PdfObjectName
+
+	// Angle is the counterclockwise rotation of the candidate as displayed, in degrees.
+	Angle float64
+
+	// FontSize is the size of the text of the KindRotatedText candidates as displayed.
+	FontSize float64
+
+	// Opacity is the fill opacity of the candidate content, 1 if it is opaque.
+	Opacity float64
+
+	// annotation is the annotation of the KindAnnotation candidates.
+	annotation *model.PdfAnnotation
+	// ops are the indices of the operations of the candidate in the content of the page.
+	ops []int
+	// contentHash is the hash of the content of the page when the candidate was detected.
+	contentHash uint64
+}
+
+// String returns a description of the candidate.
+func (c *Candidate) String() string {
+	s := fmt.Sprintf("page %d: %s", c.Page, c.Kind)
+	if c.Name != "" {
+		s += fmt.Sprintf(" /%s", c.Name)
+	}
+	if c.Text != "" {
+		s += fmt.Sprintf(" %q", c.Text)
+	}
+	return s
+}
+
+// Detect returns the watermark candidates of `pages`, ordered by page.
+func Detect(pages []*model.PdfPage, opts Options) ([]*Candidate, error) {
+	if opts.MinFontSize <= 0 {
+		opts.MinFontSize = defaultMinFontSize
+	}
+
+	var candidates []*Candidate
+	// The XObjects drawn by the pages with transparency or rotation, by their key.
+	var xobjects []map[string][]*Candidate
+	for i, page := range pages {
+		annots, err := page.GetAnnotations()
+		if err != nil {
+			return nil, err
+		}
+		for _, annot := range annots {
+			if _, ok := annot.GetContext().(*model.PdfAnnotationWatermark); !ok {
+				continue
+			}
+			text, _ := core.GetStringVal(annot.Contents)
+			candidates = append(candidates, &Candidate{
+				Kind:       KindAnnotation,
+				Page:       i + 1,
+				Text:       text,
+				Opacity:    1,
+				annotation: annot,
+			})
+		}
+
+		s, err := scanPage(page, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range s.candidates {
+			c.Page = i + 1
+		}
+		candidates = append(candidates, s.candidates...)
+		for _, list := range s.xobjects {
+			for _, c := range list {
+				c.Page = i + 1
+			}
+		}
+		xobjects = append(xobjects, s.xobjects)
+	}
+
+	if len(xobjects) > 0 {
+		for key := range xobjects[0] {
+			repeated := true
+			for _, page := range xobjects[1:] {
+				if len(page[key]) == 0 {
+					repeated = false
+					break
+				}
+			}
+			if !repeated {
+				continue
+			}
+			for _, page := range xobjects {
+				candidates = append(candidates, page[key]...)
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Page != b.Page {
+			return a.Page < b.Page
+		}
+		return a.firstOp() < b.firstOp()
+	})
+	return candidates, nil
+}
+
+// firstOp returns the index of the first operation of the candidate, -1 for the annotations.
+func (c *Candidate) firstOp() int {
+	if len(c.ops) == 0 {
+		return -1
+	}
+	return c.ops[0]
+}
+
+// Remove removes the watermark `candidates` detected in `pages` by Detect. The content of the
+// pages must not have been changed since the detection.
+func Remove(pages []*model.PdfPage, candidates []*Candidate) error {
+	byPage := make(map[int][]*Candidate)
+	for _, c := range candidates {
+		if c.Page < 1 || c.Page > len(pages) {
+			return fmt.Errorf("invalid candidate page %d", c.Page)
+		}
+		byPage[c.Page] = append(byPage[c.Page], c)
+	}
+
+	for num, list := range byPage {
+		page := pages[num-1]
+		if err := removeAnnotations(page, list); err != nil {
+			return err
+		}
+		if err := removeOperations(page, list); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeAnnotations removes the annotations of the candidates `candidates` from `page`.
+func removeAnnotations(page *model.PdfPage, candidates []*Candidate) error {
+	removed := make(map[*model.PdfAnnotation]bool)
+	for _, c := range candidates {
+		if c.annotation != nil {
+			removed[c.annotation] = true
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	annots, err := page.GetAnnotations()
+	if err != nil {
+		return err
+	}
+	kept := make([]*model.PdfAnnotation, 0, len(annots))
+	for _, annot := range annots {
+		if !removed[annot] {
+			kept = append(kept, annot)
+		}
+	}
+	page.SetAnnotations(kept)
+	return nil
+}
+
+// removeOperations removes the content stream operations of the candidates `candidates` from
+// `page`.
+func removeOperations(page *model.PdfPage, candidates []*Candidate) error {
+	removed := make(map[int]bool)
+	for _, c := range candidates {
+		for _, i := range c.ops {
+			removed[i] = true
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+
+	content, err := page.GetAllContentStreams()
+	if err != nil {
+		return err
+	}
+	for _, c := range candidates {
+		if len(c.ops) > 0 && c.contentHash != hashContent(content) {
+			return errors.New("page content changed since the watermark detection")
+		}
+	}
+	ops, err := contentstream.NewContentStreamParser(content).Parse()
+	if err != nil {
+		return err
+	}
+
+	var kept contentstream.ContentStreamOperations
+	for i, op := range *ops {
+		if !removed[i] {
+			kept = append(kept, op)
+		}
+	}
+	return page.SetContentStreams([]string{string(kept.Bytes())}, core.NewFlateEncoder())
+}
+
+// hashContent returns the hash of the page content `content`.
+func hashContent(content string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(content))
+	return h.Sum64()
+}
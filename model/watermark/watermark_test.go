@@ -0,0 +1,136 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package watermark_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/TheLinker/unipdf/v3/core"
+	"github.com/TheLinker/unipdf/v3/model"
+	"github.com/TheLinker/unipdf/v3/model/watermark"
+)
+
+// pageContent is the content of the test pages: body text, large rotated text, a watermark
+// artifact and a transparent form XObject.
+const pageContent = `BT /F1 12 Tf 72 720 Td (Body text) Tj ET
+q 0.7071 0.7071 -0.7071 0.7071 200 200 cm BT /F1 72 Tf 0 0 Td (DRAFT) Tj ET Q
+/Artifact <</Type /Pagination /Subtype /Watermark>> BDC
+q 0.5 0.866 -0.866 0.5 100 100 cm BT /F1 48 Tf (COPY) Tj ET Q
+EMC
+q /GS0 gs /Fm0 Do Q
+`
+
+// testPages returns two pages with watermarks, the first one with a Watermark annotation.
+func testPages(t *testing.T) []*model.PdfPage {
+	font, err := model.NewStandard14Font(model.HelveticaName)
+	require.NoError(t, err)
+	form, err := core.MakeStream([]byte("BT /F1 36 Tf 100 400 Td (CONFIDENTIAL) Tj ET"), nil)
+	require.NoError(t, err)
+	form.Set("Subtype", core.MakeName("Form"))
+	form.Set("BBox", core.MakeArrayFromFloats([]float64{0, 0, 612, 792}))
+	fonts := core.MakeDict()
+	fonts.Set("F1", font.ToPdfObject())
+	formResources := core.MakeDict()
+	formResources.Set("Font", fonts)
+	form.Set("Resources", formResources)
+
+	var pages []*model.PdfPage
+	for i := 0; i < 2; i++ {
+		page := model.NewPdfPage()
+		page.MediaBox = &model.PdfRectangle{Urx: 612, Ury: 792}
+		page.Resources = model.NewPdfPageResources()
+		require.NoError(t, page.Resources.SetFontByName("F1", font.ToPdfObject()))
+		gs := core.MakeDict()
+		gs.Set("ca", core.MakeFloat(0.3))
+		require.NoError(t, page.Resources.AddExtGState("GS0", gs))
+		require.NoError(t, page.Resources.SetXObjectByName("Fm0", form))
+		require.NoError(t, page.SetContentStreams([]string{pageContent}, nil))
+		pages = append(pages, page)
+	}
+	annot := model.NewPdfAnnotationWatermark()
+	annot.Contents = core.MakeString("Sample")
+	annot.Rect = core.MakeArrayFromFloats([]float64{0, 0, 100, 100})
+	pages[0].AddAnnotation(annot.PdfAnnotation)
+	return pages
+}
+
+func TestDetect(t *testing.T) {
+	pages := testPages(t)
+	candidates, err := watermark.Detect(pages, watermark.Options{})
+	require.NoError(t, err)
+	require.Len(t, candidates, 7)
+
+	expected := []struct {
+		kind watermark.Kind
+		page int
+		text string
+	}{
+		{watermark.KindAnnotation, 1, "Sample"},
+		{watermark.KindRotatedText, 1, "DRAFT"},
+		{watermark.KindArtifact, 1, "COPY"},
+		{watermark.KindRepeatedXObject, 1, "CONFIDENTIAL"},
+		{watermark.KindRotatedText, 2, "DRAFT"},
+		{watermark.KindArtifact, 2, "COPY"},
+		{watermark.KindRepeatedXObject, 2, "CONFIDENTIAL"},
+	}
+	for i, e := range expected {
+		c := candidates[i]
+		require.Equal(t, e.kind, c.Kind, c.String())
+		require.Equal(t, e.page, c.Page, c.String())
+		require.Equal(t, e.text, c.Text, c.String())
+	}
+	require.InDelta(t, 45, candidates[1].Angle, 0.1)
+	require.InDelta(t, 72, candidates[1].FontSize, 0.1)
+	require.InDelta(t, 60, candidates[2].Angle, 0.1)
+	require.Equal(t, core.PdfObjectName("Fm0"), candidates[3].Name)
+	require.InDelta(t, 0.3, candidates[3].Opacity, 1e-6)
+
+	// Large text which is not rotated and small rotated text are not detected.
+	opts := watermark.Options{MinFontSize: 100}
+	candidates, err = watermark.Detect(pages[1:], opts)
+	require.NoError(t, err)
+	for _, c := range candidates {
+		require.NotEqual(t, watermark.KindRotatedText, c.Kind)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	pages := testPages(t)
+	candidates, err := watermark.Detect(pages, watermark.Options{})
+	require.NoError(t, err)
+
+	// Keep the repeated XObject.
+	var selected []*watermark.Candidate
+	for _, c := range candidates {
+		if c.Kind != watermark.KindRepeatedXObject {
+			selected = append(selected, c)
+		}
+	}
+	require.NoError(t, watermark.Remove(pages, selected))
+
+	for _, page := range pages {
+		content, err := page.GetAllContentStreams()
+		require.NoError(t, err)
+		require.Contains(t, content, "(Body text)")
+		require.Contains(t, content, "/Fm0 Do")
+		require.NotContains(t, content, "(DRAFT)")
+		require.NotContains(t, content, "(COPY)")
+		require.NotContains(t, content, "/Artifact")
+	}
+	annots, err := pages[0].GetAnnotations()
+	require.NoError(t, err)
+	require.Empty(t, annots)
+
+	candidates, err = watermark.Detect(pages, watermark.Options{})
+	require.NoError(t, err)
+	require.Len(t, candidates, 2)
+
+	// The candidates cannot be removed once the content changed.
+	require.NoError(t, pages[0].SetContentStreams([]string{pageContent}, nil))
+	require.Error(t, watermark.Remove(pages, candidates[:1]))
+}
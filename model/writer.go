@@ -25,6 +25,7 @@ import (
 	"github.com/TheLinker/unipdf/v3/core"
 	"github.com/TheLinker/unipdf/v3/core/security"
 	"github.com/TheLinker/unipdf/v3/core/security/crypt"
+	"github.com/TheLinker/unipdf/v3/internal/parallel"
 )
 
 var pdfAuthor = ""
@@ -152,12 +153,18 @@ type PdfWriter struct {
 	crypter     *core.PdfCrypt
 	encryptDict *core.PdfObjectDictionary
 	encryptObj  *core.PdfIndirectObject
+	encryptOpts *EncryptOptions
+	encryptPass *encryptPasswords
 	ids         *core.PdfObjectArray
 
 	// PDF version
 	majorVersion int
 	minorVersion int
 
+	// Specifies whether the version was set by a standard applier, in which
+	// case the features requiring a higher version are not used.
+	versionLocked bool
+
 	// Force whether or not to use cross reference streams.
 	// Otherwise is used/not used depending on the PDF version (1.5 and above).
 	useCrossReferenceStream *bool
@@ -172,7 +179,14 @@ type PdfWriter struct {
 	// Forms.
 	acroForm *PdfAcroForm
 
+	// Action performed when the document is opened.
+	openAction *OpenAction
+
+	// Embedded files, keyed by name.
+	embeddedFiles map[string]*PdfFilespec
+
 	optimizer              Optimizer
+	standard               StandardApplier
 	crossReferenceMap      map[int]crossReference
 	writeOffset            int64 // used by PdfAppender
 	ObjNumOffset           int
@@ -185,6 +199,15 @@ type PdfWriter struct {
 
 	// Cache of objects traversed while resolving references.
 	traversed map[core.PdfObject]struct{}
+
+	// Number of object streams encoded concurrently by Write and the object streams encoded
+	// prior to writing.
+	encodingWorkers      int
+	encodedObjectStreams map[*core.PdfObjectStreams]encodedObjectStream
+
+	// Number of the next object written after the objects, used for the lengths of the
+	// streamed streams.
+	nextObjectNumber int64
 }
 
 // NewPdfWriter initializes a new PdfWriter.
@@ -291,10 +314,9 @@ func copyObject(obj core.PdfObject, objectToObjectCopyMap map[core.PdfObject]cor
 		}
 		return newObj
 	case *core.PdfObjectStream:
-		newObj := &core.PdfObjectStream{
-			Stream:             t.Stream,
-			PdfObjectReference: t.PdfObjectReference,
-		}
+		// The copy shares the data of the stream, including the source of the streamed streams.
+		newObj := &core.PdfObjectStream{}
+		*newObj = *t
 		objectToObjectCopyMap[obj] = newObj
 		newObj.PdfObjectDictionary = copyObject(t.PdfObjectDictionary, objectToObjectCopyMap).(*core.PdfObjectDictionary)
 		return newObj
@@ -438,11 +460,55 @@ func (w *PdfWriter) SetPageLabels(pageLabels core.PdfObject) error {
 	return w.addObjects(pageLabels)
 }
 
+// SetStructTreeRoot sets the StructTreeRoot entry in the PDF catalog.
+// See section 14.7.2 "Structure Hierarchy" (p. 570 PDF32000_2008).
+func (w *PdfWriter) SetStructTreeRoot(structTreeRoot core.PdfObject) error {
+	if structTreeRoot == nil {
+		return nil
+	}
+
+	common.Log.Trace("Setting catalog StructTreeRoot...")
+	w.catalog.Set("StructTreeRoot", structTreeRoot)
+	return w.addObjects(structTreeRoot)
+}
+
+// SetMarkInfo sets the MarkInfo entry in the PDF catalog.
+// See section 14.7.1 "Marked Content" (p. 563 PDF32000_2008).
+func (w *PdfWriter) SetMarkInfo(markInfo core.PdfObject) error {
+	if markInfo == nil {
+		return nil
+	}
+
+	common.Log.Trace("Setting catalog MarkInfo...")
+	w.catalog.Set("MarkInfo", markInfo)
+	return w.addObjects(markInfo)
+}
+
+// SetLang sets the natural language of the document (Lang entry in the PDF catalog), e.g. "en-US".
+// See section 14.9.2 "Natural Language Specification" (p. 587 PDF32000_2008).
+func (w *PdfWriter) SetLang(lang string) {
+	if lang == "" {
+		w.catalog.Remove("Lang")
+		return
+	}
+	w.catalog.Set("Lang", core.MakeString(lang))
+}
+
 // SetOptimizer sets the optimizer to optimize PDF before writing.
 func (w *PdfWriter) SetOptimizer(optimizer Optimizer) {
 	w.optimizer = optimizer
 }
 
+// SetEncodingWorkers sets the number of object streams (ObjStm) which Write serializes and Flate
+// encodes concurrently. The object streams are encoded sequentially by default. The output does not
+// depend on the number of workers.
+// The other streams are written with the data they hold, which is encoded when the streams are
+// created or by the optimizers. The optimizers encoding the streams and the images have their own
+// number of workers, see optimize.Options.
+func (w *PdfWriter) SetEncodingWorkers(workers int) {
+	w.encodingWorkers = workers
+}
+
 // GetOptimizer returns current PDF optimizer.
 func (w *PdfWriter) GetOptimizer() Optimizer {
 	return w.optimizer
@@ -754,6 +820,10 @@ func (w *PdfWriter) writeObject(num int, obj core.PdfObject) {
 	// TODO: Add a default encoder if Filter not specified?
 	// Still need to make sure is encrypted.
 	if pobj, isStream := obj.(*core.PdfObjectStream); isStream {
+		if pobj.IsStreamed() {
+			w.writeStreamedObject(num, pobj)
+			return
+		}
 		w.crossReferenceMap[num] = crossReference{Type: 1, Offset: w.writePos, Generation: pobj.GenerationNumber}
 		outStr := fmt.Sprintf("%d 0 obj\n", num)
 		outStr += pobj.PdfObjectDictionary.WriteString()
@@ -766,42 +836,21 @@ func (w *PdfWriter) writeObject(num int, obj core.PdfObject) {
 
 	if ostreams, isObjStreams := obj.(*core.PdfObjectStreams); isObjStreams {
 		w.crossReferenceMap[num] = crossReference{Type: 1, Offset: w.writePos, Generation: ostreams.GenerationNumber}
-		outStr := fmt.Sprintf("%d 0 obj\n", num)
-		var offsets []string
-		var objData string
-		var offset int64
-
 		for index, obj := range ostreams.Elements() {
-			io, isIndirect := obj.(*core.PdfIndirectObject)
-			if !isIndirect {
-				common.Log.Debug("ERROR: Object streams N %d contains non indirect pdf object %v", num, obj)
-				continue
+			if io, isIndirect := obj.(*core.PdfIndirectObject); isIndirect {
+				w.crossReferenceMap[int(io.ObjectNumber)] = crossReference{Type: 2, ObjectNumber: num, Index: index}
 			}
-			data := io.PdfObject.WriteString() + " "
-			objData = objData + data
-			offsets = append(offsets, fmt.Sprintf("%d %d", io.ObjectNumber, offset))
-			w.crossReferenceMap[int(io.ObjectNumber)] = crossReference{Type: 2, ObjectNumber: num, Index: index}
-			offset = offset + int64(len([]byte(data)))
 		}
-		offsetsStr := strings.Join(offsets, " ") + " "
-		encoder := core.NewFlateEncoder()
-		// For debugging:
-		//encoder := core.NewRawEncoder()
-		dict := encoder.MakeStreamDict()
-		dict.Set(core.PdfObjectName("Type"), core.MakeName("ObjStm"))
-		n := int64(ostreams.Len())
-		dict.Set(core.PdfObjectName("N"), core.MakeInteger(n))
-		first := int64(len(offsetsStr))
-		dict.Set(core.PdfObjectName("First"), core.MakeInteger(first))
-
-		data, _ := encoder.EncodeBytes([]byte(offsetsStr + objData))
-		length := int64(len(data))
-
-		dict.Set(core.PdfObjectName("Length"), core.MakeInteger(length))
-		outStr += dict.WriteString()
+
+		encoded, ok := w.encodedObjectStreams[ostreams]
+		if !ok {
+			encoded = encodeObjectStream(num, ostreams)
+		}
+		outStr := fmt.Sprintf("%d 0 obj\n", num)
+		outStr += encoded.dict.WriteString()
 		outStr += "\nstream\n"
 		w.writeString(outStr)
-		w.writeBytes(data)
+		w.writeBytes(encoded.data)
 		w.writeString("\nendstream\nendobj\n")
 		return
 	}
@@ -809,6 +858,107 @@ func (w *PdfWriter) writeObject(num int, obj core.PdfObject) {
 	w.writeString(obj.WriteString())
 }
 
+// writeStreamedObject writes the streamed stream `stream` of number `num`, copying its data from
+// its source without holding it in memory. As the length of the data is not known before it is
+// written, the Length of the stream is an indirect object written after the stream.
+func (w *PdfWriter) writeStreamedObject(num int, stream *core.PdfObjectStream) {
+	w.crossReferenceMap[num] = crossReference{Type: 1, Offset: w.writePos, Generation: stream.GenerationNumber}
+	length := core.MakeIndirectObject(core.MakeInteger(0))
+	length.ObjectNumber = w.nextObjectNumber
+	w.nextObjectNumber++
+	stream.PdfObjectDictionary.Set("Length", length)
+
+	outStr := fmt.Sprintf("%d 0 obj\n", num)
+	outStr += stream.PdfObjectDictionary.WriteString()
+	outStr += "\nstream\n"
+	w.writeString(outStr)
+	n := w.writeStreamData(stream)
+	w.writeString("\nendstream\nendobj\n")
+
+	length.PdfObject = core.MakeInteger(n)
+	w.writeObject(int(length.ObjectNumber), length)
+}
+
+// writeStreamData copies the data of the streamed stream `stream` from its source and returns
+// the number of bytes written.
+func (w *PdfWriter) writeStreamData(stream *core.PdfObjectStream) int64 {
+	if w.werr != nil {
+		return 0
+	}
+	r, err := core.NewStreamReader(stream)
+	if err != nil {
+		w.werr = err
+		return 0
+	}
+	defer r.Close()
+	n, err := io.Copy(w.writer, r)
+	w.writePos += n
+	w.werr = err
+	return n
+}
+
+// encodedObjectStream is an object stream encoded prior to writing.
+type encodedObjectStream struct {
+	dict *core.PdfObjectDictionary
+	data []byte
+}
+
+// encodeObjectStream encodes the object stream `ostreams` of number `num`.
+func encodeObjectStream(num int, ostreams *core.PdfObjectStreams) encodedObjectStream {
+	var offsets []string
+	var objData string
+	var offset int64
+
+	for _, obj := range ostreams.Elements() {
+		io, isIndirect := obj.(*core.PdfIndirectObject)
+		if !isIndirect {
+			common.Log.Debug("ERROR: Object streams N %d contains non indirect pdf object %v", num, obj)
+			continue
+		}
+		data := io.PdfObject.WriteString() + " "
+		objData = objData + data
+		offsets = append(offsets, fmt.Sprintf("%d %d", io.ObjectNumber, offset))
+		offset = offset + int64(len([]byte(data)))
+	}
+	offsetsStr := strings.Join(offsets, " ") + " "
+	encoder := core.NewFlateEncoder()
+	// For debugging:
+	//encoder := core.NewRawEncoder()
+	dict := encoder.MakeStreamDict()
+	dict.Set(core.PdfObjectName("Type"), core.MakeName("ObjStm"))
+	n := int64(ostreams.Len())
+	dict.Set(core.PdfObjectName("N"), core.MakeInteger(n))
+	first := int64(len(offsetsStr))
+	dict.Set(core.PdfObjectName("First"), core.MakeInteger(first))
+
+	data, _ := encoder.EncodeBytes([]byte(offsetsStr + objData))
+	length := int64(len(data))
+
+	dict.Set(core.PdfObjectName("Length"), core.MakeInteger(length))
+	return encodedObjectStream{dict: dict, data: data}
+}
+
+// encodeObjectStreams serializes and encodes the object streams of `objects` concurrently prior to
+// writing. The other objects are written as they are.
+func (w *PdfWriter) encodeObjectStreams(objects []core.PdfObject) {
+	var ostreams []*core.PdfObjectStreams
+	for _, obj := range objects {
+		if o, isObjStreams := obj.(*core.PdfObjectStreams); isObjStreams {
+			ostreams = append(ostreams, o)
+		}
+	}
+
+	encoded := make([]encodedObjectStream, len(ostreams))
+	parallel.Run(len(ostreams), w.encodingWorkers, func(i int) error {
+		encoded[i] = encodeObjectStream(int(ostreams[i].ObjectNumber), ostreams[i])
+		return nil
+	})
+	w.encodedObjectStreams = make(map[*core.PdfObjectStreams]encodedObjectStream, len(ostreams))
+	for i, o := range ostreams {
+		w.encodedObjectStreams[o] = encoded[i]
+	}
+}
+
 // Update all the object numbers prior to writing.
 func (w *PdfWriter) updateObjectNumbers() {
 	offset := w.ObjNumOffset
@@ -907,6 +1057,8 @@ func (w *PdfWriter) Encrypt(userPass, ownerPass []byte, options *EncryptOptions)
 		return err
 	}
 	w.crypter = crypter
+	w.encryptOpts = &EncryptOptions{Permissions: perm, Algorithm: algo}
+	w.encryptPass = &encryptPasswords{user: userPass, owner: ownerPass}
 	if info.Major != 0 {
 		w.SetVersion(info.Major, info.Minor)
 	}
@@ -922,6 +1074,12 @@ func (w *PdfWriter) Encrypt(userPass, ownerPass []byte, options *EncryptOptions)
 	return nil
 }
 
+// encryptPasswords holds the passwords used for encrypting the output file.
+type encryptPasswords struct {
+	user  []byte
+	owner []byte
+}
+
 // Wrapper function to handle writing out string.
 func (w *PdfWriter) writeString(s string) {
 	if w.werr != nil {
@@ -976,6 +1134,16 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 		}
 	}
 
+	// Open action.
+	if err := w.setOpenAction(); err != nil {
+		return err
+	}
+
+	// Embedded files.
+	if err := w.setEmbeddedFiles(); err != nil {
+		return err
+	}
+
 	// Check pending objects prior to write.
 	for pendingObj, pendingObjDicts := range w.pendingObjects {
 		if !w.hasObject(pendingObj) {
@@ -992,6 +1160,13 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 			}
 		}
 	}
+	// Apply PDF standard (e.g. PDF/X) if set.
+	if w.standard != nil {
+		if err := w.applyStandard(); err != nil {
+			return err
+		}
+	}
+
 	// Set version in the catalog.
 	w.catalog.Set("Version", core.MakeName(fmt.Sprintf("%d.%d", w.majorVersion, w.minorVersion)))
 
@@ -1019,6 +1194,20 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 	if w.useCrossReferenceStream != nil {
 		useCrossReferenceStream = *w.useCrossReferenceStream
 	}
+	if w.versionLocked && w.majorVersion == 1 && w.minorVersion < 5 {
+		// Cross reference streams and object streams require PDF 1.5.
+		// Write the objects contained in object streams directly.
+		useCrossReferenceStream = false
+		objects := w.objects[:0]
+		for _, obj := range w.objects {
+			if _, isObjectStreams := obj.(*core.PdfObjectStreams); isObjectStreams {
+				delete(w.objectsMap, obj)
+				continue
+			}
+			objects = append(objects, obj)
+		}
+		w.objects = objects
+	}
 
 	// Make a map of objects within object streams (if used).
 	objectsInObjectStreams := make(map[core.PdfObject]bool)
@@ -1068,7 +1257,31 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 		}
 	}
 
+	// The lengths of the streamed streams are numbered after all the objects.
+	w.nextObjectNumber = 1
+	for idx := range w.crossReferenceMap {
+		if int64(idx) >= w.nextObjectNumber {
+			w.nextObjectNumber = int64(idx) + 1
+		}
+	}
+	for _, obj := range w.objects {
+		var objectNumber int64
+		switch t := obj.(type) {
+		case *core.PdfIndirectObject:
+			objectNumber = t.ObjectNumber
+		case *core.PdfObjectStream:
+			objectNumber = t.ObjectNumber
+		case *core.PdfObjectStreams:
+			objectNumber = t.ObjectNumber
+		}
+		if objectNumber >= w.nextObjectNumber {
+			w.nextObjectNumber = objectNumber + 1
+		}
+	}
+
 	// Write out indirect/stream objects that are not in object streams.
+	var objects []core.PdfObject
+	var objectNumbers []int64
 	for _, obj := range w.objects {
 		if skip := objectsInObjectStreams[obj]; skip {
 			continue
@@ -1096,8 +1309,16 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 				return err
 			}
 		}
-		w.writeObject(int(objectNumber), obj)
+		objects = append(objects, obj)
+		objectNumbers = append(objectNumbers, objectNumber)
+	}
+
+	// Encode the object streams concurrently, then write the objects in order.
+	w.encodeObjectStreams(objects)
+	for i, obj := range objects {
+		w.writeObject(int(objectNumbers[i]), obj)
 	}
+	w.encodedObjectStreams = nil
 
 	xrefOffset := w.writePos
 	var maxIndex int
@@ -1171,6 +1392,8 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 		// If encrypted!
 		if w.crypter != nil {
 			crossReferenceStream.Set("Encrypt", w.encryptObj)
+		}
+		if w.ids != nil {
 			crossReferenceStream.Set("ID", w.ids)
 			common.Log.Trace("Ids: %s", w.ids)
 		}
@@ -1224,6 +1447,8 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 		// If encrypted!
 		if w.crypter != nil {
 			trailer.Set("Encrypt", w.encryptObj)
+		}
+		if w.ids != nil {
 			trailer.Set("ID", w.ids)
 			common.Log.Trace("Ids: %s", w.ids)
 		}
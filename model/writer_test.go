@@ -8,10 +8,14 @@ package model
 import (
 	"bytes"
 	"errors"
+	"io"
+	"io/ioutil"
 	"os"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/TheLinker/unipdf/v3/core"
 )
 
 // Tests loading annotations from file, writing back out and reloading.
@@ -127,3 +131,113 @@ func TestWriterErrorHandling(t *testing.T) {
 	err = w.Write(&out)
 	require.Error(t, err)
 }
+
+// splitObjectStreams is an optimizer grouping the indirect objects in two object streams.
+type splitObjectStreams struct{}
+
+func (splitObjectStreams) Optimize(objects []core.PdfObject) ([]core.PdfObject, error) {
+	streams := []*core.PdfObjectStreams{{}, {}}
+	var optimized []core.PdfObject
+	for _, obj := range objects {
+		if io, isIndirect := obj.(*core.PdfIndirectObject); isIndirect {
+			streams[len(optimized)%2].Append(io)
+		}
+		optimized = append(optimized, obj)
+	}
+	return append([]core.PdfObject{streams[0], streams[1]}, optimized...), nil
+}
+
+func TestWriterEncodingWorkers(t *testing.T) {
+	write := func(workers int) []byte {
+		w := NewPdfWriter()
+		for i := 0; i < 3; i++ {
+			require.NoError(t, w.AddPage(NewPdfPage()))
+		}
+		w.SetOptimizer(splitObjectStreams{})
+		w.SetEncodingWorkers(workers)
+		var buf bytes.Buffer
+		require.NoError(t, w.Write(&buf))
+		return buf.Bytes()
+	}
+
+	// The output is the same with concurrent encoding.
+	sequential := write(0)
+	require.Equal(t, sequential, write(4))
+
+	reader, err := NewPdfReader(bytes.NewReader(sequential))
+	require.NoError(t, err)
+	numPages, err := reader.GetNumPages()
+	require.NoError(t, err)
+	require.Equal(t, 3, numPages)
+}
+
+func TestWriterStreamedStreams(t *testing.T) {
+	content := bytes.Repeat([]byte("BT /F1 12 Tf 72 720 Td (Streamed content) Tj ET\n"), 2000)
+	data, err := ioutil.ReadFile(`testdata/OoPdfFormExample.pdf`)
+	require.NoError(t, err)
+	read := func(data []byte, password string) *PdfReader {
+		reader, err := NewPdfReader(bytes.NewReader(data))
+		require.NoError(t, err)
+		isEncrypted, err := reader.IsEncrypted()
+		require.NoError(t, err)
+		require.Equal(t, password != "", isEncrypted)
+		if isEncrypted {
+			ok, err := reader.Decrypt([]byte(password))
+			require.NoError(t, err)
+			require.True(t, ok)
+		}
+		return reader
+	}
+	pageContent := func(reader *PdfReader, num int) string {
+		page, err := reader.GetPage(num)
+		require.NoError(t, err)
+		s, err := page.GetAllContentStreams()
+		require.NoError(t, err)
+		return s
+	}
+
+	for _, algo := range []EncryptionAlgorithm{-1, RC4_128bit, AES_128bit, AES_256bit} {
+		password := ""
+		if algo >= 0 {
+			password = "password"
+		}
+
+		// Streams made from a reader, read when the document is written.
+		stream, err := core.MakeStreamFromReader(func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(content)), nil
+		}, core.NewFlateEncoder())
+		require.NoError(t, err)
+		page := NewPdfPage()
+		page.Contents = stream
+
+		w := NewPdfWriter()
+		require.NoError(t, w.AddPage(page))
+		if algo >= 0 {
+			require.NoError(t, w.Encrypt([]byte(password), []byte(password), &EncryptOptions{Algorithm: algo}))
+		}
+		var buf bytes.Buffer
+		require.NoError(t, w.Write(&buf))
+		require.Equal(t, string(content), pageContent(read(buf.Bytes(), password), 1))
+
+		// Streams of a document read from the file when the document is written.
+		f, err := os.Open(`testdata/OoPdfFormExample.pdf`)
+		require.NoError(t, err)
+		streamedReader, err := NewPdfReaderStreamed(f, 64)
+		require.NoError(t, err)
+		streamedPage, err := streamedReader.GetPage(1)
+		require.NoError(t, err)
+		contents, ok := core.GetStream(streamedPage.Contents)
+		require.True(t, ok)
+		require.True(t, contents.IsStreamed())
+
+		w = NewPdfWriter()
+		require.NoError(t, w.AddPage(streamedPage))
+		if algo >= 0 {
+			require.NoError(t, w.Encrypt([]byte(password), []byte(password), &EncryptOptions{Algorithm: algo}))
+		}
+		buf.Reset()
+		require.NoError(t, w.Write(&buf))
+		require.NoError(t, f.Close())
+		require.Equal(t, pageContent(read(data, ""), 1), pageContent(read(buf.Bytes(), password), 1))
+	}
+}
@@ -30,6 +30,7 @@ type XObjectForm struct {
 	OPI           core.PdfObject
 	OC            core.PdfObject
 	Name          core.PdfObject
+	AF            core.PdfObject // Associated files (PDF 2.0).
 
 	// Stream data.
 	Stream []byte
@@ -106,6 +107,7 @@ func NewXObjectFormFromStream(stream *core.PdfObjectStream) (*XObjectForm, error
 	form.OPI = dict.Get("OPI")
 	form.OC = dict.Get("OC")
 	form.Name = dict.Get("Name")
+	form.AF = dict.Get("AF")
 
 	form.Stream = stream.Stream
 
@@ -181,6 +183,7 @@ func (xform *XObjectForm) ToPdfObject() core.PdfObject {
 	dict.SetIfNotNil("OPI", xform.OPI)
 	dict.SetIfNotNil("OC", xform.OC)
 	dict.SetIfNotNil("Name", xform.Name)
+	dict.SetIfNotNil("AF", xform.AF)
 
 	dict.Set("Length", core.MakeInteger(int64(len(xform.Stream))))
 	stream.Stream = xform.Stream
@@ -213,6 +216,7 @@ type XObjectImage struct {
 	OPI          core.PdfObject
 	Metadata     core.PdfObject
 	OC           core.PdfObject
+	AF           core.PdfObject // Associated files (PDF 2.0).
 	Stream       []byte
 	// Primitive
 	primitive *core.PdfObjectStream
@@ -442,6 +446,7 @@ func NewXObjectImageFromStream(stream *core.PdfObjectStream) (*XObjectImage, err
 	img.OPI = dict.Get("OPI")
 	img.Metadata = dict.Get("Metadata")
 	img.OC = dict.Get("OC")
+	img.AF = dict.Get("AF")
 
 	img.Stream = stream.Stream
 
@@ -595,6 +600,7 @@ func (ximg *XObjectImage) ToPdfObject() core.PdfObject {
 	dict.SetIfNotNil("OPI", ximg.OPI)
 	dict.SetIfNotNil("Metadata", ximg.Metadata)
 	dict.SetIfNotNil("OC", ximg.OC)
+	dict.SetIfNotNil("AF", ximg.AF)
 
 	dict.Set("Length", core.MakeInteger(int64(len(ximg.Stream))))
 	stream.Stream = ximg.Stream
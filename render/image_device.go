@@ -23,6 +23,12 @@ import (
 // ImageDevice is used to render PDF pages to image targets.
 type ImageDevice struct {
 	renderer
+
+	// OutputWidth represents the width of the rendered images in pixels.
+	// The heights of the output images are calculated based on the selected
+	// width and the original height of each rendered page.
+	// If not set, the pages are rendered at 72 DPI.
+	OutputWidth int
 }
 
 // NewImageDevice returns a new image device.
@@ -40,8 +46,12 @@ func (d *ImageDevice) Render(page *model.PdfPage) (image.Image, error) {
 
 	// Render page.
 	width, height := mbox.Llx+mbox.Width(), mbox.Lly+mbox.Height()
+	scale := 1.0
+	if d.OutputWidth > 0 {
+		scale = float64(d.OutputWidth) / width
+	}
 
-	ctx := imagerender.NewContext(int(width), int(height))
+	ctx := imagerender.NewContext(int(width*scale), int(height*scale))
 	if err := d.renderPage(ctx, page); err != nil {
 		return nil, err
 	}
@@ -50,8 +60,8 @@ func (d *ImageDevice) Render(page *model.PdfPage) (image.Image, error) {
 	img := ctx.Image()
 	if box := page.CropBox; box != nil {
 		// Calculate crop bounds and crop start position.
-		cropBounds := image.Rect(0, 0, int(box.Width()), int(box.Height()))
-		cropStart := image.Pt(int(box.Llx), int(height-box.Ury))
+		cropBounds := image.Rect(0, 0, int(box.Width()*scale), int(box.Height()*scale))
+		cropStart := image.Pt(int(box.Llx*scale), int((height-box.Ury)*scale))
 
 		// Crop image.
 		cropImg := image.NewRGBA(cropBounds)
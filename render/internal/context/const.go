@@ -35,6 +35,27 @@ const (
 	LineJoinBevel
 )
 
+// BlendMode represents the blend mode used by a context instance for compositing
+// the painted colors with the backdrop. Only the separable blend modes are
+// supported (section 11.3.5.2 of PDF32000_2008).
+type BlendMode int
+
+// Blend modes.
+const (
+	BlendModeNormal BlendMode = iota
+	BlendModeMultiply
+	BlendModeScreen
+	BlendModeOverlay
+	BlendModeDarken
+	BlendModeLighten
+	BlendModeColorDodge
+	BlendModeColorBurn
+	BlendModeHardLight
+	BlendModeSoftLight
+	BlendModeDifference
+	BlendModeExclusion
+)
+
 // Pattern represents a pattern which can be rendered by a context instance.
 type Pattern interface {
 	ColorAt(x, y int) color.Color
@@ -153,6 +153,10 @@ type Context interface {
 	// SetStrokeStyle sets current stroke pattern.
 	SetStrokeStyle(pattern Pattern)
 
+	// SetBlendMode sets the blend mode used for compositing the painted
+	// paths, text and images with the backdrop.
+	SetBlendMode(mode BlendMode)
+
 	//
 	// Text operations
 	//
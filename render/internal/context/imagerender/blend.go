@@ -0,0 +1,145 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package imagerender
+
+import (
+	"image"
+	"math"
+
+	"github.com/TheLinker/unipdf/v3/render/internal/context"
+)
+
+// paint calls `draw`, which paints onto the image of the context. If the blend
+// mode of the context is not Normal, `draw` paints onto a transparent layer,
+// which is then composited with the image of the context using the blend mode.
+func (dc *Context) paint(draw func()) {
+	if dc.blendMode == context.BlendModeNormal {
+		draw()
+		return
+	}
+
+	backdrop := dc.im
+	layer := image.NewRGBA(backdrop.Bounds())
+	dc.im = layer
+	defer func() { dc.im = backdrop }()
+	draw()
+
+	blendLayer(backdrop, layer, blendFunc(dc.blendMode))
+}
+
+// blendLayer composites `layer` with `backdrop` using the separable blend
+// function `blend` (section 11.3.6 of PDF32000_2008). The images must have the
+// same bounds.
+func blendLayer(backdrop, layer *image.RGBA, blend func(cb, cs float64) float64) {
+	b := layer.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			i := layer.PixOffset(x, y)
+			j := backdrop.PixOffset(x, y)
+			src := layer.Pix[i : i+4 : i+4]
+			dst := backdrop.Pix[j : j+4 : j+4]
+			if src[3] == 0 {
+				continue
+			}
+
+			// The pixels are alpha-premultiplied.
+			as := float64(src[3]) / 255
+			ab := float64(dst[3]) / 255
+			for k := 0; k < 3; k++ {
+				ps := float64(src[k]) / 255
+				pb := float64(dst[k]) / 255
+				var mixed float64
+				if ab > 0 {
+					mixed = as * ab * blend(pb/ab, ps/as)
+				}
+				dst[k] = clampByte((1-as)*pb + (1-ab)*ps + mixed)
+			}
+			dst[3] = clampByte(as + ab - as*ab)
+		}
+	}
+}
+
+// clampByte returns `v` in range 0-1 scaled to a byte value.
+func clampByte(v float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(1, v)) * 255))
+}
+
+// blendFunc returns the separable blend function of blend mode `mode`, which
+// returns the blended color component of the backdrop color component `cb`
+// and of the source color component `cs` (section 11.3.5.2 of PDF32000_2008).
+func blendFunc(mode context.BlendMode) func(cb, cs float64) float64 {
+	switch mode {
+	case context.BlendModeMultiply:
+		return multiply
+	case context.BlendModeScreen:
+		return screen
+	case context.BlendModeOverlay:
+		return func(cb, cs float64) float64 { return hardLight(cs, cb) }
+	case context.BlendModeDarken:
+		return math.Min
+	case context.BlendModeLighten:
+		return math.Max
+	case context.BlendModeColorDodge:
+		return colorDodge
+	case context.BlendModeColorBurn:
+		return colorBurn
+	case context.BlendModeHardLight:
+		return hardLight
+	case context.BlendModeSoftLight:
+		return softLight
+	case context.BlendModeDifference:
+		return func(cb, cs float64) float64 { return math.Abs(cb - cs) }
+	case context.BlendModeExclusion:
+		return func(cb, cs float64) float64 { return cb + cs - 2*cb*cs }
+	}
+	return func(cb, cs float64) float64 { return cs }
+}
+
+func multiply(cb, cs float64) float64 {
+	return cb * cs
+}
+
+func screen(cb, cs float64) float64 {
+	return cb + cs - cb*cs
+}
+
+func colorDodge(cb, cs float64) float64 {
+	switch {
+	case cb == 0:
+		return 0
+	case cs >= 1:
+		return 1
+	}
+	return math.Min(1, cb/(1-cs))
+}
+
+func colorBurn(cb, cs float64) float64 {
+	switch {
+	case cb >= 1:
+		return 1
+	case cs == 0:
+		return 0
+	}
+	return 1 - math.Min(1, (1-cb)/cs)
+}
+
+func hardLight(cb, cs float64) float64 {
+	if cs <= 0.5 {
+		return multiply(cb, 2*cs)
+	}
+	return screen(cb, 2*cs-1)
+}
+
+func softLight(cb, cs float64) float64 {
+	if cs <= 0.5 {
+		return cb - (1-2*cs)*cb*(1-cb)
+	}
+	d := math.Sqrt(cb)
+	if cb <= 0.25 {
+		d = ((16*cb-12)*cb + 4) * cb
+	}
+	return cb + (2*cs-1)*(d-cb)
+}
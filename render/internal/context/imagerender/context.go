@@ -46,6 +46,7 @@ type Context struct {
 	lineCap       context.LineCap
 	lineJoin      context.LineJoin
 	fillRule      context.FillRule
+	blendMode     context.BlendMode
 	matrix        transform.Matrix
 	textState     *context.TextState
 	stack         []*Context
@@ -161,6 +162,12 @@ func (dc *Context) SetStrokeStyle(pattern context.Pattern) {
 	dc.strokePattern = pattern
 }
 
+// SetBlendMode sets the blend mode used for compositing the painted paths,
+// text and images with the backdrop.
+func (dc *Context) SetBlendMode(mode context.BlendMode) {
+	dc.blendMode = mode
+}
+
 // SetColor sets the current color(for both fill and stroke).
 func (dc *Context) SetColor(c color.Color) {
 	dc.setFillAndStrokeColor(c)
@@ -403,6 +410,10 @@ func (dc *Context) fill(painter raster.Painter) {
 // line cap, line join and dash settings. The path is preserved after this
 // operation.
 func (dc *Context) StrokePreserve() {
+	dc.paint(dc.strokePreserve)
+}
+
+func (dc *Context) strokePreserve() {
 	var painter raster.Painter
 	if dc.mask == nil {
 		if pattern, ok := dc.strokePattern.(*solidPattern); ok {
@@ -430,6 +441,10 @@ func (dc *Context) Stroke() {
 // FillPreserve fills the current path with the current color. Open subpaths
 // are implicity closed. The path is preserved after this operation.
 func (dc *Context) FillPreserve() {
+	dc.paint(dc.fillPreserve)
+}
+
+func (dc *Context) fillPreserve() {
 	var painter raster.Painter
 	if dc.mask == nil {
 		if pattern, ok := dc.fillPattern.(*solidPattern); ok {
@@ -650,14 +665,16 @@ func (dc *Context) DrawImageAnchored(im image.Image, x, y int, ax, ay float64) {
 	m := dc.matrix.Clone()
 	m.Translate(float64(x), float64(y))
 	s2d := f64.Aff3{m[0], m[3], m[6], m[1], m[4], m[7]}
-	if dc.mask == nil {
-		transformer.Transform(dc.im, s2d, im, im.Bounds(), draw.Over, nil)
-	} else {
-		transformer.Transform(dc.im, s2d, im, im.Bounds(), draw.Over, &draw.Options{
-			DstMask:  dc.mask,
-			DstMaskP: image.ZP,
-		})
-	}
+	dc.paint(func() {
+		if dc.mask == nil {
+			transformer.Transform(dc.im, s2d, im, im.Bounds(), draw.Over, nil)
+		} else {
+			transformer.Transform(dc.im, s2d, im, im.Bounds(), draw.Over, &draw.Options{
+				DstMask:  dc.mask,
+				DstMaskP: image.ZP,
+			})
+		}
+	})
 }
 
 //
@@ -715,13 +732,15 @@ func (dc *Context) DrawStringAnchored(s string, x, y, ax, ay float64) {
 	w, h := dc.MeasureString(s)
 	x -= ax * w
 	y += ay * h
-	if dc.mask == nil {
-		dc.drawString(dc.im, s, x, y)
-	} else {
-		im := image.NewRGBA(image.Rect(0, 0, dc.width, dc.height))
-		dc.drawString(im, s, x, y)
-		draw.DrawMask(dc.im, dc.im.Bounds(), im, image.ZP, dc.mask, image.ZP, draw.Over)
-	}
+	dc.paint(func() {
+		if dc.mask == nil {
+			dc.drawString(dc.im, s, x, y)
+		} else {
+			im := image.NewRGBA(image.Rect(0, 0, dc.width, dc.height))
+			dc.drawString(im, s, x, y)
+			draw.DrawMask(dc.im, dc.im.Bounds(), im, image.ZP, dc.mask, image.ZP, draw.Over)
+		}
+	})
 }
 
 // MeasureString returns the rendered width and height of the specified text
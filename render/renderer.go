@@ -7,6 +7,8 @@ package render
 
 import (
 	"errors"
+	"image"
+	"image/color"
 
 	"github.com/adrg/sysfont"
 
@@ -33,9 +35,13 @@ func (r renderer) renderPage(ctx context.Context, page *model.PdfPage) error {
 		return err
 	}
 
-	// Change coordinate system.
-	ctx.Translate(0, float64(ctx.Height()))
-	ctx.Scale(1, -1)
+	// Calculate the scale of the rendering area, relative to the page size.
+	scale := 1.0
+	if mbox, err := page.GetMediaBox(); err == nil {
+		if width := mbox.Llx + mbox.Width(); width > 0 {
+			scale = float64(ctx.Width()) / width
+		}
+	}
 
 	// Create white background.
 	ctx.Push()
@@ -44,19 +50,30 @@ func (r renderer) renderPage(ctx context.Context, page *model.PdfPage) error {
 	ctx.Fill()
 	ctx.Pop()
 
+	// Change coordinate system.
+	ctx.Translate(0, float64(ctx.Height()))
+	ctx.Scale(scale, -scale)
+
 	// Set defaults.
-	ctx.SetLineWidth(1.0)
+	ctx.SetLineWidth(scale)
 	ctx.SetRGBA(0, 0, 0, 1)
 
-	return r.renderContentStream(ctx, contents, page.Resources)
+	return r.renderContentStream(ctx, contents, page.Resources, alphaState{fill: 1, stroke: 1})
+}
+
+// alphaState represents the constant alpha values of the graphics state.
+type alphaState struct {
+	fill   float64
+	stroke float64
 }
 
-func (r renderer) renderContentStream(ctx context.Context, contents string, resources *model.PdfPageResources) error {
+func (r renderer) renderContentStream(ctx context.Context, contents string, resources *model.PdfPageResources, alpha alphaState) error {
 	operations, err := contentstream.NewContentStreamParser(contents).Parse()
 	if err != nil {
 		return err
 	}
 
+	var alphaStack []alphaState
 	textState := ctx.TextState()
 	fontCache := map[string]*context.TextFont{}
 	fontFinder := sysfont.NewFinder(&sysfont.FinderOpts{
@@ -75,9 +92,14 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 			// Push current graphics state to the stack.
 			case "q":
 				ctx.Push()
+				alphaStack = append(alphaStack, alpha)
 			// Pop graphics state from the stack.
 			case "Q":
 				ctx.Pop()
+				if n := len(alphaStack); n > 0 {
+					alpha = alphaStack[n-1]
+					alphaStack = alphaStack[:n-1]
+				}
 			// Modify graphics state matrix.
 			case "cm":
 				if len(op.Params) != 6 {
@@ -108,7 +130,8 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 				}
 
 				// TODO: Take angle into account for line widths (8.4.3.2 Line Width).
-				s := (gs.CTM.ScalingFactorX() + gs.CTM.ScalingFactorY()) / 2.0
+				m := ctx.Matrix()
+				s := (m.ScalingFactorX() + m.ScalingFactorY()) / 2.0
 				ctx.SetLineWidth(s * fw[0])
 			// Set line cap style.
 			case "J":
@@ -236,6 +259,19 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 				}
 				common.Log.Debug("GS dict: %s", extdict.String())
 
+				// Constant alpha.
+				if ca, err := core.GetNumberAsFloat(core.TraceToDirectObject(extdict.Get("ca"))); err == nil {
+					alpha.fill = ca
+				}
+				if ca, err := core.GetNumberAsFloat(core.TraceToDirectObject(extdict.Get("CA"))); err == nil {
+					alpha.stroke = ca
+				}
+
+				// Blend mode.
+				if bm := extdict.Get("BM"); bm != nil {
+					ctx.SetBlendMode(blendMode(bm))
+				}
+
 			//
 			// Path operators
 			//
@@ -330,7 +366,7 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 					return err
 				}
 
-				ctx.SetRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), 1)
+				ctx.SetRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), alpha.stroke)
 				ctx.Stroke()
 			// Close and stroke.
 			case "s":
@@ -348,7 +384,7 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 
 				ctx.ClosePath()
 				ctx.NewSubPath()
-				ctx.SetRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), 1)
+				ctx.SetRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), alpha.stroke)
 				ctx.Stroke()
 			// Fill path using non-zero winding number rule.
 			case "f", "F":
@@ -364,7 +400,7 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 					return err
 				}
 
-				ctx.SetRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), 1)
+				ctx.SetRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), alpha.fill)
 				ctx.SetFillRule(context.FillRuleWinding)
 				ctx.Fill()
 			// Fill path using even-odd rule.
@@ -381,7 +417,7 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 					return err
 				}
 
-				ctx.SetRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), 1)
+				ctx.SetRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), alpha.fill)
 				ctx.SetFillRule(context.FillRuleEvenOdd)
 				ctx.Fill()
 			// Fill then stroke the path using non-zero winding rule.
@@ -394,7 +430,7 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 				}
 
 				rgbColor := color.(*model.PdfColorDeviceRGB)
-				ctx.SetRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), 1)
+				ctx.SetRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), alpha.fill)
 				ctx.SetFillRule(context.FillRuleWinding)
 				ctx.FillPreserve()
 
@@ -406,7 +442,7 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 				}
 
 				rgbColor = color.(*model.PdfColorDeviceRGB)
-				ctx.SetRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), 1)
+				ctx.SetRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), alpha.stroke)
 				ctx.Stroke()
 			// Fill then stroke the path using even-odd rule.
 			case "B*":
@@ -418,7 +454,7 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 				}
 
 				rgbColor := color.(*model.PdfColorDeviceRGB)
-				ctx.SetRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), 1)
+				ctx.SetRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), alpha.fill)
 				ctx.SetFillRule(context.FillRuleEvenOdd)
 				ctx.FillPreserve()
 
@@ -430,7 +466,7 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 				}
 
 				rgbColor = color.(*model.PdfColorDeviceRGB)
-				ctx.SetRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), 1)
+				ctx.SetRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), alpha.stroke)
 				ctx.Stroke()
 			// Close, fill and stroke the path using non-zero winding rule.
 			case "b":
@@ -442,7 +478,7 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 				}
 
 				rgbColor := color.(*model.PdfColorDeviceRGB)
-				ctx.SetRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), 1)
+				ctx.SetRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), alpha.fill)
 				ctx.ClosePath()
 				ctx.NewSubPath()
 				ctx.SetFillRule(context.FillRuleWinding)
@@ -456,7 +492,7 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 				}
 
 				rgbColor = color.(*model.PdfColorDeviceRGB)
-				ctx.SetRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), 1)
+				ctx.SetRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), alpha.stroke)
 				ctx.Stroke()
 			// Close, fill and stroke the path using even-odd rule.
 			case "b*":
@@ -471,7 +507,7 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 				}
 
 				rgbColor := color.(*model.PdfColorDeviceRGB)
-				ctx.SetRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), 1)
+				ctx.SetRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), alpha.fill)
 				ctx.NewSubPath()
 				ctx.SetFillRule(context.FillRuleEvenOdd)
 				ctx.FillPreserve()
@@ -484,7 +520,7 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 				}
 
 				rgbColor = color.(*model.PdfColorDeviceRGB)
-				ctx.SetRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), 1)
+				ctx.SetRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), alpha.stroke)
 				ctx.Stroke()
 			// End the current path without filling or stroking.
 			case "n":
@@ -520,7 +556,7 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 					common.Log.Debug("Error converting color: %v", gs.ColorNonStroking)
 					return nil
 				}
-				ctx.SetFillRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), 1)
+				ctx.SetFillRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), alpha.fill)
 			// Set RGB stroking color.
 			case "RG":
 				rgbColor, ok := gs.ColorStroking.(*model.PdfColorDeviceRGB)
@@ -528,7 +564,7 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 					common.Log.Debug("Error converting color: %v", gs.ColorStroking)
 					return nil
 				}
-				ctx.SetStrokeRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), 1)
+				ctx.SetStrokeRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), alpha.stroke)
 			// Set CMYK non-stroking color.
 			case "k":
 				cmykColor, ok := gs.ColorNonStroking.(*model.PdfColorDeviceCMYK)
@@ -546,7 +582,7 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 					common.Log.Debug("Error converting color: %v", color)
 					return nil
 				}
-				ctx.SetFillRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), 1)
+				ctx.SetFillRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), alpha.fill)
 			// Set CMYK stroking color.
 			case "K":
 				cmykColor, ok := gs.ColorStroking.(*model.PdfColorDeviceCMYK)
@@ -564,7 +600,7 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 					common.Log.Debug("Error converting color: %v", color)
 					return nil
 				}
-				ctx.SetStrokeRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), 1)
+				ctx.SetStrokeRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), alpha.stroke)
 			// Set Grayscale non-stroking color.
 			case "g":
 				grayColor, ok := gs.ColorNonStroking.(*model.PdfColorDeviceGray)
@@ -582,7 +618,7 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 					common.Log.Debug("Error converting color: %v", color)
 					return nil
 				}
-				ctx.SetFillRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), 1)
+				ctx.SetFillRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), alpha.fill)
 			// Set Grayscale stroking color.
 			case "G":
 				grayColor, ok := gs.ColorStroking.(*model.PdfColorDeviceGray)
@@ -600,7 +636,7 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 					common.Log.Debug("Error converting color: %v", color)
 					return nil
 				}
-				ctx.SetStrokeRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), 1)
+				ctx.SetStrokeRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), alpha.stroke)
 			case "cs", "sc", "scn":
 				color, err := gs.ColorspaceNonStroking.ColorToRGB(gs.ColorNonStroking)
 				if err != nil {
@@ -612,7 +648,7 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 					common.Log.Debug("Error converting color: %v", color)
 					return nil
 				}
-				ctx.SetFillRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), 1)
+				ctx.SetFillRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), alpha.fill)
 			case "CS", "SC", "SCN":
 				color, err := gs.ColorspaceStroking.ColorToRGB(gs.ColorStroking)
 				if err != nil {
@@ -624,7 +660,7 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 					common.Log.Debug("Error converting color: %v", color)
 					return nil
 				}
-				ctx.SetStrokeRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), 1)
+				ctx.SetStrokeRGBA(rgbColor.R(), rgbColor.G(), rgbColor.B(), alpha.stroke)
 
 			//
 			// Image operators
@@ -660,9 +696,18 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 					if err != nil {
 						return err
 					}
+
+					// Apply soft mask and constant alpha.
+					var mask image.Image
+					if smask, ok := core.GetStream(ximg.SMask); ok {
+						mask, err = loadSoftMask(smask)
+						if err != nil {
+							common.Log.Debug("ERROR: could not load soft mask: %v", err)
+						}
+					}
+					goImg = applyImageAlpha(goImg, mask, alpha.fill)
 					bounds := goImg.Bounds()
 
-					// TODO: Handle soft masks.
 					ctx.Push()
 					ctx.Scale(1.0/float64(bounds.Dx()), -1.0/float64(bounds.Dy()))
 					ctx.DrawImageAnchored(goImg, 0, 0, 0, 1)
@@ -729,7 +774,7 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 					}
 
 					// Process the content stream in the Form object.
-					err = r.renderContentStream(ctx, string(formContent), formResources)
+					err = r.renderContentStream(ctx, string(formContent), formResources, alpha)
 					if err != nil {
 						return err
 					}
@@ -755,6 +800,7 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 				if err != nil {
 					return err
 				}
+				goImg = applyImageAlpha(goImg, nil, alpha.fill)
 				bounds := goImg.Bounds()
 
 				ctx.Push()
@@ -1069,3 +1115,89 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 
 	return nil
 }
+
+// loadSoftMask loads the soft mask image contained in the specified stream.
+func loadSoftMask(stream *core.PdfObjectStream) (image.Image, error) {
+	ximg, err := model.NewXObjectImageFromStream(stream)
+	if err != nil {
+		return nil, err
+	}
+	img, err := ximg.ToImage()
+	if err != nil {
+		return nil, err
+	}
+	return img.ToGoImage()
+}
+
+// applyImageAlpha returns a copy of `img` having the alpha channel multiplied
+// by the luminosity of `mask` (which is scaled to the size of the image) and
+// by the constant `alpha` value. If there is nothing to apply, the input
+// image is returned.
+func applyImageAlpha(img, mask image.Image, alpha float64) image.Image {
+	if mask == nil && alpha >= 1 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	out := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	var mbounds image.Rectangle
+	if mask != nil {
+		mbounds = mask.Bounds()
+	}
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			c := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			a := float64(c.A) / 255 * alpha
+			if mask != nil {
+				mx := mbounds.Min.X + x*mbounds.Dx()/bounds.Dx()
+				my := mbounds.Min.Y + y*mbounds.Dy()/bounds.Dy()
+				gray := color.GrayModel.Convert(mask.At(mx, my)).(color.Gray)
+				a *= float64(gray.Y) / 255
+			}
+			c.A = uint8(a*255 + 0.5)
+			out.SetNRGBA(x, y, c)
+		}
+	}
+	return out
+}
+
+// blendModes maps the names of the separable blend modes to the blend modes of
+// the rendering context.
+var blendModes = map[string]context.BlendMode{
+	"Normal":     context.BlendModeNormal,
+	"Compatible": context.BlendModeNormal,
+	"Multiply":   context.BlendModeMultiply,
+	"Screen":     context.BlendModeScreen,
+	"Overlay":    context.BlendModeOverlay,
+	"Darken":     context.BlendModeDarken,
+	"Lighten":    context.BlendModeLighten,
+	"ColorDodge": context.BlendModeColorDodge,
+	"ColorBurn":  context.BlendModeColorBurn,
+	"HardLight":  context.BlendModeHardLight,
+	"SoftLight":  context.BlendModeSoftLight,
+	"Difference": context.BlendModeDifference,
+	"Exclusion":  context.BlendModeExclusion,
+}
+
+// blendMode returns the blend mode specified by the BM entry `bm` of a graphics
+// state parameter dictionary, which is either a name or an array of names, the
+// first supported blend mode of the array being used. The non-separable blend
+// modes (Hue, Saturation, Color and Luminosity) are not supported: the content
+// painted with them is rendered with the Normal blend mode.
+func blendMode(bm core.PdfObject) context.BlendMode {
+	names := []core.PdfObject{bm}
+	if arr, ok := core.GetArray(bm); ok {
+		names = arr.Elements()
+	}
+	for _, obj := range names {
+		name, ok := core.GetNameVal(obj)
+		if !ok {
+			continue
+		}
+		if mode, ok := blendModes[name]; ok {
+			return mode
+		}
+		common.Log.Debug("Blend mode %s not supported: rendered with Normal blend mode", name)
+	}
+	return context.BlendModeNormal
+}